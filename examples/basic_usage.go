@@ -37,7 +37,10 @@ func main() {
 	config.JSONDir = "json"
 
 	// Initialize chunker
-	chunkerInstance := chunker.NewChunker(config, aiProvider)
+	chunkerInstance := chunker.NewChunker(
+		chunker.WithConfig(config),
+		chunker.WithProvider(aiProvider),
+	)
 
 	// Example 2: Process PDF file with token usage tracking
 	fmt.Println("\n=== Example 2: Processing PDF File with Token Usage ===")
@@ -1,822 +1,785 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"image"
-	"image/png"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
-
-	"io"
-
-	"github.com/gen2brain/go-fitz"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/cache"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/coord"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/doctor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/mcp"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/providers"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/search"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/server"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/sink"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/store"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tenant"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/worker"
+	_ "github.com/lib/pq"  // Registers the "postgres" driver of SERVE_STORE
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver of SERVE_STORE
 )
 
 // Configuration constants
 const (
-	DataDir    = "data"
-	OutputDir  = "output"
-	ChunkDir   = "chunk"
-	JSONDir    = "json"
-	TempPrefix = "temp_page_"
-	PageSep    = "\n\n--- Page %d ---\n\n"
+	DataDir   = "data"
+	OutputDir = "output"
+	ChunkDir  = "chunk"
+	JSONDir   = "json"
 )
 
-// OpenAI API configuration
+// Chunk size constants
 const (
-	OpenAIAPIURL   = "https://api.openai.com/v1/chat/completions"
 	MaxChunkSize   = 4000 // Maximum characters per chunk before sending to AI
 	LocalChunkSize = 3000 // Maximum characters for local chunking
 )
 
-// OpenAIRequest represents the request structure for OpenAI API
-type OpenAIRequest struct {
-	Model     string          `json:"model"`
-	Messages  []OpenAIMessage `json:"messages"`
-	MaxTokens int             `json:"max_tokens"`
-}
-
-// OpenAIMessage represents a message in the OpenAI API
-type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// OpenAIResponse represents the response structure from OpenAI API
-type OpenAIResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-}
-
-// ChunkData represents a structured chunk for vector database embedding
-type ChunkData struct {
-	Filename   string `json:"filename"`
-	ChunkIndex int    `json:"chunk_index"`
-	PageRange  string `json:"page_range"`
-	Text       string `json:"text"`
-}
-
-// PDFProcessor handles PDF text extraction with OCR fallback and intelligent chunking
-type PDFProcessor struct {
-	dataDir   string
-	outputDir string
-	chunkDir  string
-	jsonDir   string
-	apiKey    string
-	useAI     bool
-}
-
-// NewPDFProcessor creates a new PDF processor instance
-func NewPDFProcessor(dataDir, outputDir, chunkDir, jsonDir string) *PDFProcessor {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	useAI := apiKey != ""
-
-	if !useAI {
-		log.Println("⚠️  OpenAI API key not found. Using local intelligent chunking.")
-	}
-
-	return &PDFProcessor{
-		dataDir:   dataDir,
-		outputDir: outputDir,
-		chunkDir:  chunkDir,
-		jsonDir:   jsonDir,
-		apiKey:    apiKey,
-		useAI:     useAI,
-	}
-}
+// snippetLength is the number of characters of chunk text printed with each match
+const snippetLength = 200
 
 func main() {
-	processor := NewPDFProcessor(DataDir, OutputDir, ChunkDir, JSONDir)
-
-	if err := processor.ensureDirectories(); err != nil {
-		log.Fatal("Failed to create directories:", err)
+	// `chunk --stdin` chunks one document from a pipe and writes its chunks to stdout
+	if len(os.Args) > 1 && os.Args[1] == "chunk" {
+		runChunk(os.Args[2:])
+		return
+	}
+	// `search "query"` looks up chunks of an earlier run instead of processing documents
+	if len(os.Args) > 1 && os.Args[1] == "search" {
+		runSearch(os.Args[2:])
+		return
+	}
+	// `doctor` checks that tesseract, MuPDF and the output directories are ready
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		runDoctor(os.Args[2:])
+		return
+	}
+	// `progress` prints the documents instances sharing a Redis have claimed and processed
+	if len(os.Args) > 1 && os.Args[1] == "progress" {
+		runProgress(os.Args[2:])
+		return
+	}
+	// `worker` chunks documents named by the jobs of an SQS or RabbitMQ queue
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		runWorker(os.Args[2:])
+		return
+	}
+	// `serve` chunks documents uploaded over HTTP, keeping the teams of a deployment apart
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return
+	}
+	// `mcp` serves chunking tools to LLM agents over the Model Context Protocol on stdio
+	if len(os.Args) > 1 && os.Args[1] == "mcp" {
+		runMCP(os.Args[2:])
+		return
+	}
+	// --retry-failed processes only the documents listed in output/failures.json
+	retryFailed := flag.Bool("retry-failed", false, "process only the documents that failed in the last run")
+	flag.Parse()
+
+	cfg := loadConfig()
+	// Check tesseract, MuPDF and the output directories once instead of failing per page
+	preflight(cfg)
+
+	// Only the PDFs at the top of data/ are processed, as they always were
+	opts := append(chunkerOptions(cfg), chunker.WithFileFilter(isDataPDF))
+	// REDIS_ADDR shares the files of data/ with the other instances using the same Redis
+	if coordinator := openCoordinator(); coordinator != nil {
+		defer coordinator.Close()
+		log.Printf("Sharing documents with other instances through Redis as %s", coordinator.Owner())
+		opts = append(opts, chunker.WithCoordinator(coordinator))
+	}
+
+	// Full text goes to output/, chunks to chunk/ and json/
+	chunkerInstance := chunker.NewChunker(opts...)
+	defer chunkerInstance.Close()
+	// Ctrl-C finishes the documents in progress and saves a checkpoint; a second one
+	// removes OCR page images and other temp files and quits at once
+	defer chunkerInstance.StopOnSignal()()
+	var result *chunker.BatchResult
+	var err error
+	if *retryFailed {
+		result, err = chunkerInstance.WithProgress(printProgress).RetryFailed(chunker.OutputRawText)
+		if errors.Is(err, chunker.ErrNoFailures) {
+			fmt.Println("No failed documents to retry")
+			return
+		}
+	} else {
+		result, err = chunkerInstance.WithProgress(printProgress).ChunkDirectory(DataDir, chunker.OutputRawText)
 	}
-
-	if err := processor.processAllPDFs(); err != nil {
-		log.Fatal("Failed to process PDFs:", err)
+	if err != nil && !errors.Is(err, chunker.ErrStopped) {
+		log.Fatal("Failed to process documents:", err)
 	}
-}
 
-// ensureDirectories creates the output and chunk directories if they don't exist
-func (p *PDFProcessor) ensureDirectories() error {
-	dirs := []string{p.outputDir, p.chunkDir, p.jsonDir}
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
-		}
+	report := result.Report()
+	fmt.Println()
+	if err := report.WriteTable(os.Stdout); err != nil {
+		log.Fatal("Failed to write run report:", err)
 	}
-	return nil
-}
-
-// processAllPDFs processes all PDF files in the data directory
-func (p *PDFProcessor) processAllPDFs() error {
-	entries, err := os.ReadDir(p.dataDir)
-	if err != nil {
-		return fmt.Errorf("failed to read data directory: %w", err)
+	fmt.Printf("\nRun report saved to %s\n", filepath.Join(OutputDir, chunker.RunReportFilename))
+	if report.Failed > 0 {
+		fmt.Printf("Failed documents saved to %s; run with --retry-failed to process only them again\n", filepath.Join(OutputDir, chunker.FailuresFilename))
 	}
-
-	processedCount := 0
-	for _, entry := range entries {
-		if p.isPDFFile(entry) {
-			if err := p.processSinglePDF(entry.Name()); err != nil {
-				log.Printf("Error processing %s: %v", entry.Name(), err)
-			} else {
-				processedCount++
-				fmt.Printf("✓ Successfully processed: %s\n", entry.Name())
-			}
+	if errors.Is(err, chunker.ErrStopped) {
+		if *retryFailed {
+			fmt.Printf("%s still lists every failed document; run with --retry-failed again\n", filepath.Join(OutputDir, chunker.FailuresFilename))
+			chunkerInstance.Close()
+			os.Exit(130)
 		}
+		fmt.Printf("Checkpoint saved to %s; run again to resume\n", filepath.Join(OutputDir, chunker.CheckpointFilename))
+		chunkerInstance.Close()
+		os.Exit(130)
 	}
-
-	fmt.Printf("\n🎉 Processing complete! %d PDF files processed.\n", processedCount)
-	return nil
-}
-
-// isPDFFile checks if the given entry is a PDF file
-func (p *PDFProcessor) isPDFFile(entry os.DirEntry) bool {
-	return !entry.IsDir() && strings.HasSuffix(strings.ToLower(entry.Name()), ".pdf")
 }
 
-// processSinglePDF processes a single PDF file
-func (p *PDFProcessor) processSinglePDF(filename string) error {
-	pdfPath := filepath.Join(p.dataDir, filename)
-	outputPath := filepath.Join(p.outputDir, strings.TrimSuffix(filename, ".pdf")+".txt")
-
-	fmt.Printf("\n📄 Processing: %s\n", filename)
-
-	// Extract text from PDF
-	if err := p.extractTextFromPDF(pdfPath, outputPath); err != nil {
-		return err
-	}
-
-	// Create intelligent chunks
-	chunkDir := filepath.Join(p.chunkDir, strings.TrimSuffix(filename, ".pdf"))
-	if err := p.createIntelligentChunks(outputPath, chunkDir, filename); err != nil {
-		return err
+// printProgress prints the pages and chunks of a run as they are done
+func printProgress(event chunker.ProgressEvent) {
+	switch event.Type {
+	case chunker.EventPageProcessed:
+		fmt.Printf("   ✅ Page %d of %d extracted\n", event.Page, event.Pages)
+	case chunker.EventChunkCreated:
+		fmt.Printf("   ✅ %s: chunk %d (%d chars)\n", event.Chunk.Filename, event.Chunk.ChunkIndex, len(event.Chunk.Text))
 	}
+}
 
-	return nil
+// isDataPDF reports whether a file of data/ is processed by a run: PDFs directly in
+// data/, not in its subdirectories
+func isDataPDF(filename string) bool {
+	return !strings.Contains(filename, "/") && strings.HasSuffix(strings.ToLower(filename), ".pdf")
 }
 
-// extractTextFromPDF extracts text from a PDF file with OCR fallback
-func (p *PDFProcessor) extractTextFromPDF(pdfPath, outputPath string) error {
-	doc, err := fitz.New(pdfPath)
-	if err != nil {
-		return fmt.Errorf("failed to open PDF: %w", err)
-	}
-	defer doc.Close()
+// loadConfig returns the configuration of a run: the default one with the directories
+// and chunk sizes above and the overrides of the environment
+func loadConfig() config.ChunkerConfig {
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = OutputDir
+	cfg.ChunkDir = ChunkDir
+	cfg.JSONDir = JSONDir
+	cfg.MaxChunkSize = MaxChunkSize
+	cfg.LocalChunkSize = LocalChunkSize
 
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+	// OCR_LANGUAGES overrides the default tesseract languages, e.g. "eng+ara"
+	if langs := os.Getenv("OCR_LANGUAGES"); langs != "" {
+		cfg.OCRLanguages = strings.Split(langs, "+")
 	}
-	defer outputFile.Close()
-
-	totalPages := doc.NumPage()
-	fmt.Printf("   📊 Total pages: %d\n", totalPages)
-
-	for pageIndex := 0; pageIndex < totalPages; pageIndex++ {
-		if err := p.processPage(doc, outputFile, pageIndex, totalPages); err != nil {
-			log.Printf("   ⚠️  Warning: failed to process page %d: %v", pageIndex+1, err)
+	cfg.OCRAutoDetect = os.Getenv("OCR_AUTO_DETECT") == "true"
+	// TESSERACT_PATH points at tesseract when it is neither on PATH nor in its usual install location
+	cfg.TesseractPath = os.Getenv("TESSERACT_PATH")
+	cfg.DockerImage = os.Getenv("PDF_CHUNK_DOCKER_IMAGE")
+	// OCR_PAGE_TIMEOUT kills tesseract on a page after that long, e.g. "5m"
+	if timeout := os.Getenv("OCR_PAGE_TIMEOUT"); timeout != "" {
+		value, err := time.ParseDuration(timeout)
+		if err != nil {
+			log.Fatal("Invalid OCR_PAGE_TIMEOUT:", err)
 		}
+		cfg.OCRPageTimeout = value
+	}
+	// TMPDIR-style override for the temp root, e.g. a larger scratch volume
+	cfg.TempDir = os.Getenv("PDF_CHUNK_TEMP_DIR")
+	// Mask emails, phone numbers, NIK, NPWP and card numbers before chunking
+	cfg.RedactPII = os.Getenv("REDACT_PII") == "true"
+	// Write elements.json of unstructured.io-style elements next to every manifest
+	cfg.Elements = os.Getenv("ELEMENTS") == "true"
+
+	// AI_WORKERS sends that many slices of a document to the AI provider at once
+	if workers := os.Getenv("AI_WORKERS"); workers != "" {
+		value, err := strconv.Atoi(workers)
+		if err != nil {
+			log.Fatal("Invalid AI_WORKERS:", err)
+		}
+		cfg.AIWorkers = value
 	}
 
-	return nil
+	// LOCAL_ONLY guarantees documents never leave the machine, even with an API key set
+	cfg.LocalOnly = os.Getenv("LOCAL_ONLY") == "true"
+	// AI_AUDIT_LOG records every AI provider request (hashes, tokens, latency) for compliance and billing
+	cfg.AuditLogPath = os.Getenv("AI_AUDIT_LOG")
+	return cfg
 }
 
-// processPage extracts text from a single page
-func (p *PDFProcessor) processPage(doc *fitz.Document, outputFile *os.File, pageIndex, totalPages int) error {
-	pageNum := pageIndex + 1
-
-	// Try direct text extraction first
-	text, err := doc.Text(pageIndex)
-	if err != nil {
-		log.Printf("   ⚠️  Warning: failed to extract text from page %d: %v", pageNum, err)
+// preflight runs the doctor checks before processing: it prints the problems found with
+// their fixes and stops when a check failed, such as an output directory not writable
+func preflight(cfg config.ChunkerConfig) {
+	report := doctor.Run(cfg)
+	if err := doctor.WriteFixes(os.Stderr, report.Problems()); err != nil {
+		log.Fatal("Failed to write preflight checks:", err)
 	}
-
-	// If no text found, use OCR
-	if strings.TrimSpace(text) == "" {
-		fmt.Printf("   🔍 Page %d: No text found, using OCR...\n", pageNum)
-		text = p.extractTextWithOCR(doc, pageIndex, pageNum)
-	} else {
-		fmt.Printf("   ✅ Page %d: extracted %d characters\n", pageNum, len(strings.TrimSpace(text)))
+	if report.Failed() {
+		log.Fatal("Preflight checks failed; fix the problems above, or run `pdf-chunk-extractor doctor` for details")
 	}
-
-	// Write page separator and content
-	p.writePageContent(outputFile, pageIndex, text)
-	return nil
 }
 
-// extractTextWithOCR uses OCR to extract text from a page image
-func (p *PDFProcessor) extractTextWithOCR(doc *fitz.Document, pageIndex, pageNum int) string {
-	// Render page as image
-	img, err := doc.Image(pageIndex)
-	if err != nil {
-		log.Printf("   ⚠️  Warning: failed to render page %d as image: %v", pageNum, err)
-		return ""
-	}
+// runDoctor runs `doctor`: it checks tesseract, its language packs, the PDF engine and
+// the output directories for the configuration of a run, and prints how to fix problems
+func runDoctor(args []string) {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	flags.Parse(args)
 
-	// Save temporary image
-	tempImagePath := fmt.Sprintf("%s%d.png", TempPrefix, pageIndex)
-	if err := p.saveTemporaryImage(img, tempImagePath); err != nil {
-		log.Printf("   ⚠️  Warning: failed to save temp image: %v", err)
-		return ""
+	report := doctor.Run(loadConfig())
+	if err := report.WriteTable(os.Stdout); err != nil {
+		log.Fatal("Failed to write checks:", err)
 	}
-	defer os.Remove(tempImagePath)
-
-	// Perform OCR
-	ocrText, err := p.runTesseract(tempImagePath)
-	if err != nil {
-		log.Printf("   ⚠️  Warning: OCR failed for page %d: %v", pageNum, err)
-		return ""
+	if report.Failed() {
+		os.Exit(1)
 	}
-
-	fmt.Printf("   ✅ Page %d: OCR extracted %d characters\n", pageNum, len(strings.TrimSpace(ocrText)))
-	return ocrText
 }
 
-// saveTemporaryImage saves an image to a temporary file
-func (p *PDFProcessor) saveTemporaryImage(img image.Image, tempPath string) error {
-	imgFile, err := os.Create(tempPath)
+// chunkerOptions returns the options of a run's chunker: its configuration and the AI
+// provider of OPENAI_API_KEY or MISTRAL_API_KEY, or none for local chunking
+func chunkerOptions(cfg config.ChunkerConfig) []chunker.Option {
+	opts := []chunker.Option{chunker.WithConfig(cfg)}
+	openAIKey, mistralKey := os.Getenv("OPENAI_API_KEY"), os.Getenv("MISTRAL_API_KEY")
+	switch {
+	case cfg.LocalOnly && (openAIKey != "" || mistralKey != ""):
+		log.Fatal("LOCAL_ONLY is set but an AI provider API key is too; unset one of them")
+	case openAIKey != "":
+		aiProvider := providers.NewChatGPTProvider(openAIKey).WithParams(modelParams())
+		if responseCache := openResponseCache(); responseCache != nil {
+			aiProvider = aiProvider.WithCache(responseCache)
+		}
+		opts = append(opts, chunker.WithProvider(aiProvider))
+	case mistralKey != "":
+		// MISTRAL_API_KEY uses Mistral AI, hosted in the EU, when no OpenAI key is set
+		aiProvider := providers.NewMistralProvider(mistralKey).WithParams(modelParams())
+		if responseCache := openResponseCache(); responseCache != nil {
+			aiProvider = aiProvider.WithCache(responseCache)
+		}
+		opts = append(opts, chunker.WithProvider(aiProvider))
+	default:
+		log.Println("⚠️  No OpenAI or Mistral API key found. Using local intelligent chunking.")
+	}
+	return opts
+}
+
+// openCoordinator connects to the REDIS_ADDR Redis (with REDIS_PASSWORD and
+// REDIS_KEY_PREFIX) that instances processing one input share; it returns nil when the
+// variable is not set
+func openCoordinator() *coord.Redis {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		return nil
+	}
+	coordinator, err := coord.NewRedis(coord.RedisOptions{
+		Addr:      addr,
+		Password:  os.Getenv("REDIS_PASSWORD"),
+		KeyPrefix: os.Getenv("REDIS_KEY_PREFIX"),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create temp image file: %w", err)
-	}
-	defer imgFile.Close()
-
-	if err := png.Encode(imgFile, img); err != nil {
-		os.Remove(tempPath)
-		return fmt.Errorf("failed to encode image: %w", err)
+		log.Fatal("Failed to connect to the coordination Redis:", err)
 	}
-
-	return nil
+	return coordinator
 }
 
-// writePageContent writes page content to the output file
-func (p *PDFProcessor) writePageContent(outputFile *os.File, pageIndex int, text string) {
-	pageNum := pageIndex + 1
-	separator := fmt.Sprintf(PageSep, pageNum)
+// runProgress runs `progress`: it prints every document the instances sharing the
+// REDIS_ADDR Redis have claimed, with its status, instance and result
+func runProgress(args []string) {
+	flags := flag.NewFlagSet("progress", flag.ExitOnError)
+	flags.Parse(args)
 
-	outputFile.WriteString(separator)
-	outputFile.WriteString(text)
-}
-
-// runTesseract executes the tesseract OCR command
-func (p *PDFProcessor) runTesseract(imagePath string) (string, error) {
-	cmd := exec.Command("tesseract", imagePath, "stdout", "-l", "eng+ind")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("tesseract command failed: %w", err)
+	coordinator := openCoordinator()
+	if coordinator == nil {
+		log.Fatal("REDIS_ADDR is not set; progress is only shared between instances through Redis")
 	}
-
-	return string(output), nil
-}
-
-// createIntelligentChunks creates intelligent chunks using AI or local processing
-func (p *PDFProcessor) createIntelligentChunks(textFilePath, chunkDir, filename string) error {
-	// Read the extracted text
-	content, err := os.ReadFile(textFilePath)
+	defer coordinator.Close()
+	progress, err := coordinator.Progress()
 	if err != nil {
-		return fmt.Errorf("failed to read text file: %w", err)
+		log.Fatal("Failed to read progress:", err)
 	}
 
-	text := string(content)
-	if strings.TrimSpace(text) == "" {
-		return fmt.Errorf("text file is empty")
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FILE\tSTATUS\tINSTANCE\tUPDATED\tPAGES\tCHUNKS\tERROR")
+	counts := make(map[string]int)
+	for _, file := range progress {
+		counts[file.Status]++
+		var result chunker.FileResult
+		if file.Result != nil {
+			result = *file.Result
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n", file.Filename, file.Status, file.Owner,
+			file.UpdatedAt.Local().Format(time.DateTime), result.Pages, result.Chunks, result.Error)
 	}
+	table.Flush()
+	fmt.Printf("\n%d documents: %d processing, %d ok, %d local, %d failed\n", len(progress), counts[coord.StatusProcessing],
+		counts[chunker.FileStatusOK], counts[chunker.FileStatusLocal], counts[chunker.FileStatusFailed])
+}
 
-	// Create chunk directory
-	if err := os.MkdirAll(chunkDir, 0755); err != nil {
-		return fmt.Errorf("failed to create chunk directory: %w", err)
+// openResponseCache opens the AI_CACHE_DIR response cache, so re-runs don't pay for
+// identical completions; it returns nil when the variable is not set
+func openResponseCache() cache.Cache {
+	cacheDir := os.Getenv("AI_CACHE_DIR")
+	if cacheDir == "" {
+		return nil
 	}
-
-	if p.useAI {
-		fmt.Printf("   🧠 Creating AI-powered intelligent chunks...\n")
-		return p.createAIChunks(text, chunkDir, filename)
-	} else {
-		fmt.Printf("   🧠 Creating local intelligent chunks...\n")
-		return p.createLocalChunks(text, chunkDir, filename)
+	responseCache, err := cache.NewDisk(cacheDir)
+	if err != nil {
+		log.Fatal("Failed to open AI response cache:", err)
 	}
+	return responseCache
 }
 
-// createAIChunks creates chunks using OpenAI API
-func (p *PDFProcessor) createAIChunks(text, chunkDir, filename string) error {
-	// Split text into manageable chunks for AI processing
-	textChunks := p.splitTextIntoChunks(text)
-
-	chunkIndex := 1
-	for i, chunk := range textChunks {
-		if strings.TrimSpace(chunk) == "" {
-			continue
-		}
-
-		fmt.Printf("   📝 Processing chunk %d/%d (%d chars)\n", i+1, len(textChunks), len(chunk))
-
-		// Get intelligent chunk from AI
-		intelligentChunk, err := p.getIntelligentChunk(chunk)
+// modelParams reads OPENAI_TEMPERATURE and OPENAI_SEED, which make chunking more
+// repeatable between runs, and OPENAI_REASONING_OUTPUT_TOKENS
+func modelParams() providers.ModelParams {
+	var params providers.ModelParams
+	if temperature := os.Getenv("OPENAI_TEMPERATURE"); temperature != "" {
+		value, err := strconv.ParseFloat(temperature, 64)
 		if err != nil {
-			log.Printf("   ⚠️  Warning: AI chunking failed for chunk %d: %v", i+1, err)
-			// Fallback to local chunking
-			intelligentChunk = p.createLocalIntelligentChunk(chunk)
-		}
-
-		// Save chunk to file
-		chunkPath := filepath.Join(chunkDir, fmt.Sprintf("chunk_%d.txt", chunkIndex))
-		if err := os.WriteFile(chunkPath, []byte(intelligentChunk), 0644); err != nil {
-			log.Printf("   ⚠️  Warning: failed to save chunk %d: %v", chunkIndex, err)
-		} else {
-			fmt.Printf("   ✅ Saved chunk_%d.txt (%d chars)\n", chunkIndex, len(intelligentChunk))
-		}
-
-		// Create JSON chunk
-		if err := p.createJSONChunk(chunk, chunkIndex, filename); err != nil {
-			log.Printf("   ⚠️  Warning: failed to create JSON chunk %d: %v", chunkIndex, err)
+			log.Fatal("Invalid OPENAI_TEMPERATURE:", err)
 		}
-
-		chunkIndex++
+		params.Temperature = providers.Param(value)
 	}
-
-	fmt.Printf("   🎯 Created %d AI-powered chunks in %s\n", chunkIndex-1, chunkDir)
-	return nil
-}
-
-// createLocalChunks creates chunks using local intelligent processing
-func (p *PDFProcessor) createLocalChunks(text, chunkDir, filename string) error {
-	chunks := p.splitTextIntoLocalChunks(text)
-
-	chunkIndex := 1
-	for i, chunk := range chunks {
-		if strings.TrimSpace(chunk) == "" {
-			continue
+	if seed := os.Getenv("OPENAI_SEED"); seed != "" {
+		value, err := strconv.Atoi(seed)
+		if err != nil {
+			log.Fatal("Invalid OPENAI_SEED:", err)
 		}
-
-		// Format the chunk with headers and structure
-		formattedChunk := p.formatLocalChunk(chunk, i+1, len(chunks))
-
-		// Save chunk to file
-		chunkPath := filepath.Join(chunkDir, fmt.Sprintf("chunk_%d.txt", chunkIndex))
-		if err := os.WriteFile(chunkPath, []byte(formattedChunk), 0644); err != nil {
-			log.Printf("   ⚠️  Warning: failed to save chunk %d: %v", chunkIndex, err)
-		} else {
-			fmt.Printf("   ✅ Saved chunk_%d.txt (%d chars)\n", chunkIndex, len(formattedChunk))
+		params.Seed = providers.Param(value)
+	}
+	// OPENAI_REASONING_OUTPUT_TOKENS is the completion budget of reasoning models per slice, reasoning included
+	if tokens := os.Getenv("OPENAI_REASONING_OUTPUT_TOKENS"); tokens != "" {
+		value, err := strconv.Atoi(tokens)
+		if err != nil {
+			log.Fatal("Invalid OPENAI_REASONING_OUTPUT_TOKENS:", err)
 		}
-
-		// Create JSON chunk
-		if err := p.createJSONChunk(chunk, chunkIndex, filename); err != nil {
-			log.Printf("   ⚠️  Warning: failed to create JSON chunk %d: %v", chunkIndex, err)
+		params.ReasoningOutputTokens = value
+	}
+	return params
+}
+
+// runChunk runs `chunk [-format jsonl|json] [-filename name] -stdin | file...`: it chunks
+// the document read from stdin, or the files named, and writes the chunks to stdout, one
+// JSON object per line with jsonl or a JSON array with json. Nothing is written to the
+// output directories, so the command composes with shell pipelines:
+//
+//	cat doc.pdf | pdf-chunk-extractor chunk --stdin --format jsonl > chunks.jsonl
+//
+// A file that fails is reported on stderr and the others are still chunked; the exit
+// status is then 1.
+func runChunk(args []string) {
+	flags := flag.NewFlagSet("chunk", flag.ExitOnError)
+	stdin := flags.Bool("stdin", false, "read the document from stdin")
+	format := flags.String("format", "jsonl", "jsonl for one chunk per line, or json for a JSON array of the chunks")
+	filename := flags.String("filename", "", "name of the stdin document in its chunks, e.g. report.pdf; its extension also tells text formats apart")
+	flags.Parse(args)
+	if *stdin == (flags.NArg() > 0) {
+		log.Fatal("Usage: pdf-chunk-extractor chunk [-format jsonl|json] [-filename name] -stdin | file...")
+	}
+	if *format != "jsonl" && *format != "json" {
+		log.Fatalf("Unknown format %q; use jsonl or json", *format)
+	}
+
+	cfg := loadConfig()
+	// Chunks only go to stdout, so the output directories are neither checked nor created
+	cfg.OutputDir, cfg.ChunkDir, cfg.JSONDir = "", "", ""
+	preflight(cfg)
+	chunkerInstance := chunker.NewChunker(chunkerOptions(cfg)...)
+	defer chunkerInstance.Close()
+	// Ctrl-C stops the document in progress, as when a downstream command exits
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	documents := chunkerInstance.WithContext(ctx)
+
+	out := bufio.NewWriter(os.Stdout)
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	var all []chunker.ChunkData
+	write := func(chunks []chunker.ChunkData) {
+		if *format == "json" {
+			all = append(all, chunks...)
+			return
 		}
-
-		chunkIndex++
-	}
-
-	fmt.Printf("   🎯 Created %d local intelligent chunks in %s\n", chunkIndex-1, chunkDir)
-	return nil
-}
-
-// splitTextIntoLocalChunks splits text into intelligent chunks based on natural breaks
-func (p *PDFProcessor) splitTextIntoLocalChunks(text string) []string {
-	var chunks []string
-	var currentChunk strings.Builder
-
-	// Split text into lines for processing
-	lines := strings.Split(text, "\n")
-
-	for i, line := range lines {
-		trimmedLine := strings.TrimSpace(line)
-
-		// Check if this line is a natural break point
-		if p.isNaturalBreak(trimmedLine, i, lines) {
-			// If current chunk is getting large, save it and start new one
-			if currentChunk.Len() > LocalChunkSize {
-				chunk := strings.TrimSpace(currentChunk.String())
-				if chunk != "" {
-					chunks = append(chunks, chunk)
-				}
-				currentChunk.Reset()
+		for _, chunk := range chunks {
+			if err := encoder.Encode(chunk); err != nil {
+				log.Fatal("Failed to write chunks:", err)
 			}
 		}
-
-		// Add the line to current chunk
-		currentChunk.WriteString(line + "\n")
-
-		// If chunk is getting too large, force a break
-		if currentChunk.Len() > LocalChunkSize {
-			chunk := strings.TrimSpace(currentChunk.String())
-			if chunk != "" {
-				chunks = append(chunks, chunk)
-			}
-			currentChunk.Reset()
+		// Flush every document, so the next command in the pipeline starts on it at once
+		if err := out.Flush(); err != nil {
+			log.Fatal("Failed to write chunks:", err)
 		}
 	}
 
-	// Add remaining content
-	if currentChunk.Len() > 0 {
-		chunk := strings.TrimSpace(currentChunk.String())
-		if chunk != "" {
-			chunks = append(chunks, chunk)
+	failed := false
+	if *stdin {
+		result, err := documents.ChunkReader(os.Stdin, *filename, chunker.OutputJSON)
+		if err != nil {
+			log.Fatal("Failed to chunk stdin:", err)
 		}
+		write(result.Chunks)
 	}
-
-	return chunks
-}
-
-// isNaturalBreak checks if a line represents a natural break point
-func (p *PDFProcessor) isNaturalBreak(line string, lineIndex int, allLines []string) bool {
-	trimmed := strings.TrimSpace(line)
-
-	// Empty lines are natural breaks
-	if trimmed == "" {
-		return true
-	}
-
-	// Check for various heading patterns
-	headingPatterns := []string{
-		`^Bab\s+\d+`,         // Bab 1, Bab 2, etc.
-		`^Pasal\s+\d+`,       // Pasal 1, Pasal 2, etc.
-		`^Chapter\s+\d+`,     // Chapter 1, Chapter 2, etc.
-		`^Section\s+\d+`,     // Section 1, Section 2, etc.
-		`^Artikel\s+\d+`,     // Artikel 1, Artikel 2, etc.
-		`^BAB\s+\d+`,         // BAB 1, BAB 2, etc.
-		`^PASAL\s+\d+`,       // PASAL 1, PASAL 2, etc.
-		`^\d+\.\s+[A-Z]`,     // 1. Title, 2. Title, etc.
-		`^[A-Z][A-Z\s]{3,}$`, // ALL CAPS HEADINGS
-		`^[A-Z][a-z\s]{3,}$`, // Title Case Headings
-	}
-
-	for _, pattern := range headingPatterns {
-		if matched, _ := regexp.MatchString(pattern, trimmed); matched {
-			return true
+	for _, path := range flags.Args() {
+		result, err := documents.ChunkFile(path, chunker.OutputJSON)
+		if err != nil {
+			log.Printf("Failed to chunk %s: %v", path, err)
+			failed = true
+			continue
 		}
+		write(result.Chunks)
 	}
 
-	// Check for bullet points or numbered lists
-	if strings.HasPrefix(trimmed, "•") || strings.HasPrefix(trimmed, "-") ||
-		strings.HasPrefix(trimmed, "*") {
-		return true
-	}
-
-	// Check for numbered lists
-	if matched, _ := regexp.MatchString(`^\d+\.`, trimmed); matched {
-		return true
-	}
-
-	// Check for page separators
-	if strings.Contains(trimmed, "--- Page") {
-		return true
-	}
-
-	// Check if previous line was empty and this line looks like a heading
-	if lineIndex > 0 && strings.TrimSpace(allLines[lineIndex-1]) == "" {
-		if len(trimmed) < 100 && (strings.ToUpper(trimmed) == trimmed ||
-			strings.HasSuffix(trimmed, ":") || strings.HasSuffix(trimmed, ".")) {
-			return true
+	if *format == "json" {
+		if all == nil {
+			all = []chunker.ChunkData{}
+		}
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(all); err != nil {
+			log.Fatal("Failed to write chunks:", err)
 		}
 	}
-
-	return false
-}
-
-// formatLocalChunk formats a chunk with headers and structure
-func (p *PDFProcessor) formatLocalChunk(chunk string, chunkNum, totalChunks int) string {
-	var formatted strings.Builder
-
-	// Extract metadata
-	metadata := p.extractMetadata(chunk)
-	pageRange := p.extractPageRange(chunk)
-
-	// Add comprehensive metadata header
-	formatted.WriteString("# Document Chunk\n\n")
-
-	// Metadata section
-	formatted.WriteString("## Metadata\n")
-	formatted.WriteString(fmt.Sprintf("- **Chunk Number**: %d of %d\n", chunkNum, totalChunks))
-
-	if pageRange != "" {
-		formatted.WriteString(fmt.Sprintf("- **Page Range**: %s\n", pageRange))
+	if err := out.Flush(); err != nil {
+		log.Fatal("Failed to write chunks:", err)
 	}
-
-	if metadata != "" {
-		formatted.WriteString(metadata)
+	if failed {
+		stop()
+		chunkerInstance.Close()
+		os.Exit(1)
 	}
-
-	formatted.WriteString("\n")
-
-	// Content section with clear formatting for future embedding
-	formatted.WriteString("## Content\n\n")
-	formatted.WriteString(p.cleanAndStructureContent(chunk))
-
-	return formatted.String()
 }
 
-// extractMetadata extracts document metadata from the chunk
-func (p *PDFProcessor) extractMetadata(chunk string) string {
-	var metadata strings.Builder
-
-	// Look for document codes
-	docCodePattern := regexp.MustCompile(`(SOP|KCN|AGR|KEP|PER|UU|PP|PMK)[/-][A-Z0-9/]+`)
-	if matches := docCodePattern.FindAllString(chunk, -1); len(matches) > 0 {
-		metadata.WriteString(fmt.Sprintf("- **Document Code**: %s\n", strings.Join(matches, ", ")))
+// runSearch runs `search [-limit n] [-dir chunk] query`: it ranks the chunks saved under
+// the chunk directory by BM25, or by embedding similarity when they have vectors and
+// COHERE_API_KEY is set, and prints the best ones with their document and pages
+func runSearch(args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := flags.Int("limit", 10, "number of chunks to print")
+	chunkDir := flags.String("dir", ChunkDir, "chunk directory of the run")
+	flags.Parse(args)
+	query := strings.Join(flags.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		log.Fatal(`Usage: pdf-chunk-extractor search [-limit n] [-dir chunk] "query"`)
 	}
 
-	// Look for dates
-	datePattern := regexp.MustCompile(`(\d{1,2}\s+[-–]\s+[A-Za-z]+\s+[-–]\s+\d{4})`)
-	if matches := datePattern.FindAllString(chunk, -1); len(matches) > 0 {
-		metadata.WriteString(fmt.Sprintf("- **Date**: %s\n", strings.Join(matches, ", ")))
+	chunks, err := search.Load(*chunkDir, ".")
+	if err != nil {
+		log.Fatal("Failed to load chunks:", err)
 	}
+	index := search.NewIndex(chunks)
 
-	// Look for document titles
-	titlePattern := regexp.MustCompile(`(?m)^([A-Z][A-Za-z\s]{3,50})$`)
-	if matches := titlePattern.FindAllString(chunk, -1); len(matches) > 0 {
-		// Filter out common non-titles
-		var titles []string
-		for _, match := range matches {
-			trimmed := strings.TrimSpace(match)
-			if !strings.Contains(trimmed, "Page") && !strings.Contains(trimmed, "---") &&
-				len(trimmed) > 5 && len(trimmed) < 100 {
-				titles = append(titles, trimmed)
-			}
-		}
-		if len(titles) > 0 {
-			metadata.WriteString(fmt.Sprintf("- **Document Title**: %s\n", strings.Join(titles[:1], ", ")))
+	var results []search.Result
+	method := "BM25"
+	if cohereKey := os.Getenv("COHERE_API_KEY"); cohereKey != "" && index.HasEmbeddings() {
+		vector, err := providers.NewCohereProvider(cohereKey).EmbedQuery(query)
+		if err != nil {
+			log.Fatal("Failed to embed query:", err)
 		}
+		results = index.SearchVector(vector, *limit)
+		method = "embedding similarity"
+	} else {
+		results = index.Search(query, *limit)
 	}
 
-	return metadata.String()
-}
-
-// extractPageRange extracts page range from the chunk
-func (p *PDFProcessor) extractPageRange(chunk string) string {
-	// Look for page separators like "--- Page X ---"
-	pagePattern := regexp.MustCompile(`--- Page (\d+) ---`)
-	matches := pagePattern.FindAllStringSubmatch(chunk, -1)
-
-	if len(matches) == 0 {
-		return ""
+	fmt.Printf("%d matching chunks of %d in %s, by %s\n", len(results), index.Len(), *chunkDir, method)
+	for i, result := range results {
+		chunk := result.Chunk
+		location := fmt.Sprintf("chunk %d", chunk.ChunkIndex)
+		if chunk.PageRange != "" {
+			location = chunk.PageRange + ", " + location
+		}
+		fmt.Printf("\n%d. %s (%s), score %.3f\n   %s\n", i+1, chunk.Filename, location, result.Score, search.Snippet(chunk, query, snippetLength))
 	}
+}
 
-	if len(matches) == 1 {
-		// Single page
-		return fmt.Sprintf("Page %s", matches[0][1])
+// runWorker runs `worker -jobs url [-results url] [-concurrency n]`: it takes jobs from
+// an SQS or RabbitMQ queue (see worker.OpenQueue), chunks their documents and publishes
+// a result per job, until interrupted. Any number of workers can share the queues.
+func runWorker(args []string) {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	jobsURL := flags.String("jobs", os.Getenv("WORKER_JOBS_URL"), "SQS queue URL or amqp://…?queue= URL of the jobs")
+	resultsURL := flags.String("results", os.Getenv("WORKER_RESULTS_URL"), "queue URL the results are published to; none drops them")
+	concurrency := flags.Int("concurrency", 2, "jobs processed at once")
+	includeChunks := flags.Bool("include-chunks", false, "put the chunks into results, not only the output directories and sinks")
+	flags.Parse(args)
+	if *jobsURL == "" {
+		log.Fatal("Usage: pdf-chunk-extractor worker -jobs url [-results url] [-concurrency n]")
 	}
 
-	// Multiple pages - get first and last
-	firstPage := matches[0][1]
-	lastPage := matches[len(matches)-1][1]
-
-	if firstPage == lastPage {
-		return fmt.Sprintf("Page %s", firstPage)
+	cfg := loadConfig()
+	preflight(cfg)
+	jobs, err := worker.OpenQueue(*jobsURL)
+	if err != nil {
+		log.Fatal("Failed to open jobs queue:", err)
 	}
-
-	return fmt.Sprintf("Page %s–%s", firstPage, lastPage)
-}
-
-// cleanAndStructureContent cleans and structures the content for better embedding
-func (p *PDFProcessor) cleanAndStructureContent(chunk string) string {
-	lines := strings.Split(chunk, "\n")
-	var cleaned strings.Builder
-
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		// Skip empty lines at the beginning and end
-		if trimmed == "" && (i == 0 || i == len(lines)-1) {
-			continue
-		}
-
-		// Clean up page separators
-		if strings.Contains(trimmed, "--- Page") {
-			cleaned.WriteString(fmt.Sprintf("\n### Page %s\n\n",
-				regexp.MustCompile(`Page (\d+)`).FindStringSubmatch(trimmed)[1]))
-			continue
-		}
-
-		// Format headings
-		if p.isHeading(trimmed) {
-			cleaned.WriteString(fmt.Sprintf("\n### %s\n\n", trimmed))
-			continue
-		}
-
-		// Format bullet points and numbered lists
-		if strings.HasPrefix(trimmed, "•") || strings.HasPrefix(trimmed, "-") ||
-			strings.HasPrefix(trimmed, "*") {
-			cleaned.WriteString(fmt.Sprintf("- %s\n", strings.TrimSpace(trimmed[1:])))
-			continue
+	defer jobs.Close()
+	var results worker.Queue
+	if *resultsURL != "" {
+		results, err = worker.OpenQueue(*resultsURL)
+		if err != nil {
+			log.Fatal("Failed to open results queue:", err)
 		}
-
-		// Format numbered lists
-		if matched, _ := regexp.MatchString(`^\d+\.`, trimmed); matched {
-			cleaned.WriteString(fmt.Sprintf("%s\n", trimmed))
-			continue
+		defer results.Close()
+	}
+
+	chunkerInstance := chunker.NewChunker(chunkerOptions(cfg)...)
+	defer chunkerInstance.Close()
+	// S3_ENDPOINT reads s3:// sources from an S3-compatible store such as MinIO
+	w := worker.New(chunkerInstance, jobs, results, worker.Options{
+		Concurrency:   *concurrency,
+		OutputType:    chunker.OutputBoth,
+		IncludeChunks: *includeChunks,
+		S3:            worker.NewS3(worker.S3Options{Endpoint: os.Getenv("S3_ENDPOINT")}),
+	})
+
+	// Ctrl-C or SIGTERM stops taking jobs and finishes the ones in progress
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("Taking jobs from %s with %d workers", jobs.GetName(), *concurrency)
+	if err := w.Run(ctx); err != nil {
+		log.Fatal("Worker failed:", err)
+	}
+}
+
+// runMCP runs `mcp [-root dir]`: it serves the extract_pdf_text, chunk_document and
+// search_chunks tools (see mcp.Server) to the MCP client that started it, over stdin and
+// stdout, until the client closes stdin. Documents are read from under the root
+// directory only; saved chunks go to the chunk directory, as in a run.
+func runMCP(args []string) {
+	flags := flag.NewFlagSet("mcp", flag.ExitOnError)
+	root := flags.String("root", ".", "directory tools may read documents from")
+	flags.Parse(args)
+
+	cfg := loadConfig()
+	preflight(cfg)
+	chunkerInstance := chunker.NewChunker(chunkerOptions(cfg)...)
+	defer chunkerInstance.Close()
+	options := mcp.Options{Chunker: chunkerInstance, Root: *root, ChunkDir: cfg.ChunkDir}
+	// COHERE_API_KEY ranks search_chunks by embedding similarity, as in `search`
+	if cohereKey := os.Getenv("COHERE_API_KEY"); cohereKey != "" {
+		options.EmbedQuery = providers.NewCohereProvider(cohereKey).EmbedQuery
+	}
+	server, err := mcp.New(options)
+	if err != nil {
+		log.Fatal("Failed to start MCP server:", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("Serving MCP tools on stdio for documents under %s", *root)
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil {
+		log.Fatal("MCP server failed:", err)
+	}
+}
+
+// runServe runs `serve [-addr :8080]`: it chunks documents uploaded over HTTP (see
+// server.Server) for the tenant named by the X-Tenant-ID header. Each tenant has its own
+// directories under output/, chunk/ and json/, its own AI rate limits and budget, and
+// its own Kafka topic and NATS subject, suffixed with the tenant ID.
+//
+// `serve -demo` runs a public playground instead: one tenant, local chunking of small
+// documents (see config.DemoConfig), rate-limited uploads, and nothing kept.
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	workers := flags.Int("workers", 2, "jobs processed at once")
+	defaultTenant := flags.String("default-tenant", "", "tenant of requests without an X-Tenant-ID header; none rejects them")
+	jobRetention := flags.Duration("job-retention", server.DefaultJobRetention, "how long finished jobs are kept; negative keeps them")
+	maxJobs := flags.Int("max-jobs", 0, "finished jobs kept at most, the oldest removed first; 0 is unlimited")
+	uploadsPerMinute := flags.Int("uploads-per-minute", 0, "uploads accepted per minute from one client address; 0 is unlimited")
+	trustProxy := flags.Bool("trust-proxy", false, "take client addresses from X-Forwarded-For, for a server behind a reverse proxy")
+	demo := flags.Bool("demo", false, "run a public demo: local chunking of small documents, rate-limited uploads, nothing kept")
+	flags.Parse(args)
+
+	var cfg config.ChunkerConfig
+	var opts []chunker.Option
+	sinks := tenantSinks
+	var allowed []string
+	if *demo {
+		if os.Getenv("SERVE_STORE") != "" {
+			log.Fatal("SERVE_STORE is set, but -demo keeps nothing; unset one of them")
 		}
-
-		// Regular text
-		if trimmed != "" {
-			cleaned.WriteString(trimmed + "\n")
-		} else {
-			cleaned.WriteString("\n")
+		var cleanup func()
+		cfg, cleanup = demoConfig()
+		defer cleanup()
+		preflight(cfg)
+		opts = []chunker.Option{chunker.WithConfig(cfg)}
+		sinks = nil
+		demoDefaults(flags, defaultTenant, jobRetention, maxJobs, uploadsPerMinute)
+		allowed = []string{*defaultTenant}
+	} else {
+		cfg = loadConfig()
+		preflight(cfg)
+		opts = chunkerOptions(cfg)
+	}
+	// SERVE_TENANTS, e.g. "search,legal", serves only those tenants; a demo serves one
+	if tenants := os.Getenv("SERVE_TENANTS"); tenants != "" && !*demo {
+		for _, id := range strings.Split(tenants, ",") {
+			id = strings.TrimSpace(id)
+			if err := tenant.ValidateID(id); err != nil {
+				log.Fatalf("Invalid tenant %q in SERVE_TENANTS: %v", id, err)
+			}
+			allowed = append(allowed, id)
 		}
 	}
-
-	return strings.TrimSpace(cleaned.String())
-}
-
-// isHeading checks if a line is a heading
-func (p *PDFProcessor) isHeading(line string) bool {
-	trimmed := strings.TrimSpace(line)
-
-	// Check for various heading patterns
-	headingPatterns := []string{
-		`^Bab\s+\d+`,         // Bab 1, Bab 2, etc.
-		`^Pasal\s+\d+`,       // Pasal 1, Pasal 2, etc.
-		`^Chapter\s+\d+`,     // Chapter 1, Chapter 2, etc.
-		`^Section\s+\d+`,     // Section 1, Section 2, etc.
-		`^Artikel\s+\d+`,     // Artikel 1, Artikel 2, etc.
-		`^BAB\s+\d+`,         // BAB 1, BAB 2, etc.
-		`^PASAL\s+\d+`,       // PASAL 1, PASAL 2, etc.
-		`^\d+\.\s+[A-Z]`,     // 1. Title, 2. Title, etc.
-		`^[A-Z][A-Z\s]{3,}$`, // ALL CAPS HEADINGS
-		`^[A-Z][a-z\s]{3,}$`, // Title Case Headings
-	}
-
-	for _, pattern := range headingPatterns {
-		if matched, _ := regexp.MatchString(pattern, trimmed); matched {
-			return true
+	tenants := tenant.NewPool(tenant.Options{
+		Config:  cfg,
+		Options: opts,
+		Sinks:   sinks,
+		Allowed: allowed,
+	})
+	defer tenants.Close()
+	// SERVE_API_KEYS names a JSON file of server.APIKeys, which requests must then carry
+	var keys *server.Keys
+	if path := os.Getenv("SERVE_API_KEYS"); path != "" {
+		var err error
+		keys, err = server.LoadKeys(path, cfg.AIPromptPrice, cfg.AICompletionPrice)
+		if err != nil {
+			log.Fatal("Failed to load API keys:", err)
 		}
 	}
 
-	// Check if it looks like a heading (short, ends with colon or period)
-	if len(trimmed) < 100 && (strings.HasSuffix(trimmed, ":") || strings.HasSuffix(trimmed, ".")) {
-		return true
-	}
-
-	return false
-}
-
-// createLocalIntelligentChunk creates a local intelligent chunk (fallback for AI)
-func (p *PDFProcessor) createLocalIntelligentChunk(text string) string {
-	chunks := p.splitTextIntoLocalChunks(text)
-	if len(chunks) == 0 {
-		return text
-	}
-
-	// Return the first chunk (since this is called for individual chunks)
-	return p.formatLocalChunk(chunks[0], 1, 1)
-}
-
-// splitTextIntoChunks splits text into manageable chunks for AI processing
-func (p *PDFProcessor) splitTextIntoChunks(text string) []string {
-	var chunks []string
-	lines := strings.Split(text, "\n")
-	var currentChunk strings.Builder
-
-	for _, line := range lines {
-		currentChunk.WriteString(line + "\n")
-
-		// If chunk is getting too large, split it
-		if currentChunk.Len() > MaxChunkSize {
-			chunks = append(chunks, currentChunk.String())
-			currentChunk.Reset()
+	// SERVE_STORE persists finished jobs with their results (see store.Open), instead of
+	// saving chunk and JSON files under the tenants' local directories
+	var jobStore store.Store
+	if url := os.Getenv("SERVE_STORE"); url != "" {
+		var err error
+		jobStore, err = store.Open(url)
+		if err != nil {
+			log.Fatal("Failed to open job store:", err)
 		}
-	}
-
-	// Add remaining content
-	if currentChunk.Len() > 0 {
-		chunks = append(chunks, currentChunk.String())
-	}
-
-	return chunks
-}
-
-// getIntelligentChunk uses OpenAI API to create intelligent chunks
-func (p *PDFProcessor) getIntelligentChunk(text string) (string, error) {
-	prompt := `You are an AI system optimizing document processing. If the chunking below fails or produces low-quality results, please gracefully degrade by returning the original text as fallback. Always include metadata like page numbers, chunk index, and document title in the output.
-
-Your task is to chunk the provided text into meaningful, coherent sections based on themes, topics, or logical flow.
-
-Please analyze the text and create a well-structured chunk that:
-1. Groups related content together
-2. Maintains logical flow and context
-3. Includes relevant metadata when available (document codes, dates, etc.)
-4. Preserves important formatting and structure
-5. Makes the content easy to understand and navigate
-6. Always includes page numbers, chunk index, and document title in the output
-7. If chunking fails or produces poor results, return the original text with basic formatting
-
-IMPORTANT: If you cannot create a meaningful chunk or the result would be worse than the original, simply return the original text with basic headers and metadata extraction.
-
-Text to chunk:
-` + text + `
-
-Please return the chunked content with appropriate headers, sections, and formatting to make it clear and organized. If chunking is not beneficial, return the original text with basic structure.`
-
-	request := OpenAIRequest{
-		Model: "gpt-3.5-turbo",
-		Messages: []OpenAIMessage{
-			{
-				Role:    "system",
-				Content: "You are an AI system optimizing document processing with intelligent chunking capabilities. You excel at organizing and structuring text content for better readability and understanding. Always prioritize preserving meaning and context over aggressive restructuring. If chunking would degrade the content quality, gracefully fall back to the original text with basic formatting and metadata extraction.",
-			},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		MaxTokens: 2000,
-	}
+		defer jobStore.Close()
+	}
+
+	srv := server.New(server.Options{
+		Tenants:          tenants,
+		DefaultTenant:    *defaultTenant,
+		OutputType:       serveOutputType(*demo, jobStore),
+		Workers:          *workers,
+		MaxUploadMB:      cfg.MaxFileSizeMB,
+		TempDir:          cfg.TempDir,
+		Keys:             keys,
+		JobRetention:     *jobRetention,
+		MaxJobs:          *maxJobs,
+		Store:            jobStore,
+		UploadsPerMinute: *uploadsPerMinute,
+		TrustProxy:       *trustProxy,
+	})
+	httpServer := &http.Server{Addr: *addr, Handler: srv.Handler()}
+
+	// Ctrl-C or SIGTERM stops taking requests and finishes the jobs in progress
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+	if *demo {
+		log.Printf("Demo mode: local chunking of documents up to %d MB and %d pages, %d uploads a minute per client, jobs kept %s",
+			cfg.MaxFileSizeMB, cfg.MaxPages, *uploadsPerMinute, *jobRetention)
+	} else if keys == nil {
+		log.Printf("⚠️  SERVE_API_KEYS is not set; serving without authentication")
+	}
+	log.Printf("Serving on %s", *addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal("Server failed:", err)
+	}
+	srv.Wait()
+}
+
+// serveOutputType returns what the server's chunkers save besides their responses: chunk
+// and JSON files under the tenants' directories, or nothing with a job store, which
+// keeps the results instead, and in a demo, which keeps nothing
+func serveOutputType(demo bool, jobStore store.Store) chunker.OutputType {
+	if demo || jobStore != nil {
+		return chunker.OutputJSON
+	}
+	return chunker.OutputBoth
+}
+
+// Defaults of `serve -demo` for the flags not given
+const (
+	demoTenant           = "demo"
+	demoJobRetention     = 10 * time.Minute
+	demoMaxJobs          = 200
+	demoUploadsPerMinute = 5
+)
 
-	response, err := p.callOpenAIAPI(request)
+// demoConfig returns the configuration of `serve -demo`: config.DemoConfig with every
+// output and temp directory in a fresh temp directory, which cleanup removes
+func demoConfig() (config.ChunkerConfig, func()) {
+	cfg := config.DemoConfig()
+	root, err := os.MkdirTemp(os.Getenv("PDF_CHUNK_TEMP_DIR"), "pdf-chunk-demo-")
 	if err != nil {
-		return "", fmt.Errorf("OpenAI API call failed: %w", err)
+		log.Fatal("Failed to create demo directory:", err)
 	}
-
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI API")
+	cfg.OutputDir = filepath.Join(root, OutputDir)
+	cfg.ChunkDir = filepath.Join(root, ChunkDir)
+	cfg.JSONDir = filepath.Join(root, JSONDir)
+	cfg.TempDir = filepath.Join(root, "tmp")
+	if langs := os.Getenv("OCR_LANGUAGES"); langs != "" {
+		cfg.OCRLanguages = strings.Split(langs, "+")
 	}
-
-	return response.Choices[0].Message.Content, nil
+	cfg.TesseractPath = os.Getenv("TESSERACT_PATH")
+	return cfg, func() { os.RemoveAll(root) }
 }
 
-// callOpenAIAPI makes a request to the OpenAI API
-func (p *PDFProcessor) callOpenAIAPI(request OpenAIRequest) (*OpenAIResponse, error) {
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create HTTP request
-	req, err := http.NewRequest("POST", OpenAIAPIURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// demoDefaults applies the defaults of `serve -demo` to the flags not given, so a demo
+// always serves one tenant, removes jobs after minutes and limits every client's uploads
+func demoDefaults(flags *flag.FlagSet, defaultTenant *string, jobRetention *time.Duration, maxJobs, uploadsPerMinute *int) {
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if !given["default-tenant"] || *defaultTenant == "" {
+		*defaultTenant = demoTenant
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+p.apiKey)
-
-	// Make the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	if !given["job-retention"] || *jobRetention < 0 {
+		*jobRetention = demoJobRetention
 	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	if !given["max-jobs"] || *maxJobs <= 0 {
+		*maxJobs = demoMaxJobs
 	}
-
-	// Parse response
-	var response OpenAIResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if !given["uploads-per-minute"] || *uploadsPerMinute <= 0 {
+		*uploadsPerMinute = demoUploadsPerMinute
 	}
-
-	return &response, nil
 }
 
-// createJSONChunk creates a JSON object for vector database embedding
-func (p *PDFProcessor) createJSONChunk(chunk string, chunkIndex int, filename string) error {
-	// Extract page range
-	pageRange := p.extractPageRange(chunk)
-
-	// Clean and structure the text
-	cleanedText := p.cleanAndStructureContent(chunk)
-
-	// Create chunk data
-	chunkData := ChunkData{
-		Filename:   filename,
-		ChunkIndex: chunkIndex,
-		PageRange:  pageRange,
-		Text:       cleanedText,
-	}
-
-	// Marshal to JSON
-	jsonData, err := json.Marshal(chunkData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
+// tenantSinks opens the sinks of a tenant: KAFKA_BROKERS publishes to the topic
+// <KAFKA_TOPIC>.<tenant> and NATS_ADDR to the subject <NATS_SUBJECT>.<tenant>, both
+// prefixed "chunks" by default
+func tenantSinks(id string) ([]chunker.Sink, error) {
+	var sinks []chunker.Sink
+	if brokers := os.Getenv("KAFKA_BROKERS"); brokers != "" {
+		kafka, err := sink.NewKafka(sink.KafkaOptions{
+			Brokers: strings.Split(brokers, ","),
+			Topic:   envOr("KAFKA_TOPIC", "chunks") + "." + id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, kafka)
 	}
-
-	// Create JSON directory for this file
-	jsonFileDir := filepath.Join(p.jsonDir, strings.TrimSuffix(filename, ".pdf"))
-	if err := os.MkdirAll(jsonFileDir, 0755); err != nil {
-		return fmt.Errorf("failed to create JSON directory: %w", err)
+	if addr := os.Getenv("NATS_ADDR"); addr != "" {
+		nats, err := sink.NewNATS(sink.NATSOptions{
+			Addr:    addr,
+			Subject: envOr("NATS_SUBJECT", "chunks") + "." + id,
+		})
+		if err != nil {
+			if len(sinks) > 0 {
+				sinks[0].(*sink.Kafka).Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, nats)
 	}
+	return sinks, nil
+}
 
-	// Save JSON file
-	jsonPath := filepath.Join(jsonFileDir, fmt.Sprintf("chunk_%d.json", chunkIndex))
-	if err := os.WriteFile(jsonPath, jsonData, 0644); err != nil {
-		return fmt.Errorf("failed to save JSON file: %w", err)
+// envOr returns the environment variable key, or fallback when it is not set
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
-
-	fmt.Printf("   📄 Saved chunk_%d.json (%d chars)\n", chunkIndex, len(cleanedText))
-	return nil
+	return fallback
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/server"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tenant"
+)
+
+// TestDemoKeepsNothing runs a job on the server `serve -demo` sets up and checks that
+// no file is left under the demo directory once the job is done
+func TestDemoKeepsNothing(t *testing.T) {
+	t.Setenv("PDF_CHUNK_TEMP_DIR", t.TempDir())
+	cfg, cleanup := demoConfig()
+	defer cleanup()
+	tenants := tenant.NewPool(tenant.Options{Config: cfg, Options: []chunker.Option{chunker.WithConfig(cfg)}, Allowed: []string{demoTenant}})
+	defer tenants.Close()
+	srv := server.New(server.Options{
+		Tenants:       tenants,
+		DefaultTenant: demoTenant,
+		OutputType:    serveOutputType(true, nil),
+		MaxUploadMB:   cfg.MaxFileSizeMB,
+		TempDir:       cfg.TempDir,
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/jobs?filename=notes.txt", "text/plain", strings.NewReader("Notes of the meeting.\n\nThe demo keeps nothing."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job server.Job
+	json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /v1/jobs status = %d, want 202", resp.StatusCode)
+	}
+	srv.Wait()
+
+	resp, err = http.Get(ts.URL + "/v1/jobs/" + job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if job.Status != server.JobDone || job.Result == nil || len(job.Result.Chunks) == 0 {
+		t.Fatalf("job = %s %q, want done with chunks", job.Status, job.Error)
+	}
+
+	root := filepath.Dir(cfg.OutputDir)
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			t.Errorf("demo job left %s", path)
+		}
+		return nil
+	})
+}
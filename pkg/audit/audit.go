@@ -0,0 +1,101 @@
+// Package audit writes a JSON lines log of every AI provider request, for compliance
+// reviews and for reconciling token counts with the provider's bill
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry is one AI provider request in the audit log
+type Entry struct {
+	Time             time.Time `json:"time"` // When the request was sent; when the result was collected for batch jobs
+	Provider         string    `json:"provider"`
+	Filename         string    `json:"filename"`
+	Slice            int       `json:"slice"`              // Index of the slice sent, starting at 1, as in ChunkData.ParentIndex
+	BatchID          string    `json:"batch_id,omitempty"` // Provider batch the request was part of
+	RequestSHA256    string    `json:"request_sha256"`
+	ResponseSHA256   string    `json:"response_sha256,omitempty"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Estimated        bool      `json:"estimated,omitempty"` // Token counts estimated because the provider does not report them
+	LatencyMS        int64     `json:"latency_ms"`          // 0 for batch jobs
+	Error            string    `json:"error,omitempty"`     // The request failed and the slice was chunked locally
+	Request          string    `json:"request,omitempty"`   // Only when the log includes content
+	Response         string    `json:"response,omitempty"`  // Only when the log includes content
+}
+
+// Log appends entries to a JSON lines writer; it is safe for concurrent use
+type Log struct {
+	mu             sync.Mutex
+	writer         io.Writer
+	closer         io.Closer
+	includeContent bool
+}
+
+// New creates a log that writes to writer. Request and response text are only written
+// with includeContent; hashes always are.
+func New(writer io.Writer, includeContent bool) *Log {
+	return &Log{writer: writer, includeContent: includeContent}
+}
+
+// Open creates a log that appends to the file at path, creating it and its directory
+// when needed
+func Open(path string, includeContent bool) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	log := New(file, includeContent)
+	log.closer = file
+	return log, nil
+}
+
+// Record writes an entry, filling in the hashes of its request and response and
+// dropping their text unless the log includes content
+func (l *Log) Record(entry Entry) error {
+	entry.RequestSHA256 = Hash(entry.Request)
+	if entry.Response != "" {
+		entry.ResponseSHA256 = Hash(entry.Response)
+	}
+	if !l.includeContent {
+		entry.Request, entry.Response = "", ""
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file of a log created with Open
+func (l *Log) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Hash returns the hex SHA-256 of text, as recorded for requests and responses
+func Hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+)
+
+// Cache stores AI provider responses so identical requests are not paid for twice.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored under key; ok is false on a miss
+	Get(key string) (value []byte, ok bool, err error)
+	// Set stores value under key
+	Set(key string, value []byte) error
+}
+
+// Key returns the cache key of a request to model at endpoint: the hex SHA-256 of the
+// model name, the endpoint URL, the headers that change the response (not the API key)
+// and the request body, so a change of any of them never hits an old entry
+func Key(model, endpoint string, headers map[string]string, request []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(model))
+	hash.Write([]byte{0})
+	hash.Write([]byte(endpoint))
+	hash.Write([]byte{0})
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write([]byte(headers[name]))
+		hash.Write([]byte{0})
+	}
+	hash.Write(request)
+	return hex.EncodeToString(hash.Sum(nil))
+}
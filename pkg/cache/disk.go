@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Disk stores each entry as a file under a directory, sharded by the first two
+// characters of the key. Entries never expire; delete the directory to clear it.
+type Disk struct {
+	dir string
+}
+
+// NewDisk creates a disk cache in dir, creating the directory if needed
+func NewDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Disk{dir: dir}, nil
+}
+
+// Get reads the entry stored under key
+func (d *Disk) Get(key string) ([]byte, bool, error) {
+	value, err := os.ReadFile(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	return value, true, nil
+}
+
+// Set writes the entry through a temp file and a rename, so concurrent readers never
+// see a partial entry
+func (d *Disk) Set(key string, value []byte) error {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), ".entry-")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(value); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save cache entry: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of a key
+func (d *Disk) path(key string) string {
+	shard := key
+	if len(shard) > 2 {
+		shard = shard[:2]
+	}
+	return filepath.Join(d.dir, shard, key)
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/resp"
+)
+
+// RedisOptions configures a Redis cache
+type RedisOptions struct {
+	Addr      string        // host:port, default "localhost:6379"
+	Password  string        // Sent with AUTH when set
+	DB        int           // Selected with SELECT when not 0
+	KeyPrefix string        // Prepended to every key, default "pdfchunk:"
+	TTL       time.Duration // Entry expiry; 0 keeps entries forever
+	Timeout   time.Duration // Dial and command timeout, default 5s
+}
+
+// Redis stores entries in a Redis server, so several machines can share one cache.
+// It speaks the Redis protocol over a single connection that is redialled after errors.
+type Redis struct {
+	options RedisOptions
+	client  *resp.Client
+}
+
+// NewRedis creates a Redis cache and checks that the server is reachable
+func NewRedis(options RedisOptions) (*Redis, error) {
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "pdfchunk:"
+	}
+
+	r := &Redis{
+		options: options,
+		client:  resp.New(resp.Options{Addr: options.Addr, Password: options.Password, DB: options.DB, Timeout: options.Timeout}),
+	}
+	if _, err := r.client.Do("PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return r, nil
+}
+
+// Get reads the entry stored under key
+func (r *Redis) Get(key string) ([]byte, bool, error) {
+	reply, err := r.client.Do("GET", r.options.KeyPrefix+key)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, nil
+	}
+	return value, true, nil
+}
+
+// Set stores the entry, with the configured TTL
+func (r *Redis) Set(key string, value []byte) error {
+	args := []string{"SET", r.options.KeyPrefix + key, string(value)}
+	if r.options.TTL > 0 {
+		args = append(args, "PX", strconv.FormatInt(r.options.TTL.Milliseconds(), 10))
+	}
+	if _, err := r.client.Do(args...); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,49 @@
+package chunker
+
+import (
+	"errors"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/audit"
+)
+
+// openAuditLog opens AuditLogPath unless WithAuditLog set a log. A log that cannot be
+// opened fails every document, as requests must not go unrecorded.
+func (c *Chunker) openAuditLog() {
+	if c.auditLog != nil || c.config.AuditLogPath == "" {
+		return
+	}
+	log, err := audit.Open(c.config.AuditLogPath, c.config.AuditLogContent)
+	if err != nil {
+		c.configErr = err
+		c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+		return
+	}
+	c.auditLog, c.ownsAuditLog = log, true
+}
+
+// recordAI writes an AI request to the audit log, if any. A failed write fails the
+// document.
+func (c *Chunker) recordAI(entry audit.Entry, usage TokenUsage) error {
+	if c.auditLog == nil {
+		return nil
+	}
+	entry.Provider = c.aiProvider.GetName()
+	entry.PromptTokens = usage.PromptTokens
+	entry.CompletionTokens = usage.CompletionTokens
+	entry.TotalTokens = usage.TotalTokens
+	return c.auditLog.Record(entry)
+}
+
+// Close releases the audit log opened from AuditLogPath and stops the container started
+// from DockerImage; logs and containers set with WithAuditLog and WithContainer are left
+// to the caller
+func (c *Chunker) Close() error {
+	var errs []error
+	if c.ownsAuditLog {
+		errs = append(errs, c.auditLog.Close())
+	}
+	if c.ownsContainer {
+		errs = append(errs, c.container.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,377 @@
+package chunker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// File statuses in a batch run report
+const (
+	FileStatusOK        = "ok"
+	FileStatusLocal     = "local" // Chunked locally because the AI budget ran out
+	FileStatusFailed    = "failed"
+	FileStatusElsewhere = "elsewhere" // Processed or being processed by another instance, see WithCoordinator
+)
+
+// RunReportFilename is the run report written to OutputDir by batch runs that save files
+const RunReportFilename = "run_report.json"
+
+// ErrArchiveFiles is returned for archive input by the entry points returning a single
+// ChunkResult, such as ChunkInputWithUsage and ChunkReader, when any file inside the
+// archive fails; ChunkArchive reports every file in BatchResult.Files instead
+var ErrArchiveFiles = errors.New("archive files failed")
+
+// BatchResult represents the result of chunking multiple documents in one call
+type BatchResult struct {
+	Chunks     []ChunkData  `json:"chunks"`
+	TokenUsage TokenUsage   `json:"token_usage"`
+	Files      []FileResult `json:"files"`
+	StartedAt  time.Time    `json:"started_at"`
+	DurationMS int64        `json:"duration_ms"`
+	Stopped    bool         `json:"stopped,omitempty"` // Stop was called before every document was processed
+
+	root       string          // See batchRoot
+	checkpoint *checkpoint     // Documents finished so far, see openCheckpoint
+	retry      map[string]bool // Failed files processed again by RetryFailed, nil to process all
+}
+
+// FileResult records the outcome of a single file in a batch
+type FileResult struct {
+	Filename   string     `json:"filename"`
+	Status     string     `json:"status"` // FileStatusOK, FileStatusLocal, FileStatusFailed or FileStatusElsewhere
+	Pages      int        `json:"pages"`
+	OCRPages   int        `json:"ocr_pages"`
+	Engine     string     `json:"engine,omitempty"` // PDF extraction engine, see processor.DocumentReport.Engine
+	Chunks     int        `json:"chunks"`
+	TokenUsage TokenUsage `json:"token_usage"`
+	DurationMS int64      `json:"duration_ms"`
+	Error      string     `json:"error,omitempty"`
+	Stack      string     `json:"stack,omitempty"` // Stack of a panic recovered while processing the file, see processor.PanicError
+
+	BudgetExceeded bool `json:"budget_exceeded,omitempty"` // Switched to local chunking or aborted by the AI budget
+	Resumed        bool `json:"resumed,omitempty"`         // Processed by an earlier run that was stopped, see Resume
+}
+
+// ChunkDirectory processes every supported file under dir (archives included) and
+// returns the combined result. ChunkData.Filename holds each file's path relative to dir.
+// Output types that save files also write a run report to OutputDir/run_report.json,
+// the failed files to OutputDir/failures.json (see RetryFailed), and a checkpoint to
+// resume from when the run is stopped (see Stop and Resume).
+func (c *Chunker) ChunkDirectory(dir string, outputType OutputType) (*BatchResult, error) {
+	root := batchRoot(dir)
+	result := &BatchResult{StartedAt: time.Now(), root: root, checkpoint: c.openCheckpoint(root, outputType)}
+	if err := c.chunkTree(dir, "", outputType, true, nil, result); err != nil {
+		return nil, err
+	}
+	return result, c.finishBatch(result, outputType)
+}
+
+// ChunkArchive unpacks a .zip, .tar.gz or .tar archive (file path, []byte or io.Reader)
+// into a temp directory and processes every supported file inside.
+// ChunkData.Filename holds each file's path inside the archive.
+// Output types that save files also write a run report to OutputDir/run_report.json
+// and failures.json, and for archive paths a checkpoint, as ChunkDirectory does.
+func (c *Chunker) ChunkArchive(input interface{}, outputType OutputType) (*BatchResult, error) {
+	root := batchRoot(input)
+	result := &BatchResult{StartedAt: time.Now(), root: root, checkpoint: c.openCheckpoint(root, outputType)}
+	if err := c.chunkArchive(input, "", outputType, nil, result); err != nil {
+		return nil, err
+	}
+	return result, c.finishBatch(result, outputType)
+}
+
+// archiveChunkResult returns the chunks of an archive run as one ChunkResult, failing
+// with ErrArchiveFiles and the errors of the failed files when any file failed
+func archiveChunkResult(result *BatchResult) (*ChunkResult, error) {
+	var failed []string
+	for _, file := range result.Files {
+		if file.Status == FileStatusFailed {
+			failed = append(failed, file.Filename+": "+file.Error)
+		}
+	}
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("%w: %d of %d: %s", ErrArchiveFiles, len(failed), len(result.Files), strings.Join(failed, "; "))
+	}
+	return &ChunkResult{Chunks: result.Chunks, TokenUsage: result.TokenUsage}, nil
+}
+
+// finishBatch records the batch duration, flushes the sinks and, when files are written,
+// saves the run report and failures and removes the checkpoint of a complete run. Stopped runs
+// return ErrStopped.
+func (c *Chunker) finishBatch(result *BatchResult, outputType OutputType) error {
+	result.DurationMS = time.Since(result.StartedAt).Milliseconds()
+	if err := c.flushSinks(); err != nil {
+		return err
+	}
+	if outputType != OutputJSON {
+		if err := c.saveRunReport(result); err != nil {
+			return err
+		}
+		if err := c.saveFailures(result); err != nil {
+			return err
+		}
+	}
+	if err := result.checkpoint.finish(result.Stopped); err != nil {
+		return err
+	}
+	if result.Stopped {
+		return ErrStopped
+	}
+	return nil
+}
+
+// saveRunReport saves the run report, and the dedup report with Dedup, to OutputDir
+func (c *Chunker) saveRunReport(result *BatchResult) error {
+	if err := os.MkdirAll(c.config.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	reportFile, err := os.Create(filepath.Join(c.config.OutputDir, RunReportFilename))
+	if err != nil {
+		return fmt.Errorf("failed to create run report: %w", err)
+	}
+	defer reportFile.Close()
+
+	if err := c.saveDedupReport(); err != nil {
+		return err
+	}
+	return result.Report().WriteJSON(reportFile)
+}
+
+// chunkArchive unpacks an archive and processes its files with prefix prepended to their
+// names. A panic while unpacking it is returned as a processor.PanicError.
+func (c *Chunker) chunkArchive(input interface{}, prefix string, outputType OutputType, metadata map[string]any, result *BatchResult) (err error) {
+	defer processor.RecoverPanic(&err)
+	return c.withUnpackedArchive(input, func(dir string) error {
+		// Archives nested inside archives are not expanded
+		return c.chunkTree(dir, prefix, outputType, false, metadata, result)
+	})
+}
+
+// withUnpackedArchive unpacks an archive (file path, []byte or io.Reader) into a temp
+// directory, calls fn with it and removes it afterwards
+func (c *Chunker) withUnpackedArchive(input interface{}, fn func(dir string) error) error {
+	var data []byte
+	var err error
+
+	switch v := input.(type) {
+	case string:
+		data, err = os.ReadFile(v)
+	case []byte:
+		data = v
+	case io.Reader:
+		data, err = io.ReadAll(v)
+	default:
+		return fmt.Errorf("unsupported archive input: %T", input)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	tempDir, err := tempfile.MkdirTemp(c.config.TempDir, "archive-")
+	if err != nil {
+		return fmt.Errorf("failed to create archive temp directory: %w", err)
+	}
+	defer tempfile.Remove(tempDir)
+
+	if err := c.unpackArchive(data, tempDir); err != nil {
+		return fmt.Errorf("failed to unpack archive: %w", err)
+	}
+
+	return fn(tempDir)
+}
+
+// batchJob is a single file of a batch run
+type batchJob struct {
+	inputType InputType
+	path      string
+	filename  string
+}
+
+// chunkTree walks root and processes every supported file with Workers concurrent
+// workers, naming each by prefix plus its slash-separated path relative to root and
+// attaching metadata to every chunk. Results keep the walk order. In retry runs, only
+// the failed files are processed (see RetryFailed). Files whose output names collide take
+// them in walk order, see outputClaims.
+func (c *Chunker) chunkTree(root, prefix string, outputType OutputType, expandArchives bool, metadata map[string]any, result *BatchResult) error {
+	if c.outputClaims == nil {
+		batch := *c
+		batch.outputClaims = &outputClaims{owners: make(map[string]string)}
+		c = &batch
+	}
+	var jobs []batchJob
+	err := walkSupportedFiles(root, prefix, func(inputType InputType, path, filename string) {
+		if inputType == InputArchive && !expandArchives {
+			c.logger.Printf("Warning: skipping nested archive %s", filename)
+			return
+		}
+		if !result.retrying(filename) || (c.fileFilter != nil && !c.fileFilter(filename)) {
+			return
+		}
+		jobs = append(jobs, batchJob{inputType: inputType, path: path, filename: filename})
+	})
+	if err != nil {
+		return err
+	}
+
+	if !c.config.OutputHash {
+		// Without content hashes the names are known up front, so claim them before the
+		// workers race for them
+		for _, job := range jobs {
+			if job.inputType != InputArchive {
+				c.outputClaims.claim(utils.Slug(job.filename), job.filename, c.logger)
+			}
+		}
+	}
+
+	results := make([]BatchResult, len(jobs))
+	runParallel(c.config.Workers, len(jobs), func(i int) {
+		job := jobs[i]
+		results[i].checkpoint = result.checkpoint
+		results[i].retry = result.retry
+		if c.stopped.Load() {
+			results[i].Stopped = true
+			return
+		}
+		if job.inputType == InputArchive {
+			if result.retry[job.filename] {
+				results[i].retry = nil // The archive itself failed, so all of it is retried
+			}
+			if err := c.chunkArchive(job.path, job.filename+"/", outputType, metadata, &results[i]); err != nil {
+				results[i].Files = append(results[i].Files, FileResult{Filename: job.filename, Status: FileStatusFailed, Error: err.Error(), Stack: processor.PanicStack(err)})
+			}
+			return
+		}
+		fileResult, hash, finished := result.checkpoint.finished(job.path, job.filename)
+		if finished {
+			results[i].Files = append(results[i].Files, fileResult)
+			return
+		}
+		if !c.claimBatchFile(job.path, job.filename, &hash, &results[i]) {
+			return
+		}
+		c.chunkBatchFile(job.inputType, job.path, job.filename, outputType, metadata, &results[i])
+		c.finishBatchFile(hash, results[i].Files[len(results[i].Files)-1])
+		if err := result.checkpoint.record(hash, results[i].Files[len(results[i].Files)-1]); err != nil {
+			c.logger.Printf("Warning: failed to save checkpoint: %v", err)
+		}
+	})
+
+	for _, jobResult := range results {
+		result.merge(jobResult)
+	}
+	return nil
+}
+
+// runParallel calls fn for every index in [0, n) using up to workers goroutines
+func runParallel(workers, n int, fn func(i int)) {
+	workers = min(max(workers, 1), n)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer tempfile.CleanupOnPanic()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// merge appends the files and chunks of another result
+func (r *BatchResult) merge(other BatchResult) {
+	r.Stopped = r.Stopped || other.Stopped
+	r.Chunks = append(r.Chunks, other.Chunks...)
+	r.Files = append(r.Files, other.Files...)
+	r.TokenUsage.PromptTokens += other.TokenUsage.PromptTokens
+	r.TokenUsage.CompletionTokens += other.TokenUsage.CompletionTokens
+	r.TokenUsage.TotalTokens += other.TokenUsage.TotalTokens
+}
+
+// walkSupportedFiles calls fn for every supported file under root, naming each by
+// prefix plus its slash-separated path relative to root
+func walkSupportedFiles(root, prefix string, fn func(inputType InputType, path, filename string)) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip hidden files and archive metadata such as __MACOSX
+		name := entry.Name()
+		if path != root && (strings.HasPrefix(name, ".") || name == "__MACOSX") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		inputType, ok := InputTypeForFilename(name)
+		if !ok {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		fn(inputType, path, prefix+filepath.ToSlash(rel))
+		return nil
+	})
+}
+
+// chunkBatchFile processes a single file of a batch and records its outcome
+func (c *Chunker) chunkBatchFile(inputType InputType, path, filename string, outputType OutputType, metadata map[string]any, result *BatchResult) {
+	fileResult := FileResult{Filename: filename, Status: FileStatusOK}
+	started := time.Now()
+
+	chunkResult, err := c.chunkNamedInput(inputType, path, outputType, filename, metadata)
+	if err != nil {
+		c.logger.Printf("Error processing %s: %v", filename, err)
+		fileResult.Status = FileStatusFailed
+		fileResult.Error = err.Error()
+		fileResult.Stack = processor.PanicStack(err)
+		fileResult.BudgetExceeded = errors.Is(err, ErrBudgetExceeded)
+	} else {
+		if chunkResult.BudgetExceeded {
+			fileResult.Status = FileStatusLocal
+			fileResult.BudgetExceeded = true
+		}
+		fileResult.Pages = chunkResult.Pages
+		if chunkResult.Report != nil {
+			fileResult.OCRPages = chunkResult.Report.OCRPages
+			fileResult.Engine = chunkResult.Report.Engine
+		}
+		fileResult.Chunks = len(chunkResult.Chunks)
+		fileResult.TokenUsage = chunkResult.TokenUsage
+		result.Chunks = append(result.Chunks, chunkResult.Chunks...)
+		result.TokenUsage.PromptTokens += chunkResult.TokenUsage.PromptTokens
+		result.TokenUsage.CompletionTokens += chunkResult.TokenUsage.CompletionTokens
+		result.TokenUsage.TotalTokens += chunkResult.TokenUsage.TotalTokens
+	}
+
+	fileResult.DurationMS = time.Since(started).Milliseconds()
+	result.Files = append(result.Files, fileResult)
+}
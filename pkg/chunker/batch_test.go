@@ -0,0 +1,94 @@
+package chunker_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+)
+
+// TestArchiveFileFailure checks that the single-result entry points fail archive input
+// when a file inside fails, rather than returning the chunks of the others
+func TestArchiveFileFailure(t *testing.T) {
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	for name, content := range map[string]string{
+		"broken.pdf": "%PDF-1.4\nnot a PDF after all",
+		"notes.txt":  "The notes of the meeting.",
+	} {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	instance := chunker.NewChunker(chunker.WithConfig(chunkertest.Config(t)))
+	defer instance.Close()
+
+	_, err := instance.ChunkReader(bytes.NewReader(archive.Bytes()), "upload.zip", chunker.OutputJSON)
+	if !errors.Is(err, chunker.ErrArchiveFiles) {
+		t.Fatalf("ChunkReader error = %v, want ErrArchiveFiles", err)
+	}
+	if !strings.Contains(err.Error(), "broken.pdf") {
+		t.Errorf("error %q does not name broken.pdf", err)
+	}
+	_, err = instance.ChunkInputWithMetadata(chunker.InputArchive, archive.Bytes(), chunker.OutputJSON, map[string]any{"tenant": "a"})
+	if !errors.Is(err, chunker.ErrArchiveFiles) {
+		t.Errorf("ChunkInputWithMetadata error = %v, want ErrArchiveFiles", err)
+	}
+
+	result, err := instance.ChunkArchive(archive.Bytes(), chunker.OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]string{}
+	for _, file := range result.Files {
+		statuses[file.Filename] = file.Status
+	}
+	if statuses["broken.pdf"] != chunker.FileStatusFailed || statuses["notes.txt"] != chunker.FileStatusOK {
+		t.Errorf("ChunkArchive statuses = %v, want broken.pdf failed and notes.txt ok", statuses)
+	}
+}
+
+// TestChunkDirectoryFileFilter checks that ChunkDirectory skips the files rejected by
+// WithFileFilter
+func TestChunkDirectoryFileFilter(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"notes.txt":     "The notes of the meeting.",
+		"readme.md":     "# Read me",
+		"sub/draft.txt": "A draft in a subdirectory.",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	topLevelText := func(filename string) bool {
+		return !strings.Contains(filename, "/") && strings.HasSuffix(filename, ".txt")
+	}
+	instance := chunker.NewChunker(chunker.WithConfig(chunkertest.Config(t)), chunker.WithFileFilter(topLevelText))
+	defer instance.Close()
+
+	result, err := instance.ChunkDirectory(dir, chunker.OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 1 || result.Files[0].Filename != "notes.txt" {
+		t.Errorf("processed %+v, want only notes.txt", result.Files)
+	}
+}
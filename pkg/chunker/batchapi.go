@@ -0,0 +1,290 @@
+package chunker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/audit"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/providers"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// BatchAPIProvider is an AI provider that can run chunking requests as an asynchronous
+// batch, such as the OpenAI Batch API; *providers.ChatGPTProvider implements it
+type BatchAPIProvider interface {
+	AIProvider
+	SubmitBatch(requests []providers.BatchRequest) (string, error)
+	GetBatch(id string) (*providers.BatchStatus, error)
+	BatchResults(status *providers.BatchStatus) (map[string]*providers.ChunkResult, error)
+}
+
+// ErrBatchPending is returned by CollectBatchJob while the provider is still running the batch
+var ErrBatchPending = errors.New("batch job is still running")
+
+// BatchJob is the resumable handle of documents submitted to a provider batch. It holds
+// the extracted pages and AI slices of every document, so it can be saved with Save and
+// collected later, even by another process, without extracting the documents again.
+type BatchJob struct {
+	ID          string             `json:"id"` // Provider batch ID
+	Provider    string             `json:"provider"`
+	SubmittedAt time.Time          `json:"submitted_at"`
+	Documents   []BatchJobDocument `json:"documents"`
+}
+
+// BatchJobDocument is a single document of a batch job
+type BatchJobDocument struct {
+	Filename string           `json:"filename"`
+	Pages    []processor.Page `json:"pages,omitempty"`
+	Slices   []string         `json:"slices,omitempty"` // Text sent to the provider, one request each
+	OCRPages int              `json:"ocr_pages,omitempty"`
+	Error    string           `json:"error,omitempty"` // Extraction failure; the document is not submitted
+}
+
+// SubmitBatchJob extracts the files, directories and archives in paths like ChunkDirectory
+// and submits all their AI slices as one provider batch. Batches cost less but may take
+// up to a day; collect the results with CollectBatchJob.
+func (c *Chunker) SubmitBatchJob(paths ...string) (*BatchJob, error) {
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+	provider, ok := c.aiProvider.(BatchAPIProvider)
+	if !ok {
+		return nil, fmt.Errorf("AI provider does not support batch jobs")
+	}
+	if c.config.AIMode == config.AIModeStructure {
+		return nil, fmt.Errorf("batch jobs do not support the %s AI mode", config.AIModeStructure)
+	}
+	if c.config.Profile != config.ProfileGeneral {
+		return nil, fmt.Errorf("batch jobs do not support the %s profile", c.config.Profile)
+	}
+
+	job := &BatchJob{Provider: provider.GetName()}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input: %w", err)
+		}
+
+		if info.IsDir() {
+			if err := c.addBatchJobTree(job, path, "", true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		inputType, ok := InputTypeForFilename(path)
+		if !ok {
+			job.Documents = append(job.Documents, BatchJobDocument{Filename: filepath.Base(path), Error: "unsupported file type"})
+			continue
+		}
+		c.addBatchJobFile(job, inputType, path, filepath.Base(path), true)
+	}
+
+	var requests []providers.BatchRequest
+	for i, document := range job.Documents {
+		for j, slice := range document.Slices {
+			if strings.TrimSpace(slice) != "" {
+				requests = append(requests, providers.BatchRequest{CustomID: batchCustomID(i, j), Text: slice})
+			}
+		}
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no text to submit")
+	}
+
+	id, err := provider.SubmitBatch(requests)
+	if err != nil {
+		return nil, err
+	}
+	job.ID = id
+	job.SubmittedAt = time.Now()
+	return job, nil
+}
+
+// addBatchJobTree adds every supported file under root to the job
+func (c *Chunker) addBatchJobTree(job *BatchJob, root, prefix string, expandArchives bool) error {
+	return walkSupportedFiles(root, prefix, func(inputType InputType, path, filename string) {
+		c.addBatchJobFile(job, inputType, path, filename, expandArchives)
+	})
+}
+
+// addBatchJobFile extracts a file and adds it to the job, expanding archives one level deep
+func (c *Chunker) addBatchJobFile(job *BatchJob, inputType InputType, path, filename string, expandArchives bool) {
+	if inputType == InputArchive {
+		if !expandArchives {
+			c.logger.Printf("Warning: skipping nested archive %s", filename)
+			return
+		}
+		err := c.withUnpackedArchive(path, func(dir string) error {
+			return c.addBatchJobTree(job, dir, filename+"/", false)
+		})
+		if err != nil {
+			job.Documents = append(job.Documents, BatchJobDocument{Filename: filename, Error: err.Error()})
+		}
+		return
+	}
+
+	document := BatchJobDocument{Filename: filename}
+	pages, _, report, err := c.forDocument().extractPages(inputType, path)
+	switch {
+	case err != nil:
+		document.Error = err.Error()
+	case strings.TrimSpace(newPagedText(pages).text) == "":
+		document.Error = "input text is empty"
+	default:
+		document.Pages = pages
+		document.Slices = c.splitForAI(newPagedText(pages), inputType == InputPPTX)
+		if report != nil {
+			document.OCRPages = report.OCRPages
+		}
+	}
+	job.Documents = append(job.Documents, document)
+}
+
+// CollectBatchJob builds the chunks of a finished batch job and saves them as the output
+// type requires, like ChunkDirectory. It returns ErrBatchPending while the batch is still
+// running. Slices the provider failed to answer are chunked locally.
+func (c *Chunker) CollectBatchJob(job *BatchJob, outputType OutputType) (*BatchResult, error) {
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+	provider, ok := c.aiProvider.(BatchAPIProvider)
+	if !ok {
+		return nil, fmt.Errorf("AI provider does not support batch jobs")
+	}
+
+	status, err := provider.GetBatch(job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !status.Done() {
+		return nil, fmt.Errorf("%w: %s (%d of %d requests done)", ErrBatchPending, status.Status,
+			status.RequestCounts.Completed+status.RequestCounts.Failed, status.RequestCounts.Total)
+	}
+
+	responses, err := provider.BatchResults(status)
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) == 0 && status.Status != "completed" {
+		return nil, fmt.Errorf("batch %s %s without results", job.ID, status.Status)
+	}
+
+	result := &BatchResult{StartedAt: time.Now()}
+	for i, document := range job.Documents {
+		fileResult := FileResult{Filename: document.Filename, Status: FileStatusOK, Pages: len(document.Pages), OCRPages: document.OCRPages}
+		if document.Error != "" {
+			fileResult.Status = FileStatusFailed
+			fileResult.Error = document.Error
+			result.Files = append(result.Files, fileResult)
+			continue
+		}
+
+		chunks, tokenUsage, err := c.collectBatchDocument(job.ID, i, document, responses, outputType)
+		if err != nil {
+			c.logger.Printf("Error processing %s: %v", document.Filename, err)
+			fileResult.Status = FileStatusFailed
+			fileResult.Error = err.Error()
+		} else {
+			fileResult.Chunks = len(chunks)
+			fileResult.TokenUsage = tokenUsage
+			result.Chunks = append(result.Chunks, chunks...)
+			result.TokenUsage.PromptTokens += tokenUsage.PromptTokens
+			result.TokenUsage.CompletionTokens += tokenUsage.CompletionTokens
+			result.TokenUsage.TotalTokens += tokenUsage.TotalTokens
+		}
+		result.Files = append(result.Files, fileResult)
+	}
+
+	return result, c.finishBatch(result, outputType)
+}
+
+// collectBatchDocument builds and saves the chunks of one document from the batch responses
+func (c *Chunker) collectBatchDocument(batchID string, documentIndex int, document BatchJobDocument, responses map[string]*providers.ChunkResult, outputType OutputType) ([]ChunkData, TokenUsage, error) {
+	text := newPagedText(document.Pages)
+	spans := text.locate(document.Slices)
+
+	var chunks []ChunkData
+	var tokenUsage TokenUsage
+	for i, slice := range document.Slices {
+		if strings.TrimSpace(slice) == "" {
+			continue
+		}
+
+		response, ok := responses[batchCustomID(documentIndex, i)]
+		entry := audit.Entry{Time: time.Now(), Filename: document.Filename, Slice: i + 1, BatchID: batchID, Request: slice}
+		if !ok {
+			entry.Error = "no result in batch"
+			if err := c.recordAI(entry, TokenUsage{}); err != nil {
+				return nil, TokenUsage{}, err
+			}
+			// Fallback to local chunking
+			chunks = appendSections(chunks, c.createLocalIntelligentChunk(slice, text.pageRange(spans[i])), document.Filename, i+1, text, spans[i], nil)
+			continue
+		}
+
+		entry.Response = response.Text
+		if err := c.recordAI(entry, TokenUsage(response.TokenUsage)); err != nil {
+			return nil, TokenUsage{}, err
+		}
+		c.budget.record(TokenUsage(response.TokenUsage))
+		tokenUsage.PromptTokens += response.TokenUsage.PromptTokens
+		tokenUsage.CompletionTokens += response.TokenUsage.CompletionTokens
+		tokenUsage.TotalTokens += response.TokenUsage.TotalTokens
+
+		// Batch results cannot be retried, so rejected ones are chunked locally right away
+		answer := response.Text
+		validation := c.newValidation()
+		if validation != nil {
+			if validation.Failures = c.validateAI(slice, answer); len(validation.Failures) > 0 {
+				validation.Outcome = schema.ValidationFallback
+				answer = c.createLocalIntelligentChunk(slice, text.pageRange(spans[i]))
+			}
+		}
+		chunks = appendSections(chunks, answer, document.Filename, i+1, text, spans[i], validation)
+	}
+
+	result := &ChunkResult{Chunks: chunks, TokenUsage: tokenUsage, Pages: len(document.Pages)}
+	if err := c.saveDocument(result, text, document.Filename, outputType); err != nil {
+		return nil, TokenUsage{}, err
+	}
+	return chunks, tokenUsage, nil
+}
+
+// batchCustomID identifies a slice of a document in the provider batch
+func batchCustomID(documentIndex, sliceIndex int) string {
+	return fmt.Sprintf("doc-%d-slice-%d", documentIndex, sliceIndex)
+}
+
+// Save writes the job handle as JSON to path
+func (j *BatchJob) Save(path string) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch job: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save batch job: %w", err)
+	}
+	return nil
+}
+
+// LoadBatchJob reads a job handle written by Save
+func LoadBatchJob(path string) (*BatchJob, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch job: %w", err)
+	}
+
+	var job BatchJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to parse batch job: %w", err)
+	}
+	return &job, nil
+}
@@ -0,0 +1,86 @@
+package chunker
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+// ErrBudgetExceeded is returned for documents started after the AI budget ran out
+// when AbortOnBudget is set
+var ErrBudgetExceeded = errors.New("AI budget exceeded")
+
+// budget tracks the AI tokens and cost spent by a chunker against its configured limits
+type budget struct {
+	mu                sync.Mutex
+	maxTokens         int
+	maxCost           float64
+	promptPrice       float64
+	completionPrice   float64
+	tokens            int
+	cost              float64
+	exceededAnnounced bool
+}
+
+// newBudget creates a budget from the config limits; zero limits are unlimited
+func newBudget(config config.ChunkerConfig) *budget {
+	return &budget{
+		maxTokens:       config.MaxTotalTokens,
+		maxCost:         config.MaxCostUSD,
+		promptPrice:     config.AIPromptPrice,
+		completionPrice: config.AICompletionPrice,
+	}
+}
+
+// record adds the usage of one AI call
+func (b *budget) record(usage TokenUsage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += usage.TotalTokens
+	b.cost += float64(usage.PromptTokens)/1e6*b.promptPrice + float64(usage.CompletionTokens)/1e6*b.completionPrice
+}
+
+// exceeded reports whether either limit has been reached
+func (b *budget) exceeded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return (b.maxTokens > 0 && b.tokens >= b.maxTokens) || (b.maxCost > 0 && b.cost >= b.maxCost)
+}
+
+// useAI decides whether a document is chunked with the AI provider. Once the budget is
+// exceeded, documents are chunked locally (budgetExceeded is true) or rejected with
+// ErrBudgetExceeded when AbortOnBudget is set. Documents already in progress finish with AI.
+func (c *Chunker) useAI() (useAI, budgetExceeded bool, err error) {
+	if c.aiProvider == nil {
+		return false, false, nil
+	}
+	if !c.budget.exceeded() {
+		return true, false, nil
+	}
+
+	if c.config.AbortOnBudget {
+		return false, true, ErrBudgetExceeded
+	}
+
+	c.budget.mu.Lock()
+	if !c.budget.exceededAnnounced {
+		c.budget.exceededAnnounced = true
+		c.logger.Printf("Warning: AI budget exceeded, chunking remaining documents locally")
+	}
+	c.budget.mu.Unlock()
+	return false, true, nil
+}
+
+// estimateUsage estimates the usage of an AI call for providers that do not report it
+func estimateUsage(prompt, completion string) TokenUsage {
+	promptTokens := len(prompt)/charsPerToken + promptOverheadTokens
+	completionTokens := len(completion) / charsPerToken
+	return TokenUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
@@ -0,0 +1,188 @@
+package chunker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// CheckpointFilename is the checkpoint written to OutputDir by batch runs that save files
+const CheckpointFilename = "checkpoint.json"
+
+// ErrStopped is returned, with the documents processed so far, by batch runs stopped
+// with Stop before every document was processed
+var ErrStopped = errors.New("batch run stopped before every document was processed")
+
+// Stop makes the batch runs of the chunker finish the documents in progress and start no
+// others; they then flush their sinks, save their run report and checkpoint, and return
+// ErrStopped. It does not wait for them.
+func (c *Chunker) Stop() {
+	c.stopped.Store(true)
+}
+
+// StopOnSignal calls Stop when the process receives SIGINT or SIGTERM. A second signal
+// removes temp files and exits with status 130 at once, like tempfile.CleanupOnSignal.
+// Call the returned function to stop handling the signals.
+func (c *Chunker) StopOnSignal() (stop func()) {
+	signals := make(chan os.Signal, 2)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			c.logger.Printf("Stopping after the documents in progress; interrupt again to quit now")
+			c.Stop()
+		case <-done:
+			return
+		}
+		select {
+		case <-signals:
+			tempfile.Cleanup()
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// checkpoint records the documents a batch run has finished in OutputDir, so a run that
+// was stopped or killed resumes where it left off
+type checkpoint struct {
+	mu    sync.Mutex
+	path  string
+	Root  string                    `json:"root"`  // Absolute path of the directory or archive of the run
+	Files map[string]checkpointFile `json:"files"` // By filename
+}
+
+// checkpointFile is a finished document of a checkpoint
+type checkpointFile struct {
+	SHA256 string     `json:"sha256"` // Of the file, so changed files are processed again
+	Result FileResult `json:"result"`
+}
+
+// openCheckpoint returns the checkpoint of a batch run over root, resuming the saved one
+// of an earlier run over the same root with Resume. Runs that do not save files, or
+// whose input is not a path (see batchRoot), have none.
+func (c *Chunker) openCheckpoint(root string, outputType OutputType) *checkpoint {
+	if root == "" || outputType == OutputJSON {
+		return nil
+	}
+	cp := &checkpoint{
+		path:  filepath.Join(c.config.OutputDir, CheckpointFilename),
+		Root:  root,
+		Files: make(map[string]checkpointFile),
+	}
+	if !c.config.Resume {
+		return cp
+	}
+
+	data, err := os.ReadFile(cp.path)
+	if err != nil {
+		return cp
+	}
+	var saved checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		c.logger.Printf("Warning: ignoring unreadable checkpoint %s: %v", cp.path, err)
+		return cp
+	}
+	if saved.Root == root && saved.Files != nil {
+		c.logger.Printf("Resuming from %s: %d documents already processed", cp.path, len(saved.Files))
+		cp.Files = saved.Files
+	}
+	return cp
+}
+
+// finished returns the result of a file processed by the run being resumed, unless it
+// changed since. It also returns the hash of the file for record.
+func (cp *checkpoint) finished(path, filename string) (FileResult, string, bool) {
+	if cp == nil {
+		return FileResult{}, "", false
+	}
+	hash, err := fileSHA256(path)
+	if err != nil {
+		return FileResult{}, "", false
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	file, ok := cp.Files[filename]
+	if !ok || file.SHA256 != hash {
+		return FileResult{}, hash, false
+	}
+	result := file.Result
+	result.Resumed = true
+	return result, hash, true
+}
+
+// record adds a processed file to the checkpoint and saves it. Failed files are left
+// out, so a resumed run tries them again.
+func (cp *checkpoint) record(hash string, result FileResult) error {
+	if cp == nil || hash == "" || result.Status == FileStatusFailed {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.Files[result.Filename] = checkpointFile{SHA256: hash, Result: result}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(cp.path, data, 0644)
+}
+
+// finish removes the checkpoint of a run that processed every document; stopped runs
+// keep it to resume from
+func (cp *checkpoint) finish(stopped bool) error {
+	if cp == nil || stopped {
+		return nil
+	}
+	if err := os.Remove(cp.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// fileSHA256 returns the SHA-256 of a file's content
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// flushSinks flushes the sinks that buffer chunks
+func (c *Chunker) flushSinks() error {
+	for _, sink := range c.sinks {
+		if flushSink, ok := sink.(FlushSink); ok {
+			if err := flushSink.Flush(); err != nil {
+				return fmt.Errorf("failed to flush sink %s: %w", sink.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
@@ -1,25 +1,39 @@
 package chunker
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
 
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/audit"
 	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/container"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/dedup"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/invoice"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/linefilter"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ocr"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ocrfix"
 	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
 	"github.com/firdasafridi/pdf-chunk-extractor/pkg/providers"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ratelimit"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/redact"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
 	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
 )
 
-// ChunkData represents a structured chunk for vector database embedding
-type ChunkData struct {
-	Filename   string `json:"filename"`
-	ChunkIndex int    `json:"chunk_index"`
-	PageRange  string `json:"page_range"`
-	Text       string `json:"text"`
-}
+// ChunkData represents a structured chunk for vector database embedding. It is defined in
+// the schema package so consumers can decode chunk files without importing the chunker.
+type ChunkData = schema.Chunk
 
 // TokenUsage represents token usage information
 type TokenUsage struct {
@@ -30,8 +44,23 @@ type TokenUsage struct {
 
 // ChunkResult represents the result of chunking with token usage
 type ChunkResult struct {
-	Chunks     []ChunkData `json:"chunks"`
-	TokenUsage TokenUsage  `json:"token_usage"`
+	Chunks     []ChunkData               `json:"chunks"`
+	TokenUsage TokenUsage                `json:"token_usage"`
+	Report     *processor.DocumentReport `json:"report,omitempty"` // Extraction report for PDF and office inputs
+	Pages      int                       `json:"pages"`
+
+	BudgetExceeded bool              `json:"budget_exceeded,omitempty"` // Chunked locally because the AI budget ran out
+	Redactions     *RedactionReport  `json:"redactions,omitempty"`      // Set with RedactPII
+	Outline        []*schema.Heading `json:"outline,omitempty"`         // Numbered headings of the document, nested by level
+	Invoice        *invoice.Invoice  `json:"invoice,omitempty"`         // Key fields of the document, set in the invoice profile
+	TOC            *schema.TOC       `json:"toc,omitempty"`             // Table of contents with the chunks of every section, set with TableOfContents
+	Elements       []schema.Element  `json:"elements,omitempty"`        // The document as unstructured.io-style elements, set with Elements
+	Duplicates     int               `json:"duplicates,omitempty"`      // Chunks flagged or dropped as near-duplicates of earlier documents with Dedup
+	LowQuality     int               `json:"low_quality,omitempty"`     // Chunks left out for scoring below MinQualityScore
+	PageHashes     []string          `json:"page_hashes,omitempty"`     // Hash of the extracted text of every page, in order, for ChunkUpdate
+
+	sourcePDF   string        // Path of the PDF input, for SplitPDF and the coordinates of Elements
+	dedupChecks []dedup.Check // Near-duplicate checks of the chunks, committed once they are saved
 }
 
 // InputType represents the type of input data
@@ -39,10 +68,41 @@ type InputType int
 
 const (
 	InputPDF InputType = iota
+	// InputTXT treats a string as a file path when a file with that name exists and as
+	// content otherwise; use ChunkFile or ChunkString when that ambiguity matters
 	InputTXT
 	InputString
+	InputPPTX    // PowerPoint: slide text and notes, chunked by slide groups
+	InputXLSX    // Excel: sheets serialized to Markdown tables
+	InputEmail   // Email (.eml/.msg): headers, body and attachments
+	InputArchive // Archive (.zip/.tar.gz/.tar): every supported file inside is processed
 )
 
+// String returns the short name of the input type
+func (t InputType) String() string {
+	switch t {
+	case InputPDF:
+		return "pdf"
+	case InputTXT:
+		return "txt"
+	case InputString:
+		return "string"
+	case InputPPTX:
+		return "pptx"
+	case InputXLSX:
+		return "xlsx"
+	case InputEmail:
+		return "email"
+	case InputArchive:
+		return "archive"
+	default:
+		return fmt.Sprintf("InputType(%d)", int(t))
+	}
+}
+
+// maxEmailDepth limits how deeply attached emails are expanded
+const maxEmailDepth = 3
+
 // OutputType represents the type of output format
 type OutputType int
 
@@ -50,6 +110,7 @@ const (
 	OutputJSON OutputType = iota
 	OutputFile
 	OutputBoth
+	OutputRawText // Like OutputBoth, and also writes the consolidated extracted text to OutputDir/<name>.txt
 )
 
 // AIProvider represents different AI providers for chunking
@@ -66,140 +127,547 @@ type AIProviderWithUsage interface {
 
 // Chunker is the main library interface
 type Chunker struct {
-	config        config.ChunkerConfig
-	aiProvider    AIProvider
-	pdfProcessor  *processor.PDFProcessor
-	textProcessor *utils.TextProcessor
+	config         config.ChunkerConfig
+	aiProvider     AIProvider
+	embedder       EmbeddingProvider
+	validators     []Validator // Checks of AI outputs, see ValidateAI and WithValidators
+	strategy       Strategy
+	tokenizer      Tokenizer          // Set with WithTokenizer
+	chunkTemplate  *template.Template // Set with WithChunkTemplate or ChunkTemplatePath
+	sinks          []Sink
+	logger         Logger
+	ocrEngine      ocr.Engine
+	container      *container.Container // Set with WithContainer or DockerImage
+	ownsContainer  bool                 // container was started from DockerImage and is closed by Close
+	coordinator    Coordinator          // Set with WithCoordinator
+	fileFilter     func(string) bool    // Set with WithFileFilter
+	pdfFallbacks   []processor.TextExtractor
+	pdfRepairers   []processor.Repairer
+	pdfSplitters   []processor.PageSplitter // Set with WithPDFSplitters
+	limiter        *ratelimit.Limiter
+	budget         *budget
+	deadline       time.Time       // MaxProcessingTime deadline of the current document, see forDocument
+	ctx            context.Context // Set with WithContext
+	progress       ProgressFunc    // Set with WithProgress
+	pageProgress   *pageProgress   // Pages of the current document reported by the PDF processor, see forDocument
+	pdfProcessor   *processor.PDFProcessor
+	pptxProcessor  *processor.PPTXProcessor
+	xlsxProcessor  *processor.XLSXProcessor
+	emailProcessor *processor.EmailProcessor
+	textProcessor  *utils.TextProcessor
+	redactor       *redact.Redactor   // Set with RedactPII
+	lineFilter     *linefilter.Filter // Set with FilterNoiseLines or NoiseLinePatterns
+	ocrDictionary  ocrfix.Dictionary  // Set with OCRCorrection
+	dedupIndex     *dedup.Index       // Set with Dedup; shared by every document of the chunker
+	auditLog       *audit.Log
+	ownsAuditLog   bool          // auditLog was opened from AuditLogPath and is closed by Close
+	stopped        *atomic.Bool  // Set by Stop; shared by the copies of forDocument
+	outputClaims   *outputClaims // Output names taken in the current batch run, see chunkTree
+	configErr      error         // Set when the configuration cannot be applied, see applyLocalOnly, checkAIMode, checkProfile, checkSizeUnit, checkChunkFileFormat, checkImageFormats, checkSplitPDF, setupChunkTemplate, setupOCRCorrection, setupLineFilter, setupDedup and openAuditLog; every document fails with it
 }
 
-// NewChunker creates a new chunker instance
-func NewChunker(config config.ChunkerConfig, aiProvider AIProvider) *Chunker {
-	return &Chunker{
-		config:        config,
-		aiProvider:    aiProvider,
-		pdfProcessor:  processor.NewPDFProcessor(config),
-		textProcessor: utils.NewTextProcessor(config.MaxChunkSize, config.LocalChunkSize),
+// NewChunker creates a new chunker instance. Without options it uses DefaultConfig,
+// local chunking and Tesseract OCR. With LocalOnly, remote AI providers and OCR engines
+// are dropped here and every document fails with ErrRemoteDisabled.
+func NewChunker(opts ...Option) *Chunker {
+	c := &Chunker{
+		config:  config.DefaultConfig(),
+		logger:  log.Default(),
+		stopped: new(atomic.Bool),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.applyLocalOnly()
+	c.checkAIMode()
+	c.checkProfile()
+	c.checkSizeUnit()
+	c.checkChunkFileFormat()
+	c.checkImageFormats()
+	c.checkSplitPDF()
+	c.setupChunkTemplate()
+	c.setupOCRCorrection()
+	c.setupLineFilter()
+	c.setupDedup()
+	c.openAuditLog()
+	c.budget = newBudget(c.config)
+	if c.validators == nil && c.config.ValidateAI {
+		c.validators = DefaultValidators(c.config)
+	} else if c.validators == nil && c.config.FidelityThreshold > 0 {
+		c.validators = []Validator{FidelityValidator(c.config.FidelityThreshold)}
+	}
+	c.sweepTempFiles()
+	if c.limiter == nil && (c.config.AIRequestsPerMinute > 0 || c.config.AITokensPerMinute > 0) {
+		c.limiter = ratelimit.New(c.config.AIRequestsPerMinute, c.config.AITokensPerMinute)
+	}
+
+	c.pdfProcessor = processor.NewPDFProcessor(c.config).WithLogger(c.logger)
+	if c.container == nil && c.config.DockerImage != "" {
+		c.container = container.New(c.config.DockerImage, tempfile.Root(c.config.TempDir))
+		c.ownsContainer = true
+	}
+	if c.container != nil {
+		c.pdfProcessor = c.pdfProcessor.WithContainer(c.container)
+	}
+	if c.ocrEngine != nil {
+		c.pdfProcessor = c.pdfProcessor.WithOCREngine(c.ocrEngine)
 	}
+	if c.pdfFallbacks != nil {
+		c.pdfProcessor = c.pdfProcessor.WithFallbackExtractors(c.pdfFallbacks...)
+	}
+	if c.pdfRepairers != nil {
+		c.pdfProcessor = c.pdfProcessor.WithRepairers(c.pdfRepairers...)
+	}
+	c.pptxProcessor = processor.NewPPTXProcessor(c.config)
+	c.xlsxProcessor = processor.NewXLSXProcessor(c.config)
+	c.emailProcessor = processor.NewEmailProcessor(c.config)
+	c.textProcessor = utils.NewTextProcessor(c.config.MaxChunkSize, c.config.LocalChunkSize).WithSizeUnit(c.config.SizeUnit)
+	if c.config.Summarize {
+		c.textProcessor = c.textProcessor.WithSummary(c.config.SummarySentences)
+	}
+	if c.tokenizer != nil {
+		c.textProcessor = c.textProcessor.WithTokenizer(c.tokenizer)
+	}
+	if c.chunkTemplate != nil {
+		c.textProcessor = c.textProcessor.WithTemplate(c.chunkTemplate)
+	}
+	if c.config.PlainText {
+		c.textProcessor = c.textProcessor.WithPlainText()
+	}
+	if c.config.RedactPII {
+		c.redactor = redact.New(c.config.RedactKinds...)
+	}
+	return c
+}
+
+// sweepTempFiles removes the temp files of crashed runs from TempDir, once per process
+func (c *Chunker) sweepTempFiles() {
+	if c.config.TempMaxAge <= 0 {
+		return
+	}
+	removed, err := tempfile.SweepOnce(c.config.TempDir, c.config.TempMaxAge)
+	if err != nil {
+		c.logger.Printf("Warning: failed to sweep temp directory: %v", err)
+	} else if removed > 0 {
+		c.logger.Printf("Removed %d stale temp files from %s", removed, tempfile.Root(c.config.TempDir))
+	}
+}
+
+// NewChunkerWithConfig creates a new chunker instance from a config and an optional AI provider.
+//
+// Deprecated: use NewChunker(WithConfig(config), WithProvider(aiProvider)).
+func NewChunkerWithConfig(config config.ChunkerConfig, aiProvider AIProvider) *Chunker {
+	return NewChunker(WithConfig(config), WithProvider(aiProvider))
 }
 
-// ChunkInput processes input data and returns chunks based on output type
+// ChunkInput processes input data like ChunkInputWithUsage and returns its chunks
 func (c *Chunker) ChunkInput(inputType InputType, input interface{}, outputType OutputType) ([]ChunkData, error) {
-	var text string
-	var filename string
+	result, err := c.ChunkInputWithUsage(inputType, input, outputType)
+	if err != nil {
+		return nil, err
+	}
+	return result.Chunks, nil
+}
 
-	// Process input based on type
-	switch inputType {
-	case InputPDF:
-		text, filename = c.processPDFInput(input)
-	case InputTXT:
-		text, filename = c.processTXTInput(input)
-	case InputString:
-		text, filename = c.processStringInput(input)
-	default:
-		return nil, fmt.Errorf("unsupported input type: %v", inputType)
+// ChunkInputWithUsage processes input data and returns chunks with token usage information.
+// Archive input fails with ErrArchiveFiles when any file inside it fails.
+func (c *Chunker) ChunkInputWithUsage(inputType InputType, input interface{}, outputType OutputType) (*ChunkResult, error) {
+	if inputType == InputArchive {
+		result, err := c.ChunkArchive(input, outputType)
+		if err != nil {
+			return nil, err
+		}
+		return archiveChunkResult(result)
+	}
+
+	return c.chunkNamedInput(inputType, input, outputType, "", nil)
+}
+
+// ChunkInputWithMetadata processes input data like ChunkInputWithUsage and attaches
+// metadata (e.g. tenant ID, source system, document category) to every chunk
+func (c *Chunker) ChunkInputWithMetadata(inputType InputType, input interface{}, outputType OutputType, metadata map[string]any) (*ChunkResult, error) {
+	if inputType == InputArchive {
+		result := &BatchResult{}
+		if err := c.chunkArchive(input, "", outputType, metadata, result); err != nil {
+			return nil, err
+		}
+		return archiveChunkResult(result)
+	}
+
+	return c.chunkNamedInput(inputType, input, outputType, "", metadata)
+}
+
+// chunkNamedInput processes input data like ChunkInputWithUsage, using name as the
+// document filename when it is not empty and attaching metadata to every chunk. A
+// panic while processing the document is returned as a processor.PanicError.
+func (c *Chunker) chunkNamedInput(inputType InputType, input interface{}, outputType OutputType, name string, metadata map[string]any) (_ *ChunkResult, err error) {
+	defer processor.RecoverPanic(&err)
+	c = c.forDocument()
+	input, sourcePDF, cleanup, err := c.splitSource(inputType, input)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	pages, filename, report, err := c.extractPages(inputType, input)
+	if err != nil {
+		return nil, err
 	}
 
-	if strings.TrimSpace(text) == "" {
+	document := newPagedText(pages)
+	if strings.TrimSpace(document.text) == "" {
 		return nil, fmt.Errorf("input text is empty")
 	}
 
-	// Create chunks
-	chunks, err := c.createChunks(text, filename)
+	if name != "" {
+		filename = name
+	}
+
+	// Create chunks with usage tracking
+	useAI, budgetExceeded, err := c.useAI()
+	if err != nil {
+		return nil, err
+	}
+	chunks, tokenUsage, err := c.createChunksWithUsage(document, filename, inputType == InputPPTX, useAI)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create chunks: %w", err)
 	}
+	attachMetadata(chunks, metadata)
+	result := &ChunkResult{Chunks: chunks, TokenUsage: tokenUsage, Report: report, Pages: len(pages), BudgetExceeded: budgetExceeded, PageHashes: pageHashes(pages), sourcePDF: sourcePDF}
+	if result.Invoice, err = c.extractInvoice(document, filename, useAI, &result.TokenUsage); err != nil {
+		return nil, err
+	}
+	if err := c.saveDocument(result, document, filename, outputType); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// saveDocument names a document's output (see outputName), writes its chunks to the
+// sinks and saves them as the output type requires
+func (c *Chunker) saveDocument(result *ChunkResult, document pagedText, filename string, outputType OutputType) error {
+	name, err := c.outputName(filename, document, outputType)
+	if err != nil {
+		return err
+	}
+	if err := c.finishChunks(result, name); err != nil {
+		return err
+	}
+	// Slices whose AI request was cancelled were chunked locally, so check before anything is written
+	if err := c.checkContext(); err != nil {
+		return err
+	}
+	c.describeDocument(result, document, filename, name)
+	c.reportChunks(result)
+	if err := c.writeSinks(result.Chunks); err != nil {
+		return err
+	}
+	if err := c.saveOutput(result, document, filename, name, outputType); err != nil {
+		return err
+	}
+	c.commitDedup(result.dedupChecks)
+	return nil
+}
+
+// finishChunks completes the chunks of a result one by one: metadata, page images,
+// quality scores, near-duplicate checks, summaries and embeddings
+func (c *Chunker) finishChunks(result *ChunkResult, name string) error {
+	for i := range result.Chunks {
+		result.Chunks[i].Slug = name
+	}
+	c.completeChunks(result.Chunks)
+	attachPageImages(result.Chunks, result.Report)
 
-	// Handle output based on type
+	var lowQuality, duplicates int
+	var checks []dedup.Check
+	result.Chunks, lowQuality = c.scoreChunks(result.Chunks)
+	result.Chunks, checks, duplicates = c.dedupChunks(result.Chunks)
+	result.dedupChecks = append(result.dedupChecks, checks...)
+	result.LowQuality += lowQuality
+	result.Duplicates += duplicates
+	c.summarizeChunks(result.Chunks)
+	return c.embedChunks(result.Chunks)
+}
+
+// describeDocument sets what a result says about the document as a whole, from all of
+// its finished chunks: the redaction report, outline, table of contents and elements,
+// and the split PDFs of SplitPDF
+func (c *Chunker) describeDocument(result *ChunkResult, document pagedText, filename, name string) {
+	if c.redactor != nil {
+		result.Redactions = newRedactionReport(result.Chunks)
+	}
+	result.Outline = c.documentOutline(document, result.Chunks)
+	if c.config.TableOfContents {
+		result.TOC = c.documentTOC(document, result.Report, result.Chunks, filename, name)
+	}
+	if c.config.Elements {
+		result.Elements = c.documentElements(document, filename, result.sourcePDF)
+	}
+	c.splitPDF(result, document, filename, name)
+}
+
+// saveOutput saves a document's chunks and manifest, and for OutputRawText its full
+// text, under the output name as the output type requires
+func (c *Chunker) saveOutput(result *ChunkResult, document pagedText, filename, name string, outputType OutputType) error {
+	manifest := c.newManifest(result, filename, name)
 	switch outputType {
 	case OutputJSON:
-		return chunks, nil
-	case OutputFile:
-		return chunks, c.saveChunksToFiles(chunks, filename)
-	case OutputBoth:
-		if err := c.saveChunksToFiles(chunks, filename); err != nil {
-			return nil, fmt.Errorf("failed to save chunks to files: %w", err)
+		return nil
+	case OutputFile, OutputBoth:
+		if err := c.saveChunksToFiles(result.Chunks, name, manifest); err != nil {
+			return fmt.Errorf("failed to save chunks to files: %w", err)
+		}
+		return nil
+	case OutputRawText:
+		// The raw text goes first, so the manifest is only written once everything is saved
+		if err := c.saveRawText(document.text, name); err != nil {
+			return err
 		}
-		return chunks, nil
+		manifest.RawTextFile = filepath.ToSlash(c.rawTextPath(name))
+		if err := c.saveChunksToFiles(result.Chunks, name, manifest); err != nil {
+			return fmt.Errorf("failed to save chunks to files: %w", err)
+		}
+		return nil
 	default:
-		return nil, fmt.Errorf("unsupported output type: %v", outputType)
+		return fmt.Errorf("unsupported output type: %v", outputType)
 	}
 }
 
-// ChunkInputWithUsage processes input data and returns chunks with token usage information
-func (c *Chunker) ChunkInputWithUsage(inputType InputType, input interface{}, outputType OutputType) (*ChunkResult, error) {
-	var text string
-	var filename string
+// extractPages extracts the pages of a single-document input, enforcing MaxFileSizeMB
+// and MaxPages, repairs right-to-left text, corrects OCR errors, drops noise lines and
+// masks personal data with RedactPII
+func (c *Chunker) extractPages(inputType InputType, input interface{}) ([]processor.Page, string, *processor.DocumentReport, error) {
+	if c.configErr != nil {
+		return nil, "", nil, c.configErr
+	}
+	input, err := c.limitInput(inputType, input)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	pages, filename, report, err := c.extractInput(inputType, input)
+	if err != nil {
+		return nil, filename, nil, err
+	}
+	if err := processor.CheckPages(len(pages), c.config.MaxPages); err != nil {
+		return nil, filename, nil, err
+	}
+	for i := range pages {
+		pages[i] = c.normalizePage(pages[i])
+	}
+	if corrections := c.correctPages(pages); corrections > 0 && report != nil {
+		report.OCRCorrections = corrections
+	}
+	for i := range pages {
+		pages[i] = c.redactPage(c.filterPage(pages[i]))
+	}
+	c.reportPages(len(pages))
+	return pages, filename, report, nil
+}
 
-	// Process input based on type
+// extractInput extracts the pages of a single-document input by type
+func (c *Chunker) extractInput(inputType InputType, input interface{}) ([]processor.Page, string, *processor.DocumentReport, error) {
 	switch inputType {
 	case InputPDF:
-		text, filename = c.processPDFInput(input)
+		document, filename, err := c.processPDFDocument(input)
+		if err != nil {
+			return nil, filename, nil, err
+		}
+		return document.Pages, filename, &document.Report, nil
+	case InputPPTX, InputXLSX:
+		document, filename, err := c.processOfficeDocument(inputType, input)
+		if err != nil {
+			return nil, filename, nil, err
+		}
+		return document.Pages, filename, &document.Report, nil
+	case InputEmail:
+		pages, filename, err := c.processEmailInput(input, 0)
+		return pages, filename, nil, err
 	case InputTXT:
-		text, filename = c.processTXTInput(input)
+		text, filename := c.processTXTInput(input)
+		return c.textPages(text), filename, nil, nil
 	case InputString:
-		text, filename = c.processStringInput(input)
+		text, filename := c.processStringInput(input)
+		return c.textPages(text), filename, nil, nil
+	case InputArchive:
+		return nil, "", nil, fmt.Errorf("input type %v holds multiple documents; use ChunkArchive", inputType)
 	default:
-		return nil, fmt.Errorf("unsupported input type: %v", inputType)
+		return nil, "", nil, fmt.Errorf("unsupported input type: %v", inputType)
 	}
+}
 
-	if strings.TrimSpace(text) == "" {
-		return nil, fmt.Errorf("input text is empty")
+// processPDFDocument extracts a PDF input (file path or binary data) page by page
+func (c *Chunker) processPDFDocument(input interface{}) (*processor.Document, string, error) {
+	var document *processor.Document
+	var err error
+	filename := "input.pdf"
+	if path, ok := input.(string); ok {
+		filename = filepath.Base(path)
 	}
 
-	// Create chunks with usage tracking
-	chunks, tokenUsage, err := c.createChunksWithUsage(text, filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create chunks: %w", err)
+	pdfProcessor := c.pdfProcessor
+	if c.config.SearchablePDF {
+		searchablePath := filepath.Join(c.config.OutputDir, filepath.FromSlash(utils.Slug(filename))+".searchable.pdf")
+		pdfProcessor = pdfProcessor.WithOptions(processor.ExtractOptions{SearchablePDFPath: searchablePath})
+	}
+	if c.config.PageImages {
+		imageDir := filepath.Join(c.config.PageImageDir, filepath.FromSlash(utils.Slug(filename)))
+		pdfProcessor = pdfProcessor.WithOptions(processor.ExtractOptions{PageImageDir: imageDir})
+	}
+	if c.config.Thumbnail {
+		pdfProcessor = pdfProcessor.WithOptions(processor.ExtractOptions{Thumbnail: true})
 	}
 
-	// Handle output based on type
-	switch outputType {
-	case OutputJSON:
-		return &ChunkResult{Chunks: chunks, TokenUsage: tokenUsage}, nil
-	case OutputFile:
-		if err := c.saveChunksToFiles(chunks, filename); err != nil {
-			return nil, fmt.Errorf("failed to save chunks to files: %w", err)
-		}
-		return &ChunkResult{Chunks: chunks, TokenUsage: tokenUsage}, nil
-	case OutputBoth:
-		if err := c.saveChunksToFiles(chunks, filename); err != nil {
-			return nil, fmt.Errorf("failed to save chunks to files: %w", err)
-		}
-		return &ChunkResult{Chunks: chunks, TokenUsage: tokenUsage}, nil
+	switch v := input.(type) {
+	case string:
+		// File path
+		document, err = pdfProcessor.ExtractDocumentFromPDFPath(v)
+	case []byte:
+		// Binary data
+		document, err = pdfProcessor.ExtractDocumentFromPDFBytes(v)
+	case io.Reader:
+		// Reader
+		document, err = pdfProcessor.ExtractDocumentFromPDFReader(v)
 	default:
-		return nil, fmt.Errorf("unsupported output type: %v", outputType)
+		return nil, "unknown.pdf", fmt.Errorf("unsupported PDF input: %T", input)
 	}
+
+	return document, filename, err
 }
 
-// processPDFInput handles PDF input (file path or binary data)
-func (c *Chunker) processPDFInput(input interface{}) (string, string) {
+// processOfficeDocument extracts a PPTX or XLSX input (file path or binary data) page by page
+func (c *Chunker) processOfficeDocument(inputType InputType, input interface{}) (*processor.Document, string, error) {
+	ext := ".pptx"
+	if inputType == InputXLSX {
+		ext = ".xlsx"
+	}
+	filename := "input" + ext
+
+	var data []byte
+	var err error
+
 	switch v := input.(type) {
 	case string:
 		// File path
-		filename := filepath.Base(v)
-		text, err := c.pdfProcessor.ExtractTextFromPDFPath(v)
-		if err != nil {
-			return "", filename
-		}
-		return text, filename
+		filename = filepath.Base(v)
+		data, err = os.ReadFile(v)
 	case []byte:
 		// Binary data
-		filename := "input.pdf"
-		text, err := c.pdfProcessor.ExtractTextFromPDFBytes(v)
-		if err != nil {
-			return "", filename
-		}
-		return text, filename
+		data = v
+	case io.Reader:
+		// Reader
+		data, err = io.ReadAll(v)
+	default:
+		return nil, "unknown" + ext, fmt.Errorf("unsupported office input: %T", input)
+	}
+	if err != nil {
+		return nil, filename, fmt.Errorf("failed to read office document: %w", err)
+	}
+
+	var document *processor.Document
+	if inputType == InputXLSX {
+		document, err = c.xlsxProcessor.ExtractDocumentFromXLSXBytes(data)
+	} else {
+		document, err = c.pptxProcessor.ExtractDocumentFromPPTXBytes(data)
+	}
+	return document, filename, err
+}
+
+// processEmailInput handles email input (file path or binary data), expanding
+// attachments through the extractor matching their file type
+func (c *Chunker) processEmailInput(input interface{}, depth int) ([]processor.Page, string, error) {
+	filename := "input.eml"
+	var data []byte
+	var err error
+
+	switch v := input.(type) {
+	case string:
+		// File path
+		filename = filepath.Base(v)
+		data, err = os.ReadFile(v)
+	case []byte:
+		// Binary data
+		data = v
 	case io.Reader:
 		// Reader
-		filename := "input.pdf"
-		text, err := c.pdfProcessor.ExtractTextFromPDFReader(v)
+		data, err = io.ReadAll(v)
+	default:
+		return nil, "unknown.eml", fmt.Errorf("unsupported email input: %T", input)
+	}
+	if err != nil {
+		return nil, filename, fmt.Errorf("failed to read email: %w", err)
+	}
+
+	email, err := c.emailProcessor.ExtractEmailFromBytes(data)
+	if err != nil {
+		return nil, filename, err
+	}
+
+	pages := []processor.Page{{Number: 1, Text: email.Text(), Source: processor.SourceText}}
+	for _, attachment := range email.Attachments {
+		attached := c.extractAttachment(attachment, depth)
+		if strings.TrimSpace(newPagedText(attached).text) == "" {
+			continue
+		}
+
+		// Attachment pages continue the email's page numbering
+		pages = appendPages(pages, attached, "Attachment: "+attachment.Filename)
+	}
+
+	return pages, filename, nil
+}
+
+// extractAttachment extracts the pages of an email attachment with the matching extractor
+func (c *Chunker) extractAttachment(attachment processor.Attachment, depth int) []processor.Page {
+	inputType, ok := InputTypeForFilename(attachment.Filename)
+	if !ok || inputType == InputArchive {
+		c.logger.Printf("Warning: skipping unsupported attachment %s", attachment.Filename)
+		return nil
+	}
+
+	if inputType == InputEmail {
+		if depth+1 >= maxEmailDepth {
+			c.logger.Printf("Warning: skipping nested email %s (depth limit reached)", attachment.Filename)
+			return nil
+		}
+		pages, _, err := c.processEmailInput(attachment.Data, depth+1)
 		if err != nil {
-			return "", filename
+			c.logger.Printf("Warning: failed to extract attachment %s: %v", attachment.Filename, err)
 		}
-		return text, filename
+		return pages
+	}
+
+	unmute := c.muteAttachmentPages()
+	pages, _, _, err := c.extractPages(inputType, attachment.Data)
+	unmute()
+	if err != nil {
+		c.logger.Printf("Warning: failed to extract attachment %s: %v", attachment.Filename, err)
+		return nil
+	}
+	return pages
+}
+
+// InputTypeForFilename returns the input type matching a file extension
+func InputTypeForFilename(filename string) (InputType, bool) {
+	if strings.HasSuffix(strings.ToLower(filename), ".tar.gz") {
+		return InputArchive, true
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf":
+		return InputPDF, true
+	case ".pptx":
+		return InputPPTX, true
+	case ".xlsx":
+		return InputXLSX, true
+	case ".eml", ".msg":
+		return InputEmail, true
+	case ".txt", ".md", ".csv", ".log":
+		return InputTXT, true
+	case ".zip", ".tgz", ".tar":
+		return InputArchive, true
 	default:
-		return "", "unknown.pdf"
+		return 0, false
 	}
 }
 
@@ -248,113 +716,305 @@ func (c *Chunker) processStringInput(input interface{}) (string, string) {
 	}
 }
 
-// createChunks creates intelligent chunks using AI or local processing
-func (c *Chunker) createChunks(text, filename string) ([]ChunkData, error) {
-	if c.aiProvider != nil {
-		return c.createAIChunks(text, filename)
+// createChunksWithUsage creates intelligent chunks using AI or local processing, with
+// token usage tracking. When pageGroups is set, pages are never split across chunks (e.g. slides).
+func (c *Chunker) createChunksWithUsage(document pagedText, filename string, pageGroups, useAI bool) ([]ChunkData, TokenUsage, error) {
+	if chunks, ok := c.profileChunks(document, filename); ok {
+		return chunks, TokenUsage{}, nil
+	}
+	if useAI {
+		return c.createAIChunksWithUsage(document, filename, pageGroups)
 	} else {
-		return c.createLocalChunks(text, filename)
+		chunks, err := c.createLocalChunks(document, filename, pageGroups)
+		return chunks, TokenUsage{}, err
 	}
 }
 
-// createChunksWithUsage creates intelligent chunks with token usage tracking
-func (c *Chunker) createChunksWithUsage(text, filename string) ([]ChunkData, TokenUsage, error) {
-	if c.aiProvider != nil {
-		return c.createAIChunksWithUsage(text, filename)
-	} else {
-		chunks, err := c.createLocalChunks(text, filename)
-		return chunks, TokenUsage{}, err
+// splitForAI splits text into manageable chunks for AI processing
+func (c *Chunker) splitForAI(document pagedText, pageGroups bool) []string {
+	if c.strategy != nil {
+		return c.strategy.Split(document.text, c.config.MaxChunkSize)
 	}
+	if pageGroups {
+		return c.textProcessor.GroupPages(document.pageTexts(), c.config.MaxChunkSize)
+	}
+	return c.textProcessor.SplitTextIntoChunks(document.text)
 }
 
-// createAIChunks creates chunks using AI provider
-func (c *Chunker) createAIChunks(text, filename string) ([]ChunkData, error) {
+// splitForLocal splits text into intelligent chunks for local processing
+func (c *Chunker) splitForLocal(document pagedText, pageGroups bool) []string {
+	if c.strategy != nil {
+		return c.strategy.Split(document.text, c.config.LocalChunkSize)
+	}
+	if pageGroups {
+		return c.textProcessor.GroupPages(document.pageTexts(), c.config.LocalChunkSize)
+	}
+	return c.textProcessor.SplitTextIntoLocalChunks(document.text)
+}
+
+// createAIChunksWithUsage creates chunks using AI provider with token usage tracking.
+// Only providers that implement AIProviderWithUsage report usage.
+func (c *Chunker) createAIChunksWithUsage(document pagedText, filename string, pageGroups bool) ([]ChunkData, TokenUsage, error) {
 	// Split text into manageable chunks for AI processing
-	textChunks := c.textProcessor.SplitTextIntoChunks(text)
-	var chunks []ChunkData
+	textChunks := c.splitForAI(document, pageGroups)
+	spans := document.locate(textChunks)
 
-	for i, chunk := range textChunks {
-		if strings.TrimSpace(chunk) == "" {
-			continue
+	// Up to AIWorkers slices are sent at once; their chunks are put back in slice order
+	results := make([]sliceChunks, len(textChunks))
+	var failed atomic.Bool
+	runParallel(c.config.AIWorkers, len(textChunks), func(i int) {
+		chunk := textChunks[i]
+		if strings.TrimSpace(chunk) == "" || failed.Load() {
+			return
+		}
+		result := &results[i]
+		defer func() {
+			if result.err != nil {
+				failed.Store(true)
+			}
+		}()
+		defer processor.RecoverPanic(&result.err)
+		if result.err = c.checkDeadline(); result.err == nil {
+			if c.config.AIMode == config.AIModeStructure {
+				result.chunks, result.usage, result.err = c.structureSlice(nil, chunk, filename, i+1, document, spans[i])
+			} else {
+				var intelligentChunk string
+				var validation *schema.Validation
+				intelligentChunk, result.usage, validation, result.err = c.chunkSlice(chunk, filename, i+1, document.pageRange(spans[i]))
+				result.chunks = appendSections(nil, intelligentChunk, filename, i+1, document, spans[i], validation)
+			}
 		}
+	})
 
-		// Get intelligent chunk from AI
-		intelligentChunk, err := c.aiProvider.ChunkText(chunk)
-		if err != nil {
-			// Fallback to local chunking
-			intelligentChunk = c.textProcessor.CreateLocalIntelligentChunk(chunk)
+	var chunks []ChunkData
+	var totalTokenUsage TokenUsage
+	for _, result := range results {
+		// Add token usage to total
+		totalTokenUsage.PromptTokens += result.usage.PromptTokens
+		totalTokenUsage.CompletionTokens += result.usage.CompletionTokens
+		totalTokenUsage.TotalTokens += result.usage.TotalTokens
+		if result.err != nil {
+			return nil, totalTokenUsage, result.err
 		}
+		for _, chunk := range result.chunks {
+			chunk.ChunkIndex = len(chunks) + 1
+			chunks = append(chunks, chunk)
+		}
+	}
 
-		// Create chunk data
-		chunkData := ChunkData{
-			Filename:   filename,
-			ChunkIndex: i + 1,
-			PageRange:  c.textProcessor.ExtractPageRange(chunk),
-			Text:       intelligentChunk,
+	return chunks, totalTokenUsage, nil
+}
+
+// sliceChunks are the chunks made from one AI slice
+type sliceChunks struct {
+	chunks []ChunkData
+	usage  TokenUsage
+	err    error
+}
+
+// aiRequest is the kind of request askAI sends
+type aiRequest int
+
+const (
+	aiChunk     aiRequest = iota // ChunkTextWithUsage, or ChunkText
+	aiStrict                     // ChunkTextStrict, retrying a rejected answer
+	aiStructure                  // StructureText, for AIModeStructure
+	aiInvoice                    // ExtractInvoice, for ProfileInvoice
+)
+
+// aiAnswer is the answer of the AI provider for one slice
+type aiAnswer struct {
+	text      string
+	usage     TokenUsage
+	estimated bool  // The provider does not report usage, so it was estimated for the budget
+	err       error // The request failed
+}
+
+// chunkSlice sends a slice to the AI provider and returns the text to split into
+// sections, the reported token usage and, with validators, the validation outcome. A
+// rejected answer is retried once with a stricter prompt; slices without a usable
+// answer are chunked locally. Only audit log failures are returned as errors.
+func (c *Chunker) chunkSlice(slice, filename string, index int, pageRange string) (string, TokenUsage, *schema.Validation, error) {
+	var usage TokenUsage
+	answer, err := c.askAI(slice, filename, index, aiChunk, &usage)
+	if err != nil {
+		return "", usage, nil, err
+	}
+	if answer.err != nil {
+		// Fallback to local chunking
+		return c.createLocalIntelligentChunk(slice, pageRange), usage, nil, nil
+	}
+
+	validation := c.newValidation()
+	if validation == nil {
+		return answer.text, usage, nil, nil
+	}
+	validation.Failures = c.validateAI(slice, answer.text)
+	if len(validation.Failures) == 0 {
+		return answer.text, usage, validation, nil
+	}
+
+	c.logger.Printf("Warning: AI output for slice %d of %s rejected (%s), retrying", index, filename, strings.Join(validation.Failures, "; "))
+	answer, err = c.askAI(slice, filename, index, aiStrict, &usage)
+	if err != nil {
+		return "", usage, nil, err
+	}
+	if answer.err != nil {
+		validation.Failures = append(validation.Failures, "retry: "+answer.err.Error())
+	} else if failures := c.validateAI(slice, answer.text); len(failures) > 0 {
+		for _, failure := range failures {
+			validation.Failures = append(validation.Failures, "retry: "+failure)
 		}
+	} else {
+		validation.Outcome = schema.ValidationRetried
+		return answer.text, usage, validation, nil
+	}
+
+	c.logger.Printf("Warning: retried AI output for slice %d of %s rejected too, chunking it locally", index, filename)
+	validation.Outcome = schema.ValidationFallback
+	return c.createLocalIntelligentChunk(slice, pageRange), usage, validation, nil
+}
+
+// askAI sends a request for a slice to the AI provider, records it in the budget and
+// the audit log, and adds the reported token usage to usage
+func (c *Chunker) askAI(slice, filename string, index int, request aiRequest, usage *TokenUsage) (aiAnswer, error) {
+	if err := c.waitForAI(slice); err != nil {
+		return aiAnswer{}, err
+	}
+	started := time.Now()
 
-		chunks = append(chunks, chunkData)
+	var answer aiAnswer
+	var result *providers.ChunkResult
+	strictProvider, canRetryStrict := c.aiProvider.(StrictAIProvider)
+	structureProvider, canStructure := c.aiProvider.(StructureAIProvider)
+	invoiceProvider, canExtract := c.aiProvider.(InvoiceAIProvider)
+	usageProvider, reportsUsage := c.aiProvider.(AIProviderWithUsage)
+	switch {
+	case request == aiStructure && canStructure:
+		result, answer.err = structureProvider.StructureText(slice)
+	case request == aiInvoice && canExtract:
+		result, answer.err = invoiceProvider.ExtractInvoice(slice)
+	case request == aiStrict && canRetryStrict:
+		result, answer.err = strictProvider.ChunkTextStrict(slice)
+	case reportsUsage:
+		result, answer.err = usageProvider.ChunkTextWithUsage(slice)
+	default:
+		var text string
+		text, answer.err = c.aiProvider.ChunkText(slice)
+		result = &providers.ChunkResult{Text: text}
+		answer.estimated = true
+	}
+
+	entry := audit.Entry{Time: started, Filename: filename, Slice: index, Request: slice, LatencyMS: time.Since(started).Milliseconds()}
+	if answer.err != nil {
+		entry.Error = answer.err.Error()
+		return answer, c.recordAI(entry, TokenUsage{})
 	}
 
-	return chunks, nil
+	answer.text = result.Text
+	if answer.estimated {
+		answer.usage = estimateUsage(slice, answer.text)
+	} else {
+		answer.usage = TokenUsage(result.TokenUsage)
+		usage.PromptTokens += answer.usage.PromptTokens
+		usage.CompletionTokens += answer.usage.CompletionTokens
+		usage.TotalTokens += answer.usage.TotalTokens
+	}
+	entry.Response, entry.Estimated = answer.text, answer.estimated
+	if err := c.recordAI(entry, answer.usage); err != nil {
+		return answer, err
+	}
+	c.budget.record(answer.usage)
+	return answer, nil
 }
 
-// createAIChunksWithUsage creates chunks using AI provider with token usage tracking
-func (c *Chunker) createAIChunksWithUsage(text, filename string) ([]ChunkData, TokenUsage, error) {
-	// Split text into manageable chunks for AI processing
-	textChunks := c.textProcessor.SplitTextIntoChunks(text)
-	var chunks []ChunkData
-	var totalTokenUsage TokenUsage
+// attachMetadata adds the document metadata to the metadata of every chunk, replacing
+// the values the chunker found under the same keys
+func attachMetadata(chunks []ChunkData, metadata map[string]any) {
+	if len(metadata) == 0 {
+		return
+	}
+	for i := range chunks {
+		if chunks[i].Metadata == nil {
+			chunks[i].Metadata = make(map[string]any, len(metadata))
+		}
+		maps.Copy(chunks[i].Metadata, metadata)
+	}
+}
 
-	// Check if AI provider supports usage tracking
-	aiProviderWithUsage, ok := c.aiProvider.(AIProviderWithUsage)
-	if !ok {
-		// Fallback to regular AI chunking
-		chunks, err := c.createAIChunks(text, filename)
-		return chunks, TokenUsage{}, err
+// waitForAI blocks until an AI call for chunk fits the shared rate limits, and fails
+// with the error of the chunker's context once it is done. Tokens are estimated as the
+// prompt plus the full completion allowance, as OpenAI counts them.
+func (c *Chunker) waitForAI(chunk string) error {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	return c.limiter.Wait(ctx, len(chunk)/charsPerToken+promptOverheadTokens+maxCompletionTokensPerAI)
+}
 
-	for i, chunk := range textChunks {
-		if strings.TrimSpace(chunk) == "" {
-			continue
+// appendSections appends the sections of an AI response as chunks numbered after the
+// existing ones. Sections split from one slice keep that slice as their parent, and
+// its validation outcome.
+func appendSections(chunks []ChunkData, response, filename string, parentIndex int, document pagedText, s span, validation *schema.Validation) []ChunkData {
+	sections := splitSections(response)
+	for _, section := range sections {
+		chunk := newChunkData(filename, len(chunks)+1, document, s, section)
+		chunk.Validation = validation
+		if len(sections) > 1 {
+			chunk.ParentIndex = parentIndex
 		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
 
-		// Get intelligent chunk from AI with usage tracking
-		result, err := aiProviderWithUsage.ChunkTextWithUsage(chunk)
-		if err != nil {
-			// Fallback to local chunking
-			intelligentChunk := c.textProcessor.CreateLocalIntelligentChunk(chunk)
-			chunkData := ChunkData{
-				Filename:   filename,
-				ChunkIndex: i + 1,
-				PageRange:  c.textProcessor.ExtractPageRange(chunk),
-				Text:       intelligentChunk,
-			}
-			chunks = append(chunks, chunkData)
-		} else {
-			// Add token usage to total
-			totalTokenUsage.PromptTokens += result.TokenUsage.PromptTokens
-			totalTokenUsage.CompletionTokens += result.TokenUsage.CompletionTokens
-			totalTokenUsage.TotalTokens += result.TokenUsage.TotalTokens
-
-			// Create chunk data
-			chunkData := ChunkData{
-				Filename:   filename,
-				ChunkIndex: i + 1,
-				PageRange:  c.textProcessor.ExtractPageRange(chunk),
-				Text:       result.Text,
-			}
+// splitSections splits an AI response at section delimiter lines, dropping empty sections
+func splitSections(response string) []string {
+	var sections []string
+	var current strings.Builder
+	flush := func() {
+		if section := strings.TrimSpace(current.String()); section != "" {
+			sections = append(sections, section)
+		}
+		current.Reset()
+	}
 
-			chunks = append(chunks, chunkData)
+	for _, line := range strings.Split(response, "\n") {
+		if strings.TrimSpace(line) == providers.SectionDelimiter {
+			flush()
+			continue
 		}
+		current.WriteString(line + "\n")
 	}
+	flush()
 
-	return chunks, totalTokenUsage, nil
+	if len(sections) == 0 {
+		return []string{response}
+	}
+	return sections
+}
+
+// createLocalIntelligentChunk chunks a slice locally when the AI provider fails or its
+// output is rejected. The local chunks are returned as sections of one response, so
+// appendSections keeps all of the slice.
+func (c *Chunker) createLocalIntelligentChunk(chunk, pageRange string) string {
+	chunks := c.textProcessor.SplitTextIntoLocalChunks(chunk)
+	if len(chunks) == 0 {
+		return chunk
+	}
+
+	sections := make([]string, len(chunks))
+	for i, local := range chunks {
+		sections[i] = c.textProcessor.FormatChunk(local, pageRange, i+1, len(chunks))
+	}
+	return strings.Join(sections, "\n"+providers.SectionDelimiter+"\n")
 }
 
 // createLocalChunks creates chunks using local intelligent processing
-func (c *Chunker) createLocalChunks(text, filename string) ([]ChunkData, error) {
-	chunks := c.textProcessor.SplitTextIntoLocalChunks(text)
+func (c *Chunker) createLocalChunks(document pagedText, filename string, pageGroups bool) ([]ChunkData, error) {
+	chunks := c.splitForLocal(document, pageGroups)
+	spans := document.locate(chunks)
 	var chunkData []ChunkData
 
 	for i, chunk := range chunks {
@@ -363,15 +1023,12 @@ func (c *Chunker) createLocalChunks(text, filename string) ([]ChunkData, error)
 		}
 
 		// Format the chunk with headers and structure
-		formattedChunk := c.textProcessor.FormatLocalChunk(chunk, i+1, len(chunks))
+		index := len(chunkData) + 1
+		formattedChunk := c.textProcessor.FormatChunk(chunk, document.pageRange(spans[i]), index, len(chunks))
 
 		// Create chunk data
-		data := ChunkData{
-			Filename:   filename,
-			ChunkIndex: i + 1,
-			PageRange:  c.textProcessor.ExtractPageRange(chunk),
-			Text:       formattedChunk,
-		}
+		data := newChunkData(filename, index, document, spans[i], formattedChunk)
+		data.RenderedText = c.renderedText(chunk, document.pageRange(spans[i]), "", index, len(chunks))
 
 		chunkData = append(chunkData, data)
 	}
@@ -379,36 +1036,120 @@ func (c *Chunker) createLocalChunks(text, filename string) ([]ChunkData, error)
 	return chunkData, nil
 }
 
-// saveChunksToFiles saves chunks to files
-func (c *Chunker) saveChunksToFiles(chunks []ChunkData, filename string) error {
+// saveChunksToFiles saves chunks to files under ChunkDir/<name> and JSONDir/<name>. Each
+// directory is written in full to a staging directory first and then swapped into place,
+// so a run that dies mid-document never leaves a partial set of chunks behind, and
+// chunks from an earlier, longer version of the document are removed.
+func (c *Chunker) saveChunksToFiles(chunks []ChunkData, name string, manifest *Manifest) error {
 	// Ensure directories exist
 	if err := c.ensureDirectories(); err != nil {
 		return err
 	}
-
-	// Create chunk directory for this file
-	chunkDir := filepath.Join(c.config.ChunkDir, strings.TrimSuffix(filename, filepath.Ext(filename)))
-	if err := os.MkdirAll(chunkDir, 0755); err != nil {
-		return fmt.Errorf("failed to create chunk directory: %w", err)
+	if c.config.Compression == config.CompressionTarZstd {
+		return c.saveChunkArchive(chunks, name, manifest)
 	}
+	ext := utils.CompressionExt(c.fileCompression())
 
-	// Save each chunk
-	for _, chunk := range chunks {
-		// Save text chunk
-		chunkPath := filepath.Join(chunkDir, fmt.Sprintf("chunk_%d.txt", chunk.ChunkIndex))
-		if err := os.WriteFile(chunkPath, []byte(chunk.Text), 0644); err != nil {
-			return fmt.Errorf("failed to save chunk %d: %w", chunk.ChunkIndex, err)
+	// JSON first, so a complete chunk directory implies complete JSON
+	jsonDir := filepath.Join(c.config.JSONDir, filepath.FromSlash(name))
+	c.removeStaleStaging(jsonDir)
+	manifest.Chunks = make([]ManifestChunk, len(chunks))
+	err := utils.WriteDirAtomic(jsonDir, func(staging string) error {
+		for i, chunk := range chunks {
+			jsonData, err := json.Marshal(chunk)
+			if err != nil {
+				return fmt.Errorf("failed to marshal JSON chunk %d: %w", chunk.ChunkIndex, err)
+			}
+			jsonFile := chunkFilename(chunk, ".json"+ext)
+			jsonHash, err := c.writeOutputFile(filepath.Join(staging, jsonFile), jsonData)
+			if err != nil {
+				return fmt.Errorf("failed to save JSON chunk %d: %w", chunk.ChunkIndex, err)
+			}
+			manifest.Chunks[i] = newManifestChunk(chunk)
+			manifest.Chunks[i].JSONFile = filepath.ToSlash(filepath.Join(jsonDir, jsonFile))
+			manifest.Chunks[i].JSONSHA256 = jsonHash
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		// Save JSON chunk
-		if err := c.saveJSONChunk(chunk); err != nil {
-			return fmt.Errorf("failed to save JSON chunk %d: %w", chunk.ChunkIndex, err)
+	chunkDir := filepath.Join(c.config.ChunkDir, filepath.FromSlash(name))
+	c.removeStaleStaging(chunkDir)
+	return utils.WriteDirAtomic(chunkDir, func(staging string) error {
+		for i, chunk := range chunks {
+			textFile := chunkFilename(chunk, ".txt"+ext)
+			textHash, err := c.writeOutputFile(filepath.Join(staging, textFile), c.chunkFileText(chunk))
+			if err != nil {
+				return fmt.Errorf("failed to save chunk %d: %w", chunk.ChunkIndex, err)
+			}
+			manifest.Chunks[i].TextFile = filepath.ToSlash(filepath.Join(chunkDir, textFile))
+			manifest.Chunks[i].TextSHA256 = textHash
 		}
-	}
+		if manifest.toc != nil {
+			files, err := tocFiles(manifest.toc, ".txt"+ext)
+			if err != nil {
+				return err
+			}
+			for file, data := range files {
+				if err := os.WriteFile(filepath.Join(staging, file), data, 0644); err != nil {
+					return fmt.Errorf("failed to save table of contents: %w", err)
+				}
+			}
+		}
+		if manifest.elements != nil {
+			data, err := json.MarshalIndent(manifest.elements, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode elements: %w", err)
+			}
+			if err := os.WriteFile(filepath.Join(staging, ElementsFilename), data, 0644); err != nil {
+				return fmt.Errorf("failed to save elements: %w", err)
+			}
+		}
+		if manifest.thumbnail != nil {
+			file := c.thumbnailFilename()
+			if err := os.WriteFile(filepath.Join(staging, file), manifest.thumbnail, 0644); err != nil {
+				return fmt.Errorf("failed to save thumbnail: %w", err)
+			}
+			manifest.Thumbnail = filepath.ToSlash(filepath.Join(chunkDir, file))
+		}
+		return manifest.save(filepath.Join(staging, ManifestFilename))
+	})
+}
 
+// saveRawText writes the consolidated extracted text of a document to OutputDir/<name>.txt
+func (c *Chunker) saveRawText(text, name string) error {
+	outputPath := c.rawTextPath(name)
+	c.removeStaleStaging(outputPath)
+	data, err := utils.Compress([]byte(text), c.fileCompression())
+	if err == nil {
+		err = utils.WriteFileAtomic(outputPath, data, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save extracted text: %w", err)
+	}
 	return nil
 }
 
+// rawTextPath returns the OutputRawText file of an output name
+func (c *Chunker) rawTextPath(name string) string {
+	return filepath.Join(c.config.OutputDir, filepath.FromSlash(name)+".txt"+utils.CompressionExt(c.fileCompression()))
+}
+
+// chunkFilename returns the name of a chunk's file in its document directory
+func chunkFilename(chunk ChunkData, ext string) string {
+	return fmt.Sprintf("chunk_%d%s", chunk.ChunkIndex, ext)
+}
+
+// removeStaleStaging removes staging copies of an output path left by crashed runs once
+// they are older than TempMaxAge
+func (c *Chunker) removeStaleStaging(path string) {
+	if c.config.TempMaxAge > 0 {
+		utils.RemoveStaleStaging(path, c.config.TempMaxAge)
+	}
+}
+
 // ensureDirectories creates the output and chunk directories if they don't exist
 func (c *Chunker) ensureDirectories() error {
 	dirs := []string{c.config.OutputDir, c.config.ChunkDir, c.config.JSONDir}
@@ -419,8 +1160,3 @@ func (c *Chunker) ensureDirectories() error {
 	}
 	return nil
 }
-
-// saveJSONChunk creates a JSON object for vector database embedding
-func (c *Chunker) saveJSONChunk(chunk ChunkData) error {
-	return c.textProcessor.SaveJSONChunk(chunk, c.config.JSONDir, chunk.Filename, chunk.ChunkIndex)
-}
@@ -0,0 +1,24 @@
+package chunker_test
+
+import (
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+)
+
+// BenchmarkChunkString times local chunking end to end on the synthetic corpus, without
+// AI or saved files
+func BenchmarkChunkString(b *testing.B) {
+	corpus := chunkertest.Corpus(chunkertest.CorpusPages)
+	instance := chunker.NewChunker(chunker.WithConfig(chunkertest.Config(b)))
+	defer instance.Close()
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := instance.ChunkString(corpus, chunker.OutputJSON); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package chunker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/providers"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ratelimit"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// panickingStrategy panics on every document, like a buggy custom strategy
+type panickingStrategy struct{}
+
+func (panickingStrategy) Split(text string, maxSize int) []string { panic("strategy bug") }
+func (panickingStrategy) GetName() string                         { return "panicking" }
+
+// TestPanicRecovered checks that the single-document entry points return a panic while
+// processing a document as a processor.PanicError rather than crashing the caller
+func TestPanicRecovered(t *testing.T) {
+	instance := chunker.NewChunker(chunker.WithConfig(chunkertest.Config(t)), chunker.WithStrategy(panickingStrategy{}))
+	defer instance.Close()
+	const text = "Some text to chunk."
+
+	entryPoints := map[string]func() error{
+		"ChunkInput": func() error {
+			_, err := instance.ChunkInput(chunker.InputString, text, chunker.OutputJSON)
+			return err
+		},
+		"ChunkInputWithUsage": func() error {
+			_, err := instance.ChunkInputWithUsage(chunker.InputString, text, chunker.OutputJSON)
+			return err
+		},
+		"ChunkString": func() error {
+			_, err := instance.ChunkString(text, chunker.OutputJSON)
+			return err
+		},
+		"ChunkUpdate": func() error {
+			_, err := instance.ChunkUpdate(chunker.InputString, text, chunker.OutputJSON, nil)
+			return err
+		},
+	}
+	for name, chunk := range entryPoints {
+		var panicErr *processor.PanicError
+		if err := chunk(); !errors.As(err, &panicErr) {
+			t.Errorf("%s error = %v, want a processor.PanicError", name, err)
+		}
+	}
+}
+
+// rejectingValidator rejects every AI output, so slices are chunked locally
+type rejectingValidator struct{}
+
+func (rejectingValidator) Validate(input, output string) error { return errors.New("rejected") }
+func (rejectingValidator) GetName() string                     { return "rejecting" }
+
+// longSlice returns text several local chunks long that fits in one AI slice, with
+// numbered sentences to check that none is lost
+func longSlice() string {
+	var text strings.Builder
+	for i := 1; i <= 40; i++ {
+		fmt.Fprintf(&text, "Sentence number %d of the handbook explains the leave policy.\n", i)
+		if i%5 == 0 {
+			text.WriteString("\n")
+		}
+	}
+	return text.String()
+}
+
+// assertKeepsSlice checks that chunks chunked locally after a rejected AI output keep
+// every sentence of longSlice, numbered in order
+func assertKeepsSlice(t *testing.T, chunks []chunker.ChunkData) {
+	t.Helper()
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want the slice chunked locally into several", len(chunks))
+	}
+	var joined strings.Builder
+	for i, chunk := range chunks {
+		if chunk.Validation == nil || chunk.Validation.Outcome != schema.ValidationFallback {
+			t.Errorf("chunk %d validation = %+v, want fallback", i+1, chunk.Validation)
+		}
+		if want := fmt.Sprintf("- **Chunk Number**: %d of %d", i+1, len(chunks)); !strings.Contains(chunk.Text, want) {
+			t.Errorf("chunk %d does not say %q:\n%s", i+1, want, chunk.Text)
+		}
+		joined.WriteString(chunk.Text)
+	}
+	for i := 1; i <= 40; i++ {
+		if sentence := fmt.Sprintf("Sentence number %d of", i); !strings.Contains(joined.String(), sentence) {
+			t.Errorf("chunks lost %q", sentence)
+		}
+	}
+}
+
+// TestValidationFallbackKeepsSlice checks that a slice whose AI output is rejected twice
+// is chunked locally whole, not cut to its first local chunk
+func TestValidationFallbackKeepsSlice(t *testing.T) {
+	cfg := chunkertest.Config(t)
+	cfg.MaxChunkSize, cfg.LocalChunkSize = 10000, 500
+	provider := providers.NewFakeProviderFunc(func(text string) (*providers.ChunkResult, error) {
+		return &providers.ChunkResult{Text: "A summary instead of the text."}, nil
+	})
+	instance := chunker.NewChunker(chunker.WithConfig(cfg), chunker.WithProvider(provider), chunker.WithValidators(rejectingValidator{}))
+	defer instance.Close()
+
+	result, err := instance.ChunkInputWithUsage(chunker.InputString, longSlice(), chunker.OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Strict() != 1 {
+		t.Errorf("strict retries = %d, want 1", provider.Strict())
+	}
+	assertKeepsSlice(t, result.Chunks)
+}
+
+// TestFidelityFallbackKeepsSlice checks that a long slice whose AI outputs fail the
+// fidelity check is chunked locally whole, so the check never ends in lost text
+func TestFidelityFallbackKeepsSlice(t *testing.T) {
+	cfg := chunkertest.Config(t)
+	cfg.MaxChunkSize, cfg.LocalChunkSize = 10000, 500
+	cfg.FidelityThreshold = 0.9
+	provider := providers.NewFakeProviderFunc(func(text string) (*providers.ChunkResult, error) {
+		// Keeps the first sentences and drops the rest
+		return &providers.ChunkResult{Text: text[:len(text)/4]}, nil
+	})
+	instance := chunker.NewChunker(chunker.WithConfig(cfg), chunker.WithProvider(provider))
+	defer instance.Close()
+
+	result, err := instance.ChunkInputWithUsage(chunker.InputString, longSlice(), chunker.OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKeepsSlice(t, result.Chunks)
+	if validation := result.Chunks[0].Validation; validation == nil || len(validation.Failures) == 0 || !strings.HasPrefix(validation.Failures[0], "fidelity: ") {
+		t.Errorf("validation = %+v, want the fidelity check failed", validation)
+	}
+}
+
+// TestTextPageSeparators checks that a page separator line in text input is text unless
+// TextPageSeparators is set
+func TestTextPageSeparators(t *testing.T) {
+	const text = "Release notes.\n\n--- Page 7 ---\n\nThe separator above is part of the notes."
+	for _, separators := range []bool{false, true} {
+		cfg := chunkertest.Config(t)
+		cfg.TextPageSeparators = separators
+		instance := chunker.NewChunker(chunker.WithConfig(cfg))
+		result, err := instance.ChunkInputWithUsage(chunker.InputString, text, chunker.OutputJSON)
+		instance.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Chunks) != 1 {
+			t.Fatalf("TextPageSeparators=%v: got %d chunks, want 1", separators, len(result.Chunks))
+		}
+		chunk := result.Chunks[0]
+		wantPages, wantEnd := 1, 0
+		if separators {
+			wantPages, wantEnd = 2, 7
+		}
+		if result.Pages != wantPages || chunk.EndPage != wantEnd {
+			t.Errorf("TextPageSeparators=%v: %d pages, chunk ends on page %d (%q), want %d pages ending on page %d", separators, result.Pages, chunk.EndPage, chunk.PageRange, wantPages, wantEnd)
+		}
+		if !separators && chunk.PageRange != "" {
+			t.Errorf("page range = %q, want none for text without page structure", chunk.PageRange)
+		}
+		if !strings.Contains(chunk.Text, "Page 7") {
+			t.Errorf("TextPageSeparators=%v: chunk lost the separator line:\n%s", separators, chunk.Text)
+		}
+	}
+}
+
+// TestEmailInputErrors checks that email input that cannot be read or parsed returns an
+// error, like the other input types
+func TestEmailInputErrors(t *testing.T) {
+	instance := chunker.NewChunker(chunker.WithConfig(chunkertest.Config(t)))
+	defer instance.Close()
+
+	tests := []struct {
+		name    string
+		input   interface{}
+		wantErr string
+	}{
+		{"malformed message", []byte("This is not an email.\nIt has no headers.\n"), "failed to parse email"},
+		{"missing file", filepath.Join(t.TempDir(), "missing.eml"), "failed to read email"},
+		{"unsupported input", 42, "unsupported email input: int"},
+	}
+	for _, test := range tests {
+		_, err := instance.ChunkInputWithUsage(chunker.InputEmail, test.input, chunker.OutputJSON)
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%s: error = %v, want %s", test.name, err, test.wantErr)
+		}
+	}
+}
+
+// TestRateLimitCancelled checks that a document waiting for the AI rate limit fails with
+// the error of the chunker's context once it is done, rather than after the delay
+func TestRateLimitCancelled(t *testing.T) {
+	provider := providers.NewFakeProviderFunc(func(text string) (*providers.ChunkResult, error) {
+		return &providers.ChunkResult{Text: text}, nil
+	})
+	limiter := ratelimit.New(1, 0)
+	instance := chunker.NewChunker(chunker.WithConfig(chunkertest.Config(t)), chunker.WithProvider(provider), chunker.WithRateLimiter(limiter))
+	defer instance.Close()
+	if _, err := instance.ChunkString("The first document uses the request of this minute.", chunker.OutputJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := instance.WithContext(ctx).ChunkString("The second document waits a minute for the next one.", chunker.OutputJSON)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("returned after %v, want soon after the context is done", elapsed)
+	}
+}
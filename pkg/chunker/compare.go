@@ -0,0 +1,47 @@
+package chunker
+
+import (
+	"fmt"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/diff"
+)
+
+// CompareInputs chunks two versions of a document of the same input type, such as
+// revisions of a regulation or an SOP, and returns the chunks added, removed and modified
+// in the new version with their page references. Nothing is saved or written to sinks.
+func (c *Chunker) CompareInputs(inputType InputType, oldInput, newInput interface{}) (*diff.Diff, error) {
+	compare := c.forComparison()
+	oldResult, err := compare.chunkNamedInput(inputType, oldInput, OutputJSON, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to chunk old version: %w", err)
+	}
+	newResult, err := compare.chunkNamedInput(inputType, newInput, OutputJSON, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to chunk new version: %w", err)
+	}
+	return diff.Compare(oldResult.Chunks, newResult.Chunks, c.config.DiffThreshold), nil
+}
+
+// CompareFiles compares two versions of a document like CompareInputs, detecting the
+// type of each file like ChunkFile
+func (c *Chunker) CompareFiles(oldPath, newPath string) (*diff.Diff, error) {
+	compare := c.forComparison()
+	oldResult, err := compare.ChunkFile(oldPath, OutputJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to chunk old version: %w", err)
+	}
+	newResult, err := compare.ChunkFile(newPath, OutputJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to chunk new version: %w", err)
+	}
+	return diff.Compare(oldResult.Chunks, newResult.Chunks, c.config.DiffThreshold), nil
+}
+
+// forComparison returns a copy of the chunker that neither writes to sinks nor checks
+// for near-duplicates, as the versions being compared are not part of the corpus
+func (c *Chunker) forComparison() *Chunker {
+	compare := *c
+	compare.sinks = nil
+	compare.dedupIndex = nil
+	return &compare
+}
@@ -0,0 +1,44 @@
+package chunker
+
+import "fmt"
+
+// claimBatchFile claims a file of a batch with the coordinator, hashing it unless the
+// checkpoint already did. It returns false, having recorded the file's outcome in
+// result, when the file is not to be processed by this instance.
+func (c *Chunker) claimBatchFile(path, filename string, hash *string, result *BatchResult) bool {
+	if c.coordinator == nil {
+		return true
+	}
+	if *hash == "" {
+		sum, err := fileSHA256(path)
+		if err != nil {
+			c.logger.Printf("Error processing %s: %v", filename, err)
+			result.Files = append(result.Files, FileResult{Filename: filename, Status: FileStatusFailed, Error: fmt.Sprintf("failed to hash file: %v", err)})
+			return false
+		}
+		*hash = sum
+	}
+
+	claimed, err := c.coordinator.Claim(filename, *hash)
+	if err != nil {
+		// Processing unclaimed files could duplicate the work of other instances
+		c.logger.Printf("Error processing %s: %v", filename, err)
+		result.Files = append(result.Files, FileResult{Filename: filename, Status: FileStatusFailed, Error: fmt.Sprintf("failed to claim file: %v", err)})
+		return false
+	}
+	if !claimed {
+		result.Files = append(result.Files, FileResult{Filename: filename, Status: FileStatusElsewhere})
+		return false
+	}
+	return true
+}
+
+// finishBatchFile releases the coordinator's claim on a processed file
+func (c *Chunker) finishBatchFile(hash string, fileResult FileResult) {
+	if c.coordinator == nil {
+		return
+	}
+	if err := c.coordinator.Finish(fileResult.Filename, hash, fileResult); err != nil {
+		c.logger.Printf("Warning: failed to release claim on %s: %v", fileResult.Filename, err)
+	}
+}
@@ -0,0 +1,101 @@
+package chunker
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/dedup"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// DedupReportFilename is the near-duplicate report written to OutputDir by batch runs
+// that save files with Dedup
+const DedupReportFilename = "dedup_report.json"
+
+// DedupReport lists the near-duplicate chunks found across documents
+type DedupReport struct {
+	dedup.Report
+	Mode string `json:"mode"` // config.DedupFlag or config.DedupDrop
+}
+
+// setupDedup creates the near-duplicate index for Dedup, or makes every document fail
+// when the mode is unknown
+func (c *Chunker) setupDedup() {
+	switch c.config.Dedup {
+	case config.DedupOff:
+		return
+	case config.DedupFlag, config.DedupDrop:
+		c.dedupIndex = dedup.NewIndex(c.config.DedupThreshold, c.config.DedupMaxChunks)
+		return
+	}
+	err := fmt.Errorf("unknown dedup mode %q", c.config.Dedup)
+	if c.configErr == nil {
+		c.configErr = err
+		c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+	}
+}
+
+// dedupChunks checks the chunks of a document against the saved chunks of the documents
+// the chunker processed before it, and returns the chunks to output with the number of
+// near-duplicates: flagged with DuplicateOf for DedupFlag, or left out for DedupDrop.
+// Kept chunks keep their ChunkIndex, so dropped ones leave gaps. The checks it returns
+// are committed with commitDedup once the chunks are saved.
+func (c *Chunker) dedupChunks(chunks []ChunkData) ([]ChunkData, []dedup.Check, int) {
+	if c.dedupIndex == nil {
+		return chunks, nil, 0
+	}
+	kept := chunks[:0]
+	checks := make([]dedup.Check, 0, len(chunks))
+	duplicates := 0
+	for _, chunk := range chunks {
+		ref := schema.ChunkRef{Filename: chunk.Filename, Slug: chunk.Slug, ChunkIndex: chunk.ChunkIndex}
+		check := c.dedupIndex.Check(ref, chunk.Text)
+		checks = append(checks, check)
+		if check.Canonical != nil {
+			duplicates++
+			if c.config.Dedup == config.DedupDrop {
+				continue
+			}
+			chunk.DuplicateOf = check.Canonical
+		}
+		kept = append(kept, chunk)
+	}
+	return kept, checks, duplicates
+}
+
+// commitDedup records checked chunks in the near-duplicate index and report once they
+// are saved, so chunks of a document that failed are not canonical for later ones
+func (c *Chunker) commitDedup(checks []dedup.Check) {
+	if c.dedupIndex != nil {
+		c.dedupIndex.Commit(checks...)
+	}
+}
+
+// DedupReport returns the near-duplicates found so far by a chunker with Dedup, or nil
+// without it. Documents are compared in the order they are saved, so with parallel
+// batches the canonical chunk is the one of the first document saved.
+func (c *Chunker) DedupReport() *DedupReport {
+	if c.dedupIndex == nil {
+		return nil
+	}
+	return &DedupReport{Report: c.dedupIndex.Report(), Mode: c.config.Dedup}
+}
+
+// saveDedupReport writes the near-duplicate report to OutputDir/dedup_report.json
+func (c *Chunker) saveDedupReport() error {
+	report := c.DedupReport()
+	if report == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dedup report: %w", err)
+	}
+	if err := utils.WriteFileAtomic(filepath.Join(c.config.OutputDir, DedupReportFilename), data, 0644); err != nil {
+		return fmt.Errorf("failed to save dedup report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package chunker_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+// TestDedupAfterSave checks that the chunks of a document are recorded as canonical only
+// once it is saved, so a document whose output failed flags nothing after it
+func TestDedupAfterSave(t *testing.T) {
+	cfg := chunkertest.Config(t)
+	cfg.Dedup = config.DedupFlag
+	// A file where the chunk directory should be makes every OutputFile save fail
+	cfg.ChunkDir = filepath.Join(t.TempDir(), "chunks")
+	if err := os.WriteFile(cfg.ChunkDir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	instance := chunker.NewChunker(chunker.WithConfig(cfg))
+	defer instance.Close()
+	const boilerplate = "This agreement is governed by the laws of the Republic of Indonesia, and any dispute arising from it is settled by the district court of South Jakarta."
+
+	if _, err := instance.ChunkReader(strings.NewReader(boilerplate), "failed.txt", chunker.OutputFile); err == nil {
+		t.Fatal("saving into a chunk directory that is a file succeeded")
+	}
+	first, err := instance.ChunkReader(strings.NewReader(boilerplate), "first.txt", chunker.OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Duplicates != 0 || first.Chunks[0].DuplicateOf != nil {
+		t.Errorf("chunk of the first saved document is a duplicate of %+v", first.Chunks[0].DuplicateOf)
+	}
+	second, err := instance.ChunkReader(strings.NewReader(boilerplate), "second.txt", chunker.OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Duplicates != 1 || second.Chunks[0].DuplicateOf == nil || second.Chunks[0].DuplicateOf.Filename != "first.txt" {
+		t.Errorf("chunk of the second document is a duplicate of %+v, want first.txt", second.Chunks[0].DuplicateOf)
+	}
+	if report := instance.DedupReport(); report.Chunks != 2 || len(report.Duplicates) != 1 {
+		t.Errorf("report = %d chunks and %d duplicates, want 2 and 1", report.Chunks, len(report.Duplicates))
+	}
+}
@@ -0,0 +1,151 @@
+package chunker
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// maxPathLength is the longest string considered as a possible file path
+const maxPathLength = 4096
+
+// emailHeaderPattern matches the first line of an RFC 822 message
+var emailHeaderPattern = regexp.MustCompile(`(?i)^(Return-Path|Received|From|To|Subject|Date|Message-ID|MIME-Version|Delivered-To|X-[A-Za-z-]+):\s`)
+
+// Chunk detects the input type and processes the input. Strings are treated as file
+// paths only when they are a single line naming an existing file; everything else is
+// sniffed by content (magic bytes, office package parts, email headers).
+func (c *Chunker) Chunk(input any, outputType OutputType) (*ChunkResult, error) {
+	inputType, normalized, err := DetectInput(input)
+	if err != nil {
+		return nil, err
+	}
+	return c.ChunkInputWithUsage(inputType, normalized, outputType)
+}
+
+// DetectInput detects the type of input. Readers are consumed, so the returned value
+// (a path or []byte) must be used in place of the original input.
+func DetectInput(input any) (InputType, any, error) {
+	switch v := input.(type) {
+	case string:
+		if isExistingFilePath(v) {
+			inputType, err := detectFile(v)
+			return inputType, v, err
+		}
+		return InputString, v, nil
+	case []byte:
+		inputType, err := DetectContent(v)
+		return inputType, v, err
+	case io.Reader:
+		data, err := io.ReadAll(v)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read input: %w", err)
+		}
+		inputType, err := DetectContent(data)
+		return inputType, data, err
+	default:
+		return 0, nil, fmt.Errorf("unsupported input: %T", input)
+	}
+}
+
+// isExistingFilePath reports whether s could only be a path to a regular file
+func isExistingFilePath(s string) bool {
+	if s == "" || len(s) > maxPathLength || strings.ContainsAny(s, "\n\r\x00") {
+		return false
+	}
+	info, err := os.Stat(s)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// detectFile detects a file's type by extension, falling back to its content
+func detectFile(path string) (InputType, error) {
+	if inputType, ok := InputTypeForFilename(path); ok && inputType != InputTXT {
+		return inputType, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open input: %w", err)
+	}
+	defer file.Close()
+
+	// The head of the file is enough for everything except zip packages
+	head := make([]byte, 8192)
+	n, _ := io.ReadFull(file, head)
+	head = head[:n]
+
+	if bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read input: %w", err)
+		}
+		return DetectContent(data)
+	}
+
+	inputType, err := DetectContent(head)
+	if err != nil {
+		return 0, err
+	}
+	if inputType == InputString {
+		// A text file read from disk
+		return InputTXT, nil
+	}
+	return inputType, nil
+}
+
+// DetectContent detects the input type of raw data from its magic bytes and structure
+func DetectContent(data []byte) (InputType, error) {
+	switch {
+	case bytes.HasPrefix(bytes.TrimLeft(data[:min(len(data), 1024)], "\x00\t\r\n "), []byte("%PDF-")):
+		return InputPDF, nil
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return detectZipContent(data), nil
+	case utils.IsArchive(data):
+		return InputArchive, nil
+	case processor.IsCompoundFile(data):
+		// Outlook messages are the only compound files supported
+		return InputEmail, nil
+	case emailHeaderPattern.Match(data):
+		return InputEmail, nil
+	case validText(data):
+		return InputString, nil
+	default:
+		return 0, fmt.Errorf("unable to detect input type")
+	}
+}
+
+// detectZipContent distinguishes OOXML packages from plain zip archives
+func detectZipContent(data []byte) InputType {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return InputArchive
+	}
+
+	for _, file := range archive.File {
+		switch file.Name {
+		case "ppt/presentation.xml":
+			return InputPPTX
+		case "xl/workbook.xml":
+			return InputXLSX
+		}
+	}
+	return InputArchive
+}
+
+// validText reports whether data is UTF-8, tolerating a rune cut off at the end of a sample
+func validText(data []byte) bool {
+	for cut := 0; cut < utf8.UTFMax && cut <= len(data); cut++ {
+		if utf8.Valid(data[:len(data)-cut]) {
+			return true
+		}
+	}
+	return false
+}
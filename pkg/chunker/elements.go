@@ -0,0 +1,388 @@
+package chunker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"math"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// ElementsFilename is the file of a document's elements, written next to its manifest
+// with Elements
+const ElementsFilename = "elements.json"
+
+// Limits of the lines taken for unnumbered titles and table cells
+const (
+	maxTitleWords = 12
+	maxCellWords  = 5
+)
+
+// listItemPattern matches the bullet or number starting a list item
+var listItemPattern = regexp.MustCompile(`^(?:[•●▪◦*–-]\s+|\d{1,3}[.)]\s+|[a-zA-Z][.)]\s+|\(\w{1,4}\)\s+)`)
+
+// bulletPattern matches the bullets cut from the text of list items; numbers are kept
+var bulletPattern = regexp.MustCompile(`^[•●▪◦*–-]\s+`)
+
+// tableSeparatorPattern matches the row under the header of a Markdown table
+var tableSeparatorPattern = regexp.MustCompile(`^\|?[\s:|-]+\|?$`)
+
+// mimeTypes are the MIME types of the inputs the system MIME table may not know
+var mimeTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".eml":  "message/rfc822",
+	".msg":  "application/vnd.ms-outlook",
+}
+
+// elementBlock is an element of a page before it gets its ID and place
+type elementBlock struct {
+	kind  string
+	lines []string   // Lines of the page text, for locating the element in the layout
+	depth int        // Level of a title, from 0
+	rows  [][]string // Cells of a table
+}
+
+// documentElements splits a document into unstructured.io-style elements, page by page,
+// and locates them in the layout of the PDF at sourcePDF when there is one. Titles nest
+// by depth, and every other element is under the title before it.
+func (c *Chunker) documentElements(document pagedText, filename, sourcePDF string) []schema.Element {
+	layouts := c.pageLayouts(filename, sourcePDF)
+	filetype := mimeType(filename)
+	type openTitle struct {
+		id    string
+		depth int
+	}
+	var titles []openTitle // The open title of each depth above the current element
+	var elements []schema.Element
+	for _, page := range document.pages {
+		layout, located := layouts[page.Number]
+		used := make([]bool, len(layout.Lines))
+		for _, block := range c.pageElements(page.Text) {
+			element := schema.Element{
+				Type: block.kind,
+				Text: block.text(),
+				Metadata: schema.ElementMetadata{
+					Filename:   filename,
+					Filetype:   filetype,
+					PageNumber: page.Number,
+				},
+			}
+			element.ElementID = elementID(filename, page.Number, len(elements), element.Text)
+			if block.kind == schema.ElementTitle {
+				for len(titles) > 0 && titles[len(titles)-1].depth >= block.depth {
+					titles = titles[:len(titles)-1]
+				}
+				depth := block.depth
+				element.Metadata.CategoryDepth = &depth
+			}
+			if len(titles) > 0 {
+				element.Metadata.ParentID = titles[len(titles)-1].id
+			}
+			if block.kind == schema.ElementTitle {
+				titles = append(titles, openTitle{id: element.ElementID, depth: block.depth})
+			}
+			if block.kind == schema.ElementTable {
+				element.Metadata.TextAsHTML = tableHTML(block.rows)
+			}
+			if located {
+				element.Metadata.Coordinates = locateElement(layout, block.lines, used)
+			}
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// pageLayouts reads the layout of the pages of a PDF by page number, or returns nil for
+// other inputs and PDFs whose layout cannot be read
+func (c *Chunker) pageLayouts(filename, sourcePDF string) map[int]processor.PageLayout {
+	if sourcePDF == "" {
+		return nil
+	}
+	layouts, err := processor.ReadLayout(sourcePDF)
+	if err != nil {
+		c.logger.Printf("Warning: elements of %s have no coordinates: %v", filename, err)
+		return nil
+	}
+	byNumber := make(map[int]processor.PageLayout, len(layouts))
+	for _, layout := range layouts {
+		byNumber[layout.Number] = layout
+	}
+	return byNumber
+}
+
+// pageElements splits the text of a page into elements. Blocks are separated by blank
+// lines; a run of blocks with the same number of short lines is a table with a row per
+// block, as PDF engines extract tables cell by cell, and so is a block of Markdown table
+// rows. Within other blocks, numbered headings and title-like lines are titles, lines
+// starting with a bullet or number start list items, and the other lines make up
+// paragraphs.
+func (c *Chunker) pageElements(text string) []elementBlock {
+	var blocks [][]string
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		} else if len(lines) > 0 {
+			blocks, lines = append(blocks, lines), nil
+		}
+	}
+	if len(lines) > 0 {
+		blocks = append(blocks, lines)
+	}
+
+	var elements []elementBlock
+	for i := 0; i < len(blocks); i++ {
+		if rows := markdownTable(blocks[i]); rows != nil {
+			elements = append(elements, elementBlock{kind: schema.ElementTable, lines: blocks[i], rows: rows})
+			continue
+		}
+		end := i
+		for end < len(blocks) && len(blocks[end]) == len(blocks[i]) && len(blocks[i]) > 1 && cellRow(blocks[end]) {
+			end++
+		}
+		if end-i >= 2 {
+			table := elementBlock{kind: schema.ElementTable, rows: blocks[i:end]}
+			for _, row := range table.rows {
+				table.lines = append(table.lines, row...)
+			}
+			elements = append(elements, table)
+			i = end - 1
+			continue
+		}
+		elements = append(elements, c.blockElements(blocks[i])...)
+	}
+	return elements
+}
+
+// blockElements splits a block of lines into titles, list items and paragraphs
+func (c *Chunker) blockElements(lines []string) []elementBlock {
+	var elements []elementBlock
+	var current *elementBlock // The list item or paragraph lines are added to
+	flush := func() {
+		if current != nil {
+			elements = append(elements, *current)
+			current = nil
+		}
+	}
+	for _, line := range lines {
+		switch level := c.textProcessor.HeadingLevel(line); {
+		case level > 0:
+			flush()
+			elements = append(elements, elementBlock{kind: schema.ElementTitle, lines: []string{line}, depth: level - 1})
+		case listItemPattern.MatchString(line):
+			flush()
+			current = &elementBlock{kind: schema.ElementListItem, lines: []string{line}}
+		case (current == nil || endsSentence(current.lines[len(current.lines)-1])) && looksLikeTitle(line):
+			flush()
+			elements = append(elements, elementBlock{kind: schema.ElementTitle, lines: []string{line}})
+		case current != nil:
+			current.lines = append(current.lines, line)
+		default:
+			current = &elementBlock{kind: schema.ElementNarrativeText, lines: []string{line}}
+		}
+	}
+	flush()
+	for i, element := range elements {
+		if element.kind == schema.ElementNarrativeText && !narrative(element.text()) {
+			elements[i].kind = schema.ElementUncategorized
+		}
+	}
+	return elements
+}
+
+// text returns the text of an element: the lines of a paragraph or list item joined,
+// without the bullet of a list item, and the cells of a table row by row
+func (b elementBlock) text() string {
+	if b.kind == schema.ElementTable {
+		rows := make([]string, len(b.rows))
+		for i, row := range b.rows {
+			rows[i] = strings.Join(row, " ")
+		}
+		return strings.Join(rows, "\n")
+	}
+	text := strings.Join(strings.Fields(strings.Join(b.lines, " ")), " ")
+	if b.kind == schema.ElementListItem {
+		text = bulletPattern.ReplaceAllString(text, "")
+	}
+	return text
+}
+
+// markdownTable returns the cells of a block of Markdown table rows, or nil for other
+// blocks
+func markdownTable(lines []string) [][]string {
+	var rows [][]string
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "|") || !strings.HasSuffix(line, "|") || len(line) < 2 {
+			return nil
+		}
+		if tableSeparatorPattern.MatchString(line) {
+			continue
+		}
+		cells := strings.Split(line[1:len(line)-1], "|")
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+// cellRow reports whether a block can be a table row extracted cell by cell: every line
+// short and none a sentence or list item
+func cellRow(lines []string) bool {
+	for _, line := range lines {
+		if len(strings.Fields(line)) > maxCellWords || endsSentence(line) || listItemPattern.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// tableHTML renders the cells of a table as an HTML table
+func tableHTML(rows [][]string) string {
+	var b strings.Builder
+	b.WriteString("<table>")
+	for _, row := range rows {
+		b.WriteString("<tr>")
+		for _, cell := range row {
+			b.WriteString("<td>" + html.EscapeString(cell) + "</td>")
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</table>")
+	return b.String()
+}
+
+// looksLikeTitle reports whether a line reads as an unnumbered title: a few words, mostly
+// letters, capitalized like a title or in capitals, and not ending like a sentence
+func looksLikeTitle(line string) bool {
+	words := strings.Fields(line)
+	if len(words) > maxTitleWords || endsSentence(line) {
+		return false
+	}
+	letters, others := 0, 0
+	for _, r := range line {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case !unicode.IsSpace(r):
+			others++
+		}
+	}
+	if letters == 0 || letters < others {
+		return false
+	}
+	for _, word := range words {
+		first := []rune(word)[0]
+		// Short words such as "of" and "and" stay lowercase in titles
+		if unicode.IsLower(first) && len([]rune(word)) > 3 {
+			return false
+		}
+	}
+	first := []rune(line)[0]
+	return unicode.IsUpper(first) || unicode.IsDigit(first)
+}
+
+// narrative reports whether a paragraph reads as running text rather than a fragment
+// such as a page number, a code or a caption
+func narrative(text string) bool {
+	words := len(strings.Fields(text))
+	return words >= 3 && (endsSentence(text) || words >= 8)
+}
+
+// endsSentence reports whether a line ends with punctuation that ends or continues a
+// sentence
+func endsSentence(line string) bool {
+	return strings.TrimRight(line, ".,;:!?") != line
+}
+
+// locateElement returns the box around the layout lines holding the lines of an element,
+// marking them used so repeated text is located once, or nil when none is found. Lines
+// are matched on their letters, or by one containing the other when the text extracted
+// for chunking and the layout split lines differently.
+func locateElement(layout processor.PageLayout, lines []string, used []bool) *schema.Coordinates {
+	var x0, y0, x1, y1 float64
+	found := false
+	for _, line := range lines {
+		want := normalizeLine(line)
+		match := -1
+		for i, candidate := range layout.Lines {
+			if !used[i] && normalizeLine(candidate.Text) == want {
+				match = i
+				break
+			}
+		}
+		if match < 0 && len(want) >= 8 {
+			for i, candidate := range layout.Lines {
+				have := normalizeLine(candidate.Text)
+				if !used[i] && len(have) >= 8 && (strings.Contains(have, want) || strings.Contains(want, have)) {
+					match = i
+					break
+				}
+			}
+		}
+		if match < 0 {
+			continue
+		}
+		used[match] = true
+		box := layout.Lines[match]
+		if !found {
+			x0, y0, x1, y1, found = box.X0, box.Y0, box.X1, box.Y1, true
+			continue
+		}
+		x0, y0 = math.Min(x0, box.X0), math.Min(y0, box.Y0)
+		x1, y1 = math.Max(x1, box.X1), math.Max(y1, box.Y1)
+	}
+	if !found {
+		return nil
+	}
+	x0, y0, x1, y1 = roundPoint(x0), roundPoint(y0), roundPoint(x1), roundPoint(y1)
+	return &schema.Coordinates{
+		Points:       [][2]float64{{x0, y0}, {x0, y1}, {x1, y1}, {x1, y0}},
+		System:       schema.CoordinateSystemPixelSpace,
+		LayoutWidth:  layout.Width,
+		LayoutHeight: layout.Height,
+	}
+}
+
+// normalizeLine lowercases a line and drops its whitespace, for comparing lines whose
+// words engines spaced differently
+func normalizeLine(line string) string {
+	return strings.ToLower(strings.Join(strings.Fields(line), ""))
+}
+
+// roundPoint rounds a coordinate to hundredths of a point
+func roundPoint(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// elementID returns the ID of an element: a hash of its document, page, position and
+// text, so the same document always gets the same IDs
+func elementID(filename string, page, index int, text string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%s", filename, page, index, text)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// mimeType returns the MIME type of a file name, without parameters, or "" when unknown
+func mimeType(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if known, ok := mimeTypes[ext]; ok {
+		return known
+	}
+	mediaType, _, _ := strings.Cut(mime.TypeByExtension(ext), ";")
+	return mediaType
+}
@@ -0,0 +1,36 @@
+package chunker
+
+import (
+	"fmt"
+)
+
+// EmbeddingProvider turns chunk text into vectors for a vector database;
+// *providers.CohereProvider implements it. Embed returns one vector per text, in order.
+type EmbeddingProvider interface {
+	Embed(texts []string) ([][]float32, error)
+	GetName() string
+}
+
+// embedChunks sets the Embedding of every chunk when an embedding provider is set. A
+// failed request fails the document, so no chunk reaches sinks without its vector.
+func (c *Chunker) embedChunks(chunks []ChunkData) error {
+	if c.embedder == nil || len(chunks) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	embeddings, err := c.embedder.Embed(texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed chunks with %s: %w", c.embedder.GetName(), err)
+	}
+	if len(embeddings) != len(chunks) {
+		return fmt.Errorf("embedding provider %s returned %d vectors for %d chunks", c.embedder.GetName(), len(embeddings), len(chunks))
+	}
+	for i := range chunks {
+		chunks[i].Embedding = embeddings[i]
+	}
+	return nil
+}
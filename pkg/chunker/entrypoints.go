@@ -0,0 +1,99 @@
+package chunker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+)
+
+// ChunkFile processes the file at path. The type is detected from the extension and
+// content; the path is never interpreted as text content.
+func (c *Chunker) ChunkFile(path string, outputType OutputType) (*ChunkResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("input %s is not a regular file", path)
+	}
+
+	inputType, err := detectFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if inputType == InputArchive {
+		return c.ChunkInputWithUsage(InputArchive, path, outputType)
+	}
+
+	if inputType == InputTXT {
+		// Pass the content, not the path, so the text input never re-checks the filesystem
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input file: %w", err)
+		}
+		return c.chunkNamedInput(InputTXT, data, outputType, filepath.Base(path), nil)
+	}
+
+	return c.chunkNamedInput(inputType, path, outputType, filepath.Base(path), nil)
+}
+
+// ChunkReader processes data read from reader. The type is detected from the content,
+// with filename's extension used for text formats; filename names the document in
+// ChunkData and output paths and may be empty. PDFs are passed on as a stream, so large
+// ones are spooled to disk (see ReaderSpillSize) instead of read into memory.
+func (c *Chunker) ChunkReader(reader io.Reader, filename string, outputType OutputType) (*ChunkResult, error) {
+	return c.ChunkReaderWithMetadata(reader, filename, outputType, nil)
+}
+
+// ChunkReaderWithMetadata processes data read from reader like ChunkReader and attaches
+// metadata to every chunk, as ChunkInputWithMetadata does
+func (c *Chunker) ChunkReaderWithMetadata(reader io.Reader, filename string, outputType OutputType, metadata map[string]any) (*ChunkResult, error) {
+	reader = processor.LimitReader(reader, c.config.MaxFileSizeMB)
+	head := make([]byte, 1024)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	head = head[:n]
+
+	if inputType, err := DetectContent(head); err == nil && inputType == InputPDF {
+		return c.chunkNamedInput(InputPDF, io.MultiReader(bytes.NewReader(head), reader), outputType, filename, metadata)
+	}
+
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	data := append(head, rest...)
+
+	inputType, err := DetectContent(data)
+	if err != nil {
+		if byName, ok := InputTypeForFilename(filename); ok {
+			inputType, err = byName, nil
+		} else {
+			return nil, err
+		}
+	}
+
+	if inputType == InputString {
+		inputType = InputTXT
+	}
+	if inputType == InputArchive && metadata == nil {
+		return c.ChunkInputWithUsage(InputArchive, data, outputType)
+	}
+	if inputType == InputArchive {
+		return c.ChunkInputWithMetadata(InputArchive, data, outputType, metadata)
+	}
+
+	return c.chunkNamedInput(inputType, data, outputType, filename, metadata)
+}
+
+// ChunkString processes text content. The string is never interpreted as a file path.
+func (c *Chunker) ChunkString(text string, outputType OutputType) (*ChunkResult, error) {
+	return c.chunkNamedInput(InputString, text, outputType, "", nil)
+}
@@ -0,0 +1,38 @@
+package chunker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+)
+
+// DocumentText holds the raw extracted text of a document, page by page
+type DocumentText struct {
+	Filename string                    `json:"filename"`
+	Pages    []processor.Page          `json:"pages"`
+	Report   *processor.DocumentReport `json:"report,omitempty"`
+}
+
+// Text returns the consolidated text with "--- Page N ---" separators, as written by OutputRawText
+func (d DocumentText) Text() string {
+	return newPagedText(d.Pages).text
+}
+
+// ExtractText extracts the raw text of a document without chunking it or calling the
+// AI provider. The input type is detected as in Chunk.
+func (c *Chunker) ExtractText(input any) (DocumentText, error) {
+	inputType, normalized, err := DetectInput(input)
+	if err != nil {
+		return DocumentText{}, err
+	}
+
+	pages, filename, report, err := c.forDocument().extractPages(inputType, normalized)
+	if err != nil {
+		return DocumentText{}, err
+	}
+	if strings.TrimSpace(newPagedText(pages).text) == "" {
+		return DocumentText{}, fmt.Errorf("input text is empty")
+	}
+	return DocumentText{Filename: filename, Pages: pages, Report: report}, nil
+}
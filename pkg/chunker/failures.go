@@ -0,0 +1,150 @@
+package chunker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// FailuresFilename lists the documents a batch run that saves files failed to process,
+// in OutputDir
+const FailuresFilename = "failures.json"
+
+// ErrNoFailures is returned by RetryFailed when the last batch run left no failed
+// documents to process again
+var ErrNoFailures = errors.New("no failed documents to retry")
+
+// Failures lists the documents a batch run failed to process, as saved to
+// OutputDir/failures.json
+type Failures struct {
+	Root  string    `json:"root"` // Absolute path of the directory or archive of the run, empty for other inputs
+	Files []Failure `json:"files"`
+}
+
+// Failure is a document a batch run failed to process and the reason
+type Failure struct {
+	Filename string `json:"filename"` // As in FileResult
+	Error    string `json:"error"`
+
+	BudgetExceeded bool `json:"budget_exceeded,omitempty"` // Aborted by the AI budget
+}
+
+// LoadFailures reads a failures.json saved by a batch run
+func LoadFailures(path string) (*Failures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read failures: %w", err)
+	}
+	var failures Failures
+	if err := json.Unmarshal(data, &failures); err != nil {
+		return nil, fmt.Errorf("failed to parse failures %s: %w", path, err)
+	}
+	return &failures, nil
+}
+
+// RetryFailed processes again only the documents listed in OutputDir/failures.json by
+// the last batch run, from the directory or archive it processed, and returns their
+// result. failures.json is then replaced by the documents that failed again, or removed
+// when none did; a retry run that is stopped leaves it as it was. Retry runs neither
+// resume from nor save a checkpoint. It returns ErrNoFailures when there is nothing to
+// retry.
+func (c *Chunker) RetryFailed(outputType OutputType) (*BatchResult, error) {
+	path := filepath.Join(c.config.OutputDir, FailuresFilename)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, ErrNoFailures
+	}
+	failures, err := LoadFailures(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(failures.Files) == 0 {
+		return nil, ErrNoFailures
+	}
+	if failures.Root == "" {
+		return nil, fmt.Errorf("cannot retry failed documents: the run did not process a directory or archive path")
+	}
+	info, err := os.Stat(failures.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", failures.Root, err)
+	}
+
+	retry := make(map[string]bool, len(failures.Files))
+	for _, failure := range failures.Files {
+		retry[failure.Filename] = true
+	}
+	result := &BatchResult{StartedAt: time.Now(), root: failures.Root, retry: retry}
+	if info.IsDir() {
+		err = c.chunkTree(failures.Root, "", outputType, true, nil, result)
+	} else {
+		err = c.chunkArchive(failures.Root, "", outputType, nil, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, c.finishBatch(result, outputType)
+}
+
+// retrying reports whether a file or archive of a batch is processed: every one in a
+// normal run, and only the failed files and the archives holding them in a retry run
+func (r *BatchResult) retrying(filename string) bool {
+	if r.retry == nil || r.retry[filename] {
+		return true
+	}
+	for failed := range r.retry {
+		if strings.HasPrefix(failed, filename+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// saveFailures saves the failed files of a batch to OutputDir/failures.json, or removes
+// it when none failed, so it always lists the failures of the last run
+func (c *Chunker) saveFailures(result *BatchResult) error {
+	if result.retry != nil && result.Stopped {
+		return nil // The documents not retried yet still need to be
+	}
+
+	failures := Failures{Root: result.root}
+	for _, file := range result.Files {
+		if file.Status == FileStatusFailed {
+			failures.Files = append(failures.Files, Failure{Filename: file.Filename, Error: file.Error, BudgetExceeded: file.BudgetExceeded})
+		}
+	}
+	path := filepath.Join(c.config.OutputDir, FailuresFilename)
+	if len(failures.Files) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove failures: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(failures, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal failures: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save failures: %w", err)
+	}
+	return nil
+}
+
+// batchRoot returns the absolute path of a batch input that is a path, or "" for data
+// and readers
+func batchRoot(input interface{}) string {
+	path, ok := input.(string)
+	if !ok {
+		return ""
+	}
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return root
+}
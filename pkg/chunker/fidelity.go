@@ -0,0 +1,105 @@
+package chunker
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// fidelityGram is the number of words in the n-grams compared by FidelityValidator.
+// Trigrams survive added headings and line breaks but not dropped or reworded sentences.
+const fidelityGram = 3
+
+type fidelityValidator struct {
+	threshold float64
+}
+
+// FidelityValidator rejects outputs that keep less than threshold (0–1) of the word
+// trigrams of their input, compared case-insensitively without punctuation. It catches
+// dropped paragraphs and heavy rewording, which matter for legal documents, while
+// allowing the headings and section breaks the AI adds.
+func FidelityValidator(threshold float64) Validator {
+	return fidelityValidator{threshold: threshold}
+}
+
+// Validate rejects an output that lost too much of the input, naming the longest
+// passage it dropped
+func (v fidelityValidator) Validate(input, output string) error {
+	inputWords := fidelityWords(input)
+	inputGrams := wordGrams(inputWords)
+	if len(inputGrams) == 0 {
+		return nil
+	}
+
+	kept := map[string]bool{}
+	for _, gram := range wordGrams(fidelityWords(output)) {
+		kept[gram] = true
+	}
+
+	covered := 0
+	longestStart, longestLength, runStart := 0, 0, -1
+	for i, gram := range inputGrams {
+		if kept[gram] {
+			covered++
+			runStart = -1
+			continue
+		}
+		if runStart < 0 {
+			runStart = i
+		}
+		if length := i - runStart + 1; length > longestLength {
+			longestStart, longestLength = runStart, length
+		}
+	}
+
+	coverage := float64(covered) / float64(len(inputGrams))
+	if coverage >= v.threshold {
+		return nil
+	}
+	return fmt.Errorf("output keeps %.0f%% of the input, below %.0f%%; dropped %q", coverage*100, v.threshold*100,
+		droppedPassage(inputWords, longestStart, longestLength))
+}
+
+// GetName returns the validator name
+func (fidelityValidator) GetName() string {
+	return "fidelity"
+}
+
+// fidelityWords splits text into lowercase words without surrounding punctuation
+func fidelityWords(text string) []string {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		word = strings.TrimFunc(strings.ToLower(word), func(r rune) bool {
+			return unicode.IsPunct(r) || unicode.IsSymbol(r)
+		})
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// wordGrams returns the word n-grams of words in order, or the words themselves when
+// there are fewer than fidelityGram
+func wordGrams(words []string) []string {
+	if len(words) < fidelityGram {
+		return words
+	}
+	grams := make([]string, 0, len(words)-fidelityGram+1)
+	for i := 0; i+fidelityGram <= len(words); i++ {
+		grams = append(grams, strings.Join(words[i:i+fidelityGram], " "))
+	}
+	return grams
+}
+
+// droppedPassage returns the words covered by a run of missing n-grams, shortened to a
+// dozen words
+func droppedPassage(words []string, start, length int) string {
+	end := min(start+length+fidelityGram-1, len(words))
+	start = min(start, len(words))
+	passage := words[start:end]
+	if len(passage) > 12 {
+		return strings.Join(passage[:12], " ") + " ..."
+	}
+	return strings.Join(passage, " ")
+}
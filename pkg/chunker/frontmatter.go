@@ -0,0 +1,83 @@
+package chunker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// checkChunkFileFormat makes every document fail when the chunk file format is unknown,
+// rather than saving chunk files in a layout their readers do not expect
+func (c *Chunker) checkChunkFileFormat() {
+	switch c.config.ChunkFileFormat {
+	case config.ChunkFileMarkdown, config.ChunkFileFrontMatter:
+		return
+	}
+	err := fmt.Errorf("unknown chunk file format %q", c.config.ChunkFileFormat)
+	if c.configErr == nil {
+		c.configErr = err
+		c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+	}
+}
+
+// chunkFileText returns the content of a chunk's text file in the ChunkFileFormat: its
+// RenderedText, when Text is kept plain, or Text
+func (c *Chunker) chunkFileText(chunk ChunkData) []byte {
+	if c.config.ChunkFileFormat == config.ChunkFileFrontMatter {
+		return []byte(frontMatter(chunk))
+	}
+	if chunk.RenderedText != "" {
+		return []byte(chunk.RenderedText)
+	}
+	return []byte(chunk.Text)
+}
+
+// frontMatter renders a chunk as a YAML front matter block with its metadata, followed
+// by its content without the formatter's Markdown metadata section. Values are written
+// as JSON, which YAML parses as the same strings, numbers and maps. hash is the SHA-256
+// of the content, so ingesters can skip chunks they already have.
+func frontMatter(chunk ChunkData) string {
+	content := utils.ChunkContent(chunk.Text)
+	var front strings.Builder
+	field := func(key string, value any) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return // Only caller metadata can fail, and then the JSON file fails too
+		}
+		front.WriteString(key + ": " + string(data) + "\n")
+	}
+
+	front.WriteString("---\n")
+	field("filename", chunk.Filename)
+	field("slug", chunk.Slug)
+	field("chunk_index", chunk.ChunkIndex)
+	if chunk.PageRange != "" {
+		field("pages", chunk.PageRange)
+	}
+	if chunk.StartPage > 0 {
+		field("start_page", chunk.StartPage)
+		field("end_page", chunk.EndPage)
+	}
+	if chunk.Section != "" {
+		field("section", chunk.Section)
+	}
+	if chunk.Title != "" {
+		field("title", chunk.Title)
+	}
+	if chunk.Citation != nil {
+		field("citation", chunk.Citation.Text)
+	}
+	if chunk.Summary != "" {
+		field("summary", chunk.Summary)
+	}
+	if len(chunk.Metadata) > 0 {
+		field("metadata", chunk.Metadata)
+	}
+	field("hash", sha256Hex([]byte(content)))
+	front.WriteString("---\n\n")
+	front.WriteString(content + "\n")
+	return front.String()
+}
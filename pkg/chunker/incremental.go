@@ -0,0 +1,372 @@
+package chunker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// Operations of a ChunkOp
+const (
+	OpUpsert = "upsert" // Insert the chunk, or replace the one with the same Slug and ChunkIndex
+	OpDelete = "delete" // Remove the chunk with the Slug and ChunkIndex of Chunk
+)
+
+// ChunkOp is a change to a store holding the chunks of a document by Slug and ChunkIndex
+type ChunkOp struct {
+	Op    string    `json:"op"`
+	Chunk ChunkData `json:"chunk"` // Only Filename, Slug and ChunkIndex are set for deletes
+}
+
+// UpdateSink is a Sink that applies the operations of ChunkUpdate. Other sinks receive
+// the upserted chunks through Write, and keep the chunks deleted from the document.
+type UpdateSink interface {
+	Sink
+	Apply(ops []ChunkOp) error
+}
+
+// UpdateResult is the outcome of ChunkUpdate
+type UpdateResult struct {
+	*ChunkResult           // Every chunk of the new version
+	Operations   []ChunkOp `json:"operations"`
+	ChangedPages []int     `json:"changed_pages,omitempty"` // Pages of the new version that are new or changed
+	RemovedPages []int     `json:"removed_pages,omitempty"` // Pages of the previous version past the end of the new one
+	Rechunked    int       `json:"rechunked"`               // Chunks made from changed regions; the others were carried over
+}
+
+// pageHashes returns a hash of the extracted text of every page
+func pageHashes(pages []processor.Page) []string {
+	hashes := make([]string, len(pages))
+	for i, page := range pages {
+		hashes[i] = sha256Hex([]byte(page.Text))[:16]
+	}
+	return hashes
+}
+
+// ChunkUpdate chunks a new version of a document from the result of the previous one
+// (see Manifest.LoadResult), chunking again only the regions around pages whose text
+// changed. Chunks on unchanged pages are carried over with their summaries and
+// embeddings, and the operations turning the previous chunks into the new ones are
+// applied to sinks (see UpdateSink) instead of writing every chunk. Without page hashes
+// in previous, every chunk is made anew. A panic while processing the document is
+// returned as a processor.PanicError.
+func (c *Chunker) ChunkUpdate(inputType InputType, input interface{}, outputType OutputType, previous *ChunkResult) (_ *UpdateResult, err error) {
+	defer processor.RecoverPanic(&err)
+	c = c.forDocument()
+	input, sourcePDF, cleanup, err := c.splitSource(inputType, input)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	pages, filename, report, err := c.extractPages(inputType, input)
+	if err != nil {
+		return nil, err
+	}
+	document := newPagedText(pages)
+	if strings.TrimSpace(document.text) == "" {
+		return nil, fmt.Errorf("input text is empty")
+	}
+	useAI, budgetExceeded, err := c.useAI()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ChunkResult{Report: report, Pages: len(pages), BudgetExceeded: budgetExceeded, PageHashes: pageHashes(pages), sourcePDF: sourcePDF}
+	update := &UpdateResult{ChunkResult: result}
+	name, err := c.outputName(filename, document, outputType)
+	if err != nil {
+		return nil, err
+	}
+	segments := c.planUpdate(previous, pages, update)
+
+	var carried []int // ChunkIndex in previous of every chunk of the new version, 0 for new chunks
+	for _, segment := range segments {
+		if segment.kept != nil {
+			result.Chunks = append(result.Chunks, *segment.kept)
+			carried = append(carried, segment.kept.ChunkIndex)
+			continue
+		}
+		region := &ChunkResult{Report: report}
+		var usage TokenUsage
+		region.Chunks, usage, err = c.chunkRegion(segment.region.document(pages), segment.region, filename, inputType == InputPPTX, useAI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create chunks: %w", err)
+		}
+		if err := c.finishChunks(region, name); err != nil {
+			return nil, err
+		}
+		result.TokenUsage.PromptTokens += usage.PromptTokens
+		result.TokenUsage.CompletionTokens += usage.CompletionTokens
+		result.TokenUsage.TotalTokens += usage.TotalTokens
+		result.LowQuality += region.LowQuality
+		result.Duplicates += region.Duplicates
+		result.dedupChecks = append(result.dedupChecks, region.dedupChecks...)
+		result.Chunks = append(result.Chunks, region.Chunks...)
+		carried = append(carried, make([]int, len(region.Chunks))...)
+		update.Rechunked += len(region.Chunks)
+	}
+	schema.Renumber(result.Chunks)
+	update.Operations = updateOperations(previous, result.Chunks, carried)
+
+	if result.Invoice, err = c.extractInvoice(document, filename, useAI, &result.TokenUsage); err != nil {
+		return nil, err
+	}
+	c.describeDocument(result, document, filename, name)
+	if err := c.applySinks(update.Operations); err != nil {
+		return nil, err
+	}
+	if err := c.saveOutput(result, document, filename, name, outputType); err != nil {
+		return nil, err
+	}
+	c.commitDedup(result.dedupChecks)
+	return update, nil
+}
+
+// textRegion is a part of a document to chunk again, from start up to end
+type textRegion struct {
+	start, end position
+}
+
+// updateSegment is a chunk carried over from the previous version, or a region of the
+// new version to chunk again
+type updateSegment struct {
+	kept   *ChunkData
+	region textRegion
+}
+
+// planUpdate splits the new version of a document into chunks carried over from the
+// previous version and regions to chunk again, and records the changed and removed
+// pages. A previous chunk is carried over when none of its pages changed; a region runs
+// from the end of one carried chunk to the start of the next, and is chunked again when
+// it held a chunk that was not carried over or has a changed page. Page numbers are part
+// of chunks, so pages inserted or removed change every page after them.
+func (c *Chunker) planUpdate(previous *ChunkResult, pages []processor.Page, update *UpdateResult) []updateSegment {
+	whole := []updateSegment{{region: textRegion{start: position{1, 0}, end: endOf(pages)}}}
+	changed := make([]bool, len(pages))
+	for i := range pages {
+		changed[i] = previous == nil || i >= len(previous.PageHashes) || previous.PageHashes[i] != update.PageHashes[i]
+		if changed[i] {
+			update.ChangedPages = append(update.ChangedPages, i+1)
+		}
+	}
+	if previous != nil {
+		for page := len(pages) + 1; page <= len(previous.PageHashes); page++ {
+			update.RemovedPages = append(update.RemovedPages, page)
+		}
+	}
+	if previous == nil || len(previous.PageHashes) == 0 {
+		return whole
+	}
+	for i, page := range pages {
+		if page.Number != i+1 {
+			return whole // Pages without numbers cannot be matched to chunks
+		}
+	}
+
+	unchanged := func(first, last int) bool {
+		if first < 1 || last < first || last > len(pages) {
+			return false
+		}
+		return !slices.Contains(changed[first-1:last], true)
+	}
+	var segments []updateSegment
+	cursor, dropped := position{1, 0}, false
+	addRegion := func(end position) {
+		if start := skipSpace(pages, cursor); start.before(end) && (dropped || !unchanged(cursor.page, end.page)) {
+			segments = append(segments, updateSegment{region: textRegion{start: start, end: end}})
+		}
+	}
+	for i := range previous.Chunks {
+		chunk := previous.Chunks[i]
+		if !unchanged(chunk.StartPage, chunk.EndPage) {
+			dropped = true // Also chunks not located in the document, which have no pages
+			continue
+		}
+		addRegion(position{chunk.StartPage, chunk.StartOffset}) // Nothing when it overlaps the previous chunk
+		segments = append(segments, updateSegment{kept: &chunk})
+		if end := (position{chunk.EndPage, chunk.EndOffset}); cursor.before(end) {
+			cursor = end
+		}
+		dropped = false
+	}
+	addRegion(endOf(pages))
+	return segments
+}
+
+// skipSpace returns the position of the first character at or after p that is not
+// whitespace, so chunks of a region start where they would in the whole document
+func skipSpace(pages []processor.Page, p position) position {
+	for p.page <= len(pages) {
+		text := pages[p.page-1].Text
+		rest := text[byteOffset(text, p.offset):]
+		trimmed := strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if trimmed != "" {
+			p.offset += utf8.RuneCountInString(rest) - utf8.RuneCountInString(trimmed)
+			return p
+		}
+		p = position{p.page + 1, 0}
+	}
+	return p
+}
+
+// endOf returns the position just past the text of the last page
+func endOf(pages []processor.Page) position {
+	if len(pages) == 0 {
+		return position{1, 0}
+	}
+	return position{len(pages), utf8.RuneCountInString(pages[len(pages)-1].Text)}
+}
+
+// document returns the text of a region as a document of its own, with the pages it
+// overlaps cut to the region. A region starting within a page leaves out its separator,
+// as the chunks of the whole document would.
+func (r textRegion) document(pages []processor.Page) pagedText {
+	var regionPages []processor.Page
+	for number := r.start.page; number <= r.end.page && number <= len(pages); number++ {
+		page := pages[number-1]
+		if number == r.end.page {
+			if r.end.offset == 0 && number > r.start.page {
+				break
+			}
+			page.Text = page.Text[:byteOffset(page.Text, r.end.offset)]
+		}
+		if number == r.start.page {
+			page.Text = page.Text[byteOffset(page.Text, r.start.offset):]
+		}
+		regionPages = append(regionPages, page)
+	}
+	return joinPages(regionPages, r.start.offset > 0)
+}
+
+// byteOffset returns the byte offset of the character at a character offset of text
+func byteOffset(text string, offset int) int {
+	for i := range text {
+		if offset == 0 {
+			return i
+		}
+		offset--
+	}
+	return len(text)
+}
+
+// chunkRegion chunks the text of a region like a document, and moves the offsets of its
+// chunks on the first page of the region to that page of the whole document
+func (c *Chunker) chunkRegion(document pagedText, region textRegion, filename string, pageGroups, useAI bool) ([]ChunkData, TokenUsage, error) {
+	if strings.TrimSpace(document.text) == "" {
+		return nil, TokenUsage{}, nil
+	}
+	chunks, usage, err := c.createChunksWithUsage(document, filename, pageGroups, useAI)
+	if err != nil {
+		return nil, usage, err
+	}
+	for i := range chunks {
+		if chunks[i].StartPage == region.start.page {
+			chunks[i].StartOffset += region.start.offset
+		}
+		if chunks[i].EndPage == region.start.page {
+			chunks[i].EndOffset += region.start.offset
+		}
+	}
+	return chunks, usage, nil
+}
+
+// updateOperations returns the operations turning the previous chunks of a document into
+// the new ones: an upsert for every new chunk, except chunks carried over unchanged at
+// the same index, and a delete for every previous index past the new chunks
+func updateOperations(previous *ChunkResult, chunks []ChunkData, carried []int) []ChunkOp {
+	before := make(map[int]ChunkData)
+	if previous != nil {
+		for _, chunk := range previous.Chunks {
+			before[chunk.ChunkIndex] = chunk
+		}
+	}
+
+	var ops []ChunkOp
+	for i, chunk := range chunks {
+		old, ok := before[chunk.ChunkIndex]
+		if carried[i] == chunk.ChunkIndex && ok && old.Text == chunk.Text {
+			continue
+		}
+		ops = append(ops, ChunkOp{Op: OpUpsert, Chunk: chunk})
+	}
+	var deleted []int
+	for index := range before {
+		if index > len(chunks) {
+			deleted = append(deleted, index)
+		}
+	}
+	slices.Sort(deleted)
+	for _, index := range deleted {
+		old := before[index]
+		ops = append(ops, ChunkOp{Op: OpDelete, Chunk: ChunkData{Filename: old.Filename, Slug: old.Slug, ChunkIndex: index}})
+	}
+	return ops
+}
+
+// applySinks passes the operations of a document update to every configured sink: to
+// Apply for an UpdateSink, and the upserted chunks to Write for other sinks
+func (c *Chunker) applySinks(ops []ChunkOp) error {
+	var upserts []ChunkData
+	deletes := 0
+	for _, op := range ops {
+		if op.Op == OpUpsert {
+			upserts = append(upserts, op.Chunk)
+		} else {
+			deletes++
+		}
+	}
+	for _, sink := range c.sinks {
+		var err error
+		if updateSink, ok := sink.(UpdateSink); ok {
+			err = updateSink.Apply(ops)
+		} else {
+			if deletes > 0 {
+				c.logger.Printf("Warning: sink %s cannot delete chunks; %d removed chunks remain in it", sink.GetName(), deletes)
+			}
+			if len(upserts) > 0 {
+				err = sink.Write(upserts)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write chunks to sink %s: %w", sink.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// LoadResult reads the chunk JSON files of the manifest into a result with its page
+// hashes, the previous version for ChunkUpdate. Relative paths are resolved against dir,
+// the directory the run was started in, as in Verify.
+func (m *Manifest) LoadResult(dir string) (*ChunkResult, error) {
+	result := &ChunkResult{Pages: m.Pages, PageHashes: m.PageHashes, TokenUsage: m.TokenUsage}
+	var files map[string][]byte
+	if m.Archive != "" {
+		var err error
+		if files, err = readArchive(resolvePath(dir, m.Archive)); err != nil {
+			return nil, fmt.Errorf("chunk archive unreadable: %w", err)
+		}
+	}
+	for _, entry := range m.Chunks {
+		var chunk ChunkData
+		var err error
+		if files != nil {
+			data, ok := files[entry.JSONFile]
+			if !ok {
+				return nil, fmt.Errorf("chunk file %s missing from %s", entry.JSONFile, m.Archive)
+			}
+			chunk, err = schema.Unmarshal(data)
+		} else {
+			chunk, err = schema.ReadFile(resolvePath(dir, entry.JSONFile))
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Chunks = append(result.Chunks, chunk)
+	}
+	return result, nil
+}
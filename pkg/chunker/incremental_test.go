@@ -0,0 +1,135 @@
+package chunker_test
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+)
+
+// pagedDocument returns a text document of pages separated by "--- Page N ---" lines
+func pagedDocument(pages ...string) string {
+	var text strings.Builder
+	for i, page := range pages {
+		fmt.Fprintf(&text, "\n\n--- Page %d ---\n\n%s", i+1, page)
+	}
+	return text.String()
+}
+
+// handbookPages returns pages of a few paragraphs, each longer than a local chunk
+func handbookPages(n int) []string {
+	pages := make([]string, n)
+	for i := range pages {
+		var page strings.Builder
+		for paragraph := 1; paragraph <= 3; paragraph++ {
+			fmt.Fprintf(&page, "Section %d.%d of the handbook explains the leave policy of the company in detail. Employees keep their leave days for one year after they are granted.\n\n", i+1, paragraph)
+		}
+		pages[i] = page.String()
+	}
+	return pages
+}
+
+// applyOps applies update operations to the chunk texts of a store, by chunk index
+func applyOps(store map[int]string, ops []chunker.ChunkOp) {
+	for _, op := range ops {
+		if op.Op == chunker.OpDelete {
+			delete(store, op.Chunk.ChunkIndex)
+		} else {
+			store[op.Chunk.ChunkIndex] = op.Chunk.Text
+		}
+	}
+}
+
+// TestChunkUpdate checks the chunks and operations of ChunkUpdate for an unchanged
+// document, text inserted at its start and its last page deleted: applying the
+// operations to the previous chunks must give the new ones, and chunks away from the
+// change must be carried over
+func TestChunkUpdate(t *testing.T) {
+	cfg := chunkertest.Config(t)
+	cfg.TextPageSeparators = true
+	cfg.PlainText = true // Chunks do not state their total, so they only change with their text
+	cfg.LocalChunkSize = 200
+	instance := chunker.NewChunker(chunker.WithConfig(cfg))
+	defer instance.Close()
+
+	pages := handbookPages(4)
+	previous, err := instance.ChunkInputWithUsage(chunker.InputString, pagedDocument(pages...), chunker.OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previous.Chunks) != 6 {
+		t.Fatalf("previous version has %d chunks, want 6", len(previous.Chunks))
+	}
+	const insertion = "A new first paragraph about the scope of the handbook."
+	inserted := slices.Clone(pages)
+	inserted[0] = insertion + "\n\n" + inserted[0]
+
+	tests := []struct {
+		name             string
+		pages            []string
+		wantChanged      []int
+		wantRemoved      []int
+		wantUntouched    []int // Indexes of previous chunks no operation touches
+		wantMaxRechunked int
+	}{
+		{"unchanged", pages, nil, nil, []int{1, 2, 3, 4, 5, 6}, 0},
+		{"insertion at the start", inserted, []int{1}, nil, nil, 3},
+		{"deletion at the end", pages[:3], nil, []int{4}, []int{1, 2, 3}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			update, err := instance.ChunkUpdate(chunker.InputString, pagedDocument(test.pages...), chunker.OutputJSON, previous)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.wantChanged != nil && !slices.Equal(update.ChangedPages, test.wantChanged) {
+				t.Errorf("changed pages = %v, want %v", update.ChangedPages, test.wantChanged)
+			}
+			if !slices.Equal(update.RemovedPages, test.wantRemoved) {
+				t.Errorf("removed pages = %v, want %v", update.RemovedPages, test.wantRemoved)
+			}
+			if test.wantMaxRechunked == 0 && len(update.Operations) > 0 {
+				t.Errorf("%d operations, want none when nothing was re-chunked", len(update.Operations))
+			}
+			if update.Rechunked > test.wantMaxRechunked {
+				t.Errorf("%d chunks re-chunked, want at most %d", update.Rechunked, test.wantMaxRechunked)
+			}
+
+			store := make(map[int]string)
+			for _, chunk := range previous.Chunks {
+				store[chunk.ChunkIndex] = chunk.Text
+			}
+			applyOps(store, update.Operations)
+			want := make(map[int]string)
+			for _, chunk := range update.Chunks {
+				want[chunk.ChunkIndex] = chunk.Text
+			}
+			if !reflect.DeepEqual(store, want) {
+				t.Errorf("previous chunks with the operations applied = %q, want the new chunks %q", store, want)
+			}
+			for _, op := range update.Operations {
+				if slices.Contains(test.wantUntouched, op.Chunk.ChunkIndex) {
+					t.Errorf("%s of chunk %d, want it untouched", op.Op, op.Chunk.ChunkIndex)
+				}
+			}
+		})
+	}
+
+	// Text inserted at the start moves the chunks after it to the next indexes, carried
+	// over rather than chunked again
+	update, err := instance.ChunkUpdate(chunker.InputString, pagedDocument(inserted...), chunker.OutputJSON, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(update.Chunks[0].Text, insertion) {
+		t.Errorf("first chunk = %.60q, want it to start with the inserted text", update.Chunks[0].Text)
+	}
+	last := update.Chunks[len(update.Chunks)-1]
+	if last.ChunkIndex != 7 || last.Text != previous.Chunks[5].Text {
+		t.Errorf("last chunk %d = %.60q, want chunk 6 of the previous version as chunk 7", last.ChunkIndex, last.Text)
+	}
+}
@@ -0,0 +1,42 @@
+package chunker
+
+import (
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/invoice"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/providers"
+)
+
+// InvoiceAIProvider is an AI provider that can extract the fields of invoices for
+// ProfileInvoice: ExtractInvoice answers with the JSON of invoice.Parse. The built-in
+// providers implement it; without it the fields are extracted with regular expressions.
+type InvoiceAIProvider interface {
+	AIProvider
+	ExtractInvoice(text string) (*providers.ChunkResult, error)
+}
+
+// extractInvoice extracts the key fields of a document for ProfileInvoice, asking the AI
+// provider when useAI is set and it implements InvoiceAIProvider, and with regular
+// expressions otherwise or when its answer is unusable. The reported token usage is added
+// to usage. Only audit log failures are returned as errors.
+func (c *Chunker) extractInvoice(document pagedText, filename string, useAI bool, usage *TokenUsage) (*invoice.Invoice, error) {
+	if c.config.Profile != config.ProfileInvoice {
+		return nil, nil
+	}
+	if _, ok := c.aiProvider.(InvoiceAIProvider); !useAI || !ok {
+		return invoice.Extract(document.text), nil
+	}
+
+	answer, err := c.askAI(document.text, filename, 0, aiInvoice, usage)
+	if err != nil {
+		return nil, err
+	}
+	var extracted *invoice.Invoice
+	if answer.err == nil {
+		extracted, answer.err = invoice.Parse(answer.text)
+	}
+	if answer.err != nil {
+		c.logger.Printf("Warning: no invoice fields from the AI for %s (%v), extracting them locally", filename, answer.err)
+		return invoice.Extract(document.text), nil
+	}
+	return extracted, nil
+}
@@ -0,0 +1,141 @@
+package chunker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/providers"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// Errors returned for documents over the MaxFileSizeMB, MaxPages and MaxProcessingTime
+// limits, wrapped in a *LimitError; test for them with errors.Is
+var (
+	ErrFileTooLarge      = processor.ErrFileTooLarge
+	ErrTooManyPages      = processor.ErrTooManyPages
+	ErrProcessingTimeout = processor.ErrProcessingTimeout
+)
+
+// LimitError reports a document rejected by a configured limit
+type LimitError = processor.LimitError
+
+// Limits of unpacked archives with MaxFileSizeMB, so a small decompression bomb cannot
+// fill the disk: the files inside may add up to archiveExpansion times MaxFileSizeMB
+const (
+	archiveExpansion  = 10
+	maxArchiveEntries = 1000
+)
+
+// WithContext returns a copy of the chunker whose documents fail with the error of ctx
+// once it is done: page extraction and AI slices stop, OCR in progress is killed, and
+// the requests of ChatGPTProvider, MistralProvider and CohereProvider are cancelled.
+// Other providers finish the request in flight. The copy shares everything else with
+// c: close c, not the copy.
+func (c *Chunker) WithContext(ctx context.Context) *Chunker {
+	document := *c
+	document.ctx = ctx
+	if c.aiProvider != nil {
+		document.aiProvider = providerWithContext(c.aiProvider, ctx).(AIProvider)
+	}
+	if c.embedder != nil {
+		document.embedder = providerWithContext(c.embedder, ctx).(EmbeddingProvider)
+	}
+	return &document
+}
+
+// providerWithContext returns a copy of a built-in AI or embedding provider whose
+// requests are sent with ctx, and other providers as they are
+func providerWithContext(provider any, ctx context.Context) any {
+	switch p := provider.(type) {
+	case *providers.ChatGPTProvider:
+		return p.WithContext(ctx)
+	case *providers.MistralProvider:
+		return p.WithContext(ctx)
+	case *providers.CohereProvider:
+		return p.WithContext(ctx)
+	}
+	return provider
+}
+
+// forDocument returns the chunker to process a single document with: a copy whose
+// MaxProcessingTime deadline starts now, whose PDF processor stops with its context and
+// whose progress reports count the pages of this document, or c itself when there is
+// no time limit, context or progress to report
+func (c *Chunker) forDocument() *Chunker {
+	if c.config.MaxProcessingTime <= 0 && c.ctx == nil && c.progress == nil {
+		return c
+	}
+	document := *c
+	options := processor.ExtractOptions{Context: c.ctx}
+	if c.config.MaxProcessingTime > 0 {
+		document.deadline = time.Now().Add(c.config.MaxProcessingTime)
+		options.Deadline = document.deadline
+	}
+	if c.progress != nil {
+		document.trackProgress(&options)
+	}
+	document.pdfProcessor = c.pdfProcessor.WithOptions(options)
+	return &document
+}
+
+// checkDeadline fails with ErrProcessingTimeout once the document deadline has passed,
+// and with the error of the chunker's context once it is done
+func (c *Chunker) checkDeadline() error {
+	if err := c.checkContext(); err != nil {
+		return err
+	}
+	return processor.CheckDeadline(c.deadline, c.config.MaxProcessingTime)
+}
+
+// checkContext fails with the error of the chunker's context once it is done
+func (c *Chunker) checkContext() error {
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Err()
+}
+
+// limitInput fails with ErrFileTooLarge when a file path, string or byte input exceeds
+// MaxFileSizeMB, and wraps reader inputs so they fail once they read past it
+func (c *Chunker) limitInput(inputType InputType, input interface{}) (interface{}, error) {
+	if c.config.MaxFileSizeMB <= 0 {
+		return input, nil
+	}
+
+	switch v := input.(type) {
+	case string:
+		size := int64(len(v))
+		if inputType != InputString {
+			// InputTXT strings that do not name a file are content
+			if info, err := os.Stat(v); err == nil && info.Mode().IsRegular() {
+				size = info.Size()
+			}
+		}
+		return input, processor.CheckSize(size, c.config.MaxFileSizeMB)
+	case []byte:
+		return input, processor.CheckSize(int64(len(v)), c.config.MaxFileSizeMB)
+	case io.Reader:
+		return processor.LimitReader(v, c.config.MaxFileSizeMB), nil
+	default:
+		return input, nil
+	}
+}
+
+// unpackArchive unpacks an archive into dir within the limits MaxFileSizeMB sets,
+// failing with ErrFileTooLarge when it unpacks past them
+func (c *Chunker) unpackArchive(data []byte, dir string) error {
+	if c.config.MaxFileSizeMB <= 0 {
+		return utils.UnpackArchive(data, dir)
+	}
+	maxMB := c.config.MaxFileSizeMB * archiveExpansion
+	err := utils.UnpackArchiveWithLimits(data, dir, utils.ArchiveLimits{MaxBytes: int64(maxMB) << 20, MaxEntries: maxArchiveEntries})
+	if errors.Is(err, utils.ErrArchiveTooLarge) {
+		return &LimitError{Err: ErrFileTooLarge, Limit: fmt.Sprintf("%d MB and %d files unpacked", maxMB, maxArchiveEntries)}
+	}
+	return err
+}
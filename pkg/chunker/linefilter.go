@@ -0,0 +1,31 @@
+package chunker
+
+import (
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/linefilter"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+)
+
+// setupLineFilter compiles the noise line patterns of FilterNoiseLines and
+// NoiseLinePatterns, or makes every document fail when one is invalid
+func (c *Chunker) setupLineFilter() {
+	if !c.config.FilterNoiseLines && len(c.config.NoiseLinePatterns) == 0 {
+		return
+	}
+	filter, err := linefilter.New(c.config.NoiseLinePatterns, c.config.FilterNoiseLines)
+	if err != nil {
+		if c.configErr == nil {
+			c.configErr = err
+			c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+		}
+		return
+	}
+	c.lineFilter = filter
+}
+
+// filterPage drops the noise lines of a page with FilterNoiseLines or NoiseLinePatterns
+func (c *Chunker) filterPage(page processor.Page) processor.Page {
+	if c.lineFilter != nil {
+		page.Text, _ = c.lineFilter.Apply(page.Text)
+	}
+	return page
+}
@@ -0,0 +1,257 @@
+package chunker
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/invoice"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// ManifestFilename is the manifest written to each document's chunk directory
+const ManifestFilename = "manifest.json"
+
+// ThumbnailFilename is the thumbnail written next to the manifest with Thumbnail, with the
+// extension of ThumbnailFormat
+const ThumbnailFilename = "thumbnail"
+
+// Manifest lists the saved output of a document, so loaders can check that it is
+// complete and runs can be reproduced with the same parameters
+type Manifest struct {
+	Filename    string             `json:"filename"`
+	Slug        string             `json:"slug"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Pages       int                `json:"pages"`
+	Engine      string             `json:"engine,omitempty"` // PDF extraction engine, see processor.DocumentReport.Engine
+	OCRPages    int                `json:"ocr_pages"`
+	TokenUsage  TokenUsage         `json:"token_usage"`
+	Parameters  ManifestParameters `json:"parameters"`
+	RawTextFile string             `json:"raw_text_file,omitempty"` // Set for OutputRawText
+	Archive     string             `json:"archive,omitempty"`       // Set with CompressionTarZstd; chunk files are then paths inside it
+	Redactions  *RedactionReport   `json:"redactions,omitempty"`    // Set with RedactPII
+	Outline     []*schema.Heading  `json:"outline,omitempty"`       // See ChunkResult.Outline
+	Invoice     *invoice.Invoice   `json:"invoice,omitempty"`       // See ChunkResult.Invoice
+	Thumbnail   string             `json:"thumbnail,omitempty"`     // Set with Thumbnail for PDFs
+	PageHashes  []string           `json:"page_hashes,omitempty"`   // See ChunkResult.PageHashes
+	Chunks      []ManifestChunk    `json:"chunks"`
+
+	toc       *schema.TOC      // Written to TOCFilename and TOCMarkdownFilename next to the manifest
+	elements  []schema.Element // Written to ElementsFilename next to the manifest
+	thumbnail []byte           // Written to ThumbnailFilename next to the manifest
+}
+
+// ManifestParameters records the settings a document was chunked with
+type ManifestParameters struct {
+	AIProvider     string   `json:"ai_provider,omitempty"` // Empty when the document was chunked locally
+	MaxChunkSize   int      `json:"max_chunk_size"`
+	LocalChunkSize int      `json:"local_chunk_size"`
+	SizeUnit       string   `json:"size_unit,omitempty"` // Unit of both chunk sizes, empty for characters
+	OCRLanguages   []string `json:"ocr_languages"`
+	OCRDPI         float64  `json:"ocr_dpi"`
+}
+
+// ManifestChunk lists the files of a single chunk. Paths are as saved, with / separators.
+type ManifestChunk struct {
+	ChunkIndex int    `json:"chunk_index"`
+	PageRange  string `json:"page_range"`
+	StartPage  int    `json:"start_page,omitempty"`
+	EndPage    int    `json:"end_page,omitempty"`
+	TextFile   string `json:"text_file"`
+	TextSHA256 string `json:"text_sha256"`
+	JSONFile   string `json:"json_file"`
+	JSONSHA256 string `json:"json_sha256"`
+}
+
+// LoadManifest reads a manifest written with OutputFile, OutputBoth or OutputRawText,
+// either a manifest.json or the .tar.zst archive holding it
+func LoadManifest(path string) (*Manifest, error) {
+	var data []byte
+	var err error
+	if strings.HasSuffix(path, ".tar.zst") {
+		var files map[string][]byte
+		files, err = readArchive(path)
+		data = files[ManifestFilename]
+		if err == nil && data == nil {
+			err = fmt.Errorf("no %s in %s", ManifestFilename, path)
+		}
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Verify checks that every chunk file listed in the manifest exists with the recorded
+// hash. Relative paths are resolved against dir, the directory the run was started in.
+func (m *Manifest) Verify(dir string) error {
+	if m.Archive != "" {
+		return m.verifyArchive(dir)
+	}
+	for _, chunk := range m.Chunks {
+		if err := verifyFile(dir, chunk.TextFile, chunk.TextSHA256); err != nil {
+			return err
+		}
+		if err := verifyFile(dir, chunk.JSONFile, chunk.JSONSHA256); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newManifest starts the manifest of a document; chunk files are added as they are saved
+func (c *Chunker) newManifest(result *ChunkResult, filename, name string) *Manifest {
+	manifest := &Manifest{
+		Filename:   filename,
+		Slug:       name,
+		CreatedAt:  time.Now().UTC(),
+		Pages:      result.Pages,
+		PageHashes: result.PageHashes,
+		TokenUsage: result.TokenUsage,
+		Parameters: ManifestParameters{
+			MaxChunkSize:   c.config.MaxChunkSize,
+			LocalChunkSize: c.config.LocalChunkSize,
+			SizeUnit:       c.config.SizeUnit,
+			OCRLanguages:   c.config.OCRLanguages,
+			OCRDPI:         c.config.OCRDPI,
+		},
+	}
+	if c.aiProvider != nil && !result.BudgetExceeded {
+		manifest.Parameters.AIProvider = c.aiProvider.GetName()
+	}
+	manifest.Redactions = result.Redactions
+	manifest.Outline = result.Outline
+	manifest.Invoice = result.Invoice
+	manifest.toc = result.TOC
+	manifest.elements = result.Elements
+	if result.Report != nil {
+		manifest.Engine = result.Report.Engine
+		manifest.OCRPages = result.Report.OCRPages
+		manifest.thumbnail = result.Report.Thumbnail
+	}
+	return manifest
+}
+
+// thumbnailFilename returns the file name of a document's thumbnail
+func (c *Chunker) thumbnailFilename() string {
+	return ThumbnailFilename + processor.ImageExt(c.config.ThumbnailFormat)
+}
+
+// newManifestChunk returns the manifest entry of a chunk without its files
+func newManifestChunk(chunk ChunkData) ManifestChunk {
+	return ManifestChunk{
+		ChunkIndex: chunk.ChunkIndex,
+		PageRange:  chunk.PageRange,
+		StartPage:  chunk.StartPage,
+		EndPage:    chunk.EndPage,
+	}
+}
+
+// save writes the manifest to path
+func (m *Manifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+	return nil
+}
+
+// verifyFile checks that the file at path has the expected SHA-256
+func verifyFile(dir, path, expected string) error {
+	path = resolvePath(dir, path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("chunk file missing: %w", err)
+	}
+	if actual := sha256Hex(data); actual != expected {
+		return fmt.Errorf("chunk file %s has hash %s, manifest lists %s", path, actual, expected)
+	}
+	return nil
+}
+
+// verifyArchive checks the chunk files of a CompressionTarZstd manifest in its archive
+func (m *Manifest) verifyArchive(dir string) error {
+	path := resolvePath(dir, m.Archive)
+	files, err := readArchive(path)
+	if err != nil {
+		return fmt.Errorf("chunk archive unreadable: %w", err)
+	}
+
+	for _, chunk := range m.Chunks {
+		for file, expected := range map[string]string{chunk.TextFile: chunk.TextSHA256, chunk.JSONFile: chunk.JSONSHA256} {
+			data, ok := files[file]
+			if !ok {
+				return fmt.Errorf("chunk file %s missing from %s", file, path)
+			}
+			if actual := sha256Hex(data); actual != expected {
+				return fmt.Errorf("chunk file %s in %s has hash %s, manifest lists %s", file, path, actual, expected)
+			}
+		}
+	}
+	return nil
+}
+
+// resolvePath resolves a manifest path against dir unless it is absolute
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, filepath.FromSlash(path))
+}
+
+// readArchive returns the files of a .tar.zst archive by name
+func readArchive(path string) (map[string][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := utils.NewDecompressReader(file, utils.CompressionZstd)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	files := make(map[string][]byte)
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = data
+	}
+}
+
+// sha256Hex returns the hex SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package chunker
+
+import (
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// sourceMixed is the source of chunks spanning pages of text and OCR
+const sourceMixed = "mixed"
+
+// chunkMetadata returns the metadata found in the source text of a chunk, or in its
+// text when the chunk could not be located: document codes, dates, title and direction
+// (see utils.ChunkMetadata), and "source", where the text of its pages came from
+// (processor.SourceText, processor.SourceOCR or "mixed")
+func chunkMetadata(document pagedText, s span, text string) map[string]any {
+	if s.start >= 0 && s.end <= len(document.text) {
+		text = document.text[s.start:s.end]
+	}
+	metadata := utils.ChunkMetadata(text)
+	if source := document.source(s); source != "" {
+		metadata["source"] = source
+	}
+	return metadata
+}
+
+// source returns where the text of the pages of a span came from, or "" when the span
+// could not be located
+func (p pagedText) source(s span) string {
+	if s.start < 0 || len(p.pages) == 0 {
+		return ""
+	}
+	var source processor.PageSource
+	for i, page := range p.pages {
+		end := len(p.text)
+		if i+1 < len(p.starts) {
+			end = p.starts[i+1]
+		}
+		if end <= s.start || p.starts[i] >= s.end {
+			continue
+		}
+		switch {
+		case source == "":
+			source = page.Source
+		case source != page.Source:
+			return sourceMixed
+		}
+	}
+	return string(source)
+}
+
+// completeChunks adds the section of every chunk to its metadata: its paper section,
+// regulation citation or structure-mode title. With PlainText it also strips the page
+// separators left in verbatim chunks, such as those of AIModeStructure.
+func (c *Chunker) completeChunks(chunks []ChunkData) {
+	for i := range chunks {
+		chunk := &chunks[i]
+		if c.config.PlainText {
+			chunk.Text = utils.PlainText(chunk.Text)
+		}
+		section := chunk.Section
+		if section == "" && chunk.Citation != nil {
+			section = chunk.Citation.Text
+		}
+		if section == "" {
+			section = chunk.Title
+		}
+		if _, ok := chunk.Metadata["section"]; section == "" || ok {
+			continue
+		}
+		if chunk.Metadata == nil {
+			chunk.Metadata = make(map[string]any)
+		}
+		chunk.Metadata["section"] = section
+	}
+}
+
+// renderedText returns the RenderedText of a chunk with PlainText, laid out like the
+// formatter would have, or "" otherwise
+func (c *Chunker) renderedText(text, pageRange, citation string, index, total int) string {
+	if !c.config.PlainText {
+		return ""
+	}
+	return c.textProcessor.RenderCitedChunk(text, pageRange, citation, index, total)
+}
@@ -0,0 +1,78 @@
+package chunker
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ocrfix"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+)
+
+// setupOCRCorrection loads the words of OCRCorrection for OCRLanguages and
+// OCRDictionaryPath, or makes every document fail when the dictionary cannot be read
+func (c *Chunker) setupOCRCorrection() {
+	if !c.config.OCRCorrection {
+		return
+	}
+	c.ocrDictionary = ocrfix.Builtin(c.config.OCRLanguages)
+	if c.config.OCRDictionaryPath == "" {
+		return
+	}
+	dictionary, err := readOCRDictionary(c.config.OCRDictionaryPath)
+	if err != nil {
+		if c.configErr == nil {
+			c.configErr = err
+			c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+		}
+		return
+	}
+	for word, count := range dictionary {
+		c.ocrDictionary[word] += count
+	}
+}
+
+// readOCRDictionary reads the dictionary file of OCRDictionaryPath
+func readOCRDictionary(path string) (ocrfix.Dictionary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OCR dictionary: %w", err)
+	}
+	defer file.Close()
+	dictionary, err := ocrfix.ReadDictionary(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OCR dictionary %s: %w", path, err)
+	}
+	return dictionary, nil
+}
+
+// correctPages corrects the OCR errors of the OCR pages with OCRCorrection, knowing the
+// dictionary words and the words the pages themselves repeat, and returns the number of
+// corrections
+func (c *Chunker) correctPages(pages []processor.Page) int {
+	if c.ocrDictionary == nil {
+		return 0
+	}
+	document := ocrfix.Dictionary{}
+	ocrPages := 0
+	for _, page := range pages {
+		document.AddText(page.Text)
+		if page.Source == processor.SourceOCR {
+			ocrPages++
+		}
+	}
+	if ocrPages == 0 {
+		return 0
+	}
+
+	corrector := ocrfix.NewCorrector(c.ocrDictionary, document)
+	corrections := 0
+	for i, page := range pages {
+		if page.Source != processor.SourceOCR {
+			continue
+		}
+		var n int
+		pages[i].Text, n = corrector.Correct(page.Text)
+		corrections += n
+	}
+	return corrections
+}
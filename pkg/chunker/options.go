@@ -0,0 +1,216 @@
+package chunker
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/audit"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/container"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ocr"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ratelimit"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// Option configures a Chunker created with NewChunker
+type Option func(*Chunker)
+
+// Strategy splits the consolidated text of a document into the slices that become
+// chunks. maxSize is MaxChunkSize for AI chunking and LocalChunkSize otherwise, in
+// the configured SizeUnit.
+// Slices must be cut from text in order so their page ranges can be located.
+type Strategy interface {
+	Split(text string, maxSize int) []string
+	GetName() string
+}
+
+// Sink receives the chunks of every processed document, e.g. to publish them to a
+// queue or a store, in addition to the configured output type. Batch runs with several
+// Workers call Write concurrently.
+type Sink interface {
+	Write(chunks []ChunkData) error
+	GetName() string
+}
+
+// FlushSink is a Sink that buffers chunks; batch runs call Flush when they finish or
+// are stopped
+type FlushSink interface {
+	Sink
+	Flush() error
+}
+
+// Coordinator shares the files of batch runs between chunker instances, so instances on
+// several machines can process one input directory or bucket without processing a file
+// twice; see coord.Redis. Files are identified by name and content hash, so a file that
+// changed is processed again.
+type Coordinator interface {
+	// Claim claims a file for this instance; false when another instance processed the
+	// file or is processing it
+	Claim(filename, sha256 string) (bool, error)
+	// Finish releases the claim with the file's result. Files that did not fail are
+	// recorded as processed; failed ones are left for another run to retry.
+	Finish(filename, sha256 string, result FileResult) error
+}
+
+// Logger receives warnings and per-file errors; *log.Logger satisfies it
+type Logger = processor.Logger
+
+// Tokenizer splits text into sentences and words for the built-in splitters, see
+// utils.Tokenizer
+type Tokenizer = utils.Tokenizer
+
+// WithConfig sets the chunker configuration (default: config.DefaultConfig())
+func WithConfig(config config.ChunkerConfig) Option {
+	return func(c *Chunker) {
+		c.config = config
+	}
+}
+
+// WithProvider sets the AI provider; without one, chunks are created locally
+func WithProvider(provider AIProvider) Option {
+	return func(c *Chunker) {
+		c.aiProvider = provider
+	}
+}
+
+// WithEmbeddingProvider sets the provider that fills ChunkData.Embedding before chunks
+// are saved or passed to sinks; without one, chunks have no embedding
+func WithEmbeddingProvider(provider EmbeddingProvider) Option {
+	return func(c *Chunker) {
+		c.embedder = provider
+	}
+}
+
+// WithValidators checks every AI output with validators, instead of the
+// DefaultValidators of ValidateAI. Rejected outputs are retried once with a stricter
+// prompt and then chunked locally.
+func WithValidators(validators ...Validator) Option {
+	return func(c *Chunker) {
+		c.validators = append([]Validator{}, validators...)
+	}
+}
+
+// WithStrategy replaces the built-in text splitting
+func WithStrategy(strategy Strategy) Option {
+	return func(c *Chunker) {
+		c.strategy = strategy
+	}
+}
+
+// WithTokenizer cuts lines longer than a chunk with tokenizer instead of
+// utils.UnicodeTokenizer, e.g. a dictionary-based tokenizer for Thai
+func WithTokenizer(tokenizer Tokenizer) Option {
+	return func(c *Chunker) {
+		c.tokenizer = tokenizer
+	}
+}
+
+// WithChunkTemplate formats chunks with tmpl, executed with a utils.ChunkView, instead
+// of the built-in Markdown layout; it takes precedence over ChunkTemplatePath. Add
+// utils.TemplateFuncs to tmpl to use them.
+func WithChunkTemplate(tmpl *template.Template) Option {
+	return func(c *Chunker) {
+		c.chunkTemplate = tmpl
+	}
+}
+
+// WithSink adds a sink that receives every document's chunks; it may be given more than once
+func WithSink(sink Sink) Option {
+	return func(c *Chunker) {
+		c.sinks = append(c.sinks, sink)
+	}
+}
+
+// WithLogger sets the logger for warnings (default: log.Default())
+func WithLogger(logger Logger) Option {
+	return func(c *Chunker) {
+		c.logger = logger
+	}
+}
+
+// WithRateLimiter shares limiter for AI provider calls, e.g. between several chunkers;
+// by default the chunker creates one from AIRequestsPerMinute and AITokensPerMinute
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Chunker) {
+		c.limiter = limiter
+	}
+}
+
+// WithAuditLog writes every AI request to log, e.g. to share one log between several
+// chunkers; by default the chunker opens AuditLogPath
+func WithAuditLog(log *audit.Log) Option {
+	return func(c *Chunker) {
+		c.auditLog = log
+	}
+}
+
+// WithOCREngine replaces Tesseract as the OCR engine for PDF pages
+func WithOCREngine(engine ocr.Engine) Option {
+	return func(c *Chunker) {
+		c.ocrEngine = engine
+	}
+}
+
+// WithContainer reads, renders and OCRs PDFs with mutool and tesseract in container
+// instead of on this machine, as DockerImage does with a container of its own. The
+// container must mount tempfile.Root(TempDir) and is left to the caller to close, so
+// chunkers can share it.
+func WithContainer(container *container.Container) Option {
+	return func(c *Chunker) {
+		c.container = container
+	}
+}
+
+// WithCoordinator shares the files of ChunkDirectory and ChunkArchive runs with the
+// other chunker instances of coordinator, each processing the files it claims first.
+// Files another instance claimed are reported with FileStatusElsewhere.
+func WithCoordinator(coordinator Coordinator) Option {
+	return func(c *Chunker) {
+		c.coordinator = coordinator
+	}
+}
+
+// WithFileFilter makes ChunkDirectory, ChunkArchive and RetryFailed process only the
+// supported files accept returns true for, by the filename of their FileResult, e.g.
+// "report.pdf" or "sub/notes.docx"
+func WithFileFilter(accept func(filename string) bool) Option {
+	return func(c *Chunker) {
+		c.fileFilter = accept
+	}
+}
+
+// WithPDFFallbacks sets the extractors tried in order when MuPDF cannot open or read a
+// PDF (default: poppler's pdftotext); with none, such PDFs fail
+func WithPDFFallbacks(extractors ...processor.TextExtractor) Option {
+	return func(c *Chunker) {
+		// Non-nil even when empty, so no extractors disables the default
+		c.pdfFallbacks = append([]processor.TextExtractor{}, extractors...)
+	}
+}
+
+// WithPDFRepairers sets the repairers tried in order on damaged PDFs when RepairPDF is
+// set (default: processor.DefaultRepairers())
+func WithPDFRepairers(repairers ...processor.Repairer) Option {
+	return func(c *Chunker) {
+		c.pdfRepairers = append([]processor.Repairer{}, repairers...)
+	}
+}
+
+// WithPDFSplitters sets the splitters tried in order to write the page ranges of SplitPDF
+// (default: processor.DefaultSplitters())
+func WithPDFSplitters(splitters ...processor.PageSplitter) Option {
+	return func(c *Chunker) {
+		c.pdfSplitters = append([]processor.PageSplitter{}, splitters...)
+	}
+}
+
+// writeSinks passes a document's chunks to every configured sink
+func (c *Chunker) writeSinks(chunks []ChunkData) error {
+	for _, sink := range c.sinks {
+		if err := sink.Write(chunks); err != nil {
+			return fmt.Errorf("failed to write chunks to sink %s: %w", sink.GetName(), err)
+		}
+	}
+	return nil
+}
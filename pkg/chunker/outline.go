@@ -0,0 +1,75 @@
+package chunker
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// locatedHeading is a heading of a document with where it starts
+type locatedHeading struct {
+	level  int
+	title  string
+	page   int
+	offset int  // Character offset of the heading in the page text
+	lost   bool // A bookmark found on no page, so page and offset are unknown
+}
+
+// documentHeadings returns the numbered headings of a document in order (see
+// utils.TextProcessor.HeadingLevel). Repeated headings, such as running page headers,
+// are listed once.
+func (c *Chunker) documentHeadings(document pagedText) []locatedHeading {
+	var headings []locatedHeading
+	seen := make(map[string]bool)
+	for _, page := range document.pages {
+		offset := 0
+		for _, line := range strings.SplitAfter(page.Text, "\n") {
+			title := strings.TrimSpace(line)
+			if level := c.textProcessor.HeadingLevel(title); level > 0 && !seen[title] {
+				seen[title] = true
+				headings = append(headings, locatedHeading{level: level, title: title, page: page.Number, offset: offset})
+			}
+			offset += utf8.RuneCountInString(line)
+		}
+	}
+	return headings
+}
+
+// documentOutline nests the numbered headings of a document by level and points each at
+// the chunk holding it
+func (c *Chunker) documentOutline(document pagedText, chunks []ChunkData) []*schema.Heading {
+	var outline []*schema.Heading
+	var parents []*schema.Heading // The open heading of each level above the current one
+	for _, located := range c.documentHeadings(document) {
+		heading := &schema.Heading{
+			Level:      located.level,
+			Title:      located.title,
+			Page:       located.page,
+			ChunkIndex: chunkAt(chunks, located.page, located.offset),
+		}
+		for len(parents) > 0 && parents[len(parents)-1].Level >= heading.Level {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) == 0 {
+			outline = append(outline, heading)
+		} else {
+			parent := parents[len(parents)-1]
+			parent.Children = append(parent.Children, heading)
+		}
+		parents = append(parents, heading)
+	}
+	return outline
+}
+
+// chunkAt returns the index of the last chunk starting at or before a character offset
+// of a page, or 0 when no chunk does
+func chunkAt(chunks []ChunkData, page, offset int) int {
+	index := 0
+	for _, chunk := range chunks {
+		if chunk.StartPage < page || chunk.StartPage == page && chunk.StartOffset <= offset {
+			index = chunk.ChunkIndex
+		}
+	}
+	return index
+}
@@ -0,0 +1,242 @@
+package chunker
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// ErrOutputExists is returned under OverwriteError when a document's output directory
+// already exists
+var ErrOutputExists = errors.New("output already exists")
+
+// outputName returns the name a document's files are saved under: the slug of its
+// filename, followed by a short hash of its text with OutputHash. Output types that save
+// files apply OverwritePolicy to the ChunkDir directory of that name; under
+// OverwriteReplace, a name already claimed by another file of the same batch run gets a
+// "-2", "-3"... suffix, as with OverwriteVersion, rather than replacing its output.
+func (c *Chunker) outputName(filename string, document pagedText, outputType OutputType) (string, error) {
+	name := utils.Slug(filename)
+	if c.config.OutputHash {
+		name += "-" + contentHash(document.text)
+	}
+	if outputType == OutputJSON {
+		return name, nil
+	}
+
+	switch c.config.OverwritePolicy {
+	case "", config.OverwriteReplace:
+		if c.outputClaims == nil {
+			return name, nil
+		}
+		return c.outputClaims.claim(name, filename, c.logger), nil
+	case config.OverwriteError:
+		if err := c.reserveOutput(name); err != nil {
+			if os.IsExist(err) {
+				return "", fmt.Errorf("%w: %s", ErrOutputExists, filepath.Join(c.config.ChunkDir, filepath.FromSlash(name)))
+			}
+			return "", err
+		}
+		return name, nil
+	case config.OverwriteVersion:
+		for version := 1; ; version++ {
+			candidate := name
+			if version > 1 {
+				candidate = fmt.Sprintf("%s-%d", name, version)
+			}
+			err := c.reserveOutput(candidate)
+			if err == nil {
+				return candidate, nil
+			}
+			if !os.IsExist(err) {
+				return "", err
+			}
+		}
+	default:
+		return "", fmt.Errorf("unsupported overwrite policy: %q", c.config.OverwritePolicy)
+	}
+}
+
+// outputClaims are the output names taken in a batch run, by the filename that took
+// them, so files whose names slug alike (report.txt and report.md) do not replace each
+// other's output under OverwriteReplace
+type outputClaims struct {
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+// claim returns the output name of filename: name, or name with the first free version
+// suffix when another file of the run already took it. The same filename keeps its name.
+func (o *outputClaims) claim(name, filename string, logger Logger) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for version := 1; ; version++ {
+		candidate := name
+		if version > 1 {
+			candidate = fmt.Sprintf("%s-%d", name, version)
+		}
+		owner, taken := o.owners[candidate]
+		if !taken {
+			o.owners[candidate] = filename
+			if version > 1 {
+				logger.Printf("Warning: %s is saved as %s, as %s already took %s", filename, candidate, o.owners[name], name)
+			}
+			return candidate
+		}
+		if owner == filename {
+			return candidate
+		}
+	}
+}
+
+// reserveOutput creates the ChunkDir directory of an output name, or its archive with
+// CompressionTarZstd, failing with an os.ErrExist error when it already exists.
+// Creating it is atomic, so concurrent workers never claim the same name.
+func (c *Chunker) reserveOutput(name string) error {
+	dir := filepath.Join(c.config.ChunkDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create chunk directory: %w", err)
+	}
+	if c.config.Compression == config.CompressionTarZstd {
+		file, err := os.OpenFile(c.archivePath(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		return file.Close()
+	}
+	return os.Mkdir(dir, 0755)
+}
+
+// fileCompression returns the compression of individually saved files; archives
+// compress their raw text with zstd too
+func (c *Chunker) fileCompression() string {
+	if c.config.Compression == config.CompressionTarZstd {
+		return utils.CompressionZstd
+	}
+	return c.config.Compression
+}
+
+// writeOutputFile compresses data with fileCompression, writes it to path and returns
+// the SHA-256 of the bytes written
+func (c *Chunker) writeOutputFile(path string, data []byte) (string, error) {
+	data, err := utils.Compress(data, c.fileCompression())
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return sha256Hex(data), nil
+}
+
+// archivePath returns the CompressionTarZstd archive of an output name
+func (c *Chunker) archivePath(name string) string {
+	return filepath.Join(c.config.ChunkDir, filepath.FromSlash(name)+".tar.zst")
+}
+
+// saveChunkArchive saves the chunk text and JSON files of a document with its manifest
+// as a single ChunkDir/<name>.tar.zst. Hashes in the manifest are of the archived files.
+func (c *Chunker) saveChunkArchive(chunks []ChunkData, name string, manifest *Manifest) error {
+	archivePath := c.archivePath(name)
+	manifest.Archive = filepath.ToSlash(archivePath)
+	manifest.Chunks = make([]ManifestChunk, len(chunks))
+
+	var buffer bytes.Buffer
+	compressor, err := utils.NewCompressWriter(&buffer, utils.CompressionZstd)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	archive := tar.NewWriter(compressor)
+	add := func(file string, data []byte) error {
+		header := &tar.Header{Name: file, Mode: 0644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := archive.Write(data)
+		return err
+	}
+
+	for i, chunk := range chunks {
+		jsonData, err := json.Marshal(chunk)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON chunk %d: %w", chunk.ChunkIndex, err)
+		}
+		textFile, jsonFile := chunkFilename(chunk, ".txt"), chunkFilename(chunk, ".json")
+		textData := c.chunkFileText(chunk)
+		if err := add(textFile, textData); err != nil {
+			return fmt.Errorf("failed to archive chunk %d: %w", chunk.ChunkIndex, err)
+		}
+		if err := add(jsonFile, jsonData); err != nil {
+			return fmt.Errorf("failed to archive JSON chunk %d: %w", chunk.ChunkIndex, err)
+		}
+
+		manifest.Chunks[i] = newManifestChunk(chunk)
+		manifest.Chunks[i].TextFile = textFile
+		manifest.Chunks[i].TextSHA256 = sha256Hex(textData)
+		manifest.Chunks[i].JSONFile = jsonFile
+		manifest.Chunks[i].JSONSHA256 = sha256Hex(jsonData)
+	}
+
+	if manifest.toc != nil {
+		files, err := tocFiles(manifest.toc, ".txt")
+		if err != nil {
+			return err
+		}
+		for _, file := range []string{TOCFilename, TOCMarkdownFilename} {
+			if err := add(file, files[file]); err != nil {
+				return fmt.Errorf("failed to archive table of contents: %w", err)
+			}
+		}
+	}
+
+	if manifest.elements != nil {
+		data, err := json.MarshalIndent(manifest.elements, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode elements: %w", err)
+		}
+		if err := add(ElementsFilename, data); err != nil {
+			return fmt.Errorf("failed to archive elements: %w", err)
+		}
+	}
+
+	if manifest.thumbnail != nil {
+		file := c.thumbnailFilename()
+		if err := add(file, manifest.thumbnail); err != nil {
+			return fmt.Errorf("failed to archive thumbnail: %w", err)
+		}
+		manifest.Thumbnail = file
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := add(ManifestFilename, manifestData); err != nil {
+		return fmt.Errorf("failed to archive manifest: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to archive chunks: %w", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("failed to compress archive: %w", err)
+	}
+
+	c.removeStaleStaging(archivePath)
+	if err := utils.WriteFileAtomic(archivePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to save archive: %w", err)
+	}
+	return nil
+}
+
+// contentHash returns a short hex hash of a document's text
+func contentHash(text string) string {
+	return sha256Hex([]byte(text))[:8]
+}
@@ -0,0 +1,65 @@
+package chunker_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+)
+
+// TestChunkDirectoryNameCollision checks that files whose names slug alike keep their own
+// output under the default OverwriteReplace, in walk order, also when chunked again
+func TestChunkDirectoryNameCollision(t *testing.T) {
+	data := t.TempDir()
+	for name, text := range map[string]string{
+		"report.md":  "The markdown report covers the first quarter.",
+		"report.txt": "The text report covers the second quarter.",
+	} {
+		if err := os.WriteFile(filepath.Join(data, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := chunkertest.Config(t)
+	cfg.Workers = 2
+	instance := chunker.NewChunker(chunker.WithConfig(cfg))
+	defer instance.Close()
+
+	for run := 1; run <= 2; run++ {
+		result, err := instance.ChunkDirectory(data, chunker.OutputBoth)
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		for _, file := range result.Files {
+			if file.Status != chunker.FileStatusOK {
+				t.Fatalf("run %d: %s: %s %s", run, file.Filename, file.Status, file.Error)
+			}
+		}
+
+		for dir, quarter := range map[string]string{"report": "first", "report-2": "second"} {
+			entries, err := os.ReadDir(filepath.Join(cfg.ChunkDir, dir))
+			if err != nil {
+				t.Fatalf("run %d: %v", run, err)
+			}
+			var text strings.Builder
+			for _, entry := range entries {
+				if filepath.Ext(entry.Name()) == ".txt" {
+					content, err := os.ReadFile(filepath.Join(cfg.ChunkDir, dir, entry.Name()))
+					if err != nil {
+						t.Fatal(err)
+					}
+					text.Write(content)
+				}
+			}
+			if !strings.Contains(text.String(), quarter+" quarter") {
+				t.Errorf("run %d: %s holds %q, want the %s quarter report", run, dir, text.String(), quarter)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(cfg.ChunkDir, "report-3")); !os.IsNotExist(err) {
+			t.Errorf("run %d: report-3 exists, want the names reused", run)
+		}
+	}
+}
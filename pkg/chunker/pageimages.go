@@ -0,0 +1,52 @@
+package chunker
+
+import (
+	"fmt"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+)
+
+// checkImageFormats makes every document fail when the page image or thumbnail format is
+// unknown, rather than saving images in a format their readers do not expect
+func (c *Chunker) checkImageFormats() {
+	for _, format := range []struct{ name, value string }{
+		{"page image", c.config.PageImageFormat},
+		{"thumbnail", c.config.ThumbnailFormat},
+	} {
+		var err error
+		switch format.value {
+		case "", config.ImagePNG, config.ImageJPEG:
+			continue
+		case "webp":
+			err = fmt.Errorf("%s format webp is not supported: Go has no WebP encoder; use png or jpeg", format.name)
+		default:
+			err = fmt.Errorf("unknown %s format %q", format.name, format.value)
+		}
+		if c.configErr == nil {
+			c.configErr = err
+			c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+		}
+	}
+}
+
+// attachPageImages lists the images of the pages of every chunk, written with
+// PageImages, under "page_images" in its metadata
+func attachPageImages(chunks []ChunkData, report *processor.DocumentReport) {
+	if report == nil || len(report.PageImages) == 0 {
+		return
+	}
+	for i := range chunks {
+		chunk := &chunks[i]
+		if chunk.StartPage < 1 || chunk.EndPage > len(report.PageImages) {
+			continue
+		}
+		if _, ok := chunk.Metadata["page_images"]; ok {
+			continue
+		}
+		if chunk.Metadata == nil {
+			chunk.Metadata = make(map[string]any)
+		}
+		chunk.Metadata["page_images"] = report.PageImages[chunk.StartPage-1 : chunk.EndPage]
+	}
+}
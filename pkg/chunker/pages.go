@@ -0,0 +1,252 @@
+package chunker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// pageSeparatorPattern matches a page separator on a line of its own in text inputs
+var pageSeparatorPattern = regexp.MustCompile(`(?m)^--- Page (\d+) ---$`)
+
+// pagedText is the consolidated text of a document together with the offset at which
+// each page starts, so chunk page ranges come from the page structure rather than from
+// scanning the text for separators
+type pagedText struct {
+	text      string
+	pages     []processor.Page
+	starts    []int // Offset of each page in text, including its separator
+	continued bool  // The first page is the rest of a page, written without its separator (see textRegion)
+}
+
+// newPagedText joins pages with "--- Page N ---" separators; pages without a number
+// are written without a separator
+func newPagedText(pages []processor.Page) pagedText {
+	return joinPages(pages, false)
+}
+
+// joinPages joins pages like newPagedText, leaving out the separator of the first page
+// when it is continued
+func joinPages(pages []processor.Page, continued bool) pagedText {
+	p := pagedText{pages: pages, starts: make([]int, len(pages)), continued: continued}
+	var text strings.Builder
+	for i, page := range pages {
+		p.starts[i] = text.Len()
+		text.WriteString(p.separator(i))
+		text.WriteString(page.Text)
+	}
+	p.text = text.String()
+	return p
+}
+
+// separator returns the separator written before the page at index i
+func (p pagedText) separator(i int) string {
+	if i == 0 && p.continued {
+		return ""
+	}
+	return pageSeparator(p.pages[i])
+}
+
+// pageSeparator returns the separator written before a page
+func pageSeparator(page processor.Page) string {
+	if page.Number == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\n--- Page %d ---\n\n", page.Number)
+}
+
+// pageTexts returns every page with its separator, in order
+func (p pagedText) pageTexts() []string {
+	texts := make([]string, len(p.pages))
+	for i := range p.pages {
+		end := len(p.text)
+		if i+1 < len(p.starts) {
+			end = p.starts[i+1]
+		}
+		texts[i] = p.text[p.starts[i]:end]
+	}
+	return texts
+}
+
+// span is the position of a chunk in the consolidated text; start is -1 when the
+// chunk could not be located
+type span struct {
+	start int
+	end   int
+}
+
+// locate finds each split chunk in the consolidated text. Splitters only cut and
+// trim the text, so chunks are searched in order from the end of the previous one.
+func (p pagedText) locate(chunks []string) []span {
+	return p.locateFrom(chunks, 0)
+}
+
+// locateFrom is locate for chunks split from the text after offset
+func (p pagedText) locateFrom(chunks []string, offset int) []span {
+	spans := make([]span, len(chunks))
+	cursor := offset
+	for i, chunk := range chunks {
+		trimmed := strings.TrimSpace(chunk)
+		index := strings.Index(p.text[cursor:], trimmed)
+		if trimmed == "" || index < 0 {
+			spans[i] = span{start: -1, end: -1}
+			continue
+		}
+		start := cursor + index
+		spans[i] = span{start: start, end: start + len(trimmed)}
+		cursor = spans[i].end
+	}
+	return spans
+}
+
+// pageRange returns the "Page N" or "Page N–M" range of the pages overlapping a span
+func (p pagedText) pageRange(s span) string {
+	if s.start < 0 {
+		return ""
+	}
+
+	first, last := 0, 0
+	for i, page := range p.pages {
+		if page.Number == 0 || p.starts[i] >= s.end {
+			continue
+		}
+		end := len(p.text)
+		if i+1 < len(p.starts) {
+			end = p.starts[i+1]
+		}
+		if end <= s.start {
+			continue
+		}
+		if first == 0 {
+			first = page.Number
+		}
+		last = page.Number
+	}
+
+	switch {
+	case first == 0:
+		return ""
+	case first == last:
+		return fmt.Sprintf("Page %d", first)
+	default:
+		return fmt.Sprintf("Page %d–%d", first, last)
+	}
+}
+
+// position returns the page containing a text offset and the character offset within
+// that page's text. A span end is exclusive, so it is attributed to the page holding
+// the character before it.
+func (p pagedText) position(offset int, end bool) (processor.Page, int) {
+	lookup := offset
+	if end && lookup > 0 {
+		lookup--
+	}
+
+	pageIndex := 0
+	for i, start := range p.starts {
+		if start <= lookup {
+			pageIndex = i
+		}
+	}
+
+	page := p.pages[pageIndex]
+	inPage := offset - p.starts[pageIndex] - len(p.separator(pageIndex))
+	inPage = max(0, min(inPage, len(page.Text)))
+	return page, utf8.RuneCountInString(page.Text[:inPage])
+}
+
+// newChunkData builds the chunk data for a split chunk, with its page range and its
+// page and character offsets in the source document
+func newChunkData(filename string, index int, document pagedText, s span, text string) ChunkData {
+	chunk := ChunkData{
+		SchemaVersion: schema.Version,
+		Filename:      filename,
+		Slug:          utils.Slug(filename),
+		ChunkIndex:    index,
+		PageRange:     document.pageRange(s),
+		Text:          text,
+		Metadata:      chunkMetadata(document, s, text),
+	}
+	if s.start < 0 || len(document.pages) == 0 {
+		return chunk
+	}
+
+	var startPage, endPage processor.Page
+	startPage, chunk.StartOffset = document.position(s.start, false)
+	endPage, chunk.EndOffset = document.position(s.end, true)
+	chunk.StartPage = startPage.Number
+	chunk.EndPage = endPage.Number
+	return chunk
+}
+
+// textPages returns the pages of a text or string input: a single page without a number,
+// or with TextPageSeparators the pages of pagesFromText
+func (c *Chunker) textPages(text string) []processor.Page {
+	if !c.config.TextPageSeparators {
+		return []processor.Page{{Text: text, Source: processor.SourceText}}
+	}
+	return pagesFromText(text)
+}
+
+// pagesFromText splits text at "--- Page N ---" separators, as written by OutputRawText.
+// Text without separators is a single page without a number.
+func pagesFromText(text string) []processor.Page {
+	indexes := pageSeparatorPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(indexes) == 0 {
+		return []processor.Page{{Text: text, Source: processor.SourceText}}
+	}
+
+	// Text before the first separator belongs to a page with no number of its own
+	var pages []processor.Page
+	if leading := strings.TrimSpace(text[:indexes[0][0]]); leading != "" {
+		pages = append(pages, processor.Page{Text: leading, Source: processor.SourceText})
+	}
+	for i, index := range indexes {
+		end := len(text)
+		if i+1 < len(indexes) {
+			end = indexes[i+1][0]
+		}
+
+		number, _ := strconv.Atoi(text[index[2]:index[3]])
+		pages = append(pages, processor.Page{
+			Number: number,
+			Text:   strings.TrimLeft(text[index[1]:end], "\n"),
+			Source: processor.SourceText,
+		})
+	}
+	return pages
+}
+
+// appendPages appends the pages of an attached document so they continue the page
+// numbering after the last page, labelling the first one
+func appendPages(pages, attached []processor.Page, label string) []processor.Page {
+	lastPage := 0
+	for _, page := range pages {
+		if page.Number > lastPage {
+			lastPage = page.Number
+		}
+	}
+
+	offset := lastPage
+	for i, page := range attached {
+		if page.Number == 0 {
+			page.Number = lastPage + 1
+		} else {
+			page.Number += offset
+		}
+		if page.Number > lastPage {
+			lastPage = page.Number
+		}
+		if i == 0 {
+			page.Text = label + "\n" + page.Text
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
@@ -0,0 +1,55 @@
+package chunker
+
+import (
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/paper"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+)
+
+// paperPart is a chunk of a paper before it is formatted
+type paperPart struct {
+	span    span
+	section paper.Section
+	text    string
+}
+
+// createPaperChunks chunks a paper for ProfilePaper without the AI provider: two-column
+// pages are put back in reading order, every section is split like local chunks so no
+// chunk spans two sections, and inline citations are stripped outside the references,
+// which are dropped with ExcludeReferences. Offsets refer to the reordered page text.
+func (c *Chunker) createPaperChunks(document pagedText, filename string) []ChunkData {
+	pages := make([]processor.Page, len(document.pages))
+	for i, page := range document.pages {
+		page.Text = paper.Decolumnize(page.Text)
+		pages[i] = page
+	}
+	document = newPagedText(pages)
+
+	var parts []paperPart
+	for _, section := range paper.Split(document.text) {
+		if section.Name == paper.SectionReferences && c.config.ExcludeReferences {
+			continue
+		}
+		pieces := c.textProcessor.SplitTextIntoLocalChunks(document.text[section.Start:section.End])
+		for i, s := range document.locateFrom(pieces, section.Start) {
+			if s.start < 0 {
+				continue
+			}
+			text := pieces[i]
+			if section.Name != paper.SectionReferences {
+				text = paper.StripCitations(text)
+			}
+			parts = append(parts, paperPart{span: s, section: section, text: text})
+		}
+	}
+
+	chunks := make([]ChunkData, 0, len(parts))
+	for i, part := range parts {
+		formatted := c.textProcessor.FormatChunk(part.text, document.pageRange(part.span), i+1, len(parts))
+		chunk := newChunkData(filename, i+1, document, part.span, formatted)
+		chunk.RenderedText = c.renderedText(part.text, document.pageRange(part.span), "", i+1, len(parts))
+		chunk.Title = part.section.Heading
+		chunk.Section = part.section.Name
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
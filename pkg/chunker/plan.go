@@ -0,0 +1,189 @@
+package chunker
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+)
+
+// Rough token figures used to estimate AI usage without calling the provider
+const (
+	charsPerToken            = 4    // Average for English and Indonesian text
+	promptOverheadTokens     = 350  // Chunking instructions sent with every slice
+	maxCompletionTokensPerAI = 2000 // Completion limit requested per slice
+)
+
+// Plan reports what chunking a set of inputs would do, estimated without extracting
+// text or calling the AI provider
+type Plan struct {
+	Provider         string     `json:"provider"` // Empty for local chunking
+	ChunkSize        int        `json:"chunk_size"`
+	Files            []FilePlan `json:"files"`
+	TotalPages       int        `json:"total_pages"`
+	EstimatedChunks  int        `json:"estimated_chunks"`
+	PromptTokens     int        `json:"estimated_prompt_tokens"`
+	CompletionTokens int        `json:"estimated_completion_tokens"`
+	EstimatedCost    float64    `json:"estimated_cost_usd"`
+}
+
+// FilePlan is the estimate for a single file of a Plan
+type FilePlan struct {
+	Filename         string  `json:"filename"`
+	Type             string  `json:"type"`
+	Bytes            int64   `json:"bytes"`
+	Pages            int     `json:"pages"`
+	Characters       int     `json:"estimated_characters"`
+	EstimatedChunks  int     `json:"estimated_chunks"`
+	PromptTokens     int     `json:"estimated_prompt_tokens"`
+	CompletionTokens int     `json:"estimated_completion_tokens"`
+	EstimatedCost    float64 `json:"estimated_cost_usd"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// Plan scans files, directories and archives like ChunkDirectory and reports the files,
+// pages, chunks, tokens and API cost that chunking them would take, so settings can be
+// checked on a large corpus first. Page counts are exact; sizes are estimates.
+func (c *Chunker) Plan(paths ...string) (*Plan, error) {
+	plan := &Plan{ChunkSize: c.config.LocalChunkSize}
+	if c.aiProvider != nil {
+		plan.Provider = c.aiProvider.GetName()
+		plan.ChunkSize = c.config.MaxChunkSize
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input: %w", err)
+		}
+
+		if info.IsDir() {
+			if err := c.planTree(path, "", true, plan); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		inputType, ok := InputTypeForFilename(path)
+		if !ok {
+			plan.Files = append(plan.Files, FilePlan{Filename: filepath.Base(path), Bytes: info.Size(), Error: "unsupported file type"})
+			continue
+		}
+		c.planPath(inputType, path, filepath.Base(path), true, plan)
+	}
+
+	return plan, nil
+}
+
+// planTree adds every supported file under root to the plan
+func (c *Chunker) planTree(root, prefix string, expandArchives bool, plan *Plan) error {
+	return walkSupportedFiles(root, prefix, func(inputType InputType, path, filename string) {
+		c.planPath(inputType, path, filename, expandArchives, plan)
+	})
+}
+
+// planPath adds a file to the plan, expanding archives one level deep
+func (c *Chunker) planPath(inputType InputType, path, filename string, expandArchives bool, plan *Plan) {
+	if inputType != InputArchive {
+		c.addFilePlan(plan, c.planFile(inputType, path, filename))
+		return
+	}
+
+	if !expandArchives {
+		c.logger.Printf("Warning: skipping nested archive %s", filename)
+		return
+	}
+	err := c.withUnpackedArchive(path, func(dir string) error {
+		return c.planTree(dir, filename+"/", false, plan)
+	})
+	if err != nil {
+		plan.Files = append(plan.Files, FilePlan{Filename: filename, Type: inputType.String(), Error: err.Error()})
+	}
+}
+
+// planFile estimates a single document
+func (c *Chunker) planFile(inputType InputType, path, filename string) FilePlan {
+	filePlan := FilePlan{Filename: filename, Type: inputType.String()}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		filePlan.Error = err.Error()
+		return filePlan
+	}
+	filePlan.Bytes = info.Size()
+
+	var size processor.DocumentSize
+	switch inputType {
+	case InputPDF:
+		size, err = processor.InspectPDFPath(path)
+	case InputPPTX:
+		size, err = processor.InspectPPTXPath(path)
+	case InputXLSX:
+		size, err = processor.InspectXLSXPath(path)
+	default:
+		// Emails and text files are one page; attachments are not counted separately
+		size = processor.DocumentSize{Pages: 1, Characters: int(info.Size())}
+	}
+	if err != nil {
+		filePlan.Error = err.Error()
+		return filePlan
+	}
+
+	filePlan.Pages = size.Pages
+	filePlan.Characters = size.Characters
+	c.estimateChunks(&filePlan)
+	return filePlan
+}
+
+// estimateChunks fills in the chunk, token and cost estimates of a file
+func (c *Chunker) estimateChunks(filePlan *FilePlan) {
+	chunkSize := c.config.LocalChunkSize
+	if c.aiProvider != nil {
+		chunkSize = c.config.MaxChunkSize
+	}
+	if chunkSize <= 0 || filePlan.Characters == 0 {
+		return
+	}
+
+	size := filePlan.Characters
+	if c.config.SizeUnit == config.SizeTokens {
+		size /= charsPerToken
+	}
+	filePlan.EstimatedChunks = (size + chunkSize - 1) / chunkSize
+	if c.aiProvider == nil {
+		return
+	}
+
+	textTokens := filePlan.Characters / charsPerToken
+	filePlan.PromptTokens = textTokens + filePlan.EstimatedChunks*promptOverheadTokens
+	filePlan.CompletionTokens = min(textTokens, filePlan.EstimatedChunks*maxCompletionTokensPerAI)
+	filePlan.EstimatedCost = float64(filePlan.PromptTokens)/1e6*c.config.AIPromptPrice +
+		float64(filePlan.CompletionTokens)/1e6*c.config.AICompletionPrice
+}
+
+// addFilePlan appends a file to the plan and adds it to the totals
+func (c *Chunker) addFilePlan(plan *Plan, filePlan FilePlan) {
+	plan.Files = append(plan.Files, filePlan)
+	plan.TotalPages += filePlan.Pages
+	plan.EstimatedChunks += filePlan.EstimatedChunks
+	plan.PromptTokens += filePlan.PromptTokens
+	plan.CompletionTokens += filePlan.CompletionTokens
+	plan.EstimatedCost += filePlan.EstimatedCost
+}
+
+// WriteTable writes the plan as an aligned text table with a totals row
+func (p *Plan) WriteTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FILE\tTYPE\tPAGES\tCHUNKS\tTOKENS\tCOST (USD)\tERROR")
+	for _, file := range p.Files {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%.4f\t%s\n", file.Filename, file.Type, file.Pages,
+			file.EstimatedChunks, file.PromptTokens+file.CompletionTokens, file.EstimatedCost, file.Error)
+	}
+	fmt.Fprintf(table, "TOTAL (%d files)\t\t%d\t%d\t%d\t%.4f\t\n", len(p.Files), p.TotalPages,
+		p.EstimatedChunks, p.PromptTokens+p.CompletionTokens, p.EstimatedCost)
+	return table.Flush()
+}
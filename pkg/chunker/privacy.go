@@ -0,0 +1,50 @@
+package chunker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRemoteDisabled is returned for every document when LocalOnly is set and a remote AI
+// provider, embedding provider or OCR engine was configured
+var ErrRemoteDisabled = errors.New("remote processing disabled by LocalOnly")
+
+// Local is implemented by AI providers, embedding providers and OCR engines that process content on this
+// machine. With LocalOnly, providers and engines that do not implement it are remote.
+type Local interface {
+	IsLocal() bool
+}
+
+// isLocal reports whether an AI provider or OCR engine keeps content on this machine
+func isLocal(component any) bool {
+	local, ok := component.(Local)
+	return ok && local.IsLocal()
+}
+
+// applyLocalOnly removes remote AI providers, embedding providers and OCR engines when LocalOnly is set, so no
+// code path can reach them, and makes every document fail instead of quietly falling
+// back to local processing
+func (c *Chunker) applyLocalOnly() {
+	if !c.config.LocalOnly {
+		return
+	}
+
+	var refused []string
+	if c.aiProvider != nil && !isLocal(c.aiProvider) {
+		refused = append(refused, "AI provider "+c.aiProvider.GetName())
+		c.aiProvider = nil
+	}
+	if c.embedder != nil && !isLocal(c.embedder) {
+		refused = append(refused, "embedding provider "+c.embedder.GetName())
+		c.embedder = nil
+	}
+	if c.ocrEngine != nil && !isLocal(c.ocrEngine) {
+		refused = append(refused, "OCR engine "+c.ocrEngine.GetName())
+		c.ocrEngine = nil
+	}
+	if len(refused) > 0 {
+		c.configErr = fmt.Errorf("%w: refused %s", ErrRemoteDisabled, strings.Join(refused, ", "))
+		c.logger.Printf("Error: %v; every document will fail until it is removed", c.configErr)
+	}
+}
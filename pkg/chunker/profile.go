@@ -0,0 +1,34 @@
+package chunker
+
+import (
+	"fmt"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+// checkProfile makes every document fail when the profile is unknown, rather than
+// chunking it with the general splitter
+func (c *Chunker) checkProfile() {
+	switch c.config.Profile {
+	case config.ProfileGeneral, config.ProfileRegulation, config.ProfilePaper, config.ProfileInvoice:
+		return
+	}
+	err := fmt.Errorf("unknown profile %q", c.config.Profile)
+	if c.configErr == nil {
+		c.configErr = err
+		c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+	}
+}
+
+// profileChunks chunks a document with its profile and reports whether the profile
+// did; documents of the general and invoice profiles are left to the AI or local splitter
+func (c *Chunker) profileChunks(document pagedText, filename string) ([]ChunkData, bool) {
+	switch c.config.Profile {
+	case config.ProfileRegulation:
+		return c.createRegulationChunks(document, filename), true
+	case config.ProfilePaper:
+		return c.createPaperChunks(document, filename), true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,84 @@
+package chunker
+
+import (
+	"sync/atomic"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+)
+
+// Progress event types
+const (
+	EventPageProcessed = "page_processed" // The text of a page is extracted, with OCR when the page needed it
+	EventChunkCreated  = "chunk_created"  // A chunk is finished: scored, summarized and embedded, before it is written to the sinks
+)
+
+// ProgressEvent reports the progress of a document, see WithProgress
+type ProgressEvent struct {
+	Type  string     `json:"type"`
+	Page  int        `json:"page,omitempty"`  // 1-based page of EventPageProcessed
+	Pages int        `json:"pages,omitempty"` // Pages of the document
+	Chunk *ChunkData `json:"chunk,omitempty"` // The chunk of EventChunkCreated
+}
+
+// ProgressFunc receives progress events. OCR workers report pages concurrently, so it
+// must be safe for concurrent use.
+type ProgressFunc func(ProgressEvent)
+
+// WithProgress returns a copy of the chunker that reports the progress of every document
+// to fn. PDF pages are reported as they are extracted, in no particular order when
+// pages need OCR; the pages of other inputs once the whole input is read. Chunks are
+// reported once finished, so chunks left out as low quality or duplicates are not. The
+// copy shares everything else with c: close c, not the copy.
+func (c *Chunker) WithProgress(fn ProgressFunc) *Chunker {
+	progress := *c
+	progress.progress = fn
+	return &progress
+}
+
+// pageProgress counts the pages of a document reported by the PDF processor
+type pageProgress struct {
+	reported    atomic.Int32
+	attachments atomic.Int32 // Email attachments being extracted; their pages are not the document's
+}
+
+// trackProgress sets up the progress reports of a copy made by forDocument
+func (c *Chunker) trackProgress(options *processor.ExtractOptions) {
+	c.pageProgress = new(pageProgress)
+	options.OnPage = func(page, pages int) {
+		if c.pageProgress.attachments.Load() > 0 {
+			return
+		}
+		c.pageProgress.reported.Add(1)
+		c.progress(ProgressEvent{Type: EventPageProcessed, Page: page, Pages: pages})
+	}
+}
+
+// muteAttachmentPages stops page reports while an email attachment is extracted; call
+// the returned function once it is
+func (c *Chunker) muteAttachmentPages() (unmute func()) {
+	if c.pageProgress == nil {
+		return func() {}
+	}
+	c.pageProgress.attachments.Add(1)
+	return func() { c.pageProgress.attachments.Add(-1) }
+}
+
+// reportPages reports the pages of an extracted input that the PDF processor did not
+func (c *Chunker) reportPages(pages int) {
+	if c.progress == nil || c.pageProgress.attachments.Load() > 0 || c.pageProgress.reported.Load() > 0 {
+		return
+	}
+	for page := 1; page <= pages; page++ {
+		c.progress(ProgressEvent{Type: EventPageProcessed, Page: page, Pages: pages})
+	}
+}
+
+// reportChunks reports the finished chunks of a document
+func (c *Chunker) reportChunks(result *ChunkResult) {
+	if c.progress == nil {
+		return
+	}
+	for i := range result.Chunks {
+		c.progress(ProgressEvent{Type: EventChunkCreated, Pages: result.Pages, Chunk: &result.Chunks[i]})
+	}
+}
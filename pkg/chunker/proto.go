@@ -0,0 +1,121 @@
+package chunker
+
+import (
+	"fmt"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MarshalProto encodes the result as a ChunkResult message of schema/chunk.proto. The
+// extraction report is not included.
+func (r *ChunkResult) MarshalProto() ([]byte, error) {
+	var b []byte
+	for _, chunk := range r.Chunks {
+		message, err := schema.MarshalProto(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode chunk %d: %w", chunk.ChunkIndex, err)
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, message)
+	}
+
+	var usage []byte
+	for number, value := range []int{r.TokenUsage.PromptTokens, r.TokenUsage.CompletionTokens, r.TokenUsage.TotalTokens} {
+		if value != 0 {
+			usage = protowire.AppendTag(usage, protowire.Number(number+1), protowire.VarintType)
+			usage = protowire.AppendVarint(usage, uint64(value))
+		}
+	}
+	if len(usage) > 0 {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, usage)
+	}
+
+	if r.Pages != 0 {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(r.Pages))
+	}
+	if r.BudgetExceeded {
+		b = protowire.AppendTag(b, 4, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	return b, nil
+}
+
+// UnmarshalChunkResultProto decodes a ChunkResult message written by
+// ChunkResult.MarshalProto, upgrading its chunks like schema.Unmarshal
+func UnmarshalChunkResultProto(data []byte) (*ChunkResult, error) {
+	result := &ChunkResult{}
+	err := consumeMessage(data, func(number protowire.Number, value []byte, varint uint64) error {
+		switch number {
+		case 1:
+			chunk, err := schema.UnmarshalProto(value)
+			if err != nil {
+				return err
+			}
+			result.Chunks = append(result.Chunks, chunk)
+		case 2:
+			return consumeMessage(value, func(number protowire.Number, _ []byte, varint uint64) error {
+				switch number {
+				case 1:
+					result.TokenUsage.PromptTokens = int(int32(varint))
+				case 2:
+					result.TokenUsage.CompletionTokens = int(int32(varint))
+				case 3:
+					result.TokenUsage.TotalTokens = int(int32(varint))
+				}
+				return nil
+			})
+		case 3:
+			result.Pages = int(int32(varint))
+		case 4:
+			result.BudgetExceeded = varint != 0
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse chunk result: %w", err)
+	}
+	return result, nil
+}
+
+// consumeMessage calls field for every varint and length-delimited field of a message,
+// skipping fields of other wire types
+func consumeMessage(data []byte, field func(number protowire.Number, value []byte, varint uint64) error) error {
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch typ {
+		case protowire.VarintType:
+			varint, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := field(number, nil, varint); err != nil {
+				return err
+			}
+			data = data[n:]
+		case protowire.BytesType:
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := field(number, value, 0); err != nil {
+				return err
+			}
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(number, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package chunker
+
+import "github.com/firdasafridi/pdf-chunk-extractor/pkg/quality"
+
+// scoreChunks gives every chunk its Quality with ScoreQuality or MinQualityScore, and
+// returns the chunks to output with the number left out for scoring below
+// MinQualityScore. Kept chunks keep their ChunkIndex, so dropped ones leave gaps.
+func (c *Chunker) scoreChunks(chunks []ChunkData) ([]ChunkData, int) {
+	if !c.config.ScoreQuality && c.config.MinQualityScore <= 0 {
+		return chunks, 0
+	}
+	kept := chunks[:0]
+	dropped := 0
+	for _, chunk := range chunks {
+		chunk.Quality = quality.Score(chunk.Text)
+		if chunk.Quality.Score < c.config.MinQualityScore {
+			dropped++
+			continue
+		}
+		kept = append(kept, chunk)
+	}
+	if dropped > 0 {
+		c.logger.Printf("Left out %d chunks of %s scoring below %.2f", dropped, chunks[0].Filename, c.config.MinQualityScore)
+	}
+	return kept, dropped
+}
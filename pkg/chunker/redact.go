@@ -0,0 +1,46 @@
+package chunker
+
+import (
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/redact"
+)
+
+// RedactionReport counts the personal data masked in a document with RedactPII
+type RedactionReport struct {
+	Total  redact.Counts     `json:"total"`
+	Chunks []ChunkRedactions `json:"chunks,omitempty"` // Only chunks with at least one redaction
+}
+
+// ChunkRedactions counts the placeholders in a single chunk
+type ChunkRedactions struct {
+	ChunkIndex int           `json:"chunk_index"`
+	Counts     redact.Counts `json:"counts"`
+}
+
+// redactPage masks the personal data in a page with RedactPII
+func (c *Chunker) redactPage(page processor.Page) processor.Page {
+	if c.redactor != nil {
+		page.Text, _ = c.redactor.Redact(page.Text)
+	}
+	return page
+}
+
+// newRedactionReport counts the placeholders in the chunks of a document
+func newRedactionReport(chunks []ChunkData) *RedactionReport {
+	report := &RedactionReport{Total: redact.Counts{}}
+	report.add(chunks)
+	return report
+}
+
+// add counts the placeholders in chunks. Counting the chunk text rather than the
+// extracted pages keeps the counts right when the AI provider regroups text.
+func (r *RedactionReport) add(chunks []ChunkData) {
+	for _, chunk := range chunks {
+		counts := redact.Count(chunk.Text)
+		if len(counts) == 0 {
+			continue
+		}
+		r.Total.Add(counts)
+		r.Chunks = append(r.Chunks, ChunkRedactions{ChunkIndex: chunk.ChunkIndex, Counts: counts})
+	}
+}
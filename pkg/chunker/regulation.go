@@ -0,0 +1,146 @@
+package chunker
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/regulation"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// regulationPart is a chunk of a regulation before it is formatted
+type regulationPart struct {
+	span     span
+	citation *schema.Citation
+}
+
+// createRegulationChunks chunks a regulation for ProfileRegulation without the AI
+// provider: every Pasal becomes a chunk, split only between its Ayat when it is longer
+// than LocalChunkSize, and each chunk gets its citation. The preamble, the promulgation
+// and the general part of the Penjelasan are split like local chunks.
+func (c *Chunker) createRegulationChunks(document pagedText, filename string) []ChunkData {
+	parsed := regulation.Parse(document.text)
+	name := parsed.Name()
+	var introduction *schema.Citation
+	if name != "" {
+		introduction = &schema.Citation{Text: name, Regulation: name}
+	}
+
+	parts := c.regulationSection(document, 0, parsed.BodyStart, introduction)
+	for _, pasal := range regulation.Pasals(parsed.Body) {
+		parts = append(parts, c.pasalParts(document, parsed, pasal)...)
+	}
+	parts = append(parts, c.regulationSection(document, parsed.ClosingStart, parsed.ElucidationStart, introduction)...)
+
+	if parsed.ElucidationStart < len(document.text) {
+		pasals := regulation.Pasals(parsed.Elucidation)
+		end := len(document.text)
+		if len(pasals) > 0 {
+			end = pasals[0].Start
+		}
+		general := &schema.Citation{Text: strings.TrimSpace("Penjelasan " + name), Regulation: name, Elucidation: true}
+		parts = append(parts, c.regulationSection(document, parsed.ElucidationStart, end, general)...)
+		for _, pasal := range pasals {
+			parts = append(parts, c.pasalParts(document, parsed, pasal)...)
+		}
+	}
+
+	chunks := make([]ChunkData, 0, len(parts))
+	for i, part := range parts {
+		var citation string
+		if part.citation != nil {
+			citation = part.citation.Text
+		}
+		text := document.text[part.span.start:part.span.end]
+		formatted := c.textProcessor.FormatCitedChunk(text, document.pageRange(part.span), citation, i+1, len(parts))
+		chunk := newChunkData(filename, i+1, document, part.span, formatted)
+		chunk.RenderedText = c.renderedText(text, document.pageRange(part.span), citation, i+1, len(parts))
+		chunk.Citation = part.citation
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// pasalParts returns a Pasal as a single part, or, when it is longer than LocalChunkSize
+// and has several Ayat, as groups of consecutive Ayat citing them
+func (c *Chunker) pasalParts(document pagedText, parsed *regulation.Regulation, pasal *regulation.Node) []regulationPart {
+	whole := trimSpan(document.text, span{start: pasal.Start, end: pasal.End})
+	if c.textProcessor.Size(document.text[whole.start:whole.end]) <= c.config.LocalChunkSize || len(pasal.Children) < 2 {
+		return []regulationPart{{span: whole, citation: pasalCitation(parsed, pasal)}}
+	}
+
+	// The text before the first Ayat goes with the first group
+	var parts []regulationPart
+	start := pasal.Start
+	var group []*regulation.Node
+	for _, ayat := range pasal.Children {
+		if len(group) > 0 && c.textProcessor.Size(document.text[start:ayat.End]) > c.config.LocalChunkSize {
+			parts = append(parts, regulationPart{
+				span:     trimSpan(document.text, span{start: start, end: ayat.Start}),
+				citation: pasalCitation(parsed, pasal, group...),
+			})
+			start, group = ayat.Start, nil
+		}
+		group = append(group, ayat)
+	}
+	return append(parts, regulationPart{
+		span:     trimSpan(document.text, span{start: start, end: pasal.End}),
+		citation: pasalCitation(parsed, pasal, group...),
+	})
+}
+
+// pasalCitation returns the citation of a Pasal, or of some of its Ayat, with the Bab,
+// Bagian and Paragraf containing it
+func pasalCitation(parsed *regulation.Regulation, pasal *regulation.Node, ayat ...*regulation.Node) *schema.Citation {
+	citation := &schema.Citation{
+		Text:        parsed.Cite(pasal, ayat...),
+		Regulation:  parsed.Name(),
+		Pasal:       pasal.Number,
+		Elucidation: pasal.Start >= parsed.ElucidationStart,
+	}
+	if bab := pasal.Ancestor(regulation.KindBab); bab != nil {
+		citation.Bab = bab.Heading()
+	}
+	if bagian := pasal.Ancestor(regulation.KindBagian); bagian != nil {
+		citation.Bagian = bagian.Heading()
+	}
+	if paragraf := pasal.Ancestor(regulation.KindParagraf); paragraf != nil {
+		citation.Paragraf = paragraf.Heading()
+	}
+	for _, node := range ayat {
+		citation.Ayat = append(citation.Ayat, node.Number)
+	}
+	return citation
+}
+
+// regulationSection splits the text between start and end like local chunks, each part
+// with a copy of citation
+func (c *Chunker) regulationSection(document pagedText, start, end int, citation *schema.Citation) []regulationPart {
+	if start >= end || strings.TrimSpace(document.text[start:end]) == "" {
+		return nil
+	}
+	pieces := c.textProcessor.SplitTextIntoLocalChunks(document.text[start:end])
+	spans := document.locateFrom(pieces, start)
+
+	var parts []regulationPart
+	for _, s := range spans {
+		if s.start < 0 {
+			continue
+		}
+		part := regulationPart{span: s}
+		if citation != nil {
+			copied := *citation
+			part.citation = &copied
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// trimSpan shrinks a span to the text without the whitespace around it
+func trimSpan(text string, s span) span {
+	trimmed := strings.TrimLeftFunc(text[s.start:s.end], unicode.IsSpace)
+	s.start = s.end - len(trimmed)
+	s.end = s.start + len(strings.TrimRightFunc(trimmed, unicode.IsSpace))
+	return s
+}
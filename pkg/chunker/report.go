@@ -0,0 +1,103 @@
+package chunker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// RunReport summarizes a batch run per file, without the chunks themselves
+type RunReport struct {
+	StartedAt  time.Time    `json:"started_at"`
+	DurationMS int64        `json:"duration_ms"`
+	Succeeded  int          `json:"succeeded"`
+	Failed     int          `json:"failed"`
+	Elsewhere  int          `json:"elsewhere,omitempty"` // Files left to other instances, see WithCoordinator
+	Pages      int          `json:"pages"`
+	OCRPages   int          `json:"ocr_pages"`
+	Chunks     int          `json:"chunks"`
+	TokenUsage TokenUsage   `json:"token_usage"`
+	Files      []FileResult `json:"files"`
+
+	BudgetExceeded bool `json:"budget_exceeded"`   // Some documents were chunked locally or aborted by the AI budget
+	Stopped        bool `json:"stopped,omitempty"` // The run was stopped before every document was processed
+}
+
+// Report returns the run report of a batch
+func (r *BatchResult) Report() RunReport {
+	report := RunReport{
+		StartedAt:  r.StartedAt,
+		DurationMS: r.DurationMS,
+		TokenUsage: r.TokenUsage,
+		Files:      r.Files,
+		Stopped:    r.Stopped,
+	}
+	for _, file := range r.Files {
+		switch file.Status {
+		case FileStatusFailed:
+			report.Failed++
+		case FileStatusElsewhere:
+			report.Elsewhere++
+		default:
+			report.Succeeded++
+		}
+		if file.BudgetExceeded {
+			report.BudgetExceeded = true
+		}
+		report.Pages += file.Pages
+		report.OCRPages += file.OCRPages
+		report.Chunks += file.Chunks
+	}
+	return report
+}
+
+// WriteJSON writes the report as indented JSON
+func (r RunReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r); err != nil {
+		return fmt.Errorf("failed to write run report: %w", err)
+	}
+	return nil
+}
+
+// WriteTable writes the report as an aligned text table with a totals row
+func (r RunReport) WriteTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FILE\tSTATUS\tPAGES\tOCR\tCHUNKS\tTOKENS\tDURATION\tERROR")
+	for _, file := range r.Files {
+		status := file.Status
+		if file.Resumed {
+			status += " (resumed)"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n", file.Filename, status, file.Pages,
+			file.OCRPages, file.Chunks, file.TokenUsage.TotalTokens, formatDuration(file.DurationMS), file.Error)
+	}
+	counts := fmt.Sprintf("%d ok, %d failed", r.Succeeded, r.Failed)
+	if r.Elsewhere > 0 {
+		counts += fmt.Sprintf(", %d elsewhere", r.Elsewhere)
+	}
+	fmt.Fprintf(table, "TOTAL (%s)\t\t%d\t%d\t%d\t%d\t%s\t\n", counts, r.Pages,
+		r.OCRPages, r.Chunks, r.TokenUsage.TotalTokens, formatDuration(r.DurationMS))
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if r.BudgetExceeded {
+		if _, err := fmt.Fprintln(w, "AI budget exceeded: remaining documents were chunked locally (status local) or failed"); err != nil {
+			return err
+		}
+	}
+	if r.Stopped {
+		_, err := fmt.Fprintln(w, "Stopped before every document was processed: run again to resume")
+		return err
+	}
+	return nil
+}
+
+// formatDuration formats milliseconds for the report table
+func formatDuration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
@@ -0,0 +1,17 @@
+package chunker
+
+import (
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/rtl"
+)
+
+// normalizePage puts right-to-left lines extracted in visual order back in logical order
+// with the letters of their Arabic presentation forms, and replaces Arabic-Indic digits
+// with ASCIIDigits
+func (c *Chunker) normalizePage(page processor.Page) processor.Page {
+	page.Text = rtl.Normalize(page.Text)
+	if c.config.ASCIIDigits {
+		page.Text = rtl.ASCIIDigits(page.Text)
+	}
+	return page
+}
@@ -0,0 +1,21 @@
+package chunker
+
+import (
+	"fmt"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+// checkSizeUnit makes every document fail when the size unit is unknown, rather than
+// chunking with sizes it was not meant for
+func (c *Chunker) checkSizeUnit() {
+	switch c.config.SizeUnit {
+	case config.SizeRunes, config.SizeBytes, config.SizeTokens:
+		return
+	}
+	err := fmt.Errorf("unknown size unit %q", c.config.SizeUnit)
+	if c.configErr == nil {
+		c.configErr = err
+		c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+	}
+}
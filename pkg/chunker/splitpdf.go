@@ -0,0 +1,167 @@
+package chunker
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// checkSplitPDF makes every document fail when the SplitPDF mode is unknown
+func (c *Chunker) checkSplitPDF() {
+	switch c.config.SplitPDF {
+	case "", config.SplitPDFChunk, config.SplitPDFSection:
+		return
+	}
+	err := fmt.Errorf("unknown split PDF mode %q", c.config.SplitPDF)
+	if c.configErr == nil {
+		c.configErr = err
+		c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+	}
+}
+
+// splitSource returns a PDF input as a file path with SplitPDF or Elements, so its pages
+// can be split and its layout read once it is chunked, along with that path. Bytes and readers are spooled to a temp file
+// named input.pdf, which cleanup removes. Other inputs are returned as they are.
+func (c *Chunker) splitSource(inputType InputType, input interface{}) (interface{}, string, func(), error) {
+	noCleanup := func() {}
+	if inputType != InputPDF || (c.config.SplitPDF == "" && !c.config.Elements) || c.configErr != nil {
+		return input, "", noCleanup, nil
+	}
+	switch v := input.(type) {
+	case string:
+		return v, v, noCleanup, nil
+	case []byte, io.Reader:
+	default:
+		return input, "", noCleanup, nil
+	}
+
+	input, err := c.limitInput(inputType, input)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	dir, err := tempfile.MkdirTemp(c.config.TempDir, "split-")
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	path := filepath.Join(dir, "input.pdf")
+	err = writeInput(path, input)
+	if err != nil {
+		tempfile.Remove(dir)
+		return nil, "", nil, fmt.Errorf("failed to spool PDF for splitting: %w", err)
+	}
+	return path, path, func() { tempfile.Remove(dir) }, nil
+}
+
+// writeInput writes byte or reader input to a new file at path
+func writeInput(path string, input interface{}) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch v := input.(type) {
+	case []byte:
+		_, err = file.Write(v)
+	case io.Reader:
+		_, err = io.Copy(file, v)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// pageSpan is a range of pages, both 1-based and inclusive
+type pageSpan struct {
+	first, last int
+}
+
+// splitPDF writes the source pages of a PDF's chunks to SplitPDFDir/<name>, one PDF per
+// chunk page range or per top-level section, and lists the file of every chunk under
+// "source_pdf" in its metadata. Failing to split is a warning, as the chunks stand
+// without the files.
+func (c *Chunker) splitPDF(result *ChunkResult, document pagedText, filename, name string) {
+	if c.config.SplitPDF == "" || result.sourcePDF == "" {
+		return
+	}
+	spans := make([]pageSpan, len(result.Chunks))
+	for i, chunk := range result.Chunks {
+		spans[i] = pageSpan{chunk.StartPage, chunk.EndPage}
+	}
+	if c.config.SplitPDF == config.SplitPDFSection {
+		c.sectionSpans(result, document, filename, name, spans)
+	}
+
+	dir := filepath.Join(c.config.SplitPDFDir, filepath.FromSlash(name))
+	splitters := c.pdfSplitters
+	if splitters == nil {
+		splitters = processor.DefaultSplitters()
+	}
+	c.removeStaleStaging(dir)
+	files := make(map[pageSpan]string)
+	err := utils.WriteDirAtomic(dir, func(staging string) error {
+		for _, span := range spans {
+			if _, ok := files[span]; ok || span.first < 1 || span.last < span.first {
+				continue
+			}
+			file := fmt.Sprintf("pages_%d-%d.pdf", span.first, span.last)
+			if err := processor.SplitPDFPages(splitters, result.sourcePDF, filepath.Join(staging, file), span.first, span.last); err != nil {
+				return err
+			}
+			files[span] = filepath.ToSlash(filepath.Join(dir, file))
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Printf("Warning: failed to split %s: %v", filename, err)
+		return
+	}
+
+	for i := range result.Chunks {
+		file, ok := files[spans[i]]
+		if !ok {
+			continue
+		}
+		chunk := &result.Chunks[i]
+		if chunk.Metadata == nil {
+			chunk.Metadata = make(map[string]any)
+		}
+		chunk.Metadata["source_pdf"] = file
+	}
+}
+
+// sectionSpans replaces the page range of every chunk covered by a top-level section of
+// the table of contents with the pages of the first such section. Chunks outside every
+// section, such as a preface, keep their own pages.
+func (c *Chunker) sectionSpans(result *ChunkResult, document pagedText, filename, name string, spans []pageSpan) {
+	toc := result.TOC
+	if toc == nil {
+		toc = c.documentTOC(document, result.Report, result.Chunks, filename, name)
+	}
+	if toc == nil {
+		return
+	}
+	positions := make(map[int]int, len(result.Chunks)) // Chunk positions by ChunkIndex
+	for i, chunk := range result.Chunks {
+		positions[chunk.ChunkIndex] = i
+	}
+	assigned := make(map[int]bool)
+	for _, entry := range toc.Entries {
+		if entry.Page < 1 || entry.EndPage < entry.Page {
+			continue
+		}
+		for _, index := range entry.Chunks {
+			i, ok := positions[index]
+			if !ok || assigned[i] {
+				continue
+			}
+			spans[i] = pageSpan{entry.Page, entry.EndPage}
+			assigned[i] = true
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package chunker
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/redact"
+)
+
+// streamFlushChunks is the number of chunks of text buffered before a streamed document
+// is split; larger buffers cut fewer chunks short at a flush, smaller ones use less memory
+const streamFlushChunks = 8
+
+// ChunkFunc receives the chunks of a streamed document in order; returning an error
+// stops the processing
+type ChunkFunc func(chunk ChunkData) error
+
+// ChunkInputStream processes a document as a pipeline of page → splitter → emit, so
+// memory stays bounded by a few chunks instead of the whole document. PDFs are extracted
+// page by page; other inputs are extracted first and then streamed. Each chunk is passed
+// to emit (which may be nil) and to the configured sinks as soon as it is created;
+// nothing is written to the output directories and ChunkResult.Chunks stays empty.
+// Pages are buffered up to a few chunks and split at page boundaries, so a chunk can
+// end early where a buffer was flushed. Only the general profile can be streamed.
+func (c *Chunker) ChunkInputStream(inputType InputType, input interface{}, emit ChunkFunc) (*ChunkResult, error) {
+	c = c.forDocument()
+	if c.config.Profile != config.ProfileGeneral {
+		return nil, fmt.Errorf("streaming does not support the %s profile", c.config.Profile)
+	}
+	useAI, budgetExceeded, err := c.useAI()
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &chunkStream{
+		chunker:    c,
+		pageGroups: inputType == InputPPTX,
+		useAI:      useAI,
+		emit:       emit,
+	}
+	stream.flushSize = c.config.LocalChunkSize * streamFlushChunks
+	if useAI {
+		stream.flushSize = c.config.MaxChunkSize * streamFlushChunks
+	}
+
+	var report *processor.DocumentReport
+	if inputType == InputPDF {
+		stream.filename = "input.pdf"
+		if path, ok := input.(string); ok {
+			stream.filename = filepath.Base(path)
+		}
+		err = c.configErr
+		if err == nil {
+			input, err = c.limitInput(inputType, input)
+		}
+		corrections := 0
+		if err == nil {
+			report, err = c.streamPDFPages(input, func(page processor.Page) error {
+				// Only the words of the page itself are known besides the dictionary
+				pages := []processor.Page{c.normalizePage(page)}
+				corrections += c.correctPages(pages)
+				return stream.addPage(c.redactPage(c.filterPage(pages[0])))
+			})
+		}
+		if corrections > 0 && report != nil {
+			report.OCRCorrections = corrections
+		}
+	} else {
+		var pages []processor.Page
+		pages, stream.filename, report, err = c.extractPages(inputType, input)
+		for _, page := range pages {
+			if err != nil {
+				break
+			}
+			err = stream.addPage(page)
+		}
+	}
+	if err == nil {
+		err = stream.flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stream.chunks == 0 {
+		return nil, fmt.Errorf("input text is empty")
+	}
+
+	return &ChunkResult{TokenUsage: stream.tokenUsage, Report: report, Pages: stream.pages, BudgetExceeded: budgetExceeded, Redactions: stream.redactions}, nil
+}
+
+// streamPDFPages extracts a PDF input (file path, binary data or reader) page by page
+func (c *Chunker) streamPDFPages(input interface{}, fn processor.PageFunc) (*processor.DocumentReport, error) {
+	switch v := input.(type) {
+	case string:
+		return c.pdfProcessor.StreamPagesFromPDFPath(v, fn)
+	case []byte:
+		return c.pdfProcessor.StreamPagesFromPDFBytes(v, fn)
+	case io.Reader:
+		return c.pdfProcessor.StreamPagesFromPDFReader(v, fn)
+	default:
+		return nil, fmt.Errorf("unsupported PDF input: %T", input)
+	}
+}
+
+// chunkStream buffers the pages of a streamed document and turns them into chunks
+type chunkStream struct {
+	chunker    *Chunker
+	filename   string
+	pageGroups bool
+	useAI      bool
+	emit       ChunkFunc
+	flushSize  int
+
+	buffer     []processor.Page
+	size       int
+	pages      int
+	chunks     int // Chunks emitted so far
+	slices     int // AI slices sent so far
+	tokenUsage TokenUsage
+	redactions *RedactionReport // Set with RedactPII
+}
+
+// addPage buffers a page and splits the buffer once it holds enough text
+func (s *chunkStream) addPage(page processor.Page) error {
+	s.pages++
+	s.buffer = append(s.buffer, page)
+	s.size += s.chunker.textProcessor.Size(page.Text)
+	if s.size < s.flushSize {
+		return nil
+	}
+	return s.flush()
+}
+
+// flush chunks the buffered pages, numbering chunks after those already emitted
+func (s *chunkStream) flush() error {
+	document := newPagedText(s.buffer)
+	s.buffer, s.size = nil, 0
+	if strings.TrimSpace(document.text) == "" {
+		return nil
+	}
+
+	c := s.chunker
+	chunks, tokenUsage, err := c.createChunksWithUsage(document, s.filename, s.pageGroups, s.useAI)
+	if err != nil {
+		return fmt.Errorf("failed to create chunks: %w", err)
+	}
+	s.tokenUsage.PromptTokens += tokenUsage.PromptTokens
+	s.tokenUsage.CompletionTokens += tokenUsage.CompletionTokens
+	s.tokenUsage.TotalTokens += tokenUsage.TotalTokens
+
+	for i := range chunks {
+		chunks[i].ChunkIndex += s.chunks
+		if chunks[i].ParentIndex > 0 {
+			chunks[i].ParentIndex += s.slices
+		}
+	}
+	s.chunks += len(chunks)
+	if s.useAI {
+		s.slices += len(c.splitForAI(document, s.pageGroups))
+	}
+
+	if c.redactor != nil {
+		if s.redactions == nil {
+			s.redactions = &RedactionReport{Total: redact.Counts{}}
+		}
+		s.redactions.add(chunks)
+	}
+
+	c.completeChunks(chunks)
+	chunks, _ = c.scoreChunks(chunks)
+	chunks, checks, _ := c.dedupChunks(chunks)
+	c.summarizeChunks(chunks)
+	if err := c.embedChunks(chunks); err != nil {
+		return err
+	}
+	if err := c.writeSinks(chunks); err != nil {
+		return err
+	}
+	if s.emit != nil {
+		for _, chunk := range chunks {
+			if err := s.emit(chunk); err != nil {
+				return err
+			}
+		}
+	}
+	c.commitDedup(checks)
+	return nil
+}
@@ -0,0 +1,120 @@
+package chunker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/providers"
+)
+
+// StructureAIProvider is an AI provider that supports AIModeStructure: StructureText
+// answers with the JSON sections of providers.ParseStructure instead of rewritten text.
+// The built-in providers implement it.
+type StructureAIProvider interface {
+	AIProvider
+	StructureText(text string) (*providers.ChunkResult, error)
+}
+
+// checkAIMode makes every document fail when the AI mode is unknown, or when
+// AIModeStructure is set with a provider that cannot answer structure requests, rather
+// than letting the provider rewrite the text
+func (c *Chunker) checkAIMode() {
+	var err error
+	switch c.config.AIMode {
+	case config.AIModeRewrite:
+	case config.AIModeStructure:
+		if _, ok := c.aiProvider.(StructureAIProvider); c.aiProvider != nil && !ok {
+			err = fmt.Errorf("AI provider %s does not support the %s AI mode", c.aiProvider.GetName(), config.AIModeStructure)
+		}
+	default:
+		err = fmt.Errorf("unknown AI mode %q", c.config.AIMode)
+	}
+	if err != nil && c.configErr == nil {
+		c.configErr = err
+		c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+	}
+}
+
+// structureSlice asks the AI provider where the sections of a slice start and appends
+// them as chunks cut verbatim from the slice, with their titles. Slices without a usable
+// answer become a single chunk, also verbatim.
+func (c *Chunker) structureSlice(chunks []ChunkData, slice, filename string, index int, document pagedText, s span) ([]ChunkData, TokenUsage, error) {
+	var usage TokenUsage
+	answer, err := c.askAI(slice, filename, index, aiStructure, &usage)
+	if err != nil {
+		return nil, usage, err
+	}
+	var sections []providers.Section
+	if answer.err == nil {
+		sections, answer.err = providers.ParseStructure(answer.text)
+	}
+	if answer.err != nil {
+		c.logger.Printf("Warning: no structure for slice %d of %s (%v), keeping it whole", index, filename, answer.err)
+		sections = []providers.Section{{Line: 1}}
+	}
+
+	lines := providers.StructureLines(slice)
+	lineStarts := make([]int, len(lines)+1)
+	for i, line := range lines {
+		lineStarts[i+1] = lineStarts[i] + len(line)
+	}
+
+	sections = sectionStarts(sections, len(lines))
+	first := len(chunks)
+	for i, section := range sections {
+		end := len(slice)
+		if i+1 < len(sections) {
+			end = lineStarts[sections[i+1].Line-1]
+		}
+		start := lineStarts[section.Line-1]
+
+		// Only the whitespace around a section is dropped
+		text := slice[start:end]
+		trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+		start += len(text) - len(trimmed)
+		text = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+		if text == "" {
+			continue
+		}
+
+		sectionSpan := s
+		if s.start >= 0 {
+			sectionSpan = span{start: s.start + start, end: s.start + start + len(text)}
+		}
+		chunk := newChunkData(filename, len(chunks)+1, document, sectionSpan, text)
+		chunk.Title = strings.TrimSpace(section.Title)
+		chunks = append(chunks, chunk)
+	}
+
+	if len(chunks)-first > 1 {
+		for i := first; i < len(chunks); i++ {
+			chunks[i].ParentIndex = index
+		}
+	}
+	return chunks, usage, nil
+}
+
+// sectionStarts sorts the sections of an answer by line, dropping lines outside the
+// text and repeated ones, and makes sure the first section starts at line 1
+func sectionStarts(sections []providers.Section, lines int) []providers.Section {
+	var valid []providers.Section
+	for _, section := range sections {
+		if section.Line >= 1 && section.Line <= lines {
+			valid = append(valid, section)
+		}
+	}
+	slices.SortStableFunc(valid, func(a, b providers.Section) int {
+		return a.Line - b.Line
+	})
+	valid = slices.CompactFunc(valid, func(a, b providers.Section) bool {
+		return a.Line == b.Line
+	})
+
+	if len(valid) == 0 || valid[0].Line != 1 {
+		valid = append([]providers.Section{{Line: 1}}, valid...)
+	}
+	return valid
+}
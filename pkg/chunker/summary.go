@@ -0,0 +1,18 @@
+package chunker
+
+import (
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/summary"
+)
+
+// summarizeChunks sets the extractive Summary of every chunk with Summarize. It runs
+// locally for AI and local chunks alike, so summaries cost no tokens.
+func (c *Chunker) summarizeChunks(chunks []ChunkData) {
+	if !c.config.Summarize {
+		return
+	}
+	for i := range chunks {
+		chunks[i].Summary = strings.Join(summary.Summarize(chunks[i].Text, c.config.SummarySentences), " ")
+	}
+}
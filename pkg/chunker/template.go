@@ -0,0 +1,33 @@
+package chunker
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// setupChunkTemplate parses the template of ChunkTemplatePath, unless one was given with
+// WithChunkTemplate, and checks that it renders, or makes every document fail when it
+// does not
+func (c *Chunker) setupChunkTemplate() {
+	var err error
+	switch {
+	case c.chunkTemplate != nil:
+		err = utils.CheckTemplate(c.chunkTemplate)
+	case c.config.ChunkTemplatePath != "":
+		var text []byte
+		if text, err = os.ReadFile(c.config.ChunkTemplatePath); err != nil {
+			err = fmt.Errorf("failed to read chunk template: %w", err)
+			break
+		}
+		c.chunkTemplate, err = utils.ParseTemplate(string(text))
+	}
+	if err != nil {
+		c.chunkTemplate = nil
+		if c.configErr == nil {
+			c.configErr = err
+			c.logger.Printf("Error: %v; every document will fail until it is fixed", err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package chunker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// Files of a table of contents, written next to the manifest with TableOfContents
+const (
+	TOCFilename         = "toc.json"
+	TOCMarkdownFilename = "toc.md"
+)
+
+// position is a place in a document: a page and a character offset in its text
+type position struct {
+	page, offset int
+}
+
+// before reports whether p comes before q
+func (p position) before(q position) bool {
+	return p.page < q.page || p.page == q.page && p.offset < q.offset
+}
+
+// documentTOC builds the table of contents of a document from the bookmarks of the PDF,
+// or from its numbered headings when it has none, and lists the chunks covering every
+// section. It returns nil for documents without headings.
+func (c *Chunker) documentTOC(document pagedText, report *processor.DocumentReport, chunks []ChunkData, filename, slug string) *schema.TOC {
+	headings, source := c.documentHeadings(document), schema.TOCSourceHeadings
+	if report != nil && len(report.Bookmarks) > 0 {
+		headings, source = locateBookmarks(document, report.Bookmarks), schema.TOCSourceBookmarks
+	}
+	if len(headings) == 0 {
+		return nil
+	}
+
+	documentEnd := position{page: math.MaxInt}
+	lastPage := 0
+	if len(document.pages) > 0 {
+		lastPage = document.pages[len(document.pages)-1].Number
+	}
+
+	toc := &schema.TOC{Filename: filename, Slug: slug, Source: source}
+	var parents []*schema.TOCEntry // The open entry of each level above the current one
+	for i, heading := range headings {
+		entry := &schema.TOCEntry{Level: heading.level, Title: heading.title, Page: heading.page}
+		if !heading.lost {
+			start, end := position{heading.page, heading.offset}, documentEnd
+			for _, next := range headings[i+1:] {
+				if !next.lost && next.level <= heading.level {
+					end = position{next.page, next.offset}
+					break
+				}
+			}
+			switch {
+			case end == documentEnd:
+				entry.EndPage = lastPage
+			case end.offset == 0 && end.page > heading.page:
+				entry.EndPage = end.page - 1
+			default:
+				entry.EndPage = end.page
+			}
+			entry.Chunks = chunksBetween(chunks, start, end)
+		}
+
+		for len(parents) > 0 && parents[len(parents)-1].Level >= entry.Level {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) == 0 {
+			toc.Entries = append(toc.Entries, entry)
+		} else {
+			parent := parents[len(parents)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+		parents = append(parents, entry)
+	}
+	return toc
+}
+
+// chunksBetween returns the indexes of the located chunks overlapping the text from start
+// up to end
+func chunksBetween(chunks []ChunkData, start, end position) []int {
+	var indexes []int
+	for _, chunk := range chunks {
+		if chunk.EndPage == 0 && chunk.EndOffset == 0 {
+			continue // Not located in the document
+		}
+		chunkStart := position{chunk.StartPage, chunk.StartOffset}
+		chunkEnd := position{chunk.EndPage, chunk.EndOffset}
+		if chunkStart.before(end) && start.before(chunkEnd) {
+			indexes = append(indexes, chunk.ChunkIndex)
+		}
+	}
+	return indexes
+}
+
+// locateBookmarks finds the heading line of every bookmark: on its page when the engine
+// resolved it, or else on the pages after the previous bookmark. A line starting with the
+// title is preferred over one containing it, for text extracted without line breaks.
+// Bookmarks whose page is known but whose title is not found start at the top of their
+// page.
+func locateBookmarks(document pagedText, bookmarks []processor.Bookmark) []locatedHeading {
+	headings := make([]locatedHeading, 0, len(bookmarks))
+	from := position{}
+	for _, bookmark := range bookmarks {
+		heading := locatedHeading{level: bookmark.Level, title: strings.TrimSpace(bookmark.Title), page: bookmark.Page}
+		title := normalizeTitle(bookmark.Title)
+		found := false
+		for _, match := range []func(line string) bool{
+			func(line string) bool { return strings.HasPrefix(line, title) },
+			func(line string) bool { return strings.Contains(line, title) },
+		} {
+			for _, page := range document.pages {
+				if found || bookmark.Page > 0 && page.Number != bookmark.Page {
+					continue
+				}
+				minOffset := -1
+				if bookmark.Page == 0 {
+					if page.Number < from.page {
+						continue
+					}
+					if page.Number == from.page {
+						minOffset = from.offset
+					}
+				}
+				if offset, ok := findTitle(page.Text, match, minOffset); ok {
+					heading.page, heading.offset, found = page.Number, offset, true
+				}
+			}
+		}
+		heading.lost = !found && bookmark.Page == 0
+		if !heading.lost {
+			from = position{heading.page, heading.offset}
+		}
+		headings = append(headings, heading)
+	}
+	return headings
+}
+
+// findTitle returns the character offset of the first line of a page at or after
+// minOffset whose normalized text matches
+func findTitle(text string, match func(line string) bool, minOffset int) (int, bool) {
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if offset >= minOffset && match(normalizeTitle(line)) {
+			return offset, true
+		}
+		offset += utf8.RuneCountInString(line)
+	}
+	return 0, false
+}
+
+// normalizeTitle lower-cases a title and drops its whitespace, so bookmarks match
+// heading lines extracted with other spacing, or none as with the pure-Go reader
+func normalizeTitle(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), ""))
+}
+
+// tocFiles returns the toc.json and toc.md files of a table of contents. Links in toc.md
+// point to the chunk text files, named with textExt, in the same directory.
+func tocFiles(toc *schema.TOC, textExt string) (map[string][]byte, error) {
+	data, err := json.MarshalIndent(toc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal table of contents: %w", err)
+	}
+
+	var markdown strings.Builder
+	fmt.Fprintf(&markdown, "# Table of Contents: %s\n\n", toc.Filename)
+	var write func(entries []*schema.TOCEntry, depth int)
+	write = func(entries []*schema.TOCEntry, depth int) {
+		for _, entry := range entries {
+			indent := strings.Repeat("  ", depth)
+			if len(entry.Chunks) == 0 {
+				fmt.Fprintf(&markdown, "%s- %s%s\n", indent, entry.Title, tocPages(entry))
+			} else {
+				file := chunkFilename(ChunkData{ChunkIndex: entry.Chunks[0]}, textExt)
+				fmt.Fprintf(&markdown, "%s- [%s](%s)%s, %s\n", indent, entry.Title, file, tocPages(entry), tocChunks(entry.Chunks))
+			}
+			write(entry.Children, depth+1)
+		}
+	}
+	write(toc.Entries, 0)
+
+	return map[string][]byte{
+		TOCFilename:         data,
+		TOCMarkdownFilename: []byte(markdown.String()),
+	}, nil
+}
+
+// tocPages describes the pages of a table of contents entry, e.g. " (pages 3–5)"
+func tocPages(entry *schema.TOCEntry) string {
+	switch {
+	case entry.Page == 0:
+		return ""
+	case entry.EndPage <= entry.Page:
+		return fmt.Sprintf(" (page %d)", entry.Page)
+	default:
+		return fmt.Sprintf(" (pages %d–%d)", entry.Page, entry.EndPage)
+	}
+}
+
+// tocChunks describes the chunks of a table of contents entry, e.g. "chunks 4–6"
+func tocChunks(chunks []int) string {
+	first, last := chunks[0], chunks[len(chunks)-1]
+	if first == last {
+		return fmt.Sprintf("chunk %d", first)
+	}
+	return fmt.Sprintf("chunks %d–%d", first, last)
+}
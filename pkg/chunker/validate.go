@@ -0,0 +1,228 @@
+package chunker
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/providers"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// Validator checks the AI output for a slice of text. Validate returns an error saying
+// why the output is rejected, or nil when it passes.
+type Validator interface {
+	Validate(input, output string) error
+	GetName() string
+}
+
+// StrictAIProvider is an AI provider that can chunk with stricter instructions to keep
+// the text verbatim. Rejected outputs are retried with ChunkTextStrict, or with the usual
+// request for providers without it.
+type StrictAIProvider interface {
+	AIProvider
+	ChunkTextStrict(text string) (*providers.ChunkResult, error)
+}
+
+// defaultEntityCoverage is the share of key entities the EntityValidator of
+// DefaultValidators requires in an output
+const defaultEntityCoverage = 0.9
+
+// DefaultValidators returns the validators used with ValidateAI: NonEmptyValidator,
+// MinLengthValidator with MinAIOutputRatio, RefusalValidator and EntityValidator, plus
+// FidelityValidator when FidelityThreshold is set
+func DefaultValidators(cfg config.ChunkerConfig) []Validator {
+	validators := []Validator{
+		NonEmptyValidator(),
+		MinLengthValidator(cfg.MinAIOutputRatio),
+		RefusalValidator(),
+		EntityValidator(defaultEntityCoverage),
+	}
+	if cfg.FidelityThreshold > 0 {
+		validators = append(validators, FidelityValidator(cfg.FidelityThreshold))
+	}
+	return validators
+}
+
+// validateAI runs every validator on an AI output and returns the failures, each
+// prefixed with the validator name
+func (c *Chunker) validateAI(input, output string) []string {
+	output = strings.Join(splitSections(output), "\n\n")
+	var failures []string
+	for _, validator := range c.validators {
+		if err := validator.Validate(input, output); err != nil {
+			failures = append(failures, validator.GetName()+": "+err.Error())
+		}
+	}
+	return failures
+}
+
+// newValidation returns the validation of a slice answer that passed on the first try,
+// or nil when AI outputs are not validated
+func (c *Chunker) newValidation() *schema.Validation {
+	if len(c.validators) == 0 {
+		return nil
+	}
+	return &schema.Validation{Outcome: schema.ValidationPassed}
+}
+
+type nonEmptyValidator struct{}
+
+// NonEmptyValidator rejects outputs without any text
+func NonEmptyValidator() Validator {
+	return nonEmptyValidator{}
+}
+
+// Validate rejects a blank output
+func (nonEmptyValidator) Validate(input, output string) error {
+	if strings.TrimSpace(output) == "" {
+		return fmt.Errorf("output is empty")
+	}
+	return nil
+}
+
+// GetName returns the validator name
+func (nonEmptyValidator) GetName() string {
+	return "non_empty"
+}
+
+type minLengthValidator struct {
+	ratio float64
+}
+
+// MinLengthValidator rejects outputs shorter than ratio times their input, counting
+// characters other than whitespace, which usually means the AI summarized or dropped
+// part of the text
+func MinLengthValidator(ratio float64) Validator {
+	return minLengthValidator{ratio: ratio}
+}
+
+// Validate rejects an output that is too short
+func (v minLengthValidator) Validate(input, output string) error {
+	inputLength := countNonSpace(input)
+	if inputLength == 0 || v.ratio <= 0 {
+		return nil
+	}
+	if share := float64(countNonSpace(output)) / float64(inputLength); share < v.ratio {
+		return fmt.Errorf("output is %.0f%% of the input, below %.0f%%", share*100, v.ratio*100)
+	}
+	return nil
+}
+
+// GetName returns the validator name
+func (minLengthValidator) GetName() string {
+	return "min_length"
+}
+
+// countNonSpace counts the characters of text other than whitespace
+func countNonSpace(text string) int {
+	count := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			count++
+		}
+	}
+	return count
+}
+
+// refusalPattern matches the phrases models use to refuse or to talk about themselves,
+// in English and Indonesian
+var refusalPattern = regexp.MustCompile(`(?i)\bas an ai\b|\bas a(?:n ai)? language model\b|\bi(?:'m| am) (?:sorry|unable)\b|\bi (?:cannot|can't|can not) (?:help|assist|process|comply|complete)\b|\bsebagai (?:model bahasa|ai)\b|\bmaaf, saya tidak (?:dapat|bisa)\b`)
+
+type refusalValidator struct{}
+
+// RefusalValidator rejects outputs containing refusals or remarks such as "As an AI
+// language model" that are not in the input
+func RefusalValidator() Validator {
+	return refusalValidator{}
+}
+
+// Validate rejects an output with a refusal phrase the input does not have
+func (refusalValidator) Validate(input, output string) error {
+	for _, phrase := range refusalPattern.FindAllString(output, -1) {
+		if !strings.Contains(strings.ToLower(input), strings.ToLower(phrase)) {
+			return fmt.Errorf("output contains %q", phrase)
+		}
+	}
+	return nil
+}
+
+// GetName returns the validator name
+func (refusalValidator) GetName() string {
+	return "refusal"
+}
+
+type entityValidator struct {
+	minCoverage float64
+}
+
+// EntityValidator rejects outputs that keep less than minCoverage of the key entities of
+// their input: the numbers, dates, amounts, article numbers and document codes, i.e. the
+// words containing a digit
+func EntityValidator(minCoverage float64) Validator {
+	return entityValidator{minCoverage: minCoverage}
+}
+
+// Validate rejects an output that lost too many key entities
+func (v entityValidator) Validate(input, output string) error {
+	entities := keyEntities(input)
+	if len(entities) == 0 {
+		return nil
+	}
+
+	kept := keyEntities(output)
+	var missing []string
+	for entity := range entities {
+		if !kept[entity] {
+			missing = append(missing, entity)
+		}
+	}
+	coverage := float64(len(entities)-len(missing)) / float64(len(entities))
+	if coverage >= v.minCoverage {
+		return nil
+	}
+
+	// Name a few, in input order, so the failure is actionable
+	var examples []string
+	for _, word := range strings.Fields(input) {
+		entity := entityWord(word)
+		if entity != "" && !kept[entity] && !slices.Contains(examples, entity) {
+			examples = append(examples, entity)
+			if len(examples) == 3 {
+				break
+			}
+		}
+	}
+	return fmt.Errorf("output keeps %d of %d key entities, missing %s", len(entities)-len(missing), len(entities), strings.Join(examples, ", "))
+}
+
+// GetName returns the validator name
+func (entityValidator) GetName() string {
+	return "key_entities"
+}
+
+// keyEntities returns the distinct words of text that contain a digit
+func keyEntities(text string) map[string]bool {
+	entities := map[string]bool{}
+	for _, word := range strings.Fields(text) {
+		if entity := entityWord(word); entity != "" {
+			entities[entity] = true
+		}
+	}
+	return entities
+}
+
+// entityWord trims the punctuation around a word and returns it when it contains a
+// digit and is at least two characters long, or "" otherwise
+func entityWord(word string) string {
+	word = strings.TrimFunc(word, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	if len(word) < 2 || !strings.ContainsFunc(word, unicode.IsDigit) {
+		return ""
+	}
+	return word
+}
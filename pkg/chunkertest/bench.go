@@ -0,0 +1,71 @@
+package chunkertest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// CorpusPages is the length of the corpus the benchmarks of the chunker and its text
+// splitters chunk, in pages
+const CorpusPages = 1000
+
+// corpusWords are the words corpus paragraphs are made of, Indonesian and English as in
+// the documents the chunker is mostly used on
+var corpusWords = strings.Fields(`pasal ayat bab bagian peraturan pemerintah menteri
+	ketentuan sebagaimana dimaksud dalam huruf dan atau yang dengan untuk pada tersebut
+	dapat wajib berlaku sejak tanggal diundangkan the of and to in for is on that by
+	with policy employee contract payment agreement shall party notice period data`)
+
+// Corpus returns a synthetic document of pages pages joined with "--- Page N ---"
+// separators, as the PDF processor extracts them: chapters, articles, paragraphs,
+// lists, blank lines and the odd line longer than a chunk. The same pages always give
+// the same text.
+func Corpus(pages int) string {
+	random := rand.New(rand.NewPCG(uint64(pages), 1))
+	var text strings.Builder
+	article := 0
+	for page := 1; page <= pages; page++ {
+		fmt.Fprintf(&text, "\n\n--- Page %d ---\n\n", page)
+		if page%20 == 1 {
+			fmt.Fprintf(&text, "BAB %d\nKETENTUAN UMUM\n\n", page/20+1)
+		}
+		for block := 0; block < 4; block++ {
+			switch random.IntN(6) {
+			case 0:
+				article++
+				fmt.Fprintf(&text, "Pasal %d\n\n", article)
+			case 1:
+				for item := 1; item <= 3; item++ {
+					fmt.Fprintf(&text, "%d. %s\n", item, sentence(random, 12))
+				}
+			case 2:
+				for item := 0; item < 3; item++ {
+					fmt.Fprintf(&text, "- %s\n", sentence(random, 8))
+				}
+			case 3:
+				// Longer than a chunk, so it is cut into sentences
+				for i := 0; i < 60; i++ {
+					text.WriteString(sentence(random, 12) + " ")
+				}
+				text.WriteString("\n")
+			default:
+				for line := 0; line < 5; line++ {
+					text.WriteString(sentence(random, 14) + "\n")
+				}
+			}
+			text.WriteString("\n")
+		}
+	}
+	return text.String()
+}
+
+// sentence returns a capitalized sentence of words random corpus words
+func sentence(random *rand.Rand, words int) string {
+	parts := make([]string, words)
+	for i := range parts {
+		parts[i] = corpusWords[random.IntN(len(corpusWords))]
+	}
+	parts[0] = strings.ToUpper(parts[0][:1]) + parts[0][1:]
+	return strings.Join(parts, " ") + "."
+}
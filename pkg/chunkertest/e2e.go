@@ -0,0 +1,86 @@
+package chunkertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ocr"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/providers"
+)
+
+// SamplesDir is the directory of the bundled samples, relative to this package
+const SamplesDir = "testdata/samples"
+
+// goldenChunkSize is the chunk size of RunGolden, small enough that the longer samples
+// are split into several chunks
+const goldenChunkSize = 400
+
+// RunGolden runs every PDF in dir through the whole pipeline, as a subtest each, and
+// compares the result with dir/golden/<name>.json. The AI provider is a FakeProvider
+// that returns every slice unchanged, and OCR reads <name>.ocr.json, a JSON array of
+// page texts, so the results only change with the chunking logic. Scanned samples need
+// a build that renders pages, not the purego one. TestGolden runs it on the bundled
+// samples in builds with MuPDF; after an intended change, rewrite the golden files with UPDATE_GOLDEN=1.
+func RunGolden(t *testing.T, dir string) {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pdf"))
+	if err != nil {
+		t.Fatalf("failed to list samples: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no sample PDFs in %s", dir)
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".pdf")
+		t.Run(name, func(t *testing.T) {
+			engine, err := sampleOCR(filepath.Join(dir, name+".ocr.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := Config(t)
+			cfg.MaxChunkSize, cfg.LocalChunkSize = goldenChunkSize, goldenChunkSize
+			instance := chunker.NewChunker(
+				chunker.WithConfig(cfg),
+				chunker.WithProvider(providers.NewFakeProvider()),
+				chunker.WithOCREngine(engine),
+			)
+			defer instance.Close()
+
+			result, err := instance.ChunkInputWithUsage(chunker.InputPDF, path, chunker.OutputJSON)
+			if err != nil {
+				t.Fatalf("failed to chunk %s: %v", name, err)
+			}
+			AssertGoldenJSON(t, filepath.Join(dir, "golden", name+".json"), result)
+		})
+	}
+}
+
+// sampleOCR returns a fake OCR engine that reads the page texts in path on the rendered
+// pages, or recognizes no text when there is no such file
+func sampleOCR(path string) (*ocr.FakeEngine, error) {
+	var pages []string
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read OCR text: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &pages); err != nil {
+			return nil, fmt.Errorf("failed to parse OCR text: %w", err)
+		}
+	}
+
+	return ocr.NewFakeEngineFunc(func(imagePath string) (*ocr.Result, error) {
+		// The PDF processor renders page i to page_<i>.png
+		var pageIndex int
+		if _, err := fmt.Sscanf(filepath.Base(imagePath), "page_%d.png", &pageIndex); err != nil || pageIndex >= len(pages) {
+			return &ocr.Result{}, nil
+		}
+		return &ocr.Result{Text: pages[pageIndex], Confidence: 90}, nil
+	}), nil
+}
@@ -0,0 +1,94 @@
+// Package chunkertest helps unit-test pipelines built on the chunker without network
+// calls or tesseract: combine it with providers.FakeProvider and ocr.FakeEngine, and
+// compare the output with golden files
+package chunkertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+// UpdateEnv is the environment variable that makes the Assert functions rewrite golden
+// files instead of comparing them: UPDATE_GOLDEN=1 go test ./...
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// Config returns DefaultConfig with the output, chunk and JSON directories in a
+// temporary directory removed after the test
+func Config(t testing.TB) config.ChunkerConfig {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = filepath.Join(dir, "output")
+	cfg.ChunkDir = filepath.Join(dir, "chunk")
+	cfg.JSONDir = filepath.Join(dir, "json")
+	cfg.TempDir = filepath.Join(dir, "tmp")
+	return cfg
+}
+
+// AssertGolden compares got with the golden file at path and fails the test at the
+// first differing line
+func AssertGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with %s=1 to create it): %v", UpdateEnv, err)
+	}
+	if bytes.Equal(want, got) {
+		return
+	}
+	line, wantLine, gotLine := firstDiff(string(want), string(got))
+	t.Errorf("output differs from %s at line %d:\n  want: %s\n  got:  %s\n(run with %s=1 to update)", path, line, wantLine, gotLine, UpdateEnv)
+}
+
+// AssertGoldenJSON compares v, marshaled as indented JSON, with the golden file at path
+func AssertGoldenJSON(t testing.TB, path string, v any) {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal golden value: %v", err)
+	}
+	AssertGolden(t, path, append(data, '\n'))
+}
+
+// AssertChunks compares chunks with the golden file at path. Chunks hold no timestamps
+// or paths, so the same input and provider answers always give the same file.
+func AssertChunks(t testing.TB, path string, chunks []chunker.ChunkData) {
+	t.Helper()
+	AssertGoldenJSON(t, path, chunks)
+}
+
+// firstDiff returns the number and text of the first line that differs between want
+// and got; a missing line reads as <EOF>
+func firstDiff(want, got string) (int, string, string) {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; ; i++ {
+		wantLine, gotLine := "<EOF>", "<EOF>"
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+		if wantLine != gotLine || i >= len(wantLines) && i >= len(gotLines) {
+			return i + 1, wantLine, gotLine
+		}
+	}
+}
@@ -0,0 +1,15 @@
+// The golden files hold the text MuPDF extracts; the purego reader lays text out differently
+
+//go:build !purego
+
+package chunkertest_test
+
+import (
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+)
+
+func TestGolden(t *testing.T) {
+	chunkertest.RunGolden(t, chunkertest.SamplesDir)
+}
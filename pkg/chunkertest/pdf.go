@@ -0,0 +1,108 @@
+package chunkertest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// pdfText is a line of text drawn on a sample page in Helvetica, at X and Y points from
+// the bottom left corner
+type pdfText struct {
+	X, Y, Size float64
+	Text       string
+}
+
+// pdfPage is a sample page: text lines and, for scanned pages, a grayscale image
+// covering the text area instead of any text
+type pdfPage struct {
+	Texts []pdfText
+	Image bool
+}
+
+// pageWidth and pageHeight are the A4 page size in points
+const (
+	pageWidth  = 595
+	pageHeight = 842
+)
+
+// buildPDF writes a minimal PDF 1.4 document of pages. The output only depends on the
+// pages, so sample files can be regenerated byte for byte.
+func buildPDF(pages []pdfPage) []byte {
+	var objects []string
+	add := func(object string) int {
+		objects = append(objects, object)
+		return len(objects)
+	}
+
+	catalog := add("") // Filled in once the page tree is known
+	pageTree := add("")
+	font := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	var kids []string
+	for _, page := range pages {
+		resources := fmt.Sprintf("/Font << /F1 %d 0 R >>", font)
+		if page.Image {
+			image := add(streamObject("/Type /XObject /Subtype /Image /Width 64 /Height 64 /ColorSpace /DeviceGray /BitsPerComponent 8", scanImage(64, 64)))
+			resources += fmt.Sprintf(" /XObject << /Im1 %d 0 R >>", image)
+		}
+		content := add(streamObject("", pageContent(page)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", add(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << %s >> /Contents %d 0 R >>",
+			pageTree, pageWidth, pageHeight, resources, content))))
+	}
+	objects[catalog-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pageTree)
+	objects[pageTree-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, catalog, xref)
+	return out.Bytes()
+}
+
+// streamObject returns a stream object with the given dictionary entries
+func streamObject(dictionary string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dictionary, len(data), data)
+}
+
+// pageContent returns the content stream drawing a page
+func pageContent(page pdfPage) []byte {
+	var content bytes.Buffer
+	if page.Image {
+		fmt.Fprintf(&content, "q %d 0 0 %d 50 100 cm /Im1 Do Q\n", pageWidth-100, pageHeight-200)
+	}
+	for _, text := range page.Texts {
+		fmt.Fprintf(&content, "BT /F1 %g Tf %g %g Td (%s) Tj ET\n", text.Size, text.X, text.Y, escapePDFString(text.Text))
+	}
+	return content.Bytes()
+}
+
+// escapePDFString escapes the characters with a meaning in PDF literal strings
+func escapePDFString(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(text)
+}
+
+// scanImage returns the pixels of a width by height grayscale image of light paper with
+// darker bands, standing in for a scanned page
+func scanImage(width, height int) []byte {
+	pixels := make([]byte, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixels[y*width+x] = 235
+			if y%8 < 2 && x > 4 && x < width-4 {
+				pixels[y*width+x] = 60
+			}
+		}
+	}
+	return pixels
+}
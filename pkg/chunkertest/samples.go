@@ -0,0 +1,199 @@
+package chunkertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sample is a sample PDF of a kind of document the chunker meets in practice
+type Sample struct {
+	Name string   // File name without extension
+	PDF  []byte   // The document, see buildPDF
+	OCR  []string // Text the fake OCR engine recognizes on each page of scanned samples
+}
+
+// Samples returns the sample documents bundled in testdata: a digital PDF, a scanned
+// PDF without a text layer, a two-column article, a table and an Indonesian regulation
+func Samples() []Sample {
+	return []Sample{
+		{Name: "digital", PDF: buildPDF(digitalPages())},
+		{Name: "scanned", PDF: buildPDF([]pdfPage{{Image: true}, {Image: true}}), OCR: scannedText},
+		{Name: "multi_column", PDF: buildPDF(multiColumnPages())},
+		{Name: "table", PDF: buildPDF(tablePages())},
+		{Name: "indonesian_legal", PDF: buildPDF(legalPages())},
+	}
+}
+
+// WriteSamples writes every sample to dir as <name>.pdf, with the OCR text of scanned
+// samples next to it as <name>.ocr.json, to regenerate the bundled testdata
+func WriteSamples(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create sample directory: %w", err)
+	}
+	for _, sample := range Samples() {
+		if err := os.WriteFile(filepath.Join(dir, sample.Name+".pdf"), sample.PDF, 0644); err != nil {
+			return fmt.Errorf("failed to write sample %s: %w", sample.Name, err)
+		}
+		if sample.OCR == nil {
+			continue
+		}
+		data, err := json.MarshalIndent(sample.OCR, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal OCR text of %s: %w", sample.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, sample.Name+".ocr.json"), append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write OCR text of %s: %w", sample.Name, err)
+		}
+	}
+	return nil
+}
+
+// column lays out lines top down from y at x, with headings marked by a leading "# "
+// set larger
+func column(x, y float64, lines ...string) []pdfText {
+	var texts []pdfText
+	for _, line := range lines {
+		size := 10.0
+		if heading, ok := strings.CutPrefix(line, "# "); ok {
+			line, size = heading, 14
+			y -= 6
+		}
+		if line != "" {
+			texts = append(texts, pdfText{X: x, Y: y, Size: size, Text: line})
+		}
+		y -= size + 4
+	}
+	return texts
+}
+
+// digitalPages is an employee handbook with a text layer
+func digitalPages() []pdfPage {
+	return []pdfPage{
+		{Texts: column(50, 780,
+			"# Employee Handbook",
+			"HR/POL/2024/01",
+			"",
+			"# 1. Working Hours",
+			"Regular working hours are Monday to Friday, 08:00 to 17:00.",
+			"Employees record their attendance in the HR system every day.",
+			"Overtime must be approved in advance by the direct supervisor.",
+			"",
+			"# 2. Annual Leave",
+			"Every employee is entitled to 12 days of paid annual leave per year.",
+			"Leave requests are submitted at least 7 days before the first day.",
+			"- Unused leave expires at the end of the following year.",
+			"- Leave cannot be exchanged for money.",
+		)},
+		{Texts: column(50, 780,
+			"# 3. Salary Payment",
+			"Salaries are paid on the 25th of every month by bank transfer.",
+			"When the 25th falls on a holiday, salaries are paid the working day before.",
+			"Payslips are available in the HR system on the payment date.",
+			"",
+			"# 4. Code of Conduct",
+			"Employees treat colleagues, customers and partners with respect.",
+			"Conflicts of interest are reported to the compliance team.",
+		)},
+	}
+}
+
+// scannedText is what the fake OCR engine reads on the pages of the scanned sample
+var scannedText = []string{
+	"SURAT KEPUTUSAN\nNomor: KEP/DIR/015/2023\n\nTentang\nPenetapan Jam Kerja Karyawan\n\nMenimbang bahwa jam kerja perlu diatur\nuntuk meningkatkan produktivitas.\n",
+	"Memutuskan\n\n1. Jam kerja adalah 08.00 sampai 17.00.\n2. Keputusan ini berlaku sejak tanggal ditetapkan.\n\nDitetapkan di Jakarta\n12 - Januari - 2023\nDirektur Utama\n",
+}
+
+// multiColumnPages is a two-column article
+func multiColumnPages() []pdfPage {
+	texts := column(50, 800, "# Palm Oil Harvest Report 2023")
+	texts = append(texts, column(50, 760,
+		"# Summary",
+		"The 2023 harvest reached 1.2 million",
+		"tonnes of fresh fruit bunches, up",
+		"8 percent on the previous year.",
+		"",
+		"# Yield",
+		"Average yield rose to 24 tonnes per",
+		"hectare thanks to better harvest",
+		"intervals and fertilizer timing.",
+	)...)
+	texts = append(texts, column(310, 760,
+		"# Quality",
+		"Ripe bunches made up 92 percent of",
+		"deliveries to the mill, while loose",
+		"fruit losses fell below 2 percent.",
+		"",
+		"# Outlook",
+		"The 2024 plan replants 3,000 hectares",
+		"of ageing trees and adds two new",
+		"collection points per estate.",
+	)...)
+	return []pdfPage{{Texts: texts}}
+}
+
+// tablePages is a salary grade table
+func tablePages() []pdfPage {
+	texts := column(50, 780,
+		"# Salary Grades 2024",
+		"Monthly base salary in IDR by grade, effective 1 January 2024.",
+	)
+	rows := [][]string{
+		{"Grade", "Title", "Minimum", "Maximum"},
+		{"G1", "Staff", "5,000,000", "7,500,000"},
+		{"G2", "Senior Staff", "7,500,000", "11,000,000"},
+		{"G3", "Supervisor", "11,000,000", "16,000,000"},
+		{"G4", "Manager", "16,000,000", "25,000,000"},
+	}
+	for i, row := range rows {
+		y := 700 - float64(i)*20
+		for j, cell := range row {
+			texts = append(texts, pdfText{X: 50 + float64(j)*130, Y: y, Size: 10, Text: cell})
+		}
+	}
+	texts = append(texts, column(50, 580, "Allowances are paid on top of the base salary.")...)
+	return []pdfPage{{Texts: texts}}
+}
+
+// legalPages is an Indonesian company regulation of chapters and articles
+func legalPages() []pdfPage {
+	return []pdfPage{
+		{Texts: column(50, 780,
+			"# PERATURAN PERUSAHAAN",
+			"PER/HRD/001/2024",
+			"",
+			"# BAB I",
+			"# KETENTUAN UMUM",
+			"",
+			"Pasal 1",
+			"Dalam peraturan ini yang dimaksud dengan:",
+			"1. Perusahaan adalah PT Sawit Makmur Sejahtera.",
+			"2. Karyawan adalah setiap orang yang bekerja pada Perusahaan.",
+			"3. Upah adalah hak Karyawan yang diterima dalam bentuk uang.",
+			"",
+			"Pasal 2",
+			"(1) Peraturan ini berlaku bagi seluruh Karyawan.",
+			"(2) Hal yang belum diatur ditetapkan oleh Direksi.",
+		)},
+		{Texts: column(50, 780,
+			"# BAB II",
+			"# WAKTU KERJA",
+			"",
+			"Pasal 3",
+			"(1) Waktu kerja adalah 40 jam dalam satu minggu.",
+			"(2) Waktu istirahat paling sedikit 1 jam setelah 4 jam bekerja.",
+			"",
+			"Pasal 4",
+			"Kerja lembur dilakukan atas perintah tertulis atasan langsung.",
+		)},
+		{Texts: column(50, 780,
+			"# BAB III",
+			"# KETENTUAN PENUTUP",
+			"",
+			"Pasal 5",
+			"Peraturan ini berlaku sejak tanggal 2 - Januari - 2024.",
+		)},
+	}
+}
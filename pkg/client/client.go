@@ -0,0 +1,102 @@
+// Package client calls the HTTP API of `pdf-chunk-extractor serve` (see server.Server).
+// Its types and methods are generated by gen.go from the server's OpenAPI document,
+// server/openapi.json; run go generate after changing it. Chunks are schema.Chunks, so
+// the package does not depend on the extraction packages or their cgo dependencies.
+package client
+
+//go:generate go run gen.go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Options configures a Client
+type Options struct {
+	APIKey     string       // Sent as a bearer token when set
+	Tenant     string       // X-Tenant-ID of requests whose parameters name no tenant
+	HTTPClient *http.Client // Default: http.DefaultClient
+}
+
+// Client calls a server at a base URL, e.g. "http://chunker:8080". It is safe for
+// concurrent use.
+type Client struct {
+	baseURL string
+	options Options
+}
+
+// Error is an error response of the server
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("server responded %d: %s", e.StatusCode, e.Message)
+}
+
+// New creates a client of the server at baseURL
+func New(baseURL string, options Options) *Client {
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), options: options}
+}
+
+// do sends a request and decodes the JSON body of a success response into result;
+// error responses are returned as *Error
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body io.Reader, contentType string, result any) error {
+	resp, err := c.send(ctx, method, path, query, header, body, contentType, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// send sends a request and returns its success response, whose body the caller closes;
+// error responses are returned as *Error
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, header http.Header, body io.Reader, contentType, accept string) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("Accept", accept)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.options.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.options.APIKey)
+	}
+	if c.options.Tenant != "" && req.Header.Get("X-Tenant-ID") == "" {
+		req.Header.Set("X-Tenant-ID", c.options.Tenant)
+	}
+
+	resp, err := c.options.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var errorBody ErrorResponse
+		if json.Unmarshal(data, &errorBody) != nil || errorBody.Error == "" {
+			errorBody.Error = strings.TrimSpace(string(data))
+		}
+		return nil, &Error{StatusCode: resp.StatusCode, Message: errorBody.Error}
+	}
+	return resp, nil
+}
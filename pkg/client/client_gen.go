@@ -0,0 +1,336 @@
+// Code generated by gen.go from ../server/openapi.json; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Health is the status of the server
+type Health struct {
+	Status string `json:"status"`
+}
+
+// Chunk is a chunk as saved to chunk JSON files
+type Chunk = schema.Chunk
+
+// Heading is a numbered heading of the document outline
+type Heading = schema.Heading
+
+// Element is a piece of the document in the unstructured.io element schema, set with Elements
+type Element = schema.Element
+
+// TOC is the table of contents of a document with the chunks of every section
+type TOC = schema.TOC
+
+// TokenUsage is the AI tokens spent on a document
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChunkResult is the chunks of a document with its token usage and reports
+type ChunkResult struct {
+	Chunks         []Chunk        `json:"chunks"`
+	TokenUsage     TokenUsage     `json:"token_usage"`
+	Report         map[string]any `json:"report,omitempty"` // Extraction report for PDF and office inputs
+	Pages          int            `json:"pages"`
+	BudgetExceeded bool           `json:"budget_exceeded,omitempty"` // Chunked locally because the AI budget ran out
+	Redactions     map[string]any `json:"redactions,omitempty"`      // PII masked, set with RedactPII
+	Outline        []Heading      `json:"outline,omitempty"`
+	Invoice        map[string]any `json:"invoice,omitempty"` // Key fields of the document, set in the invoice profile
+	TOC            *TOC           `json:"toc,omitempty"`
+	Elements       []Element      `json:"elements,omitempty"`
+	Duplicates     int            `json:"duplicates,omitempty"`  // Chunks flagged or dropped as near-duplicates of earlier documents
+	LowQuality     int            `json:"low_quality,omitempty"` // Chunks left out for scoring below MinQualityScore
+	PageHashes     []string       `json:"page_hashes,omitempty"`
+}
+
+// Job is a document queued with createJob; finished jobs are removed after the server's retention, unless its result store keeps them
+type Job struct {
+	ID         string       `json:"id"`
+	Tenant     string       `json:"tenant"`
+	Filename   string       `json:"filename"`
+	Status     string       `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Result     *ChunkResult `json:"result,omitempty"`
+}
+
+// Values of Job.Status
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusDone      = "done"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// Event is the data of an event of streamJobEvents
+type Event struct {
+	Type  string `json:"type"`
+	Page  int    `json:"page,omitempty"`  // 1-based page of page_processed
+	Pages int    `json:"pages,omitempty"` // Pages of the document
+	Chunk *Chunk `json:"chunk,omitempty"`
+	Job   *Job   `json:"job,omitempty"`
+}
+
+// Values of Event.Type
+const (
+	EventTypePageProcessed = "page_processed"
+	EventTypeChunkCreated  = "chunk_created"
+	EventTypeStatus        = "status"
+)
+
+// Usage is what an API key spent on one UTC day
+type Usage struct {
+	Key              string  `json:"key"` // Name of the API key
+	Date             string  `json:"date"`
+	Requests         int     `json:"requests"`
+	Rejected         int     `json:"rejected"` // Requests over a quota
+	Documents        int     `json:"documents"`
+	Failed           int     `json:"failed"`
+	Pages            int     `json:"pages"`
+	UploadedBytes    int64   `json:"uploaded_bytes"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// GetHealth reports that the server is up
+func (c *Client) GetHealth(ctx context.Context) (*Health, error) {
+	query := url.Values{}
+	header := http.Header{}
+	var result Health
+	if err := c.do(ctx, "GET", "/healthz", query, header, nil, "", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetOpenAPI returns the OpenAPI document of the server
+func (c *Client) GetOpenAPI(ctx context.Context) (map[string]any, error) {
+	query := url.Values{}
+	header := http.Header{}
+	var result map[string]any
+	if err := c.do(ctx, "GET", "/openapi.json", query, header, nil, "", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ChunkDocumentParams are the optional parameters of ChunkDocument
+type ChunkDocumentParams struct {
+	Tenant   string // Tenant of the request; optional with a default tenant or an API key bound to a tenant
+	Filename string // Name of the uploaded document; a multipart upload's file name is used when it is missing
+	Metadata string // JSON object attached to the metadata of every chunk
+}
+
+// ChunkDocument chunks an upload and returns its chunks
+func (c *Client) ChunkDocument(ctx context.Context, params *ChunkDocumentParams, body io.Reader) (*ChunkResult, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Tenant != "" {
+			header.Set("X-Tenant-ID", params.Tenant)
+		}
+		if params.Filename != "" {
+			query.Set("filename", params.Filename)
+		}
+		if params.Metadata != "" {
+			query.Set("metadata", params.Metadata)
+		}
+	}
+	var result ChunkResult
+	if err := c.do(ctx, "POST", "/v1/chunk", query, header, body, "application/octet-stream", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListJobsParams are the optional parameters of ListJobs
+type ListJobsParams struct {
+	Tenant       string    // Tenant of the request; optional with a default tenant or an API key bound to a tenant
+	Status       string    // Only jobs with this status
+	OfTenant     string    // Only jobs of this tenant; other tenants than the caller's need an admin key
+	CreatedAfter time.Time // Only jobs created at or after this time
+	Limit        int       // Jobs to return at most, default 100
+}
+
+// ListJobs lists the jobs of the caller's tenant, or of every tenant for admin keys, newest first and without their results
+func (c *Client) ListJobs(ctx context.Context, params *ListJobsParams) ([]Job, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Tenant != "" {
+			header.Set("X-Tenant-ID", params.Tenant)
+		}
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+		if params.OfTenant != "" {
+			query.Set("tenant", params.OfTenant)
+		}
+		if !params.CreatedAfter.IsZero() {
+			query.Set("created_after", params.CreatedAfter.Format(time.RFC3339))
+		}
+		if params.Limit != 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	var result []Job
+	if err := c.do(ctx, "GET", "/v1/jobs", query, header, nil, "", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreateJobParams are the optional parameters of CreateJob
+type CreateJobParams struct {
+	Tenant   string // Tenant of the request; optional with a default tenant or an API key bound to a tenant
+	Filename string // Name of the uploaded document; a multipart upload's file name is used when it is missing
+	Metadata string // JSON object attached to the metadata of every chunk
+}
+
+// CreateJob queues an upload to be chunked
+func (c *Client) CreateJob(ctx context.Context, params *CreateJobParams, body io.Reader) (*Job, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Tenant != "" {
+			header.Set("X-Tenant-ID", params.Tenant)
+		}
+		if params.Filename != "" {
+			query.Set("filename", params.Filename)
+		}
+		if params.Metadata != "" {
+			query.Set("metadata", params.Metadata)
+		}
+	}
+	var result Job
+	if err := c.do(ctx, "POST", "/v1/jobs", query, header, body, "application/octet-stream", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetJobParams are the optional parameters of GetJob
+type GetJobParams struct {
+	Tenant string // Tenant of the request; optional with a default tenant or an API key bound to a tenant
+}
+
+// GetJob returns a job and, once done, its chunks
+func (c *Client) GetJob(ctx context.Context, id string, params *GetJobParams) (*Job, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Tenant != "" {
+			header.Set("X-Tenant-ID", params.Tenant)
+		}
+	}
+	var result Job
+	if err := c.do(ctx, "GET", "/v1/jobs/"+url.PathEscape(id), query, header, nil, "", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CancelJobParams are the optional parameters of CancelJob
+type CancelJobParams struct {
+	Tenant string // Tenant of the request; optional with a default tenant or an API key bound to a tenant
+}
+
+// CancelJob cancels a queued or running job, or removes a finished one
+func (c *Client) CancelJob(ctx context.Context, id string, params *CancelJobParams) (*Job, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Tenant != "" {
+			header.Set("X-Tenant-ID", params.Tenant)
+		}
+	}
+	var result Job
+	if err := c.do(ctx, "DELETE", "/v1/jobs/"+url.PathEscape(id), query, header, nil, "", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StreamJobEventsParams are the optional parameters of StreamJobEvents
+type StreamJobEventsParams struct {
+	Tenant      string // Tenant of the request; optional with a default tenant or an API key bound to a tenant
+	LastEventID int    // Resume after this event
+}
+
+// StreamJobEvents streams the events of a job as server-sent events until it is done, failed or cancelled
+func (c *Client) StreamJobEvents(ctx context.Context, id string, params *StreamJobEventsParams) (*EventStream[Event], error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Tenant != "" {
+			header.Set("X-Tenant-ID", params.Tenant)
+		}
+		if params.LastEventID != 0 {
+			header.Set("Last-Event-ID", strconv.Itoa(params.LastEventID))
+		}
+	}
+	return stream[Event](ctx, c, "GET", "/v1/jobs/"+url.PathEscape(id)+"/events", query, header)
+}
+
+// GetUsageParams are the optional parameters of GetUsage
+type GetUsageParams struct {
+	Days int // Days to report, default 7
+}
+
+// GetUsage returns the usage of the calling API key on the last days, today first
+func (c *Client) GetUsage(ctx context.Context, params *GetUsageParams) ([]Usage, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Days != 0 {
+			query.Set("days", strconv.Itoa(params.Days))
+		}
+	}
+	var result []Usage
+	if err := c.do(ctx, "GET", "/v1/usage", query, header, nil, "", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetKeysUsageParams are the optional parameters of GetKeysUsage
+type GetKeysUsageParams struct {
+	Date string // UTC day as 2006-01-02, default today
+}
+
+// GetKeysUsage returns the usage of every API key on a day; admin keys only
+func (c *Client) GetKeysUsage(ctx context.Context, params *GetKeysUsageParams) ([]Usage, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Date != "" {
+			query.Set("date", params.Date)
+		}
+	}
+	var result []Usage
+	if err := c.do(ctx, "GET", "/v1/usage/keys", query, header, nil, "", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,505 @@
+//go:build ignore
+
+// gen.go writes client_gen.go, the types and methods of the client, from the server's
+// OpenAPI document. It handles the parts of OpenAPI 3 the document uses: component
+// schemas (objects, arrays, scalars, enums and x-go-type aliases), path, query and header
+// parameters, binary request bodies, and JSON and event stream responses.
+//
+// Run it with go generate in this directory.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	specPath   = "../server/openapi.json"
+	outputPath = "client_gen.go"
+)
+
+// document is the part of an OpenAPI document the generator reads
+type document struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas       ordered[*schema]        `json:"schemas"`
+		Parameters    map[string]*parameter   `json:"parameters"`
+		RequestBodies map[string]*requestBody `json:"requestBodies"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []*parameter         `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+	GoName      string  `json:"x-go-name"` // Field name in the parameters struct, default the camel-cased name
+}
+
+type requestBody struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref          string           `json:"$ref"`
+	Type         string           `json:"type"`
+	Format       string           `json:"format"`
+	Description  string           `json:"description"`
+	Required     []string         `json:"required"`
+	Properties   ordered[*schema] `json:"properties"`
+	Items        *schema          `json:"items"`
+	Enum         []string         `json:"enum"`
+	GoType       string           `json:"x-go-type"`        // Existing Go type the schema is an alias of
+	GoTypeImport string           `json:"x-go-type-import"` // Package of GoType
+}
+
+// ordered is a JSON object that keeps the order of its keys, so structs and their
+// fields follow the document
+type ordered[T any] struct {
+	keys   []string
+	values map[string]T
+}
+
+func (o *ordered[T]) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	o.values = make(map[string]T)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		var value T
+		if err := decoder.Decode(&value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		o.keys = append(o.keys, key)
+		o.values[key] = value
+	}
+	return nil
+}
+
+// generator writes Go source for a document
+type generator struct {
+	doc     *document
+	imports map[string]bool
+	out     bytes.Buffer
+}
+
+func main() {
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		log.Fatalf("failed to parse %s: %v", specPath, err)
+	}
+
+	g := &generator{doc: &doc, imports: map[string]bool{"context": true, "net/http": true, "net/url": true}}
+	for _, name := range doc.Components.Schemas.keys {
+		g.writeSchema(name, doc.Components.Schemas.values[name])
+	}
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		for _, method := range []string{"get", "put", "post", "delete", "patch"} {
+			if op, ok := doc.Paths[path][method]; ok {
+				g.writeOperation(path, strings.ToUpper(method), op)
+			}
+		}
+	}
+
+	var file bytes.Buffer
+	fmt.Fprintf(&file, "// Code generated by gen.go from %s; DO NOT EDIT.\n\npackage client\n\nimport (\n", specPath)
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	// Standard library packages first, then the others, as goimports groups them
+	sort.Slice(imports, func(i, j int) bool {
+		if isStd(imports[i]) != isStd(imports[j]) {
+			return isStd(imports[i])
+		}
+		return imports[i] < imports[j]
+	})
+	for i, path := range imports {
+		if i > 0 && isStd(imports[i-1]) && !isStd(path) {
+			file.WriteString("\n")
+		}
+		fmt.Fprintf(&file, "%q\n", path)
+	}
+	file.WriteString(")\n")
+	file.Write(g.out.Bytes())
+
+	source, err := format.Source(file.Bytes())
+	if err != nil {
+		log.Fatalf("generated invalid Go: %v\n%s", err, file.Bytes())
+	}
+	if err := os.WriteFile(outputPath, source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeSchema writes the type of a component schema, and the constants of its enums
+func (g *generator) writeSchema(name string, s *schema) {
+	g.comment(name, s.Description)
+	if s.GoType != "" {
+		if s.GoTypeImport != "" {
+			g.imports[s.GoTypeImport] = true
+		}
+		fmt.Fprintf(&g.out, "type %s = %s\n\n", name, s.GoType)
+		return
+	}
+	if s.Type != "object" || len(s.Properties.keys) == 0 {
+		fmt.Fprintf(&g.out, "type %s %s\n\n", name, g.goType(s, true))
+		return
+	}
+
+	fmt.Fprintf(&g.out, "type %s struct {\n", name)
+	for _, key := range s.Properties.keys {
+		property := s.Properties.values[key]
+		required := contains(s.Required, key)
+		tag := key
+		if !required {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&g.out, "%s %s `json:%q`", goName(key), g.goType(property, required), tag)
+		if property.Description != "" {
+			fmt.Fprintf(&g.out, " // %s", property.Description)
+		}
+		g.out.WriteString("\n")
+	}
+	g.out.WriteString("}\n\n")
+
+	for _, key := range s.Properties.keys {
+		property := s.Properties.values[key]
+		if len(property.Enum) == 0 {
+			continue
+		}
+		fmt.Fprintf(&g.out, "// Values of %s.%s\nconst (\n", name, goName(key))
+		for _, value := range property.Enum {
+			fmt.Fprintf(&g.out, "%s%s%s = %q\n", name, goName(key), goName(value), value)
+		}
+		g.out.WriteString(")\n\n")
+	}
+}
+
+// writeOperation writes the parameters struct and method of an operation
+func (g *generator) writeOperation(path, method string, op *operation) {
+	name := goName(op.OperationID)
+	var pathParams, fieldParams []*parameter
+	for _, param := range op.Parameters {
+		param = g.parameter(param)
+		if param.In == "path" {
+			pathParams = append(pathParams, param)
+		} else {
+			fieldParams = append(fieldParams, param)
+		}
+	}
+
+	if len(fieldParams) > 0 {
+		fmt.Fprintf(&g.out, "// %sParams are the optional parameters of %s\ntype %sParams struct {\n", name, name, name)
+		for _, param := range fieldParams {
+			fmt.Fprintf(&g.out, "%s %s", paramName(param), g.goType(param.Schema, true))
+			if param.Description != "" {
+				fmt.Fprintf(&g.out, " // %s", param.Description)
+			}
+			g.out.WriteString("\n")
+		}
+		g.out.WriteString("}\n\n")
+	}
+
+	args := []string{"ctx context.Context"}
+	for _, param := range pathParams {
+		args = append(args, lowerFirst(goName(param.Name))+" string")
+	}
+	if len(fieldParams) > 0 {
+		args = append(args, "params *"+name+"Params")
+	}
+	body, contentType := g.requestBody(op.RequestBody)
+	if body {
+		args = append(args, "body io.Reader")
+		g.imports["io"] = true
+	}
+	resultType, stream := g.responseType(op.Responses)
+
+	fmt.Fprintf(&g.out, "// %s %s\n", name, lowerFirst(strings.TrimSuffix(op.Summary, ".")))
+	fmt.Fprintf(&g.out, "func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), resultType)
+	g.out.WriteString("query := url.Values{}\nheader := http.Header{}\n")
+	if len(fieldParams) > 0 {
+		g.out.WriteString("if params != nil {\n")
+		for _, param := range fieldParams {
+			field := "params." + paramName(param)
+			value, isSet := g.formatValue(param.Schema, field)
+			set := "query.Set"
+			if param.In == "header" {
+				set = "header.Set"
+			}
+			fmt.Fprintf(&g.out, "if %s {\n%s(%q, %s)\n}\n", isSet, set, param.Name, value)
+		}
+		g.out.WriteString("}\n")
+	}
+
+	if stream {
+		fmt.Fprintf(&g.out, "return stream[%s](ctx, c, %q, %s, query, header)\n}\n\n", strings.TrimSuffix(strings.TrimPrefix(resultType, "*EventStream["), "]"), method, g.pathExpr(path))
+		return
+	}
+	bodyArg := "nil"
+	if body {
+		bodyArg = "body"
+	}
+	// Struct results are returned as pointers, slices and maps as they are
+	returnValue := "result"
+	if strings.HasPrefix(resultType, "*") {
+		returnValue = "&result"
+	}
+	fmt.Fprintf(&g.out, "var result %s\n", strings.TrimPrefix(resultType, "*"))
+	fmt.Fprintf(&g.out, "if err := c.do(ctx, %q, %s, query, header, %s, %q, &result); err != nil {\nreturn nil, err\n}\nreturn %s, nil\n}\n\n",
+		method, g.pathExpr(path), bodyArg, contentType, returnValue)
+}
+
+// parameter resolves a parameter reference
+func (g *generator) parameter(param *parameter) *parameter {
+	if param.Ref == "" {
+		return param
+	}
+	resolved, ok := g.doc.Components.Parameters[refName(param.Ref)]
+	if !ok {
+		log.Fatalf("unknown parameter %s", param.Ref)
+	}
+	return resolved
+}
+
+// requestBody reports whether an operation uploads a binary body, and its content type
+func (g *generator) requestBody(body *requestBody) (bool, string) {
+	if body == nil {
+		return false, ""
+	}
+	if body.Ref != "" {
+		resolved, ok := g.doc.Components.RequestBodies[refName(body.Ref)]
+		if !ok {
+			log.Fatalf("unknown request body %s", body.Ref)
+		}
+		body = resolved
+	}
+	if _, ok := body.Content["application/octet-stream"]; ok {
+		return true, "application/octet-stream"
+	}
+	log.Fatal("request bodies other than application/octet-stream are not supported")
+	return false, ""
+}
+
+// responseType returns the Go type of the first success response, and whether it is an
+// event stream, whose schema is the type of its events' data
+func (g *generator) responseType(responses map[string]*response) (string, bool) {
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		if media, ok := responses[code].Content["text/event-stream"]; ok {
+			return "*EventStream[" + g.goType(media.Schema, true) + "]", true
+		}
+		media, ok := responses[code].Content["application/json"]
+		if !ok {
+			log.Fatalf("response %s is neither JSON nor an event stream", code)
+		}
+		return g.goType(media.Schema, false), false
+	}
+	log.Fatal("operation without a success response")
+	return "", false
+}
+
+// goType returns the Go type of a schema; optional structs and times are pointers, so
+// they are left out of JSON when unset
+func (g *generator) goType(s *schema, required bool) string {
+	if s.Ref != "" {
+		name := refName(s.Ref)
+		target, ok := g.doc.Components.Schemas.values[name]
+		if !ok {
+			log.Fatalf("unknown schema %s", s.Ref)
+		}
+		if target.Type == "object" && !required {
+			return "*" + name
+		}
+		return name
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			g.imports["time"] = true
+			if !required {
+				return "*time.Time"
+			}
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		if s.Format == "int64" {
+			return "int64"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(s.Items, true)
+	case "object":
+		return "map[string]any"
+	}
+	log.Fatalf("unsupported schema type %q", s.Type)
+	return ""
+}
+
+// formatValue returns the expression formatting a parameter value as a string, and
+// the condition that it is set
+func (g *generator) formatValue(s *schema, value string) (string, string) {
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			return value + ".Format(time.RFC3339)", "!" + value + ".IsZero()"
+		}
+		return value, value + ` != ""`
+	case "integer":
+		g.imports["strconv"] = true
+		return "strconv.Itoa(" + value + ")", value + " != 0"
+	}
+	log.Fatalf("unsupported parameter type %q", s.Type)
+	return "", ""
+}
+
+// pathExpr returns the expression of a path with its parameters escaped
+func (g *generator) pathExpr(path string) string {
+	var parts []string
+	for path != "" {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			parts = append(parts, fmt.Sprintf("%q", path))
+			break
+		}
+		end := strings.Index(path, "}")
+		if start > 0 {
+			parts = append(parts, fmt.Sprintf("%q", path[:start]))
+		}
+		parts = append(parts, "url.PathEscape("+lowerFirst(goName(path[start+1:end]))+")")
+		path = path[end+1:]
+	}
+	return strings.Join(parts, " + ")
+}
+
+// comment writes the doc comment of a type
+func (g *generator) comment(name, description string) {
+	if description != "" {
+		fmt.Fprintf(&g.out, "// %s is %s\n", name, lowerFirst(description))
+	} else {
+		fmt.Fprintf(&g.out, "// %s is the %s schema of the API\n", name, name)
+	}
+}
+
+// initialisms are written in capitals in Go names
+var initialisms = map[string]string{"id": "ID", "url": "URL", "usd": "USD", "toc": "TOC", "api": "API", "json": "JSON", "pdf": "PDF"}
+
+// goName returns the exported Go name of a snake_case, kebab-case or camelCase name
+func goName(name string) string {
+	var words []string
+	word := ""
+	for _, r := range name {
+		switch {
+		case r == '_' || r == '-' || r == '.':
+			words, word = append(words, word), ""
+		case r >= 'A' && r <= 'Z' && word != "" && !(word[len(word)-1] >= 'A' && word[len(word)-1] <= 'Z'):
+			words, word = append(words, word), string(r)
+		default:
+			word += string(r)
+		}
+	}
+	words = append(words, word)
+	var b strings.Builder
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+		if initialism, ok := initialisms[strings.ToLower(word)]; ok {
+			b.WriteString(initialism)
+		} else {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// paramName returns the field name of a parameter
+func paramName(param *parameter) string {
+	if param.GoName != "" {
+		return param.GoName
+	}
+	return goName(param.Name)
+}
+
+// lowerFirst lowers the first letter of s, or all of it when it is an initialism
+func lowerFirst(s string) string {
+	if s == strings.ToUpper(s) {
+		return strings.ToLower(s)
+	}
+	if len(s) > 1 && s[1] >= 'A' && s[1] <= 'Z' {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// isStd reports whether an import path is of the standard library, whose first
+// element has no dot
+func isStd(path string) bool {
+	return !strings.Contains(strings.Split(path, "/")[0], ".")
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
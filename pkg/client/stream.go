@@ -0,0 +1,83 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// EventStream reads the server-sent events of a streaming operation, such as
+// StreamJobEvents, decoding the data of every event into a T. Close it once done.
+type EventStream[T any] struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	lastID int
+}
+
+// stream sends a request whose success response is an event stream
+func stream[T any](ctx context.Context, c *Client, method, path string, query url.Values, header http.Header) (*EventStream[T], error) {
+	resp, err := c.send(ctx, method, path, query, header, nil, "", "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream[T]{body: resp.Body, reader: bufio.NewReader(resp.Body)}, nil
+}
+
+// Next returns the next event, or io.EOF once the server ended the stream
+func (s *EventStream[T]) Next() (*T, error) {
+	var data []string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" {
+				return nil, io.EOF
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("failed to read event stream: %w", err)
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			// A blank line dispatches the event; one without data is ignored
+			if len(data) == 0 {
+				continue
+			}
+			var event T
+			if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+				return nil, fmt.Errorf("failed to decode event %d: %w", s.lastID, err)
+			}
+			return &event, nil
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "id":
+			if id, err := strconv.Atoi(value); err == nil {
+				s.lastID = id
+			}
+		}
+		// Comments (lines starting with ":") and the event field are skipped; the
+		// event type is also in the data
+	}
+}
+
+// LastEventID returns the ID of the last event read; pass it as the LastEventID
+// parameter to resume a stream that broke off
+func (s *EventStream[T]) LastEventID() int {
+	return s.lastID
+}
+
+// Close closes the stream
+func (s *EventStream[T]) Close() error {
+	return s.body.Close()
+}
@@ -1,21 +1,238 @@
 package config
 
+import "time"
+
+// Output compression formats for ChunkerConfig.Compression
+const (
+	CompressionNone    = ""        // Plain files
+	CompressionGzip    = "gzip"    // chunk_N.txt.gz, chunk_N.json.gz and <name>.txt.gz
+	CompressionZstd    = "zstd"    // chunk_N.txt.zst, chunk_N.json.zst and <name>.txt.zst
+	CompressionTarZstd = "tar.zst" // One ChunkDir/<name>.tar.zst per document holding its chunk files and manifest
+)
+
+// Overwrite policies for ChunkerConfig.OverwritePolicy
+const (
+	OverwriteReplace = "overwrite" // Write over the existing output of a document with the same name from an earlier call
+	OverwriteError   = "error"     // Fail the document with ErrOutputExists
+	OverwriteVersion = "version"   // Save under the first free name of <name>-2, <name>-3, ...
+)
+
+// AI modes for ChunkerConfig.AIMode
+const (
+	AIModeRewrite   = ""          // The AI returns the chunked text, restructured and formatted
+	AIModeStructure = "structure" // The AI only returns where sections start and their titles; chunks are cut from the original text verbatim
+)
+
+// Splitter profiles for ChunkerConfig.Profile
+const (
+	ProfileGeneral    = ""           // Split at natural breaks and page groups, with the AI provider when there is one
+	ProfileRegulation = "regulation" // Indonesian regulations (UU, PP, Permen, ...): one chunk per Pasal with its citation, without AI
+	ProfilePaper      = "paper"      // Scientific papers: chunks within sections, two-column pages in reading order, inline citations stripped, without AI
+	ProfileInvoice    = "invoice"    // Invoices and receipts: chunked like ProfileGeneral, with vendor, date, totals and line items extracted into ChunkResult.Invoice
+)
+
+// Near-duplicate handling for ChunkerConfig.Dedup
+const (
+	DedupOff  = ""     // Chunks are not compared across documents
+	DedupFlag = "flag" // Near-duplicates of chunks of earlier documents are kept with their DuplicateOf set
+	DedupDrop = "drop" // Near-duplicates of chunks of earlier documents are left out of the output
+)
+
+// Units of ChunkerConfig.MaxChunkSize and LocalChunkSize, set with ChunkerConfig.SizeUnit
+const (
+	SizeRunes  = ""       // Characters, whatever their encoded length
+	SizeBytes  = "bytes"  // UTF-8 bytes: 2 for "é", 3 for a Chinese, Japanese or Korean character, 4 for an emoji
+	SizeTokens = "tokens" // Estimated model tokens: a token per 4 bytes of alphabetic text, or per Chinese, Japanese, Korean or Thai character
+)
+
+// Layouts of chunk text files, set with ChunkerConfig.ChunkFileFormat
+const (
+	ChunkFileMarkdown    = ""             // The chunk text as formatted, with its Markdown metadata section
+	ChunkFileFrontMatter = "front-matter" // A YAML front matter block with the chunk metadata, followed by the chunk content
+)
+
+// Image formats of ChunkerConfig.PageImageFormat and ChunkerConfig.ThumbnailFormat
+const (
+	ImagePNG  = "png"  // Lossless; best for text and line art
+	ImageJPEG = "jpeg" // Several times smaller for scans and photos
+)
+
+// Modes of ChunkerConfig.SplitPDF
+const (
+	SplitPDFChunk   = "chunk"   // One PDF per chunk page range
+	SplitPDFSection = "section" // One PDF per top-level section of the table of contents
+)
+
 // ChunkerConfig holds configuration for the chunker
 type ChunkerConfig struct {
-	MaxChunkSize   int
-	LocalChunkSize int
-	OutputDir      string
-	ChunkDir       string
-	JSONDir        string
+	MaxChunkSize        int
+	LocalChunkSize      int
+	SizeUnit            string // Unit of MaxChunkSize and LocalChunkSize: SizeRunes (default), SizeBytes or SizeTokens
+	OutputDir           string
+	ChunkDir            string
+	JSONDir             string
+	OCRLanguages        []string      // Tesseract language packs, e.g. {"eng", "ind"}
+	OCRAutoDetect       bool          // Detect the page script with tesseract OSD before OCR
+	TesseractPath       string        // Tesseract executable, e.g. C:\Program Files\Tesseract-OCR\tesseract.exe; empty to look for it on PATH and in the usual install locations
+	DockerImage         string        // Read, render and OCR PDFs with the mutool and tesseract of this Docker image instead of this machine's, e.g. container.DefaultImage; empty to run them natively
+	OCRDPI              float64       // Render resolution for OCR pages; higher is slower but reads small fonts better
+	OCRWorkers          int           // Number of concurrent tesseract processes per document
+	OCRLowConfidence    float64       // Words recognized below this confidence (0–100) are listed in the page report
+	OCRPageTimeout      time.Duration // Kill OCR of a page after this long and keep its direct text; 0 is unlimited
+	MaxRenderPixels     int           // Render pages that would be larger at a lower DPI, for OCR and page images; 0 is unlimited
+	ASCIIDigits         bool          // Replace Arabic-Indic and Persian digits with 0–9 before chunking, so page numbers, dates and amounts match ASCII patterns
+	OCRCorrection       bool          // Fix common OCR errors on OCR pages before chunking, replacing unknown words with the dictionary word they most likely misread
+	OCRDictionaryPath   string        // Extra words for OCRCorrection, one per line with an optional count, e.g. domain terms; built-in words cover OCRLanguages "eng" and "ind"
+	SearchablePDF       bool          // Also write <name>.searchable.pdf with an OCR text layer to OutputDir
+	PageImages          bool          // Also render every PDF page to PageImageDir/<name>/page_N.png, listed in the "page_images" metadata of the chunks on it, for multimodal embedding
+	PageImageDir        string        // Root directory of PageImages
+	PageImageDPI        float64       // Render resolution of PageImages; 150 suits most vision models
+	PageImageFormat     string        // ImagePNG (default) or ImageJPEG
+	Thumbnail           bool          // Also save a thumbnail of the first PDF page next to the manifest, for catalog UIs
+	ThumbnailSize       int           // Longest side of Thumbnail in pixels
+	ThumbnailFormat     string        // ImagePNG (default) or ImageJPEG
+	SplitPDF            string        // SplitPDFChunk or SplitPDFSection to also write the source pages of every chunk as a PDF of their own to SplitPDFDir/<name>; empty to disable
+	SplitPDFDir         string        // Root directory of SplitPDF
+	Workers             int           // Documents processed concurrently by ChunkDirectory and ChunkArchive
+	AIWorkers           int           // AI requests sent concurrently per document, within the shared rate limits
+	Resume              bool          // Skip the documents a stopped batch run over the same directory processed, as recorded in OutputDir/checkpoint.json
+	AIRequestsPerMinute int           // Shared limit on AI provider calls across all documents; 0 is unlimited
+	AITokensPerMinute   int           // Shared limit on estimated AI tokens across all documents; 0 is unlimited
+	MaxTotalTokens      int           // AI token budget per chunker; once spent, remaining documents are chunked locally. 0 is unlimited
+	MaxCostUSD          float64       // AI cost budget per chunker, priced with AIPromptPrice and AICompletionPrice. 0 is unlimited
+	AbortOnBudget       bool          // Fail remaining documents with ErrBudgetExceeded instead of chunking them locally
+	AIPromptPrice       float64       // USD per million prompt tokens, used for Plan estimates and MaxCostUSD
+	AICompletionPrice   float64       // USD per million completion tokens, used for Plan estimates and MaxCostUSD
+	ReaderSpillSize     int64         // PDF readers larger than this many bytes are spooled to a temp file instead of memory; 0 always buffers
+	TextPageSeparators  bool          // Split text and string inputs into numbered pages at "--- Page N ---" lines, as OutputRawText writes them; off, such inputs are one page whatever lines they contain
+	RepairPDF           bool          // Try to repair PDFs that fail to open or read (trim, qpdf, mutool clean) before the fallback extractors
+	MaxFileSizeMB       int           // Reject inputs larger than this many megabytes, and archives unpacking to more than 10 times that or 1000 files, with ErrFileTooLarge; 0 is unlimited
+	MaxPages            int           // Reject documents with more pages than this with ErrTooManyPages; 0 is unlimited
+	MaxProcessingTime   time.Duration // Abort a document with ErrProcessingTimeout after this long; 0 is unlimited
+	TempDir             string        // Root for temp files (OCR page images, spooled readers, unpacked archives); empty uses the system temp directory
+	OutputHash          bool          // Append a short hash of the document text to output names, so different documents with the same name never share a directory
+	OverwritePolicy     string        // What to do when a document's output directory exists: OverwriteReplace (default), OverwriteError or OverwriteVersion
+	PlainText           bool          // Keep chunk text as extracted, without the Markdown headings, metadata and summary sections; document codes, dates and title go to Metadata
+	ChunkTemplatePath   string        // text/template file formatting every locally formatted chunk from a utils.ChunkView, instead of the built-in Markdown layout
+	ChunkFileFormat     string        // Layout of chunk .txt files: ChunkFileMarkdown (default) or ChunkFileFrontMatter for static-site and note-taking tools
+	Compression         string        // Compress saved chunk and raw text files: CompressionNone (default), CompressionGzip, CompressionZstd or CompressionTarZstd
+	RedactPII           bool          // Mask personal data (emails, phone numbers, NIK, NPWP, card numbers) before chunking, so it is never saved or sent to the AI provider
+	RedactKinds         []string      // Kinds masked with RedactPII, see the redact package; empty masks every kind
+	LocalOnly           bool          // Guarantee document content never leaves the machine: remote AI providers and OCR engines are refused and documents fail with ErrRemoteDisabled
+	AuditLogPath        string        // Append a JSON lines entry for every AI request (hashes, tokens, latency) to this file; empty disables the audit log
+	AuditLogContent     bool          // Also write the request and response text to the audit log
+	TempMaxAge          time.Duration // Temp files older than this, left behind by crashed runs, are swept from TempDir on start; 0 disables the sweep
+	ValidateAI          bool          // Check every AI output (not empty, not much shorter than its input, no refusals, key numbers and codes kept); rejected outputs are retried once with a stricter prompt, then chunked locally
+	MinAIOutputRatio    float64       // With ValidateAI, reject AI outputs shorter than this fraction of their input
+	AIMode              string        // AIModeRewrite (default) or AIModeStructure, which guarantees chunks never contain altered or invented text
+	Summarize           bool          // Give every chunk an extractive Summary (TextRank, works offline) and add a Summary section to locally formatted chunks
+	SummarySentences    int           // Sentences in each summary with Summarize
+	Profile             string        // ProfileGeneral (default) or a profile for a kind of document, such as ProfileRegulation, ProfilePaper or ProfileInvoice
+	ExcludeReferences   bool          // With ProfilePaper, drop the references section instead of chunking it separately
+	TableOfContents     bool          // Build a table of contents with the chunks of every section and write toc.json and toc.md next to each manifest
+	Elements            bool          // Split every document into unstructured.io-style elements (Title, NarrativeText, ListItem, Table) and write elements.json next to each manifest
+	Dedup               string        // DedupOff (default), DedupFlag or DedupDrop: check every chunk against the chunks of the documents processed before it
+	DedupThreshold      float64       // Estimated Jaccard similarity of word shingles from which two chunks are near-duplicates, with Dedup
+	DedupMaxChunks      int           // Chunks kept in the near-duplicate index at most, the oldest forgotten first, so a long-running server stays bounded; 0 keeps every chunk
+	DiffThreshold       float64       // Word similarity (0–1) from which a removed and an added chunk are one modified chunk when comparing versions with CompareInputs
+	FilterNoiseLines    bool          // Drop page footers such as "Halaman 3 dari 10", confidentiality notices and watermark text before chunking, with linefilter.DefaultPatterns
+	NoiseLinePatterns   []string      // Extra regular expressions of lines to drop before chunking, matched against trimmed lines; applied even without FilterNoiseLines
+	ScoreQuality        bool          // Give every chunk a Quality score from its length, information density, stopword ratio and OCR noise
+	MinQualityScore     float64       // Leave out chunks scoring below this (0–1), such as lone page numbers and scanning artifacts, scoring them even without ScoreQuality; 0 keeps every chunk
+	FidelityThreshold   float64       // Reject AI outputs that keep less than this fraction (0–1) of the word trigrams of their input, retrying and then chunking locally like ValidateAI; 0 disables the check
 }
 
 // DefaultConfig returns a default configuration
 func DefaultConfig() ChunkerConfig {
 	return ChunkerConfig{
-		MaxChunkSize:   4000,
-		LocalChunkSize: 3000,
-		OutputDir:      "output",
-		ChunkDir:       "chunk",
-		JSONDir:        "json",
+		MaxChunkSize:        4000,
+		LocalChunkSize:      3000,
+		SizeUnit:            SizeRunes,
+		OutputDir:           "output",
+		ChunkDir:            "chunk",
+		JSONDir:             "json",
+		OCRLanguages:        []string{"eng", "ind"},
+		OCRAutoDetect:       false,
+		OCRDPI:              300,
+		OCRWorkers:          1,
+		OCRLowConfidence:    60,
+		OCRPageTimeout:      2 * time.Minute,
+		MaxRenderPixels:     40_000_000,
+		ASCIIDigits:         false,
+		OCRCorrection:       false,
+		OCRDictionaryPath:   "",
+		SearchablePDF:       false,
+		PageImages:          false,
+		PageImageDir:        "pages",
+		PageImageDPI:        150,
+		PageImageFormat:     ImagePNG,
+		Thumbnail:           false,
+		ThumbnailSize:       256,
+		ThumbnailFormat:     ImagePNG,
+		SplitPDF:            "",
+		SplitPDFDir:         "split",
+		Workers:             1,
+		AIWorkers:           1,
+		Resume:              true,
+		AIRequestsPerMinute: 0,
+		AITokensPerMinute:   0,
+		MaxTotalTokens:      0,
+		MaxCostUSD:          0,
+		AbortOnBudget:       false,
+		AIPromptPrice:       0.50,
+		AICompletionPrice:   1.50,
+		ReaderSpillSize:     32 << 20,
+		RepairPDF:           false,
+		MaxFileSizeMB:       0,
+		MaxPages:            0,
+		MaxProcessingTime:   0,
+		TempDir:             "",
+		OutputHash:          false,
+		OverwritePolicy:     OverwriteReplace,
+		PlainText:           false,
+		ChunkTemplatePath:   "",
+		ChunkFileFormat:     ChunkFileMarkdown,
+		Compression:         CompressionNone,
+		RedactPII:           false,
+		RedactKinds:         nil,
+		LocalOnly:           false,
+		AuditLogPath:        "",
+		AuditLogContent:     false,
+		TempMaxAge:          24 * time.Hour,
+		ValidateAI:          false,
+		MinAIOutputRatio:    0.5,
+		AIMode:              AIModeRewrite,
+		Summarize:           false,
+		SummarySentences:    3,
+		Profile:             ProfileGeneral,
+		ExcludeReferences:   false,
+		TableOfContents:     false,
+		Elements:            false,
+		Dedup:               DedupOff,
+		DedupThreshold:      0.8,
+		DedupMaxChunks:      100000,
+		DiffThreshold:       0.5,
+		FilterNoiseLines:    false,
+		NoiseLinePatterns:   nil,
+		ScoreQuality:        false,
+		MinQualityScore:     0,
+		FidelityThreshold:   0,
 	}
 }
+
+// DemoConfig returns the configuration of a public demo, safe to expose to anyone: no
+// document leaves the machine, small documents only, and nothing is kept between runs.
+// Callers set the output and temp directories to an ephemeral location.
+func DemoConfig() ChunkerConfig {
+	cfg := DefaultConfig()
+	cfg.LocalOnly = true                     // No AI providers or remote OCR, so demo uploads cost nothing and stay local
+	cfg.MaxFileSizeMB = 5                    // Reject larger uploads
+	cfg.MaxPages = 20                        // Reject longer documents
+	cfg.MaxProcessingTime = 30 * time.Second // Abort documents that take longer, such as scans needing OCR on every page
+	cfg.OCRWorkers = 1
+	cfg.OCRPageTimeout = 10 * time.Second
+	cfg.MaxRenderPixels = 10_000_000
+	cfg.Resume = false // No checkpoints survive a document
+	cfg.TempMaxAge = time.Hour
+	return cfg
+}
@@ -0,0 +1,158 @@
+// Package container runs the command line tools the chunker depends on, MuPDF's mutool
+// and tesseract, inside a Docker container, for hosts that do not have them installed.
+// The container is started on first use and runs until Close, with a work directory of
+// the host mounted into it to exchange files.
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// DefaultImage is the image built by `make docker-image` from docker/Dockerfile, which
+// bundles mutool, tesseract with the eng, ind and osd language packs, and pdftotext
+const DefaultImage = "pdf-chunk-extractor-tools"
+
+// MountPoint is where the work directory is mounted in the container
+const MountPoint = "/work"
+
+// Label marks the containers started by this package, so ones left behind by a killed
+// process can be found with docker ps --filter label=pdf-chunk-extractor
+const Label = "pdf-chunk-extractor"
+
+// dockerCommand is the name of the docker executable looked up on PATH
+const dockerCommand = "docker"
+
+// ErrDockerNotFound is returned when the docker executable cannot be run
+var ErrDockerNotFound = errors.New("docker not found on PATH")
+
+// ErrOutsideWorkDir is returned for files the container cannot see, as they are not
+// under the mounted work directory
+var ErrOutsideWorkDir = errors.New("path is outside the container work directory")
+
+// Container is a long-running container of an image. It is safe for concurrent use.
+type Container struct {
+	image   string
+	workDir string // Absolute host directory mounted at MountPoint
+
+	mu sync.Mutex
+	id string // Set while the container runs
+}
+
+// New returns a container of image, started on first use, that mounts workDir, such as
+// the temp directory of the chunker (see tempfile.Root), at MountPoint. An empty image
+// uses DefaultImage.
+func New(image, workDir string) *Container {
+	if image == "" {
+		image = DefaultImage
+	}
+	if abs, err := filepath.Abs(workDir); err == nil {
+		workDir = abs
+	}
+	return &Container{image: image, workDir: workDir}
+}
+
+// Image returns the image the container runs
+func (c *Container) Image() string {
+	return c.image
+}
+
+// WorkDir returns the host directory mounted into the container. Files passed to its
+// commands must be under it.
+func (c *Container) WorkDir() string {
+	return c.workDir
+}
+
+// Start starts the container unless it already runs. Commands start it themselves, so
+// calling Start is only needed to fail early.
+func (c *Container) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.id != "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.workDir, 0700); err != nil {
+		return fmt.Errorf("failed to create container work directory: %w", err)
+	}
+	args := []string{"run", "--detach", "--rm", "--label", Label,
+		"--volume", c.workDir + ":" + MountPoint,
+		// The images of the tools may set an entrypoint; keep the container idle until
+		// Close instead, and run every tool through docker exec
+		"--entrypoint", "sleep"}
+	if runtime.GOOS != "windows" {
+		// Write files into the work directory as this user, so they can be removed
+		args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+	args = append(args, c.image, "infinity")
+
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, dockerCommand, args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return dockerError("failed to start container of "+c.image, err, stderr.String())
+	}
+	c.id = strings.TrimSpace(string(output))
+	return nil
+}
+
+// Command returns a command running name with args in the container, starting the
+// container if needed. Paths in args must be container paths, see Path.
+func (c *Container) Command(ctx context.Context, name string, args ...string) (*exec.Cmd, error) {
+	if err := c.Start(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	id := c.id
+	c.mu.Unlock()
+	return exec.CommandContext(ctx, dockerCommand, append([]string{"exec", id, name}, args...)...), nil
+}
+
+// Path returns the path in the container of hostPath, a file under the work directory
+func (c *Container) Path(hostPath string) (string, error) {
+	abs, err := filepath.Abs(hostPath)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(c.workDir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideWorkDir, hostPath)
+	}
+	return path.Join(MountPoint, filepath.ToSlash(rel)), nil
+}
+
+// Close stops and removes the container, if it runs. It can be started again afterwards.
+func (c *Container) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.id == "" {
+		return nil
+	}
+
+	output, err := exec.Command(dockerCommand, "rm", "--force", c.id).CombinedOutput()
+	if err != nil {
+		return dockerError("failed to remove container", err, string(output))
+	}
+	c.id = ""
+	return nil
+}
+
+// dockerError describes a failed docker command with its output, if any
+func dockerError(action string, err error, output string) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrDockerNotFound
+	}
+	if message := strings.TrimSpace(output); message != "" {
+		return fmt.Errorf("%s: %w: %s", action, err, message)
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
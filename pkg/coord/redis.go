@@ -0,0 +1,227 @@
+// Package coord coordinates chunker instances that process one shared input, see
+// chunker.Coordinator
+package coord
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/resp"
+)
+
+// StatusProcessing is the Progress status of a claimed file
+const StatusProcessing = "processing"
+
+// ErrClaimLost is returned by Finish when the claim expired before the file was
+// processed, so another instance may have processed it too; see RedisOptions.Lease
+var ErrClaimLost = errors.New("claim expired before the file was processed")
+
+// RedisOptions configures a Redis coordinator
+type RedisOptions struct {
+	Addr      string        // host:port, default "localhost:6379"
+	Password  string        // Sent with AUTH when set
+	DB        int           // Selected with SELECT when not 0
+	KeyPrefix string        // Prepended to every key, default "pdfchunk:coord:"; instances sharing an input use the same one
+	Owner     string        // Names this instance in claims and progress, default <hostname>-<pid>
+	Lease     time.Duration // Claims expire unless renewed, so the files of an instance that died are taken over; default 2m, renewed every third of it
+	DoneTTL   time.Duration // How long processed files stay recorded; 0 keeps them until the keys are deleted
+	Timeout   time.Duration // Dial and command timeout, default 5s
+}
+
+// Redis coordinates chunker instances through a Redis server: an instance claims a file
+// with a key that expires unless the instance renews it, records the file as processed
+// when it finishes, and publishes the progress of every file in a hash. Claims and
+// results are updated with scripts, so they are atomic.
+type Redis struct {
+	options RedisOptions
+	client  *resp.Client
+
+	mu       sync.Mutex
+	renewals map[string]chan struct{} // Stops the lease renewal of a claim, by claim key
+}
+
+// Progress is the state of a file in the shared progress of a Redis coordinator
+type Progress struct {
+	Filename  string              `json:"filename"`
+	SHA256    string              `json:"sha256"`
+	Owner     string              `json:"owner"`  // Instance that claimed the file
+	Status    string              `json:"status"` // StatusProcessing, or the status of Result
+	UpdatedAt time.Time           `json:"updated_at"`
+	Result    *chunker.FileResult `json:"result,omitempty"` // Set once the file is processed
+}
+
+// claimScript claims a file unless it is processed or claimed.
+// KEYS: claim, done, progress; ARGV: owner, lease in ms, filename, progress
+const claimScript = `
+if redis.call('EXISTS', KEYS[2]) == 1 then return 0 end
+if not redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then return 0 end
+redis.call('HSET', KEYS[3], ARGV[3], ARGV[4])
+return 1`
+
+// renewScript extends a claim of the owner. KEYS: claim; ARGV: owner, lease in ms
+const renewScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end
+return 0`
+
+// finishScript releases a claim of the owner, recording the file as processed unless
+// the result is empty. KEYS: claim, done, progress; ARGV: owner, result, done TTL in ms
+// or 0, filename, progress
+const finishScript = `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then return 0 end
+redis.call('DEL', KEYS[1])
+if ARGV[2] ~= '' then
+  if ARGV[3] ~= '0' then redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3]) else redis.call('SET', KEYS[2], ARGV[2]) end
+end
+redis.call('HSET', KEYS[3], ARGV[4], ARGV[5])
+return 1`
+
+// NewRedis creates a Redis coordinator and checks that the server is reachable
+func NewRedis(options RedisOptions) (*Redis, error) {
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "pdfchunk:coord:"
+	}
+	if options.Owner == "" {
+		hostname, _ := os.Hostname()
+		options.Owner = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if options.Lease <= 0 {
+		options.Lease = 2 * time.Minute
+	}
+
+	r := &Redis{
+		options:  options,
+		client:   resp.New(resp.Options{Addr: options.Addr, Password: options.Password, DB: options.DB, Timeout: options.Timeout}),
+		renewals: make(map[string]chan struct{}),
+	}
+	if _, err := r.client.Do("PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return r, nil
+}
+
+// Owner returns the name of this instance in claims and progress
+func (r *Redis) Owner() string {
+	return r.options.Owner
+}
+
+// Claim claims a file for this instance and renews the claim until Finish
+func (r *Redis) Claim(filename, sha256 string) (bool, error) {
+	claimKey, doneKey := r.keys(filename, sha256)
+	progress, err := json.Marshal(Progress{Filename: filename, SHA256: sha256, Owner: r.options.Owner, Status: StatusProcessing, UpdatedAt: time.Now()})
+	if err != nil {
+		return false, err
+	}
+	reply, err := r.client.Do("EVAL", claimScript, "3", claimKey, doneKey, r.progressKey(),
+		r.options.Owner, strconv.FormatInt(r.options.Lease.Milliseconds(), 10), filename, string(progress))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim %s: %w", filename, err)
+	}
+	if reply != int64(1) {
+		return false, nil
+	}
+
+	stop := make(chan struct{})
+	r.mu.Lock()
+	r.renewals[claimKey] = stop
+	r.mu.Unlock()
+	go r.renew(claimKey, stop)
+	return true, nil
+}
+
+// Finish releases the claim on a file, recording it as processed unless it failed
+func (r *Redis) Finish(filename, sha256 string, result chunker.FileResult) error {
+	claimKey, doneKey := r.keys(filename, sha256)
+	r.mu.Lock()
+	if stop, ok := r.renewals[claimKey]; ok {
+		close(stop)
+		delete(r.renewals, claimKey)
+	}
+	r.mu.Unlock()
+
+	done := ""
+	if result.Status != chunker.FileStatusFailed {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		done = string(data)
+	}
+	progress, err := json.Marshal(Progress{Filename: filename, SHA256: sha256, Owner: r.options.Owner, Status: result.Status, UpdatedAt: time.Now(), Result: &result})
+	if err != nil {
+		return err
+	}
+	reply, err := r.client.Do("EVAL", finishScript, "3", claimKey, doneKey, r.progressKey(),
+		r.options.Owner, done, strconv.FormatInt(r.options.DoneTTL.Milliseconds(), 10), filename, string(progress))
+	if err != nil {
+		return fmt.Errorf("failed to finish %s: %w", filename, err)
+	}
+	if reply != int64(1) {
+		return fmt.Errorf("%s: %w", filename, ErrClaimLost)
+	}
+	return nil
+}
+
+// Progress returns the state of every file claimed through the coordinator's key
+// prefix, by filename
+func (r *Redis) Progress() ([]Progress, error) {
+	reply, err := r.client.Do("HGETALL", r.progressKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read progress: %w", err)
+	}
+	fields, _ := reply.([]any)
+	var progress []Progress
+	for i := 1; i < len(fields); i += 2 {
+		value, _ := fields[i].([]byte)
+		var file Progress
+		if err := json.Unmarshal(value, &file); err != nil {
+			return nil, fmt.Errorf("malformed progress of %s: %w", fields[i-1], err)
+		}
+		progress = append(progress, file)
+	}
+	sort.Slice(progress, func(i, j int) bool { return progress[i].Filename < progress[j].Filename })
+	return progress, nil
+}
+
+// Close stops renewing claims, which expire after the lease, and closes the connection
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	for claimKey, stop := range r.renewals {
+		close(stop)
+		delete(r.renewals, claimKey)
+	}
+	r.mu.Unlock()
+	return r.client.Close()
+}
+
+// renew extends a claim every third of the lease until stop is closed. Failures are
+// retried on the next tick; a claim that expires anyway is reported by Finish.
+func (r *Redis) renew(claimKey string, stop chan struct{}) {
+	ticker := time.NewTicker(r.options.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.client.Do("EVAL", renewScript, "1", claimKey, r.options.Owner, strconv.FormatInt(r.options.Lease.Milliseconds(), 10))
+		}
+	}
+}
+
+// keys returns the claim and done keys of a file
+func (r *Redis) keys(filename, sha256 string) (claimKey, doneKey string) {
+	file := sha256 + ":" + filename
+	return r.options.KeyPrefix + "claim:" + file, r.options.KeyPrefix + "done:" + file
+}
+
+// progressKey returns the key of the progress hash
+func (r *Redis) progressKey() string {
+	return r.options.KeyPrefix + "progress"
+}
@@ -0,0 +1,343 @@
+package coord
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+)
+
+// testLease is short so the tests can wait for claims to expire
+const testLease = 300 * time.Millisecond
+
+// fakeRedis is a Redis server that keeps string keys with expiry and hashes in memory.
+// It cannot run Lua, so it runs the coordinator's scripts as Go translations; every
+// command, script included, runs under one lock, as Redis runs them one at a time.
+type fakeRedis struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu        sync.Mutex
+	values    map[string]string
+	expires   map[string]time.Time
+	hashes    map[string]map[string]string
+	failRenew bool // Answers renewals with an error, like a network partition
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{t: t, listener: listener, values: map[string]string{}, expires: map[string]time.Time{}, hashes: map[string]map[string]string{}}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// coordinator creates a Redis coordinator on the fake server, closed with the test
+func (s *fakeRedis) coordinator(owner string) *Redis {
+	s.t.Helper()
+	r, err := NewRedis(RedisOptions{Addr: s.listener.Addr().String(), Owner: owner, Lease: testLease, Timeout: 5 * time.Second})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			if err != io.EOF {
+				s.t.Errorf("malformed command: %v", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		reply := s.execute(args)
+		s.mu.Unlock()
+		io.WriteString(conn, reply)
+	}
+}
+
+// readCommand reads a command sent as a RESP array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "*"), "\r\n"))
+	if err != nil || line[0] != '*' {
+		return nil, fmt.Errorf("command %q is not an array", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "$"), "\r\n"))
+		if err != nil || line[0] != '$' {
+			return nil, fmt.Errorf("argument %q is not a bulk string", line)
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:length])
+	}
+	return args, nil
+}
+
+// execute runs a command and returns its RESP reply
+func (s *fakeRedis) execute(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "HGETALL":
+		fields := s.hashes[args[1]]
+		reply := "*" + strconv.Itoa(2*len(fields)) + "\r\n"
+		for field, value := range fields {
+			reply += bulkString(field) + bulkString(value)
+		}
+		return reply
+	case "EVAL":
+		numKeys, _ := strconv.Atoi(args[2])
+		keys, argv := args[3:3+numKeys], args[3+numKeys:]
+		switch args[1] {
+		case claimScript:
+			if _, ok := s.get(keys[1]); ok {
+				return ":0\r\n"
+			}
+			if _, ok := s.get(keys[0]); ok {
+				return ":0\r\n"
+			}
+			s.set(keys[0], argv[0], argv[1])
+			s.hset(keys[2], argv[2], argv[3])
+			return ":1\r\n"
+		case renewScript:
+			if s.failRenew {
+				return "-ERR connection lost\r\n"
+			}
+			if owner, ok := s.get(keys[0]); ok && owner == argv[0] {
+				s.set(keys[0], argv[0], argv[1])
+				return ":1\r\n"
+			}
+			return ":0\r\n"
+		case finishScript:
+			if owner, ok := s.get(keys[0]); !ok || owner != argv[0] {
+				return ":0\r\n"
+			}
+			delete(s.values, keys[0])
+			if argv[1] != "" {
+				s.set(keys[1], argv[1], argv[2])
+			}
+			s.hset(keys[2], argv[3], argv[4])
+			return ":1\r\n"
+		}
+		s.t.Errorf("unexpected script %q", args[1])
+		return "-NOSCRIPT unknown script\r\n"
+	}
+	s.t.Errorf("unexpected command %q", args)
+	return "-ERR unknown command\r\n"
+}
+
+// get returns a string key unless it expired
+func (s *fakeRedis) get(key string) (string, bool) {
+	if expiry, ok := s.expires[key]; ok && !time.Now().Before(expiry) {
+		delete(s.values, key)
+		delete(s.expires, key)
+	}
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// set sets a string key that expires after ms milliseconds, or never when ms is "0"
+func (s *fakeRedis) set(key, value, ms string) {
+	s.values[key] = value
+	delete(s.expires, key)
+	if n, _ := strconv.Atoi(ms); n > 0 {
+		s.expires[key] = time.Now().Add(time.Duration(n) * time.Millisecond)
+	}
+}
+
+func (s *fakeRedis) hset(key, field, value string) {
+	if s.hashes[key] == nil {
+		s.hashes[key] = map[string]string{}
+	}
+	s.hashes[key][field] = value
+}
+
+func bulkString(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+// TestRedisClaim checks that a renewed claim outlives its lease, that processed files
+// are not claimed again and that failed ones are
+func TestRedisClaim(t *testing.T) {
+	server := newFakeRedis(t)
+	a, b := server.coordinator("a"), server.coordinator("b")
+
+	if claimed, err := a.Claim("report.pdf", "sha"); err != nil || !claimed {
+		t.Fatalf("a.Claim = %v, %v, want true", claimed, err)
+	}
+	time.Sleep(3 * testLease)
+	if claimed, err := b.Claim("report.pdf", "sha"); err != nil || claimed {
+		t.Fatalf("b.Claim of a renewed claim = %v, %v, want false", claimed, err)
+	}
+	if err := a.Finish("report.pdf", "sha", chunker.FileResult{Filename: "report.pdf", Status: chunker.FileStatusOK, Chunks: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := b.Claim("report.pdf", "sha"); err != nil || claimed {
+		t.Errorf("b.Claim of a processed file = %v, %v, want false", claimed, err)
+	}
+	if progress, err := b.Progress(); err != nil || len(progress) != 1 || progress[0].Owner != "a" || progress[0].Status != chunker.FileStatusOK || progress[0].Result == nil || progress[0].Result.Chunks != 3 {
+		t.Errorf("Progress() = %+v, %v, want report.pdf processed by a", progress, err)
+	}
+	// The same filename with other content is another file
+	if claimed, err := b.Claim("report.pdf", "sha2"); err != nil || !claimed {
+		t.Errorf("b.Claim of changed content = %v, %v, want true", claimed, err)
+	}
+
+	if claimed, err := a.Claim("broken.pdf", "sha"); err != nil || !claimed {
+		t.Fatalf("a.Claim = %v, %v, want true", claimed, err)
+	}
+	if err := a.Finish("broken.pdf", "sha", chunker.FileResult{Filename: "broken.pdf", Status: chunker.FileStatusFailed, Error: "damaged"}); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := b.Claim("broken.pdf", "sha"); err != nil || !claimed {
+		t.Errorf("b.Claim of a failed file = %v, %v, want true", claimed, err)
+	}
+
+	progress, err := a.Progress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) != 2 || progress[0].Filename != "broken.pdf" || progress[1].Filename != "report.pdf" {
+		t.Fatalf("Progress() = %+v, want broken.pdf and report.pdf", progress)
+	}
+	if progress[0].Owner != "b" || progress[0].Status != StatusProcessing {
+		t.Errorf("broken.pdf progress = %+v, want processing by b", progress[0])
+	}
+	if progress[1].Owner != "b" || progress[1].SHA256 != "sha2" || progress[1].Status != StatusProcessing {
+		t.Errorf("report.pdf progress = %+v, want the changed content processing by b", progress[1])
+	}
+}
+
+// TestRedisClaimExpiry checks that the claims of an instance that stopped renewing them
+// are taken over after the lease, and that the instance then loses ownership
+func TestRedisClaimExpiry(t *testing.T) {
+	server := newFakeRedis(t)
+	a, b := server.coordinator("a"), server.coordinator("b")
+
+	// Renewals fail, as during a partition: the claim expires while a processes the file
+	server.mu.Lock()
+	server.failRenew = true
+	server.mu.Unlock()
+	if claimed, err := a.Claim("report.pdf", "sha"); err != nil || !claimed {
+		t.Fatalf("a.Claim = %v, %v, want true", claimed, err)
+	}
+	time.Sleep(2 * testLease)
+	if claimed, err := b.Claim("report.pdf", "sha"); err != nil || !claimed {
+		t.Fatalf("b.Claim of an expired claim = %v, %v, want true", claimed, err)
+	}
+	result := chunker.FileResult{Filename: "report.pdf", Status: chunker.FileStatusOK}
+	if err := a.Finish("report.pdf", "sha", result); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("a.Finish after the takeover = %v, want ErrClaimLost", err)
+	}
+	if err := b.Finish("report.pdf", "sha", result); err != nil {
+		t.Errorf("b.Finish = %v", err)
+	}
+
+	// An expired claim nobody took over is lost too
+	if claimed, err := a.Claim("notes.pdf", "sha"); err != nil || !claimed {
+		t.Fatalf("a.Claim = %v, %v, want true", claimed, err)
+	}
+	time.Sleep(2 * testLease)
+	if err := a.Finish("notes.pdf", "sha", result); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("a.Finish after expiry = %v, want ErrClaimLost", err)
+	}
+
+	// The claims of an instance that stopped are taken over once their lease runs out
+	server.mu.Lock()
+	server.failRenew = false
+	server.mu.Unlock()
+	if claimed, err := a.Claim("slides.pdf", "sha"); err != nil || !claimed {
+		t.Fatalf("a.Claim = %v, %v, want true", claimed, err)
+	}
+	a.Close()
+	if claimed, err := b.Claim("slides.pdf", "sha"); err != nil || claimed {
+		t.Fatalf("b.Claim before the lease ran out = %v, %v, want false", claimed, err)
+	}
+	time.Sleep(2 * testLease)
+	if claimed, err := b.Claim("slides.pdf", "sha"); err != nil || !claimed {
+		t.Errorf("b.Claim after the lease ran out = %v, %v, want true", claimed, err)
+	}
+}
+
+// TestRedisConcurrentClaim checks that every file goes to exactly one of many instances
+// claiming it at once
+func TestRedisConcurrentClaim(t *testing.T) {
+	server := newFakeRedis(t)
+	const instances, files = 8, 10
+	var coordinators []*Redis
+	for i := range instances {
+		coordinators = append(coordinators, server.coordinator(fmt.Sprintf("instance-%d", i)))
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		owners = map[string][]string{}
+		start  = make(chan struct{})
+	)
+	for _, r := range coordinators {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for i := range files {
+				filename := fmt.Sprintf("file-%d.pdf", i)
+				claimed, err := r.Claim(filename, "sha")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if claimed {
+					mu.Lock()
+					owners[filename] = append(owners[filename], r.Owner())
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for i := range files {
+		filename := fmt.Sprintf("file-%d.pdf", i)
+		if len(owners[filename]) != 1 {
+			t.Errorf("%s claimed by %v, want exactly one instance", filename, owners[filename])
+		}
+	}
+}
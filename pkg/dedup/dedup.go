@@ -0,0 +1,259 @@
+// Package dedup finds near-duplicate chunks across documents, such as repeated legal
+// boilerplate, with MinHash signatures of word shingles and locality-sensitive hashing,
+// so a corpus can be checked chunk by chunk as it is processed
+package dedup
+
+import (
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// MinHash and LSH parameters: bands of rows of the signature are hashed into buckets, so
+// chunks become candidates when a whole band matches, and candidates are compared on the
+// full signature
+const (
+	signatureSize = 128
+	bands         = 32
+	rows          = signatureSize / bands
+	shingleWords  = 3 // Words per shingle
+)
+
+// DefaultThreshold is the estimated Jaccard similarity of the word shingles of two
+// chunks from which they are near-duplicates
+const DefaultThreshold = 0.8
+
+// minShingles skips chunks too short to compare reliably, such as lone headings
+const minShingles = 8
+
+// Duplicate is a chunk found to be a near-duplicate of an earlier one
+type Duplicate struct {
+	Chunk     schema.ChunkRef `json:"chunk"`
+	Canonical schema.ChunkRef `json:"canonical"` // With the estimated similarity
+}
+
+// Report lists the near-duplicates found by an Index
+type Report struct {
+	Threshold  float64     `json:"threshold"`
+	Chunks     int         `json:"chunks"`     // Chunks checked and committed
+	Duplicates []Duplicate `json:"duplicates"` // The latest ones, as many as the index keeps chunks
+}
+
+// entry is a chunk kept in an Index
+type entry struct {
+	ref        schema.ChunkRef
+	signature  [signatureSize]uint64
+	bandHashes [bands]uint64
+}
+
+// Check is the result of checking a chunk against an Index, to Commit once the chunk is
+// saved
+type Check struct {
+	Canonical *schema.ChunkRef // The chunk it is a near-duplicate of, with the estimated similarity; nil when it is none
+
+	ref        schema.ChunkRef
+	signature  [signatureSize]uint64
+	bandHashes [bands]uint64
+	ok         bool // The text has enough shingles to compare
+}
+
+// Index holds the signatures of the chunks committed so far, up to a maximum from which
+// the oldest are forgotten first. It is safe for concurrent use; the first of a group of
+// near-duplicates to be committed is the canonical chunk.
+type Index struct {
+	mu        sync.Mutex
+	threshold float64
+	maxChunks int
+	seeds     [signatureSize]uint64
+	entries   []entry
+	first     int              // Sequence number of entries[0]; earlier ones were forgotten
+	buckets   map[uint64][]int // Band hash to sequence numbers of entries, oldest first
+	report    Report
+}
+
+// NewIndex creates an empty index; a threshold of 0 or less means DefaultThreshold, and
+// a maxChunks of 0 or less keeps every chunk
+func NewIndex(threshold float64, maxChunks int) *Index {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	index := &Index{threshold: threshold, maxChunks: maxChunks, buckets: make(map[uint64][]int)}
+	index.report.Threshold = threshold
+	seed := uint64(0x9e3779b97f4a7c15)
+	for i := range index.seeds {
+		seed = mix(seed + uint64(i))
+		index.seeds[i] = seed
+	}
+	return index
+}
+
+// Check compares a chunk with the committed chunks of other documents and sets the
+// canonical chunk of a near-duplicate, without changing the index. Chunks of the same
+// Slug are never compared, so a document's own repetitions are kept.
+func (x *Index) Check(ref schema.ChunkRef, text string) Check {
+	check := Check{ref: ref}
+	check.signature, check.ok = x.signature(text)
+	if !check.ok {
+		return check
+	}
+	for band := range bands {
+		check.bandHashes[band] = bandHash(band, check.signature[band*rows:(band+1)*rows])
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	best, bestSimilarity := -1, 0.0
+	seen := make(map[int]bool)
+	for _, hash := range check.bandHashes {
+		for _, candidate := range x.buckets[hash] {
+			candidate -= x.first
+			if seen[candidate] || x.entries[candidate].ref.Slug == ref.Slug {
+				continue
+			}
+			seen[candidate] = true
+			if similarity := similarity(check.signature, x.entries[candidate].signature); similarity > bestSimilarity {
+				best, bestSimilarity = candidate, similarity
+			}
+		}
+	}
+	if best >= 0 && bestSimilarity >= x.threshold {
+		canonical := x.entries[best].ref
+		canonical.Similarity = bestSimilarity
+		check.Canonical = &canonical
+	}
+	return check
+}
+
+// Commit records checked chunks once they are saved: near-duplicates in the report, and
+// other chunks in the index, so later chunks are compared with them
+func (x *Index) Commit(checks ...Check) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, check := range checks {
+		x.report.Chunks++
+		if !check.ok {
+			continue
+		}
+		if check.Canonical != nil {
+			x.report.Duplicates = append(x.report.Duplicates, Duplicate{Chunk: check.ref, Canonical: *check.Canonical})
+			if x.maxChunks > 0 && len(x.report.Duplicates) > x.maxChunks {
+				x.report.Duplicates = slices.Delete(x.report.Duplicates, 0, 1)
+			}
+			continue
+		}
+		x.entries = append(x.entries, entry{ref: check.ref, signature: check.signature, bandHashes: check.bandHashes})
+		sequence := x.first + len(x.entries) - 1
+		for _, hash := range check.bandHashes {
+			x.buckets[hash] = append(x.buckets[hash], sequence)
+		}
+		if x.maxChunks > 0 && len(x.entries) > x.maxChunks {
+			x.forgetOldest()
+		}
+	}
+}
+
+// Add checks a chunk and commits it at once, returning the canonical chunk of a
+// near-duplicate
+func (x *Index) Add(ref schema.ChunkRef, text string) (*schema.ChunkRef, bool) {
+	check := x.Check(ref, text)
+	x.Commit(check)
+	return check.Canonical, check.Canonical != nil
+}
+
+// Report returns the near-duplicates found so far
+func (x *Index) Report() Report {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	report := x.report
+	report.Duplicates = append([]Duplicate(nil), x.report.Duplicates...)
+	return report
+}
+
+// forgetOldest removes the oldest entry, which comes first in each of its buckets
+func (x *Index) forgetOldest() {
+	for _, hash := range x.entries[0].bandHashes {
+		bucket := x.buckets[hash]
+		for len(bucket) > 0 && bucket[0] == x.first {
+			bucket = bucket[1:]
+		}
+		if len(bucket) == 0 {
+			delete(x.buckets, hash)
+		} else {
+			x.buckets[hash] = bucket
+		}
+	}
+	x.entries[0] = entry{}
+	x.entries = x.entries[1:]
+	x.first++
+}
+
+// signature computes the MinHash signature of the word shingles of text, without the
+// formatter's metadata lines, and reports whether the text has enough shingles
+func (x *Index) signature(text string) ([signatureSize]uint64, bool) {
+	var words []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		// Chunk numbers and page ranges differ between otherwise identical chunks
+		if utils.IsMetadataLine(line) {
+			continue
+		}
+		words = append(words, strings.FieldsFunc(strings.ToLower(line), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	var signature [signatureSize]uint64
+	if len(words) < shingleWords+minShingles-1 {
+		return signature, false
+	}
+	for i := range signature {
+		signature[i] = ^uint64(0)
+	}
+	for i := 0; i+shingleWords <= len(words); i++ {
+		hash := fnv.New64a()
+		for _, word := range words[i : i+shingleWords] {
+			hash.Write([]byte(word))
+			hash.Write([]byte{0})
+		}
+		shingle := hash.Sum64()
+		for j, seed := range x.seeds {
+			signature[j] = min(signature[j], mix(shingle^seed))
+		}
+	}
+	return signature, true
+}
+
+// similarity estimates the Jaccard similarity of two shingle sets from their signatures
+func similarity(a, b [signatureSize]uint64) float64 {
+	equal := 0
+	for i := range a {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / signatureSize
+}
+
+// bandHash hashes a band of a signature together with its number
+func bandHash(band int, values []uint64) uint64 {
+	hash := mix(uint64(band) + 1)
+	for _, value := range values {
+		hash = mix(hash ^ value)
+	}
+	return hash
+}
+
+// mix is the splitmix64 finalizer, a fast well-distributed 64-bit hash
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
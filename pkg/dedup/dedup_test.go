@@ -0,0 +1,63 @@
+package dedup
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// clause returns a distinct clause long enough to be compared
+func clause(n int) string {
+	return fmt.Sprintf("Clause %d. The supplier delivers the goods of order %d to the warehouse within fourteen days of the purchase order.", n, n)
+}
+
+// TestIndex checks that checks change nothing until committed, and that an index with a
+// maximum forgets its oldest chunks first
+func TestIndex(t *testing.T) {
+	index := NewIndex(0, 2)
+	ref := func(slug string, n int) schema.ChunkRef {
+		return schema.ChunkRef{Filename: slug + ".txt", Slug: slug, ChunkIndex: n}
+	}
+
+	if check := index.Check(ref("a", 1), clause(1)); check.Canonical != nil {
+		t.Fatalf("chunk of an empty index is a duplicate of %+v", check.Canonical)
+	}
+	// Not committed, so not canonical
+	if check := index.Check(ref("b", 1), clause(1)); check.Canonical != nil {
+		t.Fatalf("uncommitted chunk is canonical for %+v", check.Canonical)
+	}
+
+	index.Commit(index.Check(ref("a", 1), clause(1)), index.Check(ref("a", 2), clause(2)))
+	if check := index.Check(ref("a", 3), clause(1)); check.Canonical != nil {
+		t.Error("repetition within a document is a duplicate")
+	}
+	canonical, duplicate := index.Add(ref("b", 1), clause(2))
+	if !duplicate || canonical.Slug != "a" || canonical.ChunkIndex != 2 || canonical.Similarity < DefaultThreshold {
+		t.Errorf("duplicate of %+v, want a chunk 2", canonical)
+	}
+
+	// c is past the maximum of 2, so a chunk 1 is forgotten
+	index.Add(ref("c", 1), clause(3))
+	if len(index.entries) != 2 {
+		t.Fatalf("index keeps %d chunks, want 2", len(index.entries))
+	}
+	if _, duplicate := index.Add(ref("d", 1), clause(1)); duplicate {
+		t.Error("forgotten chunk is still canonical")
+	}
+	if canonical, duplicate := index.Add(ref("e", 1), clause(3)); !duplicate || canonical.Slug != "c" {
+		t.Errorf("duplicate of %+v, want c chunk 1", canonical)
+	}
+	for hash, bucket := range index.buckets {
+		for _, sequence := range bucket {
+			if sequence < index.first {
+				t.Fatalf("bucket %x keeps forgotten entry %d", hash, sequence)
+			}
+		}
+	}
+
+	report := index.Report()
+	if report.Chunks != 6 || len(report.Duplicates) != 2 {
+		t.Errorf("report = %d chunks and %d duplicates, want 6 and 2", report.Chunks, len(report.Duplicates))
+	}
+}
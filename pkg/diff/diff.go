@@ -0,0 +1,261 @@
+// Package diff compares two versions of a chunked document, such as revisions of a
+// regulation or an SOP, and lists the chunks added, removed and modified between them
+// with their page references
+package diff
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// Kinds of Change
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+// DefaultThreshold is the similarity of the words of a removed and an added chunk from
+// which they are one modified chunk
+const DefaultThreshold = 0.5
+
+// Side is the chunk of one version in a change
+type Side struct {
+	ChunkIndex int    `json:"chunk_index"`
+	PageRange  string `json:"page_range"`
+	StartPage  int    `json:"start_page,omitempty"`
+	EndPage    int    `json:"end_page,omitempty"`
+	Section    string `json:"section,omitempty"` // The "section" metadata of the chunk
+	Text       string `json:"text"`              // Document text of the chunk, without the lines the formatter adds
+}
+
+// Change is a chunk added to, removed from or modified in the new version
+type Change struct {
+	Kind       string  `json:"kind"`                 // Added, Removed or Modified
+	Old        *Side   `json:"old,omitempty"`        // Not set for added chunks
+	New        *Side   `json:"new,omitempty"`        // Not set for removed chunks
+	Similarity float64 `json:"similarity,omitempty"` // Word similarity of a modified chunk, from 0 to 1
+}
+
+// Diff lists the changes between two versions of a document in document order
+type Diff struct {
+	OldFilename string   `json:"old_filename"`
+	NewFilename string   `json:"new_filename"`
+	Unchanged   int      `json:"unchanged"` // Chunks with the same text in both versions
+	Added       int      `json:"added"`
+	Removed     int      `json:"removed"`
+	Modified    int      `json:"modified"`
+	Changes     []Change `json:"changes"`
+}
+
+// Compare lists the chunks added, removed and modified in newChunks. Chunks with the same
+// document text, ignoring whitespace and the lines the formatter adds, are unchanged; the
+// longest run of them in order anchors the comparison. Between anchors, a removed chunk
+// and an added one are a modified chunk when the similarity of their words is at least
+// threshold (DefaultThreshold when 0 or less).
+func Compare(oldChunks, newChunks []schema.Chunk, threshold float64) *Diff {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	d := &Diff{}
+	if len(oldChunks) > 0 {
+		d.OldFilename = oldChunks[0].Filename
+	}
+	if len(newChunks) > 0 {
+		d.NewFilename = newChunks[0].Filename
+	}
+
+	oldTexts, newTexts := documentTexts(oldChunks), documentTexts(newChunks)
+	i, j := 0, 0
+	for _, match := range commonChunks(oldTexts, newTexts) {
+		d.compareGap(oldChunks[i:match[0]], newChunks[j:match[1]], oldTexts[i:match[0]], newTexts[j:match[1]], threshold)
+		d.Unchanged++
+		i, j = match[0]+1, match[1]+1
+	}
+	d.compareGap(oldChunks[i:], newChunks[j:], oldTexts[i:], newTexts[j:], threshold)
+	return d
+}
+
+// compareGap pairs the chunks between two anchors in order: every old chunk is modified
+// into the most similar new chunk not yet paired after the last pair, the new chunks
+// skipped over are added, and old chunks without a similar one are removed
+func (d *Diff) compareGap(oldChunks, newChunks []schema.Chunk, oldTexts, newTexts []string, threshold float64) {
+	newWords := make([]map[string]bool, len(newTexts))
+	for k, text := range newTexts {
+		newWords[k] = wordSet(text)
+	}
+	next := 0
+	for i, chunk := range oldChunks {
+		words := wordSet(oldTexts[i])
+		best, bestSimilarity := -1, 0.0
+		for k := next; k < len(newChunks); k++ {
+			if similarity := jaccard(words, newWords[k]); similarity >= threshold && similarity > bestSimilarity {
+				best, bestSimilarity = k, similarity
+			}
+		}
+		if best < 0 {
+			d.add(Change{Kind: Removed, Old: newSide(chunk, oldTexts[i])})
+			continue
+		}
+		for ; next < best; next++ {
+			d.add(Change{Kind: Added, New: newSide(newChunks[next], newTexts[next])})
+		}
+		d.add(Change{Kind: Modified, Old: newSide(chunk, oldTexts[i]), New: newSide(newChunks[best], newTexts[best]), Similarity: round(bestSimilarity)})
+		next = best + 1
+	}
+	for ; next < len(newChunks); next++ {
+		d.add(Change{Kind: Added, New: newSide(newChunks[next], newTexts[next])})
+	}
+}
+
+// add appends a change and counts it
+func (d *Diff) add(change Change) {
+	switch change.Kind {
+	case Added:
+		d.Added++
+	case Removed:
+		d.Removed++
+	case Modified:
+		d.Modified++
+	}
+	d.Changes = append(d.Changes, change)
+}
+
+// newSide returns the side of a chunk in a change
+func newSide(chunk schema.Chunk, text string) *Side {
+	section, _ := chunk.Metadata["section"].(string)
+	return &Side{
+		ChunkIndex: chunk.ChunkIndex,
+		PageRange:  chunk.PageRange,
+		StartPage:  chunk.StartPage,
+		EndPage:    chunk.EndPage,
+		Section:    section,
+		Text:       text,
+	}
+}
+
+// documentTexts returns the document text of every chunk, one line per line of text with
+// whitespace collapsed, leaving out the headings and labels the formatter adds
+func documentTexts(chunks []schema.Chunk) []string {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		var lines []string
+		for _, line := range strings.Split(chunk.Text, "\n") {
+			line = strings.Join(strings.Fields(line), " ")
+			if line != "" && !utils.IsMetadataLine(line) {
+				lines = append(lines, line)
+			}
+		}
+		texts[i] = strings.Join(lines, "\n")
+	}
+	return texts
+}
+
+// commonChunks returns the positions in a and b of the longest common subsequence of
+// equal texts, in order
+func commonChunks(a, b []string) [][2]int {
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var matches [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			matches = append(matches, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// wordSet returns the distinct words of a text in lower case
+func wordSet(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
+
+// jaccard returns the Jaccard similarity of two word sets
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// round rounds a similarity to 3 decimals
+func round(x float64) float64 {
+	return math.Round(x*1000) / 1000
+}
+
+// WriteMarkdown writes the changes as a Markdown report for reviewers: a summary line
+// and, for every change, its kind, page references and text
+func (d *Diff) WriteMarkdown(w io.Writer) error {
+	var report strings.Builder
+	fmt.Fprintf(&report, "# Changes from %s to %s\n\n", d.OldFilename, d.NewFilename)
+	fmt.Fprintf(&report, "%d added, %d removed, %d modified, %d unchanged chunks\n", d.Added, d.Removed, d.Modified, d.Unchanged)
+	for _, change := range d.Changes {
+		report.WriteString("\n## ")
+		switch change.Kind {
+		case Added:
+			fmt.Fprintf(&report, "Added: %s\n\n%s\n", sideHeading(change.New, "new"), quote(change.New.Text))
+		case Removed:
+			fmt.Fprintf(&report, "Removed: %s\n\n%s\n", sideHeading(change.Old, "old"), quote(change.Old.Text))
+		case Modified:
+			fmt.Fprintf(&report, "Modified: %s → %s (similarity %.2f)\n\n", sideHeading(change.Old, "old"), sideHeading(change.New, "new"), change.Similarity)
+			fmt.Fprintf(&report, "Before:\n\n%s\n\nAfter:\n\n%s\n", quote(change.Old.Text), quote(change.New.Text))
+		}
+	}
+	_, err := io.WriteString(w, report.String())
+	return err
+}
+
+// sideHeading names the chunk of a side with its version, pages and section
+func sideHeading(side *Side, version string) string {
+	heading := fmt.Sprintf("%s chunk %d", version, side.ChunkIndex)
+	if side.PageRange != "" {
+		heading += ", pages " + side.PageRange
+	}
+	if side.Section != "" {
+		heading += " (" + side.Section + ")"
+	}
+	return heading
+}
+
+// quote formats text as a Markdown block quote
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(text, "\n", "\n> ")
+}
@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// chunks returns chunks of a file with the given texts, numbered from 1
+func chunks(filename string, texts ...string) []schema.Chunk {
+	result := make([]schema.Chunk, len(texts))
+	for i, text := range texts {
+		result[i] = schema.Chunk{Filename: filename, ChunkIndex: i + 1, StartPage: i + 1, EndPage: i + 1, PageRange: fmt.Sprintf("Page %d", i+1), Text: text}
+	}
+	return result
+}
+
+// summary describes the changes of a diff as "kind old→new" chunk indexes, 0 for none
+func summary(d *Diff) []string {
+	var changes []string
+	for _, change := range d.Changes {
+		old, new := 0, 0
+		if change.Old != nil {
+			old = change.Old.ChunkIndex
+		}
+		if change.New != nil {
+			new = change.New.ChunkIndex
+		}
+		changes = append(changes, fmt.Sprintf("%s %d→%d", change.Kind, old, new))
+	}
+	return changes
+}
+
+// TestCompare checks the changes found between versions whose chunks are unchanged,
+// moved, reordered, modified or added, or where one version has no chunks
+func TestCompare(t *testing.T) {
+	const (
+		scope    = "Article 1. This procedure applies to every warehouse of the company."
+		approval = "Article 2. Purchases above ten million rupiah need the approval of the director."
+		records  = "Article 3. Receipts are archived for five years in the finance office."
+		training = "Article 4. New staff attend the safety training in their first week."
+	)
+	tests := []struct {
+		name                                  string
+		old, new                              []schema.Chunk
+		wantUnchanged, wantAdded, wantRemoved int
+		wantModified                          int
+		wantChanges                           []string
+		wantOldFilename, wantNewFilename      string
+	}{
+		{
+			name: "same text", old: chunks("v1.pdf", scope, approval), new: chunks("v2.pdf", scope, " "+approval+"\n"),
+			wantUnchanged: 2, wantOldFilename: "v1.pdf", wantNewFilename: "v2.pdf",
+		},
+		{
+			name: "empty old", old: nil, new: chunks("v2.pdf", scope, approval),
+			wantAdded: 2, wantChanges: []string{"added 0→1", "added 0→2"}, wantNewFilename: "v2.pdf",
+		},
+		{
+			name: "empty new", old: chunks("v1.pdf", scope, approval), new: nil,
+			wantRemoved: 2, wantChanges: []string{"removed 1→0", "removed 2→0"}, wantOldFilename: "v1.pdf",
+		},
+		{
+			name: "both empty",
+		},
+		{
+			// A moved chunk is unchanged text, but out of the order of the anchors
+			name: "chunk moved to the end", old: chunks("v1.pdf", scope, approval, records), new: chunks("v2.pdf", approval, records, scope),
+			wantUnchanged: 2, wantAdded: 1, wantRemoved: 1, wantChanges: []string{"removed 1→0", "added 0→3"}, wantOldFilename: "v1.pdf", wantNewFilename: "v2.pdf",
+		},
+		{
+			name: "chunks swapped", old: chunks("v1.pdf", scope, approval, records, training), new: chunks("v2.pdf", scope, records, approval, training),
+			wantUnchanged: 3, wantAdded: 1, wantRemoved: 1, wantChanges: []string{"removed 2→0", "added 0→3"}, wantOldFilename: "v1.pdf", wantNewFilename: "v2.pdf",
+		},
+		{
+			name: "chunk modified and one added", old: chunks("v1.pdf", scope, approval, records), new: chunks("v2.pdf", scope, training, "Article 2. Purchases above twenty million rupiah need the approval of the director.", records),
+			wantUnchanged: 2, wantAdded: 1, wantModified: 1, wantChanges: []string{"added 0→2", "modified 2→3"}, wantOldFilename: "v1.pdf", wantNewFilename: "v2.pdf",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := Compare(test.old, test.new, 0)
+			if d.Unchanged != test.wantUnchanged || d.Added != test.wantAdded || d.Removed != test.wantRemoved || d.Modified != test.wantModified {
+				t.Errorf("%d unchanged, %d added, %d removed, %d modified, want %d, %d, %d, %d", d.Unchanged, d.Added, d.Removed, d.Modified, test.wantUnchanged, test.wantAdded, test.wantRemoved, test.wantModified)
+			}
+			if got := summary(d); !slices.Equal(got, test.wantChanges) {
+				t.Errorf("changes = %q, want %q", got, test.wantChanges)
+			}
+			if d.OldFilename != test.wantOldFilename || d.NewFilename != test.wantNewFilename {
+				t.Errorf("filenames = %q, %q, want %q, %q", d.OldFilename, d.NewFilename, test.wantOldFilename, test.wantNewFilename)
+			}
+			for _, change := range d.Changes {
+				if change.Kind == Modified && (change.Similarity < DefaultThreshold || change.Similarity >= 1) {
+					t.Errorf("modified chunk similarity = %v, want from %v to below 1", change.Similarity, DefaultThreshold)
+				}
+			}
+		})
+	}
+}
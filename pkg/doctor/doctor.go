@@ -0,0 +1,258 @@
+// Package doctor checks that the tools and directories the chunker depends on are in
+// place before a run, and says how to fix what is not
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/container"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ocr"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
+)
+
+// Check statuses. Failed checks stop a run; warnings only disable a feature, such as OCR.
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusFailed  = "failed"
+)
+
+// Check is the outcome of one check
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // StatusOK, StatusWarning or StatusFailed
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"` // What to do about a warning or failure
+}
+
+// Report is the outcome of every check
+type Report struct {
+	Checks []Check `json:"checks"`
+}
+
+// Run checks the PDF engine, tesseract and its language packs, on this machine or in a
+// container of DockerImage, and that the directories of cfg can be written
+func Run(cfg config.ChunkerConfig) Report {
+	var report Report
+	if cfg.DockerImage != "" {
+		tools := container.New(cfg.DockerImage, tempfile.Root(cfg.TempDir))
+		defer tools.Close()
+		if checkContainer(&report, tools) {
+			checkTesseract(&report, cfg, tools)
+		}
+	} else if checkEngine(&report) {
+		checkTesseract(&report, cfg, nil)
+	}
+	checkDirectories(&report, cfg)
+	return report
+}
+
+// Failed reports whether a check failed
+func (r Report) Failed() bool {
+	return slices.ContainsFunc(r.Checks, func(check Check) bool { return check.Status == StatusFailed })
+}
+
+// Problems returns the checks that did not pass
+func (r Report) Problems() []Check {
+	var problems []Check
+	for _, check := range r.Checks {
+		if check.Status != StatusOK {
+			problems = append(problems, check)
+		}
+	}
+	return problems
+}
+
+// WriteTable writes every check as an aligned text table, followed by the fixes
+func (r Report) WriteTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CHECK\tSTATUS\tDETAIL")
+	for _, check := range r.Checks {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", check.Name, check.Status, check.Detail)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	return WriteFixes(w, r.Problems())
+}
+
+// WriteFixes writes the problem and fix of each of checks that has a fix
+func WriteFixes(w io.Writer, checks []Check) error {
+	for _, check := range checks {
+		if check.Fix == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "\n%s %s: %s\n  Fix: %s\n", strings.ToUpper(check.Status), check.Name, check.Detail, check.Fix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add appends a check to the report
+func (r *Report) add(name, status, detail, fix string) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Detail: detail, Fix: fix})
+}
+
+// checkEngine checks that the PDF engine reads and renders PDFs, and returns whether it
+// renders pages for OCR
+func checkEngine(report *Report) bool {
+	engine, render, err := processor.CheckEngine()
+	switch {
+	case err != nil:
+		report.add("pdf engine", StatusFailed, fmt.Sprintf("%s: %v", engine, err),
+			"build with CGO_ENABLED=1 so go-fitz links its bundled MuPDF, or install the libmupdf shared library of the go-fitz version for CGO_ENABLED=0 builds, or build with -tags purego")
+		return false
+	case !render:
+		report.add("pdf engine", StatusWarning, engine+" reads text but cannot render pages, so scanned pages are not OCRed",
+			"build without -tags purego to OCR scanned pages")
+	default:
+		report.add("pdf engine", StatusOK, engine+" reads and renders PDFs", "")
+	}
+	return render
+}
+
+// checkContainer checks that a container of the image starts, and reads and renders PDFs
+// with mutool, and returns whether it does
+func checkContainer(report *Report, tools *container.Container) bool {
+	if err := processor.CheckContainer(tools); err != nil {
+		fix := "make docker-image, or set PDF_CHUNK_DOCKER_IMAGE to an image with mutool and tesseract"
+		if errors.Is(err, container.ErrDockerNotFound) {
+			fix = "install Docker, or unset PDF_CHUNK_DOCKER_IMAGE and install MuPDF and tesseract on this machine"
+		}
+		report.add("pdf engine", StatusFailed, fmt.Sprintf("%s in docker image %s: %v", processor.EngineDocker, tools.Image(), err), fix)
+		return false
+	}
+	report.add("pdf engine", StatusOK, fmt.Sprintf("%s reads and renders PDFs in docker image %s", processor.EngineDocker, tools.Image()), "")
+	return true
+}
+
+// checkTesseract checks that tesseract runs and has the configured language packs, in
+// tools when it is not nil
+func checkTesseract(report *Report, cfg config.ChunkerConfig, tools *container.Container) {
+	tesseract := ocr.NewTesseract(cfg.OCRLanguages, cfg.OCRAutoDetect).WithBinary(cfg.TesseractPath)
+	install, installLanguages := installTesseract(), installLanguages
+	if tools != nil {
+		tesseract = tesseract.WithContainer(tools)
+		install = "add tesseract-ocr to the image, as docker/Dockerfile does, and rebuild it"
+		installLanguages = func(missing []string) string {
+			return "add " + strings.Join(debianPackages(missing), " ") + " to the image, as docker/Dockerfile does, and rebuild it"
+		}
+	}
+	version, err := tesseract.Version()
+	if errors.Is(err, ocr.ErrTesseractNotFound) {
+		report.add("tesseract", StatusWarning, "not found on PATH or in the usual install locations; scanned pages are not OCRed", install)
+		return
+	}
+	if err != nil {
+		report.add("tesseract", StatusWarning, fmt.Sprintf("%s does not run: %v; scanned pages are not OCRed", tesseract.Binary(), err), install)
+		return
+	}
+	report.add("tesseract", StatusOK, fmt.Sprintf("%s (%s)", version, tesseract.Binary()), "")
+
+	installed, err := tesseract.InstalledLanguages()
+	if err != nil {
+		report.add("tesseract languages", StatusWarning, fmt.Sprintf("failed to list language packs: %v", err), "")
+		return
+	}
+	wanted := cfg.OCRLanguages
+	if len(wanted) == 0 {
+		wanted = ocr.DefaultLanguages
+	}
+	if cfg.OCRAutoDetect {
+		wanted = append(slices.Clone(wanted), "osd") // Script detection
+	}
+	var missing []string
+	for _, language := range wanted {
+		if !slices.Contains(installed, language) {
+			missing = append(missing, language)
+		}
+	}
+	if len(missing) > 0 {
+		report.add("tesseract languages", StatusWarning, fmt.Sprintf("missing %s of %s; OCR of those languages is poor", strings.Join(missing, ", "), strings.Join(wanted, "+")), installLanguages(missing))
+		return
+	}
+	report.add("tesseract languages", StatusOK, strings.Join(wanted, "+")+" installed", "")
+}
+
+// installTesseract says how to install tesseract on this platform
+func installTesseract() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "brew install tesseract, or set TESSERACT_PATH to the tesseract executable"
+	case "windows":
+		return `install it from https://github.com/UB-Mannheim/tesseract/wiki, or set TESSERACT_PATH to tesseract.exe`
+	default:
+		return "sudo apt install tesseract-ocr (or your distribution's package), or set TESSERACT_PATH to the tesseract executable"
+	}
+}
+
+// installLanguages says how to install the missing language packs on this platform
+func installLanguages(missing []string) string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "brew install tesseract-lang"
+	case "windows":
+		return "rerun the tesseract installer and select " + strings.Join(missing, ", ") + " under Additional language data"
+	default:
+		return "sudo apt install " + strings.Join(debianPackages(missing), " ") + ", or copy <language>.traineddata from https://github.com/tesseract-ocr/tessdata into the tessdata directory"
+	}
+}
+
+// debianPackages returns the Debian and Ubuntu packages of tesseract language packs
+func debianPackages(languages []string) []string {
+	packages := make([]string, len(languages))
+	for i, language := range languages {
+		packages[i] = "tesseract-ocr-" + strings.ReplaceAll(language, "_", "-")
+	}
+	return packages
+}
+
+// checkDirectories checks that the output and temp directories can be created and
+// written
+func checkDirectories(report *Report, cfg config.ChunkerConfig) {
+	dirs := []struct{ name, path string }{
+		{"output directory", cfg.OutputDir},
+		{"chunk directory", cfg.ChunkDir},
+		{"json directory", cfg.JSONDir},
+		{"temp directory", tempfile.Root(cfg.TempDir)},
+	}
+	if cfg.PageImages {
+		dirs = append(dirs, struct{ name, path string }{"page image directory", cfg.PageImageDir})
+	}
+	if cfg.SplitPDF != "" {
+		dirs = append(dirs, struct{ name, path string }{"split PDF directory", cfg.SplitPDFDir})
+	}
+	for _, dir := range dirs {
+		if dir.path == "" {
+			continue
+		}
+		if err := checkWritable(dir.path); err != nil {
+			report.add(dir.name, StatusFailed, err.Error(), fmt.Sprintf("make %s writable by this user, or point the run at another directory", dir.path))
+			continue
+		}
+		report.add(dir.name, StatusOK, dir.path+" is writable", "")
+	}
+}
+
+// checkWritable creates dir when needed and writes and removes a file in it
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	file, err := os.CreateTemp(dir, ".doctor-")
+	if err != nil {
+		return fmt.Errorf("failed to write to %s: %w", dir, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
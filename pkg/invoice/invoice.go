@@ -0,0 +1,351 @@
+// Package invoice extracts the key fields of invoices and receipts (vendor, number, date,
+// totals and line items) with regular expressions, and parses the answers of AI providers
+// asked for the same fields with Schema, for the invoice profile of the chunker
+package invoice
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Extractors of Invoice.Extractor
+const (
+	ExtractorRegex = "regex" // Extract
+	ExtractorAI    = "ai"    // Parse of an AI answer
+)
+
+// Invoice holds the key fields of an invoice or receipt. Amounts are in Currency; fields
+// that were not found are left empty.
+type Invoice struct {
+	Vendor    string     `json:"vendor,omitempty"`
+	Number    string     `json:"number,omitempty"`
+	Date      string     `json:"date,omitempty"`     // YYYY-MM-DD when it could be read, as written otherwise
+	Currency  string     `json:"currency,omitempty"` // ISO 4217 code, e.g. "IDR" or "USD"
+	Subtotal  float64    `json:"subtotal,omitempty"`
+	Tax       float64    `json:"tax,omitempty"`
+	Total     float64    `json:"total,omitempty"`
+	LineItems []LineItem `json:"line_items,omitempty"`
+	Extractor string     `json:"extractor"` // ExtractorRegex or ExtractorAI
+}
+
+// LineItem is a line of an invoice or receipt
+type LineItem struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity,omitempty"`
+	UnitPrice   float64 `json:"unit_price,omitempty"`
+	Amount      float64 `json:"amount,omitempty"`
+}
+
+// Schema is the JSON schema of Invoice without Extractor, in the json_schema settings of
+// an OpenAI response_format
+var Schema = json.RawMessage(`{
+  "name": "invoice",
+  "schema": {
+    "type": "object",
+    "properties": {
+      "vendor": {"type": "string", "description": "Name of the seller or store"},
+      "number": {"type": "string", "description": "Invoice or receipt number"},
+      "date": {"type": "string", "description": "Issue date as YYYY-MM-DD"},
+      "currency": {"type": "string", "description": "ISO 4217 currency code"},
+      "subtotal": {"type": "number"},
+      "tax": {"type": "number"},
+      "total": {"type": "number", "description": "Amount due"},
+      "line_items": {
+        "type": "array",
+        "items": {
+          "type": "object",
+          "properties": {
+            "description": {"type": "string"},
+            "quantity": {"type": "number"},
+            "unit_price": {"type": "number"},
+            "amount": {"type": "number"}
+          },
+          "required": ["description"]
+        }
+      }
+    }
+  }
+}`)
+
+// Parse parses the JSON answer of an AI provider asked for Schema, tolerating a Markdown
+// code fence around it
+func Parse(answer string) (*Invoice, error) {
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(answer, "```") {
+		answer = strings.TrimPrefix(answer, "```json")
+		answer = strings.TrimPrefix(answer, "```")
+		answer = strings.TrimSuffix(strings.TrimSpace(answer), "```")
+	}
+
+	var invoice Invoice
+	if err := json.Unmarshal([]byte(answer), &invoice); err != nil {
+		return nil, fmt.Errorf("failed to parse invoice: %w", err)
+	}
+	if invoice.Vendor == "" && invoice.Total == 0 && len(invoice.LineItems) == 0 {
+		return nil, fmt.Errorf("invoice has no vendor, total or line items")
+	}
+	invoice.Date = normalizeDate(invoice.Date)
+	invoice.Currency = strings.ToUpper(invoice.Currency)
+	invoice.Extractor = ExtractorAI
+	return &invoice, nil
+}
+
+var (
+	// amountPattern matches an amount at the end of a line, with an optional currency,
+	// such as "1,250.00", "Rp 1.500.000" or "$ 12.50"
+	amountPattern = regexp.MustCompile(`(?:[A-Z]{3}|Rp\.?|[$€£¥])?\s*(-?\d[\d.,]*)\s*$`)
+
+	// numberPattern matches the invoice or receipt number after its label
+	numberPattern = regexp.MustCompile(`(?i)\b(?:invoice|inv|receipt|bill|faktur|nota|kwitansi)\s*(?:no\.?|number|nomor|#)?\s*[:#.]?\s*([A-Z0-9][A-Z0-9/._-]*\d[A-Z0-9/._-]*)`)
+
+	// labeledDatePattern matches a date after a date label
+	labeledDatePattern = regexp.MustCompile(`(?i)\b(?:invoice date|date|tanggal|tgl\.?)\s*[:.]?\s*(` + datePattern + `)`)
+
+	// anyDatePattern matches the first date of a text
+	anyDatePattern = regexp.MustCompile(`(?i)\b(` + datePattern + `)`)
+
+	// vendorPattern matches a vendor after its label
+	vendorPattern = regexp.MustCompile(`(?i)^(?:from|vendor|seller|supplier|merchant|penjual|toko)\s*:\s*(.+)$`)
+
+	// titlePattern matches the document title lines that are not the vendor
+	titlePattern = regexp.MustCompile(`(?i)^(?:tax\s+)?(?:invoice|receipt|bill|faktur(?:\s+pajak)?|nota|kwitansi|struk)\b`)
+
+	// headerPattern matches the header row of the line item table
+	headerPattern = regexp.MustCompile(`(?i)\b(?:description|item|qty|quantity|keterangan|barang|jumlah\s+barang)\b`)
+
+	// itemPattern matches a line item with its quantity, unit price and amount
+	itemPattern = regexp.MustCompile(`^(.*?\p{L}.*?)\s+(\d+(?:[.,]\d+)?)\s*[xX]?\s+(?:[A-Z]{3}|Rp\.?|[$€£¥])?\s*(\d[\d.,]*)\s+(?:[A-Z]{3}|Rp\.?|[$€£¥])?\s*(\d[\d.,]*)$`)
+
+	// receiptItemPattern matches a receipt line such as "2 x Coffee 25.000"
+	receiptItemPattern = regexp.MustCompile(`^(\d+)\s*[xX]\s+(.*?\p{L}.*?)\s+(?:[A-Z]{3}|Rp\.?|[$€£¥])?\s*(\d[\d.,]*)$`)
+)
+
+// datePattern matches the dates of Extract: "2024-03-15", "15/03/2024", "15-03-24",
+// "15 March 2024", "March 15, 2024" or "15 Maret 2024"
+const datePattern = `\d{4}-\d{1,2}-\d{1,2}|\d{1,2}[/.-]\d{1,2}[/.-]\d{2,4}|\d{1,2}\s+\p{L}{3,}\.?\s+\d{4}|\p{L}{3,}\.?\s+\d{1,2},?\s+\d{4}`
+
+// Labels of the total lines, checked in this order so "Subtotal" is not taken for "Total"
+var (
+	subtotalPattern = regexp.MustCompile(`(?i)^(?:sub\s*-?\s*total|jumlah|dpp)\b`)
+	taxPattern      = regexp.MustCompile(`(?i)^(?:tax|vat|gst|ppn|pajak|sales tax)\b`)
+	totalPattern    = regexp.MustCompile(`(?i)^(?:grand\s+total|total|amount\s+due|balance\s+due|total\s+bayar|total\s+tagihan|total\s+belanja)\b`)
+)
+
+// currencies maps currency symbols and codes to ISO 4217 codes
+var currencies = []struct {
+	pattern *regexp.Regexp
+	code    string
+}{
+	{regexp.MustCompile(`\bRp\.?\s?\d|\bIDR\b`), "IDR"},
+	{regexp.MustCompile(`\bSGD\b|S\$`), "SGD"},
+	{regexp.MustCompile(`\bMYR\b|\bRM\s?\d`), "MYR"},
+	{regexp.MustCompile(`\bUSD\b|\$`), "USD"},
+	{regexp.MustCompile(`\bEUR\b|€`), "EUR"},
+	{regexp.MustCompile(`\bGBP\b|£`), "GBP"},
+	{regexp.MustCompile(`\bJPY\b|¥`), "JPY"},
+}
+
+// Extract reads the key fields of an invoice or receipt from its text with regular
+// expressions. Totals are read from labeled lines ending in an amount, and line items from
+// lines ending in a quantity, a unit price and an amount, or starting with "2 x".
+func Extract(text string) *Invoice {
+	invoice := &Invoice{Extractor: ExtractorRegex}
+	lines := strings.Split(text, "\n")
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if match := vendorPattern.FindStringSubmatch(line); match != nil {
+			invoice.Vendor = strings.TrimSpace(match[1])
+			break
+		}
+	}
+	if invoice.Vendor == "" {
+		invoice.Vendor = firstName(lines)
+	}
+	if match := numberPattern.FindStringSubmatch(text); match != nil {
+		invoice.Number = match[1]
+	}
+	if match := labeledDatePattern.FindStringSubmatch(text); match != nil {
+		invoice.Date = normalizeDate(match[1])
+	} else if match := anyDatePattern.FindStringSubmatch(text); match != nil {
+		invoice.Date = normalizeDate(match[1])
+	}
+	for _, currency := range currencies {
+		if currency.pattern.MatchString(text) {
+			invoice.Currency = currency.code
+			break
+		}
+	}
+
+	items := true
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case subtotalPattern.MatchString(line):
+			invoice.Subtotal = lineAmount(line, invoice.Subtotal)
+			items = false
+		case taxPattern.MatchString(line):
+			invoice.Tax = lineAmount(line, invoice.Tax)
+			items = false
+		case totalPattern.MatchString(line):
+			// The last total is the amount due, after discounts and tax
+			invoice.Total = lineAmount(line, invoice.Total)
+			items = false
+		case items && !headerPattern.MatchString(line):
+			if item, ok := lineItem(line); ok {
+				invoice.LineItems = append(invoice.LineItems, item)
+			}
+		}
+	}
+	return invoice
+}
+
+// firstName returns the first line that names the vendor: not blank, not the document
+// title and not a line of numbers, dates or labeled fields
+func firstName(lines []string) string {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--- Page ") || titlePattern.MatchString(line) ||
+			strings.Contains(line, ":") || anyDatePattern.MatchString(line) || !strings.ContainsFunc(line, isLetter) {
+			continue
+		}
+		return line
+	}
+	return ""
+}
+
+// isLetter reports whether r is an ASCII or Latin letter
+func isLetter(r rune) bool {
+	return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= 0xC0 && r <= 0x24F
+}
+
+// lineAmount returns the amount at the end of a line, or current when there is none
+func lineAmount(line string, current float64) float64 {
+	match := amountPattern.FindStringSubmatch(line)
+	if match == nil {
+		return current
+	}
+	if amount, ok := parseAmount(match[1]); ok {
+		return amount
+	}
+	return current
+}
+
+// lineItem reads a line item from a line of the item table
+func lineItem(line string) (LineItem, bool) {
+	if match := itemPattern.FindStringSubmatch(line); match != nil {
+		quantity, okQuantity := parseAmount(match[2])
+		price, okPrice := parseAmount(match[3])
+		amount, okAmount := parseAmount(match[4])
+		if okQuantity && okPrice && okAmount {
+			return LineItem{Description: strings.TrimSpace(match[1]), Quantity: quantity, UnitPrice: price, Amount: amount}, true
+		}
+	}
+	if match := receiptItemPattern.FindStringSubmatch(line); match != nil {
+		quantity, okQuantity := parseAmount(match[1])
+		amount, okAmount := parseAmount(match[3])
+		if okQuantity && okAmount && quantity > 0 {
+			return LineItem{Description: strings.TrimSpace(match[2]), Quantity: quantity, UnitPrice: amount / quantity, Amount: amount}, true
+		}
+	}
+	return LineItem{}, false
+}
+
+// parseAmount parses an amount written with thousand separators in either convention,
+// "1,234.56" or "1.234,56": when both separators appear the last one is the decimal
+// point, and a single separator followed by exactly three digits separates thousands
+func parseAmount(text string) (float64, bool) {
+	text = strings.TrimRight(text, ".,")
+	lastDot, lastComma := strings.LastIndex(text, "."), strings.LastIndex(text, ",")
+	decimal := -1
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		decimal = max(lastDot, lastComma)
+	case lastDot >= 0 || lastComma >= 0:
+		separator := max(lastDot, lastComma)
+		if strings.Count(text, text[separator:separator+1]) == 1 && len(text)-separator-1 != 3 {
+			decimal = separator
+		}
+	}
+
+	var digits strings.Builder
+	for i, r := range text {
+		switch {
+		case i == decimal:
+			digits.WriteByte('.')
+		case r == '-' || r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		}
+	}
+	amount, err := strconv.ParseFloat(digits.String(), 64)
+	return amount, err == nil
+}
+
+// months maps English and Indonesian month names and abbreviations to their numbers
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January, "januari": time.January,
+	"feb": time.February, "february": time.February, "februari": time.February,
+	"mar": time.March, "march": time.March, "maret": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May, "mei": time.May,
+	"jun": time.June, "june": time.June, "juni": time.June,
+	"jul": time.July, "july": time.July, "juli": time.July,
+	"aug": time.August, "august": time.August, "agu": time.August, "agustus": time.August, "agt": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October, "okt": time.October, "oktober": time.October,
+	"nov": time.November, "november": time.November, "nop": time.November, "nopember": time.November,
+	"dec": time.December, "december": time.December, "des": time.December, "desember": time.December,
+}
+
+var (
+	isoDatePattern     = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	numericDatePattern = regexp.MustCompile(`^(\d{1,2})[/.-](\d{1,2})[/.-](\d{2,4})$`)
+	dayMonthPattern    = regexp.MustCompile(`^(\d{1,2})\s+(\p{L}+)\.?\s+(\d{4})$`)
+	monthDayPattern    = regexp.MustCompile(`^(\p{L}+)\.?\s+(\d{1,2}),?\s+(\d{4})$`)
+)
+
+// normalizeDate returns a date as YYYY-MM-DD, or as written when it cannot be read.
+// Numeric dates are read day first, as in Indonesia and Europe, unless the first number
+// cannot be a day.
+func normalizeDate(text string) string {
+	text = strings.TrimSpace(text)
+	var year, month, day int
+	switch {
+	case isoDatePattern.MatchString(text):
+		match := isoDatePattern.FindStringSubmatch(text)
+		year, month, day = atoi(match[1]), atoi(match[2]), atoi(match[3])
+	case numericDatePattern.MatchString(text):
+		match := numericDatePattern.FindStringSubmatch(text)
+		day, month, year = atoi(match[1]), atoi(match[2]), atoi(match[3])
+		if month > 12 && day <= 12 {
+			day, month = month, day
+		}
+		if year < 100 {
+			year += 2000
+		}
+	case dayMonthPattern.MatchString(text):
+		match := dayMonthPattern.FindStringSubmatch(text)
+		day, month, year = atoi(match[1]), int(months[strings.ToLower(match[2])]), atoi(match[3])
+	case monthDayPattern.MatchString(text):
+		match := monthDayPattern.FindStringSubmatch(text)
+		month, day, year = int(months[strings.ToLower(match[1])]), atoi(match[2]), atoi(match[3])
+	default:
+		return text
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if month < 1 || month > 12 || date.Day() != day {
+		return text
+	}
+	return date.Format("2006-01-02")
+}
+
+// atoi converts the digits of a date
+func atoi(text string) int {
+	n, _ := strconv.Atoi(text)
+	return n
+}
@@ -0,0 +1,89 @@
+// Package linefilter drops noise lines from extracted text, such as "Halaman 3 dari 10"
+// page footers, confidentiality notices and watermark text, before it is chunked
+package linefilter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultPatterns match common Indonesian and English noise lines. Every pattern is
+// matched against whole trimmed lines.
+var DefaultPatterns = []string{
+	// Page numbers and counters: "12", "- 12 -", "Page 3 of 10", "Halaman 3 dari 10", "Hal. 3/10"
+	`^[-–—]?\s*\d{1,4}\s*[-–—]?$`,
+	`(?i)^(page|p\.|halaman|hal\.?|hlm\.?)\s*\d{1,4}(\s*(of|dari|/)\s*\d{1,4})?$`,
+	`^\d{1,4}\s*/\s*\d{1,4}$`,
+	// Confidentiality footers
+	`(?i)^(strictly\s+|highly\s+)?(private\s+and\s+)?confidential(\s*[-–—:]\s*.{0,40})?$`,
+	`(?i)^(for\s+)?internal\s+use\s+only\.?$`,
+	`(?i)^do\s+not\s+(copy|distribute|forward)\.?$`,
+	`(?i)^this\s+(document|email|message|page)\s+(is|contains)\s+(strictly\s+)?(confidential|proprietary|privileged)\b.*$`,
+	`(?i)^(sangat\s+)?rahasia(\s*[-–—:]\s*.{0,40})?$`,
+	`(?i)^(hanya\s+)?untuk\s+(kalangan|penggunaan)\s+(sendiri|internal|terbatas)\.?$`,
+	`(?i)^dokumen\s+ini\s+(bersifat\s+)?(rahasia|terbatas)\b.*$`,
+	`(?i)^dilarang\s+(memperbanyak|menyebarluaskan|menggandakan)\b.*$`,
+	// Watermark text, usually extracted as a line of its own
+	`(?i)^(draft|copy|sample|specimen|void|uncontrolled\s+copy|konsep|salinan|contoh|draf)$`,
+	`(?i)^salinan\s+sesuai\s+dengan\s+aslinya\.?$`,
+}
+
+// Filter drops the lines of a text matching any of its patterns
+type Filter struct {
+	patterns []*regexp.Regexp
+}
+
+// New creates a filter from the regular expressions of the lines to drop, adding
+// DefaultPatterns when defaults is set. A pattern matches anywhere in a trimmed line, so
+// anchor it with ^ and $ to match whole lines only.
+func New(patterns []string, defaults bool) (*Filter, error) {
+	if defaults {
+		patterns = append(append([]string(nil), DefaultPatterns...), patterns...)
+	}
+	f := &Filter{}
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line filter %q: %w", pattern, err)
+		}
+		f.patterns = append(f.patterns, compiled)
+	}
+	return f, nil
+}
+
+// Apply returns text without the lines matching a pattern, and the number dropped.
+// Blank lines are kept, so paragraphs stay apart.
+func (f *Filter) Apply(text string) (string, int) {
+	if len(f.patterns) == 0 {
+		return text, 0
+	}
+	lines := strings.SplitAfter(text, "\n")
+	kept := lines[:0]
+	dropped := 0
+	for _, line := range lines {
+		if f.Match(line) {
+			dropped++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if dropped == 0 {
+		return text, 0
+	}
+	return strings.Join(kept, ""), dropped
+}
+
+// Match reports whether a line, trimmed of surrounding whitespace, matches a pattern
+func (f *Filter) Match(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	for _, pattern := range f.patterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
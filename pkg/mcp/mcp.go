@@ -0,0 +1,296 @@
+// Package mcp serves the chunker to LLM agents and IDE assistants over the Model Context
+// Protocol: a client such as Claude Desktop, Cursor or VS Code starts the program, sends
+// JSON-RPC 2.0 messages to its stdin, one per line, and reads the replies from its
+// stdout. The server answers the lifecycle and tool methods of the protocol and exposes
+// the tools of tools.go; resources, prompts and sampling are not supported.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+)
+
+// ProtocolVersion is the latest MCP revision the server speaks; clients asking for an
+// older one in SupportedVersions get that one
+const ProtocolVersion = "2025-06-18"
+
+// SupportedVersions are the MCP revisions the server speaks
+var SupportedVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// maxMessageSize is the longest message read from the client
+const maxMessageSize = 16 << 20
+
+// Options configures a Server
+type Options struct {
+	Chunker    *chunker.Chunker
+	Root       string                                // Directory tools may read documents from, with its subdirectories; default the working directory
+	ChunkDir   string                                // Where saved chunks are written and searched, as the chunker's ChunkDir, default "chunk"
+	RunDir     string                                // Directory the chunk paths of saved manifests are relative to, default "."
+	OutputType chunker.OutputType                    // How chunk_document saves chunks when asked to, default chunker.OutputBoth
+	EmbedQuery func(query string) ([]float32, error) // Ranks search_chunks by embedding similarity when chunks have vectors; nil ranks by BM25
+	Name       string                                // Server name reported to clients, default "pdf-chunk-extractor"
+	Version    string                                // Server version reported to clients
+	Logger     chunker.Logger                        // Default: log.Default(), which writes to stderr and not to the protocol
+}
+
+// Server answers the MCP requests of one client. Tool calls run concurrently, so a slow
+// document does not hold up pings or other calls, and stop when the client cancels them.
+type Server struct {
+	options Options
+
+	writeMu sync.Mutex
+	out     *json.Encoder
+
+	mu      sync.Mutex
+	calls   map[string]context.CancelFunc // Cancels the running tool call of every request ID
+	running sync.WaitGroup
+}
+
+// New creates a server
+func New(options Options) (*Server, error) {
+	if options.Chunker == nil {
+		return nil, errors.New("mcp server needs a chunker")
+	}
+	if options.Root == "" {
+		options.Root = "."
+	}
+	root, err := filepath.Abs(options.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root directory: %w", err)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, fmt.Errorf("failed to resolve root directory: %w", err)
+	}
+	options.Root = root
+	if options.ChunkDir == "" {
+		options.ChunkDir = "chunk"
+	}
+	if options.RunDir == "" {
+		options.RunDir = "."
+	}
+	if options.OutputType == chunker.OutputJSON {
+		options.OutputType = chunker.OutputBoth
+	}
+	if options.Name == "" {
+		options.Name = "pdf-chunk-extractor"
+	}
+	if options.Logger == nil {
+		options.Logger = log.Default()
+	}
+	return &Server{options: options, calls: make(map[string]context.CancelFunc)}, nil
+}
+
+// message is a JSON-RPC request, notification or response
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // Absent in notifications
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error of a JSON-RPC response
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads messages from in and writes the replies to out until in ends or ctx is
+// done, then waits for the running tool calls to finish. Messages that are not valid
+// JSON-RPC are answered with an error and do not end the session.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.out = json.NewEncoder(out)
+	s.out.SetEscapeHTML(false)
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		s.running.Wait()
+	}()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64<<10), maxMessageSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if err != nil {
+				return fmt.Errorf("failed to read MCP messages: %w", err)
+			}
+			return nil
+		case line := <-lines:
+			if len(line) > 0 {
+				s.handle(ctx, line)
+			}
+		}
+	}
+}
+
+// handle answers a message; tool calls are answered from their own goroutine
+func (s *Server) handle(ctx context.Context, line []byte) {
+	var msg message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		s.reply(nil, nil, &rpcError{Code: codeParseError, Message: "invalid JSON: " + err.Error()})
+		return
+	}
+	if msg.JSONRPC != "2.0" || msg.Method == "" {
+		if msg.Method == "" && msg.ID != nil && (msg.Result != nil || msg.Error != nil) {
+			return // A response to a request the server never sends
+		}
+		s.reply(msg.ID, nil, &rpcError{Code: codeInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+		return
+	}
+	if msg.ID == nil {
+		s.notify(msg)
+		return
+	}
+
+	switch msg.Method {
+	case "initialize":
+		s.reply(msg.ID, s.initialize(msg.Params), nil)
+	case "ping":
+		s.reply(msg.ID, struct{}{}, nil)
+	case "tools/list":
+		s.reply(msg.ID, map[string]any{"tools": toolList()}, nil)
+	case "tools/call":
+		s.startCall(ctx, msg)
+	default:
+		s.reply(msg.ID, nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method})
+	}
+}
+
+// initialize answers the handshake with the protocol revision and the tools capability
+func (s *Server) initialize(params json.RawMessage) any {
+	var request struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	json.Unmarshal(params, &request)
+	version := ProtocolVersion
+	for _, supported := range SupportedVersions {
+		if request.ProtocolVersion == supported {
+			version = supported
+		}
+	}
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{}},
+		"serverInfo":      map[string]any{"name": s.options.Name, "version": s.options.Version},
+		"instructions":    "Extract the text of PDFs and other documents, chunk them for retrieval and search the saved chunks. Paths are relative to " + s.options.Root + ".",
+	}
+}
+
+// notify handles a notification: cancellations stop their tool call, others are ignored
+func (s *Server) notify(msg message) {
+	if msg.Method != "notifications/cancelled" {
+		return
+	}
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(msg.Params, &params) != nil {
+		return
+	}
+	s.mu.Lock()
+	cancel := s.calls[string(params.RequestID)]
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// startCall runs a tool call in its own goroutine, cancelled with the session or by the
+// client. Cancelled calls are not answered, as the protocol asks.
+func (s *Server) startCall(ctx context.Context, msg message) {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+		s.reply(msg.ID, nil, &rpcError{Code: codeInvalidParams, Message: "tools/call needs a tool name"})
+		return
+	}
+	tool, found := tools[params.Name]
+	if !found {
+		s.reply(msg.ID, nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + params.Name})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	key := string(msg.ID)
+	s.mu.Lock()
+	s.calls[key] = cancel
+	s.mu.Unlock()
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.calls, key)
+			s.mu.Unlock()
+			cancel()
+		}()
+		text, err := tool.call(ctx, s, params.Arguments)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.options.Logger.Printf("Warning: MCP tool %s failed: %v", params.Name, err)
+			s.reply(msg.ID, toolResult(err.Error(), true), nil)
+			return
+		}
+		s.reply(msg.ID, toolResult(text, false), nil)
+	}()
+}
+
+// toolResult is the result of a tool call with its text. Failed tools report their
+// error in the result rather than as a JSON-RPC error, so the model can read it.
+func toolResult(text string, isError bool) any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// reply writes a response to the request with id
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.out.Encode(message{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr}); err != nil {
+		s.options.Logger.Printf("Warning: failed to write MCP response: %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package mcp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/mcp"
+)
+
+// response is a JSON-RPC response read by client
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// toolResponse is the result of a tool call
+type toolResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+// client talks to a server over in-memory pipes, one message per line
+type client struct {
+	t       *testing.T
+	in      *io.PipeWriter
+	out     *bufio.Scanner
+	lastID  int
+	stopped chan error
+}
+
+// newClient serves a server with a chunker whose root directory holds files
+func newClient(t *testing.T, root string) *client {
+	t.Helper()
+	cfg := chunkertest.Config(t)
+	instance := chunker.NewChunker(chunker.WithConfig(cfg))
+	t.Cleanup(func() { instance.Close() })
+	server, err := mcp.New(mcp.Options{Chunker: instance, Root: root, ChunkDir: cfg.ChunkDir, Version: "test", Logger: log.New(io.Discard, "", 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	c := &client{t: t, in: inWriter, out: bufio.NewScanner(outReader), stopped: make(chan error, 1)}
+	go func() {
+		c.stopped <- server.Serve(context.Background(), inReader, outWriter)
+		outWriter.Close()
+	}()
+	t.Cleanup(func() {
+		inWriter.Close()
+		if err := <-c.stopped; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return c
+}
+
+// send writes a raw message line
+func (c *client) send(line string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.in, line+"\n"); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive reads the next response line
+func (c *client) receive() response {
+	c.t.Helper()
+	if !c.out.Scan() {
+		c.t.Fatalf("no response: %v", c.out.Err())
+	}
+	var r response
+	if err := json.Unmarshal(c.out.Bytes(), &r); err != nil {
+		c.t.Fatalf("response %s is not JSON: %v", c.out.Bytes(), err)
+	}
+	if r.JSONRPC != "2.0" {
+		c.t.Errorf("response %s is not JSON-RPC 2.0", c.out.Bytes())
+	}
+	return r
+}
+
+// request sends a request with the next ID and returns its response
+func (c *client) request(method string, params any) response {
+	c.t.Helper()
+	c.lastID++
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": c.lastID, "method": method, "params": params})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.send(string(data))
+	r := c.receive()
+	if string(r.ID) != fmt.Sprint(c.lastID) {
+		c.t.Fatalf("%s: response ID = %s, want %d", method, r.ID, c.lastID)
+	}
+	return r
+}
+
+// callTool calls a tool and returns the text of its result
+func (c *client) callTool(name string, arguments any) (string, bool) {
+	c.t.Helper()
+	r := c.request("tools/call", map[string]any{"name": name, "arguments": arguments})
+	if r.Error != nil {
+		c.t.Fatalf("%s: JSON-RPC error %d %s, want a tool result", name, r.Error.Code, r.Error.Message)
+	}
+	var result toolResponse
+	if err := json.Unmarshal(r.Result, &result); err != nil || len(result.Content) != 1 || result.Content[0].Type != "text" {
+		c.t.Fatalf("%s: result %s is not one text content", name, r.Result)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+// testRoot returns a root directory with a text note and a broken PDF, next to a file
+// outside it that a symlink in it points to
+func testRoot(t *testing.T) string {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	files := map[string]string{
+		"root/notes.txt":  "Minutes of the meeting.\n\nThe budget for the new warehouse was approved.",
+		"root/broken.pdf": "This is not a PDF.",
+		"secret.txt":      "Outside the root.",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// TestFraming checks the JSON-RPC framing of the server: the handshake, errors for
+// lines that are not JSON-RPC requests, and notifications, which get no response
+func TestFraming(t *testing.T) {
+	c := newClient(t, testRoot(t))
+
+	r := c.request("initialize", map[string]any{"protocolVersion": "2024-11-05", "capabilities": map[string]any{}})
+	var initialized struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name, Version string
+		} `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(r.Result, &initialized); err != nil || initialized.ProtocolVersion != "2024-11-05" || initialized.ServerInfo.Version != "test" {
+		t.Errorf("initialize = %s, want protocol 2024-11-05 of server version test", r.Result)
+	}
+	c.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	tests := []struct {
+		name, line string
+		wantID     string
+		wantCode   int
+	}{
+		{"invalid JSON", `{"jsonrpc":"2.0","id":`, "null", -32700},
+		{"JSON-RPC 1.0", `{"jsonrpc":"1.0","id":"a","method":"ping"}`, `"a"`, -32600},
+		{"no method", `{"jsonrpc":"2.0","id":7}`, "7", -32600},
+		{"unknown method", `{"jsonrpc":"2.0","id":8,"method":"resources/list"}`, "8", -32601},
+		{"tools/call without a name", `{"jsonrpc":"2.0","id":9,"method":"tools/call","params":{}}`, "9", -32602},
+		{"tools/call of an unknown tool", `{"jsonrpc":"2.0","id":10,"method":"tools/call","params":{"name":"delete_file"}}`, "10", -32602},
+	}
+	for _, test := range tests {
+		c.send(test.line)
+		r := c.receive()
+		if string(r.ID) != test.wantID || r.Error == nil || r.Error.Code != test.wantCode {
+			t.Errorf("%s: response ID %s, error %+v, want ID %s and code %d", test.name, r.ID, r.Error, test.wantID, test.wantCode)
+		}
+	}
+
+	// The notification above got no response, so this one is the ping's
+	r = c.request("ping", nil)
+	if r.Error != nil || string(r.Result) != "{}" {
+		t.Errorf("ping = %s %+v, want {}", r.Result, r.Error)
+	}
+
+	r = c.request("tools/list", nil)
+	var listed struct {
+		Tools []struct {
+			Name        string         `json:"name"`
+			InputSchema map[string]any `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(r.Result, &listed); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range listed.Tools {
+		names = append(names, tool.Name)
+		if tool.InputSchema["type"] != "object" {
+			t.Errorf("tool %s input schema = %v, want an object schema", tool.Name, tool.InputSchema)
+		}
+	}
+	if got := strings.Join(names, ","); got != "chunk_document,extract_pdf_text,search_chunks" {
+		t.Errorf("tools/list = %s, want chunk_document,extract_pdf_text,search_chunks", got)
+	}
+}
+
+// TestTools checks the three tools on a text document and the errors they report for
+// bad arguments, paths and documents
+func TestTools(t *testing.T) {
+	c := newClient(t, testRoot(t))
+
+	tests := []struct {
+		name, tool string
+		arguments  any
+		wantError  bool
+		want       string // Part of the result text
+	}{
+		{"extract", "extract_pdf_text", map[string]any{"path": "notes.txt"}, false, "The budget for the new warehouse was approved."},
+		{"extract pages past the end", "extract_pdf_text", map[string]any{"path": "notes.txt", "first_page": 3}, true, "has no pages from 3"},
+		{"extract cut at max_chars", "extract_pdf_text", map[string]any{"path": "notes.txt", "max_chars": 7}, false, "Minutes\n\n[Text cut at 7 characters"},
+		{"missing path", "extract_pdf_text", map[string]any{}, true, "path is empty"},
+		{"unknown argument", "extract_pdf_text", map[string]any{"file": "notes.txt"}, true, "invalid arguments"},
+		{"argument of the wrong type", "chunk_document", map[string]any{"path": 42}, true, "invalid arguments"},
+		{"arguments not an object", "chunk_document", []string{"notes.txt"}, true, "invalid arguments"},
+		{"missing file", "extract_pdf_text", map[string]any{"path": "missing.pdf"}, true, "failed to open"},
+		{"path outside the root", "chunk_document", map[string]any{"path": "../secret.txt"}, true, "outside the root directory"},
+		{"symlink outside the root", "extract_pdf_text", map[string]any{"path": "link.txt"}, true, "outside the root directory"},
+		{"broken PDF", "extract_pdf_text", map[string]any{"path": "broken.pdf"}, true, "failed to extract text"},
+		{"chunk a broken PDF", "chunk_document", map[string]any{"path": "broken.pdf"}, true, "failed to chunk document"},
+		{"empty query", "search_chunks", map[string]any{"query": "  "}, true, "query is empty"},
+		{"search before saving", "search_chunks", map[string]any{"query": "budget"}, true, "no chunks are saved yet"},
+		{"chunk", "chunk_document", map[string]any{"path": "notes.txt"}, false, `"saved": false`},
+		{"chunk and save", "chunk_document", map[string]any{"path": "notes.txt", "save": true}, false, `"saved": true`},
+		{"search", "search_chunks", map[string]any{"query": "warehouse budget", "limit": 3}, false, `"filename": "notes.txt"`},
+	}
+	for _, test := range tests {
+		text, isError := c.callTool(test.tool, test.arguments)
+		if isError != test.wantError || !strings.Contains(text, test.want) {
+			t.Errorf("%s: %s = %q (error %v), want %q (error %v)", test.name, test.tool, text, isError, test.want, test.wantError)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/processor"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/search"
+)
+
+// Limits of what tools return, so answers fit in the model's context
+const (
+	defaultMaxChars    = 100000 // Characters of extracted text returned by default
+	defaultSearchLimit = 5
+	maxSearchLimit     = 50
+)
+
+// tool is a tool of the server: its description and input schema, listed in tools/list,
+// and the function answering its calls with the text of the result
+type tool struct {
+	description string
+	schema      map[string]any
+	call        func(ctx context.Context, s *Server, arguments json.RawMessage) (string, error)
+}
+
+// tools are the tools of the server by name
+var tools = map[string]tool{
+	"extract_pdf_text": {
+		description: "Extract the text of a PDF, page by page, with OCR for scanned pages. Also reads DOCX, PPTX, XLSX, TXT and email files. Pages are separated by \"--- Page N ---\" lines.",
+		schema: objectSchema(map[string]any{
+			"path":       stringProperty("Path of the document, relative to the server's root directory"),
+			"first_page": integerProperty("First page to return, from 1; default the first page"),
+			"last_page":  integerProperty("Last page to return; default the last page"),
+			"max_chars":  integerProperty(fmt.Sprintf("Characters of text returned at most; default %d", defaultMaxChars)),
+		}, "path"),
+		call: extractText,
+	},
+	"chunk_document": {
+		description: "Split a document into chunks for retrieval, with their page ranges, titles and metadata, as JSON. With save, the chunks are also written to the chunk directory, where search_chunks finds them.",
+		schema: objectSchema(map[string]any{
+			"path": stringProperty("Path of the document, relative to the server's root directory"),
+			"save": map[string]any{"type": "boolean", "description": "Write the chunks to the chunk directory; default false"},
+		}, "path"),
+		call: chunkDocument,
+	},
+	"search_chunks": {
+		description: "Search the chunks saved in the chunk directory for a query, ranked by BM25 or by embedding similarity, and return the best ones with their document and pages as JSON.",
+		schema: objectSchema(map[string]any{
+			"query": stringProperty("Words to search for"),
+			"limit": integerProperty(fmt.Sprintf("Chunks returned at most; default %d, at most %d", defaultSearchLimit, maxSearchLimit)),
+		}, "query"),
+		call: searchChunks,
+	},
+}
+
+// toolList returns the tools for tools/list, sorted by name
+func toolList() []map[string]any {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]map[string]any, len(names))
+	for i, name := range names {
+		list[i] = map[string]any{"name": name, "description": tools[name].description, "inputSchema": tools[name].schema}
+	}
+	return list
+}
+
+// extractText answers extract_pdf_text
+func extractText(ctx context.Context, s *Server, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path      string `json:"path"`
+		FirstPage int    `json:"first_page"`
+		LastPage  int    `json:"last_page"`
+		MaxChars  int    `json:"max_chars"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	path, err := s.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	document, err := s.options.Chunker.WithContext(ctx).ExtractText(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract text: %w", err)
+	}
+
+	var pages []processor.Page
+	for _, page := range document.Pages {
+		if (args.FirstPage <= 0 || page.Number >= args.FirstPage) && (args.LastPage <= 0 || page.Number <= args.LastPage) {
+			pages = append(pages, page)
+		}
+	}
+	if len(pages) == 0 {
+		return "", fmt.Errorf("%s has no pages from %d to %d; it has %d pages", args.Path, args.FirstPage, args.LastPage, len(document.Pages))
+	}
+	document.Pages = pages
+
+	maxChars := args.MaxChars
+	if maxChars <= 0 {
+		maxChars = defaultMaxChars
+	}
+	text := strings.TrimSpace(document.Text())
+	if runes := []rune(text); len(runes) > maxChars {
+		text = string(runes[:maxChars]) + fmt.Sprintf("\n\n[Text cut at %d characters; ask for later pages with first_page]", maxChars)
+	}
+	return text, nil
+}
+
+// chunkDocument answers chunk_document
+func chunkDocument(ctx context.Context, s *Server, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path string `json:"path"`
+		Save bool   `json:"save"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	path, err := s.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	outputType := chunker.OutputJSON
+	if args.Save {
+		outputType = s.options.OutputType
+	}
+	result, err := s.options.Chunker.WithContext(ctx).ChunkFile(path, outputType)
+	if err != nil {
+		return "", fmt.Errorf("failed to chunk document: %w", err)
+	}
+
+	chunks := make([]schema.Chunk, len(result.Chunks))
+	for i, chunk := range result.Chunks {
+		chunk.Embedding = nil // Vectors are of no use to the model and would fill its context
+		chunks[i] = chunk
+	}
+	return encode(struct {
+		Pages  int            `json:"pages"`
+		Saved  bool           `json:"saved"`
+		Chunks []schema.Chunk `json:"chunks"`
+	}{result.Pages, args.Save, chunks})
+}
+
+// searchChunks answers search_chunks
+func searchChunks(ctx context.Context, s *Server, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(args.Query) == "" {
+		return "", errors.New("query is empty")
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	chunks, err := search.Load(s.options.ChunkDir, s.options.RunDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", errors.New("no chunks are saved yet; chunk documents with chunk_document and save first")
+		}
+		return "", fmt.Errorf("failed to load chunks: %w", err)
+	}
+	index := search.NewIndex(chunks)
+	var results []search.Result
+	if s.options.EmbedQuery != nil && index.HasEmbeddings() {
+		vector, err := s.options.EmbedQuery(args.Query)
+		if err != nil {
+			return "", fmt.Errorf("failed to embed query: %w", err)
+		}
+		results = index.SearchVector(vector, limit)
+	} else {
+		results = index.Search(args.Query, limit)
+	}
+
+	type match struct {
+		Filename   string  `json:"filename"`
+		ChunkIndex int     `json:"chunk_index"`
+		PageRange  string  `json:"page_range,omitempty"`
+		Score      float64 `json:"score"`
+		Text       string  `json:"text"`
+	}
+	matches := make([]match, len(results))
+	for i, result := range results {
+		matches[i] = match{result.Chunk.Filename, result.Chunk.ChunkIndex, result.Chunk.PageRange, result.Score, result.Chunk.Text}
+	}
+	return encode(struct {
+		Searched int     `json:"searched"`
+		Matches  []match `json:"matches"`
+	}{index.Len(), matches})
+}
+
+// resolve returns the path of a document argument, which must name a file under the
+// root directory, also after following symlinks, so agents cannot read other files
+func (s *Server) resolve(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", errors.New("path is empty")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.options.Root, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if rel, err := filepath.Rel(s.options.Root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the root directory %s", path, s.options.Root)
+	}
+	return resolved, nil
+}
+
+// decodeArguments decodes the arguments of a tool call, rejecting unknown ones so typos
+// are reported rather than ignored
+func decodeArguments(arguments json.RawMessage, v any) error {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(arguments)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// encode returns the indented JSON of a tool result
+func encode(v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %w", err)
+	}
+	return string(data), nil
+}
+
+// objectSchema returns the JSON schema of tool arguments
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	return map[string]any{"type": "object", "properties": properties, "required": required, "additionalProperties": false}
+}
+
+func stringProperty(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+func integerProperty(description string) map[string]any {
+	return map[string]any{"type": "integer", "description": description}
+}
@@ -0,0 +1,87 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// tesseractCommand is the name of the tesseract executable looked up on PATH; on
+// Windows exec.LookPath adds the .exe extension
+const tesseractCommand = "tesseract"
+
+// FindTesseract returns the tesseract executable to run: tesseract on PATH, or else the
+// first of the usual install locations of the platform that exists, as the Windows
+// installer does not add itself to PATH and services on macOS run with a short PATH.
+// When tesseract is nowhere to be found it returns "tesseract", so errors name it.
+func FindTesseract() string {
+	if path, err := exec.LookPath(tesseractCommand); err == nil {
+		return path
+	}
+	for _, path := range tesseractLocations() {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return tesseractCommand
+}
+
+// ErrTesseractNotFound is returned when the tesseract executable cannot be run
+var ErrTesseractNotFound = errors.New("tesseract not found: install it or set TESSERACT_PATH (see `pdf-chunk-extractor doctor`)")
+
+// notFound replaces the error of a tesseract command that could not start because the
+// executable is missing with ErrTesseractNotFound
+func notFound(err error) error {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return ErrTesseractNotFound
+	}
+	return err
+}
+
+// Binary returns the tesseract executable the engine runs, or the image of the container
+// it runs in
+func (t *Tesseract) Binary() string {
+	if t.container != nil {
+		return "docker image " + t.container.Image()
+	}
+	return t.binary
+}
+
+// Version returns the first line of tesseract --version, such as "tesseract 5.3.4"
+func (t *Tesseract) Version() (string, error) {
+	cmd, err := t.command(context.Background(), "--version")
+	if err != nil {
+		return "", err
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return "", notFound(err)
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(version), nil
+}
+
+// InstalledLanguages returns the language packs tesseract --list-langs reports, such as
+// "eng", "ind" and "osd"
+func (t *Tesseract) InstalledLanguages() ([]string, error) {
+	cmd, err := t.command(context.Background(), "--list-langs")
+	if err != nil {
+		return nil, err
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, notFound(err)
+	}
+	var languages []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(output), "\r\n", "\n"), "\n") {
+		// The first line names the tessdata directory: List of available languages in "..." (3):
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "List of available languages") {
+			languages = append(languages, line)
+		}
+	}
+	return languages, nil
+}
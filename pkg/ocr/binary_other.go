@@ -0,0 +1,8 @@
+//go:build !windows
+
+package ocr
+
+// tesseractLocations are where Homebrew, MacPorts and Linux packages install tesseract
+func tesseractLocations() []string {
+	return []string{"/opt/homebrew/bin/tesseract", "/usr/local/bin/tesseract", "/opt/local/bin/tesseract", "/usr/bin/tesseract"}
+}
@@ -0,0 +1,23 @@
+package ocr
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// tesseractLocations are where the UB Mannheim installer and Chocolatey put
+// tesseract.exe: Program Files for all users, the user's local programs otherwise
+func tesseractLocations() []string {
+	roots := []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)")}
+	if local := os.Getenv("LOCALAPPDATA"); local != "" {
+		roots = append(roots, filepath.Join(local, "Programs"))
+	}
+
+	var locations []string
+	for _, root := range roots {
+		if root != "" {
+			locations = append(locations, filepath.Join(root, "Tesseract-OCR", "tesseract.exe"))
+		}
+	}
+	return locations
+}
@@ -0,0 +1,82 @@
+package ocr
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeEngine is an OCR engine for tests that answers with scripted results instead of
+// running tesseract. Once the script runs out it recognizes no text. It is safe for
+// concurrent use.
+type FakeEngine struct {
+	mu      sync.Mutex
+	results []Result
+	handler func(imagePath string) (*Result, error)
+	calls   int
+}
+
+// NewFakeEngine creates a fake engine that returns results in order. With OCRWorkers
+// above 1 pages are recognized in any order; use NewFakeEngineFunc for those.
+func NewFakeEngine(results ...Result) *FakeEngine {
+	return &FakeEngine{results: results}
+}
+
+// NewFakeEngineFunc creates a fake engine that recognizes every image with handler
+func NewFakeEngineFunc(handler func(imagePath string) (*Result, error)) *FakeEngine {
+	return &FakeEngine{handler: handler}
+}
+
+// Recognize returns the next scripted result
+func (f *FakeEngine) Recognize(imagePath string) (*Result, error) {
+	f.mu.Lock()
+	f.calls++
+	if f.handler != nil {
+		f.mu.Unlock()
+		return f.handler(imagePath)
+	}
+	if len(f.results) == 0 {
+		f.mu.Unlock()
+		return &Result{}, nil
+	}
+	result := f.results[0]
+	f.results = f.results[1:]
+	f.mu.Unlock()
+	return &result, nil
+}
+
+// RecognizeContext returns the next scripted result, or ctx.Err() when ctx is done
+// before the handler of NewFakeEngineFunc returns, so handlers can simulate a hung page
+func (f *FakeEngine) RecognizeContext(ctx context.Context, imagePath string) (*Result, error) {
+	type recognized struct {
+		result *Result
+		err    error
+	}
+	done := make(chan recognized, 1)
+	go func() {
+		result, err := f.Recognize(imagePath)
+		done <- recognized{result, err}
+	}()
+	select {
+	case r := <-done:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Calls returns the number of images recognized so far
+func (f *FakeEngine) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// GetName returns the engine name
+func (f *FakeEngine) GetName() string {
+	return "Fake"
+}
+
+// IsLocal reports that the fake engine never sends content anywhere
+func (f *FakeEngine) IsLocal() bool {
+	return true
+}
@@ -0,0 +1,47 @@
+package ocr
+
+import "context"
+
+// Word represents a single recognized word with its confidence (0–100)
+type Word struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Result represents the output of recognizing a page image
+type Result struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"` // Average word confidence (0–100)
+	Words      []Word  `json:"words"`
+}
+
+// Engine represents an OCR backend that recognizes text in page images
+type Engine interface {
+	Recognize(imagePath string) (*Result, error)
+	GetName() string
+}
+
+// ContextEngine is implemented by engines that stop recognizing an image when ctx is
+// done, as tesseract is killed, so a hung page does not stall the document
+type ContextEngine interface {
+	RecognizeContext(ctx context.Context, imagePath string) (*Result, error)
+}
+
+// PDFRenderer is implemented by engines that can write a searchable PDF,
+// i.e. the page images with an invisible OCR text layer
+type PDFRenderer interface {
+	RenderPDF(imagePaths []string, outputPath string) error
+}
+
+// AverageConfidence returns the mean confidence of the given words
+func AverageConfidence(words []Word) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, word := range words {
+		total += word.Confidence
+	}
+	return total / float64(len(words))
+}
@@ -0,0 +1,310 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/container"
+)
+
+// DefaultLanguages are the tesseract language packs used when none are configured
+var DefaultLanguages = []string{"eng", "ind"}
+
+// scriptLanguages maps scripts reported by tesseract OSD to language packs
+var scriptLanguages = map[string][]string{
+	"Arabic":     {"ara"},
+	"Cyrillic":   {"rus", "ukr"},
+	"Devanagari": {"hin"},
+	"Greek":      {"ell"},
+	"Han":        {"chi_sim", "chi_tra"},
+	"Hangul":     {"kor"},
+	"Hebrew":     {"heb"},
+	"Japanese":   {"jpn"},
+	"Katakana":   {"jpn"},
+	"Hiragana":   {"jpn"},
+	"Thai":       {"tha"},
+}
+
+var osdScriptPattern = regexp.MustCompile(`Script:\s*(\S+)`)
+
+// killWaitDelay bounds the wait for the output of a killed tesseract process
+const killWaitDelay = 5 * time.Second
+
+// Tesseract implements Engine using the tesseract command line tool
+type Tesseract struct {
+	binary     string
+	container  *container.Container // Set with WithContainer
+	languages  []string
+	autoDetect bool
+}
+
+// NewTesseract creates a new tesseract engine running the executable FindTesseract finds
+func NewTesseract(languages []string, autoDetect bool) *Tesseract {
+	if len(languages) == 0 {
+		languages = DefaultLanguages
+	}
+
+	return &Tesseract{
+		binary:     FindTesseract(),
+		languages:  languages,
+		autoDetect: autoDetect,
+	}
+}
+
+// WithBinary returns a copy of the engine that runs the tesseract executable at path,
+// such as C:\Tools\Tesseract-OCR\tesseract.exe; an empty path keeps the one found
+func (t *Tesseract) WithBinary(path string) *Tesseract {
+	engine := *t
+	if path != "" {
+		engine.binary = path
+	}
+	return &engine
+}
+
+// WithContainer returns a copy of the engine that runs tesseract in c instead of on this
+// machine. Images must be under the work directory of c, as the page images of the PDF
+// processor are when c mounts its temp directory.
+func (t *Tesseract) WithContainer(c *container.Container) *Tesseract {
+	engine := *t
+	engine.container = c
+	return &engine
+}
+
+// Recognize runs tesseract on an image and returns the recognized text with word confidences
+func (t *Tesseract) Recognize(imagePath string) (*Result, error) {
+	return t.RecognizeContext(context.Background(), imagePath)
+}
+
+// RecognizeContext runs tesseract as Recognize does, killing it when ctx is done
+func (t *Tesseract) RecognizeContext(ctx context.Context, imagePath string) (*Result, error) {
+	languages := t.languages
+	if t.autoDetect {
+		languages = t.detectLanguages(ctx, imagePath)
+	}
+
+	// Write plain text and TSV word data next to the image in a single run
+	outputBase := strings.TrimSuffix(imagePath, filepath.Ext(imagePath))
+	defer os.Remove(outputBase + ".txt")
+	defer os.Remove(outputBase + ".tsv")
+	paths, err := t.paths(imagePath, outputBase)
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := t.command(ctx, paths[0], paths[1], "-l", strings.Join(languages, "+"), "txt", "tsv")
+	if err != nil {
+		return nil, err
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("tesseract killed: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("tesseract command failed: %w: %s", notFound(err), strings.TrimSpace(string(output)))
+	}
+
+	text, err := os.ReadFile(outputBase + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tesseract text output: %w", err)
+	}
+
+	tsv, err := os.ReadFile(outputBase + ".tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tesseract TSV output: %w", err)
+	}
+
+	words := ParseTSV(string(tsv))
+	return &Result{
+		Text:       strings.ReplaceAll(string(text), "\r\n", "\n"), // Windows builds may end lines with CRLF
+		Confidence: AverageConfidence(words),
+		Words:      words,
+	}, nil
+}
+
+// RenderPDF writes a multi-page searchable PDF from page images using tesseract's pdf
+// output. Tesseract writes it next to the images, in the temp directory, and it is then
+// moved to outputPath: output paths may be longer than the 260 characters tesseract can
+// open on Windows, where Go itself handles long paths.
+func (t *Tesseract) RenderPDF(imagePaths []string, outputPath string) error {
+	if len(imagePaths) == 0 {
+		return fmt.Errorf("no page images to render")
+	}
+
+	// Tesseract accepts a text file listing one image per line for multi-page output
+	listFile, err := os.CreateTemp(filepath.Dir(imagePaths[0]), "pages-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create image list: %w", err)
+	}
+	defer os.Remove(listFile.Name())
+
+	// Tesseract always appends .pdf to the output base
+	outputBase := strings.TrimSuffix(listFile.Name(), filepath.Ext(listFile.Name()))
+	defer os.Remove(outputBase + ".pdf")
+	paths, err := t.paths(append([]string{listFile.Name(), outputBase}, imagePaths...)...)
+	if err != nil {
+		listFile.Close()
+		return err
+	}
+
+	_, err = listFile.WriteString(strings.Join(paths[2:], "\n") + "\n")
+	listFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write image list: %w", err)
+	}
+
+	cmd, err := t.command(context.Background(), paths[0], paths[1], "-l", strings.Join(t.languages, "+"), "pdf")
+	if err != nil {
+		return err
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tesseract pdf output failed: %w: %s", notFound(err), strings.TrimSpace(string(output)))
+	}
+
+	if err := moveFile(outputBase+".pdf", outputPath); err != nil {
+		return fmt.Errorf("failed to move searchable PDF: %w", err)
+	}
+	return nil
+}
+
+// moveFile renames source to target, copying it when they are on different volumes,
+// such as a temp directory on C: and an output directory on D:
+func moveFile(source, target string) error {
+	if err := os.Rename(source, target); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// GetName returns the engine name
+func (t *Tesseract) GetName() string {
+	return "Tesseract"
+}
+
+// IsLocal reports that tesseract runs on this machine, for the LocalOnly guard
+func (t *Tesseract) IsLocal() bool {
+	return true
+}
+
+// DetectScript runs tesseract orientation and script detection (OSD) on an image
+func (t *Tesseract) DetectScript(imagePath string) (string, error) {
+	return t.detectScript(context.Background(), imagePath)
+}
+
+// detectScript runs OSD as DetectScript does, killing tesseract when ctx is done
+func (t *Tesseract) detectScript(ctx context.Context, imagePath string) (string, error) {
+	paths, err := t.paths(imagePath)
+	if err != nil {
+		return "", err
+	}
+	cmd, err := t.command(ctx, paths[0], "stdout", "--psm", "0")
+	if err != nil {
+		return "", err
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("tesseract OSD failed: %w", notFound(err))
+	}
+
+	matches := osdScriptPattern.FindStringSubmatch(string(output))
+	if len(matches) < 2 {
+		return "", fmt.Errorf("no script found in OSD output")
+	}
+
+	return matches[1], nil
+}
+
+// detectLanguages picks language packs for an image, falling back to the configured ones
+func (t *Tesseract) detectLanguages(ctx context.Context, imagePath string) []string {
+	script, err := t.detectScript(ctx, imagePath)
+	if err != nil {
+		return t.languages
+	}
+
+	languages, ok := scriptLanguages[script]
+	if !ok {
+		// Latin and unknown scripts are covered by the configured languages
+		return t.languages
+	}
+
+	return languages
+}
+
+// command returns a command running tesseract with args, on this machine or in the
+// container, that is killed when ctx is done without waiting long for the output of
+// processes it started
+func (t *Tesseract) command(ctx context.Context, args ...string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, t.binary, args...)
+	if t.container != nil {
+		var err error
+		if cmd, err = t.container.Command(ctx, tesseractCommand, args...); err != nil {
+			return nil, err
+		}
+	}
+	cmd.WaitDelay = killWaitDelay
+	return cmd, nil
+}
+
+// paths returns the paths tesseract sees for files on this machine: the same paths, or
+// their paths in the container
+func (t *Tesseract) paths(hostPaths ...string) ([]string, error) {
+	if t.container == nil {
+		return hostPaths, nil
+	}
+	paths := make([]string, len(hostPaths))
+	for i, hostPath := range hostPaths {
+		containerPath, err := t.container.Path(hostPath)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = containerPath
+	}
+	return paths, nil
+}
+
+// ParseTSV extracts recognized words from tesseract TSV output
+func ParseTSV(tsv string) []Word {
+	var words []Word
+
+	for i, line := range strings.Split(tsv, "\n") {
+		// Skip the header row
+		if i == 0 {
+			continue
+		}
+
+		// level page_num block_num par_num line_num word_num left top width height conf text
+		fields := strings.Split(line, "\t")
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+
+		text := strings.TrimSpace(fields[11])
+		confidence, err := strconv.ParseFloat(fields[10], 64)
+		if text == "" || err != nil || confidence < 0 {
+			continue
+		}
+
+		words = append(words, Word{Text: text, Confidence: confidence})
+	}
+
+	return words
+}
@@ -0,0 +1,327 @@
+// Package ocrfix corrects common OCR errors, such as "pekerjaau" for "pekerjaan" or
+// "rnodern" for "modern", by replacing unknown words with the dictionary word they most
+// likely misread: first through character confusions typical of tesseract, then by
+// edit distance
+package ocrfix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Correction parameters
+const (
+	minWordLength = 4 // Shorter words are too ambiguous to correct
+	longWord      = 8 // Words from which two edits are allowed instead of one
+	maxDistance   = 2
+	trustedCount  = 3 // Words counted this often are known and can replace unknown ones
+	builtinCount  = 5 // Count of built-in and dictionary file words without one
+)
+
+// confusions are the character sequences tesseract commonly reads for others, tried
+// in both directions
+var confusions = [][2]string{
+	{"rn", "m"}, {"vv", "w"}, {"cl", "d"}, {"li", "h"}, {"ii", "u"}, {"in", "m"},
+	{"0", "o"}, {"1", "l"}, {"1", "i"}, {"5", "s"}, {"8", "b"}, {"6", "b"},
+	{"c", "e"}, {"n", "u"}, {"h", "b"}, {"l", "i"}, {"f", "t"},
+}
+
+// Dictionary counts known words, in lower case
+type Dictionary map[string]int
+
+// Add adds count occurrences of a word
+func (d Dictionary) Add(word string, count int) {
+	d[strings.ToLower(word)] += count
+}
+
+// AddText counts the words of a text
+func (d Dictionary) AddText(text string) {
+	for _, word := range words(text) {
+		d.Add(word.text, 1)
+	}
+}
+
+// ReadDictionary reads a dictionary with one word per line, optionally followed by its
+// count; blank lines and lines starting with # are skipped
+func ReadDictionary(r io.Reader) (Dictionary, error) {
+	dictionary := Dictionary{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		count := builtinCount
+		if len(fields) > 1 {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid count %q on line %d", fields[1], line)
+			}
+			count = n
+		}
+		dictionary.Add(fields[0], count)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+	return dictionary, nil
+}
+
+// Builtin returns the built-in common words of the tesseract languages it has lists
+// for, "eng" and "ind"
+func Builtin(languages []string) Dictionary {
+	dictionary := Dictionary{}
+	for _, language := range languages {
+		for _, word := range strings.Fields(builtinWords[language]) {
+			dictionary.Add(word, builtinCount)
+		}
+	}
+	return dictionary
+}
+
+// Corrector replaces the unknown words of a text with the dictionary words they most
+// likely misread
+type Corrector struct {
+	words   Dictionary
+	deletes map[string][]string // Words by the variants left after deleting up to maxDistance characters
+}
+
+// NewCorrector creates a corrector from dictionaries, adding up their counts
+func NewCorrector(dictionaries ...Dictionary) *Corrector {
+	c := &Corrector{words: Dictionary{}, deletes: make(map[string][]string)}
+	for _, dictionary := range dictionaries {
+		for word, count := range dictionary {
+			c.words[word] += count
+		}
+	}
+	for word := range c.words {
+		if utf8.RuneCountInString(word) < minWordLength-1 {
+			continue
+		}
+		for variant := range deletes(word, distanceFor(word)) {
+			c.deletes[variant] = append(c.deletes[variant], word)
+		}
+	}
+	return c
+}
+
+// Correct returns text with its unknown words corrected, and the number of corrections.
+// Words counted trustedCount times are known, and an unknown word is only replaced by a
+// known word seen more often than itself.
+func (c *Corrector) Correct(text string) (string, int) {
+	var corrected strings.Builder
+	corrections, last := 0, 0
+	for _, word := range words(text) {
+		replacement, ok := c.correctWord(word.text)
+		if !ok {
+			continue
+		}
+		corrected.WriteString(text[last:word.start])
+		corrected.WriteString(replacement)
+		last = word.start + len(word.text)
+		corrections++
+	}
+	if corrections == 0 {
+		return text, 0
+	}
+	corrected.WriteString(text[last:])
+	return corrected.String(), corrections
+}
+
+// correctWord returns the correction of a word, keeping its case
+func (c *Corrector) correctWord(word string) (string, bool) {
+	if utf8.RuneCountInString(word) < minWordLength {
+		return "", false
+	}
+	lower := strings.ToLower(word)
+	caseOf, ok := casePattern(word)
+	if !ok || c.words[lower] >= trustedCount || !strings.ContainsFunc(lower, unicode.IsLetter) {
+		return "", false
+	}
+
+	count := c.words[lower]
+	best, ok := c.confusion(lower, count)
+	if !ok {
+		best, ok = c.nearest(lower, count)
+	}
+	if !ok {
+		return "", false
+	}
+	return caseOf(best), true
+}
+
+// confusion returns the most frequent known word reached by undoing one character
+// confusion of a word
+func (c *Corrector) confusion(word string, count int) (string, bool) {
+	best, bestCount := "", max(count, trustedCount-1)
+	for _, pair := range confusions {
+		for _, swap := range [][2]string{pair, {pair[1], pair[0]}} {
+			for i := strings.Index(word, swap[0]); i >= 0; {
+				candidate := word[:i] + swap[1] + word[i+len(swap[0]):]
+				if n := c.words[candidate]; n > bestCount {
+					best, bestCount = candidate, n
+				}
+				next := strings.Index(word[i+1:], swap[0])
+				if next < 0 {
+					break
+				}
+				i += 1 + next
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// nearest returns the known word of the same length closest to a word by substitutions,
+// the most frequent one on ties, or false when the closest words are equally frequent.
+// Insertions and deletions, and replacing the first letter other than by a known
+// confusion, mostly turn a word into another inflection of it, so they are not tried.
+func (c *Corrector) nearest(word string, count int) (string, bool) {
+	if strings.ContainsFunc(word, unicode.IsDigit) {
+		return "", false // Digits are only corrected as confusions
+	}
+	limit := distanceFor(word)
+	candidates := make(map[string]bool)
+	for variant := range deletes(word, limit) {
+		for _, candidate := range c.deletes[variant] {
+			candidates[candidate] = true
+		}
+	}
+
+	best, bestDistance, bestCount, tied := "", limit+1, 0, false
+	for candidate := range candidates {
+		distance := substitutions(word, candidate)
+		first, candidateFirst := []rune(word)[0], []rune(candidate)[0]
+		n := c.words[candidate]
+		switch {
+		case distance < 1 || distance > limit || n <= count || n < trustedCount:
+		case first != candidateFirst && !confusable(first, candidateFirst):
+		case distance < bestDistance || distance == bestDistance && n > bestCount:
+			best, bestDistance, bestCount, tied = candidate, distance, n, false
+		case distance == bestDistance && n == bestCount:
+			tied = true
+		}
+	}
+	return best, best != "" && !tied
+}
+
+// distanceFor returns the edits allowed to correct a word of its length
+func distanceFor(word string) int {
+	if utf8.RuneCountInString(word) >= longWord {
+		return maxDistance
+	}
+	return 1
+}
+
+// casePattern returns a function giving a lower-case word the case of word: lower,
+// title or upper case. Words of mixed case, such as names like "iPhone", are not
+// corrected.
+func casePattern(word string) (func(string) string, bool) {
+	var upper, lower, first int
+	for i, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+			if i == 0 {
+				first = 1
+			}
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return func(s string) string { return s }, true
+	case lower == 0:
+		return strings.ToUpper, true
+	case upper == 1 && first == 1:
+		return func(s string) string {
+			r, size := utf8.DecodeRuneInString(s)
+			return string(unicode.ToUpper(r)) + s[size:]
+		}, true
+	}
+	return nil, false
+}
+
+// word is a run of letters and digits in a text, at its byte offset
+type word struct {
+	text  string
+	start int
+}
+
+// words splits a text into its runs of letters and digits
+func words(text string) []word {
+	var found []word
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			found = append(found, word{text: text[start:i], start: start})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		found = append(found, word{text: text[start:], start: start})
+	}
+	return found
+}
+
+// deletes returns a word and every variant of it with up to n characters deleted
+func deletes(word string, n int) map[string]bool {
+	variants := map[string]bool{word: true}
+	frontier := []string{word}
+	for range n {
+		var next []string
+		for _, variant := range frontier {
+			runes := []rune(variant)
+			for i := range runes {
+				deleted := string(runes[:i]) + string(runes[i+1:])
+				if !variants[deleted] {
+					variants[deleted] = true
+					next = append(next, deleted)
+				}
+			}
+		}
+		frontier = next
+	}
+	return variants
+}
+
+// substitutions returns the number of characters to replace, or adjacent pairs to swap,
+// to turn a into b, or -1 when they differ in length
+func substitutions(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	if len(x) != len(y) {
+		return -1
+	}
+	edits := 0
+	for i := 0; i < len(x); i++ {
+		if x[i] == y[i] {
+			continue
+		}
+		edits++
+		if i+1 < len(x) && x[i] == y[i+1] && x[i+1] == y[i] {
+			i++ // Transposition
+		}
+	}
+	return edits
+}
+
+// confusable reports whether two characters are a known OCR confusion
+func confusable(a, b rune) bool {
+	for _, pair := range confusions {
+		x, y := []rune(pair[0]), []rune(pair[1])
+		if len(x) == 1 && len(y) == 1 && (x[0] == a && y[0] == b || x[0] == b && y[0] == a) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package ocrfix
+
+// builtinWords are common words of the tesseract languages, mostly of business, legal and
+// government documents, so short documents have a dictionary too
+var builtinWords = map[string]string{
+	"eng": `
+		about above according account accordance act action activities activity additional address
+		administration after agreement all also amendment amount analysis annual another any apply
+		approval approved april area article assets august authority available balance bank based
+		because been before being below between board both budget business capital case cash
+		certificate change chapter clause committee company compliance condition conditions
+		contract contractor control cost costs could country court current customer data date
+		days december decision department described design development director document
+		documents during each effective employee employees end environment every evidence
+		except expenses february fees file final financial first following form from full
+		further general government group have having however implementation including income
+		information insurance interest internal into january july june language last legal
+		liability limited management manager march market material matter may meeting member
+		members method minister ministry month months national necessary net new november number
+		object obligations october office officer only operating operations order other owner
+		page paragraph part parties party payment payments people performance period person
+		personal policy position possible president price principal procedure process product
+		products project property provide provided provision provisions public purchase purpose
+		quality quarter receipt receive received record records regarding registration
+		regulation regulations related report reports request required requirements research
+		respect responsible result results revenue rights risk rules safety sales schedule
+		second section security september service services shall share shares should signed
+		since social specified staff standard statement statements subject such supplier system
+		table than that their them then there these they third this those through time total
+		training transaction under until upon value what when where whether which while will
+		with within without work worker workers would written year years
+	`,
+	"ind": `
+		adalah adanya agar akan akhir aktiva anak anggaran anggota antara apabila atas atau
+		ayat bab badan bagi bagian bahwa baik bank barang baru batas bawah beban belum berdasarkan
+		berikut berlaku bersama besar biaya bidang bukan bulan bunga cabang cara daerah dalam
+		dana dapat dari daftar data dengan dewan diatur dimaksud direksi direktur disebut
+		dokumen ekonomi hak hal halaman hanya harga harus hari hasil hubungan hukum jabatan
+		jaminan jangka jasa jenis jika jumlah juga kantor karena karyawan kas keadaan kebijakan
+		kecuali kegiatan keputusan kerja kesehatan ketentuan ketenagakerjaan ketua keuangan
+		kewajiban kota kurang laporan lain lainnya lama lebih lembaga maka masa masing
+		masyarakat melakukan memberikan mempunyai menjadi menteri mengenai menurut modal
+		nasional negara nilai nomor oleh orang pada pajak paling paragraf pasal pejabat
+		pekerja pekerjaan pelaksanaan pembayaran pemberi pemerintah penerimaan pengadilan
+		pengelolaan penggunaan penjelasan penyelenggaraan perjanjian perubahan perusahaan
+		peraturan persen pertama pihak pokok presiden program provinsi pusat rapat republik
+		rupiah saham sampai satu saat sebagai sebagaimana sebelum secara sehingga sejak
+		sekretaris selama selanjutnya semua sesuai setelah setiap siapa sistem surat tahun
+		tanggal tanggung tenaga tentang terhadap tersebut tertentu tetap tidak tiga tugas
+		umum undang untuk upah usaha waktu wajib wilayah yaitu yang
+	`,
+}
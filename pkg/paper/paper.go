@@ -0,0 +1,272 @@
+// Package paper splits scientific papers into their sections (Abstract, Introduction,
+// Methods, Results, ..., References), restores the reading order of two-column pages and
+// strips inline citations, for the paper profile of the chunker
+package paper
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Canonical section names of Section.Name
+const (
+	SectionFront           = "front" // Title, authors and affiliations before the first heading
+	SectionAbstract        = "abstract"
+	SectionIntroduction    = "introduction"
+	SectionMethods         = "methods"
+	SectionResults         = "results"
+	SectionDiscussion      = "discussion"
+	SectionConclusion      = "conclusion"
+	SectionAcknowledgments = "acknowledgments"
+	SectionReferences      = "references"
+	SectionAppendix        = "appendix"
+	SectionOther           = "other" // A numbered top-level section with another name, e.g. "2 Related Work"
+)
+
+// sectionNames maps the lower-case section headings of English and Indonesian papers to
+// their canonical names
+var sectionNames = map[string]string{
+	"abstract":                    SectionAbstract,
+	"abstrak":                     SectionAbstract,
+	"introduction":                SectionIntroduction,
+	"background":                  SectionIntroduction,
+	"pendahuluan":                 SectionIntroduction,
+	"method":                      SectionMethods,
+	"methods":                     SectionMethods,
+	"methodology":                 SectionMethods,
+	"materials and methods":       SectionMethods,
+	"experimental setup":          SectionMethods,
+	"metode":                      SectionMethods,
+	"metodologi":                  SectionMethods,
+	"metode penelitian":           SectionMethods,
+	"results":                     SectionResults,
+	"experiments":                 SectionResults,
+	"evaluation":                  SectionResults,
+	"results and discussion":      SectionResults,
+	"hasil":                       SectionResults,
+	"hasil dan pembahasan":        SectionResults,
+	"discussion":                  SectionDiscussion,
+	"pembahasan":                  SectionDiscussion,
+	"conclusion":                  SectionConclusion,
+	"conclusions":                 SectionConclusion,
+	"conclusions and future work": SectionConclusion,
+	"kesimpulan":                  SectionConclusion,
+	"kesimpulan dan saran":        SectionConclusion,
+	"penutup":                     SectionConclusion,
+	"acknowledgments":             SectionAcknowledgments,
+	"acknowledgements":            SectionAcknowledgments,
+	"acknowledgment":              SectionAcknowledgments,
+	"acknowledgement":             SectionAcknowledgments,
+	"ucapan terima kasih":         SectionAcknowledgments,
+	"references":                  SectionReferences,
+	"bibliography":                SectionReferences,
+	"works cited":                 SectionReferences,
+	"literature cited":            SectionReferences,
+	"daftar pustaka":              SectionReferences,
+	"referensi":                   SectionReferences,
+	"appendix":                    SectionAppendix,
+	"appendices":                  SectionAppendix,
+	"lampiran":                    SectionAppendix,
+}
+
+var (
+	// numberingPattern matches the numbering before a heading: "2", "2.", "II." or "A."
+	numberingPattern = regexp.MustCompile(`^(?:\d+\.?|[IVX]+\.|[A-H]\.)\s+`)
+
+	// inlineAbstractPattern matches an abstract that starts on its heading line, as in
+	// "Abstract—We propose" or "Abstract: We propose"
+	inlineAbstractPattern = regexp.MustCompile(`^(?i:abstract|abstrak)\s*[—–:.-]\s*\S`)
+
+	// otherHeadingPattern matches a numbered top-level heading of a few capitalized words
+	otherHeadingPattern = regexp.MustCompile(`^(?:\d+\.?|[IVX]+\.)\s+\p{Lu}[\p{L}\p{N} ,:&/-]*$`)
+
+	// appendixPattern matches appendix headings such as "Appendix A" or "Lampiran 1"
+	appendixPattern = regexp.MustCompile(`^(?i:appendix|lampiran)\s+[A-Z0-9]+\b`)
+
+	// pageSeparatorPattern matches the "--- Page N ---" lines between the pages of a document
+	pageSeparatorPattern = regexp.MustCompile(`^--- Page \d+ ---$`)
+)
+
+// maxHeadingWords bounds the length of headings without a known section name
+const maxHeadingWords = 6
+
+// Section is a section of a paper
+type Section struct {
+	Name    string // SectionAbstract, SectionMethods, ...
+	Heading string // The heading line, e.g. "2. Materials and Methods"; empty for SectionFront
+	Start   int    // Byte offset of the heading in the split text
+	End     int    // Byte offset of the next section's heading, or the length of the text
+}
+
+// Split splits a paper into its sections in order. The text before the first heading
+// is SectionFront; subsections such as "3.1 Dataset" stay in their section. Text without
+// section headings is a single SectionFront.
+func Split(text string) []Section {
+	sections := []Section{{Name: SectionFront}}
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		start := offset
+		offset += len(line)
+		name := sectionName(strings.TrimSpace(line))
+		current := &sections[len(sections)-1]
+		// Numbered affiliations in the front matter and numbered entries in the
+		// references look like other headings
+		if name == "" || name == SectionOther && (current.Name == SectionFront || current.Name == SectionReferences) {
+			continue
+		}
+		current.End = start
+		sections = append(sections, Section{Name: name, Heading: strings.TrimSpace(line), Start: start})
+	}
+	sections[len(sections)-1].End = len(text)
+
+	// Drop an empty front section, e.g. when the text starts with "Abstract"
+	if strings.TrimSpace(text[sections[0].Start:sections[0].End]) == "" && len(sections) > 1 {
+		sections = sections[1:]
+	}
+	return sections
+}
+
+// sectionName returns the canonical name of the section a line is the heading of, or ""
+func sectionName(line string) string {
+	if line == "" || len(line) > 80 || pageSeparatorPattern.MatchString(line) {
+		return ""
+	}
+	if inlineAbstractPattern.MatchString(line) {
+		return SectionAbstract
+	}
+	if appendixPattern.MatchString(line) {
+		return SectionAppendix
+	}
+
+	title := strings.TrimSpace(numberingPattern.ReplaceAllString(line, ""))
+	title = strings.TrimRight(title, ":.")
+	if name, ok := sectionNames[strings.ToLower(title)]; ok {
+		return name
+	}
+	if otherHeadingPattern.MatchString(line) && !strings.HasSuffix(line, ".") &&
+		len(strings.Fields(title)) <= maxHeadingWords && isTitle(title) {
+		return SectionOther
+	}
+	return ""
+}
+
+// isTitle reports whether every long word of a heading is capitalized, as in "Related
+// Work" or "RELATED WORK", so numbered sentences are not taken for headings
+func isTitle(title string) bool {
+	for _, word := range strings.Fields(title) {
+		first, _ := utf8.DecodeRuneInString(word)
+		if len(word) > 3 && strings.ToLower(string(first)) == string(first) {
+			return false
+		}
+	}
+	return true
+}
+
+// citationPattern matches inline citations: numeric ones such as "[12]", "[3, 5–7]" or
+// "[1], [4]", and author-year ones such as "(Smith et al., 2020)" or "(Lee and Kim,
+// 2019; Wu, 2021a)", also when they wrap across lines
+var citationPattern = regexp.MustCompile(`\s?\[\d+(?:\s*[,–-]\s*\d+)*\](?:\s*,\s*\[\d+(?:\s*[,–-]\s*\d+)*\])*|\s?\((?:[\p{Lu}][\p{L}'-]+(?:\s+et\s+al\.|\s+(?:and|&|dan)\s+[\p{Lu}][\p{L}'-]+)?,?\s+\d{4}[a-z]?(?:;\s*)?)+\)`)
+
+// StripCitations removes inline citations from text, keeping the sentences around them
+func StripCitations(text string) string {
+	return citationPattern.ReplaceAllString(text, "")
+}
+
+// Two-column detection thresholds for Decolumnize
+const (
+	minColumnGap   = 3   // Spaces between the columns of a line
+	minColumnLines = 5   // Lines that must show the column split
+	minColumnShare = 0.4 // Share of the non-empty lines that must show it
+)
+
+// Decolumnize restores the reading order of a page whose text was extracted with its
+// layout, so each line holds a line of the left column and one of the right column: the
+// left column is returned first, then the right one. Lines crossing the gutter, such as
+// titles and wide figures, are kept whole between the columns around them. Pages without
+// a consistent gutter are returned unchanged.
+func Decolumnize(page string) string {
+	lines := strings.Split(page, "\n")
+	gutter := findGutter(lines)
+	if gutter < 0 {
+		return page
+	}
+
+	var out, left, right []string
+	flush := func() {
+		out = append(out, left...)
+		out = append(out, right...)
+		left, right = nil, nil
+	}
+	for _, line := range lines {
+		runes := []rune(line)
+		switch {
+		case strings.TrimSpace(line) == "":
+			// A blank row ends a paragraph in both columns
+			left = append(left, "")
+			if len(right) > 0 {
+				right = append(right, "")
+			}
+		case len(runes) <= gutter:
+			left = append(left, strings.TrimRight(line, " "))
+		case runes[gutter-1] != ' ' && runes[gutter] != ' ':
+			// The line crosses the gutter
+			flush()
+			out = append(out, strings.TrimSpace(line))
+		default:
+			if text := strings.TrimRight(string(runes[:gutter]), " "); text != "" || len(left) > 0 {
+				left = append(left, text)
+			}
+			right = append(right, strings.TrimSpace(string(runes[gutter:])))
+		}
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// findGutter returns the rune column where the right column starts on most lines, or
+// -1 when the page does not look like two columns
+func findGutter(lines []string) int {
+	starts := make(map[int]int)
+	nonEmpty, width := 0, 0
+	for _, line := range lines {
+		runes := []rune(line)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		nonEmpty++
+		width = max(width, len(runes))
+
+		// The start of the text after the last wide gap
+		spaces := 0
+		for i, r := range runes {
+			if r == ' ' {
+				spaces++
+				continue
+			}
+			if spaces >= minColumnGap && i > spaces {
+				starts[i]++
+			}
+			spaces = 0
+		}
+	}
+
+	columns := make([]int, 0, len(starts))
+	for column := range starts {
+		columns = append(columns, column)
+	}
+	sort.Ints(columns)
+	best, count := -1, 0
+	for _, column := range columns {
+		// Right columns may start a space apart, e.g. after justified lines
+		if n := starts[column] + starts[column+1]; n > count {
+			best, count = column, n
+		}
+	}
+	if best < 0 || count < minColumnLines || float64(count) < minColumnShare*float64(nonEmpty) ||
+		best < width/4 || best > width*3/4 {
+		return -1
+	}
+	return best
+}
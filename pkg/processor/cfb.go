@@ -0,0 +1,240 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Compound File Binary (OLE2) constants, see [MS-CFB]
+const (
+	cfbEndOfChain   = 0xFFFFFFFE
+	cfbFreeSector   = 0xFFFFFFFF
+	cfbNoStream     = 0xFFFFFFFF
+	cfbHeaderDIFAT  = 109
+	cfbDirEntrySize = 128
+
+	cfbTypeStorage = 1
+	cfbTypeStream  = 2
+	cfbTypeRoot    = 5
+)
+
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// cfbEntry is a directory entry of a compound file
+type cfbEntry struct {
+	name        string
+	entryType   byte
+	left        uint32
+	right       uint32
+	child       uint32
+	startSector uint32
+	size        uint64
+}
+
+// compoundFile is a minimal read-only Compound File Binary reader
+type compoundFile struct {
+	data           []byte
+	sectorSize     int
+	miniSectorSize int
+	miniCutoff     uint64
+	fat            []uint32
+	miniFAT        []uint32
+	miniStream     []byte
+	entries        []cfbEntry
+}
+
+// IsCompoundFile reports whether data starts with the OLE2 compound file signature (.msg, .doc, .xls)
+func IsCompoundFile(data []byte) bool {
+	return bytes.HasPrefix(data, cfbSignature)
+}
+
+// openCompoundFile parses the header, allocation tables and directory of a compound file
+func openCompoundFile(data []byte) (*compoundFile, error) {
+	if len(data) < 512 || !IsCompoundFile(data) {
+		return nil, fmt.Errorf("not a compound file")
+	}
+
+	cf := &compoundFile{
+		data:           data,
+		sectorSize:     1 << binary.LittleEndian.Uint16(data[0x1E:]),
+		miniSectorSize: 1 << binary.LittleEndian.Uint16(data[0x20:]),
+		miniCutoff:     uint64(binary.LittleEndian.Uint32(data[0x38:])),
+	}
+
+	if err := cf.readFAT(); err != nil {
+		return nil, err
+	}
+
+	dirData, err := cf.readChain(binary.LittleEndian.Uint32(data[0x30:]), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	for offset := 0; offset+cfbDirEntrySize <= len(dirData); offset += cfbDirEntrySize {
+		cf.entries = append(cf.entries, parseCFBEntry(dirData[offset:offset+cfbDirEntrySize]))
+	}
+	if len(cf.entries) == 0 || cf.entries[0].entryType != cfbTypeRoot {
+		return nil, fmt.Errorf("compound file has no root entry")
+	}
+
+	miniFATData, err := cf.readChain(binary.LittleEndian.Uint32(data[0x3C:]), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mini FAT: %w", err)
+	}
+	cf.miniFAT = bytesToUint32s(miniFATData)
+
+	root := cf.entries[0]
+	cf.miniStream, err = cf.readChain(root.startSector, root.size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mini stream: %w", err)
+	}
+
+	return cf, nil
+}
+
+// readFAT loads the file allocation table from the header and chained DIFAT sectors
+func (cf *compoundFile) readFAT() error {
+	var fatSectors []uint32
+	for i := 0; i < cfbHeaderDIFAT; i++ {
+		sector := binary.LittleEndian.Uint32(cf.data[0x4C+i*4:])
+		if sector != cfbFreeSector {
+			fatSectors = append(fatSectors, sector)
+		}
+	}
+
+	difatSector := binary.LittleEndian.Uint32(cf.data[0x44:])
+	perSector := cf.sectorSize/4 - 1
+	for visited := 0; difatSector != cfbEndOfChain && difatSector != cfbFreeSector; visited++ {
+		sector, err := cf.sector(difatSector)
+		if err != nil || visited > len(cf.data)/cf.sectorSize {
+			return fmt.Errorf("invalid DIFAT chain")
+		}
+		values := bytesToUint32s(sector)
+		for _, value := range values[:perSector] {
+			if value != cfbFreeSector {
+				fatSectors = append(fatSectors, value)
+			}
+		}
+		difatSector = values[perSector]
+	}
+
+	for _, fatSector := range fatSectors {
+		sector, err := cf.sector(fatSector)
+		if err != nil {
+			return fmt.Errorf("invalid FAT sector: %w", err)
+		}
+		cf.fat = append(cf.fat, bytesToUint32s(sector)...)
+	}
+	return nil
+}
+
+// sector returns the bytes of a regular sector
+func (cf *compoundFile) sector(index uint32) ([]byte, error) {
+	start := (int(index) + 1) * cf.sectorSize
+	if start < 0 || start+cf.sectorSize > len(cf.data) {
+		return nil, fmt.Errorf("sector %d out of range", index)
+	}
+	return cf.data[start : start+cf.sectorSize], nil
+}
+
+// readChain follows a FAT chain, truncating to size when it is non-zero
+func (cf *compoundFile) readChain(start uint32, size uint64) ([]byte, error) {
+	var buf bytes.Buffer
+	for sector, visited := start, 0; sector != cfbEndOfChain && sector != cfbFreeSector; visited++ {
+		if int(sector) >= len(cf.fat) || visited > len(cf.fat) {
+			return nil, fmt.Errorf("invalid sector chain")
+		}
+		data, err := cf.sector(sector)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		sector = cf.fat[sector]
+	}
+
+	if size > 0 && uint64(buf.Len()) > size {
+		return buf.Bytes()[:size], nil
+	}
+	return buf.Bytes(), nil
+}
+
+// readMiniChain follows a mini FAT chain inside the mini stream
+func (cf *compoundFile) readMiniChain(start uint32, size uint64) ([]byte, error) {
+	var buf bytes.Buffer
+	for sector, visited := start, 0; sector != cfbEndOfChain && sector != cfbFreeSector; visited++ {
+		offset := int(sector) * cf.miniSectorSize
+		if int(sector) >= len(cf.miniFAT) || offset+cf.miniSectorSize > len(cf.miniStream) || visited > len(cf.miniFAT) {
+			return nil, fmt.Errorf("invalid mini sector chain")
+		}
+		buf.Write(cf.miniStream[offset : offset+cf.miniSectorSize])
+		sector = cf.miniFAT[sector]
+	}
+
+	if uint64(buf.Len()) > size {
+		return buf.Bytes()[:size], nil
+	}
+	return buf.Bytes(), nil
+}
+
+// stream returns the contents of a stream entry
+func (cf *compoundFile) stream(entry cfbEntry) ([]byte, error) {
+	if entry.size < cf.miniCutoff {
+		return cf.readMiniChain(entry.startSector, entry.size)
+	}
+	return cf.readChain(entry.startSector, entry.size)
+}
+
+// children returns the direct children of a storage entry keyed by name
+func (cf *compoundFile) children(storage uint32) map[string]uint32 {
+	children := make(map[string]uint32)
+	if int(storage) >= len(cf.entries) {
+		return children
+	}
+
+	// Children form a red-black tree through left/right sibling links
+	stack := []uint32{cf.entries[storage].child}
+	for len(stack) > 0 && len(children) <= len(cf.entries) {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == cfbNoStream || int(id) >= len(cf.entries) {
+			continue
+		}
+		entry := cf.entries[id]
+		children[entry.name] = id
+		stack = append(stack, entry.left, entry.right)
+	}
+	return children
+}
+
+// parseCFBEntry decodes a 128-byte directory entry
+func parseCFBEntry(data []byte) cfbEntry {
+	nameLength := int(binary.LittleEndian.Uint16(data[64:]))
+	if nameLength > 64 {
+		nameLength = 64
+	}
+	// Name length includes the UTF-16 null terminator
+	units := make([]uint16, 0, nameLength/2)
+	for i := 0; i+1 < nameLength-2; i += 2 {
+		units = append(units, binary.LittleEndian.Uint16(data[i:]))
+	}
+
+	return cfbEntry{
+		name:        string(utf16.Decode(units)),
+		entryType:   data[66],
+		left:        binary.LittleEndian.Uint32(data[68:]),
+		right:       binary.LittleEndian.Uint32(data[72:]),
+		child:       binary.LittleEndian.Uint32(data[76:]),
+		startSector: binary.LittleEndian.Uint32(data[116:]),
+		size:        binary.LittleEndian.Uint64(data[120:]) & 0xFFFFFFFF,
+	}
+}
+
+// bytesToUint32s decodes a little-endian uint32 array
+func bytesToUint32s(data []byte) []uint32 {
+	values := make([]uint32, len(data)/4)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return values
+}
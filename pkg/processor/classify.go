@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// DocumentClass describes how a PDF was produced
+type DocumentClass string
+
+const (
+	ClassDigital DocumentClass = "digital" // Every page has a text layer
+	ClassScanned DocumentClass = "scanned" // No page has a usable text layer
+	ClassHybrid  DocumentClass = "hybrid"  // Mix of text and image-only pages
+)
+
+// minTextPageChars is the number of characters below which a page is treated as image-only
+const minTextPageChars = 20
+
+var (
+	pdfaPartPattern        = regexp.MustCompile(`pdfaid:part(?:>|=["'])\s*(\d)`)
+	pdfaConformancePattern = regexp.MustCompile(`pdfaid:conformance(?:>|=["'])\s*([A-Za-z])`)
+)
+
+// Classification is the result of the preflight classifier
+type Classification struct {
+	Class           DocumentClass `json:"class"`
+	TextPages       int           `json:"text_pages"`
+	ImagePages      int           `json:"image_pages"`
+	PDFA            bool          `json:"pdfa"`
+	PDFAConformance string        `json:"pdfa_conformance,omitempty"` // e.g. "1B", "2A"
+}
+
+// classifyPages decides the document class from the direct text of each page
+func classifyPages(texts []string) (DocumentClass, int) {
+	textPages := 0
+	for _, text := range texts {
+		if isTextPage(text) {
+			textPages++
+		}
+	}
+	return classifyCounts(textPages, len(texts)), textPages
+}
+
+// isTextPage reports whether a page's direct text is enough to skip OCR in a hybrid document
+func isTextPage(text string) bool {
+	return len(strings.TrimSpace(text)) >= minTextPageChars
+}
+
+// classifyCounts decides the document class from the number of text pages
+func classifyCounts(textPages, totalPages int) DocumentClass {
+	switch {
+	case textPages == totalPages:
+		return ClassDigital
+	case textPages == 0:
+		return ClassScanned
+	default:
+		return ClassHybrid
+	}
+}
+
+// detectPDFA scans the raw PDF for XMP PDF/A identification and returns the
+// conformance level (e.g. "1B"), or an empty string if the file is not PDF/A
+func detectPDFA(reader io.Reader) string {
+	const window = 64 * 1024
+	const overlap = 256
+
+	buf := make([]byte, window+overlap)
+	carry := 0
+	for {
+		n, err := io.ReadFull(reader, buf[carry:])
+		data := buf[:carry+n]
+
+		if match := pdfaPartPattern.FindSubmatch(data); match != nil {
+			part := string(match[1])
+			// The conformance attribute sits right next to the part in the XMP packet
+			rest := data[bytes.Index(data, match[0]):]
+			if conformance := pdfaConformancePattern.FindSubmatch(rest); conformance != nil {
+				return part + strings.ToUpper(string(conformance[1]))
+			}
+			return part
+		}
+
+		if err != nil {
+			return ""
+		}
+
+		// Keep the tail so a marker spanning two reads is still found
+		carry = copy(buf, data[len(data)-overlap:])
+	}
+}
+
+// detectPDFAFromPath runs PDF/A detection on a file
+func detectPDFAFromPath(pdfPath string) string {
+	file, err := os.Open(pdfPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	return detectPDFA(file)
+}
+
+// ClassifyPDFPath runs the preflight classifier without extracting or OCRing the document
+func (p *PDFProcessor) ClassifyPDFPath(pdfPath string) (*Classification, error) {
+	doc, err := openPDF(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	defer doc.Close()
+
+	texts := make([]string, doc.NumPage())
+	for pageIndex := range texts {
+		texts[pageIndex], _ = doc.Text(pageIndex)
+	}
+
+	return newClassification(texts, detectPDFAFromPath(pdfPath)), nil
+}
+
+// newClassification builds a classification from page texts and the PDF/A conformance
+func newClassification(texts []string, pdfaConformance string) *Classification {
+	_, textPages := classifyPages(texts)
+	return newClassificationFromCounts(textPages, len(texts), pdfaConformance)
+}
+
+// newClassificationFromCounts builds a classification from the number of text pages
+func newClassificationFromCounts(textPages, totalPages int, pdfaConformance string) *Classification {
+	return &Classification{
+		Class:           classifyCounts(textPages, totalPages),
+		TextPages:       textPages,
+		ImagePages:      totalPages - textPages,
+		PDFA:            pdfaConformance != "",
+		PDFAConformance: pdfaConformance,
+	}
+}
+
+// String returns a human readable summary of the classification
+func (c Classification) String() string {
+	if c.PDFA {
+		return fmt.Sprintf("%s (PDF/A-%s)", c.Class, c.PDFAConformance)
+	}
+	return string(c.Class)
+}
@@ -0,0 +1,210 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/container"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ocr"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
+)
+
+// EngineDocker is the engine named in DocumentReport.Engine for PDFs read with mutool in
+// a Docker container, see WithContainer
+const EngineDocker = "mutool (docker)"
+
+// mutoolPagesPattern matches the page count in mutool info output
+var mutoolPagesPattern = regexp.MustCompile(`(?m)^Pages:\s*(\d+)`)
+
+// stextPagePattern matches the page size in mutool structured text output
+var stextPagePattern = regexp.MustCompile(`<page [^>]*width="([\d.]+)" height="([\d.]+)"`)
+
+// WithContainer returns a copy of the processor that reads and renders PDFs with mutool,
+// and recognizes pages with tesseract, in c instead of on this machine: for hosts
+// without MuPDF or tesseract, including purego builds, which then OCR scanned pages too.
+// c must mount the temp directory, tempfile.Root(TempDir). An OCR engine other than
+// Tesseract set with WithOCREngine afterwards is kept as is.
+func (p *PDFProcessor) WithContainer(c *container.Container) *PDFProcessor {
+	clone := *p
+	clone.container = c
+	if tesseract, ok := clone.ocrEngine.(*ocr.Tesseract); ok {
+		clone.ocrEngine = tesseract.WithContainer(c)
+	}
+	return &clone
+}
+
+// engine returns the engine that reads PDFs before any fallback
+func (p *PDFProcessor) engine() string {
+	if p.container != nil {
+		return EngineDocker
+	}
+	return defaultEngine
+}
+
+// rendersPages reports whether pages can be rendered for OCR and searchable PDFs
+func (p *PDFProcessor) rendersPages() bool {
+	return renderSupported || p.container != nil
+}
+
+// openPDF opens a PDF file with the engine of the processor
+func (p *PDFProcessor) openPDF(pdfPath string) (pdfDocument, error) {
+	if p.container == nil {
+		return openPDF(pdfPath)
+	}
+	file, err := os.Open(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return openContainerPDF(p.container, file)
+}
+
+// openPDFMemory opens PDF binary data with the engine of the processor
+func (p *PDFProcessor) openPDFMemory(data []byte) (pdfDocument, error) {
+	if p.container == nil {
+		return openPDFMemory(data)
+	}
+	return openContainerPDF(p.container, bytes.NewReader(data))
+}
+
+// containerDocument reads a PDF with mutool in a container. The PDF is copied into a
+// temp directory under the work directory of the container, where pages are rendered.
+type containerDocument struct {
+	container *container.Container
+	dir       string // Host directory of the copy and the rendered pages
+	path      string // Container path of the copy
+	pages     int
+}
+
+// openContainerPDF copies a PDF into the work directory of c and reads its page count
+func openContainerPDF(c *container.Container, pdf io.Reader) (pdfDocument, error) {
+	dir, err := tempfile.MkdirTemp(c.WorkDir(), "docker-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container directory: %w", err)
+	}
+	doc := &containerDocument{container: c, dir: dir}
+	hostPath := filepath.Join(dir, "input.pdf")
+	if err := writeFile(hostPath, pdf); err != nil {
+		doc.Close()
+		return nil, fmt.Errorf("failed to copy PDF into container directory: %w", err)
+	}
+	if doc.path, err = c.Path(hostPath); err != nil {
+		doc.Close()
+		return nil, err
+	}
+
+	output, err := doc.mutool("info", doc.path)
+	if err != nil {
+		doc.Close()
+		return nil, err
+	}
+	matches := mutoolPagesPattern.FindStringSubmatch(output)
+	if matches == nil {
+		doc.Close()
+		return nil, fmt.Errorf("no page count in mutool info output")
+	}
+	doc.pages, _ = strconv.Atoi(matches[1])
+	return doc, nil
+}
+
+// writeFile writes the content of r to a new file at path
+func writeFile(path string, r io.Reader) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// mutool runs mutool with args in the container and returns its output
+func (d *containerDocument) mutool(args ...string) (string, error) {
+	cmd, err := d.container.Command(context.Background(), "mutool", args...)
+	if err != nil {
+		return "", err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", commandError("mutool "+args[0], err, stderr.String())
+	}
+	return string(output), nil
+}
+
+// NumPage returns the number of pages
+func (d *containerDocument) NumPage() int {
+	return d.pages
+}
+
+// Text returns the plain text of a page
+func (d *containerDocument) Text(pageIndex int) (string, error) {
+	output, err := d.mutool("draw", "-F", "txt", "-o", "-", d.path, strconv.Itoa(pageIndex+1))
+	if err != nil {
+		return "", err
+	}
+	// mutool ends every page with a form feed
+	return strings.TrimRight(output, "\f\n") + "\n", nil
+}
+
+// ImageDPI renders a page to a PNG in the container directory and decodes it
+func (d *containerDocument) ImageDPI(pageIndex int, dpi float64) (image.Image, error) {
+	file, err := os.CreateTemp(d.dir, "page-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page image: %w", err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	imagePath, err := d.container.Path(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.mutool("draw", "-r", strconv.FormatFloat(dpi, 'f', -1, 64), "-o", imagePath, d.path, strconv.Itoa(pageIndex+1)); err != nil {
+		return nil, err
+	}
+
+	file, err = os.Open(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open page image: %w", err)
+	}
+	defer file.Close()
+	return png.Decode(file)
+}
+
+// Bound returns the page size in points from mutool's structured text output
+func (d *containerDocument) Bound(pageIndex int) (image.Rectangle, error) {
+	output, err := d.mutool("draw", "-F", "stext", "-o", "-", d.path, strconv.Itoa(pageIndex+1))
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	matches := stextPagePattern.FindStringSubmatch(output)
+	if matches == nil {
+		return image.Rectangle{}, fmt.Errorf("no page size in mutool output")
+	}
+	width, _ := strconv.ParseFloat(matches[1], 64)
+	height, _ := strconv.ParseFloat(matches[2], 64)
+	return image.Rect(0, 0, int(width), int(height)), nil
+}
+
+// Bookmarks returns nil: the outline is not read in the container
+func (d *containerDocument) Bookmarks() []Bookmark {
+	return nil
+}
+
+// Close removes the copy of the PDF and its rendered pages
+func (d *containerDocument) Close() error {
+	return tempfile.Remove(d.dir)
+}
@@ -0,0 +1,237 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</tr>|</h[1-6]>|</li>`)
+	htmlTagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlSkipPattern  = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	blankRunPattern  = regexp.MustCompile(`\n{3,}`)
+)
+
+// Email represents a parsed email message
+type Email struct {
+	From        string
+	To          string
+	Cc          string
+	Date        string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Attachment represents a file attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// EmailProcessor handles .eml (MIME) and .msg (Outlook) parsing
+type EmailProcessor struct {
+	config config.ChunkerConfig
+}
+
+// NewEmailProcessor creates a new email processor instance
+func NewEmailProcessor(config config.ChunkerConfig) *EmailProcessor {
+	return &EmailProcessor{
+		config: config,
+	}
+}
+
+// ExtractEmailFromBytes parses an email, detecting Outlook .msg files by their signature
+func (p *EmailProcessor) ExtractEmailFromBytes(data []byte) (*Email, error) {
+	if IsCompoundFile(data) {
+		return p.ExtractEmailFromMSGBytes(data)
+	}
+	return p.ExtractEmailFromEMLBytes(data)
+}
+
+// ExtractEmailFromEMLBytes parses a MIME (.eml) message
+func (p *EmailProcessor) ExtractEmailFromEMLBytes(data []byte) (*Email, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email: %w", err)
+	}
+
+	email := &Email{
+		From:    decodeHeader(message.Header.Get("From")),
+		To:      decodeHeader(message.Header.Get("To")),
+		Cc:      decodeHeader(message.Header.Get("Cc")),
+		Date:    message.Header.Get("Date"),
+		Subject: decodeHeader(message.Header.Get("Subject")),
+	}
+
+	var plainBody, htmlBody string
+	err = p.walkPart(message.Header, message.Body, email, &plainBody, &htmlBody)
+	if err != nil {
+		return nil, err
+	}
+
+	email.Body = plainBody
+	if strings.TrimSpace(email.Body) == "" {
+		email.Body = HTMLToText(htmlBody)
+	}
+
+	return email, nil
+}
+
+// partHeader is the subset of MIME part headers used while walking a message
+type partHeader interface {
+	Get(key string) string
+}
+
+// walkPart collects body text and attachments from a MIME part, recursing into multiparts
+func (p *EmailProcessor) walkPart(header partHeader, body io.Reader, email *Email, plainBody, htmlBody *string) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read MIME part: %w", err)
+			}
+			if err := p.walkPart(part.Header, part, email, plainBody, htmlBody); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("failed to decode MIME part: %w", err)
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	switch {
+	case disposition == "attachment" || filename != "" || mediaType == "message/rfc822":
+		if filename == "" {
+			filename = "attachment"
+			if mediaType == "message/rfc822" {
+				filename += ".eml"
+			}
+		}
+		email.Attachments = append(email.Attachments, Attachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			Data:        content,
+		})
+	case mediaType == "text/plain" && *plainBody == "":
+		*plainBody = string(content)
+	case mediaType == "text/html" && *htmlBody == "":
+		*htmlBody = string(content)
+	}
+
+	return nil
+}
+
+// decodeTransferEncoding wraps a body reader with the decoder for its transfer encoding
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{reader: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// newlineStripper removes line breaks so base64 bodies decode cleanly
+type newlineStripper struct {
+	reader io.Reader
+}
+
+// Read implements io.Reader
+func (n *newlineStripper) Read(buf []byte) (int, error) {
+	count, err := n.reader.Read(buf)
+	kept := 0
+	for _, b := range buf[:count] {
+		if b != '\r' && b != '\n' {
+			buf[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
+
+// decodeHeader decodes RFC 2047 encoded words in a header value
+func decodeHeader(value string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// HTMLToText converts an HTML body to plain text
+func HTMLToText(body string) string {
+	text := htmlSkipPattern.ReplaceAllString(body, "")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = blankRunPattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// Text renders the email headers and body as plain text
+func (e *Email) Text() string {
+	var text strings.Builder
+
+	headers := []struct {
+		name  string
+		value string
+	}{
+		{"From", e.From},
+		{"To", e.To},
+		{"Cc", e.Cc},
+		{"Date", e.Date},
+		{"Subject", e.Subject},
+	}
+	for _, header := range headers {
+		if strings.TrimSpace(header.value) != "" {
+			text.WriteString(fmt.Sprintf("%s: %s\n", header.name, header.value))
+		}
+	}
+
+	if len(e.Attachments) > 0 {
+		names := make([]string, len(e.Attachments))
+		for i, attachment := range e.Attachments {
+			names[i] = attachment.Filename
+		}
+		text.WriteString(fmt.Sprintf("Attachments: %s\n", strings.Join(names, ", ")))
+	}
+
+	text.WriteString("\n")
+	text.WriteString(strings.TrimSpace(e.Body))
+	text.WriteString("\n")
+
+	return text.String()
+}
@@ -0,0 +1,30 @@
+package processor
+
+import (
+	"image/png"
+	"sync"
+)
+
+// pngBuffers pools the PNG encoder state, including its zlib writer and row buffers of
+// a page width, which would otherwise be allocated again for every rendered page
+type pngBuffers struct {
+	pool sync.Pool
+}
+
+// Get returns pooled encoder state, or nil for the encoder to allocate it
+func (b *pngBuffers) Get() *png.EncoderBuffer {
+	buffer, _ := b.pool.Get().(*png.EncoderBuffer)
+	return buffer
+}
+
+// Put returns encoder state to the pool
+func (b *pngBuffers) Put(buffer *png.EncoderBuffer) {
+	b.pool.Put(buffer)
+}
+
+// Encoders of rendered pages. OCR images are read once by tesseract and removed, so they
+// are compressed for speed rather than size.
+var (
+	pngEncoder    = &png.Encoder{BufferPool: &pngBuffers{}}
+	ocrPNGEncoder = &png.Encoder{CompressionLevel: png.BestSpeed, BufferPool: &pngBuffers{}}
+)
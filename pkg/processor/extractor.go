@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
+)
+
+// Built-in PDF engines named in DocumentReport.Engine
+const (
+	EngineMuPDF  = "mupdf"  // go-fitz, the default
+	EnginePureGo = "purego" // ledongthuc/pdf, in builds with the purego tag
+)
+
+// errUnreadablePDF is returned when the engine opens a PDF but cannot read any of its pages
+var errUnreadablePDF = errors.New("failed to extract text from any page")
+
+// TextExtractor is an alternative PDF backend, used in order when the built-in engine
+// cannot open or read a document. Fallback extraction has no OCR; pages come back as their text layer.
+type TextExtractor interface {
+	ExtractPages(pdfPath string) ([]string, error)
+	GetName() string
+}
+
+// Pdftotext implements TextExtractor with poppler's pdftotext command line tool
+type Pdftotext struct{}
+
+// NewPdftotext creates a pdftotext extractor
+func NewPdftotext() *Pdftotext {
+	return &Pdftotext{}
+}
+
+// ExtractPages runs pdftotext and splits its output at the form feeds between pages
+func (e *Pdftotext) ExtractPages(pdfPath string) ([]string, error) {
+	cmd := exec.Command("pdftotext", "-enc", "UTF-8", pdfPath, "-")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError("pdftotext command", err, stderr.String())
+	}
+
+	// Every page, including the last, ends with a form feed
+	pages := strings.Split(string(output), "\f")
+	if len(pages) > 1 && strings.TrimSpace(pages[len(pages)-1]) == "" {
+		pages = pages[:len(pages)-1]
+	}
+	return pages, nil
+}
+
+// GetName returns the extractor name
+func (e *Pdftotext) GetName() string {
+	return "pdftotext"
+}
+
+// commandError describes a failed external command with its output, if any
+func commandError(command string, err error, output string) error {
+	if message := strings.TrimSpace(output); message != "" {
+		return fmt.Errorf("%s failed: %w: %s", command, err, message)
+	}
+	return fmt.Errorf("%s failed: %w", command, err)
+}
+
+// recoverDocument handles a PDF that the built-in engine failed on: it tries each
+// repairer when RepairPDF is set, then each fallback extractor, and returns cause when
+// nothing succeeds
+func (p *PDFProcessor) recoverDocument(pdfPath string, cause error) (*Document, error) {
+	var failures []string
+	if p.config.RepairPDF {
+		document, repairFailures := p.extractRepaired(pdfPath, cause)
+		if document != nil {
+			return document, nil
+		}
+		failures = append(failures, repairFailures...)
+	}
+
+	for _, extractor := range p.fallbacks {
+		texts, err := extractor.ExtractPages(pdfPath)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", extractor.GetName(), err))
+			continue
+		}
+		if err := CheckPages(len(texts), p.config.MaxPages); err != nil {
+			return nil, err
+		}
+
+		p.logger.Printf("Warning: %s failed (%v), extracted with %s", p.engine(), cause, extractor.GetName())
+		return fallbackDocument(texts, extractor.GetName(), detectPDFAFromPath(pdfPath)), nil
+	}
+
+	if len(failures) == 0 {
+		return nil, cause
+	}
+	return nil, fmt.Errorf("%w (recovery failed: %s)", cause, strings.Join(failures, "; "))
+}
+
+// recoverBytes writes PDF binary data to a temp file for recoverDocument
+func (p *PDFProcessor) recoverBytes(data []byte, cause error) (*Document, error) {
+	if len(p.fallbacks) == 0 && !p.config.RepairPDF {
+		return nil, cause
+	}
+
+	file, err := tempfile.CreateTemp(p.config.TempDir, "recover-*.pdf")
+	if err != nil {
+		return nil, cause
+	}
+	defer tempfile.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, cause
+	}
+
+	return p.recoverDocument(file.Name(), cause)
+}
+
+// fallbackDocument builds a document from the page texts of a fallback extractor
+func fallbackDocument(texts []string, engine, pdfaConformance string) *Document {
+	pages := make([]PageReport, len(texts))
+	for i, text := range texts {
+		pages[i] = PageReport{Number: i + 1, Source: SourceText, Characters: len(strings.TrimSpace(text))}
+	}
+
+	return &Document{
+		Text:  joinPages(texts),
+		Pages: newPages(texts, pages),
+		Report: DocumentReport{
+			TotalPages:     len(texts),
+			Engine:         engine,
+			Classification: *newClassification(texts, pdfaConformance),
+			Pages:          pages,
+		},
+	}
+}
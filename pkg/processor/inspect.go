@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"archive/zip"
+	"fmt"
+)
+
+// Rough text yields used to estimate document size without extracting text
+const (
+	estimatedPDFPageChars = 2500 // A dense text page
+	officeMarkupRatio     = 5    // Bytes of slide/sheet XML per character of text
+)
+
+// DocumentSize is a cheap estimate of a document's size, read without extracting its text
+type DocumentSize struct {
+	Pages      int `json:"pages"`
+	Characters int `json:"characters"` // Estimated
+}
+
+// InspectPDFPath counts the pages of a PDF and estimates its text size
+func InspectPDFPath(pdfPath string) (DocumentSize, error) {
+	doc, err := openPDF(pdfPath)
+	if err != nil {
+		return DocumentSize{}, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	defer doc.Close()
+
+	pages := doc.NumPage()
+	return DocumentSize{Pages: pages, Characters: pages * estimatedPDFPageChars}, nil
+}
+
+// InspectPPTXPath counts the slides of a PPTX and estimates their text size
+func InspectPPTXPath(pptxPath string) (DocumentSize, error) {
+	return inspectOfficePath(pptxPath, "ppt/slides/slide")
+}
+
+// InspectXLSXPath counts the sheets of an XLSX and estimates their text size
+func InspectXLSXPath(xlsxPath string) (DocumentSize, error) {
+	return inspectOfficePath(xlsxPath, "xl/worksheets/sheet")
+}
+
+// inspectOfficePath counts the parts matching prefix and estimates text from their size
+func inspectOfficePath(officePath, prefix string) (DocumentSize, error) {
+	archive, err := zip.OpenReader(officePath)
+	if err != nil {
+		return DocumentSize{}, fmt.Errorf("failed to open office document: %w", err)
+	}
+	defer archive.Close()
+
+	parts := listOfficeParts(&archive.Reader, prefix)
+	var markup uint64
+	for _, file := range archive.File {
+		for _, part := range parts {
+			if file.Name == part {
+				markup += file.UncompressedSize64
+			}
+		}
+	}
+	return DocumentSize{Pages: len(parts), Characters: int(markup / officeMarkupRatio)}, nil
+}
@@ -0,0 +1,168 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Proportions of the font size above and below the baseline that a line's box covers
+const (
+	layoutAscent  = 0.8
+	layoutDescent = 0.2
+)
+
+// TextLine is a line of a page's text layer with its box, in points from the top left
+// corner of the page
+type TextLine struct {
+	Text   string
+	X0, Y0 float64 // Top left corner
+	X1, Y1 float64 // Bottom right corner
+}
+
+// PageLayout is the text layer of a page laid out in lines
+type PageLayout struct {
+	Number int     // 1-based
+	Width  float64 // Points
+	Height float64
+	Lines  []TextLine
+}
+
+// ReadLayout reads where the lines of the text layer of every page of a PDF are, with
+// the pure-Go reader in every build. Boxes need the glyph widths fonts carry, so lines
+// set in fonts without them, such as the standard fonts, are left out, as are rotated
+// pages and pages the reader cannot parse. Scanned pages have no lines.
+func ReadLayout(pdfPath string) ([]PageLayout, error) {
+	file, reader, err := pdf.Open(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF for layout: %w", err)
+	}
+	defer file.Close()
+
+	pages, err := numPages(reader)
+	if err != nil {
+		return nil, err
+	}
+	layouts := make([]PageLayout, 0, pages)
+	for number := 1; number <= pages; number++ {
+		if layout, ok := pageLayout(reader, number); ok {
+			layouts = append(layouts, layout)
+		}
+	}
+	return layouts, nil
+}
+
+// numPages returns the page count of a reader, which panics on malformed page trees
+func numPages(reader *pdf.Reader) (pages int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed page tree: %v", r)
+		}
+	}()
+	return reader.NumPage(), nil
+}
+
+// pageLayout lays out the text of a page in lines, or reports false when the page cannot
+// be laid out
+func pageLayout(reader *pdf.Reader, number int) (layout PageLayout, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	page := reader.Page(number)
+	if page.V.IsNull() || inherited(page.V, "Rotate").Int64()%360 != 0 {
+		return PageLayout{}, false
+	}
+	box := inherited(page.V, "MediaBox")
+	if box.Len() != 4 {
+		return PageLayout{}, false
+	}
+	left, bottom := box.Index(0).Float64(), box.Index(1).Float64()
+	layout = PageLayout{Number: number, Width: box.Index(2).Float64() - left, Height: box.Index(3).Float64() - bottom}
+	if layout.Width <= 0 || layout.Height <= 0 {
+		return PageLayout{}, false
+	}
+
+	var line lineBuilder
+	flush := func() {
+		if text, x0, x1, baseline, size, ok := line.finish(); ok {
+			layout.Lines = append(layout.Lines, TextLine{
+				Text: text,
+				X0:   x0 - left,
+				Y0:   layout.Height - (baseline - bottom + size*layoutAscent),
+				X1:   x1 - left,
+				Y1:   layout.Height - (baseline - bottom - size*layoutDescent),
+			})
+		}
+		line = lineBuilder{}
+	}
+	for _, glyph := range page.Content().Text {
+		if !line.fits(glyph) {
+			flush()
+		}
+		line.add(glyph)
+	}
+	flush()
+	return layout, true
+}
+
+// inherited returns a key of a page dictionary, or of the page tree node it inherits it from
+func inherited(page pdf.Value, key string) pdf.Value {
+	for node := page; !node.IsNull(); node = node.Key("Parent") {
+		if value := node.Key(key); !value.IsNull() {
+			return value
+		}
+	}
+	return pdf.Value{}
+}
+
+// lineBuilder collects the glyphs of a line in content order
+type lineBuilder struct {
+	text     strings.Builder
+	glyphs   int
+	x0, x1   float64
+	baseline float64
+	size     float64
+	unsized  bool // A glyph had no width, so the box would be wrong
+}
+
+// fits reports whether a glyph continues the line: on its baseline, and neither before
+// its end nor across a gap wide enough to be the gutter between columns
+func (l *lineBuilder) fits(glyph pdf.Text) bool {
+	if l.glyphs == 0 {
+		return true
+	}
+	size := math.Max(l.size, glyph.FontSize)
+	return math.Abs(glyph.Y-l.baseline) <= size*0.3 && glyph.X >= l.x1-size*0.5 && glyph.X-l.x1 <= size*3
+}
+
+// add appends a glyph to the line, with a space before it when it is set apart from the
+// previous one without one
+func (l *lineBuilder) add(glyph pdf.Text) {
+	if l.glyphs == 0 {
+		l.x0, l.x1, l.baseline = glyph.X, glyph.X, glyph.Y
+	} else if glyph.X-l.x1 > glyph.FontSize*0.15 && !strings.HasSuffix(l.text.String(), " ") && glyph.S != " " {
+		l.text.WriteByte(' ')
+	}
+	l.text.WriteString(glyph.S)
+	l.glyphs++
+	l.x0 = math.Min(l.x0, glyph.X)
+	l.x1 = math.Max(l.x1, glyph.X+glyph.W)
+	l.size = math.Max(l.size, glyph.FontSize)
+	if glyph.W <= 0 && strings.TrimSpace(glyph.S) != "" {
+		l.unsized = true
+	}
+}
+
+// finish returns the text and box of the line, or false for empty lines and lines whose
+// box is unknown
+func (l *lineBuilder) finish() (text string, x0, x1, baseline, size float64, ok bool) {
+	text = strings.TrimSpace(l.text.String())
+	if text == "" || l.unsized || l.x1 <= l.x0 {
+		return "", 0, 0, 0, 0, false
+	}
+	return text, l.x0, l.x1, l.baseline, l.size, true
+}
@@ -0,0 +1,107 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Errors wrapped by LimitError, for use with errors.Is
+var (
+	ErrFileTooLarge      = errors.New("file too large")
+	ErrTooManyPages      = errors.New("too many pages")
+	ErrProcessingTimeout = errors.New("processing time limit exceeded")
+)
+
+// ErrOCRTimeout is recorded in PageReport.OCRError for pages whose OCR was killed after
+// OCRPageTimeout
+var ErrOCRTimeout = errors.New("OCR page time limit exceeded")
+
+// LimitError reports a document rejected by MaxFileSizeMB, MaxPages or MaxProcessingTime
+type LimitError struct {
+	Err    error  // ErrFileTooLarge, ErrTooManyPages or ErrProcessingTimeout
+	Actual string // e.g. "812 pages"; empty when unknown
+	Limit  string // e.g. "500 pages"
+}
+
+// Error describes the exceeded limit
+func (e *LimitError) Error() string {
+	if e.Actual == "" {
+		return fmt.Sprintf("%v: limit is %s", e.Err, e.Limit)
+	}
+	return fmt.Sprintf("%v: %s, limit is %s", e.Err, e.Actual, e.Limit)
+}
+
+// Unwrap returns the sentinel error
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// CheckPages fails with ErrTooManyPages when pages exceeds maxPages; 0 is unlimited
+func CheckPages(pages, maxPages int) error {
+	if maxPages <= 0 || pages <= maxPages {
+		return nil
+	}
+	return &LimitError{Err: ErrTooManyPages, Actual: fmt.Sprintf("%d pages", pages), Limit: fmt.Sprintf("%d pages", maxPages)}
+}
+
+// CheckSize fails with ErrFileTooLarge when size exceeds maxMB megabytes; 0 is unlimited
+func CheckSize(size int64, maxMB int) error {
+	if maxMB <= 0 || size <= int64(maxMB)<<20 {
+		return nil
+	}
+	return &LimitError{Err: ErrFileTooLarge, Actual: formatMB(size), Limit: fmt.Sprintf("%d MB", maxMB)}
+}
+
+// CheckDeadline fails with ErrProcessingTimeout once deadline has passed; a zero
+// deadline never expires. limit is only used in the error message.
+func CheckDeadline(deadline time.Time, limit time.Duration) error {
+	if deadline.IsZero() || time.Now().Before(deadline) {
+		return nil
+	}
+	return &LimitError{Err: ErrProcessingTimeout, Limit: limit.String()}
+}
+
+// LimitReader returns a reader that fails with ErrFileTooLarge once more than maxMB
+// megabytes are read; 0 returns reader unchanged
+func LimitReader(reader io.Reader, maxMB int) io.Reader {
+	if maxMB <= 0 {
+		return reader
+	}
+	return &limitedReader{reader: reader, remaining: int64(maxMB) << 20, maxMB: maxMB}
+}
+
+// limitedReader is the reader returned by LimitReader
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+	maxMB     int
+}
+
+// Read reads from the underlying reader until the limit is passed
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, r.err()
+	}
+	// Read one byte past the limit to tell an exact fit from an oversized input
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, r.err()
+	}
+	return n, err
+}
+
+// err reports the exceeded limit; the full size is unknown as the rest is never read
+func (r *limitedReader) err() error {
+	return &LimitError{Err: ErrFileTooLarge, Limit: fmt.Sprintf("%d MB", r.maxMB)}
+}
+
+// formatMB formats a byte count in megabytes
+func formatMB(size int64) string {
+	return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+}
@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// MAPI property tags used when reading Outlook .msg files
+const (
+	msgPropSubject      = "0037"
+	msgPropSenderName   = "0C1A"
+	msgPropSenderEmail  = "0C1F"
+	msgPropDisplayTo    = "0E04"
+	msgPropDisplayCc    = "0E03"
+	msgPropBody         = "1000"
+	msgPropHTMLBody     = "1013"
+	msgPropAttachData   = "3701"
+	msgPropAttachName   = "3704"
+	msgPropAttachLong   = "3707"
+	msgPropAttachMime   = "370E"
+	msgAttachmentPrefix = "__attach_version1.0_"
+	msgPropertyPrefix   = "__substg1.0_"
+)
+
+// ExtractEmailFromMSGBytes parses an Outlook .msg (Compound File) message
+func (p *EmailProcessor) ExtractEmailFromMSGBytes(data []byte) (*Email, error) {
+	cf, err := openCompoundFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MSG file: %w", err)
+	}
+
+	root := cf.children(0)
+	email := &Email{
+		Subject: msgString(cf, root, msgPropSubject),
+		To:      msgString(cf, root, msgPropDisplayTo),
+		Cc:      msgString(cf, root, msgPropDisplayCc),
+		Body:    msgString(cf, root, msgPropBody),
+	}
+
+	email.From = msgString(cf, root, msgPropSenderName)
+	if address := msgString(cf, root, msgPropSenderEmail); address != "" && address != email.From {
+		email.From = strings.TrimSpace(fmt.Sprintf("%s <%s>", email.From, address))
+	}
+
+	if strings.TrimSpace(email.Body) == "" {
+		email.Body = HTMLToText(msgString(cf, root, msgPropHTMLBody))
+	}
+
+	// Attachment storages are numbered (__attach_version1.0_#00000000); keep their order
+	var attachmentNames []string
+	for name, id := range root {
+		if strings.HasPrefix(name, msgAttachmentPrefix) && cf.entries[id].entryType == cfbTypeStorage {
+			attachmentNames = append(attachmentNames, name)
+		}
+	}
+	sort.Strings(attachmentNames)
+
+	for _, name := range attachmentNames {
+		id := root[name]
+
+		attachment := cf.children(id)
+		filename := msgString(cf, attachment, msgPropAttachLong)
+		if filename == "" {
+			filename = msgString(cf, attachment, msgPropAttachName)
+		}
+
+		content, _ := msgProperty(cf, attachment, msgPropAttachData)
+		if content == nil {
+			// Embedded messages and OLE objects are stored as sub-storages; skip them
+			continue
+		}
+
+		email.Attachments = append(email.Attachments, Attachment{
+			Filename:    filename,
+			ContentType: msgString(cf, attachment, msgPropAttachMime),
+			Data:        content,
+		})
+	}
+
+	return email, nil
+}
+
+// msgProperty returns the raw value and type suffix of a property stream in a storage
+func msgProperty(cf *compoundFile, storage map[string]uint32, tag string) ([]byte, string) {
+	for _, propType := range []string{"001F", "001E", "0102"} {
+		id, ok := storage[msgPropertyPrefix+tag+propType]
+		if !ok || cf.entries[id].entryType != cfbTypeStream {
+			continue
+		}
+		data, err := cf.stream(cf.entries[id])
+		if err != nil {
+			return nil, ""
+		}
+		return data, propType
+	}
+	return nil, ""
+}
+
+// msgString decodes a string property (Unicode, 8-bit or binary)
+func msgString(cf *compoundFile, storage map[string]uint32, tag string) string {
+	data, propType := msgProperty(cf, storage, tag)
+	if data == nil {
+		return ""
+	}
+
+	if propType == "001F" {
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = uint16(data[i*2]) | uint16(data[i*2+1])<<8
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	}
+	return strings.TrimRight(string(data), "\x00")
+}
@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// officeRelationship is a single entry of an OOXML .rels part
+type officeRelationship struct {
+	ID     string `xml:"Id,attr"`
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+var partNumberPattern = regexp.MustCompile(`(\d+)\.xml$`)
+
+// openOfficeArchive opens an OOXML package from memory
+func openOfficeArchive(data []byte) (*zip.Reader, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open office document: %w", err)
+	}
+	return archive, nil
+}
+
+// readOfficePart returns the contents of a part, or nil if it does not exist
+func readOfficePart(archive *zip.Reader, name string) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer reader.Close()
+
+		return io.ReadAll(reader)
+	}
+	return nil, nil
+}
+
+// readOfficeRelationships resolves the relationships of a part to absolute part names keyed by ID
+func readOfficeRelationships(archive *zip.Reader, partName string) (map[string]officeRelationship, error) {
+	relsName := path.Join(path.Dir(partName), "_rels", path.Base(partName)+".rels")
+	data, err := readOfficePart(archive, relsName)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var rels struct {
+		Relationships []officeRelationship `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", relsName, err)
+	}
+
+	resolved := make(map[string]officeRelationship, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			rel.Target = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			rel.Target = path.Join(path.Dir(partName), rel.Target)
+		}
+		resolved[rel.ID] = rel
+	}
+	return resolved, nil
+}
+
+// listOfficeParts returns the parts in a directory matching a prefix, ordered by their trailing number
+func listOfficeParts(archive *zip.Reader, prefix string) []string {
+	var names []string
+	for _, file := range archive.File {
+		if strings.HasPrefix(file.Name, prefix) && path.Dir(file.Name) == path.Dir(prefix) &&
+			strings.HasSuffix(file.Name, ".xml") {
+			names = append(names, file.Name)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return partNumber(names[i]) < partNumber(names[j])
+	})
+	return names
+}
+
+// partNumber extracts the trailing number of a part name such as slide12.xml
+func partNumber(name string) int {
+	matches := partNumberPattern.FindStringSubmatch(name)
+	if len(matches) < 2 {
+		return 0
+	}
+	number, _ := strconv.Atoi(matches[1])
+	return number
+}
+
+// extractDrawingParagraphs returns the text of every DrawingML paragraph (<a:p>) in a part
+func extractDrawingParagraphs(data []byte) ([]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var paragraphs []string
+	var current strings.Builder
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse slide XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "br":
+				current.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if text := strings.TrimSpace(current.String()); text != "" {
+					paragraphs = append(paragraphs, text)
+				}
+				current.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		}
+	}
+
+	return paragraphs, nil
+}
+
+// parseInt parses a trimmed integer, reporting whether the string was a number
+func parseInt(s string) (int, bool) {
+	number, err := strconv.Atoi(strings.TrimSpace(s))
+	return number, err == nil
+}
@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// DefaultPageImageDPI is the render resolution of page images when none is configured
+const DefaultPageImageDPI = 150
+
+// imageQuality is the JPEG quality of page images and thumbnails
+const imageQuality = 85
+
+// PageImageName returns the file name of the image of a page in a format, one of
+// config.ImagePNG and config.ImageJPEG
+func PageImageName(pageNumber int, format string) string {
+	return fmt.Sprintf("page_%d%s", pageNumber, ImageExt(format))
+}
+
+// ImageExt returns the file extension of an image format, one of config.ImagePNG and
+// config.ImageJPEG
+func ImageExt(format string) string {
+	if format == config.ImageJPEG {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// addPageImages renders every page to the PageImageDir of the options and lists the
+// images in the report, or warns when they cannot be written
+func (p *PDFProcessor) addPageImages(doc pdfDocument, report *DocumentReport) {
+	images, err := p.writePageImages(doc, p.options.PageImageDir)
+	if err != nil {
+		p.logger.Printf("Warning: failed to write page images: %v", err)
+		return
+	}
+	report.PageImages = images
+}
+
+// writePageImages renders every page at PageImageDPI, or lower for pages over
+// MaxRenderPixels, into dir, replacing the images of an earlier run at once, and returns
+// their paths with / separators in page order
+func (p *PDFProcessor) writePageImages(doc pdfDocument, dir string) ([]string, error) {
+	dpi := p.config.PageImageDPI
+	if dpi <= 0 {
+		dpi = DefaultPageImageDPI
+	}
+
+	var images []string
+	err := utils.WriteDirAtomic(dir, func(staging string) error {
+		for pageIndex := 0; pageIndex < doc.NumPage(); pageIndex++ {
+			if err := p.checkDeadline(); err != nil {
+				return err
+			}
+			img, _, err := p.renderPage(doc, pageIndex, dpi)
+			if err != nil {
+				return fmt.Errorf("failed to render page %d: %w", pageIndex+1, err)
+			}
+			name := PageImageName(pageIndex+1, p.config.PageImageFormat)
+			if err := p.savePageImage(img, filepath.Join(staging, name)); err != nil {
+				return err
+			}
+			images = append(images, filepath.ToSlash(filepath.Join(dir, name)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// savePageImage encodes a page image in the PageImageFormat
+func (p *PDFProcessor) savePageImage(img image.Image, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create page image: %w", err)
+	}
+	defer file.Close()
+
+	if err := encodeImage(file, img, p.config.PageImageFormat); err != nil {
+		return fmt.Errorf("failed to encode page image: %w", err)
+	}
+	return nil
+}
+
+// encodeImage writes an image in a format, one of config.ImagePNG and config.ImageJPEG
+func encodeImage(w io.Writer, img image.Image, format string) error {
+	if format == config.ImageJPEG {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: imageQuality})
+	}
+	return pngEncoder.Encode(w, img)
+}
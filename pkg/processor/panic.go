@@ -0,0 +1,49 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is a panic recovered while processing a document or a page, returned as an
+// error so one malformed input fails on its own instead of ending the process. Crashes
+// inside MuPDF's C code are not panics and still end it.
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack string // Stack of the panicking goroutine
+}
+
+// Error returns the panic value
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value when it is an error, such as a runtime.Error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// RecoverPanic stores a panic of the calling function in *err as a PanicError. Defer it
+// directly, in the goroutine that may panic:
+//
+//	func work() (err error) {
+//		defer processor.RecoverPanic(&err)
+//		...
+//	}
+func RecoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: string(debug.Stack())}
+	}
+}
+
+// PanicStack returns the stack of the PanicError in err's chain, or "" when err is not
+// a recovered panic
+func PanicStack(err error) string {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		return panicErr.Stack
+	}
+	return ""
+}
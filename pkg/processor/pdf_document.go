@@ -0,0 +1,21 @@
+package processor
+
+import "image"
+
+// pdfDocument is an open PDF: go-fitz (MuPDF) by default, or a pure-Go reader in builds
+// with the purego tag
+type pdfDocument interface {
+	NumPage() int
+	Text(pageIndex int) (string, error)
+	ImageDPI(pageIndex int, dpi float64) (image.Image, error)
+	Bound(pageIndex int) (image.Rectangle, error) // Page size in points
+	Bookmarks() []Bookmark
+	Close() error
+}
+
+// Bookmark is an entry of the outline a PDF carries, shown as bookmarks by viewers
+type Bookmark struct {
+	Level int    `json:"level"` // Starting at 1
+	Title string `json:"title"`
+	Page  int    `json:"page,omitempty"` // 1-based; 0 when the engine cannot resolve the destination
+}
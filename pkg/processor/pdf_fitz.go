@@ -0,0 +1,57 @@
+//go:build !purego
+
+package processor
+
+import (
+	"image"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// defaultEngine is the engine that reads PDFs before any fallback
+const defaultEngine = EngineMuPDF
+
+// renderSupported reports whether pages can be rendered for OCR and searchable PDFs
+const renderSupported = true
+
+// fitzDocument adapts a go-fitz document to pdfDocument
+type fitzDocument struct {
+	*fitz.Document
+}
+
+// ImageDPI renders a page
+func (d fitzDocument) ImageDPI(pageIndex int, dpi float64) (image.Image, error) {
+	return d.Document.ImageDPI(pageIndex, dpi)
+}
+
+// Bookmarks returns the outline of the document, or nil when it has none
+func (d fitzDocument) Bookmarks() []Bookmark {
+	entries, err := d.Document.ToC()
+	if err != nil {
+		return nil
+	}
+	bookmarks := make([]Bookmark, 0, len(entries))
+	for _, entry := range entries {
+		// Pages are 0-based, and -1 for links outside the document
+		bookmarks = append(bookmarks, Bookmark{Level: entry.Level, Title: entry.Title, Page: max(entry.Page+1, 0)})
+	}
+	return bookmarks
+}
+
+// openPDF opens a PDF file
+func openPDF(pdfPath string) (pdfDocument, error) {
+	doc, err := fitz.New(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+	return fitzDocument{doc}, nil
+}
+
+// openPDFMemory opens PDF binary data
+func openPDFMemory(data []byte) (pdfDocument, error) {
+	doc, err := fitz.NewFromMemory(data)
+	if err != nil {
+		return nil, err
+	}
+	return fitzDocument{doc}, nil
+}
@@ -1,125 +1,542 @@
 package processor
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"image"
-	"image/png"
 	"io"
 	"log"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
-	"github.com/gen2brain/go-fitz"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/container"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ocr"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
 )
 
+// DefaultOCRDPI is the render resolution used when no DPI is configured
+const DefaultOCRDPI = 300
+
+// ExtractOptions holds per-document extraction settings
+type ExtractOptions struct {
+	OCRDPI            float64               // Render resolution for OCR pages (e.g. 300–400)
+	SearchablePDFPath string                // When set, also write a searchable PDF (page images with an OCR text layer) here
+	PageImageDir      string                // When set, also render every page to an image here, see PageImages
+	Thumbnail         bool                  // Also render a thumbnail of the first page into the report, see Thumbnail
+	Deadline          time.Time             // When set, fail with ErrProcessingTimeout once passed (see MaxProcessingTime)
+	OnPage            func(page, pages int) // When set, called once the text of a page is final, with OCR if the page needed it; OCR workers call it concurrently
+	Context           context.Context       // When set, fail with its error once it is done, killing OCR in progress
+}
+
+// Logger receives extraction warnings; *log.Logger satisfies it
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
 // PDFProcessor handles PDF text extraction with OCR fallback
 type PDFProcessor struct {
-	config config.ChunkerConfig
+	config    config.ChunkerConfig
+	options   ExtractOptions
+	ocrEngine ocr.Engine
+	container *container.Container // Set with WithContainer
+	fallbacks []TextExtractor
+	repairers []Repairer
+	logger    Logger
 }
 
 // NewPDFProcessor creates a new PDF processor instance
 func NewPDFProcessor(config config.ChunkerConfig) *PDFProcessor {
 	return &PDFProcessor{
 		config: config,
+		options: ExtractOptions{
+			OCRDPI: config.OCRDPI,
+		},
+		ocrEngine: ocr.NewTesseract(config.OCRLanguages, config.OCRAutoDetect).WithBinary(config.TesseractPath),
+		fallbacks: []TextExtractor{NewPdftotext()},
+		repairers: DefaultRepairers(),
+		logger:    log.Default(),
 	}
 }
 
+// WithOCREngine returns a copy of the processor that recognizes pages with engine
+func (p *PDFProcessor) WithOCREngine(engine ocr.Engine) *PDFProcessor {
+	clone := *p
+	clone.ocrEngine = engine
+	return &clone
+}
+
+// WithFallbackExtractors returns a copy of the processor that tries extractors in order
+// when MuPDF cannot open or read a document (default: pdftotext); none disables fallback
+func (p *PDFProcessor) WithFallbackExtractors(extractors ...TextExtractor) *PDFProcessor {
+	clone := *p
+	clone.fallbacks = extractors
+	return &clone
+}
+
+// WithRepairers returns a copy of the processor that tries repairers in order on PDFs the
+// built-in engine cannot open or read, when RepairPDF is set (default: DefaultRepairers())
+func (p *PDFProcessor) WithRepairers(repairers ...Repairer) *PDFProcessor {
+	clone := *p
+	clone.repairers = repairers
+	return &clone
+}
+
+// WithLogger returns a copy of the processor that reports warnings to logger
+func (p *PDFProcessor) WithLogger(logger Logger) *PDFProcessor {
+	clone := *p
+	clone.logger = logger
+	return &clone
+}
+
+// WithOptions returns a copy of the processor that overrides the extraction options for a single document
+func (p *PDFProcessor) WithOptions(options ExtractOptions) *PDFProcessor {
+	clone := *p
+	if options.OCRDPI > 0 {
+		clone.options.OCRDPI = options.OCRDPI
+	}
+	if options.SearchablePDFPath != "" {
+		clone.options.SearchablePDFPath = options.SearchablePDFPath
+	}
+	if options.PageImageDir != "" {
+		clone.options.PageImageDir = options.PageImageDir
+	}
+	if options.Thumbnail {
+		clone.options.Thumbnail = true
+	}
+	if !options.Deadline.IsZero() {
+		clone.options.Deadline = options.Deadline
+	}
+	if options.OnPage != nil {
+		clone.options.OnPage = options.OnPage
+	}
+	if options.Context != nil {
+		clone.options.Context = options.Context
+	}
+	return &clone
+}
+
 // ExtractTextFromPDFPath extracts text from a PDF file path
 func (p *PDFProcessor) ExtractTextFromPDFPath(pdfPath string) (string, error) {
-	doc, err := fitz.New(pdfPath)
+	document, err := p.ExtractDocumentFromPDFPath(pdfPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open PDF: %w", err)
+		return "", err
 	}
-	defer doc.Close()
-
-	return p.extractTextFromDocument(doc)
+	return document.Text, nil
 }
 
 // ExtractTextFromPDFBytes extracts text from PDF binary data
 func (p *PDFProcessor) ExtractTextFromPDFBytes(data []byte) (string, error) {
-	doc, err := fitz.NewFromMemory(data)
+	document, err := p.ExtractDocumentFromPDFBytes(data)
 	if err != nil {
-		return "", fmt.Errorf("failed to open PDF from memory: %w", err)
+		return "", err
 	}
-	defer doc.Close()
-
-	return p.extractTextFromDocument(doc)
+	return document.Text, nil
 }
 
 // ExtractTextFromPDFReader extracts text from PDF reader
 func (p *PDFProcessor) ExtractTextFromPDFReader(reader io.Reader) (string, error) {
-	data, err := io.ReadAll(reader)
+	document, err := p.ExtractDocumentFromPDFReader(reader)
 	if err != nil {
-		return "", fmt.Errorf("failed to read PDF data: %w", err)
+		return "", err
 	}
+	return document.Text, nil
+}
 
-	return p.ExtractTextFromPDFBytes(data)
+// ExtractDocumentFromPDFPath extracts text and an extraction report from a PDF file path
+func (p *PDFProcessor) ExtractDocumentFromPDFPath(pdfPath string) (*Document, error) {
+	doc, err := p.openPDF(pdfPath)
+	if err != nil {
+		return p.recoverDocument(pdfPath, fmt.Errorf("failed to open PDF: %w", err))
+	}
+	defer doc.Close()
+
+	document, err := p.extractDocument(doc, detectPDFAFromPath(pdfPath))
+	if errors.Is(err, errUnreadablePDF) {
+		return p.recoverDocument(pdfPath, err)
+	}
+	return document, err
 }
 
-// extractTextFromDocument extracts text from a fitz document
-func (p *PDFProcessor) extractTextFromDocument(doc *fitz.Document) (string, error) {
-	var result strings.Builder
+// ExtractDocumentFromPDFBytes extracts text and an extraction report from PDF binary data
+func (p *PDFProcessor) ExtractDocumentFromPDFBytes(data []byte) (*Document, error) {
+	doc, err := p.openPDFMemory(data)
+	if err != nil {
+		return p.recoverBytes(data, fmt.Errorf("failed to open PDF from memory: %w", err))
+	}
+	defer doc.Close()
+
+	document, err := p.extractDocument(doc, detectPDFA(bytes.NewReader(data)))
+	if errors.Is(err, errUnreadablePDF) {
+		return p.recoverBytes(data, err)
+	}
+	return document, err
+}
+
+// ExtractDocumentFromPDFReader extracts text and an extraction report from PDF reader.
+// Readers larger than ReaderSpillSize are spooled to a temp file and opened from disk.
+func (p *PDFProcessor) ExtractDocumentFromPDFReader(reader io.Reader) (*Document, error) {
+	input, cleanup, err := spillReader(reader, p.config.ReaderSpillSize, p.config.TempDir)
+	defer cleanup()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF data: %w", err)
+	}
+
+	if input.path != "" {
+		return p.ExtractDocumentFromPDFPath(input.path)
+	}
+	return p.ExtractDocumentFromPDFBytes(input.data)
+}
+
+// extractDocument extracts text from an open document, choosing the extraction
+// strategy from the preflight classification
+func (p *PDFProcessor) extractDocument(doc pdfDocument, pdfaConformance string) (_ *Document, err error) {
+	defer RecoverPanic(&err)
 	totalPages := doc.NumPage()
+	if err := CheckPages(totalPages, p.config.MaxPages); err != nil {
+		return nil, err
+	}
+	texts := make([]string, totalPages)
+	pages := make([]PageReport, totalPages)
 
+	// Preflight: read the direct text layer of every page
+	failedPages := 0
 	for pageIndex := 0; pageIndex < totalPages; pageIndex++ {
-		text, err := p.processPage(doc, pageIndex, totalPages)
+		if err := p.checkDeadline(); err != nil {
+			return nil, err
+		}
+		pages[pageIndex].Number = pageIndex + 1
+		pages[pageIndex].Source = SourceText
+
+		text, err := pageText(doc, pageIndex)
 		if err != nil {
-			log.Printf("Warning: failed to process page %d: %v", pageIndex+1, err)
-			continue
+			p.logger.Printf("Warning: failed to extract text from page %d: %v", pageIndex+1, err)
+			pages[pageIndex].TextError = err.Error()
+			pages[pageIndex].Stack = PanicStack(err)
+			failedPages++
+		}
+		texts[pageIndex] = text
+	}
+	if failedPages == totalPages {
+		return nil, errUnreadablePDF
+	}
+
+	classification := newClassification(texts, pdfaConformance)
+
+	var ocrPages []int
+	for pageIndex, text := range texts {
+		switch {
+		case classification.Class == ClassScanned:
+			// Scanned documents only carry stray text (stamps, page numbers); OCR every page
+			ocrPages = append(ocrPages, pageIndex)
+		case strings.TrimSpace(text) == "":
+			// Hybrid documents fall back to OCR per image-only page
+			ocrPages = append(ocrPages, pageIndex)
+		}
+	}
+
+	if len(ocrPages) > 0 && !p.rendersPages() {
+		p.warnOCRUnavailable(len(ocrPages))
+		ocrPages = nil
+	}
+	// Pages kept from the text layer are final now; OCR pages once they are recognized
+	ocrPage := make(map[int]bool, len(ocrPages))
+	for _, pageIndex := range ocrPages {
+		ocrPage[pageIndex] = true
+	}
+	for pageIndex := range texts {
+		if !ocrPage[pageIndex] {
+			p.pageDone(pageIndex, totalPages)
+		}
+	}
+	if len(ocrPages) > 0 {
+		if err := p.extractPagesWithOCR(doc, ocrPages, texts, pages); err != nil {
+			return nil, err
 		}
+	}
+
+	for pageIndex, text := range texts {
+		pages[pageIndex].Characters = len(strings.TrimSpace(text))
+	}
+
+	report := DocumentReport{
+		TotalPages:     totalPages,
+		OCRPages:       len(ocrPages),
+		Engine:         p.engine(),
+		Classification: *classification,
+		Pages:          pages,
+		Bookmarks:      doc.Bookmarks(),
+	}
+
+	if p.options.SearchablePDFPath != "" {
+		if err := p.writeSearchablePDF(doc, p.options.SearchablePDFPath); err != nil {
+			p.logger.Printf("Warning: failed to write searchable PDF: %v", err)
+		} else {
+			report.SearchablePDF = p.options.SearchablePDFPath
+		}
+	}
+	if p.options.PageImageDir != "" {
+		p.addPageImages(doc, &report)
+	}
+	if p.options.Thumbnail {
+		p.addThumbnail(doc, &report)
+	}
+
+	return &Document{
+		Text:   joinPages(texts),
+		Pages:  newPages(texts, pages),
+		Report: report,
+	}, nil
+}
+
+// pageText reads the direct text layer of a page, returning a panic of the reader as a
+// PanicError so the other pages are still read
+func pageText(doc pdfDocument, pageIndex int) (_ string, err error) {
+	defer RecoverPanic(&err)
+	return doc.Text(pageIndex)
+}
+
+// joinPages joins page texts with "--- Page N ---" separators
+func joinPages(texts []string) string {
+	var result strings.Builder
+	for pageIndex, text := range texts {
+		// Add page separator
+		result.WriteString(fmt.Sprintf("\n\n--- Page %d ---\n\n", pageIndex+1))
 		result.WriteString(text)
 	}
+	return result.String()
+}
+
+// writeSearchablePDF renders every page and writes them with an invisible OCR text layer
+func (p *PDFProcessor) writeSearchablePDF(doc pdfDocument, outputPath string) error {
+	renderer, ok := p.ocrEngine.(ocr.PDFRenderer)
+	if !ok {
+		return fmt.Errorf("OCR engine %s cannot render PDFs", p.ocrEngine.GetName())
+	}
+
+	tempDir, err := tempfile.MkdirTemp(p.config.TempDir, "searchable-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer tempfile.Remove(tempDir)
+
+	var imagePaths []string
+	for pageIndex := 0; pageIndex < doc.NumPage(); pageIndex++ {
+		img, _, err := p.renderPage(doc, pageIndex, p.ocrDPI())
+		if err != nil {
+			return fmt.Errorf("failed to render page %d: %w", pageIndex+1, err)
+		}
+
+		imagePath := filepath.Join(tempDir, fmt.Sprintf("page_%d.png", pageIndex))
+		if err := p.saveTemporaryImage(img, imagePath); err != nil {
+			return err
+		}
+		imagePaths = append(imagePaths, imagePath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
 
-	return result.String(), nil
+	return renderer.RenderPDF(imagePaths, outputPath)
 }
 
-// processPage extracts text from a single page
-func (p *PDFProcessor) processPage(doc *fitz.Document, pageIndex, totalPages int) (string, error) {
-	pageNum := pageIndex + 1
+// extractPagesWithOCR runs OCR on the given pages with a bounded number of workers,
+// storing each result and page report at its page index. OCR still running at the
+// document deadline or once the document's context is done is killed.
+func (p *PDFProcessor) extractPagesWithOCR(doc pdfDocument, pageIndexes []int, texts []string, pages []PageReport) error {
+	ctx := context.Background()
+	if p.options.Context != nil {
+		ctx = p.options.Context
+	}
+	if !p.options.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, p.options.Deadline)
+		defer cancel()
+	}
+
+	// Isolate temp images per document so concurrent runs never collide
+	tempDir, err := tempfile.MkdirTemp(p.config.TempDir, "ocr-")
+	if err != nil {
+		return fmt.Errorf("failed to create OCR temp directory: %w", err)
+	}
+	defer tempfile.Remove(tempDir)
+
+	workers := p.config.OCRWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	threshold := p.config.OCRLowConfidence
+	if threshold <= 0 {
+		threshold = DefaultLowConfidence
+	}
+
+	// Once tesseract turns out to be missing, the other pages are not tried: one warning
+	// says how to install it instead of one per page
+	var missing atomic.Bool
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer tempfile.CleanupOnPanic()
+			for pageIndex := range jobs {
+				// Drain the remaining pages once the deadline has passed
+				if p.checkDeadline() != nil {
+					continue
+				}
+				p.ocrPage(ctx, doc, tempDir, pageIndex, texts, pages, threshold, &missing, len(pageIndexes))
+				p.pageDone(pageIndex, len(texts))
+			}
+		}()
+	}
+
+	for _, pageIndex := range pageIndexes {
+		jobs <- pageIndex
+	}
+	close(jobs)
+	wg.Wait()
+
+	return p.checkDeadline()
+}
 
-	// Try direct text extraction first
-	text, err := doc.Text(pageIndex)
+// ocrPage recognizes one page of extractPagesWithOCR, keeping whatever direct text the
+// page had when OCR fails or finds nothing
+func (p *PDFProcessor) ocrPage(ctx context.Context, doc pdfDocument, tempDir string, pageIndex int, texts []string, pages []PageReport, threshold float64, missing *atomic.Bool, ocrPages int) {
+	pages[pageIndex].OCR = true
+	if missing.Load() {
+		pages[pageIndex].OCRError = ocr.ErrTesseractNotFound.Error()
+		return
+	}
+
+	result, err := p.extractTextWithOCR(ctx, doc, tempDir, pageIndex, &pages[pageIndex])
 	if err != nil {
-		log.Printf("Warning: failed to extract text from page %d: %v", pageNum, err)
+		pages[pageIndex].OCRError = err.Error()
+		pages[pageIndex].Stack = PanicStack(err)
+		if !errors.Is(err, ocr.ErrTesseractNotFound) {
+			p.logger.Printf("Warning: %v", err)
+		} else if missing.CompareAndSwap(false, true) {
+			p.logger.Printf("Warning: %d pages without a text layer are left as is: %v", ocrPages, ocr.ErrTesseractNotFound)
+		}
+		return
+	}
+	if strings.TrimSpace(result.Text) == "" {
+		return
 	}
 
-	// If no text found, use OCR
-	if strings.TrimSpace(text) == "" {
-		text = p.extractTextWithOCR(doc, pageIndex, pageNum)
+	texts[pageIndex] = result.Text
+	pages[pageIndex].Source = SourceOCR
+	pages[pageIndex].OCRConfidence = result.Confidence
+	pages[pageIndex].LowConfidenceWords = lowConfidenceWords(result.Words, threshold)
+}
+
+// pageDone reports a page whose text is final to OnPage
+func (p *PDFProcessor) pageDone(pageIndex, pages int) {
+	if p.options.OnPage != nil {
+		p.options.OnPage(pageIndex+1, pages)
+	}
+}
+
+// checkDeadline fails with ErrProcessingTimeout once the document deadline has passed,
+// and with the error of the document's context once it is done
+func (p *PDFProcessor) checkDeadline() error {
+	if p.options.Context != nil && p.options.Context.Err() != nil {
+		return p.options.Context.Err()
 	}
+	return CheckDeadline(p.options.Deadline, p.config.MaxProcessingTime)
+}
 
-	// Add page separator
-	separator := fmt.Sprintf("\n\n--- Page %d ---\n\n", pageNum)
-	return separator + text, nil
+// warnOCRUnavailable reports pages left without OCR because the build cannot render pages
+func (p *PDFProcessor) warnOCRUnavailable(pageCount int) {
+	p.logger.Printf("Warning: OCR is not available in purego builds; %d pages without a text layer are left as is", pageCount)
 }
 
-// extractTextWithOCR uses OCR to extract text from a page image
-func (p *PDFProcessor) extractTextWithOCR(doc *fitz.Document, pageIndex, pageNum int) string {
+// extractTextWithOCR uses OCR to extract text from a page image, // giving up after
+// OCRPageTimeout with ErrOCRTimeout or once ctx is done. A render resolution lowered by
+// MaxRenderPixels is recorded in the page report. A panic while rendering or recognizing
+// the page is returned as a PanicError.
+func (p *PDFProcessor) extractTextWithOCR(ctx context.Context, doc pdfDocument, tempDir string, pageIndex int, report *PageReport) (_ *ocr.Result, err error) {
+	defer RecoverPanic(&err)
+	pageNum := pageIndex + 1
+
 	// Render page as image
-	img, err := doc.Image(pageIndex)
+	img, dpi, err := p.renderPage(doc, pageIndex, p.ocrDPI())
 	if err != nil {
-		log.Printf("Warning: failed to render page %d as image: %v", pageNum, err)
-		return ""
+		return nil, fmt.Errorf("failed to render page %d as image: %w", pageNum, err)
+	}
+	if dpi < p.ocrDPI() {
+		report.OCRDPI = dpi
 	}
 
 	// Save temporary image
-	tempImagePath := fmt.Sprintf("temp_page_%d.png", pageIndex)
+	tempImagePath := filepath.Join(tempDir, fmt.Sprintf("page_%d.png", pageIndex))
 	if err := p.saveTemporaryImage(img, tempImagePath); err != nil {
-		log.Printf("Warning: failed to save temp image: %v", err)
-		return ""
+		return nil, err
 	}
 	defer os.Remove(tempImagePath)
 
 	// Perform OCR
-	ocrText, err := p.runTesseract(tempImagePath)
+	pageCtx := ctx
+	if p.config.OCRPageTimeout > 0 {
+		var cancel context.CancelFunc
+		pageCtx, cancel = context.WithTimeout(ctx, p.config.OCRPageTimeout)
+		defer cancel()
+	}
+	result, err := recognize(pageCtx, p.ocrEngine, tempImagePath)
 	if err != nil {
-		log.Printf("Warning: OCR failed for page %d: %v", pageNum, err)
-		return ""
+		if pageCtx.Err() != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("OCR failed for page %d: %w after %s", pageNum, ErrOCRTimeout, p.config.OCRPageTimeout)
+		}
+		return nil, fmt.Errorf("OCR failed for page %d: %w", pageNum, err)
 	}
 
-	return ocrText
+	return result, nil
+}
+
+// recognize runs engine on an image until ctx is done. Engines that do not implement
+// ocr.ContextEngine cannot be stopped, so they are left running in the background, where
+// a panic of the engine is returned as a PanicError.
+func recognize(ctx context.Context, engine ocr.Engine, imagePath string) (*ocr.Result, error) {
+	if contextEngine, ok := engine.(ocr.ContextEngine); ok {
+		return contextEngine.RecognizeContext(ctx, imagePath)
+	}
+
+	type recognized struct {
+		result *ocr.Result
+		err    error
+	}
+	done := make(chan recognized, 1)
+	go func() {
+		var r recognized
+		func() {
+			defer RecoverPanic(&r.err)
+			r.result, r.err = engine.Recognize(imagePath)
+		}()
+		done <- r
+	}()
+	select {
+	case r := <-done:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ocrDPI returns the configured render resolution or the default
+func (p *PDFProcessor) ocrDPI() float64 {
+	if p.options.OCRDPI > 0 {
+		return p.options.OCRDPI
+	}
+	return DefaultOCRDPI
 }
 
 // saveTemporaryImage saves an image to a temporary file
@@ -130,21 +547,10 @@ func (p *PDFProcessor) saveTemporaryImage(img image.Image, tempPath string) erro
 	}
 	defer imgFile.Close()
 
-	if err := png.Encode(imgFile, img); err != nil {
+	if err := ocrPNGEncoder.Encode(imgFile, img); err != nil {
 		os.Remove(tempPath)
 		return fmt.Errorf("failed to encode image: %w", err)
 	}
 
 	return nil
 }
-
-// runTesseract executes the tesseract OCR command
-func (p *PDFProcessor) runTesseract(imagePath string) (string, error) {
-	cmd := exec.Command("tesseract", imagePath, "stdout", "-l", "eng+ind")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("tesseract command failed: %w", err)
-	}
-
-	return string(output), nil
-}
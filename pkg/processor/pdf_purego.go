@@ -0,0 +1,129 @@
+//go:build purego
+
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"sync"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// defaultEngine is the engine that reads PDFs before any fallback
+const defaultEngine = EnginePureGo
+
+// renderSupported reports whether pages can be rendered for OCR and searchable PDFs.
+// The pure-Go reader only reads text layers, so OCR is disabled.
+const renderSupported = false
+
+// errRenderUnsupported is returned for page rendering in purego builds
+var errRenderUnsupported = errors.New("page rendering is not available in purego builds")
+
+// goDocument reads a PDF with the pure-Go ledongthuc/pdf reader
+type goDocument struct {
+	mu     sync.Mutex
+	file   *os.File // Nil for documents opened from memory
+	reader *pdf.Reader
+	fonts  map[string]*pdf.Font // Parsed fonts, shared between pages
+}
+
+// openPDF opens a PDF file
+func openPDF(pdfPath string) (pdfDocument, error) {
+	file, reader, err := pdf.Open(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+	return &goDocument{file: file, reader: reader, fonts: make(map[string]*pdf.Font)}, nil
+}
+
+// openPDFMemory opens PDF binary data
+func openPDFMemory(data []byte) (pdfDocument, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return &goDocument{reader: reader, fonts: make(map[string]*pdf.Font)}, nil
+}
+
+// NumPage returns the number of pages, or 0 when the page tree cannot be read
+func (d *goDocument) NumPage() (pages int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The reader panics on malformed objects
+	defer func() {
+		if recover() != nil {
+			pages = 0
+		}
+	}()
+	return d.reader.NumPage()
+}
+
+// Text returns the plain text of a page
+func (d *goDocument) Text(pageIndex int) (text string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			text, err = "", fmt.Errorf("malformed page %d: %v", pageIndex+1, r)
+		}
+	}()
+
+	page := d.reader.Page(pageIndex + 1)
+	if page.V.IsNull() {
+		return "", fmt.Errorf("page %d not found", pageIndex+1)
+	}
+	for _, name := range page.Fonts() {
+		if _, ok := d.fonts[name]; !ok {
+			font := page.Font(name)
+			d.fonts[name] = &font
+		}
+	}
+	return page.GetPlainText(d.fonts)
+}
+
+// ImageDPI is not supported by the pure-Go reader
+func (d *goDocument) ImageDPI(pageIndex int, dpi float64) (image.Image, error) {
+	return nil, errRenderUnsupported
+}
+
+// Bound is not supported by the pure-Go reader, which never renders pages
+func (d *goDocument) Bound(pageIndex int) (image.Rectangle, error) {
+	return image.Rectangle{}, errRenderUnsupported
+}
+
+// Bookmarks returns the outline of the document without page numbers, which the
+// pure-Go reader cannot resolve, or nil when it has none
+func (d *goDocument) Bookmarks() (bookmarks []Bookmark) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	defer func() {
+		if recover() != nil {
+			bookmarks = nil
+		}
+	}()
+
+	var walk func(outline pdf.Outline, level int)
+	walk = func(outline pdf.Outline, level int) {
+		for _, child := range outline.Child {
+			bookmarks = append(bookmarks, Bookmark{Level: level, Title: child.Title})
+			walk(child, level+1)
+		}
+	}
+	walk(d.reader.Outline(), 1)
+	return bookmarks
+}
+
+// Close closes the underlying file
+func (d *goDocument) Close() error {
+	if d.file == nil {
+		return nil
+	}
+	return d.file.Close()
+}
@@ -0,0 +1,190 @@
+package processor
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+const (
+	pptxPresentationPart = "ppt/presentation.xml"
+	pptxNotesRelSuffix   = "/notesSlide"
+)
+
+// PPTXProcessor handles PowerPoint text extraction (slide text and speaker notes)
+type PPTXProcessor struct {
+	config config.ChunkerConfig
+}
+
+// NewPPTXProcessor creates a new PowerPoint processor instance
+func NewPPTXProcessor(config config.ChunkerConfig) *PPTXProcessor {
+	return &PPTXProcessor{
+		config: config,
+	}
+}
+
+// ExtractDocumentFromPPTXPath extracts slide text from a PPTX file path
+func (p *PPTXProcessor) ExtractDocumentFromPPTXPath(pptxPath string) (*Document, error) {
+	data, err := os.ReadFile(pptxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PPTX: %w", err)
+	}
+
+	return p.ExtractDocumentFromPPTXBytes(data)
+}
+
+// ExtractDocumentFromPPTXReader extracts slide text from a PPTX reader
+func (p *PPTXProcessor) ExtractDocumentFromPPTXReader(reader io.Reader) (*Document, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PPTX data: %w", err)
+	}
+
+	return p.ExtractDocumentFromPPTXBytes(data)
+}
+
+// ExtractDocumentFromPPTXBytes extracts slide text from PPTX binary data.
+// Each slide becomes one page of the document.
+func (p *PPTXProcessor) ExtractDocumentFromPPTXBytes(data []byte) (*Document, error) {
+	archive, err := openOfficeArchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	slides, err := p.slideParts(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	var result strings.Builder
+	texts := make([]string, 0, len(slides))
+	pages := make([]PageReport, 0, len(slides))
+
+	for i, slidePart := range slides {
+		text, err := p.extractSlide(archive, slidePart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract slide %d: %w", i+1, err)
+		}
+
+		result.WriteString(fmt.Sprintf("\n\n--- Page %d ---\n\n", i+1))
+		result.WriteString(text)
+		texts = append(texts, text)
+		pages = append(pages, PageReport{Number: i + 1, Source: SourceText, Characters: len(strings.TrimSpace(text))})
+	}
+
+	return &Document{
+		Text:  result.String(),
+		Pages: newPages(texts, pages),
+		Report: DocumentReport{
+			TotalPages:     len(slides),
+			Classification: Classification{Class: ClassDigital, TextPages: len(slides)},
+			Pages:          pages,
+		},
+	}, nil
+}
+
+// slideParts returns slide part names in presentation order
+func (p *PPTXProcessor) slideParts(archive *zip.Reader) ([]string, error) {
+	data, err := readOfficePart(archive, pptxPresentationPart)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("not a PowerPoint document: missing %s", pptxPresentationPart)
+	}
+
+	var presentation struct {
+		Slides []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := xml.Unmarshal(data, &presentation); err != nil {
+		return nil, fmt.Errorf("failed to parse presentation: %w", err)
+	}
+
+	rels, err := readOfficeRelationships(archive, pptxPresentationPart)
+	if err != nil {
+		return nil, err
+	}
+
+	var slides []string
+	for _, slide := range presentation.Slides {
+		if rel, ok := rels[slide.RelID]; ok {
+			slides = append(slides, rel.Target)
+		}
+	}
+
+	// Fall back to file order when the presentation part has no slide list
+	if len(slides) == 0 {
+		slides = listOfficeParts(archive, "ppt/slides/slide")
+	}
+
+	return slides, nil
+}
+
+// extractSlide returns the text of a slide followed by its speaker notes
+func (p *PPTXProcessor) extractSlide(archive *zip.Reader, slidePart string) (string, error) {
+	data, err := readOfficePart(archive, slidePart)
+	if err != nil {
+		return "", err
+	}
+
+	paragraphs, err := extractDrawingParagraphs(data)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	text.WriteString(strings.Join(paragraphs, "\n"))
+
+	notes, err := p.extractNotes(archive, slidePart)
+	if err != nil {
+		return "", err
+	}
+	if notes != "" {
+		text.WriteString("\n\nNotes:\n")
+		text.WriteString(notes)
+	}
+
+	return text.String(), nil
+}
+
+// extractNotes returns the speaker notes linked to a slide
+func (p *PPTXProcessor) extractNotes(archive *zip.Reader, slidePart string) (string, error) {
+	rels, err := readOfficeRelationships(archive, slidePart)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels {
+		if !strings.HasSuffix(rel.Type, pptxNotesRelSuffix) {
+			continue
+		}
+
+		data, err := readOfficePart(archive, rel.Target)
+		if err != nil || data == nil {
+			return "", err
+		}
+
+		paragraphs, err := extractDrawingParagraphs(data)
+		if err != nil {
+			return "", err
+		}
+
+		// Notes pages repeat the slide number as a bare paragraph
+		var notes []string
+		for _, paragraph := range paragraphs {
+			if _, isNumber := parseInt(paragraph); !isNumber {
+				notes = append(notes, paragraph)
+			}
+		}
+		return strings.Join(notes, "\n"), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/container"
+)
+
+// checkPDF is a one-page PDF reading "ok", which CheckEngine opens and renders
+const checkPDF = `%PDF-1.4
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [3 0 R] /Count 1 >>
+endobj
+3 0 obj
+<< /Type /Page /Parent 2 0 R /MediaBox [0 0 72 72] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>
+endobj
+4 0 obj
+<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>
+endobj
+5 0 obj
+<< /Length 32 >>
+stream
+BT /F1 12 Tf 10 30 Td (ok) Tj ET
+endstream
+endobj
+xref
+0 6
+0000000000 65535 f 
+0000000009 00000 n 
+0000000058 00000 n 
+0000000115 00000 n 
+0000000239 00000 n 
+0000000309 00000 n 
+trailer
+<< /Size 6 /Root 1 0 R >>
+startxref
+391
+%%EOF
+`
+
+// CheckEngine opens, reads and renders a one-page PDF with the PDF engine of the build,
+// so a MuPDF library that failed to link or load is reported before any document fails
+// on it. It returns the engine name and whether the build renders pages for OCR.
+func CheckEngine() (engine string, render bool, err error) {
+	defer RecoverPanic(&err)
+	doc, err := openPDFMemory([]byte(checkPDF))
+	if err != nil {
+		return defaultEngine, renderSupported, fmt.Errorf("failed to open a PDF: %w", err)
+	}
+	defer doc.Close()
+	return defaultEngine, renderSupported, checkDocument(doc, renderSupported)
+}
+
+// CheckContainer opens, reads and renders a one-page PDF with mutool in c, as
+// WithContainer does, starting c when it does not run yet
+func CheckContainer(c *container.Container) error {
+	doc, err := openContainerPDF(c, strings.NewReader(checkPDF))
+	if err != nil {
+		return fmt.Errorf("failed to open a PDF: %w", err)
+	}
+	defer doc.Close()
+	return checkDocument(doc, true)
+}
+
+// checkDocument reads the page of checkPDF, and renders it when render is set
+func checkDocument(doc pdfDocument, render bool) error {
+	text, err := doc.Text(0)
+	if err != nil {
+		return fmt.Errorf("failed to read text: %w", err)
+	}
+	if !strings.Contains(text, "ok") {
+		return fmt.Errorf("read %q instead of the text of the page", text)
+	}
+	if render {
+		if _, err := doc.ImageDPI(0, 36); err != nil {
+			return fmt.Errorf("failed to render a page: %w", err)
+		}
+	}
+	return nil
+}
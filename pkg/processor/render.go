@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"image"
+	"math"
+)
+
+// renderPage renders a page at dpi, or at the highest lower DPI at which it is at most
+// MaxRenderPixels pixels, so an oversized page such as a poster or engineering drawing
+// does not allocate hundreds of megabytes. It returns the DPI it rendered at.
+func (p *PDFProcessor) renderPage(doc pdfDocument, pageIndex int, dpi float64) (image.Image, float64, error) {
+	dpi = p.renderDPI(doc, pageIndex, dpi)
+	img, err := doc.ImageDPI(pageIndex, dpi)
+	return img, dpi, err
+}
+
+// renderDPI returns dpi, lowered when the page would be more than MaxRenderPixels pixels
+// at it. Pages whose size is unknown are rendered at dpi.
+func (p *PDFProcessor) renderDPI(doc pdfDocument, pageIndex int, dpi float64) float64 {
+	maxPixels := p.config.MaxRenderPixels
+	if maxPixels <= 0 {
+		return dpi
+	}
+	bounds, err := doc.Bound(pageIndex)
+	if err != nil || bounds.Empty() {
+		return dpi
+	}
+
+	// Bounds are in points, which are pixels at 72 DPI
+	points := float64(bounds.Dx()) * float64(bounds.Dy())
+	scale := dpi / pointsPerInch
+	if points*scale*scale <= float64(maxPixels) {
+		return dpi
+	}
+	// Round down to a whole DPI so rounding up the pixel size stays under the cap
+	return max(math.Floor(pointsPerInch*math.Sqrt(float64(maxPixels)/points)), 1)
+}
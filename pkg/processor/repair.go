@@ -0,0 +1,144 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
+)
+
+// Repairer rewrites a damaged PDF (truncated xref, bad streams, junk around the file)
+// into a new file that the built-in engine can open
+type Repairer interface {
+	Repair(inputPath, outputPath string) error
+	GetName() string
+}
+
+// TrimRepair removes junk before the %PDF- header and after the last %%EOF marker, such
+// as mail or HTTP headers saved with the file, and restores a missing %%EOF after a
+// truncated download. MuPDF rebuilds a broken xref table itself once the file is framed.
+type TrimRepair struct{}
+
+// Repair writes the trimmed PDF to outputPath
+func (r *TrimRepair) Repair(inputPath, outputPath string) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	start := bytes.Index(data, []byte("%PDF-"))
+	if start < 0 {
+		return fmt.Errorf("no PDF header found")
+	}
+	repaired := data[start:]
+
+	if end := bytes.LastIndex(repaired, []byte("%%EOF")); end >= 0 {
+		repaired = append(repaired[:end:end], "%%EOF\n"...)
+	} else {
+		repaired = append(repaired[:len(repaired):len(repaired)], "\n%%EOF\n"...)
+	}
+	if bytes.Equal(repaired, data) {
+		return fmt.Errorf("nothing to trim")
+	}
+
+	return os.WriteFile(outputPath, repaired, 0644)
+}
+
+// GetName returns the repairer name
+func (r *TrimRepair) GetName() string {
+	return "trim"
+}
+
+// Qpdf repairs PDFs with the qpdf command line tool, which reconstructs damaged xref
+// tables and drops unreadable streams
+type Qpdf struct{}
+
+// Repair rewrites the PDF with qpdf
+func (r *Qpdf) Repair(inputPath, outputPath string) error {
+	output, err := exec.Command("qpdf", inputPath, outputPath).CombinedOutput()
+	// Exit status 3 means qpdf wrote the file but had to recover from errors
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 3 {
+		return nil
+	}
+	if err != nil {
+		return commandError("qpdf command", err, string(output))
+	}
+	return nil
+}
+
+// GetName returns the repairer name
+func (r *Qpdf) GetName() string {
+	return "qpdf"
+}
+
+// MutoolClean repairs PDFs with MuPDF's mutool clean, which rewrites the file from the
+// objects it can recover
+type MutoolClean struct{}
+
+// Repair rewrites the PDF with mutool clean
+func (r *MutoolClean) Repair(inputPath, outputPath string) error {
+	if output, err := exec.Command("mutool", "clean", inputPath, outputPath).CombinedOutput(); err != nil {
+		return commandError("mutool clean", err, string(output))
+	}
+	return nil
+}
+
+// GetName returns the repairer name
+func (r *MutoolClean) GetName() string {
+	return "mutool"
+}
+
+// DefaultRepairers returns the repairers tried when RepairPDF is set, cheapest first
+func DefaultRepairers() []Repairer {
+	return []Repairer{&TrimRepair{}, &Qpdf{}, &MutoolClean{}}
+}
+
+// extractRepaired tries each repairer in turn and extracts the first repaired file the
+// built-in engine can read. It returns the failure of every repairer otherwise.
+func (p *PDFProcessor) extractRepaired(pdfPath string, cause error) (*Document, []string) {
+	tempDir, err := tempfile.MkdirTemp(p.config.TempDir, "repair-")
+	if err != nil {
+		return nil, []string{fmt.Sprintf("repair: %v", err)}
+	}
+	defer tempfile.Remove(tempDir)
+
+	var failures []string
+	for i, repairer := range p.repairers {
+		repairedPath := filepath.Join(tempDir, fmt.Sprintf("repaired_%d.pdf", i))
+		if err := repairer.Repair(pdfPath, repairedPath); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", repairer.GetName(), err))
+			continue
+		}
+
+		document, err := p.extractPath(repairedPath)
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			return nil, []string{err.Error()}
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", repairer.GetName(), err))
+			continue
+		}
+
+		p.logger.Printf("Warning: %s failed (%v), repaired with %s", p.engine(), cause, repairer.GetName())
+		document.Report.Repaired = repairer.GetName()
+		return document, nil
+	}
+	return nil, failures
+}
+
+// extractPath extracts a PDF file with the built-in engine only
+func (p *PDFProcessor) extractPath(pdfPath string) (*Document, error) {
+	doc, err := p.openPDF(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	defer doc.Close()
+
+	return p.extractDocument(doc, detectPDFAFromPath(pdfPath))
+}
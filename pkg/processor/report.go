@@ -0,0 +1,81 @@
+package processor
+
+import "github.com/firdasafridi/pdf-chunk-extractor/pkg/ocr"
+
+// DefaultLowConfidence is the word confidence below which OCR words are reported
+const DefaultLowConfidence = 60
+
+// PageSource describes where the text of a page came from
+type PageSource string
+
+const (
+	SourceText PageSource = "text" // Direct text layer
+	SourceOCR  PageSource = "ocr"  // Tesseract on the rendered page
+)
+
+// Page is the extracted text of a single page
+type Page struct {
+	Number int        `json:"number"` // 1-based; 0 for text without page boundaries
+	Text   string     `json:"text"`
+	Source PageSource `json:"source"`
+}
+
+// PageReport describes how a single page was extracted
+type PageReport struct {
+	Number             int        `json:"number"`
+	Source             PageSource `json:"source"`
+	OCR                bool       `json:"ocr"` // OCR was attempted
+	Characters         int        `json:"characters"`
+	OCRConfidence      float64    `json:"ocr_confidence,omitempty"`
+	LowConfidenceWords []ocr.Word `json:"low_confidence_words,omitempty"`
+	OCRError           string     `json:"ocr_error,omitempty"`  // Why OCR failed, e.g. ErrOCRTimeout; the page keeps its direct text
+	OCRDPI             float64    `json:"ocr_dpi,omitempty"`    // Render resolution for OCR, when MaxRenderPixels lowered it below OCRDPI
+	TextError          string     `json:"text_error,omitempty"` // Why the direct text layer could not be read
+	Stack              string     `json:"stack,omitempty"`      // Stack of a panic recovered while extracting the page, see PanicError
+}
+
+// DocumentReport describes the extraction of a whole document
+type DocumentReport struct {
+	TotalPages     int            `json:"total_pages"`
+	OCRPages       int            `json:"ocr_pages"`
+	Engine         string         `json:"engine"`             // EngineMuPDF, or the fallback extractor that read the document
+	Repaired       string         `json:"repaired,omitempty"` // Repairer that fixed the damaged file before extraction
+	Classification Classification `json:"classification"`
+	Pages          []PageReport   `json:"pages"`
+	SearchablePDF  string         `json:"searchable_pdf,omitempty"`  // Path of the written searchable PDF, if any
+	Bookmarks      []Bookmark     `json:"bookmarks,omitempty"`       // Outline of the PDF, when it has one
+	OCRCorrections int            `json:"ocr_corrections,omitempty"` // Words of OCR pages corrected by the chunker with OCRCorrection
+	PageImages     []string       `json:"page_images,omitempty"`     // Paths of the rendered page images in page order, with PageImages
+	Thumbnail      []byte         `json:"-"`                         // First page encoded in ThumbnailFormat, with Thumbnail
+}
+
+// Document holds the extracted text of a document together with its extraction report
+type Document struct {
+	Text   string // All pages joined with "--- Page N ---" separators
+	Pages  []Page
+	Report DocumentReport
+}
+
+// newPages builds the page list from per-page texts and their reports
+func newPages(texts []string, reports []PageReport) []Page {
+	pages := make([]Page, len(texts))
+	for i, text := range texts {
+		pages[i] = Page{
+			Number: reports[i].Number,
+			Text:   text,
+			Source: reports[i].Source,
+		}
+	}
+	return pages
+}
+
+// lowConfidenceWords returns the words recognized with a confidence below the threshold
+func lowConfidenceWords(words []ocr.Word, threshold float64) []ocr.Word {
+	var low []ocr.Word
+	for _, word := range words {
+		if word.Confidence < threshold {
+			low = append(low, word)
+		}
+	}
+	return low
+}
@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
+)
+
+// spilledInput is reader content held either in memory or, when large, in a temp file
+type spilledInput struct {
+	data []byte // Set when the content fit in memory
+	path string // Set when the content was spooled to a temp file
+}
+
+// spillReader reads reader into memory until it exceeds limit bytes, then spools the
+// rest to a temp file so large streams are never held in memory. A limit of 0 or less
+// always reads into memory. The temp file is created under tempDir (see
+// tempfile.Root). Call cleanup to remove the temp file.
+func spillReader(reader io.Reader, limit int64, tempDir string) (input spilledInput, cleanup func(), err error) {
+	cleanup = func() {}
+	if limit <= 0 {
+		data, err := io.ReadAll(reader)
+		return spilledInput{data: data}, cleanup, err
+	}
+
+	var head bytes.Buffer
+	n, err := io.Copy(&head, io.LimitReader(reader, limit+1))
+	if err != nil {
+		return spilledInput{}, cleanup, err
+	}
+	if n <= limit {
+		return spilledInput{data: head.Bytes()}, cleanup, nil
+	}
+
+	file, err := tempfile.CreateTemp(tempDir, "input-*.pdf")
+	if err != nil {
+		return spilledInput{}, cleanup, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	cleanup = func() { tempfile.Remove(file.Name()) }
+
+	_, err = io.Copy(file, io.MultiReader(&head, reader))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return spilledInput{}, func() {}, fmt.Errorf("failed to spill input to disk: %w", err)
+	}
+	return spilledInput{path: file.Name()}, cleanup, nil
+}
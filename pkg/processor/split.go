@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// PageSplitter writes a range of pages of a PDF to a new PDF, keeping the pages as they
+// are: text, fonts and images are copied rather than rendered
+type PageSplitter interface {
+	SplitPages(inputPath, outputPath string, firstPage, lastPage int) error
+	GetName() string
+}
+
+// SplitPages writes pages firstPage to lastPage of the PDF with qpdf
+func (r *Qpdf) SplitPages(inputPath, outputPath string, firstPage, lastPage int) error {
+	pages := fmt.Sprintf("%d-%d", firstPage, lastPage)
+	output, err := exec.Command("qpdf", "--empty", "--pages", inputPath, pages, "--", outputPath).CombinedOutput()
+	if err != nil {
+		return commandError("qpdf command", err, string(output))
+	}
+	return nil
+}
+
+// SplitPages writes pages firstPage to lastPage of the PDF with mutool clean
+func (r *MutoolClean) SplitPages(inputPath, outputPath string, firstPage, lastPage int) error {
+	pages := fmt.Sprintf("%d-%d", firstPage, lastPage)
+	if output, err := exec.Command("mutool", "clean", inputPath, outputPath, pages).CombinedOutput(); err != nil {
+		return commandError("mutool clean", err, string(output))
+	}
+	return nil
+}
+
+// DefaultSplitters returns the splitters tried with SplitPDF: qpdf, then mutool
+func DefaultSplitters() []PageSplitter {
+	return []PageSplitter{&Qpdf{}, &MutoolClean{}}
+}
+
+// SplitPDFPages writes pages firstPage to lastPage of a PDF with the first splitter that
+// succeeds, and returns the failure of every splitter otherwise
+func SplitPDFPages(splitters []PageSplitter, inputPath, outputPath string, firstPage, lastPage int) error {
+	if len(splitters) == 0 {
+		return fmt.Errorf("no PDF splitters configured")
+	}
+	var failures []string
+	for _, splitter := range splitters {
+		err := splitter.SplitPages(inputPath, outputPath, firstPage, lastPage)
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", splitter.GetName(), err))
+	}
+	return fmt.Errorf("failed to split PDF: %s", strings.Join(failures, "; "))
+}
@@ -0,0 +1,173 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PageFunc receives the pages of a streamed document in order; returning an error stops
+// the extraction
+type PageFunc func(page Page) error
+
+// StreamPagesFromPDFPath extracts a PDF file page by page, passing each page to fn as
+// soon as it is ready, so memory stays bounded by a few pages whatever the document size
+func (p *PDFProcessor) StreamPagesFromPDFPath(pdfPath string, fn PageFunc) (*DocumentReport, error) {
+	doc, err := p.openPDF(pdfPath)
+	if err != nil {
+		document, err := p.recoverDocument(pdfPath, fmt.Errorf("failed to open PDF: %w", err))
+		return emitFallback(document, err, fn)
+	}
+	defer doc.Close()
+
+	report, err := p.streamDocument(doc, detectPDFAFromPath(pdfPath), fn)
+	if errors.Is(err, errUnreadablePDF) {
+		document, err := p.recoverDocument(pdfPath, err)
+		return emitFallback(document, err, fn)
+	}
+	return report, err
+}
+
+// StreamPagesFromPDFBytes extracts PDF binary data page by page
+func (p *PDFProcessor) StreamPagesFromPDFBytes(data []byte, fn PageFunc) (*DocumentReport, error) {
+	doc, err := p.openPDFMemory(data)
+	if err != nil {
+		document, err := p.recoverBytes(data, fmt.Errorf("failed to open PDF from memory: %w", err))
+		return emitFallback(document, err, fn)
+	}
+	defer doc.Close()
+
+	report, err := p.streamDocument(doc, detectPDFA(bytes.NewReader(data)), fn)
+	if errors.Is(err, errUnreadablePDF) {
+		document, err := p.recoverBytes(data, err)
+		return emitFallback(document, err, fn)
+	}
+	return report, err
+}
+
+// StreamPagesFromPDFReader extracts a PDF reader page by page. Readers larger than
+// ReaderSpillSize are spooled to a temp file and opened from disk.
+func (p *PDFProcessor) StreamPagesFromPDFReader(reader io.Reader, fn PageFunc) (*DocumentReport, error) {
+	input, cleanup, err := spillReader(reader, p.config.ReaderSpillSize, p.config.TempDir)
+	defer cleanup()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF data: %w", err)
+	}
+
+	if input.path != "" {
+		return p.StreamPagesFromPDFPath(input.path, fn)
+	}
+	return p.StreamPagesFromPDFBytes(input.data, fn)
+}
+
+// streamDocument extracts an open document like extractDocument, but only keeps the text
+// of the pages being OCR'd at once. The preflight pass only counts the pages with a text
+// layer; the second pass reads each page again and emits it.
+func (p *PDFProcessor) streamDocument(doc pdfDocument, pdfaConformance string, fn PageFunc) (*DocumentReport, error) {
+	totalPages := doc.NumPage()
+	if err := CheckPages(totalPages, p.config.MaxPages); err != nil {
+		return nil, err
+	}
+	pages := make([]PageReport, totalPages)
+
+	// Preflight: count the pages with a text layer
+	textPages, failedPages := 0, 0
+	for pageIndex := 0; pageIndex < totalPages; pageIndex++ {
+		if err := p.checkDeadline(); err != nil {
+			return nil, err
+		}
+		pages[pageIndex].Number = pageIndex + 1
+		pages[pageIndex].Source = SourceText
+
+		text, err := doc.Text(pageIndex)
+		if err != nil {
+			p.logger.Printf("Warning: failed to extract text from page %d: %v", pageIndex+1, err)
+			failedPages++
+		}
+		if isTextPage(text) {
+			textPages++
+		}
+	}
+	if failedPages == totalPages {
+		return nil, errUnreadablePDF
+	}
+	classification := newClassificationFromCounts(textPages, totalPages, pdfaConformance)
+
+	workers := max(p.config.OCRWorkers, 1)
+	texts := make([]string, totalPages)
+	ocrPages, skippedOCRPages := 0, 0
+
+	// Pages are emitted in windows of OCRWorkers so OCR still runs in parallel
+	for windowStart := 0; windowStart < totalPages; windowStart += workers {
+		windowEnd := min(windowStart+workers, totalPages)
+		if err := p.checkDeadline(); err != nil {
+			return nil, err
+		}
+
+		var ocrIndexes []int
+		for pageIndex := windowStart; pageIndex < windowEnd; pageIndex++ {
+			text, _ := doc.Text(pageIndex)
+			texts[pageIndex] = text
+			if classification.Class == ClassScanned || strings.TrimSpace(text) == "" {
+				ocrIndexes = append(ocrIndexes, pageIndex)
+			}
+		}
+		if len(ocrIndexes) > 0 && !p.rendersPages() {
+			skippedOCRPages += len(ocrIndexes)
+			ocrIndexes = nil
+		}
+		if len(ocrIndexes) > 0 {
+			if err := p.extractPagesWithOCR(doc, ocrIndexes, texts, pages); err != nil {
+				return nil, err
+			}
+			ocrPages += len(ocrIndexes)
+		}
+
+		for pageIndex := windowStart; pageIndex < windowEnd; pageIndex++ {
+			pages[pageIndex].Characters = len(strings.TrimSpace(texts[pageIndex]))
+			page := Page{Number: pageIndex + 1, Text: texts[pageIndex], Source: pages[pageIndex].Source}
+			texts[pageIndex] = ""
+			if err := fn(page); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if skippedOCRPages > 0 {
+		p.warnOCRUnavailable(skippedOCRPages)
+	}
+
+	report := &DocumentReport{
+		TotalPages:     totalPages,
+		OCRPages:       ocrPages,
+		Engine:         p.engine(),
+		Classification: *classification,
+		Pages:          pages,
+		Bookmarks:      doc.Bookmarks(),
+	}
+
+	if p.options.SearchablePDFPath != "" {
+		if err := p.writeSearchablePDF(doc, p.options.SearchablePDFPath); err != nil {
+			p.logger.Printf("Warning: failed to write searchable PDF: %v", err)
+		} else {
+			report.SearchablePDF = p.options.SearchablePDFPath
+		}
+	}
+
+	return report, nil
+}
+
+// emitFallback emits the pages of a document read by a fallback extractor
+func emitFallback(document *Document, err error, fn PageFunc) (*DocumentReport, error) {
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range document.Pages {
+		if err := fn(page); err != nil {
+			return nil, err
+		}
+	}
+	return &document.Report, nil
+}
@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// DefaultThumbnailSize is the longest side of thumbnails in pixels when none is configured
+const DefaultThumbnailSize = 256
+
+// pointsPerInch is the resolution at which a rendered page is as large in pixels as in
+// PDF points
+const pointsPerInch = 72
+
+// addThumbnail renders the first page into the report's Thumbnail, or warns when it
+// cannot be rendered
+func (p *PDFProcessor) addThumbnail(doc pdfDocument, report *DocumentReport) {
+	thumbnail, err := p.renderThumbnail(doc)
+	if err != nil {
+		p.logger.Printf("Warning: failed to render thumbnail: %v", err)
+		return
+	}
+	report.Thumbnail = thumbnail
+}
+
+// renderThumbnail renders the first page with its longest side ThumbnailSize pixels long
+// and encodes it in the ThumbnailFormat
+func (p *PDFProcessor) renderThumbnail(doc pdfDocument) ([]byte, error) {
+	if doc.NumPage() == 0 {
+		return nil, errors.New("document has no pages")
+	}
+	size := p.config.ThumbnailSize
+	if size <= 0 {
+		size = DefaultThumbnailSize
+	}
+
+	// Rendered at 72 DPI, the page is as many pixels as points, which gives the DPI at
+	// which it fits the thumbnail
+	img, _, err := p.renderPage(doc, 0, pointsPerInch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render page 1: %w", err)
+	}
+	if longest := max(img.Bounds().Dx(), img.Bounds().Dy()); longest > 0 && longest != size {
+		img, err = doc.ImageDPI(0, pointsPerInch*float64(size)/float64(longest))
+		if err != nil {
+			return nil, fmt.Errorf("failed to render page 1: %w", err)
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := encodeImage(&buffer, img, p.config.ThumbnailFormat); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
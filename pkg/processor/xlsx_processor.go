@@ -0,0 +1,316 @@
+package processor
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+const (
+	xlsxWorkbookPart      = "xl/workbook.xml"
+	xlsxSharedStringsPart = "xl/sharedStrings.xml"
+
+	// xlsxRowsPerTable repeats the table header every N rows so any split keeps column names
+	xlsxRowsPerTable = 50
+)
+
+// XLSXProcessor handles Excel workbook serialization to Markdown tables
+type XLSXProcessor struct {
+	config config.ChunkerConfig
+}
+
+// xlsxCell is a single cell of a worksheet row
+type xlsxCell struct {
+	Ref       string `xml:"r,attr"`
+	Type      string `xml:"t,attr"`
+	Value     string `xml:"v"`
+	InlineStr struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+// xlsxSharedString is a single entry of the shared strings table
+type xlsxSharedString struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+// NewXLSXProcessor creates a new Excel processor instance
+func NewXLSXProcessor(config config.ChunkerConfig) *XLSXProcessor {
+	return &XLSXProcessor{
+		config: config,
+	}
+}
+
+// ExtractDocumentFromXLSXPath serializes an XLSX file path
+func (p *XLSXProcessor) ExtractDocumentFromXLSXPath(xlsxPath string) (*Document, error) {
+	data, err := os.ReadFile(xlsxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XLSX: %w", err)
+	}
+
+	return p.ExtractDocumentFromXLSXBytes(data)
+}
+
+// ExtractDocumentFromXLSXReader serializes an XLSX reader
+func (p *XLSXProcessor) ExtractDocumentFromXLSXReader(reader io.Reader) (*Document, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XLSX data: %w", err)
+	}
+
+	return p.ExtractDocumentFromXLSXBytes(data)
+}
+
+// ExtractDocumentFromXLSXBytes serializes XLSX binary data.
+// Each sheet becomes one page rendered as Markdown tables.
+func (p *XLSXProcessor) ExtractDocumentFromXLSXBytes(data []byte) (*Document, error) {
+	archive, err := openOfficeArchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedStrings, err := p.sharedStrings(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets, err := p.sheets(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	var result strings.Builder
+	texts := make([]string, 0, len(sheets))
+	pages := make([]PageReport, 0, len(sheets))
+
+	for i, sheet := range sheets {
+		rows, err := p.readRows(archive, sheet.part, sharedStrings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sheet %q: %w", sheet.name, err)
+		}
+
+		text := fmt.Sprintf("Sheet: %s\n\n%s", sheet.name, renderMarkdownTables(rows))
+		result.WriteString(fmt.Sprintf("\n\n--- Page %d ---\n\n", i+1))
+		result.WriteString(text)
+		texts = append(texts, text)
+		pages = append(pages, PageReport{Number: i + 1, Source: SourceText, Characters: len(strings.TrimSpace(text))})
+	}
+
+	return &Document{
+		Text:  result.String(),
+		Pages: newPages(texts, pages),
+		Report: DocumentReport{
+			TotalPages:     len(sheets),
+			Classification: Classification{Class: ClassDigital, TextPages: len(sheets)},
+			Pages:          pages,
+		},
+	}, nil
+}
+
+// xlsxSheet is a worksheet name and its part in the package
+type xlsxSheet struct {
+	name string
+	part string
+}
+
+// sheets returns the worksheets in workbook order
+func (p *XLSXProcessor) sheets(archive *zip.Reader) ([]xlsxSheet, error) {
+	data, err := readOfficePart(archive, xlsxWorkbookPart)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("not an Excel workbook: missing %s", xlsxWorkbookPart)
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name  string `xml:"name,attr"`
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(data, &workbook); err != nil {
+		return nil, fmt.Errorf("failed to parse workbook: %w", err)
+	}
+
+	rels, err := readOfficeRelationships(archive, xlsxWorkbookPart)
+	if err != nil {
+		return nil, err
+	}
+
+	var sheets []xlsxSheet
+	for _, sheet := range workbook.Sheets {
+		if rel, ok := rels[sheet.RelID]; ok {
+			sheets = append(sheets, xlsxSheet{name: sheet.Name, part: rel.Target})
+		}
+	}
+	return sheets, nil
+}
+
+// sharedStrings loads the shared strings table
+func (p *XLSXProcessor) sharedStrings(archive *zip.Reader) ([]string, error) {
+	data, err := readOfficePart(archive, xlsxSharedStringsPart)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var table struct {
+		Items []xlsxSharedString `xml:"si"`
+	}
+	if err := xml.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse shared strings: %w", err)
+	}
+
+	strs := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		strs[i] = item.Text
+		for _, run := range item.Runs {
+			strs[i] += run.Text
+		}
+	}
+	return strs, nil
+}
+
+// readRows returns the cell values of a worksheet as a dense grid
+func (p *XLSXProcessor) readRows(archive *zip.Reader, part string, sharedStrings []string) ([][]string, error) {
+	data, err := readOfficePart(archive, part)
+	if err != nil {
+		return nil, err
+	}
+
+	var worksheet struct {
+		Rows []struct {
+			Cells []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &worksheet); err != nil {
+		return nil, fmt.Errorf("failed to parse worksheet: %w", err)
+	}
+
+	var rows [][]string
+	for _, row := range worksheet.Rows {
+		var values []string
+		for i, cell := range row.Cells {
+			column := columnIndex(cell.Ref)
+			if column < 0 {
+				column = i
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+			values[column] = cellValue(cell, sharedStrings)
+		}
+
+		if strings.TrimSpace(strings.Join(values, "")) != "" {
+			rows = append(rows, values)
+		}
+	}
+	return rows, nil
+}
+
+// cellValue resolves the display value of a cell
+func cellValue(cell xlsxCell, sharedStrings []string) string {
+	switch cell.Type {
+	case "s":
+		index, err := strconv.Atoi(cell.Value)
+		if err != nil || index < 0 || index >= len(sharedStrings) {
+			return ""
+		}
+		return sharedStrings[index]
+	case "inlineStr":
+		text := cell.InlineStr.Text
+		for _, run := range cell.InlineStr.Runs {
+			text += run.Text
+		}
+		return text
+	case "b":
+		if cell.Value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return cell.Value
+	}
+}
+
+// columnIndex converts a cell reference such as "C7" to a zero-based column index
+func columnIndex(ref string) int {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 {
+		return -1
+	}
+	return index - 1
+}
+
+// renderMarkdownTables renders rows as Markdown tables, using the first row as header
+// and repeating it every xlsxRowsPerTable rows
+func renderMarkdownTables(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	header := rows[0]
+	body := rows[1:]
+
+	var tables strings.Builder
+	for start := 0; start == 0 || start < len(body); start += xlsxRowsPerTable {
+		if start > 0 {
+			tables.WriteString("\n")
+		}
+		tables.WriteString(markdownRow(header, width))
+		tables.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+
+		end := start + xlsxRowsPerTable
+		if end > len(body) {
+			end = len(body)
+		}
+		for _, row := range body[start:end] {
+			tables.WriteString(markdownRow(row, width))
+		}
+	}
+
+	return tables.String()
+}
+
+// markdownRow renders a single table row padded to width columns
+func markdownRow(row []string, width int) string {
+	var line strings.Builder
+	line.WriteString("|")
+	for i := 0; i < width; i++ {
+		value := ""
+		if i < len(row) {
+			value = strings.ReplaceAll(strings.TrimSpace(row[i]), "|", "\\|")
+			value = strings.ReplaceAll(value, "\n", " ")
+		}
+		line.WriteString(" " + value + " |")
+	}
+	line.WriteString("\n")
+	return line.String()
+}
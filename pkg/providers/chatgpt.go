@@ -2,17 +2,59 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/cache"
 )
 
+// SectionDelimiter separates logical sections in a chunking response. The chunker turns
+// each section into its own chunk; responses without it are a single chunk.
+const SectionDelimiter = "<<<SECTION>>>"
+
 // OpenAIRequest represents the request structure for OpenAI API
 type OpenAIRequest struct {
 	Model     string          `json:"model"`
 	Messages  []OpenAIMessage `json:"messages"`
-	MaxTokens int             `json:"max_tokens"`
+	MaxTokens int             `json:"max_tokens,omitempty"`
+
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"` // Replaces MaxTokens for reasoning models, see UsesMaxCompletionTokens
+
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"top_p,omitempty"`
+	PresencePenalty  *float64        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64        `json:"frequency_penalty,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ModelParams are optional sampling parameters sent with every request. Nil fields are
+// left out, so the API default applies; use Param to set them. Reasoning models (see
+// UsesMaxCompletionTokens) are only sent Seed and ResponseFormat.
+type ModelParams struct {
+	Temperature      *float64        // 0 to 2; lower is more deterministic
+	TopP             *float64        // Nucleus sampling, 0 to 1
+	PresencePenalty  *float64        // -2 to 2
+	FrequencyPenalty *float64        // -2 to 2
+	Seed             *int            // Best-effort reproducible sampling
+	ResponseFormat   *ResponseFormat // e.g. {Type: "json_object"}; the response text becomes the chunk text as is
+
+	ReasoningOutputTokens int // max_completion_tokens of reasoning models, reasoning included; 0 or less means DefaultReasoningOutputTokens
+}
+
+// ResponseFormat is the OpenAI response_format: "text", "json_object", or "json_schema"
+// with the schema in JSONSchema
+type ResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
+}
+
+// Param returns a pointer to v, for the optional fields of ModelParams
+func Param[T any](v T) *T {
+	return &v
 }
 
 // OpenAIMessage represents a message in the OpenAI API
@@ -23,18 +65,19 @@ type OpenAIMessage struct {
 
 // OpenAIResponse represents the response structure from OpenAI API
 type OpenAIResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-	Usage struct {
+	Choices []OpenAIChoice `json:"choices"`
+	Usage   struct {
 		PromptTokens     int `json:"prompt_tokens"`
 		CompletionTokens int `json:"completion_tokens"`
 		TotalTokens      int `json:"total_tokens"`
 	} `json:"usage"`
 }
 
+// OpenAIChoice is a completion in an OpenAI response
+type OpenAIChoice struct {
+	Message OpenAIMessage `json:"message"`
+}
+
 // TokenUsage represents token usage information
 type TokenUsage struct {
 	PromptTokens     int `json:"prompt_tokens"`
@@ -53,6 +96,13 @@ type ChatGPTProvider struct {
 	apiKey string
 	model  string
 	url    string
+	cache  cache.Cache
+	params ModelParams
+
+	name    string            // Set by NewOpenAICompatibleProvider; "ChatGPT" otherwise
+	headers map[string]string // Extra headers sent with every request, see WithHeaders
+	limiter *limiter          // Shared by the copies made after WithLimits
+	ctx     context.Context   // Requests are sent with it, see WithContext
 }
 
 // NewChatGPTProvider creates a new ChatGPT provider
@@ -80,6 +130,40 @@ func NewChatGPTProviderWithConfig(apiKey, model, url string) *ChatGPTProvider {
 	}
 }
 
+// WithCache returns a copy of the provider that stores responses in responseCache and
+// answers identical requests (same model and prompt) from it without calling the API.
+// Cached responses report zero token usage, as they cost nothing.
+func (c *ChatGPTProvider) WithCache(responseCache cache.Cache) *ChatGPTProvider {
+	provider := *c
+	provider.cache = responseCache
+	return &provider
+}
+
+// WithParams returns a copy of the provider that sends params with every request,
+// including batch requests. Cached responses are keyed by them too.
+func (c *ChatGPTProvider) WithParams(params ModelParams) *ChatGPTProvider {
+	provider := *c
+	provider.params = params
+	return &provider
+}
+
+// WithLimits returns a copy of the provider that enforces limits on its chunking
+// requests. The copy and every copy made from it share one limiter, so one instance
+// passed to several chunkers stays within the limits overall. Batch jobs are not limited.
+func (c *ChatGPTProvider) WithLimits(limits Limits) *ChatGPTProvider {
+	provider := *c
+	provider.limiter = newLimiter(limits)
+	return &provider
+}
+
+// WithContext returns a copy of the provider whose requests are sent with ctx, so they
+// are cancelled with it. Batch jobs are not.
+func (c *ChatGPTProvider) WithContext(ctx context.Context) *ChatGPTProvider {
+	provider := *c
+	provider.ctx = ctx
+	return &provider
+}
+
 // ChunkText uses ChatGPT to create intelligent chunks
 func (c *ChatGPTProvider) ChunkText(text string) (string, error) {
 	result, err := c.ChunkTextWithUsage(text)
@@ -91,6 +175,40 @@ func (c *ChatGPTProvider) ChunkText(text string) (string, error) {
 
 // ChunkTextWithUsage uses ChatGPT to create intelligent chunks and returns token usage
 func (c *ChatGPTProvider) ChunkTextWithUsage(text string) (*ChunkResult, error) {
+	return c.chunk(c.chunkRequest(text), chunkTokens(text))
+}
+
+// ChunkTextStrict is ChunkTextWithUsage with stricter instructions to keep the text
+// verbatim; the chunker uses it to retry outputs that failed validation
+func (c *ChatGPTProvider) ChunkTextStrict(text string) (*ChunkResult, error) {
+	request := c.chunkRequest(text)
+	request.Messages = strictChunkMessages(text)
+	return c.chunk(request, chunkTokens(text))
+}
+
+// chunk sends a chunking request and returns the chunked text with its token usage
+func (c *ChatGPTProvider) chunk(request OpenAIRequest, tokens int) (*ChunkResult, error) {
+	response, err := c.callAPICached(request, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("ChatGPT API call failed: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no response from ChatGPT API")
+	}
+
+	return &ChunkResult{
+		Text: response.Choices[0].Message.Content,
+		TokenUsage: TokenUsage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+// chunkMessages builds the system and user messages that ask a chat model to chunk text
+func chunkMessages(text string) []OpenAIMessage {
 	prompt := `You are an AI system optimizing document processing. If the chunking below fails or produces low-quality results, please gracefully degrade by returning the original text as fallback. Always include metadata like page numbers, chunk index, and document title in the output.
 
 Your task is to chunk the provided text into meaningful, coherent sections based on themes, topics, or logical flow.
@@ -103,6 +221,7 @@ Please analyze the text and create a well-structured chunk that:
 5. Makes the content easy to understand and navigate
 6. Always includes page numbers, chunk index, and document title in the output
 7. If chunking fails or produces poor results, return the original text with basic formatting
+8. If the text covers several distinct topics, separate the sections with a line containing only ` + SectionDelimiter + `
 
 IMPORTANT: If you cannot create a meaningful chunk or the result would be worse than the original, simply return the original text with basic headers and metadata extraction.
 
@@ -111,60 +230,146 @@ Text to chunk:
 
 Please return the chunked content with appropriate headers, sections, and formatting to make it clear and organized. If chunking is not beneficial, return the original text with basic structure.`
 
-	request := OpenAIRequest{
-		Model: c.model,
-		Messages: []OpenAIMessage{
-			{
-				Role:    "system",
-				Content: "You are an AI system optimizing document processing with intelligent chunking capabilities. You excel at organizing and structuring text content for better readability and understanding. Always prioritize preserving meaning and context over aggressive restructuring. If chunking would degrade the content quality, gracefully fall back to the original text with basic formatting and metadata extraction.",
-			},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
+	return []OpenAIMessage{
+		{
+			Role:    "system",
+			Content: "You are an AI system optimizing document processing with intelligent chunking capabilities. You excel at organizing and structuring text content for better readability and understanding. Always prioritize preserving meaning and context over aggressive restructuring. If chunking would degrade the content quality, gracefully fall back to the original text with basic formatting and metadata extraction.",
+		},
+		{
+			Role:    "user",
+			Content: prompt,
 		},
-		MaxTokens: 2000,
 	}
+}
 
-	response, err := c.callAPI(request)
-	if err != nil {
-		return nil, fmt.Errorf("ChatGPT API call failed: %w", err)
-	}
+// strictInstructions are added to the system message of a retried chunking request
+const strictInstructions = "Your previous answer for this text was rejected. Keep every sentence, number, date, name and code of the text exactly as written and never summarize or leave anything out; only add headings and section delimiter lines. Answer with the chunked text only, without any commentary about yourself or the task."
 
-	if len(response.Choices) == 0 {
-		return nil, fmt.Errorf("no response from ChatGPT API")
-	}
+// strictChunkMessages builds the chunkMessages of a retry after the chunker rejected an
+// output
+func strictChunkMessages(text string) []OpenAIMessage {
+	messages := chunkMessages(text)
+	messages[0].Content += " " + strictInstructions
+	return messages
+}
 
-	return &ChunkResult{
-		Text: response.Choices[0].Message.Content,
-		TokenUsage: TokenUsage{
-			PromptTokens:     response.Usage.PromptTokens,
-			CompletionTokens: response.Usage.CompletionTokens,
-			TotalTokens:      response.Usage.TotalTokens,
-		},
-	}, nil
+// chunkRequest builds the chat completion request that chunks text
+func (c *ChatGPTProvider) chunkRequest(text string) OpenAIRequest {
+	request := OpenAIRequest{
+		Model:    c.model,
+		Messages: chunkMessages(text),
+
+		Seed:           c.params.Seed,
+		ResponseFormat: c.params.ResponseFormat,
+	}
+	// Reasoning models reject sampling parameters other than the defaults
+	if UsesMaxCompletionTokens(c.model) {
+		request.MaxCompletionTokens = DefaultReasoningOutputTokens
+		if c.params.ReasoningOutputTokens > 0 {
+			request.MaxCompletionTokens = c.params.ReasoningOutputTokens
+		}
+		return request
+	}
+	request.MaxTokens = maxOutputTokens
+	request.Temperature = c.params.Temperature
+	request.TopP = c.params.TopP
+	request.PresencePenalty = c.params.PresencePenalty
+	request.FrequencyPenalty = c.params.FrequencyPenalty
+	return request
 }
 
 // GetName returns the provider name
 func (c *ChatGPTProvider) GetName() string {
+	if c.name != "" {
+		return c.name
+	}
 	return "ChatGPT"
 }
 
-// callAPI makes a request to the ChatGPT API
+// callAPICached answers the request from the cache when possible and caches
+// successful API responses. API calls wait for the limiter with the estimated tokens.
+func (c *ChatGPTProvider) callAPICached(request OpenAIRequest, tokens int) (*OpenAIResponse, error) {
+	call := func() (*OpenAIResponse, error) {
+		release, err := c.limiter.acquire(requestContext(c.ctx), tokens)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return c.callAPI(request)
+	}
+	if c.cache == nil {
+		return call()
+	}
+	return callCached(c.cache, c.model, c.url, c.headers, request, call)
+}
+
+// callCached answers request from responseCache when possible, and otherwise calls
+// call and caches its response when it has a completion. Entries are keyed by the model,
+// the endpoint URL, the extra headers sent with the request and the request itself.
+func callCached(responseCache cache.Cache, model, endpoint string, headers map[string]string, request any, call func() (*OpenAIResponse, error)) (*OpenAIResponse, error) {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	key := cache.Key(model, endpoint, headers, jsonData)
+
+	// Cache errors only cost an API call, so they do not fail the request
+	if cached, ok, err := responseCache.Get(key); err == nil && ok {
+		var response OpenAIResponse
+		if err := json.Unmarshal(cached, &response); err == nil && len(response.Choices) > 0 {
+			response.Usage.PromptTokens = 0
+			response.Usage.CompletionTokens = 0
+			response.Usage.TotalTokens = 0
+			return &response, nil
+		}
+	}
+
+	response, err := call()
+	if err != nil || len(response.Choices) == 0 {
+		return response, err
+	}
+	if data, err := json.Marshal(response); err == nil {
+		responseCache.Set(key, data)
+	}
+	return response, nil
+}
+
+// callAPI makes a request to the ChatGPT API, through the Responses API when the
+// provider URL ends in /responses
 func (c *ChatGPTProvider) callAPI(request OpenAIRequest) (*OpenAIResponse, error) {
+	if c.usesResponsesAPI() {
+		return c.callResponsesAPI(request)
+	}
+
+	body, err := c.post(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse response
+	var response OpenAIResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// post sends a JSON request to the provider URL and returns the response body
+func (c *ChatGPTProvider) post(request any) ([]byte, error) {
 	jsonData, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", c.url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(requestContext(c.ctx), "POST", c.url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	c.setHeaders(req)
 
 	// Make the request
 	client := &http.Client{}
@@ -179,12 +384,14 @@ func (c *ChatGPTProvider) callAPI(request OpenAIRequest) (*OpenAIResponse, error
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	return body, nil
+}
 
-	// Parse response
-	var response OpenAIResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+// requestContext returns the context a provider sends its requests with, which is
+// context.Background() until WithContext sets one
+func requestContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
 	}
-
-	return &response, nil
+	return ctx
 }
@@ -0,0 +1,109 @@
+package providers
+
+import (
+	"sync"
+	"testing"
+)
+
+// memoryCache is a cache.Cache in memory
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func (m *memoryCache) Get(key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.entries[key]
+	return value, ok, nil
+}
+
+func (m *memoryCache) Set(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = value
+	return nil
+}
+
+// TestCallCached checks that a cached response answers only the same request to the
+// same model, endpoint and extra headers
+func TestCallCached(t *testing.T) {
+	responseCache := &memoryCache{entries: make(map[string][]byte)}
+	calls := 0
+	call := func() (*OpenAIResponse, error) {
+		calls++
+		return &OpenAIResponse{Choices: []OpenAIChoice{{Message: OpenAIMessage{Role: "assistant", Content: "chunked"}}}}, nil
+	}
+	const endpoint = "https://api.openai.com/v1/chat/completions"
+	request := map[string]string{"text": "Minutes of the meeting."}
+
+	tests := []struct {
+		name      string
+		model     string
+		endpoint  string
+		headers   map[string]string
+		request   any
+		wantCalls int
+	}{
+		{"first request", "gpt-4o-mini", endpoint, nil, request, 1},
+		{"same request", "gpt-4o-mini", endpoint, map[string]string{}, request, 1},
+		{"other model", "gpt-4o", endpoint, nil, request, 2},
+		{"other endpoint", "gpt-4o-mini", "https://openrouter.ai/api/v1/chat/completions", nil, request, 3},
+		{"extra header", "gpt-4o-mini", endpoint, map[string]string{"OpenAI-Organization": "org-a"}, request, 4},
+		{"same extra header", "gpt-4o-mini", endpoint, map[string]string{"OpenAI-Organization": "org-a"}, request, 4},
+		{"other header value", "gpt-4o-mini", endpoint, map[string]string{"OpenAI-Organization": "org-b"}, request, 5},
+		{"other request", "gpt-4o-mini", endpoint, nil, map[string]string{"text": "Minutes of the next meeting."}, 6},
+	}
+	for _, test := range tests {
+		response, err := callCached(responseCache, test.model, test.endpoint, test.headers, test.request, call)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if calls != test.wantCalls {
+			t.Errorf("%s: %d API calls, want %d", test.name, calls, test.wantCalls)
+		}
+		if response.Choices[0].Message.Content != "chunked" {
+			t.Errorf("%s: response = %q, want chunked", test.name, response.Choices[0].Message.Content)
+		}
+	}
+}
+
+// TestChunkRequest checks that reasoning models get max_completion_tokens with their
+// own budget and no sampling parameters, and other models max_tokens with them all
+func TestChunkRequest(t *testing.T) {
+	params := ModelParams{
+		Temperature:      Param(0.0),
+		TopP:             Param(0.9),
+		PresencePenalty:  Param(0.1),
+		FrequencyPenalty: Param(0.2),
+		Seed:             Param(42),
+	}
+	tests := []struct {
+		model                string
+		reasoningTokens      int
+		wantMaxTokens        int
+		wantCompletionTokens int
+		wantSampling         bool
+	}{
+		{ModelGPT4oMini, 0, maxOutputTokens, 0, true},
+		{ModelGPT41, 8000, maxOutputTokens, 0, true},
+		{ModelO3Mini, 0, 0, DefaultReasoningOutputTokens, false},
+		{"o3-mini-2025-01-31", 0, 0, DefaultReasoningOutputTokens, false},
+		{ModelGPT5, 32000, 0, 32000, false},
+	}
+	for _, test := range tests {
+		params := params
+		params.ReasoningOutputTokens = test.reasoningTokens
+		request := NewChatGPTProviderWithConfig("key", test.model, "").WithParams(params).chunkRequest("Minutes of the meeting.")
+		if request.MaxTokens != test.wantMaxTokens || request.MaxCompletionTokens != test.wantCompletionTokens {
+			t.Errorf("%s: max_tokens %d and max_completion_tokens %d, want %d and %d", test.model, request.MaxTokens, request.MaxCompletionTokens, test.wantMaxTokens, test.wantCompletionTokens)
+		}
+		sampling := request.Temperature != nil || request.TopP != nil || request.PresencePenalty != nil || request.FrequencyPenalty != nil
+		if sampling != test.wantSampling {
+			t.Errorf("%s: sampling parameters sent = %v, want %v", test.model, sampling, test.wantSampling)
+		}
+		if request.Seed == nil || *request.Seed != 42 {
+			t.Errorf("%s: seed = %v, want 42", test.model, request.Seed)
+		}
+	}
+}
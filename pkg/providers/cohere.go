@@ -0,0 +1,368 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/cache"
+)
+
+// Cohere chat and embedding models for NewCohereProviderWithConfig
+const (
+	CohereCommandR     = "command-r-08-2024" // The default chat model
+	CohereCommandRPlus = "command-r-plus-08-2024"
+	CohereCommandA     = "command-a-03-2025"
+	CohereEmbedMulti   = "embed-multilingual-v3.0" // The default embedding model, for Indonesian and other non-English documents
+	CohereEmbedEnglish = "embed-english-v3.0"
+	CohereEmbedV4      = "embed-v4.0"
+)
+
+const (
+	cohereURL           = "https://api.cohere.com/v2"
+	cohereEmbedMaxTexts = 96 // Texts per embed request accepted by the API
+)
+
+// cohereChatRequest is the request structure of the Cohere v2 chat API
+type cohereChatRequest struct {
+	Model            string              `json:"model"`
+	Messages         []OpenAIMessage     `json:"messages"`
+	MaxTokens        int                 `json:"max_tokens,omitempty"`
+	Temperature      *float64            `json:"temperature,omitempty"`
+	P                *float64            `json:"p,omitempty"` // ModelParams.TopP
+	PresencePenalty  *float64            `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64            `json:"frequency_penalty,omitempty"`
+	Seed             *int                `json:"seed,omitempty"`
+	ResponseFormat   *cohereResponseType `json:"response_format,omitempty"`
+}
+
+// cohereResponseType is the Cohere response_format; it only knows text and json_object,
+// the latter with an optional schema
+type cohereResponseType struct {
+	Type       string          `json:"type"`
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
+}
+
+// cohereChatResponse is the response structure of the Cohere v2 chat API
+type cohereChatResponse struct {
+	Message struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"message"`
+	Usage struct {
+		BilledUnits struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"billed_units"`
+	} `json:"usage"`
+}
+
+// cohereEmbedRequest is the request structure of the Cohere v2 embed API
+type cohereEmbedRequest struct {
+	Model          string   `json:"model"`
+	Texts          []string `json:"texts"`
+	InputType      string   `json:"input_type"`
+	EmbeddingTypes []string `json:"embedding_types"`
+}
+
+// cohereEmbedResponse is the response structure of the Cohere v2 embed API
+type cohereEmbedResponse struct {
+	Embeddings struct {
+		Float [][]float32 `json:"float"`
+	} `json:"embeddings"`
+}
+
+// CohereProvider implements AIProvider with the Cohere chat API and EmbeddingProvider
+// with its embed API
+type CohereProvider struct {
+	apiKey     string
+	model      string
+	embedModel string
+	baseURL    string
+	cache      cache.Cache
+	params     ModelParams
+	limiter    *limiter        // Shared by the copies made after WithLimits, for chat and embed requests
+	ctx        context.Context // Requests are sent with it, see WithContext
+}
+
+// NewCohereProvider creates a new Cohere provider
+func NewCohereProvider(apiKey string) *CohereProvider {
+	return NewCohereProviderWithConfig(apiKey, "", "", "")
+}
+
+// NewCohereProviderWithConfig creates a new Cohere provider with custom configuration.
+// baseURL is the API root without /chat or /embed.
+func NewCohereProviderWithConfig(apiKey, model, embedModel, baseURL string) *CohereProvider {
+	if model == "" {
+		model = CohereCommandR
+	}
+	if embedModel == "" {
+		embedModel = CohereEmbedMulti
+	}
+	if baseURL == "" {
+		baseURL = cohereURL
+	}
+
+	return &CohereProvider{
+		apiKey:     apiKey,
+		model:      model,
+		embedModel: embedModel,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// WithCache returns a copy of the provider that stores chat responses in responseCache,
+// like ChatGPTProvider.WithCache
+func (c *CohereProvider) WithCache(responseCache cache.Cache) *CohereProvider {
+	provider := *c
+	provider.cache = responseCache
+	return &provider
+}
+
+// WithParams returns a copy of the provider that sends params with every chat request;
+// TopP is sent as p, and json_schema response formats as json_object with the schema
+func (c *CohereProvider) WithParams(params ModelParams) *CohereProvider {
+	provider := *c
+	provider.params = params
+	return &provider
+}
+
+// WithLimits returns a copy of the provider that enforces limits on its chat and embed
+// requests together, like ChatGPTProvider.WithLimits
+func (c *CohereProvider) WithLimits(limits Limits) *CohereProvider {
+	provider := *c
+	provider.limiter = newLimiter(limits)
+	return &provider
+}
+
+// WithContext returns a copy of the provider whose chat and embed requests are sent
+// with ctx, like ChatGPTProvider.WithContext
+func (c *CohereProvider) WithContext(ctx context.Context) *CohereProvider {
+	provider := *c
+	provider.ctx = ctx
+	return &provider
+}
+
+// ChunkText uses Cohere to create intelligent chunks
+func (c *CohereProvider) ChunkText(text string) (string, error) {
+	result, err := c.ChunkTextWithUsage(text)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// ChunkTextWithUsage uses Cohere to create intelligent chunks and returns the billed
+// token usage
+func (c *CohereProvider) ChunkTextWithUsage(text string) (*ChunkResult, error) {
+	request, err := c.chunkRequest(text)
+	if err != nil {
+		return nil, err
+	}
+	return c.chunk(request, chunkTokens(text))
+}
+
+// ChunkTextStrict is ChunkTextWithUsage with stricter instructions to keep the text
+// verbatim, like ChatGPTProvider.ChunkTextStrict
+func (c *CohereProvider) ChunkTextStrict(text string) (*ChunkResult, error) {
+	request, err := c.chunkRequest(text)
+	if err != nil {
+		return nil, err
+	}
+	request.Messages = strictChunkMessages(text)
+	return c.chunk(request, chunkTokens(text))
+}
+
+// chunk sends a chat request and returns the chunked text with its billed token usage
+func (c *CohereProvider) chunk(request cohereChatRequest, tokens int) (*ChunkResult, error) {
+	call := func() (*OpenAIResponse, error) {
+		release, err := c.limiter.acquire(requestContext(c.ctx), tokens)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return c.callChat(request)
+	}
+
+	var response *OpenAIResponse
+	var err error
+	if c.cache != nil {
+		response, err = callCached(c.cache, c.model, c.baseURL+"/chat", nil, request, call)
+	} else {
+		response, err = call()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cohere API call failed: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no response from Cohere API")
+	}
+
+	return &ChunkResult{
+		Text: response.Choices[0].Message.Content,
+		TokenUsage: TokenUsage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+// chunkRequest builds the chat request that chunks text
+func (c *CohereProvider) chunkRequest(text string) (cohereChatRequest, error) {
+	request := cohereChatRequest{
+		Model:            c.model,
+		Messages:         chunkMessages(text),
+		MaxTokens:        maxOutputTokens,
+		Temperature:      c.params.Temperature,
+		P:                c.params.TopP,
+		PresencePenalty:  c.params.PresencePenalty,
+		FrequencyPenalty: c.params.FrequencyPenalty,
+		Seed:             c.params.Seed,
+	}
+
+	switch format := c.params.ResponseFormat; {
+	case format == nil || format.Type == "text":
+	case format.Type == "json_object":
+		request.ResponseFormat = &cohereResponseType{Type: "json_object"}
+	case format.Type == "json_schema":
+		// OpenAI nests the schema next to its name; Cohere takes the schema itself
+		var settings struct {
+			Schema json.RawMessage `json:"schema"`
+		}
+		if len(format.JSONSchema) > 0 {
+			if err := json.Unmarshal(format.JSONSchema, &settings); err != nil {
+				return cohereChatRequest{}, fmt.Errorf("failed to parse JSON schema response format: %w", err)
+			}
+		}
+		request.ResponseFormat = &cohereResponseType{Type: "json_object", JSONSchema: settings.Schema}
+	default:
+		return cohereChatRequest{}, fmt.Errorf("unsupported Cohere response format: %s", format.Type)
+	}
+	return request, nil
+}
+
+// GetName returns the provider name
+func (c *CohereProvider) GetName() string {
+	return "Cohere"
+}
+
+// Embed returns the vectors of texts, embedded as search documents, in order. Texts
+// are sent in requests of at most 96.
+func (c *CohereProvider) Embed(texts []string) ([][]float32, error) {
+	return c.embed(texts, "search_document")
+}
+
+// EmbedQuery returns the vector of a search query, to compare with the vectors of Embed
+func (c *CohereProvider) EmbedQuery(query string) ([]float32, error) {
+	embeddings, err := c.embed([]string{query}, "search_query")
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// embed returns the vectors of texts of an input type in order
+func (c *CohereProvider) embed(texts []string, inputType string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += cohereEmbedMaxTexts {
+		batch := texts[start:min(start+cohereEmbedMaxTexts, len(texts))]
+		tokens := 0
+		for _, text := range batch {
+			tokens += len(text) / charsPerToken
+		}
+		release, err := c.limiter.acquire(requestContext(c.ctx), tokens)
+		if err != nil {
+			return nil, fmt.Errorf("Cohere embed call failed: %w", err)
+		}
+		body, err := c.post("/embed", cohereEmbedRequest{
+			Model:          c.embedModel,
+			Texts:          batch,
+			InputType:      inputType,
+			EmbeddingTypes: []string{"float"},
+		})
+		release()
+		if err != nil {
+			return nil, fmt.Errorf("Cohere embed call failed: %w", err)
+		}
+
+		var response cohereEmbedResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if len(response.Embeddings.Float) != len(batch) {
+			return nil, fmt.Errorf("Cohere returned %d embeddings for %d texts", len(response.Embeddings.Float), len(batch))
+		}
+		embeddings = append(embeddings, response.Embeddings.Float...)
+	}
+	return embeddings, nil
+}
+
+// callChat makes a request to the Cohere chat API and returns the answer in the OpenAI
+// format, so caching works the same as for the other providers
+func (c *CohereProvider) callChat(request cohereChatRequest) (*OpenAIResponse, error) {
+	body, err := c.post("/chat", request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response cohereChatResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var text strings.Builder
+	for _, content := range response.Message.Content {
+		if content.Type == "text" {
+			text.WriteString(content.Text)
+		}
+	}
+
+	result := &OpenAIResponse{}
+	if text.Len() > 0 {
+		result.Choices = []OpenAIChoice{{Message: OpenAIMessage{Role: "assistant", Content: text.String()}}}
+	}
+	result.Usage.PromptTokens = response.Usage.BilledUnits.InputTokens
+	result.Usage.CompletionTokens = response.Usage.BilledUnits.OutputTokens
+	result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	return result, nil
+}
+
+// post sends a JSON request to an endpoint under the API root and returns the response
+// body, failing on non-2xx statuses
+func (c *CohereProvider) post(endpoint string, request any) ([]byte, error) {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(requestContext(c.ctx), "POST", c.baseURL+endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"sync"
+)
+
+// FakeResponse is one scripted answer of a FakeProvider
+type FakeResponse struct {
+	Text       string // Returned as the chunked text; may contain SectionDelimiter
+	Err        error  // Returned instead of Text when set
+	TokenUsage TokenUsage
+}
+
+// FakeProvider is an AI provider for tests that answers with scripted responses
+// instead of calling an API. Once the script runs out it returns the text it was sent
+// unchanged. It is safe for concurrent use.
+type FakeProvider struct {
+	mu        sync.Mutex
+	responses []FakeResponse
+	handler   func(text string) (*ChunkResult, error)
+	requests  []string
+	strict    int
+}
+
+// NewFakeProvider creates a fake provider that answers with responses in order
+func NewFakeProvider(responses ...FakeResponse) *FakeProvider {
+	return &FakeProvider{responses: responses}
+}
+
+// NewFakeProviderFunc creates a fake provider that answers every request with handler,
+// e.g. to answer by content when Workers or AIWorkers make the order of requests vary
+func NewFakeProviderFunc(handler func(text string) (*ChunkResult, error)) *FakeProvider {
+	return &FakeProvider{handler: handler}
+}
+
+// ChunkText returns the next scripted response
+func (f *FakeProvider) ChunkText(text string) (string, error) {
+	result, err := f.ChunkTextWithUsage(text)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// ChunkTextWithUsage returns the next scripted response with its token usage
+func (f *FakeProvider) ChunkTextWithUsage(text string) (*ChunkResult, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, text)
+	if f.handler != nil {
+		f.mu.Unlock()
+		return f.handler(text)
+	}
+	if len(f.responses) == 0 {
+		f.mu.Unlock()
+		return &ChunkResult{Text: text}, nil
+	}
+	response := f.responses[0]
+	f.responses = f.responses[1:]
+	f.mu.Unlock()
+
+	if response.Err != nil {
+		return nil, response.Err
+	}
+	return &ChunkResult{Text: response.Text, TokenUsage: response.TokenUsage}, nil
+}
+
+// ChunkTextStrict answers a retry after the chunker rejected an output; it takes the
+// next scripted response like ChunkTextWithUsage, and Strict counts these calls
+func (f *FakeProvider) ChunkTextStrict(text string) (*ChunkResult, error) {
+	f.mu.Lock()
+	f.strict++
+	f.mu.Unlock()
+	return f.ChunkTextWithUsage(text)
+}
+
+// StructureText answers a structure request with the next scripted response, which
+// should be the JSON of ParseStructure; once the script runs out it answers with a
+// single section starting at line 1
+func (f *FakeProvider) StructureText(text string) (*ChunkResult, error) {
+	f.mu.Lock()
+	if f.handler == nil && len(f.responses) == 0 {
+		f.requests = append(f.requests, text)
+		f.mu.Unlock()
+		return &ChunkResult{Text: `{"sections": [{"line": 1, "title": ""}]}`}, nil
+	}
+	f.mu.Unlock()
+	return f.ChunkTextWithUsage(text)
+}
+
+// ExtractInvoice answers an invoice request with the next scripted response, which
+// should be the JSON of invoice.Parse; once the script runs out it answers with an
+// invoice without fields, which invoice.Parse rejects
+func (f *FakeProvider) ExtractInvoice(text string) (*ChunkResult, error) {
+	f.mu.Lock()
+	if f.handler == nil && len(f.responses) == 0 {
+		f.requests = append(f.requests, text)
+		f.mu.Unlock()
+		return &ChunkResult{Text: `{}`}, nil
+	}
+	f.mu.Unlock()
+	return f.ChunkTextWithUsage(text)
+}
+
+// Strict returns the number of ChunkTextStrict calls so far
+func (f *FakeProvider) Strict() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.strict
+}
+
+// Requests returns the texts sent so far, in order
+func (f *FakeProvider) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+// GetName returns the provider name
+func (f *FakeProvider) GetName() string {
+	return "Fake"
+}
+
+// IsLocal reports that the fake provider never sends content anywhere
+func (f *FakeProvider) IsLocal() bool {
+	return true
+}
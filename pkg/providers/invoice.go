@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"encoding/json"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/invoice"
+)
+
+// invoiceMessages builds the messages that ask a chat model for the key fields of an
+// invoice or receipt as JSON in the form of invoice.Schema
+func invoiceMessages(text string) []OpenAIMessage {
+	prompt := `Extract the key fields of the invoice or receipt below: the vendor, the invoice or receipt number, the issue date as YYYY-MM-DD, the ISO 4217 currency code, the subtotal, the tax, the total amount due and every line item with its description, quantity, unit price and amount.
+
+Answer with JSON only, in the form {"vendor": "...", "number": "...", "date": "YYYY-MM-DD", "currency": "...", "subtotal": 0, "tax": 0, "total": 0, "line_items": [{"description": "...", "quantity": 0, "unit_price": 0, "amount": 0}]}. Write amounts as plain numbers without thousand separators and leave out fields the document does not show.
+
+` + text
+
+	return []OpenAIMessage{
+		{
+			Role:    "system",
+			Content: "You are an AI system that reads invoices and receipts. You only answer with their fields as JSON, never inventing values.",
+		},
+		{
+			Role:    "user",
+			Content: prompt,
+		},
+	}
+}
+
+// ExtractInvoice asks ChatGPT for the fields of an invoice with invoice.Schema; the result
+// text is the JSON answer, see invoice.Parse
+func (c *ChatGPTProvider) ExtractInvoice(text string) (*ChunkResult, error) {
+	request := c.chunkRequest(text)
+	request.Messages = invoiceMessages(text)
+	request.ResponseFormat = &ResponseFormat{Type: "json_schema", JSONSchema: invoice.Schema}
+	return c.chunk(request, chunkTokens(text))
+}
+
+// ExtractInvoice asks Mistral for the fields of an invoice, like ChatGPTProvider.ExtractInvoice
+func (m *MistralProvider) ExtractInvoice(text string) (*ChunkResult, error) {
+	request := m.chunkRequest(text)
+	request.Messages = invoiceMessages(text)
+	request.ResponseFormat = &ResponseFormat{Type: "json_schema", JSONSchema: invoice.Schema}
+	return m.chunk(request, chunkTokens(text))
+}
+
+// ExtractInvoice asks Cohere for the fields of an invoice, like ChatGPTProvider.ExtractInvoice
+func (c *CohereProvider) ExtractInvoice(text string) (*ChunkResult, error) {
+	request, err := c.chunkRequest(text)
+	if err != nil {
+		return nil, err
+	}
+	// Cohere takes the schema itself, without its name
+	var settings struct {
+		Schema json.RawMessage `json:"schema"`
+	}
+	if err := json.Unmarshal(invoice.Schema, &settings); err != nil {
+		return nil, err
+	}
+	request.Messages = invoiceMessages(text)
+	request.ResponseFormat = &cohereResponseType{Type: "json_object", JSONSchema: settings.Schema}
+	return c.chunk(request, chunkTokens(text))
+}
@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ratelimit"
+)
+
+// Limits bounds the API requests of a provider instance, across every chunker that uses
+// it. Cached responses do not count.
+type Limits struct {
+	MaxConcurrentRequests int // Requests in flight at once; 0 is unlimited
+	RequestsPerMinute     int // 0 is unlimited
+	TokensPerMinute       int // Estimated prompt plus completion tokens; 0 is unlimited
+}
+
+// Token estimates for TokensPerMinute, matching the chunker's cost plan
+const (
+	charsPerToken        = 4   // Average for English and Indonesian text
+	promptOverheadTokens = 350 // Chunking instructions sent with every slice
+)
+
+// limiter enforces Limits with a semaphore and token buckets; a nil limiter is unlimited
+type limiter struct {
+	rate  *ratelimit.Limiter
+	slots chan struct{}
+}
+
+// newLimiter creates the limiter of limits, or nil when they are all unlimited
+func newLimiter(limits Limits) *limiter {
+	if limits.MaxConcurrentRequests <= 0 && limits.RequestsPerMinute <= 0 && limits.TokensPerMinute <= 0 {
+		return nil
+	}
+
+	l := &limiter{}
+	if limits.RequestsPerMinute > 0 || limits.TokensPerMinute > 0 {
+		l.rate = ratelimit.New(limits.RequestsPerMinute, limits.TokensPerMinute)
+	}
+	if limits.MaxConcurrentRequests > 0 {
+		l.slots = make(chan struct{}, limits.MaxConcurrentRequests)
+	}
+	return l
+}
+
+// acquire blocks until a request using tokens fits the limits and returns the function
+// that releases its concurrency slot. It fails with the error of ctx, holding no slot,
+// when ctx is done first.
+func (l *limiter) acquire(ctx context.Context, tokens int) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	// Take the slot first, so requests queued for it do not use up the rate allowance
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+	if err := l.rate.Wait(ctx, tokens); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// chunkTokens estimates the prompt and completion tokens of a chunking request for text
+func chunkTokens(text string) int {
+	return len(text)/charsPerToken + promptOverheadTokens + maxOutputTokens
+}
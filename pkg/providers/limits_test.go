@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestAcquireCancelled checks that acquire fails with the error of its context while it
+// waits for a concurrency slot or the rate limits, and holds no slot afterwards
+func TestAcquireCancelled(t *testing.T) {
+	l := newLimiter(Limits{MaxConcurrentRequests: 1, RequestsPerMinute: 1})
+	release, err := l.acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Waiting for the slot held by the first request
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire for a busy slot error = %v, want context.DeadlineExceeded", err)
+	}
+	release()
+
+	// Waiting a minute for the rate limit
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := l.acquire(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire over the rate limit error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("acquire returned after %v, want soon after the context is done", elapsed)
+	}
+	if len(l.slots) != 0 {
+		t.Errorf("%d slots held after a cancelled acquire, want 0", len(l.slots))
+	}
+}
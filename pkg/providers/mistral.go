@@ -0,0 +1,216 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/cache"
+)
+
+// Mistral models for NewMistralProviderWithConfig
+const (
+	MistralSmall  = "mistral-small-latest" // The default
+	MistralMedium = "mistral-medium-latest"
+	MistralLarge  = "mistral-large-latest"
+	MistralNemo   = "open-mistral-nemo"
+)
+
+// mistralURL is the Mistral chat completions endpoint, hosted in the EU
+const mistralURL = "https://api.mistral.ai/v1/chat/completions"
+
+// MistralRequest represents the request structure for the Mistral chat completions API
+type MistralRequest struct {
+	Model            string          `json:"model"`
+	Messages         []OpenAIMessage `json:"messages"`
+	MaxTokens        int             `json:"max_tokens"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"top_p,omitempty"`
+	PresencePenalty  *float64        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64        `json:"frequency_penalty,omitempty"`
+	RandomSeed       *int            `json:"random_seed,omitempty"` // ModelParams.Seed
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// MistralProvider implements AIProvider for Mistral AI
+type MistralProvider struct {
+	apiKey  string
+	model   string
+	url     string
+	cache   cache.Cache
+	params  ModelParams
+	limiter *limiter        // Shared by the copies made after WithLimits
+	ctx     context.Context // Requests are sent with it, see WithContext
+}
+
+// NewMistralProvider creates a new Mistral provider
+func NewMistralProvider(apiKey string) *MistralProvider {
+	return NewMistralProviderWithConfig(apiKey, "", "")
+}
+
+// NewMistralProviderWithConfig creates a new Mistral provider with custom configuration
+func NewMistralProviderWithConfig(apiKey, model, url string) *MistralProvider {
+	if url == "" {
+		url = mistralURL
+	}
+	if model == "" {
+		model = MistralSmall
+	}
+
+	return &MistralProvider{
+		apiKey: apiKey,
+		model:  model,
+		url:    url,
+	}
+}
+
+// WithCache returns a copy of the provider that stores responses in responseCache, like
+// ChatGPTProvider.WithCache
+func (m *MistralProvider) WithCache(responseCache cache.Cache) *MistralProvider {
+	provider := *m
+	provider.cache = responseCache
+	return &provider
+}
+
+// WithParams returns a copy of the provider that sends params with every request; Seed
+// is sent as random_seed
+func (m *MistralProvider) WithParams(params ModelParams) *MistralProvider {
+	provider := *m
+	provider.params = params
+	return &provider
+}
+
+// WithLimits returns a copy of the provider that enforces limits on its requests, like
+// ChatGPTProvider.WithLimits
+func (m *MistralProvider) WithLimits(limits Limits) *MistralProvider {
+	provider := *m
+	provider.limiter = newLimiter(limits)
+	return &provider
+}
+
+// WithContext returns a copy of the provider whose requests are sent with ctx, like
+// ChatGPTProvider.WithContext
+func (m *MistralProvider) WithContext(ctx context.Context) *MistralProvider {
+	provider := *m
+	provider.ctx = ctx
+	return &provider
+}
+
+// ChunkText uses Mistral to create intelligent chunks
+func (m *MistralProvider) ChunkText(text string) (string, error) {
+	result, err := m.ChunkTextWithUsage(text)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// ChunkTextWithUsage uses Mistral to create intelligent chunks and returns token usage
+func (m *MistralProvider) ChunkTextWithUsage(text string) (*ChunkResult, error) {
+	return m.chunk(m.chunkRequest(text), chunkTokens(text))
+}
+
+// ChunkTextStrict is ChunkTextWithUsage with stricter instructions to keep the text
+// verbatim, like ChatGPTProvider.ChunkTextStrict
+func (m *MistralProvider) ChunkTextStrict(text string) (*ChunkResult, error) {
+	request := m.chunkRequest(text)
+	request.Messages = strictChunkMessages(text)
+	return m.chunk(request, chunkTokens(text))
+}
+
+// chunk sends a chunking request and returns the chunked text with its token usage
+func (m *MistralProvider) chunk(request MistralRequest, tokens int) (*ChunkResult, error) {
+	call := func() (*OpenAIResponse, error) {
+		release, err := m.limiter.acquire(requestContext(m.ctx), tokens)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return m.callAPI(request)
+	}
+
+	var response *OpenAIResponse
+	var err error
+	if m.cache != nil {
+		response, err = callCached(m.cache, m.model, m.url, nil, request, call)
+	} else {
+		response, err = call()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Mistral API call failed: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no response from Mistral API")
+	}
+
+	return &ChunkResult{
+		Text: response.Choices[0].Message.Content,
+		TokenUsage: TokenUsage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+// chunkRequest builds the chat completion request that chunks text
+func (m *MistralProvider) chunkRequest(text string) MistralRequest {
+	return MistralRequest{
+		Model:            m.model,
+		Messages:         chunkMessages(text),
+		MaxTokens:        maxOutputTokens,
+		Temperature:      m.params.Temperature,
+		TopP:             m.params.TopP,
+		PresencePenalty:  m.params.PresencePenalty,
+		FrequencyPenalty: m.params.FrequencyPenalty,
+		RandomSeed:       m.params.Seed,
+		ResponseFormat:   m.params.ResponseFormat,
+	}
+}
+
+// GetName returns the provider name
+func (m *MistralProvider) GetName() string {
+	return "Mistral"
+}
+
+// callAPI makes a request to the Mistral API. Its responses have the same choices and
+// usage fields as OpenAI's.
+func (m *MistralProvider) callAPI(request MistralRequest) (*OpenAIResponse, error) {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(requestContext(m.ctx), "POST", m.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var response OpenAIResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &response, nil
+}
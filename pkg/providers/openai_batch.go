@@ -0,0 +1,219 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// BatchRequest is one chunking request of an OpenAI batch
+type BatchRequest struct {
+	CustomID string // Identifies the request in the batch results
+	Text     string
+}
+
+// BatchStatus is the state of an OpenAI batch
+type BatchStatus struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"` // validating, in_progress, finalizing, completed, failed, expired, cancelling or cancelled
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+// Done reports whether the batch has stopped running, successfully or not
+func (s *BatchStatus) Done() bool {
+	switch s.Status {
+	case "completed", "failed", "expired", "cancelled":
+		return true
+	default:
+		return false
+	}
+}
+
+// batchLine is a request line of a batch input file
+type batchLine struct {
+	CustomID string        `json:"custom_id"`
+	Method   string        `json:"method"`
+	URL      string        `json:"url"`
+	Body     OpenAIRequest `json:"body"`
+}
+
+// batchResultLine is a line of a batch output file
+type batchResultLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int            `json:"status_code"`
+		Body       OpenAIResponse `json:"body"`
+	} `json:"response"`
+}
+
+// SubmitBatch uploads the chunking requests and starts an OpenAI batch, which completes
+// within 24 hours at half the price of regular requests. It returns the batch ID.
+func (c *ChatGPTProvider) SubmitBatch(requests []BatchRequest) (string, error) {
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	for _, request := range requests {
+		line := batchLine{
+			CustomID: request.CustomID,
+			Method:   "POST",
+			URL:      "/v1/chat/completions",
+			Body:     c.chunkRequest(request.Text),
+		}
+		if err := encoder.Encode(line); err != nil {
+			return "", fmt.Errorf("failed to marshal batch request: %w", err)
+		}
+	}
+
+	fileID, err := c.uploadBatchFile(input.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"input_file_id":     fileID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	var status BatchStatus
+	if err := c.callJSON("POST", "/batches", "application/json", bytes.NewReader(body), &status); err != nil {
+		return "", fmt.Errorf("failed to create batch: %w", err)
+	}
+	return status.ID, nil
+}
+
+// GetBatch returns the current state of a batch
+func (c *ChatGPTProvider) GetBatch(id string) (*BatchStatus, error) {
+	var status BatchStatus
+	if err := c.callJSON("GET", "/batches/"+id, "", nil, &status); err != nil {
+		return nil, fmt.Errorf("failed to get batch %s: %w", id, err)
+	}
+	return &status, nil
+}
+
+// BatchResults downloads the results of a finished batch, keyed by custom ID.
+// Requests that failed are missing from the map.
+func (c *ChatGPTProvider) BatchResults(status *BatchStatus) (map[string]*ChunkResult, error) {
+	results := make(map[string]*ChunkResult)
+	if status.OutputFileID == "" {
+		return results, nil
+	}
+
+	output, err := c.callAPIRaw("GET", "/files/"+status.OutputFileID+"/content", "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download batch results: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line batchResultLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("failed to parse batch result: %w", err)
+		}
+		if line.Response == nil || line.Response.StatusCode != http.StatusOK || len(line.Response.Body.Choices) == 0 {
+			continue
+		}
+
+		body := line.Response.Body
+		results[line.CustomID] = &ChunkResult{
+			Text: body.Choices[0].Message.Content,
+			TokenUsage: TokenUsage{
+				PromptTokens:     body.Usage.PromptTokens,
+				CompletionTokens: body.Usage.CompletionTokens,
+				TotalTokens:      body.Usage.TotalTokens,
+			},
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch results: %w", err)
+	}
+	return results, nil
+}
+
+// uploadBatchFile uploads a batch input file and returns its file ID
+func (c *ChatGPTProvider) uploadBatchFile(data []byte) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("purpose", "batch"); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+	part, err := form.CreateFormFile("file", "chunking_batch.jsonl")
+	if err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := c.callJSON("POST", "/files", form.FormDataContentType(), &body, &file); err != nil {
+		return "", fmt.Errorf("failed to upload batch file: %w", err)
+	}
+	return file.ID, nil
+}
+
+// callJSON calls an OpenAI API endpoint and decodes the JSON response into out
+func (c *ChatGPTProvider) callJSON(method, path, contentType string, body io.Reader, out any) error {
+	data, err := c.callAPIRaw(method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// callAPIRaw calls an OpenAI API endpoint relative to the provider's base URL and
+// returns the response body, failing on non-2xx statuses
+func (c *ChatGPTProvider) callAPIRaw(method, path, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, c.baseURL()+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.setHeaders(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("API returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// baseURL returns the API root, e.g. https://api.openai.com/v1, derived from the chat
+// completions or responses URL
+func (c *ChatGPTProvider) baseURL() string {
+	url := strings.TrimSuffix(c.url, "/")
+	return strings.TrimSuffix(strings.TrimSuffix(url, "/chat/completions"), "/responses")
+}
@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Base URLs of OpenAI-compatible gateways for NewOpenAICompatibleProvider
+const (
+	OpenRouterURL = "https://openrouter.ai/api/v1"
+	GroqURL       = "https://api.groq.com/openai/v1"
+	TogetherURL   = "https://api.together.xyz/v1"
+)
+
+// NewOpenAICompatibleProvider creates a provider for any endpoint that implements the
+// OpenAI chat completions API, such as OpenRouter, Groq, Together or a self-hosted vLLM
+// server. baseURL is the API root without /chat/completions, e.g. OpenRouterURL or
+// http://localhost:8000/v1; name is reported by GetName. An empty apiKey sends no
+// Authorization header.
+func NewOpenAICompatibleProvider(name, baseURL, apiKey, model string) *ChatGPTProvider {
+	return &ChatGPTProvider{
+		apiKey: apiKey,
+		model:  model,
+		url:    strings.TrimSuffix(baseURL, "/") + "/chat/completions",
+		name:   name,
+	}
+}
+
+// WithHeaders returns a copy of the provider that sends headers with every request,
+// e.g. OpenRouter's HTTP-Referer and X-Title attribution headers
+func (c *ChatGPTProvider) WithHeaders(headers map[string]string) *ChatGPTProvider {
+	provider := *c
+	provider.headers = maps.Clone(c.headers)
+	if provider.headers == nil {
+		provider.headers = make(map[string]string, len(headers))
+	}
+	maps.Copy(provider.headers, headers)
+	return &provider
+}
+
+// IsLocal reports whether the endpoint runs on this machine (localhost or a loopback
+// address), e.g. a local vLLM or Ollama server, for the LocalOnly guard
+func (c *ChatGPTProvider) IsLocal() bool {
+	endpoint, err := url.Parse(c.url)
+	if err != nil {
+		return false
+	}
+	host := endpoint.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// setHeaders sets the authorization and extra headers of an API request
+func (c *ChatGPTProvider) setHeaders(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+}
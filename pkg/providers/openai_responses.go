@@ -0,0 +1,168 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OpenAI models for NewChatGPTProviderWithConfig. Reasoning models (the o-series and
+// gpt-5) are sent max_completion_tokens instead of max_tokens, and no sampling
+// parameters.
+const (
+	ModelGPT35Turbo = "gpt-3.5-turbo" // The default
+	ModelGPT4o      = "gpt-4o"
+	ModelGPT4oMini  = "gpt-4o-mini"
+	ModelGPT41      = "gpt-4.1"
+	ModelGPT41Mini  = "gpt-4.1-mini"
+	ModelGPT5       = "gpt-5"
+	ModelGPT5Mini   = "gpt-5-mini"
+	ModelO1         = "o1"
+	ModelO3         = "o3"
+	ModelO3Mini     = "o3-mini"
+	ModelO4Mini     = "o4-mini"
+)
+
+// maxOutputTokens is the completion limit requested per slice
+const maxOutputTokens = 2000
+
+// DefaultReasoningOutputTokens is the completion limit requested per slice from
+// reasoning models unless ModelParams.ReasoningOutputTokens is set. Their reasoning
+// counts against it, so maxOutputTokens would often leave no room for the answer.
+const DefaultReasoningOutputTokens = 16000
+
+// ResponsesURL is the OpenAI Responses API endpoint, see WithResponsesAPI
+const ResponsesURL = "https://api.openai.com/v1/responses"
+
+// UsesMaxCompletionTokens reports whether model rejects max_tokens and takes
+// max_completion_tokens instead: the o-series reasoning models and gpt-5, including
+// dated snapshots such as o3-mini-2025-01-31
+func UsesMaxCompletionTokens(model string) bool {
+	model = strings.ToLower(model)
+	if strings.HasPrefix(model, "gpt-5") {
+		return true
+	}
+	return len(model) > 1 && model[0] == 'o' && model[1] >= '0' && model[1] <= '9'
+}
+
+// WithResponsesAPI returns a copy of the provider that calls the /responses endpoint
+// next to its chat completions URL instead. Batch jobs keep using chat completions.
+func (c *ChatGPTProvider) WithResponsesAPI() *ChatGPTProvider {
+	provider := *c
+	provider.url = c.baseURL() + "/responses"
+	return &provider
+}
+
+// usesResponsesAPI reports whether the provider URL is a Responses API endpoint
+func (c *ChatGPTProvider) usesResponsesAPI() bool {
+	return strings.HasSuffix(strings.TrimSuffix(c.url, "/"), "/responses")
+}
+
+// responsesRequest is the request structure of the Responses API
+type responsesRequest struct {
+	Model           string         `json:"model"`
+	Instructions    string         `json:"instructions,omitempty"`
+	Input           string         `json:"input"`
+	MaxOutputTokens int            `json:"max_output_tokens,omitempty"`
+	Temperature     *float64       `json:"temperature,omitempty"`
+	TopP            *float64       `json:"top_p,omitempty"`
+	Text            map[string]any `json:"text,omitempty"`
+}
+
+// responsesResponse is the response structure of the Responses API
+type responsesResponse struct {
+	Output []struct {
+		Type    string `json:"type"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"output"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// callResponsesAPI sends a chat completions request through the Responses API and
+// returns the answer in the chat completions format, so caching and usage tracking
+// work the same for both
+func (c *ChatGPTProvider) callResponsesAPI(request OpenAIRequest) (*OpenAIResponse, error) {
+	converted, err := newResponsesRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.post(converted)
+	if err != nil {
+		return nil, err
+	}
+
+	var response responsesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("API error: %s", response.Error.Message)
+	}
+
+	var text strings.Builder
+	for _, output := range response.Output {
+		if output.Type != "message" {
+			continue // Reasoning summaries and tool calls
+		}
+		for _, content := range output.Content {
+			if content.Type == "output_text" {
+				text.WriteString(content.Text)
+			}
+		}
+	}
+
+	result := &OpenAIResponse{}
+	if text.Len() > 0 {
+		result.Choices = []OpenAIChoice{{Message: OpenAIMessage{Role: "assistant", Content: text.String()}}}
+	}
+	result.Usage.PromptTokens = response.Usage.InputTokens
+	result.Usage.CompletionTokens = response.Usage.OutputTokens
+	result.Usage.TotalTokens = response.Usage.TotalTokens
+	return result, nil
+}
+
+// newResponsesRequest converts a chat completions request: system messages become the
+// instructions and the rest the input. The Responses API has no penalties or seed, so
+// those parameters are dropped.
+func newResponsesRequest(request OpenAIRequest) (*responsesRequest, error) {
+	converted := &responsesRequest{
+		Model:           request.Model,
+		MaxOutputTokens: max(request.MaxTokens, request.MaxCompletionTokens),
+		Temperature:     request.Temperature,
+		TopP:            request.TopP,
+	}
+
+	var instructions, input []string
+	for _, message := range request.Messages {
+		if message.Role == "system" {
+			instructions = append(instructions, message.Content)
+		} else {
+			input = append(input, message.Content)
+		}
+	}
+	converted.Instructions = strings.Join(instructions, "\n\n")
+	converted.Input = strings.Join(input, "\n\n")
+
+	if format := request.ResponseFormat; format != nil {
+		// json_schema settings move from a nested object up into the format itself
+		textFormat := map[string]any{}
+		if len(format.JSONSchema) > 0 {
+			if err := json.Unmarshal(format.JSONSchema, &textFormat); err != nil {
+				return nil, fmt.Errorf("failed to parse JSON schema response format: %w", err)
+			}
+		}
+		textFormat["type"] = format.Type
+		converted.Text = map[string]any{"format": textFormat}
+	}
+	return converted, nil
+}
@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Section is a section of a text found by a structure-only request: where it starts
+// and its title. The text itself is never sent back, so it cannot be altered.
+type Section struct {
+	Line  int    `json:"line"` // First line of the section in StructureLines, starting at 1
+	Title string `json:"title"`
+}
+
+// StructureLines splits text into the lines numbered in structure requests, keeping
+// their line breaks so joining them gives text back
+func StructureLines(text string) []string {
+	return strings.SplitAfter(text, "\n")
+}
+
+// ParseStructure parses the JSON answer of a structure request, tolerating a Markdown
+// code fence around it
+func ParseStructure(answer string) ([]Section, error) {
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(answer, "```") {
+		answer = strings.TrimPrefix(answer, "```json")
+		answer = strings.TrimPrefix(answer, "```")
+		answer = strings.TrimSuffix(strings.TrimSpace(answer), "```")
+	}
+
+	var structure struct {
+		Sections []Section `json:"sections"`
+	}
+	if err := json.Unmarshal([]byte(answer), &structure); err != nil {
+		return nil, fmt.Errorf("failed to parse structure: %w", err)
+	}
+	if len(structure.Sections) == 0 {
+		return nil, fmt.Errorf("structure has no sections")
+	}
+	return structure.Sections, nil
+}
+
+// structureMessages builds the messages that ask a chat model for the sections of text
+// as JSON line numbers and titles, without the text
+func structureMessages(text string) []OpenAIMessage {
+	var numbered strings.Builder
+	for i, line := range StructureLines(text) {
+		fmt.Fprintf(&numbered, "%d| %s", i+1, line)
+	}
+
+	prompt := `The lines of a document are numbered below. Find where each coherent section (topic, chapter, article or group of related clauses) begins and give it a short title in the language of the document.
+
+Answer with JSON only, in the form {"sections": [{"line": 1, "title": "..."}]}, listing the first line number of every section in order, starting with line 1. Never repeat, correct or rewrite the text itself.
+
+` + numbered.String()
+
+	return []OpenAIMessage{
+		{
+			Role:    "system",
+			Content: "You are an AI system that finds the logical structure of documents without changing them. You only answer with section boundaries as JSON.",
+		},
+		{
+			Role:    "user",
+			Content: prompt,
+		},
+	}
+}
+
+// StructureText asks ChatGPT for the sections of text; the result text is the JSON
+// answer, see ParseStructure
+func (c *ChatGPTProvider) StructureText(text string) (*ChunkResult, error) {
+	request := c.chunkRequest(text)
+	request.Messages = structureMessages(text)
+	request.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	return c.chunk(request, chunkTokens(text))
+}
+
+// StructureText asks Mistral for the sections of text, like ChatGPTProvider.StructureText
+func (m *MistralProvider) StructureText(text string) (*ChunkResult, error) {
+	request := m.chunkRequest(text)
+	request.Messages = structureMessages(text)
+	request.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	return m.chunk(request, chunkTokens(text))
+}
+
+// StructureText asks Cohere for the sections of text, like ChatGPTProvider.StructureText
+func (c *CohereProvider) StructureText(text string) (*ChunkResult, error) {
+	request, err := c.chunkRequest(text)
+	if err != nil {
+		return nil, err
+	}
+	request.Messages = structureMessages(text)
+	request.ResponseFormat = &cohereResponseType{Type: "json_object"}
+	return c.chunk(request, chunkTokens(text))
+}
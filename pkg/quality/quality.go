@@ -0,0 +1,136 @@
+// Package quality scores how much usable text a chunk holds, from its length, information
+// density, share of common words and share of OCR noise, so junk chunks such as lone page
+// numbers and scanning artifacts can be left out before embedding
+package quality
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/summary"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// Scoring parameters
+const (
+	fullLengthWords  = 30   // Words from which a chunk is long enough to score in full
+	diversityWords   = 200  // Words compared for diversity, so long chunks are not penalized
+	fullStopwords    = 0.15 // Share of common words from which text reads as prose
+	excessStopwords  = 0.7  // Share of common words above which text is mostly filler
+	longVowellessRun = 5    // Letters from which a lower-case word without vowels is noise
+)
+
+// Score scores the text of a chunk, leaving out the headings and labels the formatter adds
+func Score(text string) *schema.Quality {
+	var tokens []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if utils.IsMetadataLine(line) {
+			continue
+		}
+		tokens = append(tokens, strings.Fields(line)...)
+	}
+
+	quality := &schema.Quality{}
+	var letters, characters, garbage, stopwords int
+	distinct := make(map[string]bool)
+	for i, token := range tokens {
+		for _, r := range token {
+			characters++
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				letters++
+			}
+		}
+		if isGarbage(token) {
+			garbage++
+			continue
+		}
+		word := strings.ToLower(strings.TrimFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))
+		if word == "" {
+			continue
+		}
+		quality.Words++
+		if summary.IsStopWord(word) {
+			stopwords++
+		}
+		if i < diversityWords {
+			distinct[word] = true
+		}
+	}
+	if len(tokens) == 0 || characters == 0 {
+		return quality
+	}
+
+	quality.Garbage = float64(garbage) / float64(len(tokens))
+	if quality.Words > 0 {
+		diversity := float64(len(distinct)) / float64(min(quality.Words, diversityWords))
+		quality.Density = float64(letters) / float64(characters) * min(1, 2*diversity)
+		quality.Stopwords = float64(stopwords) / float64(quality.Words)
+	}
+
+	length := math.Sqrt(min(1, float64(quality.Words)/fullLengthWords))
+	clean := max(0, 1-2*quality.Garbage)
+	prose := min(1, quality.Stopwords/fullStopwords)
+	if quality.Stopwords > excessStopwords {
+		prose = max(0, 1-(quality.Stopwords-excessStopwords)/(1-excessStopwords))
+	}
+	quality.Score = round(length * (0.4*clean + 0.4*quality.Density + 0.2*prose))
+	quality.Density = round(quality.Density)
+	quality.Stopwords = round(quality.Stopwords)
+	quality.Garbage = round(quality.Garbage)
+	return quality
+}
+
+// wordSymbols are the characters other than letters and digits found in ordinary words,
+// numbers and amounts
+const wordSymbols = `-–—'’‘"“”.,:;!?()[]{}/%$€£¥+=&@#*°§_`
+
+// isGarbage reports whether a token looks like OCR noise: a run of symbols, unusual
+// symbols in a word, a long lower-case word without vowels, or letters of mixed case
+// throughout as in "tHiS"
+func isGarbage(token string) bool {
+	runes := []rune(token)
+	var letters, digits, symbols, upper, lower, vowels, caseChanges int
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+				if i > 0 && unicode.IsLower(runes[i-1]) {
+					caseChanges++
+				}
+			} else {
+				lower++
+			}
+			if strings.ContainsRune("aeiouyAEIOUY", r) {
+				vowels++
+			}
+		case unicode.IsDigit(r):
+			digits++
+		case !strings.ContainsRune(wordSymbols, r):
+			symbols++
+		}
+	}
+
+	switch {
+	case letters+digits == 0:
+		return len(runes) >= 2 // A lone dash or bullet is punctuation, "|||" is not
+	case symbols > 0:
+		return true
+	case digits == 0 && upper == 0 && lower >= longVowellessRun && vowels == 0:
+		return true
+	case lower > 0 && upper > 1 && caseChanges > 1:
+		return true
+	}
+	return false
+}
+
+// round rounds a score to 3 decimals, enough to compare with MinQualityScore
+func round(x float64) float64 {
+	return math.Round(x*1000) / 1000
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter enforces requests-per-minute and tokens-per-minute limits with token buckets.
+// It is safe for concurrent use, so one Limiter can be shared by every caller of an API.
+type Limiter struct {
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+}
+
+// bucket refills continuously up to its capacity of one minute's allowance
+type bucket struct {
+	capacity  float64
+	available float64
+	perSecond float64
+	updated   time.Time
+}
+
+// New creates a limiter; a limit of 0 or less disables that dimension
+func New(requestsPerMinute, tokensPerMinute int) *Limiter {
+	return &Limiter{
+		requests: newBucket(requestsPerMinute),
+		tokens:   newBucket(tokensPerMinute),
+	}
+}
+
+// newBucket creates a full bucket, or nil for an unlimited one
+func newBucket(perMinute int) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &bucket{
+		capacity:  float64(perMinute),
+		available: float64(perMinute),
+		perSecond: float64(perMinute) / 60,
+		updated:   time.Now(),
+	}
+}
+
+// Wait blocks until one request using the given number of tokens fits both limits,
+// then reserves it. It returns the error of ctx, reserving nothing, when ctx is done
+// first.
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l.mu.Lock()
+		now := time.Now()
+		delay := max(l.requests.delay(now, 1), l.tokens.delay(now, float64(tokens)))
+		if delay == 0 {
+			l.requests.take(1)
+			l.tokens.take(float64(tokens))
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Allow reserves one request using the given number of tokens when it fits both limits
+// now; otherwise it reserves nothing and returns how long until it would fit
+func (l *Limiter) Allow(tokens int) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	delay := max(l.requests.delay(now, 1), l.tokens.delay(now, float64(tokens)))
+	if delay > 0 {
+		return false, delay
+	}
+	l.requests.take(1)
+	l.tokens.take(float64(tokens))
+	return true, 0
+}
+
+// delay refills the bucket and returns how long until amount is available. Amounts
+// larger than the capacity only wait for a full bucket so they can never block forever.
+func (b *bucket) delay(now time.Time, amount float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.available = min(b.capacity, b.available+now.Sub(b.updated).Seconds()*b.perSecond)
+	b.updated = now
+
+	amount = min(amount, b.capacity)
+	if b.available >= amount {
+		return 0
+	}
+	return time.Duration((amount - b.available) / b.perSecond * float64(time.Second))
+}
+
+// take removes amount from the bucket, allowing oversized amounts to drive it negative
+func (b *bucket) take(amount float64) {
+	if b != nil {
+		b.available -= amount
+	}
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestWaitCancelled checks that Wait returns the error of its context during a long
+// delay, without reserving anything
+func TestWaitCancelled(t *testing.T) {
+	l := New(1, 0)
+	if err := l.Wait(context.Background(), 0); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+
+	// The next request fits in a minute, long after the context is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := l.Wait(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Wait returned after %v, want soon after the context is done", elapsed)
+	}
+	if l.requests.available < -0.01 {
+		t.Errorf("cancelled Wait reserved a request: %.2f available", l.requests.available)
+	}
+}
+
+func TestWaitContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := New(60, 0).Wait(ctx, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait error = %v, want context.Canceled", err)
+	}
+	if err := (*Limiter)(nil).Wait(ctx, 0); err != nil {
+		t.Errorf("nil Limiter Wait error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,53 @@
+package redact
+
+import (
+	"reflect"
+	"testing"
+)
+
+// javaNIK is a NIK of West Java that also passes the Luhn check of card numbers
+const javaNIK = "3201231501900007"
+
+func TestRedactNIKPassingLuhn(t *testing.T) {
+	if !luhnValid(javaNIK) {
+		t.Fatalf("%s should pass the Luhn check", javaNIK)
+	}
+	tests := []struct {
+		name  string
+		kinds []string
+		want  string
+		count Counts
+	}{
+		{"nik only", []string{KindNIK}, "NIK: [NIK]", Counts{KindNIK: 1}},
+		{"every kind", nil, "NIK: [NIK]", Counts{KindNIK: 1}},
+		{"cards only", []string{KindCreditCard}, "NIK: [CREDIT_CARD]", Counts{KindCreditCard: 1}},
+		{"neither", []string{KindEmail}, "NIK: " + javaNIK, Counts{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, counts := New(tt.kinds...).Redact("NIK: " + javaNIK)
+			if got != tt.want || !reflect.DeepEqual(counts, tt.count) {
+				t.Fatalf("Redact = %q, %v; want %q, %v", got, counts, tt.want, tt.count)
+			}
+		})
+	}
+}
+
+func TestRedactNumbers(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"card 4111111111111111", "card [CREDIT_CARD]"},          // Visa; 41 is no province
+		{"card 4111 1111 1111 1111", "card [CREDIT_CARD]"},       // Grouped digits are never a NIK
+		{"card 3782 822463 10005", "card [CREDIT_CARD]"},         // Amex
+		{"NIK 3174095506880002", "NIK [NIK]"},                    // Jakarta, a woman born 15 June 1988
+		{"NPWP 01.234.567.8-901.000", "NPWP [NPWP]"},             // Formatted
+		{"NPWP 012345678901000", "NPWP [NPWP]"},                  // Plain
+		{"invoice 4111111111111112", "invoice 4111111111111112"}, // Fails Luhn, no province
+	}
+	for _, tt := range tests {
+		if got, _ := New().Redact(tt.text); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
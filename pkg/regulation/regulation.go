@@ -0,0 +1,322 @@
+// Package regulation parses Indonesian regulations (UU, PP, Perpres, Permen, ...) into a
+// tree of Bab, Bagian, Paragraf, Pasal and Ayat, so they can be chunked at Pasal
+// boundaries and every chunk cited like "UU 13/2003 Pasal 59 Ayat (2)"
+package regulation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Kinds of Node, from the outermost to the innermost
+const (
+	KindBab      = "Bab"
+	KindBagian   = "Bagian"
+	KindParagraf = "Paragraf"
+	KindPasal    = "Pasal"
+	KindAyat     = "Ayat"
+)
+
+// ranks orders the kinds of Node; a node contains the nodes of higher rank after it
+var ranks = map[string]int{KindBab: 1, KindBagian: 2, KindParagraf: 3, KindPasal: 4, KindAyat: 5}
+
+// Heading lines of the kinds of Node. Pasal and Ayat references inside sentences, such
+// as "sebagaimana dimaksud dalam Pasal 59", never fill a line on their own.
+var (
+	babPattern      = regexp.MustCompile(`^BAB\s+([IVXLCDM]+|\d+)(?:\s+(\p{Lu}[^a-z]*))?$`)
+	bagianPattern   = regexp.MustCompile(`^(?:Bagian|BAGIAN)\s+(Ke[a-z]+|KE[A-Z]+|\d+)$`)
+	paragrafPattern = regexp.MustCompile(`^(?:Paragraf|PARAGRAF)\s+(\d+)$`)
+	pasalPattern    = regexp.MustCompile(`^(?:Pasal|PASAL)\s+(\d+[A-Z]?|[IVXLC]+)$`)
+	ayatPattern     = regexp.MustCompile(`^(?:Ayat\s+)?\((\d+[a-z]?)\)`)
+)
+
+// pageSeparatorPattern matches the "--- Page N ---" lines between the pages of a document
+var pageSeparatorPattern = regexp.MustCompile(`^--- Page \d+ ---$`)
+
+// elucidationPattern matches the heading of the Penjelasan that follows the body
+var elucidationPattern = regexp.MustCompile(`^PENJELASAN\b`)
+
+// closingPattern matches the start of the promulgation formula after the last Pasal
+var closingPattern = regexp.MustCompile(`^(?:Agar setiap orang mengetahuinya|Ditetapkan di|Disahkan di)\b`)
+
+// regulationTypes maps the regulation types of document headers to their abbreviations;
+// longer names come first so they win over their prefixes
+var regulationTypes = []struct {
+	name         string
+	abbreviation string
+}{
+	{"PERATURAN PEMERINTAH PENGGANTI UNDANG-UNDANG", "Perppu"},
+	{"UNDANG-UNDANG", "UU"},
+	{"PERATURAN PEMERINTAH", "PP"},
+	{"PERATURAN PRESIDEN", "Perpres"},
+	{"KEPUTUSAN PRESIDEN", "Keppres"},
+	{"PERATURAN MENTERI", "Permen"},
+	{"PERATURAN BANK INDONESIA", "PBI"},
+	{"PERATURAN OTORITAS JASA KEUANGAN", "POJK"},
+	{"PERATURAN DAERAH", "Perda"},
+}
+
+// numberPattern matches the "NOMOR 13 TAHUN 2003" line of a document header
+var numberPattern = regexp.MustCompile(`NOMOR\s*:?\s*(\d+[A-Z0-9/.-]*)\s+TAHUN\s+(\d{4})`)
+
+// maxHeaderLength bounds the text searched for the regulation type, number and title
+const maxHeaderLength = 3000
+
+// Node is a Bab, Bagian, Paragraf, Pasal or Ayat of a regulation
+type Node struct {
+	Kind     string
+	Number   string // "IX", "Kesatu", "59A", or "2" for Ayat (2)
+	Title    string // Title of a Bab, Bagian or Paragraf, e.g. "HUBUNGAN KERJA"
+	Start    int    // Byte offset of the node's heading in the parsed text
+	End      int    // Byte offset just past the node's last line, before the next node of the same or a lower rank
+	Parent   *Node
+	Children []*Node
+}
+
+// Heading returns the heading of the node with its title, e.g. "BAB IX HUBUNGAN KERJA",
+// "Bagian Kesatu Umum", "Pasal 59" or "Ayat (2)"
+func (n *Node) Heading() string {
+	var heading string
+	switch n.Kind {
+	case KindBab:
+		heading = "BAB " + n.Number
+	case KindAyat:
+		heading = "Ayat (" + n.Number + ")"
+	default:
+		heading = n.Kind + " " + n.Number
+	}
+	if n.Title != "" {
+		heading += " " + n.Title
+	}
+	return heading
+}
+
+// Ancestor returns the closest node of a kind that contains n, or nil
+func (n *Node) Ancestor(kind string) *Node {
+	for parent := n.Parent; parent != nil; parent = parent.Parent {
+		if parent.Kind == kind {
+			return parent
+		}
+	}
+	return nil
+}
+
+// Regulation is the parsed structure of a regulation
+type Regulation struct {
+	Type             string  // Abbreviated type, e.g. "UU", "PP" or "Permen"; empty when the header does not name one
+	Number           string  // e.g. "13"
+	Year             string  // e.g. "2003"
+	Title            string  // Subject after TENTANG, e.g. "KETENAGAKERJAAN"
+	Body             []*Node // Outermost nodes of the body: Bab, or Pasal in regulations without Bab
+	BodyStart        int     // Offset of the first node of the body; the text before it is the preamble
+	ClosingStart     int     // Offset of the promulgation formula after the last Pasal, or ElucidationStart when there is none
+	Elucidation      []*Node // Outermost nodes of the Penjelasan, usually Pasal
+	ElucidationStart int     // Offset of the PENJELASAN heading, or the length of the text when there is none
+}
+
+// Name returns the short name of the regulation, e.g. "UU 13/2003", or "" when the
+// header does not name its type and number
+func (r *Regulation) Name() string {
+	if r.Type == "" || r.Number == "" {
+		return ""
+	}
+	name := r.Type + " " + r.Number
+	if r.Year != "" {
+		name += "/" + r.Year
+	}
+	return name
+}
+
+// Cite returns the citation of a Pasal, or of some of its Ayat, e.g. "UU 13/2003 Pasal 59
+// Ayat (2)" or "UU 13/2003 Pasal 59 Ayat (2)–(4)". Pasal of the Penjelasan are cited as
+// "Penjelasan UU 13/2003 Pasal 59".
+func (r *Regulation) Cite(pasal *Node, ayat ...*Node) string {
+	var parts []string
+	if pasal.Start >= r.ElucidationStart {
+		parts = append(parts, "Penjelasan")
+	}
+	if name := r.Name(); name != "" {
+		parts = append(parts, name)
+	}
+	parts = append(parts, pasal.Heading())
+	switch {
+	case len(ayat) == 1:
+		parts = append(parts, ayat[0].Heading())
+	case len(ayat) > 1:
+		parts = append(parts, fmt.Sprintf("Ayat (%s)–(%s)", ayat[0].Number, ayat[len(ayat)-1].Number))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Pasals returns the Pasal among nodes and their descendants, in order
+func Pasals(nodes []*Node) []*Node {
+	var pasals []*Node
+	for _, node := range nodes {
+		if node.Kind == KindPasal {
+			pasals = append(pasals, node)
+			continue
+		}
+		pasals = append(pasals, Pasals(node.Children)...)
+	}
+	return pasals
+}
+
+// Parse parses the structure of a regulation. Headings are recognized when they fill a
+// line, as regulations are typeset; "--- Page N ---" separators are skipped. Text that is
+// not a regulation parses to a Regulation without nodes.
+func Parse(text string) *Regulation {
+	r := &Regulation{BodyStart: len(text), ClosingStart: -1, ElucidationStart: len(text)}
+	p := &parser{roots: &r.Body}
+
+	// Nodes end with their last line of content, without the blank lines and page
+	// separators before the next heading
+	offset, contentEnd := 0, 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		start := offset
+		offset += len(line)
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || pageSeparatorPattern.MatchString(trimmed) {
+			continue
+		}
+
+		switch {
+		case r.ElucidationStart == len(text) && len(r.Body) > 0 && elucidationPattern.MatchString(trimmed):
+			p.closeAll(contentEnd)
+			r.ElucidationStart = start
+			p = &parser{roots: &r.Elucidation}
+		case r.ClosingStart < 0 && r.ElucidationStart == len(text) && len(r.Body) > 0 && closingPattern.MatchString(trimmed):
+			// The signatures and promulgation are not part of the last Pasal
+			p.closeAll(contentEnd)
+			r.ClosingStart = start
+		case r.ClosingStart >= 0 && r.ElucidationStart == len(text):
+		default:
+			if node := p.parseLine(trimmed, start, contentEnd); node != nil && r.BodyStart == len(text) {
+				r.BodyStart = node.Start
+			}
+		}
+		contentEnd = start + len(strings.TrimRightFunc(line, unicode.IsSpace))
+	}
+	p.closeAll(contentEnd)
+	if r.ClosingStart < 0 {
+		r.ClosingStart = r.ElucidationStart
+	}
+
+	r.parseHeader(text[:min(r.BodyStart, maxHeaderLength)])
+	return r
+}
+
+// parseHeader reads the type, number, year and title of the regulation from the text
+// before its body
+func (r *Regulation) parseHeader(header string) {
+	typeIndex := len(header)
+	for _, regulationType := range regulationTypes {
+		if index := strings.Index(header, regulationType.name); index >= 0 && index < typeIndex {
+			typeIndex = index
+			r.Type = regulationType.abbreviation
+		}
+	}
+	if match := numberPattern.FindStringSubmatch(header); match != nil {
+		r.Number, r.Year = match[1], match[2]
+	}
+
+	index := strings.Index(header, "TENTANG")
+	if index < 0 {
+		return
+	}
+	var title []string
+	for _, line := range strings.Split(header[index+len("TENTANG"):], "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			if len(title) > 0 {
+				break
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "DENGAN RAHMAT") || len(title) == 3 {
+			break
+		}
+		title = append(title, trimmed)
+	}
+	r.Title = strings.Join(title, " ")
+}
+
+// parser builds the nodes of the body or of the Penjelasan line by line
+type parser struct {
+	roots *[]*Node
+	open  []*Node // The node of each rank enclosing the current line
+	title *Node   // The Bab, Bagian or Paragraf whose title is expected on the next line
+}
+
+// parseLine adds the node a line starts, or the title of the last Bab, Bagian or
+// Paragraf, and returns the new node or nil. Nodes closed by the new node end at
+// contentEnd.
+func (p *parser) parseLine(line string, start, contentEnd int) *Node {
+	node := headingNode(line)
+	if node == nil {
+		if p.title != nil {
+			if p.title.Title != "" {
+				p.title.Title += " "
+			}
+			p.title.Title += line
+			// Bab titles in capitals may take two lines
+			if p.title.Kind != KindBab || strings.ToUpper(line) != line || strings.Count(p.title.Title, " ") > 12 {
+				p.title = nil
+			}
+		}
+		return nil
+	}
+
+	p.title = nil
+	rank := ranks[node.Kind]
+	for len(p.open) > 0 && ranks[p.open[len(p.open)-1].Kind] >= rank {
+		p.open[len(p.open)-1].End = contentEnd
+		p.open = p.open[:len(p.open)-1]
+	}
+	// Numbered lines outside a Pasal are lists, not Ayat
+	if node.Kind == KindAyat && (len(p.open) == 0 || p.open[len(p.open)-1].Kind != KindPasal) {
+		return nil
+	}
+
+	node.Start = start
+	if len(p.open) == 0 {
+		*p.roots = append(*p.roots, node)
+	} else {
+		node.Parent = p.open[len(p.open)-1]
+		node.Parent.Children = append(node.Parent.Children, node)
+	}
+	p.open = append(p.open, node)
+	if node.Kind != KindPasal && node.Kind != KindAyat && node.Title == "" {
+		p.title = node
+	}
+	return node
+}
+
+// closeAll ends every open node at offset
+func (p *parser) closeAll(offset int) {
+	for _, node := range p.open {
+		node.End = offset
+	}
+	p.open = nil
+}
+
+// headingNode returns the node a heading line starts, without its offsets, or nil
+func headingNode(line string) *Node {
+	if match := babPattern.FindStringSubmatch(line); match != nil {
+		return &Node{Kind: KindBab, Number: match[1], Title: match[2]}
+	}
+	if match := bagianPattern.FindStringSubmatch(line); match != nil {
+		return &Node{Kind: KindBagian, Number: match[1]}
+	}
+	if match := paragrafPattern.FindStringSubmatch(line); match != nil {
+		return &Node{Kind: KindParagraf, Number: match[1]}
+	}
+	if match := pasalPattern.FindStringSubmatch(line); match != nil {
+		return &Node{Kind: KindPasal, Number: match[1]}
+	}
+	if match := ayatPattern.FindStringSubmatch(line); match != nil {
+		return &Node{Kind: KindAyat, Number: match[1]}
+	}
+	return nil
+}
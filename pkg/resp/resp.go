@@ -0,0 +1,189 @@
+// Package resp is a minimal Redis client: it sends commands over a single connection
+// in the Redis serialization protocol, for the Redis cache and coordinator.
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Options configures a Redis connection
+type Options struct {
+	Addr     string        // host:port, default "localhost:6379"
+	Password string        // Sent with AUTH when set
+	DB       int           // Selected with SELECT when not 0
+	Timeout  time.Duration // Dial and command timeout, default 5s
+}
+
+// Client sends commands to a Redis server over a single connection that is redialled
+// after errors. It is safe for concurrent use; commands are sent one at a time.
+type Client struct {
+	options Options
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Error is an error reply from the server
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// New creates a client; it connects on the first command
+func New(options Options) *Client {
+	if options.Addr == "" {
+		options.Addr = "localhost:6379"
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Second
+	}
+	return &Client{options: options}
+}
+
+// Do sends a command and returns its reply: []byte for simple and bulk strings, int64
+// for integers, []any for arrays and nil for nil replies
+func (c *Client) Do(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.dial(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(args)
+	if err != nil {
+		if _, isReplyError := err.(Error); !isReplyError {
+			// The connection state is unknown after an I/O error
+			c.conn.Close()
+			c.conn = nil
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// dial opens the connection and authenticates
+func (c *Client) dial() error {
+	conn, err := net.DialTimeout("tcp", c.options.Addr, c.options.Timeout)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+
+	var setup [][]string
+	if c.options.Password != "" {
+		setup = append(setup, []string{"AUTH", c.options.Password})
+	}
+	if c.options.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.options.DB)})
+	}
+	for _, args := range setup {
+		if _, err := c.roundTrip(args); err != nil {
+			conn.Close()
+			c.conn = nil
+			return fmt.Errorf("%s failed: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// roundTrip writes a command as a RESP array and reads one reply
+func (c *Client) roundTrip(args []string) (any, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(c.options.Timeout)); err != nil {
+		return nil, err
+	}
+
+	command := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		command += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	if _, err := io.WriteString(c.conn, command); err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+// readReply reads a simple string, error, integer, bulk string or array reply
+func (c *Client) readReply() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("malformed redis reply %q", line)
+	}
+	payload := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return []byte(payload), nil
+	case '-':
+		return nil, Error(payload)
+	case ':':
+		n, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis reply %q", line)
+		}
+		return n, nil
+	case '$':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis reply %q", line)
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:length], nil
+	case '*':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis reply %q", line)
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		items := make([]any, length)
+		for i := range items {
+			item, err := c.readReply()
+			// Error replies inside arrays, as EXEC returns, are kept as items
+			if replyErr, ok := err.(Error); ok {
+				item = replyErr
+			} else if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
@@ -0,0 +1,169 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name, reply string
+		want        any
+		wantErr     error
+	}{
+		{"simple string", "+OK\r\n", []byte("OK"), nil},
+		{"error", "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", nil, Error("WRONGTYPE Operation against a key holding the wrong kind of value")},
+		{"integer", ":-42\r\n", int64(-42), nil},
+		{"bulk string", "$7\r\nline\r\n2\r\n", []byte("line\r\n2"), nil},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}, nil},
+		{"nil bulk string", "$-1\r\n", nil, nil},
+		{"nil array", "*-1\r\n", nil, nil},
+		{"array", "*3\r\n$5\r\nfield\r\n:1\r\n*1\r\n+nested\r\n", []any{[]byte("field"), int64(1), []any{[]byte("nested")}}, nil},
+		{"array with an error item", "*2\r\n+OK\r\n-ERR no such key\r\n", []any{[]byte("OK"), Error("ERR no such key")}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Client{reader: bufio.NewReader(strings.NewReader(test.reply))}
+			reply, err := c.readReply()
+			if err != test.wantErr {
+				t.Fatalf("readReply() error = %v, want %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(reply, test.want) {
+				t.Errorf("readReply() = %#v, want %#v", reply, test.want)
+			}
+		})
+	}
+}
+
+func TestReadReplyMalformed(t *testing.T) {
+	for _, reply := range []string{
+		"\r\n",
+		"?what\r\n",
+		":forty-two\r\n",
+		"$five\r\nhello\r\n",
+		"$5\r\nhel",
+		"*2\r\n+OK\r\n",
+		"*x\r\n",
+	} {
+		c := &Client{reader: bufio.NewReader(strings.NewReader(reply))}
+		if _, err := c.readReply(); err == nil {
+			t.Errorf("readReply(%q) succeeded, want an error", reply)
+		} else if _, isReplyError := err.(Error); isReplyError {
+			t.Errorf("readReply(%q) error = %v, want a protocol error", reply, err)
+		}
+	}
+}
+
+// fakeServer answers commands with replies keyed by command name, and records every
+// command it received with the connection it arrived on
+type fakeServer struct {
+	listener net.Listener
+	replies  map[string]string
+	commands chan []string
+}
+
+func newFakeServer(t *testing.T, replies map[string]string) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener, replies: replies, commands: make(chan []string, 100)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for conn := 1; ; conn++ {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c, strconv.Itoa(conn))
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn, id string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := []string{id}
+		for range n {
+			reader.ReadString('\n') // $<length>
+			arg, _ := reader.ReadString('\n')
+			args = append(args, strings.TrimSuffix(arg, "\r\n"))
+		}
+		s.commands <- args
+		reply, ok := s.replies[args[1]]
+		if !ok {
+			return // Drops the connection
+		}
+		io.WriteString(conn, reply)
+	}
+}
+
+// TestDo checks that a client authenticates and selects its database on dial, keeps its
+// connection after error replies and redials after I/O errors
+func TestDo(t *testing.T) {
+	server := newFakeServer(t, map[string]string{
+		"AUTH":   "+OK\r\n",
+		"SELECT": "+OK\r\n",
+		"GET":    "$5\r\nvalue\r\n",
+		"INCR":   "-ERR value is not an integer or out of range\r\n",
+	})
+	c := New(Options{Addr: server.listener.Addr().String(), Password: "secret", DB: 2, Timeout: 5 * time.Second})
+	defer c.Close()
+
+	if reply, err := c.Do("GET", "key"); err != nil || string(reply.([]byte)) != "value" {
+		t.Fatalf("Do(GET) = %v, %v, want value", reply, err)
+	}
+	var replyErr Error
+	if _, err := c.Do("INCR", "key"); !errors.As(err, &replyErr) {
+		t.Fatalf("Do(INCR) error = %v, want an error reply", err)
+	}
+	// The server drops the connection instead of answering
+	if _, err := c.Do("QUIT"); err == nil {
+		t.Fatal("Do(QUIT) succeeded, want an I/O error")
+	}
+	if _, err := c.Do("GET", "key"); err != nil {
+		t.Fatalf("Do(GET) after an I/O error: %v", err)
+	}
+
+	want := [][]string{
+		{"1", "AUTH", "secret"},
+		{"1", "SELECT", "2"},
+		{"1", "GET", "key"},
+		{"1", "INCR", "key"},
+		{"1", "QUIT"},
+		{"2", "AUTH", "secret"},
+		{"2", "SELECT", "2"},
+		{"2", "GET", "key"},
+	}
+	for _, command := range want {
+		if got := <-server.commands; !reflect.DeepEqual(got, command) {
+			t.Errorf("connection %s received %q, want %q", got[0], got[1:], command[1:])
+		}
+	}
+}
+
+func TestDoAuthFailure(t *testing.T) {
+	server := newFakeServer(t, map[string]string{"AUTH": "-WRONGPASS invalid username-password pair\r\n"})
+	c := New(Options{Addr: server.listener.Addr().String(), Password: "wrong", Timeout: 5 * time.Second})
+	defer c.Close()
+
+	_, err := c.Do("PING")
+	var replyErr Error
+	if !errors.As(err, &replyErr) || !strings.HasPrefix(err.Error(), "AUTH failed: redis: WRONGPASS") {
+		t.Fatalf("Do error = %v, want AUTH failed: redis: WRONGPASS", err)
+	}
+}
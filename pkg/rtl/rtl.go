@@ -0,0 +1,184 @@
+// Package rtl repairs right-to-left text extracted from PDFs: Arabic presentation forms
+// are replaced with the letters they shape, and lines extracted in visual order, with
+// the letters of every word reversed, are put back in logical order. It also detects
+// right-to-left text and converts Arabic-Indic digits.
+package rtl
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Positions of an Arabic presentation form in its word
+const (
+	isolated = iota
+	final
+	initial
+	medial
+)
+
+// presentationForm is the letter a presentation form shapes and its position
+type presentationForm struct {
+	letters  string
+	position int
+}
+
+// presentationForms maps the Arabic presentation forms used by PDF fonts to letters
+var presentationForms = map[rune]presentationForm{}
+
+func init() {
+	// Arabic Presentation Forms-B run from U+FE80 through the letters in order, with two
+	// forms (isolated, final) for letters that do not join the next one and four
+	// (isolated, final, initial, medial) for the others
+	form := rune(0xFE80)
+	presentationForms[form] = presentationForm{"ء", isolated}
+	form++
+	for _, letter := range []struct {
+		letter rune
+		forms  int
+	}{
+		{0x0622, 2}, {0x0623, 2}, {0x0624, 2}, {0x0625, 2}, {0x0626, 4}, {0x0627, 2},
+		{0x0628, 4}, {0x0629, 2}, {0x062A, 4}, {0x062B, 4}, {0x062C, 4}, {0x062D, 4},
+		{0x062E, 4}, {0x062F, 2}, {0x0630, 2}, {0x0631, 2}, {0x0632, 2}, {0x0633, 4},
+		{0x0634, 4}, {0x0635, 4}, {0x0636, 4}, {0x0637, 4}, {0x0638, 4}, {0x0639, 4},
+		{0x063A, 4}, {0x0641, 4}, {0x0642, 4}, {0x0643, 4}, {0x0644, 4}, {0x0645, 4},
+		{0x0646, 4}, {0x0647, 4}, {0x0648, 2}, {0x0649, 2}, {0x064A, 4},
+	} {
+		for position := range letter.forms {
+			presentationForms[form] = presentationForm{string(letter.letter), position}
+			form++
+		}
+	}
+	// Lam-alef ligatures, isolated and final
+	for _, alef := range []rune{0x0622, 0x0623, 0x0625, 0x0627} {
+		presentationForms[form] = presentationForm{"ل" + string(alef), isolated}
+		presentationForms[form+1] = presentationForm{"ل" + string(alef), final}
+		form += 2
+	}
+
+	// Persian and Urdu letters of Presentation Forms-A, four forms each
+	for start, letter := range map[rune]rune{
+		0xFB56: 0x067E, // Peh
+		0xFB7A: 0x0686, // Tcheh
+		0xFB8E: 0x06A9, // Keheh
+		0xFB92: 0x06AF, // Gaf
+		0xFBFC: 0x06CC, // Farsi yeh
+	} {
+		for position := range 4 {
+			presentationForms[start+rune(position)] = presentationForm{string(letter), position}
+		}
+	}
+	presentationForms[0xFB8A] = presentationForm{"ژ", isolated} // Jeh
+	presentationForms[0xFB8B] = presentationForm{"ژ", final}
+}
+
+// IsRTL reports whether most letters of a text are of a right-to-left script
+func IsRTL(text string) bool {
+	rtl, ltr := 0, 0
+	for _, r := range text {
+		switch {
+		case isRTL(r):
+			rtl++
+		case unicode.IsLetter(r):
+			ltr++
+		}
+	}
+	return rtl > ltr
+}
+
+// isRTL reports whether a character is a letter of a right-to-left script
+func isRTL(r rune) bool {
+	return unicode.In(r, unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko) && unicode.IsLetter(r)
+}
+
+// Normalize replaces the Arabic presentation forms of a text with the letters they
+// shape, after reversing the lines whose words start with final forms, which PDFs
+// store in visual order. Text without presentation forms is returned as it is.
+func Normalize(text string) string {
+	if !strings.ContainsFunc(text, func(r rune) bool { _, ok := presentationForms[r]; return ok }) {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if visualOrder(line) {
+			line = reverseLine(line)
+		}
+		var normalized strings.Builder
+		for _, r := range line {
+			if form, ok := presentationForms[r]; ok {
+				normalized.WriteString(form.letters)
+			} else {
+				normalized.WriteRune(r)
+			}
+		}
+		lines[i] = normalized.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// visualOrder reports whether a line holds Arabic words in visual order: written in
+// logical order, a word starts with an initial or isolated form and ends with a final
+// or isolated one, so reversed words start with final forms and end with initial ones
+func visualOrder(line string) bool {
+	logical, visual := 0, 0
+	for _, word := range strings.FieldsFunc(line, func(r rune) bool { return !isRTL(r) }) {
+		runes := []rune(word)
+		if len(runes) < 2 {
+			continue
+		}
+		first, firstOK := presentationForms[runes[0]]
+		last, lastOK := presentationForms[runes[len(runes)-1]]
+		switch {
+		case firstOK && first.position == initial, lastOK && last.position == final:
+			logical++
+		case firstOK && first.position == final, lastOK && last.position == initial:
+			visual++
+		}
+	}
+	return visual > logical
+}
+
+// reverseLine turns a line from visual to logical order: it reverses the characters
+// and then the runs of left-to-right text, such as numbers and Latin words, back
+func reverseLine(line string) string {
+	runes := []rune(line)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	for i := 0; i < len(runes); {
+		if !isLTR(runes[i]) {
+			i++
+			continue
+		}
+		end := i
+		for j := i; j < len(runes) && !isRTL(runes[j]); j++ {
+			if isLTR(runes[j]) {
+				end = j + 1 // The run ends at its last left-to-right character
+			}
+		}
+		for a, b := i, end-1; a < b; a, b = a+1, b-1 {
+			runes[a], runes[b] = runes[b], runes[a]
+		}
+		i = end
+	}
+	return string(runes)
+}
+
+// isLTR reports whether a character is a digit or a letter of a left-to-right script
+func isLTR(r rune) bool {
+	return unicode.IsDigit(r) || unicode.IsLetter(r) && !isRTL(r)
+}
+
+// ASCIIDigits replaces Arabic-Indic (٠–٩) and Persian (۰–۹) digits with 0–9, so
+// numbers, dates and page references match patterns written for ASCII digits
+func ASCIIDigits(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 0x0660 && r <= 0x0669:
+			return '0' + r - 0x0660
+		case r >= 0x06F0 && r <= 0x06F9:
+			return '0' + r - 0x06F0
+		}
+		return r
+	}, text)
+}
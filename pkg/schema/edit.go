@@ -0,0 +1,175 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// summarySectionPattern matches the Summary section of a formatted chunk, which no longer
+// sums up the content once it is edited
+var summarySectionPattern = regexp.MustCompile(`(?s)## Summary\n.*?\n\n`)
+
+// Merge returns chunks with the chunk at index i and the one after it, of the same
+// document, merged into one: their content is joined by a blank line under the metadata
+// of the first, and they span from the start of the first to the end of the second.
+// Chunks are numbered again from 1 (see Renumber). Fields computed from the text, such
+// as Embedding, Summary and Quality, are cleared on the merged chunk.
+func Merge(chunks []Chunk, i int) ([]Chunk, error) {
+	if i < 0 || i+1 >= len(chunks) {
+		return nil, fmt.Errorf("no chunk after position %d of %d to merge with", i, len(chunks))
+	}
+	first, second := chunks[i], chunks[i+1]
+	if first.Slug != second.Slug || first.Filename != second.Filename {
+		return nil, fmt.Errorf("chunks %d and %d belong to different documents", first.ChunkIndex, second.ChunkIndex)
+	}
+
+	merged := first
+	merged.Text = mergeText(first.Text, second.Text)
+	if first.RenderedText != "" || second.RenderedText != "" {
+		merged.RenderedText = mergeText(renderedText(first), renderedText(second))
+	}
+	if second.StartPage > 0 {
+		merged.EndPage, merged.EndOffset = second.EndPage, second.EndOffset
+	}
+	if first.StartPage == 0 {
+		merged.StartPage, merged.StartOffset = second.StartPage, second.StartOffset
+	}
+	if first.ParentIndex != second.ParentIndex {
+		merged.ParentIndex = 0
+	}
+	merged.Metadata = nil
+	if len(first.Metadata) > 0 || len(second.Metadata) > 0 {
+		merged.Metadata = make(map[string]any, len(first.Metadata)+len(second.Metadata))
+		for key, value := range second.Metadata {
+			merged.Metadata[key] = value
+		}
+		for key, value := range first.Metadata {
+			merged.Metadata[key] = value
+		}
+	}
+	setPages(&merged, first.PageRange)
+
+	edited := make([]Chunk, 0, len(chunks)-1)
+	edited = append(edited, chunks[:i]...)
+	edited = append(edited, merged)
+	edited = append(edited, chunks[i+2:]...)
+	Renumber(edited)
+	return edited, nil
+}
+
+// Split returns chunks with the chunk at index i split in two at offset, a character
+// offset in its content: the text after the metadata lines of a formatted chunk, or all
+// of its text otherwise. Both halves keep the metadata of the chunk, with whitespace
+// around the split trimmed. The pages of the halves are exact when the chunk holds the
+// text of a single page as extracted, as plain text chunks mostly do; otherwise both
+// keep the pages of the whole chunk. Chunks are numbered again from 1 (see Renumber),
+// and fields computed from the text are cleared as in Merge.
+func Split(chunks []Chunk, i, offset int) ([]Chunk, error) {
+	if i < 0 || i >= len(chunks) {
+		return nil, fmt.Errorf("no chunk at position %d of %d to split", i, len(chunks))
+	}
+	chunk := chunks[i]
+	header, content := utils.SplitFormattedChunk(chunk.Text)
+	cut := byteOffset(content, offset)
+	before := strings.TrimRightFunc(content[:cut], unicode.IsSpace)
+	after := strings.TrimLeftFunc(content[cut:], unicode.IsSpace)
+	if strings.TrimSpace(before) == "" || strings.TrimSpace(after) == "" {
+		return nil, fmt.Errorf("offset %d does not split the content of chunk %d", offset, chunk.ChunkIndex)
+	}
+
+	first, second := chunk, chunk
+	first.Text, second.Text = header+before, header+after
+	if chunk.RenderedText != "" {
+		renderedHeader, renderedContent := utils.SplitFormattedChunk(chunk.RenderedText)
+		if renderedContent == content {
+			first.RenderedText, second.RenderedText = renderedHeader+before, renderedHeader+after
+		} else {
+			first.RenderedText, second.RenderedText = "", "" // Laid out by a template; chunk files get Text
+		}
+	}
+	if chunk.StartPage > 0 && chunk.StartPage == chunk.EndPage && utf8.RuneCountInString(content) == chunk.EndOffset-chunk.StartOffset {
+		first.EndOffset = chunk.StartOffset + utf8.RuneCountInString(before)
+		second.StartOffset = chunk.EndOffset - utf8.RuneCountInString(after)
+	}
+	setPages(&first, chunk.PageRange)
+	setPages(&second, chunk.PageRange)
+
+	edited := make([]Chunk, 0, len(chunks)+1)
+	edited = append(edited, chunks[:i]...)
+	edited = append(edited, first, second)
+	edited = append(edited, chunks[i+1:]...)
+	Renumber(edited)
+	return edited, nil
+}
+
+// Renumber numbers chunks from 1 in order, updating the Chunk Number line of formatted
+// chunks. Chunks of several documents are numbered per document.
+func Renumber(chunks []Chunk) {
+	totals := make(map[string]int)
+	for _, chunk := range chunks {
+		totals[chunk.Slug]++
+	}
+	numbers := make(map[string]int)
+	for i := range chunks {
+		slug := chunks[i].Slug
+		numbers[slug]++
+		chunks[i].ChunkIndex = numbers[slug]
+		chunks[i].Text = utils.RenumberChunk(chunks[i].Text, numbers[slug], totals[slug])
+		if chunks[i].RenderedText != "" {
+			chunks[i].RenderedText = utils.RenumberChunk(chunks[i].RenderedText, numbers[slug], totals[slug])
+		}
+	}
+}
+
+// mergeText joins the content of two chunk texts under the header of the first
+func mergeText(first, second string) string {
+	header, content := utils.SplitFormattedChunk(first)
+	_, next := utils.SplitFormattedChunk(second)
+	return header + strings.TrimRightFunc(content, unicode.IsSpace) + "\n\n" + strings.TrimLeftFunc(next, unicode.IsSpace)
+}
+
+// renderedText returns the text a chunk file of a chunk holds
+func renderedText(chunk Chunk) string {
+	if chunk.RenderedText != "" {
+		return chunk.RenderedText
+	}
+	return chunk.Text
+}
+
+// setPages updates the page range of an edited chunk from its pages, keeping pageRange
+// when it has none, and clears the fields computed from its text
+func setPages(chunk *Chunk, pageRange string) {
+	switch {
+	case chunk.StartPage == 0:
+		chunk.PageRange = pageRange
+	case chunk.StartPage == chunk.EndPage:
+		chunk.PageRange = fmt.Sprintf("Page %d", chunk.StartPage)
+	default:
+		chunk.PageRange = fmt.Sprintf("Page %d–%d", chunk.StartPage, chunk.EndPage)
+	}
+	for _, text := range []*string{&chunk.Text, &chunk.RenderedText} {
+		header, content := utils.SplitFormattedChunk(*text)
+		header = utils.SetChunkPageRange(summarySectionPattern.ReplaceAllLiteralString(header, ""), chunk.PageRange)
+		*text = header + content
+	}
+	chunk.Embedding = nil
+	chunk.Summary = ""
+	chunk.Quality = nil
+	chunk.DuplicateOf = nil
+}
+
+// byteOffset returns the byte offset of the character at a character offset of text
+func byteOffset(text string, offset int) int {
+	for i := range text {
+		if offset <= 0 {
+			return i
+		}
+		offset--
+	}
+	return len(text)
+}
@@ -0,0 +1,43 @@
+package schema
+
+// Types of an Element, as unstructured.io names them
+const (
+	ElementTitle         = "Title"             // A heading; CategoryDepth is its level from 0
+	ElementNarrativeText = "NarrativeText"     // A paragraph of sentences
+	ElementListItem      = "ListItem"          // A bulleted or numbered item, without its bullet
+	ElementTable         = "Table"             // A table, with TextAsHTML
+	ElementUncategorized = "UncategorizedText" // Text that is none of the above, such as a page number or a caption
+)
+
+// CoordinateSystemPixelSpace places points in page units from the top left corner, as
+// unstructured.io does; for PDFs the units are points, 1/72 of an inch
+const CoordinateSystemPixelSpace = "PixelSpace"
+
+// Element is a piece of a document in the element schema of unstructured.io, which
+// Haystack, LangChain and LlamaIndex loaders read. A document's elements are written to
+// elements.json next to its manifest.
+type Element struct {
+	Type      string          `json:"type"`       // ElementTitle, ElementNarrativeText, ...
+	ElementID string          `json:"element_id"` // Hash of the document, page, position and text
+	Text      string          `json:"text"`
+	Metadata  ElementMetadata `json:"metadata"`
+}
+
+// ElementMetadata is where an element is in its document
+type ElementMetadata struct {
+	Filename      string       `json:"filename,omitempty"`
+	Filetype      string       `json:"filetype,omitempty"`       // MIME type of the document
+	PageNumber    int          `json:"page_number,omitempty"`    // 1-based; 0 for inputs without pages
+	ParentID      string       `json:"parent_id,omitempty"`      // The Title the element is under
+	CategoryDepth *int         `json:"category_depth,omitempty"` // Level of a Title, from 0
+	TextAsHTML    string       `json:"text_as_html,omitempty"`   // The table of a Table as an HTML <table>
+	Coordinates   *Coordinates `json:"coordinates,omitempty"`    // Box of the element on its page, when the text layer gives one
+}
+
+// Coordinates is the box of an element on its page
+type Coordinates struct {
+	Points       [][2]float64 `json:"points"` // Top left, bottom left, bottom right and top right corners
+	System       string       `json:"system"` // CoordinateSystemPixelSpace
+	LayoutWidth  float64      `json:"layout_width"`
+	LayoutHeight float64      `json:"layout_height"`
+}
@@ -0,0 +1,11 @@
+package schema
+
+// Heading is an entry of a document outline, built from the headings the local
+// formatter recognizes by their numbering
+type Heading struct {
+	Level      int        `json:"level"` // 1 to 3: BAB and "1." are 1, Bagian and "1.1" 2, Pasal and "1.1.1" 3
+	Title      string     `json:"title"` // The heading line as it appears in the document
+	Page       int        `json:"page,omitempty"`
+	ChunkIndex int        `json:"chunk_index,omitempty"` // Chunk holding the heading, when it could be located
+	Children   []*Heading `json:"children,omitempty"`    // Headings of a higher level up to the next heading of this level or lower
+}
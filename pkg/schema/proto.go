@@ -0,0 +1,557 @@
+package schema
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Field numbers of the Chunk message in chunk.proto
+const (
+	fieldSchemaVersion = 1
+	fieldFilename      = 2
+	fieldSlug          = 3
+	fieldChunkIndex    = 4
+	fieldParentIndex   = 5
+	fieldPageRange     = 6
+	fieldStartPage     = 7
+	fieldEndPage       = 8
+	fieldStartOffset   = 9
+	fieldEndOffset     = 10
+	fieldText          = 11
+	fieldMetadata      = 12
+	fieldEmbedding     = 13
+	fieldValidation    = 14
+	fieldTitle         = 15
+	fieldSummary       = 16
+	fieldCitation      = 17
+	fieldSection       = 18
+	fieldDuplicateOf   = 19
+	fieldQuality       = 20
+)
+
+// Field numbers of the Validation message in chunk.proto
+const (
+	fieldValidationOutcome  = 1
+	fieldValidationFailures = 2
+)
+
+// Field numbers of the Citation message in chunk.proto
+const (
+	fieldCitationText        = 1
+	fieldCitationRegulation  = 2
+	fieldCitationBab         = 3
+	fieldCitationBagian      = 4
+	fieldCitationParagraf    = 5
+	fieldCitationPasal       = 6
+	fieldCitationAyat        = 7
+	fieldCitationElucidation = 8
+)
+
+// Field numbers of the ChunkRef message in chunk.proto
+const (
+	fieldChunkRefFilename   = 1
+	fieldChunkRefSlug       = 2
+	fieldChunkRefChunkIndex = 3
+	fieldChunkRefSimilarity = 4
+)
+
+// Field numbers of the Quality message in chunk.proto
+const (
+	fieldQualityScore     = 1
+	fieldQualityWords     = 2
+	fieldQualityDensity   = 3
+	fieldQualityStopwords = 4
+	fieldQualityGarbage   = 5
+)
+
+// maxDelimitedSize bounds the length prefix accepted by ReadDelimited
+const maxDelimitedSize = 64 << 20
+
+// MarshalProto encodes a chunk as a Chunk message of chunk.proto
+func MarshalProto(chunk Chunk) ([]byte, error) {
+	return AppendProto(nil, chunk)
+}
+
+// AppendProto appends the Chunk message encoding of chunk to b
+func AppendProto(b []byte, chunk Chunk) ([]byte, error) {
+	b = appendInt(b, fieldSchemaVersion, chunk.SchemaVersion)
+	b = appendString(b, fieldFilename, chunk.Filename)
+	b = appendString(b, fieldSlug, chunk.Slug)
+	b = appendInt(b, fieldChunkIndex, chunk.ChunkIndex)
+	b = appendInt(b, fieldParentIndex, chunk.ParentIndex)
+	b = appendString(b, fieldPageRange, chunk.PageRange)
+	b = appendInt(b, fieldStartPage, chunk.StartPage)
+	b = appendInt(b, fieldEndPage, chunk.EndPage)
+	b = appendInt(b, fieldStartOffset, chunk.StartOffset)
+	b = appendInt(b, fieldEndOffset, chunk.EndOffset)
+	b = appendString(b, fieldText, chunk.Text)
+
+	if len(chunk.Metadata) > 0 {
+		metadata, err := metadataStruct(chunk.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := proto.Marshal(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		b = protowire.AppendTag(b, fieldMetadata, protowire.BytesType)
+		b = protowire.AppendBytes(b, encoded)
+	}
+
+	if len(chunk.Embedding) > 0 {
+		// Packed, as proto3 encodes repeated scalars
+		b = protowire.AppendTag(b, fieldEmbedding, protowire.BytesType)
+		b = protowire.AppendVarint(b, uint64(4*len(chunk.Embedding)))
+		for _, value := range chunk.Embedding {
+			b = protowire.AppendFixed32(b, math.Float32bits(value))
+		}
+	}
+
+	if chunk.Validation != nil {
+		validation := appendString(nil, fieldValidationOutcome, chunk.Validation.Outcome)
+		for _, failure := range chunk.Validation.Failures {
+			validation = protowire.AppendTag(validation, fieldValidationFailures, protowire.BytesType)
+			validation = protowire.AppendString(validation, failure)
+		}
+		b = protowire.AppendTag(b, fieldValidation, protowire.BytesType)
+		b = protowire.AppendBytes(b, validation)
+	}
+	b = appendString(b, fieldTitle, chunk.Title)
+	b = appendString(b, fieldSummary, chunk.Summary)
+
+	if citation := chunk.Citation; citation != nil {
+		encoded := appendString(nil, fieldCitationText, citation.Text)
+		encoded = appendString(encoded, fieldCitationRegulation, citation.Regulation)
+		encoded = appendString(encoded, fieldCitationBab, citation.Bab)
+		encoded = appendString(encoded, fieldCitationBagian, citation.Bagian)
+		encoded = appendString(encoded, fieldCitationParagraf, citation.Paragraf)
+		encoded = appendString(encoded, fieldCitationPasal, citation.Pasal)
+		for _, ayat := range citation.Ayat {
+			encoded = protowire.AppendTag(encoded, fieldCitationAyat, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, ayat)
+		}
+		if citation.Elucidation {
+			encoded = protowire.AppendTag(encoded, fieldCitationElucidation, protowire.VarintType)
+			encoded = protowire.AppendVarint(encoded, 1)
+		}
+		b = protowire.AppendTag(b, fieldCitation, protowire.BytesType)
+		b = protowire.AppendBytes(b, encoded)
+	}
+	b = appendString(b, fieldSection, chunk.Section)
+
+	if ref := chunk.DuplicateOf; ref != nil {
+		encoded := appendString(nil, fieldChunkRefFilename, ref.Filename)
+		encoded = appendString(encoded, fieldChunkRefSlug, ref.Slug)
+		encoded = appendInt(encoded, fieldChunkRefChunkIndex, ref.ChunkIndex)
+		encoded = appendDouble(encoded, fieldChunkRefSimilarity, ref.Similarity)
+		b = protowire.AppendTag(b, fieldDuplicateOf, protowire.BytesType)
+		b = protowire.AppendBytes(b, encoded)
+	}
+
+	if quality := chunk.Quality; quality != nil {
+		encoded := appendDouble(nil, fieldQualityScore, quality.Score)
+		encoded = appendInt(encoded, fieldQualityWords, quality.Words)
+		encoded = appendDouble(encoded, fieldQualityDensity, quality.Density)
+		encoded = appendDouble(encoded, fieldQualityStopwords, quality.Stopwords)
+		encoded = appendDouble(encoded, fieldQualityGarbage, quality.Garbage)
+		b = protowire.AppendTag(b, fieldQuality, protowire.BytesType)
+		b = protowire.AppendBytes(b, encoded)
+	}
+	return b, nil
+}
+
+// UnmarshalProto decodes a Chunk message and upgrades it to Version like Unmarshal
+func UnmarshalProto(data []byte) (Chunk, error) {
+	var chunk Chunk
+	err := consumeFields(data, func(number protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch number {
+		case fieldSchemaVersion:
+			return consumeInt(typ, value, &chunk.SchemaVersion)
+		case fieldFilename:
+			return consumeString(typ, value, &chunk.Filename)
+		case fieldSlug:
+			return consumeString(typ, value, &chunk.Slug)
+		case fieldChunkIndex:
+			return consumeInt(typ, value, &chunk.ChunkIndex)
+		case fieldParentIndex:
+			return consumeInt(typ, value, &chunk.ParentIndex)
+		case fieldPageRange:
+			return consumeString(typ, value, &chunk.PageRange)
+		case fieldStartPage:
+			return consumeInt(typ, value, &chunk.StartPage)
+		case fieldEndPage:
+			return consumeInt(typ, value, &chunk.EndPage)
+		case fieldStartOffset:
+			return consumeInt(typ, value, &chunk.StartOffset)
+		case fieldEndOffset:
+			return consumeInt(typ, value, &chunk.EndOffset)
+		case fieldText:
+			return consumeString(typ, value, &chunk.Text)
+		case fieldMetadata:
+			var encoded []byte
+			n, err := consumeBytes(typ, value, &encoded)
+			if err != nil {
+				return n, err
+			}
+			var metadata structpb.Struct
+			if err := proto.Unmarshal(encoded, &metadata); err != nil {
+				return n, fmt.Errorf("failed to decode metadata: %w", err)
+			}
+			chunk.Metadata = metadata.AsMap()
+			return n, nil
+		case fieldEmbedding:
+			return consumeFloats(typ, value, &chunk.Embedding)
+		case fieldValidation:
+			var encoded []byte
+			n, err := consumeBytes(typ, value, &encoded)
+			if err != nil {
+				return n, err
+			}
+			chunk.Validation, err = unmarshalValidation(encoded)
+			return n, err
+		case fieldTitle:
+			return consumeString(typ, value, &chunk.Title)
+		case fieldSummary:
+			return consumeString(typ, value, &chunk.Summary)
+		case fieldCitation:
+			var encoded []byte
+			n, err := consumeBytes(typ, value, &encoded)
+			if err != nil {
+				return n, err
+			}
+			chunk.Citation, err = unmarshalCitation(encoded)
+			return n, err
+		case fieldSection:
+			return consumeString(typ, value, &chunk.Section)
+		case fieldDuplicateOf:
+			var encoded []byte
+			n, err := consumeBytes(typ, value, &encoded)
+			if err != nil {
+				return n, err
+			}
+			chunk.DuplicateOf, err = unmarshalChunkRef(encoded)
+			return n, err
+		case fieldQuality:
+			var encoded []byte
+			n, err := consumeBytes(typ, value, &encoded)
+			if err != nil {
+				return n, err
+			}
+			chunk.Quality, err = unmarshalQuality(encoded)
+			return n, err
+		default:
+			return -1, nil
+		}
+	})
+	if err != nil {
+		return Chunk{}, fmt.Errorf("failed to parse chunk: %w", err)
+	}
+
+	if err := Upgrade(&chunk); err != nil {
+		return Chunk{}, err
+	}
+	return chunk, nil
+}
+
+// WriteDelimited writes a chunk to w as a Chunk message preceded by its varint length,
+// the framing of Java's writeDelimitedTo, so chunks can be streamed one by one
+func WriteDelimited(w io.Writer, chunk Chunk) error {
+	message, err := MarshalProto(chunk)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(protowire.AppendBytes(nil, message))
+	return err
+}
+
+// ReadDelimited reads the next chunk written by WriteDelimited. It returns io.EOF when r
+// is exhausted between chunks.
+func ReadDelimited(r *bufio.Reader) (Chunk, error) {
+	var length uint64
+	for shift := 0; ; shift += 7 {
+		c, err := r.ReadByte()
+		if err != nil {
+			if shift > 0 && errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return Chunk{}, err
+		}
+		if shift >= 64 {
+			return Chunk{}, fmt.Errorf("invalid chunk length prefix")
+		}
+		length |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			break
+		}
+	}
+	if length > maxDelimitedSize {
+		return Chunk{}, fmt.Errorf("chunk of %d bytes exceeds the %d byte limit", length, maxDelimitedSize)
+	}
+
+	message := make([]byte, length)
+	if _, err := io.ReadFull(r, message); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return Chunk{}, err
+	}
+	return UnmarshalProto(message)
+}
+
+// appendInt appends an int32 field, omitting zero values as proto3 does
+func appendInt(b []byte, number protowire.Number, value int) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(int64(int32(value))))
+}
+
+// appendString appends a string field, omitting empty strings as proto3 does
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// appendDouble appends a double field, omitting zero values as proto3 does
+func appendDouble(b []byte, number protowire.Number, value float64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, number, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(value))
+}
+
+// consumeFields calls field for every field of a message. field returns the number of
+// value bytes it consumed, or -1 to skip an unknown field.
+func consumeFields(data []byte, field func(number protowire.Number, typ protowire.Type, value []byte) (int, error)) error {
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		n, err := field(number, typ, data)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			n = protowire.ConsumeFieldValue(number, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// consumeInt decodes an int32 field value into value
+func consumeInt(typ protowire.Type, data []byte, value *int) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, fmt.Errorf("unexpected wire type %d for integer field", typ)
+	}
+	v, n := protowire.ConsumeVarint(data)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*value = int(int32(v))
+	return n, nil
+}
+
+// consumeDouble decodes a double field value into value
+func consumeDouble(typ protowire.Type, data []byte, value *float64) (int, error) {
+	if typ != protowire.Fixed64Type {
+		return 0, fmt.Errorf("unexpected wire type %d for double field", typ)
+	}
+	v, n := protowire.ConsumeFixed64(data)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*value = math.Float64frombits(v)
+	return n, nil
+}
+
+// consumeString decodes a string field value into value
+func consumeString(typ protowire.Type, data []byte, value *string) (int, error) {
+	var b []byte
+	n, err := consumeBytes(typ, data, &b)
+	*value = string(b)
+	return n, err
+}
+
+// consumeBytes decodes a bytes or embedded message field value into value
+func consumeBytes(typ protowire.Type, data []byte, value *[]byte) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, fmt.Errorf("unexpected wire type %d for length-delimited field", typ)
+	}
+	v, n := protowire.ConsumeBytes(data)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*value = v
+	return n, nil
+}
+
+// unmarshalValidation decodes a Validation message
+func unmarshalValidation(data []byte) (*Validation, error) {
+	validation := &Validation{}
+	err := consumeFields(data, func(number protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch number {
+		case fieldValidationOutcome:
+			return consumeString(typ, value, &validation.Outcome)
+		case fieldValidationFailures:
+			var failure string
+			n, err := consumeString(typ, value, &failure)
+			validation.Failures = append(validation.Failures, failure)
+			return n, err
+		default:
+			return -1, nil
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode validation: %w", err)
+	}
+	return validation, nil
+}
+
+// unmarshalCitation decodes a Citation message
+func unmarshalCitation(data []byte) (*Citation, error) {
+	citation := &Citation{}
+	err := consumeFields(data, func(number protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch number {
+		case fieldCitationText:
+			return consumeString(typ, value, &citation.Text)
+		case fieldCitationRegulation:
+			return consumeString(typ, value, &citation.Regulation)
+		case fieldCitationBab:
+			return consumeString(typ, value, &citation.Bab)
+		case fieldCitationBagian:
+			return consumeString(typ, value, &citation.Bagian)
+		case fieldCitationParagraf:
+			return consumeString(typ, value, &citation.Paragraf)
+		case fieldCitationPasal:
+			return consumeString(typ, value, &citation.Pasal)
+		case fieldCitationAyat:
+			var ayat string
+			n, err := consumeString(typ, value, &ayat)
+			citation.Ayat = append(citation.Ayat, ayat)
+			return n, err
+		case fieldCitationElucidation:
+			var elucidation int
+			n, err := consumeInt(typ, value, &elucidation)
+			citation.Elucidation = elucidation != 0
+			return n, err
+		default:
+			return -1, nil
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode citation: %w", err)
+	}
+	return citation, nil
+}
+
+// unmarshalChunkRef decodes a ChunkRef message
+func unmarshalChunkRef(data []byte) (*ChunkRef, error) {
+	ref := &ChunkRef{}
+	err := consumeFields(data, func(number protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch number {
+		case fieldChunkRefFilename:
+			return consumeString(typ, value, &ref.Filename)
+		case fieldChunkRefSlug:
+			return consumeString(typ, value, &ref.Slug)
+		case fieldChunkRefChunkIndex:
+			return consumeInt(typ, value, &ref.ChunkIndex)
+		case fieldChunkRefSimilarity:
+			return consumeDouble(typ, value, &ref.Similarity)
+		default:
+			return -1, nil
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode chunk reference: %w", err)
+	}
+	return ref, nil
+}
+
+// unmarshalQuality decodes a Quality message
+func unmarshalQuality(data []byte) (*Quality, error) {
+	quality := &Quality{}
+	err := consumeFields(data, func(number protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch number {
+		case fieldQualityScore:
+			return consumeDouble(typ, value, &quality.Score)
+		case fieldQualityWords:
+			return consumeInt(typ, value, &quality.Words)
+		case fieldQualityDensity:
+			return consumeDouble(typ, value, &quality.Density)
+		case fieldQualityStopwords:
+			return consumeDouble(typ, value, &quality.Stopwords)
+		case fieldQualityGarbage:
+			return consumeDouble(typ, value, &quality.Garbage)
+		default:
+			return -1, nil
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode quality: %w", err)
+	}
+	return quality, nil
+}
+
+// consumeFloats appends a packed or unpacked repeated float field value to values
+func consumeFloats(typ protowire.Type, data []byte, values *[]float32) (int, error) {
+	switch typ {
+	case protowire.Fixed32Type:
+		v, n := protowire.ConsumeFixed32(data)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		*values = append(*values, math.Float32frombits(v))
+		return n, nil
+	case protowire.BytesType:
+		packed, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		if len(packed)%4 != 0 {
+			return 0, fmt.Errorf("invalid packed float field of %d bytes", len(packed))
+		}
+		for len(packed) > 0 {
+			v, m := protowire.ConsumeFixed32(packed)
+			*values = append(*values, math.Float32frombits(v))
+			packed = packed[m:]
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("unexpected wire type %d for float field", typ)
+	}
+}
+
+// metadataStruct converts chunk metadata to a google.protobuf.Struct. Values go through
+// JSON first, so they are encoded as they would be in a chunk JSON file.
+func metadataStruct(metadata map[string]any) (*structpb.Struct, error) {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	var generic map[string]any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	return structpb.NewStruct(generic)
+}
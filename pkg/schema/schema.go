@@ -0,0 +1,178 @@
+// Package schema defines the JSON format of chunks written by the chunker, for consumers
+// that load chunk files without depending on the extraction packages (and their cgo
+// dependencies), and upgrades chunks written by older versions
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// Version is the schema_version of chunks written by this version of the library.
+//
+//   - 1: filename, chunk_index, page_range and text; no schema_version field
+//   - 2: adds schema_version, slug, parent_index, start_page, end_page, start_offset,
+//     end_offset and metadata; later adds the optional embedding, validation, title,
+//     summary, citation, section, duplicate_of and quality
+//
+// Version is bumped when a field is renamed, removed or changes meaning, or when a new
+// field must be derived by Upgrade. Optional fields that older readers can ignore and
+// older chunks simply lack are added without a bump, and listed under the current
+// version above.
+const Version = 2
+
+// ErrUnsupportedVersion is returned for chunks written by a newer version of the library
+var ErrUnsupportedVersion = errors.New("unsupported chunk schema version")
+
+// Chunk is a structured chunk for vector database embedding, as returned by the chunker
+// and saved to chunk JSON files
+type Chunk struct {
+	SchemaVersion int            `json:"schema_version"` // Version for chunks written by this library, 0 only before upgrading
+	Filename      string         `json:"filename"`
+	Slug          string         `json:"slug"`                   // Sanitized Filename without extension; the document's output path under ChunkDir and JSONDir
+	ChunkIndex    int            `json:"chunk_index"`            // Sequential per document, starting at 1
+	ParentIndex   int            `json:"parent_index,omitempty"` // Index of the split slice when AI chunking divided it into several chunks
+	PageRange     string         `json:"page_range"`
+	StartPage     int            `json:"start_page,omitempty"`
+	EndPage       int            `json:"end_page,omitempty"`
+	StartOffset   int            `json:"start_offset"` // Character offset of the chunk start in the StartPage text
+	EndOffset     int            `json:"end_offset"`   // Character offset just past the chunk end in the EndPage text
+	Text          string         `json:"text"`
+	RenderedText  string         `json:"-"`                      // Text laid out with its metadata for chunk files, set when Text is kept plain; not saved in JSON
+	Metadata      map[string]any `json:"metadata,omitempty"`     // Document codes, dates, title, direction, source and section of the chunk, and caller-supplied metadata, see chunker.ChunkInputWithMetadata
+	Embedding     []float32      `json:"embedding,omitempty"`    // Vector of Text, set when the chunker has an embedding provider
+	Validation    *Validation    `json:"validation,omitempty"`   // Outcome of the AI output checks, set when the chunker validates AI outputs
+	Title         string         `json:"title,omitempty"`        // Section title found by the AI in structure-only mode, or the section heading in the paper profile
+	Summary       string         `json:"summary,omitempty"`      // Key sentences of Text, set when the chunker summarizes chunks
+	Citation      *Citation      `json:"citation,omitempty"`     // Where the chunk sits in a regulation, set in the regulation profile
+	Section       string         `json:"section,omitempty"`      // Section of a paper, e.g. "methods" or "references", set in the paper profile
+	DuplicateOf   *ChunkRef      `json:"duplicate_of,omitempty"` // Earlier chunk of another document this one nearly repeats, set when the chunker flags duplicates
+	Quality       *Quality       `json:"quality,omitempty"`      // How much usable text the chunk holds, set when the chunker scores chunks
+}
+
+// Validation outcomes of the AI output a chunk was made from
+const (
+	ValidationPassed   = "passed"   // The first answer passed every check
+	ValidationRetried  = "retried"  // The first answer was rejected and the retry passed
+	ValidationFallback = "fallback" // Both answers were rejected, so the chunk was made locally
+)
+
+// Validation records the checks of the AI output a chunk was made from
+type Validation struct {
+	Outcome  string   `json:"outcome"`            // ValidationPassed, ValidationRetried or ValidationFallback
+	Failures []string `json:"failures,omitempty"` // Why answers were rejected, e.g. "min_length: output is 12% of the input"
+}
+
+// Citation locates a chunk of an Indonesian regulation by its Bab, Bagian, Paragraf,
+// Pasal and Ayat
+type Citation struct {
+	Text        string   `json:"text"`                  // e.g. "UU 13/2003 Pasal 59 Ayat (2)"
+	Regulation  string   `json:"regulation,omitempty"`  // e.g. "UU 13/2003", when the document header names it
+	Bab         string   `json:"bab,omitempty"`         // e.g. "BAB IX HUBUNGAN KERJA"
+	Bagian      string   `json:"bagian,omitempty"`      // e.g. "Bagian Kesatu Umum"
+	Paragraf    string   `json:"paragraf,omitempty"`    // e.g. "Paragraf 2 Pengupahan"
+	Pasal       string   `json:"pasal,omitempty"`       // e.g. "59"; empty for the preamble and general elucidation
+	Ayat        []string `json:"ayat,omitempty"`        // e.g. ["2", "3"], when the chunk holds only some Ayat of its Pasal
+	Elucidation bool     `json:"elucidation,omitempty"` // The chunk is from the Penjelasan
+}
+
+// ChunkRef refers to a chunk of another document
+type ChunkRef struct {
+	Filename   string  `json:"filename"`
+	Slug       string  `json:"slug"`
+	ChunkIndex int     `json:"chunk_index"`
+	Similarity float64 `json:"similarity,omitempty"` // Estimated Jaccard similarity of the word shingles, for near-duplicates
+}
+
+// Quality scores how much usable text a chunk holds, so page numbers, scanning artifacts
+// and other junk can be left out of embedding. Ratios are between 0 and 1.
+type Quality struct {
+	Score     float64 `json:"score"`     // Overall score from 0 (junk) to 1
+	Words     int     `json:"words"`     // Words of text, without the formatter's headings and labels
+	Density   float64 `json:"density"`   // Share of letters and digits, weighted by word diversity
+	Stopwords float64 `json:"stopwords"` // Share of common words; prose usually has 0.2 to 0.5
+	Garbage   float64 `json:"garbage"`   // Share of tokens that look like OCR noise
+}
+
+// pageRangePattern matches the "Page 3" and "Page 3–5" page ranges of version 1 chunks
+var pageRangePattern = regexp.MustCompile(`^Page (\d+)(?:[–-](\d+))?$`)
+
+// Unmarshal decodes a chunk JSON object of any supported version and upgrades it to Version
+func Unmarshal(data []byte) (Chunk, error) {
+	var chunk Chunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return Chunk{}, fmt.Errorf("failed to parse chunk: %w", err)
+	}
+	if err := Upgrade(&chunk); err != nil {
+		return Chunk{}, err
+	}
+	return chunk, nil
+}
+
+// UnmarshalList decodes a JSON array of chunks, as returned with OutputJSON, and upgrades
+// each one to Version
+func UnmarshalList(data []byte) ([]Chunk, error) {
+	var chunks []Chunk
+	if err := json.Unmarshal(data, &chunks); err != nil {
+		return nil, fmt.Errorf("failed to parse chunks: %w", err)
+	}
+	for i := range chunks {
+		if err := Upgrade(&chunks[i]); err != nil {
+			return nil, err
+		}
+	}
+	return chunks, nil
+}
+
+// ReadFile reads a chunk JSON file, decompressing .gz and .zst files, and upgrades it
+// to Version
+func ReadFile(path string) (Chunk, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Chunk{}, fmt.Errorf("failed to read chunk: %w", err)
+	}
+	data, err = utils.Decompress(data, utils.CompressionForPath(path))
+	if err != nil {
+		return Chunk{}, fmt.Errorf("failed to decompress chunk: %w", err)
+	}
+	return Unmarshal(data)
+}
+
+// Upgrade fills the fields added since the chunk's schema version, as far as they can be
+// derived from the older fields, and sets SchemaVersion to Version. Chunks without a
+// schema_version are version 1.
+func Upgrade(chunk *Chunk) error {
+	if chunk.SchemaVersion == 0 {
+		chunk.SchemaVersion = 1
+	}
+	if chunk.SchemaVersion > Version {
+		return fmt.Errorf("%w: %d (newest supported is %d)", ErrUnsupportedVersion, chunk.SchemaVersion, Version)
+	}
+
+	if chunk.SchemaVersion < 2 {
+		upgradeV1(chunk)
+		chunk.SchemaVersion = 2
+	}
+	return nil
+}
+
+// upgradeV1 derives the slug and page numbers of a version 1 chunk; offsets are unknown
+// and left at 0
+func upgradeV1(chunk *Chunk) {
+	if chunk.Slug == "" {
+		chunk.Slug = utils.Slug(chunk.Filename)
+	}
+	if match := pageRangePattern.FindStringSubmatch(chunk.PageRange); match != nil && chunk.StartPage == 0 {
+		chunk.StartPage, _ = strconv.Atoi(match[1])
+		chunk.EndPage = chunk.StartPage
+		if match[2] != "" {
+			chunk.EndPage, _ = strconv.Atoi(match[2])
+		}
+	}
+}
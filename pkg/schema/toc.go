@@ -0,0 +1,26 @@
+package schema
+
+// Sources of a table of contents
+const (
+	TOCSourceBookmarks = "bookmarks" // The outline stored in the PDF
+	TOCSourceHeadings  = "headings"  // Numbered headings found in the text, as in Heading
+)
+
+// TOC is the table of contents of a document, written to toc.json next to its manifest
+type TOC struct {
+	Filename string      `json:"filename"`
+	Slug     string      `json:"slug"`
+	Source   string      `json:"source"` // TOCSourceBookmarks or TOCSourceHeadings
+	Entries  []*TOCEntry `json:"entries"`
+}
+
+// TOCEntry is a section of a table of contents. A section runs from its heading to the
+// next heading of the same or a lower level.
+type TOCEntry struct {
+	Level    int         `json:"level"` // Starting at 1
+	Title    string      `json:"title"`
+	Page     int         `json:"page,omitempty"`     // Page of the heading, 0 when it could not be located
+	EndPage  int         `json:"end_page,omitempty"` // Last page of the section
+	Chunks   []int       `json:"chunks,omitempty"`   // ChunkIndex of every chunk covering the section, in order
+	Children []*TOCEntry `json:"children,omitempty"`
+}
@@ -0,0 +1,49 @@
+package search
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// Load reads the chunks of every document saved under chunkDir, through the
+// manifest.json of each chunk directory or .tar.zst archive. Relative paths in the
+// manifests are resolved against dir, the directory the run was started in, as in
+// Manifest.Verify. Hidden entries, such as the staging copies of an unfinished run, are
+// skipped.
+func Load(chunkDir, dir string) ([]schema.Chunk, error) {
+	var chunks []schema.Chunk
+	err := filepath.WalkDir(chunkDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != chunkDir && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || entry.Name() != chunker.ManifestFilename && !strings.HasSuffix(entry.Name(), ".tar.zst") {
+			return nil
+		}
+
+		manifest, err := chunker.LoadManifest(path)
+		if err != nil {
+			return err
+		}
+		result, err := manifest.LoadResult(dir)
+		if err != nil {
+			return fmt.Errorf("failed to load chunks of %s: %w", path, err)
+		}
+		chunks = append(chunks, result.Chunks...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", chunkDir, err)
+	}
+	return chunks, nil
+}
@@ -0,0 +1,186 @@
+// Package search finds the chunks matching a query among chunks saved by the chunker,
+// ranked by BM25 over their words or, when they carry embeddings, by the cosine
+// similarity of their vectors to the vector of the query. It is meant for checking
+// chunk quality without a vector database.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/schema"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2  // Saturation of term frequency
+	b  = 0.75 // Weight of chunk length normalization
+)
+
+// Result is a chunk matching a query and its score
+type Result struct {
+	Chunk schema.Chunk `json:"chunk"`
+	Score float64      `json:"score"` // BM25 score, or cosine similarity from -1 to 1
+}
+
+// Index ranks chunks by their relevance to a query
+type Index struct {
+	chunks    []schema.Chunk
+	terms     []map[string]int // Term frequencies of every chunk
+	lengths   []int            // Words of every chunk
+	docFreq   map[string]int   // Chunks containing every term
+	avgLength float64
+}
+
+// NewIndex indexes the words of chunks
+func NewIndex(chunks []schema.Chunk) *Index {
+	ix := &Index{
+		chunks:  chunks,
+		terms:   make([]map[string]int, len(chunks)),
+		lengths: make([]int, len(chunks)),
+		docFreq: make(map[string]int),
+	}
+	total := 0
+	for i, chunk := range chunks {
+		ix.terms[i] = make(map[string]int)
+		for _, word := range Words(chunk.Text) {
+			ix.terms[i][word]++
+			ix.lengths[i]++
+		}
+		for term := range ix.terms[i] {
+			ix.docFreq[term]++
+		}
+		total += ix.lengths[i]
+	}
+	if len(chunks) > 0 {
+		ix.avgLength = float64(total) / float64(len(chunks))
+	}
+	return ix
+}
+
+// Len returns the number of indexed chunks
+func (ix *Index) Len() int {
+	return len(ix.chunks)
+}
+
+// HasEmbeddings reports whether any indexed chunk has an embedding
+func (ix *Index) HasEmbeddings() bool {
+	for _, chunk := range ix.chunks {
+		if len(chunk.Embedding) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns the limit chunks with the highest BM25 score for the words of query,
+// best first; chunks without any of them are left out
+func (ix *Index) Search(query string, limit int) []Result {
+	queryTerms := make(map[string]bool)
+	for _, word := range Words(query) {
+		queryTerms[word] = true
+	}
+
+	n := float64(len(ix.chunks))
+	var results []Result
+	for i, chunk := range ix.chunks {
+		score := 0.0
+		for term := range queryTerms {
+			tf := float64(ix.terms[i][term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(ix.docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := 1 - b + b*float64(ix.lengths[i])/ix.avgLength
+			score += idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+		if score > 0 {
+			results = append(results, Result{Chunk: chunk, Score: score})
+		}
+	}
+	return top(results, limit)
+}
+
+// SearchVector returns the limit chunks whose embeddings are most similar to vector,
+// best first; chunks without an embedding of the same length are left out
+func (ix *Index) SearchVector(vector []float32, limit int) []Result {
+	var results []Result
+	for _, chunk := range ix.chunks {
+		if len(chunk.Embedding) != len(vector) || len(vector) == 0 {
+			continue
+		}
+		results = append(results, Result{Chunk: chunk, Score: cosine(vector, chunk.Embedding)})
+	}
+	return top(results, limit)
+}
+
+// top sorts results by score, keeping chunks in index order on ties, and returns the
+// first limit of them, or all when limit is 0 or less
+func top(results []Result, limit int) []Result {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// cosine returns the cosine similarity of two vectors of the same length
+func cosine(x, y []float32) float64 {
+	var dot, xx, yy float64
+	for i := range x {
+		dot += float64(x[i]) * float64(y[i])
+		xx += float64(x[i]) * float64(x[i])
+		yy += float64(y[i]) * float64(y[i])
+	}
+	if xx == 0 || yy == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(xx*yy)
+}
+
+// Words splits text into the lower-case runs of letters and digits that are searched
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Snippet returns the first line of a chunk containing a word of query, without its
+// Markdown markers and cut to maxRunes, or its first line of text when none does
+func Snippet(chunk schema.Chunk, query string, maxRunes int) string {
+	queryTerms := make(map[string]bool)
+	for _, word := range Words(query) {
+		queryTerms[word] = true
+	}
+
+	first := ""
+	for _, line := range strings.Split(chunk.Text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if line == "" || strings.HasPrefix(line, "- **") || strings.HasPrefix(line, "--- Page ") {
+			continue // Blank, metadata and page separator lines
+		}
+		if first == "" {
+			first = line
+		}
+		for _, word := range Words(line) {
+			if queryTerms[word] {
+				return truncate(line, maxRunes)
+			}
+		}
+	}
+	return truncate(first, maxRunes)
+}
+
+// truncate cuts text to maxRunes characters, ending it with "…" when cut
+func truncate(text string, maxRunes int) string {
+	runes := []rune(text)
+	if maxRunes <= 0 || len(runes) <= maxRunes {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:maxRunes-1])) + "…"
+}
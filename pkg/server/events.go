@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+)
+
+// EventStatus is the event type of job status changes, streamed with the
+// chunker.EventPageProcessed and chunker.EventChunkCreated events of the job
+const EventStatus = "status"
+
+// keepAlive is how often an idle event stream gets a comment, so proxies keep it open
+const keepAlive = 15 * time.Second
+
+// Event is an event of GET /v1/jobs/{id}/events
+type Event struct {
+	Type  string             `json:"type"`
+	Page  int                `json:"page,omitempty"`  // 1-based page of chunker.EventPageProcessed
+	Pages int                `json:"pages,omitempty"` // Pages of the document
+	Chunk *chunker.ChunkData `json:"chunk,omitempty"` // The chunk of chunker.EventChunkCreated
+	Job   *Job               `json:"job,omitempty"`   // The job without its result, for EventStatus
+}
+
+// jobEvent is a published event, encoded when it is published, so streams never read
+// chunks the chunker is still working on
+type jobEvent struct {
+	id        int
+	eventType string
+	data      []byte
+}
+
+// publish appends an event to a job's stream under the lock and wakes its subscribers
+func (s *Server) publish(job *Job, event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		s.options.Logger.Printf("Failed to encode %s event of job %s: %v", event.Type, job.ID, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publishLocked(job, event.Type, data)
+}
+
+// publishLocked appends an encoded event to a job's stream; s.mu must be held
+func (s *Server) publishLocked(job *Job, eventType string, data []byte) {
+	job.events = append(job.events, jobEvent{id: len(job.events) + 1, eventType: eventType, data: data})
+	if job.changed != nil {
+		close(job.changed)
+	}
+	job.changed = make(chan struct{})
+}
+
+// progress returns the progress function of a job's chunker
+func (s *Server) progress(job *Job) chunker.ProgressFunc {
+	return func(event chunker.ProgressEvent) {
+		s.publish(job, Event{Type: event.Type, Page: event.Page, Pages: event.Pages, Chunk: event.Chunk})
+	}
+}
+
+// handleJobEvents streams the events of a job as server-sent events: every event the
+// job published after the Last-Event-ID header, then the new ones as they happen. The
+// stream ends after the status event of a done, failed or cancelled job.
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.findJob(w, r)
+	if !ok {
+		return
+	}
+	after := 0
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("Last-Event-ID must be an event ID"))
+			return
+		}
+		after = n
+	}
+
+	stream := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keeps nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		var pending []jobEvent
+		if after < len(job.events) {
+			pending = job.events[after:]
+		}
+		changed := job.changed
+		done := finished(job.Status)
+		s.mu.Unlock()
+
+		for _, event := range pending {
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.id, event.eventType, event.data); err != nil {
+				return
+			}
+			after = event.id
+		}
+		if err := stream.Flush(); err != nil {
+			return
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
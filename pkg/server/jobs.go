@@ -0,0 +1,202 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/store"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tenant"
+)
+
+// Limits of GET /v1/jobs
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// handleListJobs lists the jobs the caller may see, newest first and without their
+// results, filtered by the status, tenant and created_after query parameters
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	scope, ok := s.jobScope(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status != "" && !validStatus(status) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown job status %q", status))
+		return
+	}
+	tenantID := query.Get("tenant")
+	if tenantID != "" {
+		if err := tenant.ValidateID(tenantID); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if scope != "" && tenantID != scope {
+			writeError(w, http.StatusForbidden, errors.New("only admin API keys may list the jobs of other tenants"))
+			return
+		}
+	}
+	if scope != "" {
+		tenantID = scope
+	}
+	var createdAfter time.Time
+	if value := query.Get("created_after"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("created_after must be an RFC 3339 time"))
+			return
+		}
+		createdAfter = t
+	}
+	limit := defaultListLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxListLimit {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be a number from 1 to %d", maxListLimit))
+			return
+		}
+		limit = n
+	}
+
+	s.mu.Lock()
+	s.pruneLocked(time.Now())
+	jobs := []Job{}
+	listed := make(map[string]bool)
+	for _, job := range s.jobs {
+		if (tenantID != "" && job.Tenant != tenantID) || (status != "" && job.Status != status) || job.CreatedAt.Before(createdAfter) {
+			continue
+		}
+		snapshot := job.snapshot()
+		snapshot.Result = nil
+		jobs = append(jobs, snapshot)
+		listed[job.ID] = true
+	}
+	s.mu.Unlock()
+	// Stored jobs still in memory are listed as they are there
+	stored, err := s.storedJobs(store.Filter{Tenant: tenantID, Status: status, CreatedAfter: createdAfter, Limit: limit})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list stored jobs: %w", err))
+		return
+	}
+	for _, job := range stored {
+		if !listed[job.ID] {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// handleCancelJob cancels a queued or running job, responding 202 with the job, which
+// becomes cancelled once its chunking stops; a finished job is removed instead, from
+// memory and Options.Store, and returned with 200
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.findJob(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	snapshot := job.snapshot()
+	if finished(job.Status) {
+		delete(s.jobs, job.ID)
+	}
+	s.mu.Unlock()
+	if finished(snapshot.Status) {
+		if s.options.Store != nil {
+			if err := s.options.Store.Delete(job.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+				writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to delete stored job: %w", err))
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, snapshot)
+		return
+	}
+	job.cancel()
+	s.options.Logger.Printf("Cancelling job %s of tenant %s", job.ID, job.Tenant)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// jobScope returns the tenant whose jobs a request may see, or "" for every tenant
+// when it comes with an admin API key not bound to a tenant, writing an error response
+// when the caller is not authorized
+func (s *Server) jobScope(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key, ok := s.authenticate(w, r)
+	if !ok {
+		return "", false
+	}
+	if key != nil && key.Admin && key.Tenant == "" {
+		return "", true
+	}
+	who, ok := s.caller(w, r)
+	return who.tenant, ok
+}
+
+// findJob returns the job of a request's path, from memory or else Options.Store,
+// writing 404 when it does not exist or the caller may not see it
+func (s *Server) findJob(w http.ResponseWriter, r *http.Request) (*Job, bool) {
+	scope, ok := s.jobScope(w, r)
+	if !ok {
+		return nil, false
+	}
+	id := r.PathValue("id")
+	s.mu.Lock()
+	s.pruneLocked(time.Now())
+	job, found := s.jobs[id]
+	s.mu.Unlock()
+	if !found {
+		stored, err := s.storedJob(id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to read stored job: %w", err))
+			return nil, false
+		}
+		job, found = stored, stored != nil
+	}
+	// Jobs of other tenants are reported missing, so their IDs reveal nothing
+	if !found || (scope != "" && job.Tenant != scope) {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return nil, false
+	}
+	return job, true
+}
+
+// pruneLocked removes the finished jobs older than JobRetention, then the oldest
+// finished jobs beyond MaxJobs; s.mu must be held
+func (s *Server) pruneLocked(now time.Time) {
+	var kept []*Job
+	for id, job := range s.jobs {
+		if job.FinishedAt == nil {
+			continue
+		}
+		if s.options.JobRetention > 0 && now.Sub(*job.FinishedAt) > s.options.JobRetention {
+			delete(s.jobs, id)
+			continue
+		}
+		kept = append(kept, job)
+	}
+	if s.options.MaxJobs <= 0 || len(kept) <= s.options.MaxJobs {
+		return
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].FinishedAt.Before(*kept[j].FinishedAt) })
+	for _, job := range kept[:len(kept)-s.options.MaxJobs] {
+		delete(s.jobs, job.ID)
+	}
+}
+
+// finished reports whether a job with status has stopped
+func finished(status string) bool {
+	return status == JobDone || status == JobFailed || status == JobCancelled
+}
+
+// validStatus reports whether status is a job status
+func validStatus(status string) bool {
+	return status == JobQueued || status == JobRunning || finished(status)
+}
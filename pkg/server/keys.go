@@ -0,0 +1,214 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tenant"
+)
+
+// ErrDailyBudget is returned for requests of a key that spent its daily tokens or cost
+var ErrDailyBudget = errors.New("daily budget of the API key is spent")
+
+// APIKey is a key clients authenticate with, sent as "Authorization: Bearer <key>" or
+// in the X-API-Key header, with its quotas. Daily quotas reset at midnight UTC.
+type APIKey struct {
+	Key          string  `json:"key"`
+	Name         string  `json:"name"`                     // Names the key in usage reports; required and unique
+	Tenant       string  `json:"tenant,omitempty"`         // Tenant of the key's requests; empty lets them name any tenant in the tenant header
+	Admin        bool    `json:"admin,omitempty"`          // May read the usage of every key
+	MaxUploadMB  int     `json:"max_upload_mb,omitempty"`  // Uploads larger than this are rejected with 413; 0 leaves Options.MaxUploadMB
+	DailyTokens  int     `json:"daily_tokens,omitempty"`   // AI tokens a day; once spent, requests are rejected with 429. 0 is unlimited
+	DailyCostUSD float64 `json:"daily_cost_usd,omitempty"` // AI cost a day, priced as MaxCostUSD is; 0 is unlimited
+	Revoked      bool    `json:"revoked,omitempty"`        // Rejected with 401; the key stays in usage reports
+}
+
+// Usage is what a key spent on one day
+type Usage struct {
+	Key              string  `json:"key"`
+	Date             string  `json:"date"` // UTC, as 2006-01-02
+	Requests         int     `json:"requests"`
+	Rejected         int     `json:"rejected"` // Requests over a quota
+	Documents        int     `json:"documents"`
+	Failed           int     `json:"failed"`
+	Pages            int     `json:"pages"`
+	UploadedBytes    int64   `json:"uploaded_bytes"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Keys authenticates requests and keeps the usage of every key in memory, so usage
+// restarts from zero with the server
+type Keys struct {
+	byHash          map[[sha256.Size]byte]*APIKey
+	promptPrice     float64
+	completionPrice float64
+	now             func() time.Time
+
+	mu    sync.Mutex
+	usage map[string]map[string]*Usage // By key name and date
+}
+
+// NewKeys creates the keys of a server. Usage is priced in USD per million prompt and
+// completion tokens, e.g. with the AIPromptPrice and AICompletionPrice of the config.
+func NewKeys(keys []APIKey, promptPrice, completionPrice float64) (*Keys, error) {
+	k := &Keys{
+		byHash:          make(map[[sha256.Size]byte]*APIKey),
+		promptPrice:     promptPrice,
+		completionPrice: completionPrice,
+		now:             time.Now,
+		usage:           make(map[string]map[string]*Usage),
+	}
+	names := make(map[string]bool)
+	for i := range keys {
+		key := keys[i]
+		if key.Key == "" || key.Name == "" {
+			return nil, fmt.Errorf("API key %d: key and name are required", i+1)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("API key %s: duplicate name", key.Name)
+		}
+		if key.Tenant != "" {
+			if err := tenant.ValidateID(key.Tenant); err != nil {
+				return nil, fmt.Errorf("API key %s: %w", key.Name, err)
+			}
+		}
+		hash := sha256.Sum256([]byte(key.Key))
+		if _, ok := k.byHash[hash]; ok {
+			return nil, fmt.Errorf("API key %s: duplicate key", key.Name)
+		}
+		names[key.Name] = true
+		k.byHash[hash] = &key
+	}
+	return k, nil
+}
+
+// LoadKeys reads a JSON array of APIKeys from path
+func LoadKeys(path string, promptPrice, completionPrice float64) (*Keys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys: %w", err)
+	}
+	return NewKeys(keys, promptPrice, completionPrice)
+}
+
+// lookup returns the key of a request's credentials. Keys are looked up by hash, so
+// the lookup takes as long whatever prefix of a key a client guesses.
+func (k *Keys) lookup(authorization, apiKey string) *APIKey {
+	secret := apiKey
+	if bearer, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		secret = strings.TrimSpace(bearer)
+	}
+	if secret == "" {
+		return nil
+	}
+	return k.byHash[sha256.Sum256([]byte(secret))]
+}
+
+// allow counts a request of key and returns ErrDailyBudget when the key spent its
+// daily quota. Documents in progress finish, so a day's usage may exceed the quota by
+// the documents running when it was reached.
+func (k *Keys) allow(key *APIKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	usage := k.today(key)
+	usage.Requests++
+	if (key.DailyTokens > 0 && usage.TotalTokens >= key.DailyTokens) || (key.DailyCostUSD > 0 && usage.CostUSD >= key.DailyCostUSD) {
+		usage.Rejected++
+		return ErrDailyBudget
+	}
+	return nil
+}
+
+// reject counts a request of key rejected for exceeding a quota other than the budget
+func (k *Keys) reject(key *APIKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.today(key).Rejected++
+}
+
+// record adds a document of key to its usage of the day it finished
+func (k *Keys) record(key *APIKey, uploaded int64, result *chunker.ChunkResult, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	usage := k.today(key)
+	usage.Documents++
+	usage.UploadedBytes += uploaded
+	if err != nil {
+		usage.Failed++
+	}
+	if result == nil {
+		return
+	}
+	usage.Pages += result.Pages
+	usage.PromptTokens += result.TokenUsage.PromptTokens
+	usage.CompletionTokens += result.TokenUsage.CompletionTokens
+	usage.TotalTokens += result.TokenUsage.TotalTokens
+	usage.CostUSD += float64(result.TokenUsage.PromptTokens)/1e6*k.promptPrice + float64(result.TokenUsage.CompletionTokens)/1e6*k.completionPrice
+}
+
+// Usage returns the usage of the named key on the last days days, today first; days
+// without requests are left out
+func (k *Keys) Usage(name string, days int) []Usage {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var usage []Usage
+	day := k.now().UTC()
+	for range days {
+		if dayUsage, ok := k.usage[name][day.Format(time.DateOnly)]; ok {
+			usage = append(usage, *dayUsage)
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+	return usage
+}
+
+// UsageOn returns the usage of every key with requests on date (2006-01-02), by name
+func (k *Keys) UsageOn(date string) []Usage {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var usage []Usage
+	for _, days := range k.usage {
+		if dayUsage, ok := days[date]; ok {
+			usage = append(usage, *dayUsage)
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Key < usage[j].Key })
+	return usage
+}
+
+// today returns the usage of key today, under the lock
+func (k *Keys) today(key *APIKey) *Usage {
+	date := k.now().UTC().Format(time.DateOnly)
+	days, ok := k.usage[key.Name]
+	if !ok {
+		days = make(map[string]*Usage)
+		k.usage[key.Name] = days
+	}
+	usage, ok := days[date]
+	if !ok {
+		usage = &Usage{Key: key.Name, Date: date}
+		days[date] = usage
+	}
+	return usage
+}
+
+// resetIn returns the time until the daily quotas reset
+func (k *Keys) resetIn() time.Duration {
+	now := k.now().UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+)
+
+// TestKeys checks that the handler rejects requests without a valid key with 401,
+// uploads over the key's size quota with 413, and requests of a key whose daily budget
+// is spent with 429 until the next day
+func TestKeys(t *testing.T) {
+	midnight := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		uploadMB       int           // Size of the upload; 0 uploads a short text
+		spent          int           // Tokens the key spent 30 seconds before midnight
+		elapsed        time.Duration // Time from then to the request
+		want           int
+		wantRetryAfter string
+	}{
+		{name: "no key", want: http.StatusUnauthorized},
+		{name: "unknown key", headers: map[string]string{"X-API-Key": "guessed"}, want: http.StatusUnauthorized},
+		{name: "key prefix", headers: map[string]string{"Authorization": "Bearer secre"}, want: http.StatusUnauthorized},
+		{name: "basic authorization", headers: map[string]string{"Authorization": "Basic c2VjcmV0"}, want: http.StatusUnauthorized},
+		{name: "revoked key", headers: map[string]string{"X-API-Key": "retired"}, want: http.StatusUnauthorized},
+		{name: "bearer key", headers: map[string]string{"Authorization": "Bearer secret"}, want: http.StatusOK},
+		{name: "X-API-Key", headers: map[string]string{"X-API-Key": "secret"}, want: http.StatusOK},
+		{name: "upload over the key's quota", headers: map[string]string{"X-API-Key": "secret"}, uploadMB: 2, want: http.StatusRequestEntityTooLarge},
+		{name: "budget almost spent", headers: map[string]string{"X-API-Key": "secret"}, spent: 999, want: http.StatusOK},
+		{name: "budget spent", headers: map[string]string{"X-API-Key": "secret"}, spent: 1000, elapsed: 10 * time.Second, want: http.StatusTooManyRequests, wantRetryAfter: "21"},
+		{name: "budget spent yesterday", headers: map[string]string{"X-API-Key": "secret"}, spent: 1000, elapsed: time.Minute, want: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, err := NewKeys([]APIKey{
+				{Key: "secret", Name: "app", Tenant: "a", MaxUploadMB: 1, DailyTokens: 1000},
+				{Key: "retired", Name: "old-app", Tenant: "a", Revoked: true},
+			}, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := midnight.Add(-30 * time.Second)
+			keys.now = func() time.Time { return now }
+			if test.spent > 0 {
+				keys.record(keys.lookup("", "secret"), 0, &chunker.ChunkResult{TokenUsage: chunker.TokenUsage{TotalTokens: test.spent}}, nil)
+			}
+			now = now.Add(test.elapsed)
+
+			s := newTestServer(t, Options{Keys: keys})
+			body := "Notes of the meeting."
+			if test.uploadMB > 0 {
+				body = strings.Repeat("A line of the notes.\n", test.uploadMB<<20/21+1)
+			}
+			request := httptest.NewRequest("POST", "/v1/chunk?filename=notes.txt", strings.NewReader(body))
+			for name, value := range test.headers {
+				request.Header.Set(name, value)
+			}
+			response := httptest.NewRecorder()
+			s.handler.ServeHTTP(response, request)
+
+			if response.Code != test.want {
+				t.Fatalf("status = %d, want %d: %s", response.Code, test.want, response.Body)
+			}
+			if got := response.Header().Get("Retry-After"); got != test.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, test.wantRetryAfter)
+			}
+			if test.want == http.StatusUnauthorized && response.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", response.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// TestKeysUsage checks that usage is counted per key and day, with rejected requests,
+// and that only admin keys read the usage of every key
+func TestKeysUsage(t *testing.T) {
+	keys, err := NewKeys([]APIKey{
+		{Key: "secret", Name: "app", Tenant: "a", MaxUploadMB: 1},
+		{Key: "admin", Name: "ops", Admin: true},
+	}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 9, 23, 59, 0, 0, time.UTC)
+	keys.now = func() time.Time { return now }
+	s := newTestServer(t, Options{Keys: keys})
+	app := map[string]string{"X-API-Key": "secret"}
+
+	s.do("POST", "/v1/chunk?filename=notes.txt", app, "Notes of the meeting.")
+	s.do("POST", "/v1/chunk?filename=notes.txt", app, strings.Repeat("x", 2<<20))
+	now = now.Add(time.Hour)
+	s.do("POST", "/v1/chunk?filename=notes.txt", app, "Notes of the next meeting.")
+
+	usage := keys.Usage("app", 7)
+	if len(usage) != 2 || usage[0].Date != "2026-03-10" || usage[1].Date != "2026-03-09" {
+		t.Fatalf("usage = %+v, want 2026-03-10 then 2026-03-09", usage)
+	}
+	if got := usage[1]; got.Requests != 2 || got.Rejected != 1 || got.Documents != 1 {
+		t.Errorf("usage of 2026-03-09 = %+v, want 2 requests, 1 rejected, 1 document", got)
+	}
+	if got := usage[0]; got.Requests != 1 || got.Rejected != 0 || got.Documents != 1 {
+		t.Errorf("usage of 2026-03-10 = %+v, want 1 request and 1 document", got)
+	}
+
+	tests := []struct {
+		name, path string
+		headers    map[string]string
+		want       int
+	}{
+		{"own usage", "/v1/usage", app, http.StatusOK},
+		{"own usage without a key", "/v1/usage", nil, http.StatusUnauthorized},
+		{"every key's usage", "/v1/usage/keys?date=2026-03-09", app, http.StatusForbidden},
+		{"every key's usage as admin", "/v1/usage/keys?date=2026-03-09", map[string]string{"X-API-Key": "admin"}, http.StatusOK},
+		{"bad date", "/v1/usage/keys?date=yesterday", map[string]string{"X-API-Key": "admin"}, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if status, body := s.do("GET", test.path, test.headers, ""); status != test.want {
+			t.Errorf("%s: GET %s status = %d, want %d: %s", test.name, test.path, status, test.want, body)
+		}
+	}
+}
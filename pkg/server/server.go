@@ -0,0 +1,625 @@
+// Package server serves the chunker over HTTP to several teams: every request names its
+// tenant, whose documents are chunked by the tenant's own chunker (see tenant.Pool) and
+// whose jobs are invisible to other tenants.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/store"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tempfile"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tenant"
+)
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// DefaultJobRetention is how long finished jobs are kept when Options.JobRetention is 0
+const DefaultJobRetention = 24 * time.Hour
+
+// errJobCancelled is the error of jobs cancelled with DELETE /v1/jobs/{id}
+var errJobCancelled = errors.New("job cancelled")
+
+// OpenAPI is the OpenAPI 3 document of the server's endpoints, also served at
+// /openapi.json; the client package is generated from it
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// Options configures a Server
+type Options struct {
+	Tenants          *tenant.Pool
+	TenantHeader     string             // Header naming the tenant of a request, default "X-Tenant-ID"
+	DefaultTenant    string             // Tenant of requests without the header; empty rejects them
+	OutputType       chunker.OutputType // What the chunkers save besides the response, e.g. chunker.OutputBoth for chunk files under the tenant's directories
+	Workers          int                // Jobs processed at once, default 2
+	MaxUploadMB      int                // Reject larger uploads with 413 before reading them whole; 0 is unlimited
+	TempDir          string             // Where job uploads wait to be processed (see tempfile.Root)
+	Logger           chunker.Logger     // Default: log.Default()
+	Keys             *Keys              // API keys requests authenticate with, and their quotas; nil serves without authentication
+	JobRetention     time.Duration      // How long finished jobs are kept, default DefaultJobRetention; negative keeps them until MaxJobs removes them
+	MaxJobs          int                // Finished jobs kept at most, the oldest removed first; 0 is unlimited
+	Store            store.Store        // Where finished jobs and their results are persisted; nil keeps them in memory only
+	UploadsPerMinute int                // Uploads accepted per minute from one client address, answered with 429 beyond; 0 is unlimited
+	TrustProxy       bool               // Take client addresses from X-Forwarded-For, for servers behind a reverse proxy
+}
+
+// Server handles chunking requests:
+//
+//	POST   /v1/chunk             chunk an upload and respond with its chunks
+//	POST   /v1/jobs              queue an upload and respond 202 with the job
+//	GET    /v1/jobs              the jobs, newest first (?status=, ?tenant=, ?created_after=, ?limit=)
+//	GET    /v1/jobs/{id}         the job and, once done, its chunks
+//	DELETE /v1/jobs/{id}         cancel a queued or running job, or remove a finished one
+//	GET    /v1/jobs/{id}/events  server-sent events of the job's pages, chunks and status
+//	GET    /v1/usage             usage of the request's API key on the last days (?days=, default 7)
+//	GET    /v1/usage/keys        usage of every API key on a day (?date=, default today); admin keys only
+//	GET    /healthz              liveness
+//	GET    /openapi.json         the OpenAPI document of these endpoints
+//
+// Uploads are the request body, with the filename in the filename query parameter, or
+// the "file" part of a multipart form. Metadata for every chunk is a JSON object in the
+// metadata query parameter or form field; the tenant ID is added to it under
+// tenant.MetadataKey.
+//
+// With Options.Keys, every request but /healthz and /openapi.json needs an API key, and a key bound to a
+// tenant only serves that tenant. Requests of a key whose daily budget is spent are
+// rejected with 429 until midnight UTC. Admin keys not bound to a tenant see, list and
+// cancel the jobs of every tenant. With Options.UploadsPerMinute, uploads from one client
+// address beyond it are rejected with 429 too.
+//
+// Jobs are kept in memory. Finished jobs are removed after Options.JobRetention and
+// beyond Options.MaxJobs, checked as requests come in. With Options.Store, finished jobs
+// are also persisted there with their results, and served from it once they have left
+// memory, until DELETE /v1/jobs/{id} removes them.
+type Server struct {
+	options  Options
+	slots    chan struct{}
+	wg       sync.WaitGroup
+	throttle *throttle
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// Job is a document queued with POST /v1/jobs
+type Job struct {
+	ID         string               `json:"id"`
+	Tenant     string               `json:"tenant"`
+	Filename   string               `json:"filename"`
+	Status     string               `json:"status"`
+	Error      string               `json:"error,omitempty"`
+	CreatedAt  time.Time            `json:"created_at"`
+	StartedAt  *time.Time           `json:"started_at,omitempty"`
+	FinishedAt *time.Time           `json:"finished_at,omitempty"`
+	Result     *chunker.ChunkResult `json:"result,omitempty"`
+
+	metadata map[string]any
+	upload   string             // Spooled upload, removed once processed
+	size     int64              // Bytes of the upload
+	key      *APIKey            // Key the job is accounted to; nil without Options.Keys
+	events   []jobEvent         // Streamed by GET /v1/jobs/{id}/events
+	changed  chan struct{}      // Closed when an event is published
+	ctx      context.Context    // Done once the job is cancelled
+	cancel   context.CancelFunc // Cancels ctx, and with it the job's chunking
+}
+
+// caller is who sent a request
+type caller struct {
+	tenant string
+	key    *APIKey // nil without Options.Keys
+}
+
+// errorResponse is the body of every error response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New creates a server
+func New(options Options) *Server {
+	if options.TenantHeader == "" {
+		options.TenantHeader = "X-Tenant-ID"
+	}
+	if options.Workers <= 0 {
+		options.Workers = 2
+	}
+	if options.Logger == nil {
+		options.Logger = log.Default()
+	}
+	if options.JobRetention == 0 {
+		options.JobRetention = DefaultJobRetention
+	}
+	return &Server{
+		options:  options,
+		slots:    make(chan struct{}, options.Workers),
+		throttle: newThrottle(options.UploadsPerMinute),
+		jobs:     make(map[string]*Job),
+	}
+}
+
+// Handler returns the handler of the server's endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+	mux.HandleFunc("POST /v1/chunk", s.handleChunk)
+	mux.HandleFunc("POST /v1/jobs", s.handleCreateJob)
+	mux.HandleFunc("GET /v1/jobs", s.handleListJobs)
+	mux.HandleFunc("GET /v1/jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("DELETE /v1/jobs/{id}", s.handleCancelJob)
+	mux.HandleFunc("GET /v1/jobs/{id}/events", s.handleJobEvents)
+	if s.options.Keys != nil {
+		mux.HandleFunc("GET /v1/usage", s.handleUsage)
+		mux.HandleFunc("GET /v1/usage/keys", s.handleKeysUsage)
+	}
+	return mux
+}
+
+// Wait waits for the jobs queued so far to finish
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPI)
+}
+
+func (s *Server) handleChunk(w http.ResponseWriter, r *http.Request) {
+	who, c, ok := s.tenantChunker(w, r)
+	if !ok || !s.admit(w, r, who.key) {
+		return
+	}
+	body, filename, metadata, err := s.upload(w, r, who.key)
+	if err != nil {
+		s.uploadFailed(w, who.key, err)
+		return
+	}
+	counted := &countingReader{reader: body}
+	result, err := c.ChunkReaderWithMetadata(counted, filename, s.options.OutputType, tenant.Metadata(who.tenant, metadata))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		// The upload was streamed into the chunker, which read past the size quota
+		s.uploadFailed(w, who.key, err)
+		return
+	}
+	s.record(who.key, counted.n, result, err)
+	if err != nil {
+		writeError(w, chunkStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	who, c, ok := s.tenantChunker(w, r)
+	if !ok || !s.admit(w, r, who.key) {
+		return
+	}
+	body, filename, metadata, err := s.upload(w, r, who.key)
+	if err != nil {
+		s.uploadFailed(w, who.key, err)
+		return
+	}
+	path, size, err := s.spool(body)
+	if err != nil {
+		s.uploadFailed(w, who.key, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		ID:        newJobID(),
+		Tenant:    who.tenant,
+		Filename:  filename,
+		Status:    JobQueued,
+		CreatedAt: time.Now().UTC(),
+		metadata:  tenant.Metadata(who.tenant, metadata),
+		upload:    path,
+		size:      size,
+		key:       who.key,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	s.setStatus(job, JobQueued, nil, nil)
+	s.mu.Lock()
+	s.pruneLocked(time.Now())
+	s.jobs[job.ID] = job
+	snapshot := job.snapshot()
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.run(job, c.WithProgress(s.progress(job)).WithContext(ctx))
+	w.Header().Set("Location", "/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.findJob(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	snapshot := job.snapshot()
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	key, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	days := 7
+	if value := r.URL.Query().Get("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("days must be a positive number"))
+			return
+		}
+		days = n
+	}
+	writeJSON(w, http.StatusOK, s.options.Keys.Usage(key.Name, days))
+}
+
+func (s *Server) handleKeysUsage(w http.ResponseWriter, r *http.Request) {
+	key, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if !key.Admin {
+		writeError(w, http.StatusForbidden, errors.New("only admin API keys may read the usage of other keys"))
+		return
+	}
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = s.options.Keys.now().UTC().Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, date); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("date must be formatted as 2006-01-02"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.options.Keys.UsageOn(date))
+}
+
+// run processes a job once a worker slot is free
+func (s *Server) run(job *Job, c *chunker.Chunker) {
+	defer s.wg.Done()
+	defer s.persist(job)
+	defer tempfile.Remove(job.upload)
+	defer job.cancel()
+	select {
+	case s.slots <- struct{}{}:
+	case <-job.ctx.Done():
+		s.setStatus(job, JobCancelled, nil, errJobCancelled)
+		return
+	}
+	defer func() { <-s.slots }()
+
+	s.setStatus(job, JobRunning, nil, nil)
+	file, err := os.Open(job.upload)
+	if err != nil {
+		s.setStatus(job, JobFailed, nil, fmt.Errorf("failed to open upload: %w", err))
+		return
+	}
+	defer file.Close()
+	result, err := c.ChunkReaderWithMetadata(file, job.Filename, s.options.OutputType, job.metadata)
+	s.record(job.key, job.size, result, err)
+	if job.ctx.Err() != nil {
+		s.options.Logger.Printf("Job %s of tenant %s cancelled", job.ID, job.Tenant)
+		s.setStatus(job, JobCancelled, nil, errJobCancelled)
+		return
+	}
+	if err != nil {
+		s.options.Logger.Printf("Job %s of tenant %s failed: %v", job.ID, job.Tenant, err)
+		s.setStatus(job, JobFailed, nil, err)
+		return
+	}
+	s.setStatus(job, JobDone, result, nil)
+}
+
+// setStatus updates a job under the lock and publishes its status event
+func (s *Server) setStatus(job *Job, status string, result *chunker.ChunkResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.Status = status
+	job.Result = result
+	if err != nil {
+		job.Error = err.Error()
+	}
+	now := time.Now().UTC()
+	if status == JobRunning {
+		job.StartedAt = &now
+	}
+	if finished(status) {
+		job.FinishedAt = &now
+	}
+
+	snapshot := job.snapshot()
+	snapshot.Result = nil
+	data, err := json.Marshal(Event{Type: EventStatus, Job: &snapshot})
+	if err != nil {
+		s.options.Logger.Printf("Failed to encode status event of job %s: %v", job.ID, err)
+		return
+	}
+	s.publishLocked(job, EventStatus, data)
+}
+
+// snapshot returns a copy of the job's exported fields, under the lock
+func (job *Job) snapshot() Job {
+	return Job{
+		ID:         job.ID,
+		Tenant:     job.Tenant,
+		Filename:   job.Filename,
+		Status:     job.Status,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+		Result:     job.Result,
+	}
+}
+
+// authenticate returns the API key of a request, writing an error response when it
+// has none; without Options.Keys every request is let through with a nil key
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (*APIKey, bool) {
+	if s.options.Keys == nil {
+		return nil, true
+	}
+	key := s.options.Keys.lookup(r.Header.Get("Authorization"), r.Header.Get("X-API-Key"))
+	if key == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid API key"))
+		return nil, false
+	}
+	if key.Revoked {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("API key is revoked"))
+		return nil, false
+	}
+	return key, true
+}
+
+// caller returns the API key and tenant of a request, writing an error response when
+// it has none or its key is bound to another tenant
+func (s *Server) caller(w http.ResponseWriter, r *http.Request) (caller, bool) {
+	key, ok := s.authenticate(w, r)
+	if !ok {
+		return caller{}, false
+	}
+	id := r.Header.Get(s.options.TenantHeader)
+	if key != nil && key.Tenant != "" {
+		if id != "" && id != key.Tenant {
+			writeError(w, http.StatusForbidden, fmt.Errorf("API key is not valid for tenant %q", id))
+			return caller{}, false
+		}
+		id = key.Tenant
+	}
+	if id == "" {
+		id = s.options.DefaultTenant
+	}
+	if id == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing %s header", s.options.TenantHeader))
+		return caller{}, false
+	}
+	if err := tenant.ValidateID(id); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return caller{}, false
+	}
+	return caller{tenant: id, key: key}, true
+}
+
+// tenantChunker returns the caller of a request and its tenant's chunker, writing an
+// error response when the caller is not authorized or the tenant is not served
+func (s *Server) tenantChunker(w http.ResponseWriter, r *http.Request) (caller, *chunker.Chunker, bool) {
+	who, ok := s.caller(w, r)
+	if !ok {
+		return caller{}, nil, false
+	}
+	c, err := s.options.Tenants.Chunker(who.tenant)
+	if errors.Is(err, tenant.ErrUnknownTenant) {
+		writeError(w, http.StatusForbidden, err)
+		return caller{}, nil, false
+	}
+	if err != nil {
+		s.options.Logger.Printf("Failed to set up tenant %s: %v", who.tenant, err)
+		writeError(w, http.StatusInternalServerError, err)
+		return caller{}, nil, false
+	}
+	return who, c, true
+}
+
+// admit counts an upload against its client's Options.UploadsPerMinute and its key's
+// usage, writing 429 when the client uploads too often or the key's daily budget is spent
+func (s *Server) admit(w http.ResponseWriter, r *http.Request, key *APIKey) bool {
+	if ok, wait := s.throttle.allow(s.clientAddr(r)); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		writeError(w, http.StatusTooManyRequests, fmt.Errorf("more than %d uploads a minute", s.options.UploadsPerMinute))
+		return false
+	}
+	if key == nil {
+		return true
+	}
+	if err := s.options.Keys.allow(key); err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.options.Keys.resetIn().Seconds())+1))
+		writeError(w, http.StatusTooManyRequests, err)
+		return false
+	}
+	return true
+}
+
+// record adds a processed document to its key's usage
+func (s *Server) record(key *APIKey, uploaded int64, result *chunker.ChunkResult, err error) {
+	if key != nil {
+		s.options.Keys.record(key, uploaded, result, err)
+	}
+}
+
+// uploadFailed writes the error response of an upload that could not be read, counting
+// uploads over the size quota as rejected
+func (s *Server) uploadFailed(w http.ResponseWriter, key *APIKey, err error) {
+	status := uploadStatus(err)
+	if status == http.StatusRequestEntityTooLarge && key != nil {
+		s.options.Keys.reject(key)
+	}
+	writeError(w, status, err)
+}
+
+// errBadUpload marks requests whose upload cannot be read
+var errBadUpload = errors.New("bad upload")
+
+// upload returns the document of a request with its filename and chunk metadata. Form
+// fields before the "file" part are read; the ones after it are not. Uploads are
+// limited to the smaller of Options.MaxUploadMB and the key's MaxUploadMB.
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, key *APIKey) (io.Reader, string, map[string]any, error) {
+	limit := s.options.MaxUploadMB
+	if key != nil && key.MaxUploadMB > 0 && (limit == 0 || key.MaxUploadMB < limit) {
+		limit = key.MaxUploadMB
+	}
+	if limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(limit)<<20)
+	}
+	filename := r.URL.Query().Get("filename")
+	rawMetadata := r.URL.Query().Get("metadata")
+
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		form, err := r.MultipartReader()
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("%w: %v", errBadUpload, err)
+		}
+		body = nil
+		for body == nil {
+			part, err := form.NextPart()
+			if err == io.EOF {
+				return nil, "", nil, fmt.Errorf("%w: no file part", errBadUpload)
+			}
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("%w: %v", errBadUpload, err)
+			}
+			switch part.FormName() {
+			case "file":
+				if filename == "" {
+					filename = part.FileName()
+				}
+				body = part
+			case "metadata":
+				value, err := io.ReadAll(io.LimitReader(part, 1<<20))
+				if err != nil {
+					return nil, "", nil, fmt.Errorf("%w: %v", errBadUpload, err)
+				}
+				rawMetadata = string(value)
+			}
+		}
+	}
+
+	var metadata map[string]any
+	if rawMetadata != "" {
+		if err := json.Unmarshal([]byte(rawMetadata), &metadata); err != nil {
+			return nil, "", nil, fmt.Errorf("%w: metadata is not a JSON object: %v", errBadUpload, err)
+		}
+	}
+	if filename != "" {
+		// Only the name is kept, so an upload cannot name paths outside the tenant's directories
+		filename = filepath.Base(filename)
+	}
+	return body, filename, metadata, nil
+}
+
+// spool copies a job's upload to a temp file, which the job removes once processed,
+// and returns its path and size
+func (s *Server) spool(body io.Reader) (string, int64, error) {
+	file, err := tempfile.CreateTemp(s.options.TempDir, "upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to spool upload: %w", err)
+	}
+	size, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		tempfile.Remove(file.Name())
+		return "", 0, fmt.Errorf("failed to spool upload: %w", err)
+	}
+	return file.Name(), size, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// uploadStatus returns the status of a request whose upload failed
+func uploadStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errBadUpload):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// chunkStatus returns the status of a request whose document failed
+func chunkStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge), errors.Is(err, chunker.ErrFileTooLarge), errors.Is(err, chunker.ErrTooManyPages):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, chunker.ErrProcessingTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+// newJobID returns a random job ID
+func newJobID() string {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/tenant"
+)
+
+// testServer is a server of tenants a and b with its handler
+type testServer struct {
+	*Server
+	handler http.Handler
+	outputs []string // Directories the tenants' chunkers write to
+}
+
+// newTestServer creates a server of tenants a and b with options, whose chunkers save
+// chunk and JSON files under a temporary directory
+func newTestServer(t *testing.T, options Options) *testServer {
+	t.Helper()
+	cfg := chunkertest.Config(t)
+	tenants := tenant.NewPool(tenant.Options{Config: cfg, Allowed: []string{"a", "b"}})
+	t.Cleanup(func() { tenants.Close() })
+	options.Tenants = tenants
+	options.OutputType = chunker.OutputBoth
+	options.TempDir = cfg.TempDir
+	options.Logger = log.New(io.Discard, "", 0)
+	s := New(options)
+	t.Cleanup(s.Wait)
+	return &testServer{Server: s, handler: s.Handler(), outputs: []string{cfg.OutputDir, cfg.ChunkDir, cfg.JSONDir}}
+}
+
+// do sends a request with the given headers and returns its status and body
+func (s *testServer) do(method, target string, headers map[string]string, body string) (int, string) {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	s.handler.ServeHTTP(recorder, request)
+	data, _ := io.ReadAll(recorder.Result().Body)
+	return recorder.Code, string(data)
+}
+
+// createJob queues a text document with the given headers and waits for it to finish
+func (s *testServer) createJob(t *testing.T, headers map[string]string, filename string) Job {
+	t.Helper()
+	status, body := s.do("POST", "/v1/jobs?filename="+filename, headers, "Minutes of the meeting.\n\nThe budget was approved.")
+	if status != http.StatusAccepted {
+		t.Fatalf("POST /v1/jobs status = %d, want 202: %s", status, body)
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(body), &job); err != nil {
+		t.Fatal(err)
+	}
+	s.Wait()
+	return job
+}
+
+// files returns the files under the tenant's directories of the server's chunkers
+func (s *testServer) files(id string) []string {
+	var files []string
+	for _, dir := range s.outputs {
+		filepath.WalkDir(filepath.Join(dir, id), func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// TestTenantIsolation checks that a tenant cannot read, stream, list or cancel the jobs
+// of another tenant, whether the tenant comes from the tenant header or an API key
+// bound to it, and that the chunkers of tenants write to their own directories
+func TestTenantIsolation(t *testing.T) {
+	keys, err := NewKeys([]APIKey{
+		{Key: "secret-a", Name: "a", Tenant: "a"},
+		{Key: "secret-b", Name: "b", Tenant: "b"},
+		{Key: "secret-admin", Name: "admin", Admin: true},
+	}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name             string
+		keys             *Keys
+		tenantA, tenantB map[string]string // Headers of the tenants' requests
+	}{
+		{"tenant header", nil, map[string]string{"X-Tenant-ID": "a"}, map[string]string{"X-Tenant-ID": "b"}},
+		{"API keys", keys, map[string]string{"X-API-Key": "secret-a"}, map[string]string{"X-API-Key": "secret-b"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t, Options{Keys: test.keys})
+			job := s.createJob(t, test.tenantA, "minutes.txt")
+			path := "/v1/jobs/" + job.ID
+
+			for _, request := range []struct{ method, path string }{
+				{"GET", path},
+				{"GET", path + "/events"},
+				{"DELETE", path},
+			} {
+				if status, body := s.do(request.method, request.path, test.tenantB, ""); status != http.StatusNotFound {
+					t.Errorf("tenant b: %s %s status = %d, want 404: %s", request.method, request.path, status, body)
+				}
+			}
+			status, body := s.do("GET", "/v1/jobs", test.tenantB, "")
+			if status != http.StatusOK || strings.Contains(body, job.ID) {
+				t.Errorf("tenant b: GET /v1/jobs = %d %s, want 200 without job %s", status, body, job.ID)
+			}
+			if status, body := s.do("GET", "/v1/jobs?tenant=a", test.tenantB, ""); status != http.StatusForbidden {
+				t.Errorf("tenant b: GET /v1/jobs?tenant=a status = %d, want 403: %s", status, body)
+			}
+			if test.keys != nil {
+				spoofed := map[string]string{"X-API-Key": "secret-b", "X-Tenant-ID": "a"}
+				if status, body := s.do("GET", path, spoofed, ""); status != http.StatusForbidden {
+					t.Errorf("key of b naming tenant a: GET %s status = %d, want 403: %s", path, status, body)
+				}
+			}
+
+			// The job is still there for its tenant, with its chunks
+			status, body = s.do("GET", path, test.tenantA, "")
+			if status != http.StatusOK || !strings.Contains(body, `"status":"done"`) || !strings.Contains(body, "The budget was approved.") {
+				t.Errorf("tenant a: GET %s = %d %s, want the done job with its chunks", path, status, body)
+			}
+			status, body = s.do("GET", "/v1/jobs", test.tenantA, "")
+			if status != http.StatusOK || !strings.Contains(body, job.ID) {
+				t.Errorf("tenant a: GET /v1/jobs = %d %s, want job %s", status, body, job.ID)
+			}
+			if test.keys != nil {
+				admin := map[string]string{"Authorization": "Bearer secret-admin"}
+				if status, body := s.do("GET", path, admin, ""); status != http.StatusOK {
+					t.Errorf("admin: GET %s status = %d, want 200: %s", path, status, body)
+				}
+			}
+
+			if files := s.files("a"); len(files) == 0 {
+				t.Error("tenant a: no chunk or JSON files under its directories")
+			}
+			if files := s.files("b"); len(files) > 0 {
+				t.Errorf("tenant b: files of tenant a's job under its directories: %q", files)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/store"
+)
+
+// persist saves a finished job to Options.Store, logging failures, since the job has
+// finished either way and stays in memory until it is pruned
+func (s *Server) persist(job *Job) {
+	if s.options.Store == nil {
+		return
+	}
+	s.mu.Lock()
+	snapshot := job.snapshot()
+	s.mu.Unlock()
+	if !finished(snapshot.Status) {
+		return
+	}
+	if err := s.put(snapshot); err != nil {
+		s.options.Logger.Printf("Failed to store job %s of tenant %s: %v", job.ID, job.Tenant, err)
+	}
+}
+
+// put encodes a job and its result into a store record
+func (s *Server) put(job Job) error {
+	record := store.Record{ID: job.ID, Tenant: job.Tenant, Status: job.Status, CreatedAt: job.CreatedAt}
+	if job.Result != nil {
+		result, err := json.Marshal(job.Result)
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		record.Result = result
+	}
+	job.Result = nil
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+	record.Job = data
+	return s.options.Store.Put(record)
+}
+
+// storedJob returns a job of Options.Store, with its result and the status event its
+// stream replays, or nil when it is not stored
+func (s *Server) storedJob(id string) (*Job, error) {
+	if s.options.Store == nil {
+		return nil, nil
+	}
+	record, err := s.options.Store.Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job, err := decodeJob(*record)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(Event{Type: EventStatus, Job: job})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode status event of job %s: %w", id, err)
+	}
+	if len(record.Result) > 0 {
+		job.Result = &chunker.ChunkResult{}
+		if err := json.Unmarshal(record.Result, job.Result); err != nil {
+			return nil, fmt.Errorf("failed to decode result of job %s: %w", id, err)
+		}
+	}
+	job.events = []jobEvent{{id: 1, eventType: EventStatus, data: data}}
+	return job, nil
+}
+
+// storedJobs lists the jobs of Options.Store, without their results
+func (s *Server) storedJobs(filter store.Filter) ([]Job, error) {
+	if s.options.Store == nil {
+		return nil, nil
+	}
+	records, err := s.options.Store.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]Job, 0, len(records))
+	for _, record := range records {
+		job, err := decodeJob(record)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// decodeJob decodes the job of a store record
+func decodeJob(record store.Record) (*Job, error) {
+	var job Job
+	if err := json.Unmarshal(record.Job, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job %s: %w", record.ID, err)
+	}
+	return &job, nil
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/ratelimit"
+)
+
+// clientIdle is how long a client's limiter is kept after its last upload; by then its
+// bucket is full again, so forgetting it changes nothing
+const clientIdle = time.Minute
+
+// throttle limits the uploads of every client address to Options.UploadsPerMinute
+type throttle struct {
+	perMinute int
+
+	mu      sync.Mutex
+	clients map[string]*clientLimiter
+	swept   time.Time
+}
+
+// clientLimiter is the upload limiter of a client address
+type clientLimiter struct {
+	limiter *ratelimit.Limiter
+	seen    time.Time
+}
+
+// newThrottle returns the throttle of Options.UploadsPerMinute, or nil when it is 0
+func newThrottle(perMinute int) *throttle {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &throttle{perMinute: perMinute, clients: make(map[string]*clientLimiter), swept: time.Now()}
+}
+
+// allow reserves an upload of addr, or returns how long until it may upload
+func (t *throttle) allow(addr string) (bool, time.Duration) {
+	if t == nil {
+		return true, 0
+	}
+	t.mu.Lock()
+	now := time.Now()
+	if now.Sub(t.swept) > clientIdle {
+		for key, c := range t.clients {
+			if now.Sub(c.seen) > clientIdle {
+				delete(t.clients, key)
+			}
+		}
+		t.swept = now
+	}
+	c, found := t.clients[addr]
+	if !found {
+		c = &clientLimiter{limiter: ratelimit.New(t.perMinute, 0)}
+		t.clients[addr] = c
+	}
+	c.seen = now
+	t.mu.Unlock()
+	return c.limiter.Allow(0)
+}
+
+// clientAddr returns the address uploads of a request are limited by: the last address
+// of X-Forwarded-For, which the reverse proxy in front of the server appended, with
+// Options.TrustProxy, or else the address of the connection
+func (s *Server) clientAddr(r *http.Request) string {
+	if s.options.TrustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			addrs := strings.Split(forwarded[len(forwarded)-1], ",")
+			if addr := strings.TrimSpace(addrs[len(addrs)-1]); addr != "" {
+				return addr
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
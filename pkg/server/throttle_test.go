@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestUploadsPerMinute checks that uploads beyond Options.UploadsPerMinute are rejected
+// with 429 per client address, taken from X-Forwarded-For only with TrustProxy
+func TestUploadsPerMinute(t *testing.T) {
+	type upload struct {
+		remoteAddr, forwardedFor string
+	}
+	tests := []struct {
+		name       string
+		trustProxy bool
+		uploads    []upload
+		want       []int
+	}{
+		{
+			name:    "one address",
+			uploads: []upload{{"192.0.2.1:1234", ""}, {"192.0.2.1:5678", ""}},
+			want:    []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:    "two addresses",
+			uploads: []upload{{"192.0.2.1:1234", ""}, {"192.0.2.2:1234", ""}},
+			want:    []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:    "forwarded addresses of an untrusted proxy",
+			uploads: []upload{{"192.0.2.1:1234", "198.51.100.1"}, {"192.0.2.1:1234", "198.51.100.2"}},
+			want:    []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:       "forwarded addresses of a trusted proxy",
+			trustProxy: true,
+			uploads:    []upload{{"192.0.2.1:1234", "198.51.100.1"}, {"192.0.2.1:1234", "198.51.100.2"}, {"192.0.2.1:1234", "203.0.113.9, 198.51.100.1"}},
+			want:       []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t, Options{DefaultTenant: "a", UploadsPerMinute: 1, TrustProxy: test.trustProxy})
+			for i, u := range test.uploads {
+				request := httptest.NewRequest("POST", "/v1/chunk?filename=notes.txt", strings.NewReader("Notes of the meeting."))
+				request.RemoteAddr = u.remoteAddr
+				if u.forwardedFor != "" {
+					request.Header.Set("X-Forwarded-For", u.forwardedFor)
+				}
+				response := httptest.NewRecorder()
+				s.handler.ServeHTTP(response, request)
+
+				if response.Code != test.want[i] {
+					t.Errorf("upload %d: status = %d, want %d: %s", i+1, response.Code, test.want[i], response.Body)
+				}
+				if retryAfter := response.Header().Get("Retry-After"); (response.Code == http.StatusTooManyRequests) != (retryAfter != "") {
+					t.Errorf("upload %d: status %d with Retry-After %q", i+1, response.Code, retryAfter)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// JSONLines writes every chunk as one JSON object per line
+type JSONLines struct {
+	mu         sync.Mutex
+	writer     io.Writer
+	compressor io.WriteCloser // Set by NewCompressedJSONLines
+}
+
+// NewJSONLines creates a sink that writes JSON lines to writer
+func NewJSONLines(writer io.Writer) *JSONLines {
+	return &JSONLines{writer: writer}
+}
+
+// NewCompressedJSONLines creates a sink that writes a utils.CompressionGzip or
+// utils.CompressionZstd compressed JSON lines stream to writer. Close must be called
+// to finish the stream.
+func NewCompressedJSONLines(writer io.Writer, format string) (*JSONLines, error) {
+	compressor, err := utils.NewCompressWriter(writer, format)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLines{writer: compressor, compressor: compressor}, nil
+}
+
+// Write writes the chunks of a document, one per line
+func (s *JSONLines) Write(chunks []chunker.ChunkData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoder := json.NewEncoder(s.writer)
+	for _, chunk := range chunks {
+		if err := encoder.Encode(chunk); err != nil {
+			return fmt.Errorf("failed to write chunk %d: %w", chunk.ChunkIndex, err)
+		}
+	}
+	return nil
+}
+
+// Apply writes the operations of a document update, one per line, for consumers that
+// keep the chunks of a document in a store (see chunker.ChunkUpdate)
+func (s *JSONLines) Apply(ops []chunker.ChunkOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoder := json.NewEncoder(s.writer)
+	for _, op := range ops {
+		if err := encoder.Encode(op); err != nil {
+			return fmt.Errorf("failed to write %s of chunk %d: %w", op.Op, op.Chunk.ChunkIndex, err)
+		}
+	}
+	return nil
+}
+
+// Flush writes the chunks buffered by a compressed stream to the underlying writer, so
+// a stopped batch run leaves a readable stream; it does nothing for uncompressed ones
+func (s *JSONLines) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flusher, ok := s.compressor.(interface{ Flush() error })
+	if !ok {
+		return nil
+	}
+	if err := flusher.Flush(); err != nil {
+		return fmt.Errorf("failed to flush compressed stream: %w", err)
+	}
+	return nil
+}
+
+// Close finishes a compressed stream; it does not close the underlying writer
+func (s *JSONLines) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.compressor == nil {
+		return nil
+	}
+	if err := s.compressor.Close(); err != nil {
+		return fmt.Errorf("failed to finish compressed stream: %w", err)
+	}
+	return nil
+}
+
+// GetName returns the sink name
+func (s *JSONLines) GetName() string {
+	return "JSONLines"
+}
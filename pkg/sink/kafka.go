@@ -0,0 +1,573 @@
+package sink
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+)
+
+// KafkaOptions configures a Kafka sink
+type KafkaOptions struct {
+	Brokers  []string      // host:port of bootstrap brokers, default "localhost:9092"
+	Topic    string        // Topic of the corpus, e.g. "chunks.handbook"; required
+	ClientID string        // Sent with every request, default "pdf-chunk-extractor"
+	TLS      *tls.Config   // Connect to brokers with TLS when set
+	Timeout  time.Duration // Dial, request and replication timeout, default 10s
+}
+
+// Kafka publishes every chunk as a JSON message to a Kafka topic, for consumers that
+// embed and index chunks asynchronously. Messages are keyed by the document slug, so
+// the chunks of a document land on one partition in order, the partition Kafka's Java
+// producer picks for that key. Write returns once every in-sync replica has the chunks
+// of the document (acks=all).
+//
+// It speaks the Kafka protocol (Metadata v4, Produce v3, so brokers 0.11 and later)
+// over one connection per broker that is redialled after errors. SASL authentication
+// and compression are not supported.
+type Kafka struct {
+	options KafkaOptions
+
+	mu            sync.Mutex
+	conns         map[int32]*kafkaConn // By broker node ID
+	brokers       map[int32]string     // Addresses by node ID, from the last metadata
+	leaders       []int32              // Leader of every partition of Topic, by partition
+	correlationID int32
+}
+
+// kafkaConn is a connection to one broker
+type kafkaConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Kafka API keys and versions of the requests the sink sends
+const (
+	kafkaProduce         = 0
+	kafkaProduceVersion  = 3
+	kafkaMetadata        = 3
+	kafkaMetadataVersion = 4
+)
+
+// kafkaErrorNames names the error codes a produce or metadata request commonly fails with
+var kafkaErrorNames = map[int16]string{
+	2:  "CORRUPT_MESSAGE",
+	3:  "UNKNOWN_TOPIC_OR_PARTITION",
+	5:  "LEADER_NOT_AVAILABLE",
+	6:  "NOT_LEADER_OR_FOLLOWER",
+	7:  "REQUEST_TIMED_OUT",
+	10: "MESSAGE_TOO_LARGE",
+	17: "INVALID_TOPIC_EXCEPTION",
+	19: "NOT_ENOUGH_REPLICAS",
+	20: "NOT_ENOUGH_REPLICAS_AFTER_APPEND",
+	29: "TOPIC_AUTHORIZATION_FAILED",
+	87: "INVALID_RECORD",
+}
+
+// kafkaError is an error code returned by a broker
+type kafkaError int16
+
+func (e kafkaError) Error() string {
+	if name, ok := kafkaErrorNames[int16(e)]; ok {
+		return fmt.Sprintf("kafka: error %d (%s)", int16(e), name)
+	}
+	return fmt.Sprintf("kafka: error %d", int16(e))
+}
+
+// retriable reports whether the request may succeed against fresh metadata, as after a
+// leader election
+func (e kafkaError) retriable() bool {
+	return e == 3 || e == 5 || e == 6 || e == 7 || e == 19 || e == 20
+}
+
+// NewKafka creates a Kafka sink and reads the partitions of the topic from the brokers
+func NewKafka(options KafkaOptions) (*Kafka, error) {
+	if options.Topic == "" {
+		return nil, fmt.Errorf("kafka topic is required")
+	}
+	if len(options.Brokers) == 0 {
+		options.Brokers = []string{"localhost:9092"}
+	}
+	if options.ClientID == "" {
+		options.ClientID = "pdf-chunk-extractor"
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+
+	k := &Kafka{options: options, conns: make(map[int32]*kafkaConn)}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.refreshMetadata(); err != nil {
+		k.closeConns()
+		return nil, fmt.Errorf("failed to read kafka metadata: %w", err)
+	}
+	return k, nil
+}
+
+// Write publishes the chunks of a document, keyed by its slug, retrying once against
+// fresh metadata when a partition moved to another leader
+func (k *Kafka) Write(chunks []chunker.ChunkData) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	values := make([][]byte, len(chunks))
+	for i, chunk := range chunks {
+		value, err := json.Marshal(chunk)
+		if err != nil {
+			return fmt.Errorf("failed to marshal chunk %d: %w", chunk.ChunkIndex, err)
+		}
+		values[i] = value
+	}
+
+	err := k.produce(chunks, values)
+	var brokerErr kafkaError
+	if err != nil && (!errors.As(err, &brokerErr) || brokerErr.retriable()) {
+		if refreshErr := k.refreshMetadata(); refreshErr != nil {
+			return fmt.Errorf("failed to publish chunks: %w (metadata refresh failed: %v)", err, refreshErr)
+		}
+		err = k.produce(chunks, values)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish chunks: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connections to the brokers
+func (k *Kafka) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.closeConns()
+}
+
+// GetName returns the sink name
+func (k *Kafka) GetName() string {
+	return "Kafka"
+}
+
+// produce sends the chunks to the leaders of their partitions, one request per leader
+func (k *Kafka) produce(chunks []chunker.ChunkData, values [][]byte) error {
+	if len(k.leaders) == 0 {
+		return kafkaError(5)
+	}
+
+	// Records of one partition, by partition, and partitions by leader
+	records := make(map[int32][]kafkaRecord)
+	for i, chunk := range chunks {
+		key := []byte(chunk.Slug)
+		partition := KafkaPartition(key, len(k.leaders))
+		records[partition] = append(records[partition], kafkaRecord{key: key, value: values[i]})
+	}
+	byLeader := make(map[int32][]int32)
+	for partition := range records {
+		leader := k.leaders[partition]
+		byLeader[leader] = append(byLeader[leader], partition)
+	}
+
+	for leader, partitions := range byLeader {
+		if leader < 0 {
+			return kafkaError(5)
+		}
+		var body kafkaEncoder
+		body.int16(-1) // Null transactional ID
+		body.int16(-1) // acks=all
+		body.int32(int32(k.options.Timeout.Milliseconds()))
+		body.int32(1) // Topics
+		body.string(k.options.Topic)
+		body.int32(int32(len(partitions)))
+		for _, partition := range partitions {
+			body.int32(partition)
+			body.bytes(recordBatch(records[partition], time.Now()))
+		}
+
+		response, err := k.request(leader, kafkaProduce, kafkaProduceVersion, body.buf)
+		if err != nil {
+			return err
+		}
+		if err := produceError(response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// produceError returns the first error code of a produce response
+func produceError(response []byte) error {
+	decoder := kafkaDecoder{buf: response}
+	for topics := decoder.int32(); topics > 0 && decoder.err == nil; topics-- {
+		decoder.string()
+		for partitions := decoder.int32(); partitions > 0 && decoder.err == nil; partitions-- {
+			decoder.int32() // Partition
+			code := decoder.int16()
+			decoder.int64() // Base offset
+			decoder.int64() // Log append time
+			if code != 0 && decoder.err == nil {
+				return kafkaError(code)
+			}
+		}
+	}
+	return decoder.err
+}
+
+// refreshMetadata reads the brokers and the partition leaders of the topic from any
+// broker that answers, bootstrap brokers first
+func (k *Kafka) refreshMetadata() error {
+	var body kafkaEncoder
+	body.int32(1)
+	body.string(k.options.Topic)
+	body.int8(1) // Allow auto topic creation, if the brokers do
+
+	var errs []error
+	for i, addr := range k.options.Brokers {
+		// Bootstrap brokers get negative IDs, as their node IDs are not known yet
+		nodeID := int32(-1 - i)
+		if k.brokers == nil {
+			k.brokers = make(map[int32]string)
+		}
+		k.brokers[nodeID] = addr
+		response, err := k.request(nodeID, kafkaMetadata, kafkaMetadataVersion, body.buf)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+			continue
+		}
+		return k.readMetadata(response)
+	}
+	return errors.Join(errs...)
+}
+
+// readMetadata stores the brokers and partition leaders of a metadata response
+func (k *Kafka) readMetadata(response []byte) error {
+	decoder := kafkaDecoder{buf: response}
+	decoder.int32() // Throttle time
+	brokers := make(map[int32]string)
+	for count := decoder.int32(); count > 0 && decoder.err == nil; count-- {
+		nodeID := decoder.int32()
+		host := decoder.string()
+		port := decoder.int32()
+		decoder.string() // Rack
+		brokers[nodeID] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	decoder.string() // Cluster ID
+	decoder.int32()  // Controller ID
+
+	var leaders []int32
+	var topicErr error
+	for topics := decoder.int32(); topics > 0 && decoder.err == nil; topics-- {
+		code := decoder.int16()
+		name := decoder.string()
+		decoder.int8() // Internal
+		partitions := decoder.int32()
+		topicLeaders := make([]int32, max(partitions, 0))
+		for ; partitions > 0 && decoder.err == nil; partitions-- {
+			decoder.int16() // Partition error, such as a missing leader
+			index := decoder.int32()
+			leader := decoder.int32()
+			decoder.int32Array() // Replicas
+			decoder.int32Array() // In-sync replicas
+			if index >= 0 && int(index) < len(topicLeaders) {
+				topicLeaders[index] = leader
+			}
+		}
+		if name == k.options.Topic {
+			leaders = topicLeaders
+			if code != 0 {
+				topicErr = kafkaError(code)
+			}
+		}
+	}
+	if decoder.err != nil {
+		return decoder.err
+	}
+	if topicErr != nil {
+		return fmt.Errorf("topic %s: %w", k.options.Topic, topicErr)
+	}
+	if len(leaders) == 0 {
+		return fmt.Errorf("topic %s has no partitions", k.options.Topic)
+	}
+
+	// Connections to brokers that left or moved are dropped
+	for nodeID, conn := range k.conns {
+		if nodeID < 0 || brokers[nodeID] != k.brokers[nodeID] {
+			conn.conn.Close()
+			delete(k.conns, nodeID)
+		}
+	}
+	k.brokers, k.leaders = brokers, leaders
+	return nil
+}
+
+// request sends a request to a broker and returns the response body after the
+// correlation ID, dropping the connection after any I/O error
+func (k *Kafka) request(nodeID int32, apiKey, apiVersion int16, body []byte) ([]byte, error) {
+	conn, err := k.conn(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	response, err := k.roundTrip(conn, apiKey, apiVersion, body)
+	if err != nil {
+		conn.conn.Close()
+		delete(k.conns, nodeID)
+		return nil, err
+	}
+	return response, nil
+}
+
+// conn returns the connection to a broker, dialling it when needed
+func (k *Kafka) conn(nodeID int32) (*kafkaConn, error) {
+	if conn, ok := k.conns[nodeID]; ok {
+		return conn, nil
+	}
+	addr, ok := k.brokers[nodeID]
+	if !ok {
+		return nil, fmt.Errorf("unknown kafka broker %d", nodeID)
+	}
+
+	dialer := &net.Dialer{Timeout: k.options.Timeout}
+	var conn net.Conn
+	var err error
+	if k.options.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, k.options.TLS)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	k.conns[nodeID] = &kafkaConn{conn: conn, reader: bufio.NewReader(conn)}
+	return k.conns[nodeID], nil
+}
+
+// roundTrip writes a size-prefixed request with a v1 header and reads its response
+func (k *Kafka) roundTrip(conn *kafkaConn, apiKey, apiVersion int16, body []byte) ([]byte, error) {
+	// Produce waits up to Timeout for the replicas, on top of the network time
+	if err := conn.conn.SetDeadline(time.Now().Add(2 * k.options.Timeout)); err != nil {
+		return nil, err
+	}
+
+	k.correlationID++
+	var request kafkaEncoder
+	request.int32(0) // Size, set below
+	request.int16(apiKey)
+	request.int16(apiVersion)
+	request.int32(k.correlationID)
+	request.string(k.options.ClientID)
+	request.buf = append(request.buf, body...)
+	binary.BigEndian.PutUint32(request.buf, uint32(len(request.buf)-4))
+	if _, err := conn.conn.Write(request.buf); err != nil {
+		return nil, err
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(conn.reader, header[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 {
+		return nil, fmt.Errorf("malformed kafka response size %d", size)
+	}
+	if correlationID := int32(binary.BigEndian.Uint32(header[4:])); correlationID != k.correlationID {
+		return nil, fmt.Errorf("kafka response %d does not match request %d", correlationID, k.correlationID)
+	}
+	response := make([]byte, size-4)
+	if _, err := io.ReadFull(conn.reader, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// closeConns closes every broker connection
+func (k *Kafka) closeConns() error {
+	var errs []error
+	for nodeID, conn := range k.conns {
+		errs = append(errs, conn.conn.Close())
+		delete(k.conns, nodeID)
+	}
+	return errors.Join(errs...)
+}
+
+// KafkaPartition returns the partition of key among partitions as Kafka's Java producer
+// picks it: the positive murmur2 hash of the key modulo the partition count
+func KafkaPartition(key []byte, partitions int) int32 {
+	return int32(uint32(murmur2(key))&0x7fffffff) % int32(partitions)
+}
+
+// murmur2 is the hash of Kafka's default partitioner
+func murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// kafkaRecord is a message of a record batch
+type kafkaRecord struct {
+	key, value []byte
+}
+
+// castagnoli is the CRC-32C table of record batch checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// recordBatch encodes records as an uncompressed v2 record batch
+func recordBatch(records []kafkaRecord, now time.Time) []byte {
+	var batch kafkaEncoder
+	batch.int64(0) // Base offset, assigned by the broker
+	batch.int32(0) // Batch length, set below
+	batch.int32(-1)
+	batch.int8(2)  // Magic
+	batch.int32(0) // CRC, set below
+	crcStart := len(batch.buf)
+	batch.int16(0) // Attributes: no compression, create time
+	batch.int32(int32(len(records) - 1))
+	batch.int64(now.UnixMilli())
+	batch.int64(now.UnixMilli())
+	batch.int64(-1) // Producer ID
+	batch.int16(-1) // Producer epoch
+	batch.int32(-1) // Base sequence
+	batch.int32(int32(len(records)))
+	for i, record := range records {
+		var body kafkaEncoder
+		body.int8(0)   // Attributes
+		body.varint(0) // Timestamp delta
+		body.varint(int64(i))
+		body.varint(int64(len(record.key)))
+		body.buf = append(body.buf, record.key...)
+		body.varint(int64(len(record.value)))
+		body.buf = append(body.buf, record.value...)
+		body.varint(0) // Headers
+		batch.varint(int64(len(body.buf)))
+		batch.buf = append(batch.buf, body.buf...)
+	}
+
+	binary.BigEndian.PutUint32(batch.buf[8:], uint32(len(batch.buf)-12))
+	binary.BigEndian.PutUint32(batch.buf[crcStart-4:], crc32.Checksum(batch.buf[crcStart:], castagnoli))
+	return batch.buf
+}
+
+// kafkaEncoder appends big-endian protocol fields
+type kafkaEncoder struct {
+	buf []byte
+}
+
+func (e *kafkaEncoder) int8(v int8)   { e.buf = append(e.buf, byte(v)) }
+func (e *kafkaEncoder) int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *kafkaEncoder) int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *kafkaEncoder) int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+
+// varint appends a zigzag varint, as records use
+func (e *kafkaEncoder) varint(v int64) { e.buf = binary.AppendVarint(e.buf, v) }
+
+// string appends an int16 length and the string
+func (e *kafkaEncoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// bytes appends an int32 length and the bytes
+func (e *kafkaEncoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// kafkaDecoder reads big-endian protocol fields, recording the first short read in err
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+// next returns the next n bytes, or nil once the response is exhausted
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.err != nil || n < 0 || n > len(d.buf) {
+		if d.err == nil {
+			d.err = fmt.Errorf("malformed kafka response")
+		}
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// string reads a nullable string; null reads as ""
+func (d *kafkaDecoder) string() string {
+	length := d.int16()
+	if length < 0 {
+		return ""
+	}
+	return string(d.next(int(length)))
+}
+
+// int32Array skips an array of int32
+func (d *kafkaDecoder) int32Array() {
+	if count := d.int32(); count > 0 {
+		d.next(4 * int(count))
+	}
+}
@@ -0,0 +1,345 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+)
+
+// javaMurmur2 are the hashes Kafka's Java client computes, from its UtilsTest.testMurmur2
+var javaMurmur2 = map[string]int32{
+	"21":                         -973932308,
+	"foobar":                     -790332482,
+	"a-little-bit-long-string":   -985981536,
+	"a-little-bit-longer-string": -1486304829,
+	"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+	"abc": 479470107,
+}
+
+func TestMurmur2(t *testing.T) {
+	for key, want := range javaMurmur2 {
+		if got := murmur2([]byte(key)); got != want {
+			t.Errorf("murmur2(%q) = %d, want %d", key, got, want)
+		}
+	}
+}
+
+// TestKafkaPartition checks the partitions against the Java producer's
+// Utils.toPositive(Utils.murmur2(key)) % numPartitions
+func TestKafkaPartition(t *testing.T) {
+	for key, hash := range javaMurmur2 {
+		for _, partitions := range []int{1, 3, 12, 100} {
+			want := int32(hash&0x7fffffff) % int32(partitions)
+			if got := KafkaPartition([]byte(key), partitions); got != want {
+				t.Errorf("KafkaPartition(%q, %d) = %d, want %d", key, partitions, got, want)
+			}
+		}
+	}
+}
+
+// decodedRecord is a record read back from a record batch
+type decodedRecord struct {
+	offsetDelta int64
+	key, value  []byte
+}
+
+// decodeRecordBatch reads an uncompressed v2 record batch as the broker does, checking
+// its length, magic byte and CRC-32C
+func decodeRecordBatch(t *testing.T, batch []byte) []decodedRecord {
+	t.Helper()
+	if len(batch) < 61 {
+		t.Fatalf("record batch of %d bytes is shorter than its header", len(batch))
+	}
+	if length := binary.BigEndian.Uint32(batch[8:]); int(length) != len(batch)-12 {
+		t.Fatalf("batch length = %d, want %d", length, len(batch)-12)
+	}
+	if magic := batch[16]; magic != 2 {
+		t.Fatalf("magic = %d, want 2", magic)
+	}
+	if crc := binary.BigEndian.Uint32(batch[17:]); crc != crc32.Checksum(batch[21:], crc32.MakeTable(crc32.Castagnoli)) {
+		t.Fatalf("CRC %08x does not match the batch", crc)
+	}
+	if attributes := binary.BigEndian.Uint16(batch[21:]); attributes != 0 {
+		t.Errorf("attributes = %d, want 0 (uncompressed, create time)", attributes)
+	}
+	lastOffsetDelta := int32(binary.BigEndian.Uint32(batch[23:]))
+	count := int(binary.BigEndian.Uint32(batch[57:]))
+	if int(lastOffsetDelta) != count-1 {
+		t.Errorf("last offset delta = %d, want %d", lastOffsetDelta, count-1)
+	}
+
+	reader := bytes.NewReader(batch[61:])
+	varint := func() int64 {
+		v, err := binary.ReadVarint(reader)
+		if err != nil {
+			t.Fatalf("malformed record: %v", err)
+		}
+		return v
+	}
+	varbytes := func() []byte {
+		b := make([]byte, varint())
+		if _, err := io.ReadFull(reader, b); err != nil {
+			t.Fatalf("malformed record: %v", err)
+		}
+		return b
+	}
+	records := make([]decodedRecord, count)
+	for i := range records {
+		length := varint()
+		start := reader.Len()
+		if attributes, _ := reader.ReadByte(); attributes != 0 {
+			t.Errorf("record attributes = %d, want 0", attributes)
+		}
+		varint() // Timestamp delta
+		records[i].offsetDelta = varint()
+		records[i].key = varbytes()
+		records[i].value = varbytes()
+		if headers := varint(); headers != 0 {
+			t.Errorf("record has %d headers, want 0", headers)
+		}
+		if read := int64(start - reader.Len()); read != length {
+			t.Fatalf("record length = %d, read %d bytes", length, read)
+		}
+	}
+	if reader.Len() != 0 {
+		t.Fatalf("%d bytes after the last record", reader.Len())
+	}
+	return records
+}
+
+func TestRecordBatch(t *testing.T) {
+	records := []kafkaRecord{
+		{key: []byte("handbook"), value: []byte(`{"chunk_index":1}`)},
+		{key: []byte("handbook"), value: bytes.Repeat([]byte("x"), 300)}, // Lengths past one varint byte
+	}
+	decoded := decodeRecordBatch(t, recordBatch(records, time.UnixMilli(1700000000000)))
+	if len(decoded) != len(records) {
+		t.Fatalf("decoded %d records, want %d", len(decoded), len(records))
+	}
+	for i, record := range decoded {
+		if record.offsetDelta != int64(i) || !bytes.Equal(record.key, records[i].key) || !bytes.Equal(record.value, records[i].value) {
+			t.Errorf("record %d = %d %q %q, want %d %q %q", i, record.offsetDelta, record.key, record.value, i, records[i].key, records[i].value)
+		}
+	}
+}
+
+// kafkaFrame builds protocol fields for the fake broker, independently of kafkaEncoder
+type kafkaFrame struct{ bytes.Buffer }
+
+func (f *kafkaFrame) int8(v int8)   { f.WriteByte(byte(v)) }
+func (f *kafkaFrame) int16(v int16) { binary.Write(f, binary.BigEndian, v) }
+func (f *kafkaFrame) int32(v int32) { binary.Write(f, binary.BigEndian, v) }
+func (f *kafkaFrame) int64(v int64) { binary.Write(f, binary.BigEndian, v) }
+func (f *kafkaFrame) string(s string) {
+	f.int16(int16(len(s)))
+	f.WriteString(s)
+}
+
+// fakeBroker is a single Kafka broker, node 1, leading every partition of a topic. It
+// answers Metadata v4 and Produce v3 requests and records the produced batches.
+type fakeBroker struct {
+	t          *testing.T
+	listener   net.Listener
+	topic      string
+	partitions int32
+	produceErr []int16 // Error codes of the next produce responses, then 0
+
+	mu      sync.Mutex
+	batches map[int32][][]byte // Record batches by partition
+}
+
+func newFakeBroker(t *testing.T, topic string, partitions int32) *fakeBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{t: t, listener: listener, topic: topic, partitions: partitions, batches: make(map[int32][][]byte)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		var size int32
+		if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+			return
+		}
+		request := make([]byte, size)
+		if _, err := io.ReadFull(reader, request); err != nil {
+			return
+		}
+		decoder := kafkaDecoder{buf: request}
+		apiKey, apiVersion, correlationID := decoder.int16(), decoder.int16(), decoder.int32()
+		if clientID := decoder.string(); clientID != "pdf-chunk-extractor" {
+			b.t.Errorf("client ID = %q, want pdf-chunk-extractor", clientID)
+		}
+
+		var response kafkaFrame
+		response.int32(correlationID)
+		switch {
+		case apiKey == kafkaMetadata && apiVersion == 4:
+			b.metadata(&response)
+		case apiKey == kafkaProduce && apiVersion == 3:
+			b.produce(&decoder, &response)
+		default:
+			b.t.Errorf("unexpected request: API key %d version %d", apiKey, apiVersion)
+			return
+		}
+		binary.Write(conn, binary.BigEndian, int32(response.Len()))
+		conn.Write(response.Bytes())
+	}
+}
+
+// metadata answers a Metadata v4 request
+func (b *fakeBroker) metadata(response *kafkaFrame) {
+	host, port, _ := net.SplitHostPort(b.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	response.int32(0) // Throttle time
+	response.int32(1) // Brokers
+	response.int32(1)
+	response.string(host)
+	response.int32(int32(portNumber))
+	response.int16(-1) // Null rack
+	response.string("cluster")
+	response.int32(1) // Controller
+	response.int32(1) // Topics
+	response.int16(0)
+	response.string(b.topic)
+	response.int8(0) // Not internal
+	response.int32(b.partitions)
+	for partition := int32(0); partition < b.partitions; partition++ {
+		response.int16(0)
+		response.int32(partition)
+		response.int32(1) // Leader
+		response.int32(1) // Replicas
+		response.int32(1)
+		response.int32(1) // In-sync replicas
+		response.int32(1)
+	}
+}
+
+// produce records the batches of a Produce v3 request and answers it
+func (b *fakeBroker) produce(request *kafkaDecoder, response *kafkaFrame) {
+	if transactionalID := request.int16(); transactionalID != -1 {
+		b.t.Errorf("transactional ID length = %d, want null", transactionalID)
+	}
+	if acks := request.int16(); acks != -1 {
+		b.t.Errorf("acks = %d, want -1 (all)", acks)
+	}
+	request.int32() // Timeout
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	code := int16(0)
+	if len(b.produceErr) > 0 {
+		code, b.produceErr = b.produceErr[0], b.produceErr[1:]
+	}
+	topics := request.int32()
+	response.int32(topics)
+	for ; topics > 0; topics-- {
+		response.string(request.string())
+		partitions := request.int32()
+		response.int32(partitions)
+		for ; partitions > 0; partitions-- {
+			partition := request.int32()
+			batch := request.next(int(request.int32()))
+			if code == 0 {
+				b.batches[partition] = append(b.batches[partition], batch)
+			}
+			response.int32(partition)
+			response.int16(code)
+			response.int64(0)  // Base offset
+			response.int64(-1) // Log append time
+		}
+	}
+	if request.err != nil || len(request.buf) != 0 {
+		b.t.Errorf("malformed produce request: %v, %d trailing bytes", request.err, len(request.buf))
+	}
+	response.int32(0) // Throttle time
+}
+
+func TestKafkaWrite(t *testing.T) {
+	broker := newFakeBroker(t, "chunks.handbook", 3)
+	broker.produceErr = []int16{6} // NOT_LEADER_OR_FOLLOWER once, as after a leader election
+	k, err := NewKafka(KafkaOptions{Brokers: []string{broker.listener.Addr().String()}, Topic: "chunks.handbook", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Close()
+
+	documents := map[string][]chunker.ChunkData{}
+	for _, slug := range []string{"handbook", "policy", "faq"} {
+		for index := 1; index <= 2; index++ {
+			documents[slug] = append(documents[slug], chunker.ChunkData{Slug: slug, ChunkIndex: index, Text: slug + " text " + strconv.Itoa(index)})
+		}
+		if err := k.Write(documents[slug]); err != nil {
+			t.Fatalf("Write(%s): %v", slug, err)
+		}
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	found := 0
+	for partition, batches := range broker.batches {
+		for _, batch := range batches {
+			for i, record := range decodeRecordBatch(t, batch) {
+				slug := string(record.key)
+				if want := KafkaPartition(record.key, 3); partition != want {
+					t.Errorf("%s was produced to partition %d, want %d", slug, partition, want)
+				}
+				var chunk chunker.ChunkData
+				if err := json.Unmarshal(record.value, &chunk); err != nil {
+					t.Fatalf("record value is not a chunk: %v", err)
+				}
+				if chunk.Slug != slug || chunk.ChunkIndex != documents[slug][i].ChunkIndex || chunk.Text != documents[slug][i].Text {
+					t.Errorf("record %d of %s = %+v, want %+v", i, slug, chunk, documents[slug][i])
+				}
+				found++
+			}
+		}
+	}
+	if found != 6 {
+		t.Errorf("broker stored %d records, want 6", found)
+	}
+}
+
+func TestKafkaWriteError(t *testing.T) {
+	broker := newFakeBroker(t, "chunks", 1)
+	broker.produceErr = []int16{10} // MESSAGE_TOO_LARGE is not retried
+	k, err := NewKafka(KafkaOptions{Brokers: []string{broker.listener.Addr().String()}, Topic: "chunks", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Close()
+
+	err = k.Write([]chunker.ChunkData{{Slug: "big", ChunkIndex: 1}})
+	var brokerErr kafkaError
+	if !errors.As(err, &brokerErr) || brokerErr != 10 {
+		t.Fatalf("Write error = %v, want kafka error 10", err)
+	}
+	if err.Error() != "failed to publish chunks: kafka: error 10 (MESSAGE_TOO_LARGE)" {
+		t.Errorf("Write error = %q", err)
+	}
+}
@@ -0,0 +1,361 @@
+package sink
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+)
+
+// NATSOptions configures a NATS JetStream sink
+type NATSOptions struct {
+	Addr     string        // host:port, default "localhost:4222"
+	Subject  string        // Subject of the corpus, e.g. "chunks.handbook"; required. Chunks go to <Subject>.<document>, which a stream must capture, e.g. with subjects "chunks.handbook.>"
+	User     string        // Sent with Password when set
+	Password string        // Sent with User when set
+	Token    string        // Sent as the auth token when set
+	TLS      *tls.Config   // Upgrade the connection with it when set or required by the server
+	Timeout  time.Duration // Dial and acknowledgement timeout, default 5s
+}
+
+// NATS publishes every chunk as a JSON message to NATS JetStream, for consumers that
+// embed and index chunks asynchronously. Messages of a document share a subject, so
+// consumers can filter and order by document, and carry a Nats-Msg-Id of the document,
+// chunk index and content, so the stream drops a chunk published twice within its
+// duplicate window. Write returns once JetStream has stored every chunk of the document.
+// It speaks the NATS protocol over a single connection that is redialled after errors.
+type NATS struct {
+	options NATSOptions
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	inbox  string // Prefix of the reply subjects of the acknowledgements
+}
+
+// natsInfo is the part of the INFO the server sends on connect that the sink reads
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	Headers     bool `json:"headers"`
+}
+
+// natsConnect is the CONNECT the sink sends
+type natsConnect struct {
+	Verbose      bool   `json:"verbose"`
+	Pedantic     bool   `json:"pedantic"`
+	Headers      bool   `json:"headers"`
+	NoResponders bool   `json:"no_responders"` // Answer publishes no stream captures with a 503 status
+	Name         string `json:"name"`
+	Lang         string `json:"lang"`
+	Version      string `json:"version"`
+	User         string `json:"user,omitempty"`
+	Password     string `json:"pass,omitempty"`
+	Token        string `json:"auth_token,omitempty"`
+}
+
+// natsAck is the acknowledgement of a JetStream publish
+type natsAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// NewNATS creates a NATS JetStream sink and checks that the server is reachable
+func NewNATS(options NATSOptions) (*NATS, error) {
+	if options.Subject == "" {
+		return nil, fmt.Errorf("NATS subject is required")
+	}
+	if options.Addr == "" {
+		options.Addr = "localhost:4222"
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Second
+	}
+
+	s := &NATS{options: options}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.dial(); err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return s, nil
+}
+
+// Write publishes the chunks of a document and waits until JetStream acknowledges them
+func (s *NATS) Write(chunks []chunker.ChunkData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.dial(); err != nil {
+			return fmt.Errorf("failed to connect to NATS: %w", err)
+		}
+	}
+	if err := s.publish(chunks); err != nil {
+		var ackErr natsAckError
+		if !errors.As(err, &ackErr) {
+			// The connection state is unknown after an I/O error
+			s.conn.Close()
+			s.conn = nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection
+func (s *NATS) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// GetName returns the sink name
+func (s *NATS) GetName() string {
+	return "NATS"
+}
+
+// natsAckError is a publish JetStream rejected; the connection stays usable
+type natsAckError string
+
+func (e natsAckError) Error() string {
+	return "nats: " + string(e)
+}
+
+// publish sends every chunk and then reads their acknowledgements, so a document costs
+// one round trip
+func (s *NATS) publish(chunks []chunker.ChunkData) error {
+	if err := s.conn.SetDeadline(time.Now().Add(s.options.Timeout)); err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	for i, chunk := range chunks {
+		payload, err := json.Marshal(chunk)
+		if err != nil {
+			return fmt.Errorf("failed to marshal chunk %d: %w", chunk.ChunkIndex, err)
+		}
+		subject := s.options.Subject + "." + subjectToken(chunk.Slug)
+		headers := "NATS/1.0\r\nNats-Msg-Id: " + messageID(chunk, payload) + "\r\n\r\n"
+		fmt.Fprintf(&out, "HPUB %s %s.%d %d %d\r\n%s%s\r\n", subject, s.inbox, i, len(headers), len(headers)+len(payload), headers, payload)
+	}
+	if _, err := io.WriteString(s.conn, out.String()); err != nil {
+		return err
+	}
+
+	acked := make([]bool, len(chunks))
+	var failures []string
+	for remaining := len(chunks); remaining > 0; {
+		reply, status, payload, err := s.readMessage()
+		if err != nil {
+			return err
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(reply, s.inbox+"."))
+		if err != nil || index < 0 || index >= len(chunks) || acked[index] {
+			continue // A late reply to an earlier connection or Write
+		}
+		acked[index] = true
+		remaining--
+
+		if status == "503" {
+			failures = append(failures, fmt.Sprintf("chunk %d: no stream captures %s.%s", chunks[index].ChunkIndex, s.options.Subject, subjectToken(chunks[index].Slug)))
+			continue
+		}
+		if status != "" {
+			failures = append(failures, fmt.Sprintf("chunk %d: status %s", chunks[index].ChunkIndex, status))
+			continue
+		}
+		var ack natsAck
+		if err := json.Unmarshal(payload, &ack); err != nil {
+			failures = append(failures, fmt.Sprintf("chunk %d: malformed acknowledgement %q", chunks[index].ChunkIndex, payload))
+		} else if ack.Error != nil {
+			failures = append(failures, fmt.Sprintf("chunk %d: %s (%d)", chunks[index].ChunkIndex, ack.Error.Description, ack.Error.Code))
+		}
+	}
+	if len(failures) > 0 {
+		return natsAckError("failed to publish " + strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// dial opens the connection, upgrades it to TLS when needed, authenticates and
+// subscribes to the acknowledgements
+func (s *NATS) dial() error {
+	conn, err := net.DialTimeout("tcp", s.options.Addr, s.options.Timeout)
+	if err != nil {
+		return err
+	}
+	if err := s.handshake(conn); err != nil {
+		conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// handshake reads the server INFO and sends CONNECT and SUB on conn
+func (s *NATS) handshake(conn net.Conn) error {
+	if err := conn.SetDeadline(time.Now().Add(s.options.Timeout)); err != nil {
+		return err
+	}
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+
+	line, err := s.readLine()
+	if err != nil {
+		return err
+	}
+	infoJSON, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		return fmt.Errorf("unexpected NATS greeting %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		return fmt.Errorf("malformed NATS INFO: %w", err)
+	}
+	if !info.Headers {
+		return fmt.Errorf("NATS server does not support headers; JetStream needs 2.2 or later")
+	}
+	if info.TLSRequired || s.options.TLS != nil {
+		config := s.options.TLS
+		if config == nil {
+			host, _, _ := net.SplitHostPort(s.options.Addr)
+			config = &tls.Config{ServerName: host}
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		s.conn, s.reader = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	connect, err := json.Marshal(natsConnect{
+		Headers: true, NoResponders: true, Name: "pdf-chunk-extractor", Lang: "go", Version: "1",
+		User: s.options.User, Password: s.options.Password, Token: s.options.Token,
+	})
+	if err != nil {
+		return err
+	}
+	s.inbox = "_INBOX." + randomToken()
+	if _, err := fmt.Fprintf(s.conn, "CONNECT %s\r\nSUB %s.* 1\r\nPING\r\n", connect, s.inbox); err != nil {
+		return err
+	}
+	// The server answers PONG once it has accepted CONNECT and SUB, or -ERR
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS refused the connection: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// readMessage reads up to the next MSG or HMSG, answering server PINGs, and returns its
+// reply subject, the status of a status message such as "503", and its payload
+func (s *NATS) readMessage() (subject, status string, payload []byte, err error) {
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return "", "", nil, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "PING":
+			if _, err := io.WriteString(s.conn, "PONG\r\n"); err != nil {
+				return "", "", nil, err
+			}
+		case "-ERR":
+			return "", "", nil, fmt.Errorf("NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		case "MSG", "HMSG":
+			// MSG <subject> <sid> [reply] <size>; HMSG <subject> <sid> [reply] <header size> <size>
+			sizes := 1
+			if fields[0] == "HMSG" {
+				sizes = 2
+			}
+			if len(fields) < 3+sizes {
+				return "", "", nil, fmt.Errorf("malformed NATS message %q", line)
+			}
+			total, err := strconv.Atoi(fields[len(fields)-1])
+			headerSize := 0
+			if err == nil && sizes == 2 {
+				headerSize, err = strconv.Atoi(fields[len(fields)-2])
+			}
+			if err != nil || headerSize > total {
+				return "", "", nil, fmt.Errorf("malformed NATS message %q", line)
+			}
+			data := make([]byte, total+2)
+			if _, err := io.ReadFull(s.reader, data); err != nil {
+				return "", "", nil, err
+			}
+			return fields[1], headerStatus(data[:headerSize]), data[headerSize:total], nil
+		}
+	}
+}
+
+// readLine reads a protocol line without its CRLF
+func (s *NATS) readLine() (string, error) {
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// headerStatus returns the status code of a message header block, such as "503" in
+// "NATS/1.0 503", or "" for plain headers
+func headerStatus(headers []byte) string {
+	firstLine, _, _ := strings.Cut(string(headers), "\r\n")
+	fields := strings.Fields(firstLine)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// subjectToken turns a document slug into a single subject token, as '.' separates
+// tokens
+func subjectToken(slug string) string {
+	return strings.NewReplacer(".", "_", "/", "_", " ", "_", "*", "_", ">", "_").Replace(slug)
+}
+
+// messageID identifies a chunk by its document, index and content, so republishing an
+// unchanged chunk is dropped as a duplicate and an updated one is not
+func messageID(chunk chunker.ChunkData, payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return fmt.Sprintf("%s-%d-%s", subjectToken(chunk.Slug), chunk.ChunkIndex, hex.EncodeToString(sum[:8]))
+}
+
+// randomToken returns a random subject token
+func randomToken() string {
+	var b [12]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
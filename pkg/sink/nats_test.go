@@ -0,0 +1,243 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+)
+
+// TestNATSReadMessage reads server frames as the NATS protocol documentation shows them
+func TestNATSReadMessage(t *testing.T) {
+	tests := []struct {
+		name, frames                  string
+		wantSubject, wantStatus, want string
+	}{
+		{
+			name:        "MSG",
+			frames:      "MSG FOO.BAR 9 11\r\nHello World\r\n",
+			wantSubject: "FOO.BAR", want: "Hello World",
+		},
+		{
+			name:        "HMSG with headers",
+			frames:      "HMSG FOO.BAR 9 BAZ.69 34 45\r\nNATS/1.0\r\nFoodGroup: vegetable\r\n\r\nHello World\r\n",
+			wantSubject: "FOO.BAR", want: "Hello World",
+		},
+		{
+			name:        "JetStream acknowledgement after a PING",
+			frames:      "PING\r\nMSG _INBOX.abc.0 1 27\r\n{\"stream\":\"CHUNKS\",\"seq\":7}\r\n",
+			wantSubject: "_INBOX.abc.0", want: `{"stream":"CHUNKS","seq":7}`,
+		},
+		{
+			name:        "no responders",
+			frames:      "HMSG _INBOX.abc.1 1 16 16\r\nNATS/1.0 503\r\n\r\n\r\n",
+			wantSubject: "_INBOX.abc.1", wantStatus: "503",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			go func() {
+				io.WriteString(server, test.frames)
+				io.Copy(io.Discard, server) // The PONG answering a PING
+			}()
+			s := &NATS{conn: client, reader: bufio.NewReader(client)}
+			subject, status, payload, err := s.readMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subject != test.wantSubject || status != test.wantStatus || string(payload) != test.want {
+				t.Errorf("readMessage() = %q, %q, %q, want %q, %q, %q", subject, status, payload, test.wantSubject, test.wantStatus, test.want)
+			}
+		})
+	}
+}
+
+func TestNATSReadMessageMalformed(t *testing.T) {
+	for _, frames := range []string{
+		"MSG FOO\r\n",
+		"HMSG FOO.BAR 9 40 20\r\n",
+		"-ERR 'Authorization Violation'\r\n",
+	} {
+		client, server := net.Pipe()
+		go io.WriteString(server, frames)
+		s := &NATS{conn: client, reader: bufio.NewReader(client)}
+		if _, _, _, err := s.readMessage(); err == nil {
+			t.Errorf("readMessage(%q) succeeded, want an error", frames)
+		}
+		client.Close()
+	}
+}
+
+// fakeJetStream is a NATS server whose stream stores every publish to a subject under
+// chunks.>, except the chunks of rejectSlug, which it answers with a JetStream error
+type fakeJetStream struct {
+	t          *testing.T
+	listener   net.Listener
+	rejectSlug string
+	published  chan natsPublish
+}
+
+// natsPublish is an HPUB the fake server received
+type natsPublish struct {
+	subject, reply, msgID string
+	chunk                 chunker.ChunkData
+}
+
+func newFakeJetStream(t *testing.T) *fakeJetStream {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeJetStream{t: t, listener: listener, published: make(chan natsPublish, 100)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeJetStream) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	io.WriteString(conn, `INFO {"server_id":"fake","version":"2.10.0","headers":true,"jetstream":true,"max_payload":1048576}`+"\r\n")
+	for seq := 1; ; {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			var connect natsConnect
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "CONNECT ")), &connect); err != nil || !connect.Headers || !connect.NoResponders {
+				s.t.Errorf("CONNECT %q does not ask for headers and no responders: %v", line, err)
+			}
+		case "SUB", "PONG":
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "HPUB":
+			// HPUB <subject> <reply> <header size> <total size>
+			if len(fields) != 5 {
+				s.t.Errorf("malformed HPUB %q", line)
+				return
+			}
+			headerSize, _ := strconv.Atoi(fields[3])
+			total, _ := strconv.Atoi(fields[4])
+			data := make([]byte, total+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return
+			}
+			if string(data[total:]) != "\r\n" {
+				s.t.Errorf("HPUB payload of %s is not %d bytes", fields[1], total)
+			}
+			headers, payload := string(data[:headerSize]), data[headerSize:total]
+			if !strings.HasPrefix(headers, "NATS/1.0\r\n") || !strings.HasSuffix(headers, "\r\n\r\n") {
+				s.t.Errorf("malformed headers %q", headers)
+			}
+			publish := natsPublish{subject: fields[1], reply: fields[2]}
+			for _, header := range strings.Split(headers, "\r\n") {
+				if id, ok := strings.CutPrefix(header, "Nats-Msg-Id: "); ok {
+					publish.msgID = id
+				}
+			}
+			if err := json.Unmarshal(payload, &publish.chunk); err != nil {
+				s.t.Errorf("payload %q is not a chunk: %v", payload, err)
+			}
+
+			var ack string
+			switch {
+			case !strings.HasPrefix(publish.subject, "chunks."):
+				fmt.Fprintf(conn, "HMSG %s 1 16 16\r\nNATS/1.0 503\r\n\r\n\r\n", publish.reply)
+				continue
+			case publish.chunk.Slug == s.rejectSlug:
+				ack = `{"error":{"code":400,"err_code":10054,"description":"maximum messages exceeded"}}`
+			default:
+				ack = fmt.Sprintf(`{"stream":"CHUNKS","seq":%d}`, seq)
+				seq++
+				s.published <- publish
+			}
+			fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", publish.reply, len(ack), ack)
+		default:
+			s.t.Errorf("unexpected client line %q", line)
+		}
+	}
+}
+
+func TestNATSWrite(t *testing.T) {
+	server := newFakeJetStream(t)
+	server.rejectSlug = "full"
+	s, err := NewNATS(NATSOptions{Addr: server.listener.Addr().String(), Subject: "chunks", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	chunks := []chunker.ChunkData{
+		{Slug: "docs/hand.book", ChunkIndex: 1, Text: "First chunk"},
+		{Slug: "docs/hand.book", ChunkIndex: 2, Text: "Second chunk"},
+	}
+	if err := s.Write(chunks); err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{}
+	for i := range chunks {
+		publish := <-server.published
+		if publish.subject != "chunks.docs_hand_book" {
+			t.Errorf("subject = %q, want chunks.docs_hand_book", publish.subject)
+		}
+		if publish.chunk.ChunkIndex != chunks[i].ChunkIndex || publish.chunk.Text != chunks[i].Text {
+			t.Errorf("published %+v, want %+v", publish.chunk, chunks[i])
+		}
+		if !strings.HasPrefix(publish.msgID, "docs_hand_book-"+strconv.Itoa(chunks[i].ChunkIndex)+"-") {
+			t.Errorf("Nats-Msg-Id = %q", publish.msgID)
+		}
+		ids[publish.msgID] = true
+	}
+	if len(ids) != len(chunks) {
+		t.Errorf("message IDs %v are not unique", ids)
+	}
+
+	// A JetStream error fails the document but keeps the connection
+	err = s.Write([]chunker.ChunkData{{Slug: "full", ChunkIndex: 1}})
+	var ackErr natsAckError
+	if !errors.As(err, &ackErr) || !strings.Contains(err.Error(), "maximum messages exceeded (400)") {
+		t.Fatalf("Write error = %v, want the JetStream error", err)
+	}
+	if err := s.Write(chunks[:1]); err != nil {
+		t.Fatalf("Write after a JetStream error: %v", err)
+	}
+	<-server.published
+}
+
+func TestNATSWriteNoStream(t *testing.T) {
+	server := newFakeJetStream(t)
+	s, err := NewNATS(NATSOptions{Addr: server.listener.Addr().String(), Subject: "other", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = s.Write([]chunker.ChunkData{{Slug: "handbook", ChunkIndex: 3}})
+	if err == nil || !strings.Contains(err.Error(), "chunk 3: no stream captures other.handbook") {
+		t.Fatalf("Write error = %v, want no stream captures other.handbook", err)
+	}
+}
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// File stores records as JSON files, one directory per job:
+//
+//	<dir>/<id>/record.json  the record without its result
+//	<dir>/<id>/result.json  the result
+//
+// The result is written first, so a job is never listed before its result can be read.
+type File struct {
+	dir string
+}
+
+// fileRecord is the content of record.json
+type fileRecord struct {
+	ID        string          `json:"id"`
+	Tenant    string          `json:"tenant"`
+	Status    string          `json:"status"`
+	CreatedAt time.Time       `json:"created_at"`
+	Job       json.RawMessage `json:"job"`
+}
+
+// NewFile returns a store of the directory dir, creating it if needed
+func NewFile(dir string) (*File, error) {
+	if dir == "" {
+		return nil, errors.New("store directory is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &File{dir: dir}, nil
+}
+
+// Put implements Store
+func (f *File) Put(record Record) error {
+	dir, err := f.jobDir(record.ID)
+	if err != nil {
+		return err
+	}
+	resultPath := filepath.Join(dir, "result.json")
+	if len(record.Result) > 0 {
+		if err := utils.WriteFileAtomic(resultPath, record.Result, 0644); err != nil {
+			return fmt.Errorf("failed to write result of job %s: %w", record.ID, err)
+		}
+	} else if err := os.Remove(resultPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove result of job %s: %w", record.ID, err)
+	}
+	data, err := json.Marshal(fileRecord{
+		ID:        record.ID,
+		Tenant:    record.Tenant,
+		Status:    record.Status,
+		CreatedAt: record.CreatedAt,
+		Job:       json.RawMessage(record.Job),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", record.ID, err)
+	}
+	if err := utils.WriteFileAtomic(filepath.Join(dir, "record.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write job %s: %w", record.ID, err)
+	}
+	return nil
+}
+
+// Get implements Store
+func (f *File) Get(id string) (*Record, error) {
+	dir, err := f.jobDir(id)
+	if err != nil {
+		return nil, ErrNotFound // No job was stored under an invalid ID
+	}
+	record, err := readFileRecord(dir)
+	if err != nil {
+		return nil, err
+	}
+	result, err := os.ReadFile(filepath.Join(dir, "result.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read result of job %s: %w", id, err)
+	}
+	record.Result = result
+	return record, nil
+}
+
+// List implements Store, reading the record of every job
+func (f *File) List(filter Filter) ([]Record, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store directory: %w", err)
+	}
+	var records []Record
+	for _, entry := range entries {
+		if !entry.IsDir() || validID(entry.Name()) != nil {
+			continue
+		}
+		record, err := readFileRecord(filepath.Join(f.dir, entry.Name()))
+		if errors.Is(err, ErrNotFound) {
+			continue // Being written or deleted
+		}
+		if err != nil {
+			return nil, err
+		}
+		if filter.matches(*record) {
+			records = append(records, *record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.After(records[j].CreatedAt) })
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// Delete implements Store
+func (f *File) Delete(id string) error {
+	dir, err := f.jobDir(id)
+	if err != nil {
+		return ErrNotFound // No job was stored under an invalid ID
+	}
+	// The record goes first, so a failed delete never leaves a job without its result
+	if err := os.Remove(filepath.Join(dir, "record.json")); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete job %s: %w", id, err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to delete job %s: %w", id, err)
+	}
+	return nil
+}
+
+// Close implements Store
+func (f *File) Close() error {
+	return nil
+}
+
+// jobDir returns the directory of a job
+func (f *File) jobDir(id string) (string, error) {
+	if err := validID(id); err != nil {
+		return "", err
+	}
+	return filepath.Join(f.dir, id), nil
+}
+
+// readFileRecord reads the record.json of a job directory
+func readFileRecord(dir string) (*Record, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "record.json"))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job: %w", err)
+	}
+	var stored fileRecord
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filepath.Join(dir, "record.json"), err)
+	}
+	return &Record{
+		ID:        stored.ID,
+		Tenant:    stored.Tenant,
+		Status:    stored.Status,
+		CreatedAt: stored.CreatedAt,
+		Job:       stored.Job,
+	}, nil
+}
+
+// validID rejects job IDs that are not a single path element of letters, digits, '-'
+// and '_', so they never escape the store directory
+func validID(id string) error {
+	if id == "" || strings.TrimLeft(id, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_") != "" {
+		return fmt.Errorf("invalid job ID %q", id)
+	}
+	return nil
+}
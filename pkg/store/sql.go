@@ -0,0 +1,162 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect is the SQL flavour of a database
+type Dialect int
+
+const (
+	SQLite   Dialect = iota // ? placeholders
+	Postgres                // $1 placeholders
+)
+
+// schema creates the table of an SQL store; created_at is in Unix microseconds, so it
+// sorts and compares the same way in every database
+const schema = `CREATE TABLE IF NOT EXISTS job_results (
+	id         TEXT PRIMARY KEY,
+	tenant     TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	job        TEXT NOT NULL,
+	result     TEXT NOT NULL
+)`
+
+const schemaIndex = `CREATE INDEX IF NOT EXISTS job_results_tenant_created ON job_results (tenant, created_at)`
+
+// SQL stores records in the job_results table of a database
+type SQL struct {
+	db      *sql.DB
+	dialect Dialect
+	ownsDB  bool // Close closes db, which Open opened
+}
+
+// NewSQL returns a store of db, creating its table if needed. Close leaves db open.
+func NewSQL(db *sql.DB, dialect Dialect) (*SQL, error) {
+	for _, statement := range []string{schema, schemaIndex} {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, fmt.Errorf("failed to create job_results table: %w", err)
+		}
+	}
+	return &SQL{db: db, dialect: dialect}, nil
+}
+
+// Put implements Store
+func (s *SQL) Put(record Record) error {
+	_, err := s.db.Exec(s.bind(`INSERT INTO job_results (id, tenant, status, created_at, job, result)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET tenant = excluded.tenant, status = excluded.status,
+			created_at = excluded.created_at, job = excluded.job, result = excluded.result`),
+		record.ID, record.Tenant, record.Status, record.CreatedAt.UnixMicro(), string(record.Job), string(record.Result))
+	if err != nil {
+		return fmt.Errorf("failed to store job %s: %w", record.ID, err)
+	}
+	return nil
+}
+
+// Get implements Store
+func (s *SQL) Get(id string) (*Record, error) {
+	row := s.db.QueryRow(s.bind(`SELECT id, tenant, status, created_at, job, result FROM job_results WHERE id = ?`), id)
+	var record Record
+	var createdAt int64
+	var job, result string
+	if err := row.Scan(&record.ID, &record.Tenant, &record.Status, &createdAt, &job, &result); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read job %s: %w", id, err)
+	}
+	record.CreatedAt = time.UnixMicro(createdAt).UTC()
+	record.Job = []byte(job)
+	if result != "" {
+		record.Result = []byte(result)
+	}
+	return &record, nil
+}
+
+// List implements Store
+func (s *SQL) List(filter Filter) ([]Record, error) {
+	query := `SELECT id, tenant, status, created_at, job FROM job_results WHERE created_at >= ?`
+	args := []any{int64(0)}
+	if !filter.CreatedAfter.IsZero() {
+		args[0] = filter.CreatedAfter.UnixMicro()
+	}
+	if filter.Tenant != "" {
+		query += ` AND tenant = ?`
+		args = append(args, filter.Tenant)
+	}
+	if filter.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	query += ` ORDER BY created_at DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + strconv.Itoa(filter.Limit)
+	}
+
+	rows, err := s.db.Query(s.bind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+	var records []Record
+	for rows.Next() {
+		var record Record
+		var createdAt int64
+		var job string
+		if err := rows.Scan(&record.ID, &record.Tenant, &record.Status, &createdAt, &job); err != nil {
+			return nil, fmt.Errorf("failed to list jobs: %w", err)
+		}
+		record.CreatedAt = time.UnixMicro(createdAt).UTC()
+		record.Job = []byte(job)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	return records, nil
+}
+
+// Delete implements Store
+func (s *SQL) Delete(id string) error {
+	result, err := s.db.Exec(s.bind(`DELETE FROM job_results WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete job %s: %w", id, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close implements Store
+func (s *SQL) Close() error {
+	if !s.ownsDB {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// bind rewrites the ? placeholders of query for the dialect
+func (s *SQL) bind(query string) string {
+	if s.dialect != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,216 @@
+// Package summary picks the key sentences of a text with TextRank, a pure-Go extractive
+// summarizer, so chunks get summaries without an AI provider
+package summary
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TextRank parameters, as in Mihalcea and Tarau (2004)
+const (
+	damping       = 0.85
+	maxIterations = 50
+	tolerance     = 1e-4
+)
+
+// minSentenceWords skips fragments such as page numbers and lone headings
+const minSentenceWords = 4
+
+// sentenceEnd matches the end of a sentence: terminal punctuation followed by a space
+// and an upper-case letter or digit, so abbreviations such as "e.g. the" and "No. 5"
+// in the middle of a sentence are mostly kept together
+var sentenceEnd = regexp.MustCompile(`[.!?]["')\]]?\s+[\p{Lu}\d]`)
+
+// hyphenated matches a line ending in a word broken with a hyphen
+var hyphenated = regexp.MustCompile(`\p{Ll}-$`)
+
+// metadataLine matches page separators, and the Markdown headings and "- **Label**:
+// value" lines the local formatter adds around chunk text
+var metadataLine = regexp.MustCompile(`^(#+\s|- \*\*[^*]+\*\*:|--- Page \d+ ---$)`)
+
+// summaryHeading starts the section of summary bullets the local formatter adds, which
+// is not summarized again
+const summaryHeading = "## Summary"
+
+// stopWords are common English and Indonesian words left out of sentence similarity
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`a an and are as at be by for from has have in is it its of on or that the this to was were will with
+		ada adalah akan atau bagi dalam dan dari dengan di ini itu juga ke kepada oleh pada sebagai telah untuk yang`) {
+		stopWords[word] = true
+	}
+}
+
+// IsStopWord reports whether a lower-case word is one of the common English and
+// Indonesian words left out of sentence similarity
+func IsStopWord(word string) bool {
+	return stopWords[word]
+}
+
+// Summarize returns the n most central sentences of text in their original order, or
+// every sentence when there are no more than n
+func Summarize(text string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	sentences := Sentences(text)
+	if len(sentences) <= n {
+		return sentences
+	}
+
+	scores := rank(sentences)
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	top := order[:n]
+	sort.Ints(top)
+	summary := make([]string, n)
+	for i, index := range top {
+		summary[i] = sentences[index]
+	}
+	return summary
+}
+
+// Sentences splits text into sentences of at least four words, skipping page
+// separators, Markdown headings, and the metadata lines and "## Summary" section of
+// locally formatted chunks. Line breaks inside a paragraph are joined, rejoining
+// hyphenated words; blank lines and list items end a sentence.
+func Sentences(text string) []string {
+	var sentences []string
+	var paragraph strings.Builder
+	flush := func() {
+		rest := strings.TrimSpace(paragraph.String())
+		paragraph.Reset()
+		for rest != "" {
+			end := len(rest)
+			if match := sentenceEnd.FindStringIndex(rest); match != nil {
+				end = match[1] - 1 // Keep the letter that starts the next sentence
+			}
+			sentence := strings.TrimSpace(rest[:end])
+			rest = strings.TrimSpace(rest[end:])
+			if countWords(sentence) >= minSentenceWords {
+				sentences = append(sentences, sentence)
+			}
+		}
+	}
+
+	inSummary := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			inSummary = trimmed == summaryHeading
+		}
+		switch {
+		case inSummary:
+		case trimmed == "" || metadataLine.MatchString(trimmed):
+			flush()
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "• "):
+			flush()
+			paragraph.WriteString(strings.TrimSpace(trimmed[strings.Index(trimmed, " "):]))
+			flush()
+		case hyphenated.MatchString(trimmed):
+			// Rejoin a word broken across lines
+			paragraph.WriteString(strings.TrimSuffix(trimmed, "-"))
+		default:
+			paragraph.WriteString(trimmed + " ")
+		}
+	}
+	flush()
+	return sentences
+}
+
+// countWords counts the words of a sentence that contain a letter, so dot leaders and
+// runs of numbers do not make a sentence
+func countWords(sentence string) int {
+	count := 0
+	for _, word := range strings.Fields(sentence) {
+		if strings.IndexFunc(word, unicode.IsLetter) >= 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// rank scores sentences with TextRank on the graph of their word overlap
+func rank(sentences []string) []float64 {
+	words := make([]map[string]bool, len(sentences))
+	for i, sentence := range sentences {
+		words[i] = contentWords(sentence)
+	}
+
+	// weights[i][j] is the similarity of sentences i and j; totals[i] the sum of row i
+	weights := make([][]float64, len(sentences))
+	totals := make([]float64, len(sentences))
+	for i := range sentences {
+		weights[i] = make([]float64, len(sentences))
+		for j := range sentences {
+			if i != j {
+				weights[i][j] = similarity(words[i], words[j])
+				totals[i] += weights[i][j]
+			}
+		}
+	}
+
+	scores := make([]float64, len(sentences))
+	for i := range scores {
+		scores[i] = 1
+	}
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		next := make([]float64, len(sentences))
+		change := 0.0
+		for i := range sentences {
+			sum := 0.0
+			for j := range sentences {
+				if weights[j][i] > 0 {
+					sum += weights[j][i] / totals[j] * scores[j]
+				}
+			}
+			next[i] = 1 - damping + damping*sum
+			change += math.Abs(next[i] - scores[i])
+		}
+		scores = next
+		if change < tolerance {
+			break
+		}
+	}
+	return scores
+}
+
+// contentWords returns the distinct lower-case words of a sentence without stop words
+// and punctuation
+func contentWords(sentence string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 1 && !stopWords[word] {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// similarity is the TextRank similarity of two sentences: their shared words normalized
+// by the logarithms of their lengths, so long sentences are not favored
+func similarity(a, b map[string]bool) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / (math.Log(float64(len(a))) + math.Log(float64(len(b))))
+}
@@ -0,0 +1,177 @@
+// Package tempfile creates the library's temp files and directories under one root,
+// tracks them so they can be removed when the process panics or is interrupted, and
+// sweeps the ones left behind by runs that crashed anyway
+package tempfile
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Prefix starts the name of every temp file and directory created by this package
+const Prefix = "pdf-chunk-"
+
+// tracked holds the paths created and not yet removed by this process
+var tracked = struct {
+	sync.Mutex
+	paths map[string]struct{}
+}{paths: map[string]struct{}{}}
+
+// swept records the roots already swept by SweepOnce
+var swept sync.Map
+
+// Root returns dir, or the system temp directory when dir is empty
+func Root(dir string) string {
+	if dir == "" {
+		return os.TempDir()
+	}
+	return dir
+}
+
+// MkdirTemp creates a directory named Prefix+pattern under root (see Root), creating
+// root if needed. The directory is tracked until it is passed to Remove.
+func MkdirTemp(root, pattern string) (string, error) {
+	if err := os.MkdirAll(Root(root), 0700); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(Root(root), Prefix+pattern)
+	if err != nil {
+		return "", err
+	}
+	track(dir)
+	return dir, nil
+}
+
+// CreateTemp creates and opens a file named Prefix+pattern under root (see Root),
+// creating root if needed. The file is tracked until it is passed to Remove.
+func CreateTemp(root, pattern string) (*os.File, error) {
+	if err := os.MkdirAll(Root(root), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(Root(root), Prefix+pattern)
+	if err != nil {
+		return nil, err
+	}
+	track(file.Name())
+	return file, nil
+}
+
+// Remove removes a file or directory created by MkdirTemp or CreateTemp and stops
+// tracking it
+func Remove(path string) error {
+	tracked.Lock()
+	delete(tracked.paths, path)
+	tracked.Unlock()
+	return os.RemoveAll(path)
+}
+
+// Cleanup removes every tracked file and directory. Deferred calls to Remove do not run
+// when the process exits through os.Exit, a signal or a panic in another goroutine, so
+// call Cleanup on those paths (see CleanupOnPanic and CleanupOnSignal).
+func Cleanup() {
+	tracked.Lock()
+	paths := tracked.paths
+	tracked.paths = map[string]struct{}{}
+	tracked.Unlock()
+
+	for path := range paths {
+		os.RemoveAll(path)
+	}
+}
+
+// CleanupOnPanic runs Cleanup and re-panics when the calling goroutine is panicking. Defer
+// it at the top of goroutines that create temp files, as a panic there ends the process
+// without running the deferred calls of other goroutines.
+func CleanupOnPanic() {
+	if r := recover(); r != nil {
+		Cleanup()
+		panic(r)
+	}
+}
+
+// CleanupOnSignal runs Cleanup and exits with status 130 when the process receives
+// SIGINT or SIGTERM. Call the returned function to stop handling the signals.
+func CleanupOnSignal() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			Cleanup()
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// Sweep removes files and directories named Prefix* under root that are older than
+// maxAge and not tracked by this process: the leftovers of runs that were killed or
+// crashed. It returns the number of entries removed.
+func Sweep(root string, maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(Root(root))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), Prefix) {
+			continue
+		}
+		path := filepath.Join(Root(root), entry.Name())
+		if isTracked(path) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(path); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// SweepOnce runs Sweep on root the first time it is called for that root in this
+// process, and returns 0 afterwards
+func SweepOnce(root string, maxAge time.Duration) (int, error) {
+	if _, done := swept.LoadOrStore(Root(root), true); done {
+		return 0, nil
+	}
+	return Sweep(root, maxAge)
+}
+
+// track records a created path
+func track(path string) {
+	tracked.Lock()
+	tracked.paths[path] = struct{}{}
+	tracked.Unlock()
+}
+
+// isTracked reports whether path was created by this process and not yet removed
+func isTracked(path string) bool {
+	tracked.Lock()
+	defer tracked.Unlock()
+	_, ok := tracked.paths[path]
+	return ok
+}
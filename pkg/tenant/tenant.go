@@ -0,0 +1,154 @@
+// Package tenant isolates the teams that share one deployment. Every tenant gets a
+// chunker of its own, with output directories of its own, its own sinks, AI rate limits,
+// AI budget and dedup index, and its ID in the metadata of its chunks.
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunker"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+// MetadataKey is the chunk metadata key of the tenant ID
+const MetadataKey = "tenant"
+
+var (
+	// ErrInvalidID is returned for IDs that cannot name directories, topics and subjects
+	ErrInvalidID = errors.New("invalid tenant ID: use 1 to 64 lowercase letters, digits, '-' and '_', starting with a letter or digit")
+	// ErrUnknownTenant is returned for tenants outside Options.Allowed
+	ErrUnknownTenant = errors.New("unknown tenant")
+)
+
+// idPattern matches tenant IDs, which are safe as path elements, Kafka topic suffixes
+// and NATS subject tokens
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ValidateID returns ErrInvalidID unless id is a valid tenant ID
+func ValidateID(id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrInvalidID
+	}
+	return nil
+}
+
+// Options configures a Pool
+type Options struct {
+	Config  config.ChunkerConfig                    // Configuration of every tenant; see Config for the paths that get a directory per tenant
+	Options []chunker.Option                        // Options of every tenant's chunker, e.g. the AI provider; WithConfig is overridden, and WithRateLimiter shares its limits between tenants
+	Sinks   func(id string) ([]chunker.Sink, error) // Sinks of a tenant, e.g. publishing to the tenant's topic or collection; closed by Close when they are io.Closers. May be nil.
+	Allowed []string                                // Tenants served; empty serves any valid ID
+}
+
+// Pool creates the chunker of a tenant on its first request and keeps it for the next
+type Pool struct {
+	options Options
+
+	mu       sync.Mutex
+	chunkers map[string]*chunker.Chunker
+	sinks    map[string][]chunker.Sink
+}
+
+// NewPool creates a pool of tenant chunkers
+func NewPool(options Options) *Pool {
+	return &Pool{options: options, chunkers: make(map[string]*chunker.Chunker), sinks: make(map[string][]chunker.Sink)}
+}
+
+// Config returns the configuration of a tenant: base with the tenant's directory under
+// OutputDir, ChunkDir, JSONDir, PageImageDir and SplitPDFDir, and next to AuditLogPath.
+// Limits such as AIRequestsPerMinute and MaxTotalTokens apply per tenant, as every
+// tenant has a chunker of its own.
+func Config(base config.ChunkerConfig, id string) config.ChunkerConfig {
+	cfg := base
+	for _, dir := range []*string{&cfg.OutputDir, &cfg.ChunkDir, &cfg.JSONDir, &cfg.PageImageDir, &cfg.SplitPDFDir} {
+		if *dir != "" {
+			*dir = filepath.Join(*dir, id)
+		}
+	}
+	if cfg.AuditLogPath != "" {
+		cfg.AuditLogPath = filepath.Join(filepath.Dir(cfg.AuditLogPath), id, filepath.Base(cfg.AuditLogPath))
+	}
+	return cfg
+}
+
+// Metadata returns a copy of metadata with the tenant ID under MetadataKey, which
+// overrides a tenant key of the request
+func Metadata(id string, metadata map[string]any) map[string]any {
+	tagged := make(map[string]any, len(metadata)+1)
+	for key, value := range metadata {
+		tagged[key] = value
+	}
+	tagged[MetadataKey] = id
+	return tagged
+}
+
+// Chunker returns the chunker of a tenant, creating it on first use
+func (p *Pool) Chunker(id string) (*chunker.Chunker, error) {
+	if err := ValidateID(id); err != nil {
+		return nil, err
+	}
+	if len(p.options.Allowed) > 0 && !slices.Contains(p.options.Allowed, id) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTenant, id)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.chunkers[id]; ok {
+		return c, nil
+	}
+	opts := append(slices.Clone(p.options.Options), chunker.WithConfig(Config(p.options.Config, id)))
+	if p.options.Sinks != nil {
+		sinks, err := p.options.Sinks(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sinks of tenant %s: %w", id, err)
+		}
+		for _, sink := range sinks {
+			opts = append(opts, chunker.WithSink(sink))
+		}
+		p.sinks[id] = sinks
+	}
+	c := chunker.NewChunker(opts...)
+	p.chunkers[id] = c
+	return c, nil
+}
+
+// Tenants returns the IDs of the tenants served so far, sorted
+func (p *Pool) Tenants() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, 0, len(p.chunkers))
+	for id := range p.chunkers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Close closes the chunker and sinks of every tenant
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for id, c := range p.chunkers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", id, err))
+		}
+		for _, sink := range p.sinks[id] {
+			if closer, ok := sink.(io.Closer); ok {
+				if err := closer.Close(); err != nil {
+					errs = append(errs, fmt.Errorf("tenant %s: failed to close %s sink: %w", id, sink.GetName(), err))
+				}
+			}
+		}
+		delete(p.chunkers, id)
+		delete(p.sinks, id)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxArchiveEntrySize guards against decompression bombs
+const maxArchiveEntrySize = 2 << 30
+
+// ErrArchiveTooLarge is returned when an archive unpacks past its ArchiveLimits
+var ErrArchiveTooLarge = errors.New("archive unpacks too large")
+
+// ArchiveLimits bound what unpacking an archive may write, so a small decompression bomb
+// cannot fill the disk
+type ArchiveLimits struct {
+	MaxBytes   int64 // Total size of the unpacked files; 0 is unlimited
+	MaxEntries int   // Files in the archive, directories and skipped entries included; 0 is unlimited
+}
+
+// IsArchive reports whether data looks like a zip, gzip or tar archive
+func IsArchive(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04")) ||
+		bytes.HasPrefix(data, []byte{0x1f, 0x8b}) ||
+		(len(data) > 262 && string(data[257:262]) == "ustar")
+}
+
+// UnpackArchive extracts a .zip, .tar.gz/.tgz or .tar archive into destDir,
+// detecting the format from its content
+func UnpackArchive(data []byte, destDir string) error {
+	return UnpackArchiveWithLimits(data, destDir, ArchiveLimits{})
+}
+
+// UnpackArchiveWithLimits extracts an archive like UnpackArchive, failing with
+// ErrArchiveTooLarge as soon as it goes past limits
+func UnpackArchiveWithLimits(data []byte, destDir string, limits ArchiveLimits) error {
+	u := &unpacker{destDir: destDir, limits: limits}
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return u.unpackZip(data)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to open gzip archive: %w", err)
+		}
+		defer gz.Close()
+		return u.unpackTar(gz)
+	case len(data) > 262 && string(data[257:262]) == "ustar":
+		return u.unpackTar(bytes.NewReader(data))
+	default:
+		return fmt.Errorf("unsupported archive format")
+	}
+}
+
+// unpacker extracts the entries of one archive, counting them against its limits
+type unpacker struct {
+	destDir string
+	limits  ArchiveLimits
+	entries int
+	written int64
+}
+
+// unpackZip extracts a zip archive
+func (u *unpacker) unpackZip(data []byte) error {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	for _, file := range archive.File {
+		if err := u.countEntry(); err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", file.Name, err)
+		}
+		err = u.writeEntry(file.Name, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpackTar extracts a tar stream
+func (u *unpacker) unpackTar(reader io.Reader) error {
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if err := u.countEntry(); err != nil {
+			return err
+		}
+
+		// Only regular files are extracted; links could point outside destDir
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := u.writeEntry(header.Name, archive); err != nil {
+			return err
+		}
+	}
+}
+
+// countEntry fails once the archive holds more than MaxEntries entries
+func (u *unpacker) countEntry() error {
+	u.entries++
+	if u.limits.MaxEntries > 0 && u.entries > u.limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, u.limits.MaxEntries)
+	}
+	return nil
+}
+
+// writeEntry writes a single entry, refusing paths that escape destDir and sizes past
+// the limits
+func (u *unpacker) writeEntry(name string, reader io.Reader) error {
+	target := filepath.Join(u.destDir, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(u.destDir, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("archive entry %q escapes the destination directory", name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	defer file.Close()
+
+	limit := int64(maxArchiveEntrySize)
+	if u.limits.MaxBytes > 0 {
+		limit = min(limit, u.limits.MaxBytes-u.written)
+	}
+	written, err := io.Copy(file, io.LimitReader(reader, limit+1))
+	u.written += written
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	if written > limit {
+		if limit < maxArchiveEntrySize {
+			return fmt.Errorf("%w: more than %d bytes", ErrArchiveTooLarge, u.limits.MaxBytes)
+		}
+		return fmt.Errorf("archive entry %q is too large", name)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package utils_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// tarGz returns a .tar.gz archive of files with the given sizes, filled with zeros so it
+// compresses like a decompression bomb
+func tarGz(t *testing.T, sizes ...int) []byte {
+	t.Helper()
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	writer := tar.NewWriter(gz)
+	for i, size := range sizes {
+		header := &tar.Header{Name: fmt.Sprintf("file-%d.txt", i), Mode: 0644, Size: int64(size), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write(make([]byte, size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive.Bytes()
+}
+
+func TestUnpackArchiveWithLimits(t *testing.T) {
+	limits := utils.ArchiveLimits{MaxBytes: 1 << 20, MaxEntries: 3}
+	tests := []struct {
+		name    string
+		sizes   []int
+		wantErr bool
+	}{
+		{"within limits", []int{300 << 10, 300 << 10, 300 << 10}, false},
+		{"exactly MaxBytes", []int{1 << 20}, false},
+		{"bomb", []int{64 << 20}, true},
+		{"files adding up past MaxBytes", []int{600 << 10, 600 << 10}, true},
+		{"too many entries", []int{1, 1, 1, 1}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := utils.UnpackArchiveWithLimits(tarGz(t, test.sizes...), dir, limits)
+			if test.wantErr != errors.Is(err, utils.ErrArchiveTooLarge) {
+				t.Fatalf("error = %v, want ErrArchiveTooLarge: %v", err, test.wantErr)
+			}
+
+			var unpacked int64
+			filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err == nil && info.Mode().IsRegular() {
+					unpacked += info.Size()
+				}
+				return nil
+			})
+			// One byte past MaxBytes is read to tell that the limit was reached
+			if unpacked > limits.MaxBytes+1 {
+				t.Errorf("unpacked %d bytes, want at most %d", unpacked, limits.MaxBytes+1)
+			}
+		})
+	}
+}
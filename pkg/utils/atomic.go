@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stagingSuffix marks the hidden staging files and directories next to their target
+const stagingSuffix = ".tmp-"
+
+// WriteFileAtomic writes data to a temp file next to path and renames it over path, so
+// readers never see a partially written file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), stagingName(path)+"*")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// WriteDirAtomic calls write with a staging directory next to dir and, once write
+// succeeds, swaps it into place of dir, removing the previous contents. dir is never
+// seen half written: it holds either the previous or the new set of files.
+func WriteDirAtomic(dir string, write func(staging string) error) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dir), stagingName(dir)+"*")
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(staging, 0755); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+
+	if err := write(staging); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+
+	// A directory cannot be renamed over a non-empty one; move the old one aside first
+	backup := ""
+	if _, err := os.Lstat(dir); err == nil {
+		backup = staging + ".old"
+		if err := os.Rename(dir, backup); err != nil {
+			os.RemoveAll(staging)
+			return fmt.Errorf("failed to replace %s: %w", dir, err)
+		}
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		if backup != "" {
+			os.Rename(backup, dir)
+		}
+		os.RemoveAll(staging)
+		return fmt.Errorf("failed to replace %s: %w", dir, err)
+	}
+	if backup != "" {
+		os.RemoveAll(backup)
+	}
+	return nil
+}
+
+// RemoveStaleStaging removes the staging files and directories of path older than maxAge,
+// left behind when a process died before WriteFileAtomic or WriteDirAtomic finished
+func RemoveStaleStaging(path string, maxAge time.Duration) {
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), stagingName(path)+"*"))
+	cutoff := time.Now().Add(-maxAge)
+	for _, match := range matches {
+		if info, err := os.Lstat(match); err == nil && info.ModTime().Before(cutoff) {
+			os.RemoveAll(match)
+		}
+	}
+}
+
+// stagingName returns the hidden name prefix of the staging copies of path
+func stagingName(path string) string {
+	return "." + filepath.Base(path) + stagingSuffix
+}
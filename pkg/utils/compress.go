@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats for output files and streams
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// CompressionExt returns the file extension of a compression format, or "" for none
+func CompressionExt(format string) string {
+	switch format {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// CompressionForPath returns the compression format of a file from its extension, or ""
+// for uncompressed files
+func CompressionForPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return CompressionGzip
+	case strings.HasSuffix(path, ".zst"):
+		return CompressionZstd
+	default:
+		return ""
+	}
+}
+
+// NewCompressWriter returns a writer that compresses what is written to it into w.
+// Close flushes the compressed stream but does not close w. An empty format writes
+// through unchanged.
+func NewCompressWriter(w io.Writer, format string) (io.WriteCloser, error) {
+	switch format {
+	case "":
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %q", format)
+	}
+}
+
+// NewDecompressReader returns a reader that decompresses r. An empty format reads
+// through unchanged.
+func NewDecompressReader(r io.Reader, format string) (io.ReadCloser, error) {
+	switch format {
+	case "":
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression: %q", format)
+	}
+}
+
+// Compress returns data compressed with format; an empty format returns data unchanged
+func Compress(data []byte, format string) ([]byte, error) {
+	if format == "" {
+		return data, nil
+	}
+
+	var buffer bytes.Buffer
+	writer, err := NewCompressWriter(&buffer, format)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Decompress returns data decompressed with format; an empty format returns data unchanged
+func Decompress(data []byte, format string) ([]byte, error) {
+	reader, err := NewDecompressReader(bytes.NewReader(data), format)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// nopWriteCloser adds a no-op Close to a writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing
+func (nopWriteCloser) Close() error {
+	return nil
+}
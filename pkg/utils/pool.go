@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which buffers are left to the garbage collector
+// rather than pooled, so one huge chunk does not pin its memory
+const maxPooledBuffer = 1 << 20
+
+// bufferPool holds the buffers chunks are assembled in: splitting a document grows one
+// buffer to the chunk size and reuses it for every chunk, instead of growing a new
+// strings.Builder from empty per chunk
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *bytes.Buffer {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+// putBuffer returns a buffer to the pool; its content must no longer be referenced
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buffer)
+	}
+}
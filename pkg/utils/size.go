@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/config"
+)
+
+// charsPerToken is the average token length of alphabetic text, as estimated elsewhere
+const charsPerToken = 4
+
+// WithSizeUnit returns a copy of the processor whose splitters measure chunk sizes in
+// unit, one of config.SizeRunes, config.SizeBytes and config.SizeTokens; other units
+// measure characters, as the processor does by default
+func (t *TextProcessor) WithSizeUnit(unit string) *TextProcessor {
+	processor := *t
+	switch unit {
+	case config.SizeBytes:
+		processor.measure, processor.scale = byteCount, 1
+	case config.SizeTokens:
+		processor.measure, processor.scale = quarterTokens, charsPerToken
+	default:
+		processor.measure, processor.scale = utf8.RuneCountInString, 1
+	}
+	return &processor
+}
+
+// Size returns the size of text in the processor's unit, see WithSizeUnit
+func (t *TextProcessor) Size(text string) int {
+	return (t.measure(text) + t.scale - 1) / t.scale
+}
+
+// limit scales a chunk size to the processor's measure
+func (t *TextProcessor) limit(size int) int {
+	return size * t.scale
+}
+
+// byteCount measures text in bytes
+func byteCount(text string) int {
+	return len(text)
+}
+
+// quarterTokens measures text in quarters of estimated tokens, so sizes add up exactly
+// line by line: a byte of other text is a quarter token, and every character of a
+// script written without spaces, such as Chinese, Japanese, Korean or Thai, a whole
+// token, as model tokenizers split those scripts into about one token per character
+func quarterTokens(text string) int {
+	quarters := 0
+	for _, r := range text {
+		if isWide(r) {
+			quarters += charsPerToken
+		} else {
+			quarters += utf8.RuneLen(r)
+		}
+	}
+	return quarters
+}
+
+// isWide reports whether a character is of a script tokenized character by character
+func isWide(r rune) bool {
+	return r >= 0x1100 && unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar)
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"path"
+	"strings"
+	"unicode"
+)
+
+// maxSlugSegment caps each path segment of a slug, in runes
+const maxSlugSegment = 100
+
+// windowsReservedNames cannot be used as file names on Windows, whatever the extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Slug turns a document filename, which may be a relative path such as "sub/report.pdf",
+// into a safe relative output path without the extension ("sub/report"). Both / and \
+// separate segments and empty, "." and ".." segments are dropped, so the result never
+// escapes the directory it is joined to. Other characters than letters, digits, '-', '_'
+// and '.', including ':' of drive letters, become '_'. The result uses / separators and is never empty.
+func Slug(filename string) string {
+	filename = strings.ReplaceAll(filename, `\`, "/")
+	filename = strings.TrimSuffix(filename, path.Ext(filename))
+
+	var segments []string
+	for _, segment := range strings.Split(filename, "/") {
+		if segment = slugSegment(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return "document"
+	}
+	return strings.Join(segments, "/")
+}
+
+// slugSegment sanitizes a single path segment, returning "" for segments to drop
+func slugSegment(segment string) string {
+	var result strings.Builder
+	runes := 0
+	underscore := false
+	for _, r := range segment {
+		if runes == maxSlugSegment {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			result.WriteRune(r)
+			underscore = false
+		} else if !underscore {
+			// Runs of spaces, ':' and other special characters collapse into one '_'
+			result.WriteRune('_')
+			underscore = true
+		} else {
+			continue
+		}
+		runes++
+	}
+
+	// Leading dots would make ".." or hidden files; trailing dots and spaces are
+	// stripped by Windows
+	slug := strings.Trim(result.String(), "._")
+	if windowsReservedNames[strings.ToUpper(strings.SplitN(slug, ".", 2)[0])] {
+		slug = "_" + slug
+	}
+	return slug
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/rtl"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/summary"
+)
+
+// ChunkView is the data a chunk template renders, see WithTemplate
+type ChunkView struct {
+	Number        int      // Chunk number, starting at 1
+	Total         int      // Chunks of the document, or of the section the chunk was split from
+	PageRange     string   // e.g. "Page 3–5"; empty when unknown
+	Citation      string   // e.g. "UU 13/2003 Pasal 59", in the regulation profile
+	RTL           bool     // Most letters are of a right-to-left script
+	DocumentCodes []string // e.g. "SOP/HR/01"
+	Dates         []string // e.g. "12 - Januari - 2024"
+	Title         string   // Document title found in the chunk
+	Summary       []string // Key sentences, with Summarize
+	Content       string   // Text with page separators, headings and lists as Markdown
+	Text          string   // Text as extracted
+}
+
+// TemplateFuncs are the functions chunk templates parsed with ParseTemplate can call
+var TemplateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+}
+
+// ParseTemplate parses a chunk template with TemplateFuncs and checks that it renders a
+// sample chunk, so mistakes such as unknown fields are found before any document is
+// formatted
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("chunk").Funcs(TemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chunk template: %w", err)
+	}
+	if err := CheckTemplate(tmpl); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// CheckTemplate renders a sample chunk with a template and returns the error, if any
+func CheckTemplate(tmpl *template.Template) error {
+	sample := ChunkView{
+		Number: 1, Total: 1, PageRange: "Page 1", DocumentCodes: []string{"SOP/HR/01"},
+		Dates: []string{"1 - Januari - 2024"}, Title: "Sample", Summary: []string{"Sample."},
+		Content: "Sample.", Text: "Sample.",
+	}
+	if err := tmpl.Execute(new(strings.Builder), sample); err != nil {
+		return fmt.Errorf("invalid chunk template: %w", err)
+	}
+	return nil
+}
+
+// WithTemplate returns a copy of the processor that formats chunks with tmpl, executed
+// with a ChunkView, instead of the built-in Markdown layout. Chunks the template fails
+// to render get the built-in layout.
+func (t *TextProcessor) WithTemplate(tmpl *template.Template) *TextProcessor {
+	processor := *t
+	processor.template = tmpl
+	return &processor
+}
+
+// renderTemplate formats a chunk with the processor's template
+func (t *TextProcessor) renderTemplate(chunk, pageRange, citation string, chunkNum, totalChunks int) (string, error) {
+	metadata := findMetadata(chunk)
+	view := ChunkView{
+		Number:        chunkNum,
+		Total:         totalChunks,
+		PageRange:     pageRange,
+		Citation:      citation,
+		RTL:           rtl.IsRTL(chunk),
+		DocumentCodes: metadata.documentCodes,
+		Dates:         metadata.dates,
+		Title:         metadata.title,
+		Summary:       summary.Summarize(chunk, t.summarySentences),
+		Content:       t.cleanAndStructureContent(chunk),
+		Text:          chunk,
+	}
+	var formatted strings.Builder
+	if err := t.template.Execute(&formatted, view); err != nil {
+		return "", err
+	}
+	return formatted.String(), nil
+}
@@ -1,18 +1,85 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/rtl"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/summary"
 )
 
+var pageSeparatorPattern = regexp.MustCompile(`--- Page \d+ ---`)
+
+// pageNumberPattern captures the number of a page separator
+var pageNumberPattern = regexp.MustCompile(`--- Page (\d+) ---`)
+
+// metadataLinePattern matches page separators, and the Markdown headings and "- **Label**:
+// value" lines the formatter adds around chunk text
+var metadataLinePattern = regexp.MustCompile(`^(#+\s|- \*\*[^*]+\*\*:|--- Page \d+ ---$)`)
+
+// IsMetadataLine reports whether a trimmed line of a chunk was added by the formatter or
+// separates pages, rather than holding document text
+func IsMetadataLine(line string) bool {
+	return metadataLinePattern.MatchString(line)
+}
+
+// naturalBreakPatterns match the heading lines IsNaturalBreak breaks local chunks at
+var naturalBreakPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^Bab\s+\d+`),         // Bab 1, Bab 2, etc.
+	regexp.MustCompile(`^Pasal\s+\d+`),       // Pasal 1, Pasal 2, etc.
+	regexp.MustCompile(`^Chapter\s+\d+`),     // Chapter 1, Chapter 2, etc.
+	regexp.MustCompile(`^Section\s+\d+`),     // Section 1, Section 2, etc.
+	regexp.MustCompile(`^Artikel\s+\d+`),     // Artikel 1, Artikel 2, etc.
+	regexp.MustCompile(`^BAB\s+\d+`),         // BAB 1, BAB 2, etc.
+	regexp.MustCompile(`^PASAL\s+\d+`),       // PASAL 1, PASAL 2, etc.
+	regexp.MustCompile(`^\d+\.\s+[A-Z]`),     // 1. Title, 2. Title, etc.
+	regexp.MustCompile(`^[A-Z][A-Z\s]{3,}$`), // ALL CAPS HEADINGS
+	regexp.MustCompile(`^[A-Z][a-z\s]{3,}$`), // Title Case Headings
+}
+
+// numberedListPattern matches numbered list items, which are natural breaks too
+var numberedListPattern = regexp.MustCompile(`^\d+\.`)
+
+// headingLevels infers the level of a heading from its numbering scheme, in order:
+// chapters (BAB, Chapter) and "1." are level 1, parts (Bagian, Section) and "1.1" level
+// 2, articles (Paragraf, Pasal, Artikel) and "1.1.1" or deeper level 3
+var headingLevels = []struct {
+	pattern *regexp.Regexp
+	level   int
+}{
+	{regexp.MustCompile(`^(?:BAB|Bab|CHAPTER|Chapter)\s+(?:[IVXLC]+|\d+)\b`), 1},
+	{regexp.MustCompile(`^(?:BAGIAN|Bagian|SECTION|Section)\s+\S+`), 2},
+	{regexp.MustCompile(`^(?:PARAGRAF|Paragraf|PASAL|Pasal|ARTIKEL|Artikel)\s+\d+[A-Z]?$`), 3},
+	{regexp.MustCompile(`^\d+\.\d+\.\d+(?:\.\d+)*\.?\s+\p{Lu}`), 3},
+	{regexp.MustCompile(`^\d+\.\d+\.?\s+\p{Lu}`), 2},
+	{regexp.MustCompile(`^\d+\.\s+\p{Lu}`), 1},
+}
+
+// maxHeadingLength is the longest line taken for a heading; longer numbered lines are
+// list items or paragraphs
+const maxHeadingLength = 100
+
+// sentencePunctuation ends the numbered list items that continue as a sentence, such
+// as the definitions of Pasal 1, which are not headings
+const sentencePunctuation = ".,;:"
+
 // TextProcessor handles text chunking and formatting
 type TextProcessor struct {
-	maxChunkSize   int
-	localChunkSize int
+	maxChunkSize     int
+	localChunkSize   int
+	summarySentences int                   // Key sentences listed by FormatChunk, see WithSummary
+	tokenizer        Tokenizer             // Cuts lines longer than a chunk, see WithTokenizer
+	measure          func(text string) int // Size of text in units of 1/scale, see WithSizeUnit
+	scale            int
+	template         *template.Template // Replaces the built-in chunk layout, see WithTemplate
+	plainText        bool               // Chunks are not formatted, see WithPlainText
 }
 
 // NewTextProcessor creates a new text processor
@@ -20,22 +87,72 @@ func NewTextProcessor(maxChunkSize, localChunkSize int) *TextProcessor {
 	return &TextProcessor{
 		maxChunkSize:   maxChunkSize,
 		localChunkSize: localChunkSize,
+		tokenizer:      UnicodeTokenizer{},
+		measure:        utf8.RuneCountInString,
+		scale:          1,
 	}
 }
 
+// WithSummary returns a copy of the processor whose FormatChunk lists the key
+// sentences of each chunk, picked with TextRank, in a Summary section
+func (t *TextProcessor) WithSummary(sentences int) *TextProcessor {
+	processor := *t
+	processor.summarySentences = sentences
+	return &processor
+}
+
+// WithTokenizer returns a copy of the processor whose splitters cut lines longer than a
+// chunk into sentences and words with tokenizer, instead of UnicodeTokenizer
+func (t *TextProcessor) WithTokenizer(tokenizer Tokenizer) *TextProcessor {
+	processor := *t
+	processor.tokenizer = tokenizer
+	return &processor
+}
+
+// WithPlainText returns a copy of the processor whose FormatChunk returns the chunk text
+// as PlainText, without headings, metadata and summary sections
+func (t *TextProcessor) WithPlainText() *TextProcessor {
+	processor := *t
+	processor.plainText = true
+	return &processor
+}
+
 // SplitTextIntoChunks splits text into manageable chunks for AI processing
 func (t *TextProcessor) SplitTextIntoChunks(text string) []string {
 	var chunks []string
-	lines := strings.Split(text, "\n")
-	var currentChunk strings.Builder
+	currentChunk := getBuffer()
+	defer putBuffer(currentChunk)
+	size, maxSize := 0, t.limit(t.maxChunkSize)
+	newlineSize := t.measure("\n")
+	flush := func() {
+		chunks = append(chunks, currentChunk.String())
+		currentChunk.Reset()
+		size = 0
+	}
 
-	for _, line := range lines {
-		currentChunk.WriteString(line + "\n")
+	var pieces []string
+	for line := range strings.SplitSeq(text, "\n") {
+		pieces = t.splitLine(pieces[:0], line, maxSize)
+		for i, piece := range pieces {
+			last := i == len(pieces)-1
+			// Pieces of a long line start a new chunk rather than overflow this one
+			pieceSize := t.measure(piece)
+			if last {
+				pieceSize += newlineSize
+			}
+			if len(pieces) > 1 && size > 0 && size+pieceSize > maxSize {
+				flush()
+			}
+			currentChunk.WriteString(piece)
+			if last {
+				currentChunk.WriteByte('\n')
+			}
+			size += pieceSize
 
-		// If chunk is getting too large, split it
-		if currentChunk.Len() > t.maxChunkSize {
-			chunks = append(chunks, currentChunk.String())
-			currentChunk.Reset()
+			// If chunk is getting too large, split it
+			if size > maxSize {
+				flush()
+			}
 		}
 	}
 
@@ -50,50 +167,132 @@ func (t *TextProcessor) SplitTextIntoChunks(text string) []string {
 // SplitTextIntoLocalChunks splits text into intelligent chunks based on natural breaks
 func (t *TextProcessor) SplitTextIntoLocalChunks(text string) []string {
 	var chunks []string
-	var currentChunk strings.Builder
+	currentChunk := getBuffer()
+	defer putBuffer(currentChunk)
+	size, maxSize := 0, t.limit(t.localChunkSize)
+	newlineSize := t.measure("\n")
+	flush := func() {
+		if chunk := bytes.TrimSpace(currentChunk.Bytes()); len(chunk) > 0 {
+			chunks = append(chunks, string(chunk))
+		}
+		currentChunk.Reset()
+		size = 0
+	}
 
 	// Split text into lines for processing
 	lines := strings.Split(text, "\n")
 
+	var pieces []string
 	for i, line := range lines {
 		trimmedLine := strings.TrimSpace(line)
 
 		// Check if this line is a natural break point
 		if t.isNaturalBreak(trimmedLine, i, lines) {
 			// If current chunk is getting large, save it and start new one
-			if currentChunk.Len() > t.localChunkSize {
-				chunk := strings.TrimSpace(currentChunk.String())
-				if chunk != "" {
-					chunks = append(chunks, chunk)
-				}
-				currentChunk.Reset()
+			if size > maxSize {
+				flush()
 			}
 		}
 
-		// Add the line to current chunk
-		currentChunk.WriteString(line + "\n")
+		// Add the line to current chunk, cut into sentences or words when it is longer
+		// than a chunk on its own
+		pieces = t.splitLine(pieces[:0], line, maxSize)
+		for j, piece := range pieces {
+			last := j == len(pieces)-1
+			pieceSize := t.measure(piece)
+			if last {
+				pieceSize += newlineSize
+			}
+			if len(pieces) > 1 && size > 0 && size+pieceSize > maxSize {
+				flush()
+			}
+			currentChunk.WriteString(piece)
+			if last {
+				currentChunk.WriteByte('\n')
+			}
+			size += pieceSize
 
-		// If chunk is getting too large, force a break
-		if currentChunk.Len() > t.localChunkSize {
-			chunk := strings.TrimSpace(currentChunk.String())
-			if chunk != "" {
-				chunks = append(chunks, chunk)
+			// If chunk is getting too large, force a break
+			if size > maxSize {
+				flush()
 			}
-			currentChunk.Reset()
 		}
 	}
 
 	// Add remaining content
 	if currentChunk.Len() > 0 {
-		chunk := strings.TrimSpace(currentChunk.String())
-		if chunk != "" {
-			chunks = append(chunks, chunk)
+		flush()
+	}
+
+	return chunks
+}
+
+// SplitTextIntoPageGroups splits text at page separators and groups consecutive pages
+// into chunks of at most maxSize characters without splitting a page. Pages larger than
+// maxSize on their own are split with SplitTextIntoLocalChunks.
+func (t *TextProcessor) SplitTextIntoPageGroups(text string, maxSize int) []string {
+	return t.GroupPages(splitPages(text), maxSize)
+}
+
+// GroupPages groups consecutive pages into chunks of at most maxSize characters, or
+// the unit of WithSizeUnit, without splitting a page. Pages larger than maxSize on their
+// own are split with SplitTextIntoLocalChunks.
+func (t *TextProcessor) GroupPages(pages []string, maxSize int) []string {
+	var chunks []string
+	currentChunk := getBuffer()
+	defer putBuffer(currentChunk)
+	size := 0
+	maxSize = t.limit(maxSize)
+
+	flush := func() {
+		if chunk := bytes.TrimSpace(currentChunk.Bytes()); len(chunk) > 0 {
+			chunks = append(chunks, string(chunk))
+		}
+		currentChunk.Reset()
+		size = 0
+	}
+
+	for _, page := range pages {
+		pageSize := t.measure(page)
+		if size > 0 && size+pageSize > maxSize {
+			flush()
+		}
+
+		if pageSize > maxSize {
+			flush()
+			chunks = append(chunks, t.SplitTextIntoLocalChunks(page)...)
+			continue
 		}
+
+		currentChunk.WriteString(page)
+		size += pageSize
 	}
+	flush()
 
 	return chunks
 }
 
+// splitPages splits text into pages, keeping each "--- Page N ---" separator with its page
+func splitPages(text string) []string {
+	indexes := pageSeparatorPattern.FindAllStringIndex(text, -1)
+	if len(indexes) == 0 {
+		return []string{text}
+	}
+
+	var pages []string
+	if leading := text[:indexes[0][0]]; strings.TrimSpace(leading) != "" {
+		pages = append(pages, leading)
+	}
+	for i, index := range indexes {
+		end := len(text)
+		if i+1 < len(indexes) {
+			end = indexes[i+1][0]
+		}
+		pages = append(pages, text[index[0]:end])
+	}
+	return pages
+}
+
 // IsNaturalBreak checks if a line represents a natural break point
 func (t *TextProcessor) IsNaturalBreak(line string, lineIndex int, allLines []string) bool {
 	trimmed := strings.TrimSpace(line)
@@ -104,25 +303,16 @@ func (t *TextProcessor) IsNaturalBreak(line string, lineIndex int, allLines []st
 	}
 
 	// Check for various heading patterns
-	headingPatterns := []string{
-		`^Bab\s+\d+`,         // Bab 1, Bab 2, etc.
-		`^Pasal\s+\d+`,       // Pasal 1, Pasal 2, etc.
-		`^Chapter\s+\d+`,     // Chapter 1, Chapter 2, etc.
-		`^Section\s+\d+`,     // Section 1, Section 2, etc.
-		`^Artikel\s+\d+`,     // Artikel 1, Artikel 2, etc.
-		`^BAB\s+\d+`,         // BAB 1, BAB 2, etc.
-		`^PASAL\s+\d+`,       // PASAL 1, PASAL 2, etc.
-		`^\d+\.\s+[A-Z]`,     // 1. Title, 2. Title, etc.
-		`^[A-Z][A-Z\s]{3,}$`, // ALL CAPS HEADINGS
-		`^[A-Z][a-z\s]{3,}$`, // Title Case Headings
-	}
-
-	for _, pattern := range headingPatterns {
-		if matched, _ := regexp.MatchString(pattern, trimmed); matched {
+	for _, pattern := range naturalBreakPatterns {
+		if pattern.MatchString(trimmed) {
 			return true
 		}
 	}
 
+	if t.HeadingLevel(trimmed) > 0 {
+		return true
+	}
+
 	// Check for bullet points or numbered lists
 	if strings.HasPrefix(trimmed, "•") || strings.HasPrefix(trimmed, "-") ||
 		strings.HasPrefix(trimmed, "*") {
@@ -130,7 +320,7 @@ func (t *TextProcessor) IsNaturalBreak(line string, lineIndex int, allLines []st
 	}
 
 	// Check for numbered lists
-	if matched, _ := regexp.MatchString(`^\d+\.`, trimmed); matched {
+	if numberedListPattern.MatchString(trimmed) {
 		return true
 	}
 
@@ -141,7 +331,7 @@ func (t *TextProcessor) IsNaturalBreak(line string, lineIndex int, allLines []st
 
 	// Check if previous line was empty and this line looks like a heading
 	if lineIndex > 0 && strings.TrimSpace(allLines[lineIndex-1]) == "" {
-		if len(trimmed) < 100 && (strings.ToUpper(trimmed) == trimmed ||
+		if utf8.RuneCountInString(trimmed) < 100 && (strings.ToUpper(trimmed) == trimmed ||
 			strings.HasSuffix(trimmed, ":") || strings.HasSuffix(trimmed, ".")) {
 			return true
 		}
@@ -157,11 +347,36 @@ func (t *TextProcessor) isNaturalBreak(line string, lineIndex int, allLines []st
 
 // FormatLocalChunk formats a chunk with headers and structure
 func (t *TextProcessor) FormatLocalChunk(chunk string, chunkNum, totalChunks int) string {
+	return t.FormatChunk(chunk, t.extractPageRange(chunk), chunkNum, totalChunks)
+}
+
+// FormatChunk formats a chunk with headers and structure using a known page range
+func (t *TextProcessor) FormatChunk(chunk, pageRange string, chunkNum, totalChunks int) string {
+	return t.FormatCitedChunk(chunk, pageRange, "", chunkNum, totalChunks)
+}
+
+// FormatCitedChunk is FormatChunk with a citation, such as "UU 13/2003 Pasal 59", in the
+// metadata section when it is not empty
+func (t *TextProcessor) FormatCitedChunk(chunk, pageRange, citation string, chunkNum, totalChunks int) string {
+	if t.plainText {
+		return PlainText(chunk)
+	}
+	return t.RenderCitedChunk(chunk, pageRange, citation, chunkNum, totalChunks)
+}
+
+// RenderCitedChunk lays out a chunk like FormatCitedChunk, with the template or built-in
+// Markdown layout, even with WithPlainText
+func (t *TextProcessor) RenderCitedChunk(chunk, pageRange, citation string, chunkNum, totalChunks int) string {
+	if t.template != nil {
+		if formatted, err := t.renderTemplate(chunk, pageRange, citation, chunkNum, totalChunks); err == nil {
+			return formatted
+		}
+	}
+
 	var formatted strings.Builder
 
 	// Extract metadata
 	metadata := t.extractMetadata(chunk)
-	pageRange := t.extractPageRange(chunk)
 
 	// Add comprehensive metadata header
 	formatted.WriteString("# Document Chunk\n\n")
@@ -174,52 +389,164 @@ func (t *TextProcessor) FormatLocalChunk(chunk string, chunkNum, totalChunks int
 		formatted.WriteString(fmt.Sprintf("- **Page Range**: %s\n", pageRange))
 	}
 
+	if citation != "" {
+		formatted.WriteString(fmt.Sprintf("- **Citation**: %s\n", citation))
+	}
+
+	// Tell renderers and models that the content reads right to left
+	if rtl.IsRTL(chunk) {
+		formatted.WriteString("- **Direction**: rtl\n")
+	}
+
 	if metadata != "" {
 		formatted.WriteString(metadata)
 	}
 
 	formatted.WriteString("\n")
 
+	if sentences := summary.Summarize(chunk, t.summarySentences); len(sentences) > 0 {
+		formatted.WriteString("## Summary\n")
+		for _, sentence := range sentences {
+			formatted.WriteString("- " + sentence + "\n")
+		}
+		formatted.WriteString("\n")
+	}
+
 	// Content section with clear formatting for future embedding
-	formatted.WriteString("## Content\n\n")
+	formatted.WriteString(contentHeading)
 	formatted.WriteString(t.cleanAndStructureContent(chunk))
 
 	return formatted.String()
 }
 
-// ExtractMetadata extracts document metadata from the chunk
-func (t *TextProcessor) ExtractMetadata(chunk string) string {
-	var metadata strings.Builder
+// chunkNumberPattern matches the Chunk Number line of a formatted chunk
+var chunkNumberPattern = regexp.MustCompile(`(?m)^- \*\*Chunk Number\*\*: \d+ of \d+$`)
+
+// RenumberChunk returns a formatted chunk with its Chunk Number line set to chunkNum of
+// totalChunks; chunks without one are returned as they are
+func RenumberChunk(chunk string, chunkNum, totalChunks int) string {
+	return chunkNumberPattern.ReplaceAllLiteralString(chunk, fmt.Sprintf("- **Chunk Number**: %d of %d", chunkNum, totalChunks))
+}
+
+// pageRangeLinePattern matches the Page Range line of a formatted chunk
+var pageRangeLinePattern = regexp.MustCompile(`(?m)^- \*\*Page Range\*\*: .*$`)
+
+// SetChunkPageRange returns a formatted chunk with its Page Range line set to pageRange;
+// chunks without one are returned as they are
+func SetChunkPageRange(chunk, pageRange string) string {
+	return pageRangeLinePattern.ReplaceAllLiteralString(chunk, "- **Page Range**: "+pageRange)
+}
+
+// contentHeading starts the content section of a formatted chunk
+const contentHeading = "## Content\n\n"
+
+// SplitFormattedChunk splits a chunk laid out by RenderCitedChunk into its header, up to
+// and including the Content heading, and its content. Chunks without one, such as plain
+// text chunks, are all content.
+func SplitFormattedChunk(chunk string) (header, content string) {
+	if !strings.HasPrefix(chunk, "# Document Chunk\n") {
+		return "", chunk
+	}
+	i := strings.Index(chunk, contentHeading)
+	if i < 0 {
+		return "", chunk
+	}
+	return chunk[:i+len(contentHeading)], chunk[i+len(contentHeading):]
+}
+
+// pageSeparatorLinePattern matches page separator lines with the blank lines around them
+var pageSeparatorLinePattern = regexp.MustCompile(`\s*\n?--- Page \d+ ---\n?\s*`)
+
+// blankLinesPattern matches runs of blank lines
+var blankLinesPattern = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+\n`)
+
+// PlainText returns the text of a chunk without page separators, with paragraphs kept
+// apart by a single blank line
+func PlainText(chunk string) string {
+	text := pageSeparatorLinePattern.ReplaceAllString(chunk, "\n\n")
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(text, "\n\n"))
+}
+
+// ChunkMetadata returns the document metadata the formatter lists for a chunk, under
+// the keys "document_codes" and "dates" ([]string), "title" and "direction" ("rtl"), for
+// chunks kept without formatting. Keys without a value are left out.
+func ChunkMetadata(chunk string) map[string]any {
+	found := findMetadata(chunk)
+	metadata := make(map[string]any)
+	if len(found.documentCodes) > 0 {
+		metadata["document_codes"] = found.documentCodes
+	}
+	if len(found.dates) > 0 {
+		metadata["dates"] = found.dates
+	}
+	if found.title != "" {
+		metadata["title"] = found.title
+	}
+	if rtl.IsRTL(chunk) {
+		metadata["direction"] = "rtl"
+	}
+	return metadata
+}
+
+// ChunkContent returns the content of a chunk formatted by FormatChunk, without its
+// heading, metadata and summary sections. Other text, such as AI output, is returned
+// trimmed.
+func ChunkContent(text string) string {
+	if !strings.HasPrefix(text, "# Document Chunk\n") {
+		return strings.TrimSpace(text)
+	}
+	if _, content, ok := strings.Cut(text, "\n## Content\n"); ok {
+		return strings.TrimSpace(content)
+	}
+	return strings.TrimSpace(text)
+}
+
+// chunkMetadata is the document metadata found in a chunk
+type chunkMetadata struct {
+	documentCodes []string
+	dates         []string
+	title         string
+}
+
+// findMetadata finds the document codes, dates and title of a chunk
+func findMetadata(chunk string) chunkMetadata {
+	var metadata chunkMetadata
 
 	// Look for document codes
 	docCodePattern := regexp.MustCompile(`(SOP|KCN|AGR|KEP|PER|UU|PP|PMK)[/-][A-Z0-9/]+`)
-	if matches := docCodePattern.FindAllString(chunk, -1); len(matches) > 0 {
-		metadata.WriteString(fmt.Sprintf("- **Document Code**: %s\n", strings.Join(matches, ", ")))
-	}
+	metadata.documentCodes = docCodePattern.FindAllString(chunk, -1)
 
 	// Look for dates
-	datePattern := regexp.MustCompile(`(\d{1,2}\s+[-–]\s+[A-Za-z]+\s+[-–]\s+\d{4})`)
-	if matches := datePattern.FindAllString(chunk, -1); len(matches) > 0 {
-		metadata.WriteString(fmt.Sprintf("- **Date**: %s\n", strings.Join(matches, ", ")))
-	}
+	datePattern := regexp.MustCompile(`(\d{1,2}[ \t]+[-–][ \t]+[A-Za-z]+[ \t]+[-–][ \t]+\d{4})`)
+	metadata.dates = datePattern.FindAllString(chunk, -1)
 
-	// Look for document titles
-	titlePattern := regexp.MustCompile(`(?m)^([A-Z][A-Za-z\s]{3,50})$`)
-	if matches := titlePattern.FindAllString(chunk, -1); len(matches) > 0 {
+	// Look for document titles, on a line of their own
+	titlePattern := regexp.MustCompile(`(?m)^([A-Z][A-Za-z \t]{3,50})$`)
+	for _, match := range titlePattern.FindAllString(chunk, -1) {
 		// Filter out common non-titles
-		var titles []string
-		for _, match := range matches {
-			trimmed := strings.TrimSpace(match)
-			if !strings.Contains(trimmed, "Page") && !strings.Contains(trimmed, "---") &&
-				len(trimmed) > 5 && len(trimmed) < 100 {
-				titles = append(titles, trimmed)
-			}
-		}
-		if len(titles) > 0 {
-			metadata.WriteString(fmt.Sprintf("- **Document Title**: %s\n", strings.Join(titles[:1], ", ")))
+		trimmed := strings.TrimSpace(match)
+		if !strings.Contains(trimmed, "Page") && !strings.Contains(trimmed, "---") &&
+			utf8.RuneCountInString(trimmed) > 5 && utf8.RuneCountInString(trimmed) < 100 {
+			metadata.title = trimmed
+			break
 		}
 	}
+	return metadata
+}
 
+// ExtractMetadata extracts document metadata from the chunk
+func (t *TextProcessor) ExtractMetadata(chunk string) string {
+	var metadata strings.Builder
+	found := findMetadata(chunk)
+	if len(found.documentCodes) > 0 {
+		metadata.WriteString(fmt.Sprintf("- **Document Code**: %s\n", strings.Join(found.documentCodes, ", ")))
+	}
+	if len(found.dates) > 0 {
+		metadata.WriteString(fmt.Sprintf("- **Date**: %s\n", strings.Join(found.dates, ", ")))
+	}
+	if found.title != "" {
+		metadata.WriteString(fmt.Sprintf("- **Document Title**: %s\n", found.title))
+	}
 	return metadata.String()
 }
 
@@ -231,8 +558,7 @@ func (t *TextProcessor) extractMetadata(chunk string) string {
 // ExtractPageRange extracts page range from the chunk
 func (t *TextProcessor) ExtractPageRange(chunk string) string {
 	// Look for page separators like "--- Page X ---"
-	pagePattern := regexp.MustCompile(`--- Page (\d+) ---`)
-	matches := pagePattern.FindAllStringSubmatch(chunk, -1)
+	matches := pageNumberPattern.FindAllStringSubmatch(chunk, -1)
 
 	if len(matches) == 0 {
 		return ""
@@ -272,28 +598,30 @@ func (t *TextProcessor) CleanAndStructureContent(chunk string) string {
 			continue
 		}
 
-		// Clean up page separators
-		if strings.Contains(trimmed, "--- Page") {
-			cleaned.WriteString(fmt.Sprintf("\n### Page %s\n\n",
-				regexp.MustCompile(`Page (\d+)`).FindStringSubmatch(trimmed)[1]))
+		// Clean up page separators; a line mentioning "--- Page" without a number is text
+		if match := pageNumberPattern.FindStringSubmatch(trimmed); match != nil {
+			cleaned.WriteString(fmt.Sprintf("\n### Page %s\n\n", match[1]))
 			continue
 		}
 
-		// Format headings
+		// Format headings, nested by their numbering below the "### Page" headings
+		if level := t.HeadingLevel(trimmed); level > 0 {
+			cleaned.WriteString(fmt.Sprintf("\n%s %s\n\n", strings.Repeat("#", level+2), trimmed))
+			continue
+		}
 		if t.isHeading(trimmed) {
 			cleaned.WriteString(fmt.Sprintf("\n### %s\n\n", trimmed))
 			continue
 		}
 
 		// Format bullet points and numbered lists
-		if strings.HasPrefix(trimmed, "•") || strings.HasPrefix(trimmed, "-") ||
-			strings.HasPrefix(trimmed, "*") {
-			cleaned.WriteString(fmt.Sprintf("- %s\n", strings.TrimSpace(trimmed[1:])))
+		if bullet, ok := cutBullet(trimmed); ok {
+			cleaned.WriteString(fmt.Sprintf("- %s\n", strings.TrimSpace(bullet)))
 			continue
 		}
 
 		// Format numbered lists
-		if matched, _ := regexp.MatchString(`^\d+\.`, trimmed); matched {
+		if numberedListPattern.MatchString(trimmed) {
 			cleaned.WriteString(fmt.Sprintf("%s\n", trimmed))
 			continue
 		}
@@ -309,37 +637,54 @@ func (t *TextProcessor) CleanAndStructureContent(chunk string) string {
 	return strings.TrimSpace(cleaned.String())
 }
 
+// cutBullet returns line without its leading bullet, "•", "-" or "*". The bullet is cut
+// as a whole rune, so a multi-byte "•" does not leave invalid UTF-8 behind.
+func cutBullet(line string) (string, bool) {
+	for _, bullet := range []string{"•", "-", "*"} {
+		if rest, ok := strings.CutPrefix(line, bullet); ok {
+			return rest, true
+		}
+	}
+	return line, false
+}
+
 // cleanAndStructureContent is the internal version used by FormatLocalChunk
 func (t *TextProcessor) cleanAndStructureContent(chunk string) string {
 	return t.CleanAndStructureContent(chunk)
 }
 
+// HeadingLevel returns the level, 1 to 3, of a heading inferred from its numbering:
+// BAB and "1." are level 1, Bagian and "1.1" level 2, Pasal and "1.1.1" level 3. It
+// returns 0 for lines that are not numbered headings.
+func (t *TextProcessor) HeadingLevel(line string) int {
+	trimmed := strings.TrimSpace(line)
+	if utf8.RuneCountInString(trimmed) > maxHeadingLength || strings.TrimRight(trimmed, sentencePunctuation) != trimmed {
+		return 0
+	}
+	for _, heading := range headingLevels {
+		if heading.pattern.MatchString(trimmed) {
+			return heading.level
+		}
+	}
+	return 0
+}
+
 // IsHeading checks if a line is a heading
 func (t *TextProcessor) IsHeading(line string) bool {
 	trimmed := strings.TrimSpace(line)
+	if t.HeadingLevel(trimmed) > 0 {
+		return true
+	}
 
 	// Check for various heading patterns
-	headingPatterns := []string{
-		`^Bab\s+\d+`,         // Bab 1, Bab 2, etc.
-		`^Pasal\s+\d+`,       // Pasal 1, Pasal 2, etc.
-		`^Chapter\s+\d+`,     // Chapter 1, Chapter 2, etc.
-		`^Section\s+\d+`,     // Section 1, Section 2, etc.
-		`^Artikel\s+\d+`,     // Artikel 1, Artikel 2, etc.
-		`^BAB\s+\d+`,         // BAB 1, BAB 2, etc.
-		`^PASAL\s+\d+`,       // PASAL 1, PASAL 2, etc.
-		`^\d+\.\s+[A-Z]`,     // 1. Title, 2. Title, etc.
-		`^[A-Z][A-Z\s]{3,}$`, // ALL CAPS HEADINGS
-		`^[A-Z][a-z\s]{3,}$`, // Title Case Headings
-	}
-
-	for _, pattern := range headingPatterns {
-		if matched, _ := regexp.MatchString(pattern, trimmed); matched {
+	for _, pattern := range naturalBreakPatterns {
+		if pattern.MatchString(trimmed) {
 			return true
 		}
 	}
 
 	// Check if it looks like a heading (short, ends with colon or period)
-	if len(trimmed) < 100 && (strings.HasSuffix(trimmed, ":") || strings.HasSuffix(trimmed, ".")) {
+	if utf8.RuneCountInString(trimmed) < 100 && (strings.HasSuffix(trimmed, ":") || strings.HasSuffix(trimmed, ".")) {
 		return true
 	}
 
@@ -371,7 +716,7 @@ func (t *TextProcessor) SaveJSONChunk(chunk interface{}, jsonDir, filename strin
 	}
 
 	// Create JSON directory for this file
-	jsonFileDir := filepath.Join(jsonDir, strings.TrimSuffix(filename, filepath.Ext(filename)))
+	jsonFileDir := filepath.Join(jsonDir, filepath.FromSlash(Slug(filename)))
 	if err := os.MkdirAll(jsonFileDir, 0755); err != nil {
 		return fmt.Errorf("failed to create JSON directory: %w", err)
 	}
@@ -0,0 +1,35 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/chunkertest"
+	"github.com/firdasafridi/pdf-chunk-extractor/pkg/utils"
+)
+
+// corpus is the text the splitters are benchmarked on, built once
+var corpus = chunkertest.Corpus(chunkertest.CorpusPages)
+
+// benchmarkSplit times a splitter on the corpus, reporting MB/s and allocations
+func benchmarkSplit(b *testing.B, split func(processor *utils.TextProcessor, text string) []string) {
+	processor := utils.NewTextProcessor(4000, 3000)
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		split(processor, corpus)
+	}
+}
+
+func BenchmarkSplitTextIntoChunks(b *testing.B) {
+	benchmarkSplit(b, (*utils.TextProcessor).SplitTextIntoChunks)
+}
+
+func BenchmarkSplitTextIntoLocalChunks(b *testing.B) {
+	benchmarkSplit(b, (*utils.TextProcessor).SplitTextIntoLocalChunks)
+}
+
+func BenchmarkSplitTextIntoPageGroups(b *testing.B) {
+	benchmarkSplit(b, func(processor *utils.TextProcessor, text string) []string {
+		return processor.SplitTextIntoPageGroups(text, 3000)
+	})
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// fuzzSeeds are the inputs every fuzz target starts from: extracted text as the PDF
+// processor produces it, and the malformed separators, bullets and scripts that made
+// the text functions panic or cut runes before
+var fuzzSeeds = []string{
+	"",
+	"\n\n--- Page 1 ---\n\nBAB I\nKETENTUAN UMUM\n\nPasal 1\n\n1. Karyawan wajib hadir.\n- Cuti tahunan\n",
+	"--- Page 3 ---\ntext\n--- Page 4 ---\nmore text",
+	"--- Page\n--- Page ---\n--- Page x ---\nsee --- Page 7 --- above",
+	"• bullet\n•\n* star\n-\n- dash",
+	"SOP/HR/001\n12 - Januari - 2024\nPeraturan Perusahaan\n",
+	"日本語のテキストです。中文文本。ภาษาไทยไม่มีช่องว่าง",
+	"A very long line without breaks " + strings.Repeat("word ", 200),
+	"\xff\xfe--- Page 2 ---\xc0\n",
+	"--- Page",                                                    // Made CleanAndStructureContent index past the end of the line
+	strings.Repeat("x", 1000) + "\n" + strings.Repeat("ไทย", 200), // Runs without spaces were kept as one chunk
+}
+
+// separatorPattern matches the page separators ExtractPageRange reads
+var separatorPattern = regexp.MustCompile(`--- Page \d+ ---`)
+
+// fuzzProcessor returns the processor the fuzz targets run, with chunks small enough
+// that seeds are cut into several
+func fuzzProcessor() *TextProcessor {
+	return NewTextProcessor(200, 100)
+}
+
+// FuzzSplitTextIntoLocalChunks checks that SplitTextIntoLocalChunks never panics, keeps
+// every non-space character of the text in order, returns no empty, untrimmed or
+// oversized chunk and keeps valid UTF-8 valid. A chunk is flushed once it passes the
+// local chunk size, so it holds at most twice that: the lines before, and a last line
+// or piece of a line of at most the size. Run it with go test -fuzz=FuzzSplitTextIntoLocalChunks
+// ./pkg/utils; the other Fuzz functions run the same way.
+func FuzzSplitTextIntoLocalChunks(f *testing.F) {
+	addSeeds(f)
+	processor := fuzzProcessor()
+	f.Fuzz(func(t *testing.T, text string) {
+		var kept strings.Builder
+		for i, chunk := range processor.SplitTextIntoLocalChunks(text) {
+			if chunk == "" || chunk != strings.TrimSpace(chunk) {
+				t.Fatalf("chunk %d is empty or untrimmed: %q", i, chunk)
+			}
+			if size := processor.Size(chunk); size > 2*processor.localChunkSize {
+				t.Fatalf("chunk %d is %d characters, over twice the local chunk size %d: %q", i, size, processor.localChunkSize, chunk)
+			}
+			if utf8.ValidString(text) && !utf8.ValidString(chunk) {
+				t.Fatalf("chunk %d is invalid UTF-8: %q", i, chunk)
+			}
+			kept.WriteString(withoutSpace(chunk))
+		}
+		if got, want := kept.String(), withoutSpace(text); got != want {
+			t.Fatalf("chunks do not keep the text:\n  want: %q\n  got:  %q", want, got)
+		}
+	})
+}
+
+// FuzzExtractPageRange checks that ExtractPageRange never panics and returns either
+// nothing or "Page " and numbers found in the text
+func FuzzExtractPageRange(f *testing.F) {
+	addSeeds(f)
+	processor := fuzzProcessor()
+	f.Fuzz(func(t *testing.T, text string) {
+		pageRange := processor.ExtractPageRange(text)
+		if pageRange == "" {
+			if separatorPattern.MatchString(text) {
+				t.Fatalf("no page range in text with a separator: %q", text)
+			}
+			return
+		}
+		pages, ok := strings.CutPrefix(pageRange, "Page ")
+		if !ok {
+			t.Fatalf("page range %q does not start with Page", pageRange)
+		}
+		for _, page := range strings.Split(pages, "–") {
+			if !strings.Contains(text, "--- Page "+page+" ---") {
+				t.Fatalf("page %q of range %q is not in the text", page, pageRange)
+			}
+		}
+	})
+}
+
+// FuzzCleanAndStructureContent checks that CleanAndStructureContent never panics, as
+// it did on "--- Page" without a number, and keeps valid UTF-8 valid
+func FuzzCleanAndStructureContent(f *testing.F) {
+	addSeeds(f)
+	processor := fuzzProcessor()
+	f.Fuzz(func(t *testing.T, text string) {
+		cleaned := processor.CleanAndStructureContent(text)
+		if utf8.ValidString(text) && !utf8.ValidString(cleaned) {
+			t.Fatalf("cleaned content is invalid UTF-8: %q", cleaned)
+		}
+	})
+}
+
+// FuzzExtractMetadata checks that ExtractMetadata never panics and lists only values
+// found in the text
+func FuzzExtractMetadata(f *testing.F) {
+	addSeeds(f)
+	processor := fuzzProcessor()
+	f.Fuzz(func(t *testing.T, text string) {
+		for _, line := range strings.Split(strings.TrimSpace(processor.ExtractMetadata(text)), "\n") {
+			if line == "" {
+				continue
+			}
+			_, value, ok := strings.Cut(line, "**: ")
+			if !ok {
+				t.Fatalf("metadata line %q has no value", line)
+			}
+			for _, item := range strings.Split(value, ", ") {
+				if !strings.Contains(text, item) {
+					t.Fatalf("metadata %q is not in the text", item)
+				}
+			}
+		}
+	})
+}
+
+// addSeeds adds fuzzSeeds to the corpus of f
+func addSeeds(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+}
+
+// withoutSpace returns text without its whitespace
+func withoutSpace(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer splits text into sentences and words, which the splitters use to cut lines
+// longer than a chunk. Plug in a language-specific tokenizer with WithTokenizer where
+// punctuation and whitespace do not separate them, as in Thai. Both methods return
+// consecutive pieces that join back into text, so chunks can be located in the document.
+type Tokenizer interface {
+	Sentences(text string) []string // Each with the whitespace following it
+	Words(text string) []string     // Each with the punctuation and whitespace following it
+}
+
+// UnicodeTokenizer is the default Tokenizer. Sentences end at terminal punctuation
+// followed by whitespace, or at the ideographic full stop and full-width marks of
+// Chinese and Japanese. Words are runs of letters and digits, except that every Han,
+// Hiragana and Katakana character is a word of its own, as those scripts do not use
+// spaces; runs of Thai, Lao, Khmer and Myanmar, which need a dictionary to split, are
+// single words, which the splitters cut between characters when longer than a chunk.
+type UnicodeTokenizer struct{}
+
+// sentenceTerminals end a sentence when whitespace follows them
+const sentenceTerminals = ".!?…"
+
+// fullWidthTerminals end a sentence on their own
+const fullWidthTerminals = "。！？．"
+
+// sentenceClosers may follow the end of a sentence, as in `"Done."`
+const sentenceClosers = `"'”’)]」』）`
+
+// Sentences splits text into sentences
+func (UnicodeTokenizer) Sentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		fullWidth := strings.ContainsRune(fullWidthTerminals, r)
+		if !fullWidth && !strings.ContainsRune(sentenceTerminals, r) {
+			continue
+		}
+		end := skipRunes(text, i, func(r rune) bool {
+			return strings.ContainsRune(sentenceClosers, r) || strings.ContainsRune(sentenceTerminals+fullWidthTerminals, r)
+		})
+		next := skipRunes(text, end, unicode.IsSpace)
+		if !fullWidth && next == end && next < len(text) {
+			continue // No whitespace follows, as in "3.5" or "e.g."
+		}
+		sentences = append(sentences, text[start:next])
+		start, i = next, next
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
+
+// Words splits text into words
+func (UnicodeTokenizer) Words(text string) []string {
+	var words []string
+	start := skipRunes(text, 0, func(r rune) bool { return !isWordRune(r) })
+	if start > 0 {
+		words = append(words, text[:start])
+	}
+	for i := start; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		end := i + size
+		if !isIdeographic(r) {
+			end = skipRunes(text, end, func(r rune) bool { return isWordRune(r) && !isIdeographic(r) })
+		}
+		end = skipRunes(text, end, func(r rune) bool { return !isWordRune(r) })
+		words = append(words, text[i:end])
+		i = end
+	}
+	return words
+}
+
+// isWordRune reports whether a character belongs to a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r)
+}
+
+// isIdeographic reports whether a character is a word on its own: Han, Hiragana or
+// Katakana
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// skipRunes returns the byte offset of the first character at or after i that does not
+// satisfy skip
+func skipRunes(text string, i int, skip func(r rune) bool) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !skip(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// splitLine cuts a line longer than maxSize, in the processor's measure, into
+// consecutive pieces of at most maxSize: whole sentences where they fit, otherwise whole
+// words, and a word longer than maxSize, such as a run without spaces, is cut between
+// characters. Shorter lines are returned as they are. The pieces are appended to
+// pieces, so callers reuse one slice for every line.
+func (t *TextProcessor) splitLine(pieces []string, line string, maxSize int) []string {
+	if maxSize <= 0 || t.measure(line) <= maxSize {
+		return append(pieces, line)
+	}
+	var current strings.Builder
+	size := 0
+	add := func(part string) {
+		partSize := t.measure(part)
+		if size > 0 && size+partSize > maxSize {
+			pieces = append(pieces, current.String())
+			current.Reset()
+			size = 0
+		}
+		current.WriteString(part)
+		size += partSize
+	}
+	for _, sentence := range t.tokenizer.Sentences(line) {
+		if t.measure(sentence) <= maxSize {
+			add(sentence)
+			continue
+		}
+		for _, word := range t.tokenizer.Words(sentence) {
+			for word != "" {
+				part := t.cutRunes(word, maxSize)
+				add(part)
+				word = word[len(part):]
+			}
+		}
+	}
+	if current.Len() > 0 {
+		pieces = append(pieces, current.String())
+	}
+	return pieces
+}
+
+// cutRunes returns the longest prefix of text of at most maxSize, in the processor's
+// measure, that ends at a character boundary, and at least the first character
+func (t *TextProcessor) cutRunes(text string, maxSize int) string {
+	if t.measure(text) <= maxSize {
+		return text
+	}
+	end, size := 0, 0
+	for end < len(text) {
+		_, width := utf8.DecodeRuneInString(text[end:])
+		runeSize := t.measure(text[end : end+width])
+		if end > 0 && size+runeSize > maxSize {
+			break
+		}
+		end, size = end+width, size+runeSize
+	}
+	return text[:end]
+}
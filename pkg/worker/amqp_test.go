@@ -0,0 +1,339 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestAMQPFrames checks encoded frames against the byte layout of the AMQP 0-9-1
+// specification: type, channel, payload size, payload and the 0xCE frame end
+func TestAMQPFrames(t *testing.T) {
+	var heartbeat amqpEncoder
+	heartbeat.frame(amqpFrameHeartbeat, 0, nil)
+	if want := []byte{8, 0, 0, 0, 0, 0, 0, 0xCE}; !bytes.Equal(heartbeat.buf, want) {
+		t.Errorf("heartbeat frame = % x, want % x", heartbeat.buf, want)
+	}
+
+	// basic.get of queue "pdf-jobs" on channel 1, without no-ack
+	var get amqpEncoder
+	get.uint16(0)
+	get.shortstr("pdf-jobs")
+	get.octet(0)
+	var frame amqpEncoder
+	frame.frame(amqpFrameMethod, 1, append([]byte{0, 60, 0, 70}, get.buf...))
+	want := []byte{
+		1, 0, 1, 0, 0, 0, 16, // Method frame, channel 1, 16 bytes
+		0, 60, 0, 70, // basic.get
+		0, 0, // Reserved
+		8, 'p', 'd', 'f', '-', 'j', 'o', 'b', 's',
+		0, // no-ack off
+		0xCE,
+	}
+	if !bytes.Equal(frame.buf, want) {
+		t.Errorf("basic.get frame = % x, want % x", frame.buf, want)
+	}
+
+	var table amqpEncoder
+	table.stringTable(map[string]string{"product": "x"})
+	if want := []byte{0, 0, 0, 14, 7, 'p', 'r', 'o', 'd', 'u', 'c', 't', 'S', 0, 0, 0, 1, 'x'}; !bytes.Equal(table.buf, want) {
+		t.Errorf("field table = % x, want % x", table.buf, want)
+	}
+}
+
+// TestAMQPReadContent reads a delivered message as RabbitMQ frames it: a content header
+// with content-type, delivery-mode and message-id, then the body over two frames with a
+// heartbeat between them
+func TestAMQPReadContent(t *testing.T) {
+	var header amqpEncoder
+	header.uint16(60)
+	header.uint16(0)
+	header.uint64(11)
+	header.uint16(1<<15 | 1<<13 | 1<<12 | 1<<7) // content-type, headers, delivery-mode, message-id
+	header.shortstr("application/json")
+	header.stringTable(map[string]string{"x-attempt": "2"})
+	header.octet(2)
+	header.shortstr("job-42")
+
+	var frames amqpEncoder
+	frames.frame(amqpFrameHeader, 1, header.buf)
+	frames.frame(amqpFrameBody, 1, []byte(`{"id":`))
+	frames.frame(amqpFrameHeartbeat, 0, nil)
+	frames.frame(amqpFrameBody, 1, []byte(`"42"}`))
+
+	q := &AMQP{reader: bufio.NewReader(bytes.NewReader(frames.buf))}
+	messageID, body, err := q.readContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageID != "job-42" || string(body) != `{"id":"42"}` {
+		t.Errorf("readContent() = %q, %q, want job-42, {\"id\":\"42\"}", messageID, body)
+	}
+}
+
+func TestAMQPReadFrameMalformed(t *testing.T) {
+	for name, frame := range map[string][]byte{
+		"missing frame end": {1, 0, 1, 0, 0, 0, 1, 0, 0},
+		"short":             {1, 0, 1, 0, 0, 0, 9, 0},
+		"too large":         {3, 0, 1, 0xFF, 0xFF, 0xFF, 0xFF},
+	} {
+		q := &AMQP{reader: bufio.NewReader(bytes.NewReader(frame))}
+		if _, _, _, err := q.readFrame(); err == nil {
+			t.Errorf("%s: readFrame succeeded, want an error", name)
+		}
+	}
+}
+
+// amqpFrame is a frame the fake broker read
+type amqpFrame struct {
+	frameType byte
+	channel   uint16
+	payload   []byte
+}
+
+// fakeRabbit is a RabbitMQ broker with one queue, which does not exist until declared
+// durable. It reads frames independently of AMQP.readFrame.
+type fakeRabbit struct {
+	t        *testing.T
+	listener net.Listener
+	frameMax uint32
+
+	mu          sync.Mutex
+	declared    bool
+	queue       [][]byte          // Messages waiting in the queue
+	unacked     map[uint64][]byte // Delivered messages by delivery tag
+	deliveryTag uint64
+	login       string
+}
+
+func newFakeRabbit(t *testing.T) *fakeRabbit {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeRabbit{t: t, listener: listener, frameMax: 4096, unacked: make(map[uint64][]byte)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func readAMQPFrame(reader io.Reader) (amqpFrame, error) {
+	var header [7]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return amqpFrame{}, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[3:])+1)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return amqpFrame{}, err
+	}
+	if payload[len(payload)-1] != 0xCE {
+		return amqpFrame{}, errors.New("missing frame end")
+	}
+	return amqpFrame{header[0], binary.BigEndian.Uint16(header[1:]), payload[:len(payload)-1]}, nil
+}
+
+func writeAMQPFrame(w io.Writer, frameType byte, channel uint16, payload []byte) {
+	frame := []byte{frameType}
+	frame = binary.BigEndian.AppendUint16(frame, channel)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	w.Write(append(frame, 0xCE))
+}
+
+func writeAMQPMethod(w io.Writer, channel, class, method uint16, args ...byte) {
+	writeAMQPFrame(w, 1, channel, append(binary.BigEndian.AppendUint32(nil, uint32(class)<<16|uint32(method)), args...))
+}
+
+// shortstr encodes an AMQP short string
+func shortstr(s string) []byte {
+	return append([]byte{byte(len(s))}, s...)
+}
+
+func (r *fakeRabbit) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	protocol := make([]byte, 8)
+	if _, err := io.ReadFull(reader, protocol); err != nil || string(protocol) != "AMQP\x00\x00\x09\x01" {
+		r.t.Errorf("protocol header = %q, want AMQP 0-9-1", protocol)
+		return
+	}
+
+	// connection.start: version 0-9, empty server properties, mechanisms and locales
+	start := []byte{0, 9, 0, 0, 0, 0}
+	start = append(binary.BigEndian.AppendUint32(start, 14), "AMQPLAIN PLAIN"...)
+	start = append(binary.BigEndian.AppendUint32(start, 5), "en_US"...)
+	writeAMQPMethod(conn, 0, 10, 10, start...)
+
+	for {
+		frame, err := readAMQPFrame(reader)
+		if err != nil {
+			return
+		}
+		if frame.frameType == amqpFrameHeartbeat {
+			continue
+		}
+		class, method := binary.BigEndian.Uint16(frame.payload), binary.BigEndian.Uint16(frame.payload[2:])
+		args := frame.payload[4:]
+		switch {
+		case class == 10 && method == 11: // connection.start-ok
+			decoder := amqpDecoder{buf: args}
+			decoder.table()
+			mechanism, response := decoder.shortstr(), decoder.longstr()
+			r.mu.Lock()
+			r.login = mechanism + " " + strings.ReplaceAll(response, "\x00", "|")
+			r.mu.Unlock()
+			tune := binary.BigEndian.AppendUint16(nil, 2047)
+			tune = binary.BigEndian.AppendUint32(tune, r.frameMax)
+			tune = binary.BigEndian.AppendUint16(tune, 0)
+			writeAMQPMethod(conn, 0, 10, 30, tune...)
+		case class == 10 && method == 31: // connection.tune-ok
+		case class == 10 && method == 40: // connection.open
+			writeAMQPMethod(conn, 0, 10, 41, 0)
+		case class == 10 && method == 50: // connection.close
+			writeAMQPMethod(conn, 0, 10, 51)
+			return
+		case class == 20 && method == 10: // channel.open
+			writeAMQPMethod(conn, frame.channel, 20, 11, 0, 0, 0, 0)
+		case class == 20 && method == 41: // channel.close-ok
+		case class == 85 && method == 10: // confirm.select
+			writeAMQPMethod(conn, frame.channel, 85, 11)
+		case class == 50 && method == 10: // queue.declare
+			passive := args[2+1+int(args[2])]&1 != 0
+			r.mu.Lock()
+			if passive && !r.declared {
+				r.mu.Unlock()
+				closeArgs := append(binary.BigEndian.AppendUint16(nil, 404), shortstr("NOT_FOUND - no queue 'pdf-jobs'")...)
+				writeAMQPMethod(conn, frame.channel, 20, 40, append(closeArgs, 0, 50, 0, 10)...)
+				continue
+			}
+			r.declared = true
+			r.mu.Unlock()
+			declareOK := append(shortstr("pdf-jobs"), 0, 0, 0, 0, 0, 0, 0, 0)
+			writeAMQPMethod(conn, frame.channel, 50, 11, declareOK...)
+		case class == 60 && method == 40: // basic.publish, then the content
+			header, err := readAMQPFrame(reader)
+			if err != nil || header.frameType != amqpFrameHeader {
+				r.t.Errorf("basic.publish is not followed by a content header: %v", err)
+				return
+			}
+			size := binary.BigEndian.Uint64(header.payload[4:])
+			var body []byte
+			for uint64(len(body)) < size {
+				frame, err := readAMQPFrame(reader)
+				if err != nil {
+					return
+				}
+				if len(frame.payload) > int(r.frameMax)-8 {
+					r.t.Errorf("body frame of %d bytes is over the negotiated frame max", len(frame.payload))
+				}
+				body = append(body, frame.payload...)
+			}
+			r.mu.Lock()
+			r.queue = append(r.queue, body)
+			r.mu.Unlock()
+			writeAMQPMethod(conn, frame.channel, 60, 80, append(binary.BigEndian.AppendUint64(nil, 1), 0)...)
+		case class == 60 && method == 70: // basic.get
+			r.mu.Lock()
+			if len(r.queue) == 0 {
+				r.mu.Unlock()
+				writeAMQPMethod(conn, frame.channel, 60, 72, 0)
+				continue
+			}
+			body := r.queue[0]
+			r.queue = r.queue[1:]
+			r.deliveryTag++
+			tag := r.deliveryTag
+			r.unacked[tag] = body
+			r.mu.Unlock()
+
+			getOK := binary.BigEndian.AppendUint64(nil, tag)
+			getOK = append(getOK, 0)
+			getOK = append(getOK, shortstr("")...)
+			getOK = append(getOK, shortstr("pdf-jobs")...)
+			getOK = binary.BigEndian.AppendUint32(getOK, 0)
+			writeAMQPMethod(conn, frame.channel, 60, 71, getOK...)
+			header := binary.BigEndian.AppendUint16(nil, 60)
+			header = binary.BigEndian.AppendUint16(header, 0)
+			header = binary.BigEndian.AppendUint64(header, uint64(len(body)))
+			header = binary.BigEndian.AppendUint16(header, 1<<7)
+			header = append(header, shortstr("job-1")...)
+			writeAMQPFrame(conn, amqpFrameHeader, frame.channel, header)
+			for rest := body; len(rest) > 0; {
+				n := min(len(rest), int(r.frameMax)-8)
+				writeAMQPFrame(conn, amqpFrameBody, frame.channel, rest[:n])
+				rest = rest[n:]
+			}
+		case class == 60 && method == 80: // basic.ack
+			r.mu.Lock()
+			delete(r.unacked, binary.BigEndian.Uint64(args))
+			r.mu.Unlock()
+		default:
+			r.t.Errorf("unexpected method %d.%d", class, method)
+		}
+	}
+}
+
+func TestAMQPQueue(t *testing.T) {
+	rabbit := newFakeRabbit(t)
+	q, err := NewAMQP(AMQPOptions{URL: "amqp://worker:secret@" + rabbit.listener.Addr().String() + "/?queue=pdf-jobs", Timeout: 5 * time.Second, PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	rabbit.mu.Lock()
+	login, declared := rabbit.login, rabbit.declared
+	rabbit.mu.Unlock()
+	if login != "PLAIN |worker|secret" {
+		t.Errorf("login = %q, want PLAIN with worker and secret", login)
+	}
+	if !declared {
+		t.Error("the missing queue was not declared")
+	}
+
+	// A body over the 4096-byte frame max is sent over several frames
+	body := []byte(`{"id":"` + strings.Repeat("x", 10000) + `"}`)
+	if err := q.Send(context.Background(), body); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	messages, err := q.Receive(ctx, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].ID != "job-1" || !bytes.Equal(messages[0].Body, body) {
+		t.Fatalf("Receive() = %d messages, want the sent one with ID job-1", len(messages))
+	}
+	if err := q.Ack(ctx, messages[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// The ack is sent without waiting for an answer; a round trip makes sure it arrived
+	if _, err := q.get(1); err != nil {
+		t.Fatal(err)
+	}
+	rabbit.mu.Lock()
+	unacked := len(rabbit.unacked)
+	rabbit.mu.Unlock()
+	if unacked != 0 {
+		t.Errorf("%d messages are unacknowledged after Ack", unacked)
+	}
+}
@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exampleCredentials are the credentials of the AWS Signature Version 4 test suite and
+// documentation examples
+var exampleCredentials = AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+// TestAuthorize signs requests of the AWS Signature Version 4 test suite
+// (aws-sig-v4-test-suite) and the IAM example of the AWS documentation, and compares
+// the Authorization headers with the published ones
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		headers map[string]string
+		body    string
+		service string
+		want    string
+	}{
+		{
+			name:    "get-vanilla",
+			method:  "GET",
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:    "get-vanilla-query-order-key-case",
+			method:  "GET",
+			url:     "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:    "get-vanilla-query-unreserved",
+			method:  "GET",
+			url:     "https://example.amazonaws.com/?-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz=-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=9c3e54bfcdf0b19771a7f523ee5669cdf59bc7cc0884027167c21bb143a40197",
+		},
+		{
+			name:    "post-vanilla",
+			method:  "POST",
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:    "post-header-key-sort",
+			method:  "POST",
+			url:     "https://example.amazonaws.com/",
+			headers: map[string]string{"My-Header1": "value1"},
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;my-header1;x-amz-date, Signature=c5410059b04c1ee005303aed430f6e6645f61f4dc9e1461ec8f8916fdf18852c",
+		},
+		{
+			name:    "post-x-www-form-urlencoded",
+			method:  "POST",
+			url:     "https://example.amazonaws.com/",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:    "Param1=value1",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+		{
+			name:    "IAM ListUsers documentation example",
+			method:  "GET",
+			url:     "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			service: "iam",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+			req.Header.Set("X-Amz-Date", "20150830T123600Z")
+			authorize(req, sha256Hex([]byte(test.body)), exampleCredentials, "us-east-1", test.service, "20150830T123600Z")
+			if got := req.Header.Get("Authorization"); got != test.want {
+				t.Errorf("Authorization =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+// TestSigningKey checks the key derivation against the example of the AWS documentation
+func TestSigningKey(t *testing.T) {
+	key := hmacSHA256([]byte("AWS4"+exampleCredentials.SecretAccessKey), "20150830")
+	key = hmacSHA256(key, "us-east-1")
+	key = hmacSHA256(key, "iam")
+	key = hmacSHA256(key, "aws4_request")
+	if got, want := hex.EncodeToString(key), "c4afb1cc5771d871763a393e44b703571b55cc28424d1a5e86da6ed3c154a4b9"; got != want {
+		t.Errorf("signing key = %s, want %s", got, want)
+	}
+}
+
+// TestSignV4 checks the headers signV4 adds: the payload hash S3 needs and the session
+// token of temporary credentials, both signed
+func TestSignV4(t *testing.T) {
+	credentials := exampleCredentials
+	credentials.SessionToken = "session-token"
+	body := []byte("Action=ReceiveMessage")
+	req, err := http.NewRequest("POST", "https://sqs.eu-west-1.amazonaws.com/123456789012/documents", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signV4(req, body, credentials, "eu-west-1", "sqs", time.Date(2015, 8, 30, 14, 36, 0, 0, time.FixedZone("CEST", 2*60*60)))
+
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %s, want the time in UTC", got)
+	}
+	if got, want := req.Header.Get("X-Amz-Content-Sha256"), sha256Hex(body); got != want {
+		t.Errorf("X-Amz-Content-Sha256 = %s, want %s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session-token" {
+		t.Errorf("X-Amz-Security-Token = %q", got)
+	}
+	authorization := req.Header.Get("Authorization")
+	if !strings.Contains(authorization, "Credential=AKIDEXAMPLE/20150830/eu-west-1/sqs/aws4_request,") ||
+		!strings.Contains(authorization, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Authorization = %s", authorization)
+	}
+}
+
+func TestAWSEscape(t *testing.T) {
+	tests := []struct {
+		in        string
+		keepSlash bool
+		want      string
+	}{
+		{"-._~AZaz09", false, "-._~AZaz09"},
+		{"a b+c=d&e", false, "a%20b%2Bc%3Dd%26e"},
+		{"docs/2024 report.pdf", true, "docs/2024%20report.pdf"},
+		{"docs/report", false, "docs%2Freport"},
+		{"é", false, "%C3%A9"},
+	}
+	for _, test := range tests {
+		if got := awsEscape(test.in, test.keepSlash); got != test.want {
+			t.Errorf("awsEscape(%q, %v) = %q, want %q", test.in, test.keepSlash, got, test.want)
+		}
+	}
+}
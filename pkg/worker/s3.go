@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Options configures reading job sources from S3
+type S3Options struct {
+	Region      string         // e.g. "ap-southeast-3"; default AWS_REGION
+	Endpoint    string         // S3-compatible endpoint, addressed path-style, e.g. "http://localhost:9000" for MinIO; default AWS S3
+	Credentials AWSCredentials // Default AWSCredentialsFromEnv()
+	Client      *http.Client   // Default http.DefaultClient
+}
+
+// S3 reads the s3://bucket/key sources of jobs
+type S3 struct {
+	options S3Options
+}
+
+// NewS3 creates an S3 reader
+func NewS3(options S3Options) *S3 {
+	if options.Region == "" {
+		options.Region = awsRegionFromEnv()
+	}
+	if options.Credentials == (AWSCredentials{}) {
+		options.Credentials = AWSCredentialsFromEnv()
+	}
+	if options.Client == nil {
+		options.Client = http.DefaultClient
+	}
+	return &S3{options: options}
+}
+
+// Open starts reading the object at uri, s3://bucket/key; close the returned reader
+func (s *S3) Open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(uri, "s3://"), "/")
+	if !strings.HasPrefix(uri, "s3://") || !ok || bucket == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 URI %q, want s3://bucket/key", uri)
+	}
+	if s.options.Region == "" {
+		return nil, fmt.Errorf("no S3 region: set AWS_REGION")
+	}
+
+	// Virtual-hosted style on AWS, except for bucket names with dots, which do not match
+	// the wildcard certificate; path style on other endpoints
+	objectURL := &url.URL{Scheme: "https", Host: bucket + ".s3." + s.options.Region + ".amazonaws.com", Path: "/" + key}
+	switch {
+	case s.options.Endpoint != "":
+		endpoint, err := url.Parse(s.options.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+		}
+		objectURL = &url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host, Path: strings.TrimSuffix(endpoint.Path, "/") + "/" + bucket + "/" + key}
+	case strings.Contains(bucket, "."):
+		objectURL = &url.URL{Scheme: "https", Host: "s3." + s.options.Region + ".amazonaws.com", Path: "/" + bucket + "/" + key}
+	}
+	objectURL.RawPath = awsEscape(objectURL.Path, true)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	signV4(req, nil, s.options.Credentials, s.options.Region, "s3", time.Now())
+	resp, err := s.options.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", uri, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to get %s: %s: %s", uri, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp.Body, nil
+}
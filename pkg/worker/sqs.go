@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SQSOptions configures an SQS queue
+type SQSOptions struct {
+	QueueURL          string         // e.g. "https://sqs.ap-southeast-3.amazonaws.com/123456789012/pdf-jobs"; required
+	Region            string         // Default: from QueueURL, else AWS_REGION
+	Credentials       AWSCredentials // Default AWSCredentialsFromEnv()
+	WaitTime          time.Duration  // Long poll of Receive, at most 20s (the default)
+	VisibilityTimeout time.Duration  // Time a received job is hidden from other workers; longer than the slowest document. 0 keeps the queue's setting.
+	Client            *http.Client   // Default http.DefaultClient
+}
+
+// SQS is a Queue on Amazon SQS, or a service speaking its JSON protocol such as
+// ElasticMQ. Jobs that are not acknowledged reappear after the visibility timeout; a
+// redrive policy moves jobs that keep failing to a dead-letter queue.
+type SQS struct {
+	options  SQSOptions
+	endpoint string
+}
+
+// sqsMessage is a message of a ReceiveMessage response
+type sqsMessage struct {
+	MessageID     string `json:"MessageId"`
+	ReceiptHandle string `json:"ReceiptHandle"`
+	Body          string `json:"Body"`
+}
+
+// NewSQS creates an SQS queue
+func NewSQS(options SQSOptions) (*SQS, error) {
+	queueURL, err := url.Parse(options.QueueURL)
+	if err != nil || queueURL.Host == "" {
+		return nil, fmt.Errorf("invalid SQS queue URL %q", options.QueueURL)
+	}
+	if options.Region == "" {
+		// sqs.<region>.amazonaws.com
+		if labels := strings.Split(queueURL.Hostname(), "."); len(labels) >= 4 && labels[0] == "sqs" {
+			options.Region = labels[1]
+		} else {
+			options.Region = awsRegionFromEnv()
+		}
+	}
+	if options.Region == "" {
+		return nil, fmt.Errorf("no SQS region: set AWS_REGION")
+	}
+	if options.Credentials == (AWSCredentials{}) {
+		options.Credentials = AWSCredentialsFromEnv()
+	}
+	if options.WaitTime <= 0 || options.WaitTime > 20*time.Second {
+		options.WaitTime = 20 * time.Second
+	}
+	if options.Client == nil {
+		options.Client = http.DefaultClient
+	}
+	return &SQS{options: options, endpoint: queueURL.Scheme + "://" + queueURL.Host + "/"}, nil
+}
+
+// Receive long-polls for up to limit jobs, at most 10
+func (q *SQS) Receive(ctx context.Context, limit int) ([]Message, error) {
+	request := map[string]any{
+		"QueueUrl":            q.options.QueueURL,
+		"MaxNumberOfMessages": min(max(limit, 1), 10),
+		"WaitTimeSeconds":     int(q.options.WaitTime.Seconds()),
+	}
+	if q.options.VisibilityTimeout > 0 {
+		request["VisibilityTimeout"] = int(q.options.VisibilityTimeout.Seconds())
+	}
+	var response struct {
+		Messages []sqsMessage `json:"Messages"`
+	}
+	if err := q.call(ctx, "ReceiveMessage", request, &response); err != nil {
+		return nil, err
+	}
+
+	messages := make([]Message, len(response.Messages))
+	for i, message := range response.Messages {
+		messages[i] = Message{ID: message.MessageID, Body: []byte(message.Body), handle: message.ReceiptHandle}
+	}
+	return messages, nil
+}
+
+// Ack deletes a processed job
+func (q *SQS) Ack(ctx context.Context, message Message) error {
+	return q.call(ctx, "DeleteMessage", map[string]any{
+		"QueueUrl":      q.options.QueueURL,
+		"ReceiptHandle": message.handle,
+	}, nil)
+}
+
+// Nack makes a job visible again at once, for another worker to retry
+func (q *SQS) Nack(ctx context.Context, message Message) error {
+	return q.call(ctx, "ChangeMessageVisibility", map[string]any{
+		"QueueUrl":          q.options.QueueURL,
+		"ReceiptHandle":     message.handle,
+		"VisibilityTimeout": 0,
+	}, nil)
+}
+
+// Send sends a message. FIFO queues get a group of their own and a deduplication ID of
+// the body, so a result sent twice is delivered once.
+func (q *SQS) Send(ctx context.Context, body []byte) error {
+	request := map[string]any{
+		"QueueUrl":    q.options.QueueURL,
+		"MessageBody": string(body),
+	}
+	if strings.HasSuffix(q.options.QueueURL, ".fifo") {
+		sum := sha256.Sum256(body)
+		request["MessageGroupId"] = "pdf-chunk-extractor"
+		request["MessageDeduplicationId"] = hex.EncodeToString(sum[:])
+	}
+	return q.call(ctx, "SendMessage", request, nil)
+}
+
+// Close does nothing; SQS requests share no connection state
+func (q *SQS) Close() error {
+	return nil
+}
+
+// GetName returns the queue name
+func (q *SQS) GetName() string {
+	return "SQS"
+}
+
+// call sends an SQS JSON protocol request and decodes its response into response, when
+// not nil
+func (q *SQS) call(ctx context.Context, action string, request any, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", action, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "AmazonSQS."+action)
+	signV4(req, body, q.options.Credentials, q.options.Region, "sqs", time.Now())
+
+	resp, err := q.options.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("SQS %s failed: %w", action, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read SQS %s response: %w", action, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &failure) == nil && failure.Type != "" {
+			return fmt.Errorf("SQS %s failed: %s: %s", action, failure.Type, failure.Message)
+		}
+		return fmt.Errorf("SQS %s failed: %s: %s", action, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if response == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("failed to parse SQS %s response: %w", action, err)
+	}
+	return nil
+}